@@ -64,7 +64,7 @@ func main() {
 	}
 
 	fmt.Printf("➡️  Redirect the customer's browser to: %s\n", resp.PaymentURL)
-	if form, ok := resp.Metadata["form_data"].(url.Values); ok {
+	if form, ok := resp.Metadata[rimpay.MetadataKeyFormData].(url.Values); ok {
 		fmt.Println("   POST these hidden form fields:")
 		for key := range form {
 			fmt.Printf("     %s = %s\n", key, form.Get(key))
@@ -148,9 +148,9 @@ func processMasrviPayment(client *rimpay.Client, ctx context.Context, request *r
 	fmt.Printf("   Transaction ID: %s\n", response.TransactionID)
 	fmt.Printf("   Status: %s (pending customer action)\n", response.Status)
 
-	if paymentURL, exists := response.Metadata["payment_url"]; exists {
-		fmt.Printf("   🌐 Payment URL: %s\n", paymentURL)
-		fmt.Printf("   💡 Customer should be redirected to this URL to complete payment\n")
+	if redirect, ok := response.RedirectInfo(); ok {
+		fmt.Printf("   🌐 Payment URL: %s (%s, %d form fields)\n", redirect.URL, redirect.Method, len(redirect.Fields))
+		fmt.Printf("   💡 Serve response.RenderAutoSubmitForm to redirect the customer\n")
 	}
 
 	fmt.Printf("   ⏳ Waiting for customer to complete payment...\n")
@@ -160,7 +160,11 @@ func processMasrviPayment(client *rimpay.Client, ctx context.Context, request *r
 
 // Webhook server to handle MASRVI notifications
 func startWebhookServer() {
-	http.HandleFunc("/webhook", handleWebhook)
+	http.Handle("/webhook", rimpay.NewMasrviWebhookHandler(rimpay.MasrviWebhookOptions{
+		Handle: handleWebhook,
+		// SigningKey: config.Security.SigningKey, // verify notification signatures
+		MaxAge: 10 * time.Minute,
+	}))
 
 	fmt.Println("🚀 Starting webhook server on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -168,58 +172,42 @@ func startWebhookServer() {
 	}
 }
 
-func handleWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Parse MASRVI notification parameters
-	status := r.URL.Query().Get("status")
-	clientID := r.URL.Query().Get("clientid")
-	customerName := r.URL.Query().Get("cname")
-	mobile := r.URL.Query().Get("mobile")
-	purchaseRef := r.URL.Query().Get("purchaseref")
-	paymentRef := r.URL.Query().Get("paymentref")
-	payID := r.URL.Query().Get("payid")
-	timestamp := r.URL.Query().Get("timestamp")
-	ipAddr := r.URL.Query().Get("ipaddr")
-	errorMsg := r.URL.Query().Get("error")
+// handleWebhook receives parsed, verified notifications. Returning an error
+// answers 500 so MASRVI retries; otherwise MASRVI receives "OK".
+func handleWebhook(ctx context.Context, n *rimpay.MasrviNotificationData) error {
+	errorMsg, _ := n.Data["error"].(string)
 
 	fmt.Printf("\n🔔 Webhook Notification Received:\n")
-	fmt.Printf("   Status: %s\n", status)
-	fmt.Printf("   Client ID: %s\n", clientID)
-	fmt.Printf("   Customer: %s\n", customerName)
-	fmt.Printf("   Mobile: %s\n", mobile)
-	fmt.Printf("   Purchase Ref: %s\n", purchaseRef)
-	fmt.Printf("   Payment Ref: %s\n", paymentRef)
-	fmt.Printf("   Pay ID: %s\n", payID)
-	fmt.Printf("   Timestamp: %s\n", timestamp)
-	fmt.Printf("   IP Address: %s\n", ipAddr)
+	fmt.Printf("   Status: %s\n", n.Status)
+	fmt.Printf("   Client ID: %v\n", n.Data["client_id"])
+	fmt.Printf("   Customer: %v\n", n.Data["customer_name"])
+	fmt.Printf("   Mobile: %s\n", n.PhoneNumber)
+	fmt.Printf("   Purchase Ref: %s\n", n.Reference)
+	fmt.Printf("   Payment Ref: %s\n", n.TransactionID)
+	fmt.Printf("   Pay ID: %v\n", n.Data["pay_id"])
+	fmt.Printf("   Timestamp: %s\n", n.Timestamp)
 
 	if errorMsg != "" {
 		fmt.Printf("   Error: %s\n", errorMsg)
 	}
 
 	// Process the notification
-	switch status {
+	switch n.Status {
 	case "Ok":
 		fmt.Printf("   ✅ Payment successful!\n")
 		// Update your database, send confirmation email, etc.
-		handleSuccessfulPayment(purchaseRef, paymentRef, mobile)
+		handleSuccessfulPayment(n.Reference, n.TransactionID, n.PhoneNumber)
 
 	case "NOK":
 		fmt.Printf("   ❌ Payment failed!\n")
 		// Handle failed payment
-		handleFailedPayment(purchaseRef, errorMsg)
+		handleFailedPayment(n.Reference, errorMsg)
 
 	default:
-		fmt.Printf("   ❓ Unknown status: %s\n", status)
+		fmt.Printf("   ❓ Unknown status: %s\n", n.Status)
 	}
 
-	// Respond to MASRVI
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	return nil
 }
 
 func handleSuccessfulPayment(purchaseRef, paymentRef, mobile string) {
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	_ "github.com/CatoSystems/rim-pay/pkg/providers" // register all providers
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay/metrics/prometheus"
+)
+
+func main() {
+	config := rimpay.DefaultConfig()
+	config.DefaultProvider = rimpay.ProviderMock
+	config.Providers[rimpay.ProviderMock] = rimpay.ProviderConfig{
+		Enabled: true,
+		Timeout: 30 * time.Second,
+		Options: map[string]interface{}{"latency": "200ms"},
+	}
+
+	client, err := rimpay.NewClient(config)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close(context.Background())
+
+	// Set the collector before adding providers so that their retries and
+	// HTTP calls are measured too
+	collector := prometheus.NewCollector("rimpay")
+	client.WithMetrics(collector)
+
+	if err := client.AddMockProvider(config.Providers[rimpay.ProviderMock]); err != nil {
+		log.Fatalf("Failed to add mock provider: %v", err)
+	}
+
+	phoneNumber, err := phone.NewPhone("22334455")
+	if err != nil {
+		log.Fatalf("Failed to create phone: %v", err)
+	}
+
+	// Generate some traffic: successful, declined and flaky payments
+	for i, prefix := range []string{"ORDER-", "DECLINE-", "FLAKY-"} {
+		_, err := client.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+			Amount:      money.FromFloat64(100, money.MRU),
+			PhoneNumber: phoneNumber,
+			Reference:   fmt.Sprintf("%s%d", prefix, i),
+		})
+		fmt.Printf("%s%d: %v\n", prefix, i, err)
+	}
+
+	// Expose the metrics for Prometheus to scrape
+	http.Handle("/metrics", collector)
+	fmt.Println("Serving metrics on http://localhost:9090/metrics")
+	log.Fatal(http.ListenAndServe(":9090", nil))
+}
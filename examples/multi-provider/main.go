@@ -46,11 +46,16 @@ func main() {
 	fmt.Println("\n🔄 Example 3: Dynamic Provider Selection")
 	demonstrateProviderSelection(client, ctx)
 
+	// Example 4: Automatic failover
+	fmt.Println("\n🛟 Example 4: Automatic Provider Failover")
+	demonstrateFailover(client, ctx)
+
 	fmt.Println("\n💡 Multi-Provider Features Demonstrated:")
 	fmt.Println("✅ Multiple payment providers in one configuration")
 	fmt.Println("✅ Provider-specific request types")
 	fmt.Println("✅ Type-safe payment methods")
 	fmt.Println("✅ Dynamic provider selection")
+	fmt.Println("✅ Automatic provider failover")
 	fmt.Println("✅ Unified error handling")
 }
 
@@ -78,6 +83,8 @@ func createMultiProviderConfig() *rimpay.Config {
 				},
 			},
 		},
+		// Fall back to MASRVI when B-PAY is down or erroring
+		Failover: rimpay.FailoverPolicy{Providers: []string{"bpay", "masrvi"}},
 	}
 }
 
@@ -134,22 +141,33 @@ func processMasrviPayment(client *rimpay.Client, ctx context.Context) {
 	fmt.Printf("   ✅ MASRVI payment form created!\n")
 	fmt.Printf("   Transaction ID: %s\n", response.TransactionID)
 	fmt.Printf("   Provider: %s\n", response.Provider)
-	if paymentURL, exists := response.Metadata["payment_url"]; exists {
+	if paymentURL, ok := response.PaymentURLValue(); ok {
 		fmt.Printf("   Payment URL: %s\n", paymentURL)
 	}
 }
 
 func demonstrateProviderSelection(client *rimpay.Client, ctx context.Context) {
-	// Example: Choose provider based on amount
+	// Example: Choose provider based on amount. Client.ProcessRouted applies
+	// Config.Routing.Strategy directly to generic payment requests.
+	strategy := rimpay.ByAmountThresholds(
+		// Small and medium amounts - use B-PAY for instant processing
+		rimpay.AmountThreshold{Below: 20000, Provider: "bpay"},
+		// Large amounts - use MASRVI for web-based confirmation
+		rimpay.AmountThreshold{Provider: "masrvi"},
+	)
 	testAmounts := []float64{10.00, 100.00, 500.00}
 
 	for _, amount := range testAmounts {
-		provider := selectProvider(amount)
-		fmt.Printf("   Amount: %.2f MRU → Recommended provider: %s\n", amount, provider)
-
 		phone, _ := phone.NewPhone("22334455")
 		money := money.New(decimal.NewFromFloat(amount), money.MRU)
 
+		provider, err := strategy.ChooseProvider(ctx, &rimpay.PaymentRequest{Amount: money, PhoneNumber: phone}, client.ListProviders())
+		if err != nil {
+			fmt.Printf("   ❌ No provider for %.2f MRU: %v\n", amount, err)
+			continue
+		}
+		fmt.Printf("   Amount: %.2f MRU → Recommended provider: %s\n", amount, provider)
+
 		switch provider {
 		case "bpay":
 			request := &rimpay.BPayPaymentRequest{
@@ -191,17 +209,21 @@ func demonstrateProviderSelection(client *rimpay.Client, ctx context.Context) {
 	}
 }
 
-func selectProvider(amount float64) string {
-	// Business logic for provider selection
-	switch {
-	case amount < 50.00:
-		// Small amounts - use B-PAY for instant processing
-		return "bpay"
-	case amount < 200.00:
-		// Medium amounts - either provider works
-		return "bpay"
-	default:
-		// Large amounts - use MASRVI for web-based confirmation
-		return "masrvi"
+func demonstrateFailover(client *rimpay.Client, ctx context.Context) {
+	phone, _ := phone.NewPhone("22334455")
+	request := &rimpay.PaymentRequest{
+		Amount:      money.New(decimal.NewFromFloat(120.00), money.MRU),
+		PhoneNumber: phone,
+		Reference:   fmt.Sprintf("FAILOVER-%d", time.Now().Unix()),
+		Description: "Payment with automatic failover",
+		Passcode:    "1234",
+	}
+
+	fmt.Printf("   Processing with failover chain bpay → masrvi...\n")
+	response, err := client.ProcessPaymentWithFailover(ctx, request)
+	if err != nil {
+		fmt.Printf("   ❌ All providers failed: %v\n", err)
+		return
 	}
+	fmt.Printf("   ✅ Handled by %s (path: %v)\n", response.Provider, response.Metadata[rimpay.MetadataFailoverPath])
 }
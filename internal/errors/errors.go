@@ -39,6 +39,30 @@ var (
 	ErrAuthenticationFailed = errors.New("authentication failed")
 	ErrNetworkError         = errors.New("network error")
 	ErrTimeout              = errors.New("request timeout")
+	ErrReturnMismatch       = errors.New("return parameters do not match provider status")
+	ErrPollingExhausted     = errors.New("transaction still pending after polling schedule")
+	ErrNoRoute              = errors.New("no routing strategy matched the payment")
+	ErrRefundNotSupported   = errors.New("provider does not support refunds")
+	ErrStatusNotSupported   = errors.New("provider does not support status queries")
+	ErrClientClosed         = errors.New("payment client is closed")
+	ErrCredentialNotFound   = errors.New("credential not found")
+
+	ErrScheduledPaymentNotFound = errors.New("scheduled payment not found")
+	ErrScheduleConflict         = errors.New("scheduled payment changed state")
+	ErrSchedulerRunning         = errors.New("payment scheduler already running")
+	ErrSubscriptionNotFound     = errors.New("subscription not found")
+	ErrSubscriptionState        = errors.New("subscription state does not allow this change")
+	ErrNotificationRejected     = errors.New("notification rejected")
+	ErrNoRedirect               = errors.New("payment response has no payment URL")
+	ErrIntentNotFound           = errors.New("payment intent not found")
+	ErrIntentConflict           = errors.New("payment intent changed or has an attempt in progress")
+	ErrIntentSucceeded          = errors.New("payment intent already succeeded")
+	ErrAmountMismatch           = errors.New("notification amount does not match the payment")
+	ErrAmountUnverified         = errors.New("notification amount could not be verified")
+	ErrDisbursementNotSupported = errors.New("provider does not support disbursements")
+	ErrLinkNotFound             = errors.New("payment link not found")
+	ErrLinkExpired              = errors.New("payment link expired")
+	ErrLinkUsed                 = errors.New("payment link already used")
 )
 
 // WrapError wraps an error with additional context
@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
+// OptionTokenExpiryMargin is how long before its expiry an access token is
+// renewed (duration, default 30s)
+const OptionTokenExpiryMargin = "token_expiry_margin"
+
+const defaultTokenExpiryMargin = 30 * time.Second
+
 type AuthManager struct {
 	config     rimpay.ProviderConfig
 	httpClient common.HTTPClient
@@ -22,46 +30,114 @@ type AuthManager struct {
 	auth      *AuthResponse
 	authMutex sync.RWMutex
 	baseURL   string
+
+	// expiresAt and refreshExpiresAt are zero when B-PAY did not say
+	expiresAt        time.Time
+	refreshExpiresAt time.Time
+	expiryMargin     time.Duration
+	now              func() time.Time
+
+	// credentialsVersion is the version of the credentials auth was
+	// obtained with
+	credentialsVersion string
 }
 
 // NewAuthManager creates new authentication manager
 func NewAuthManager(config rimpay.ProviderConfig, httpClient common.HTTPClient, logger rimpay.Logger) *AuthManager {
+	margin, err := config.DurationOption(OptionTokenExpiryMargin, defaultTokenExpiryMargin)
+	if err != nil {
+		margin = defaultTokenExpiryMargin
+	}
 	return &AuthManager{
-		config:     config,
-		httpClient: httpClient,
-		logger:     logger,
-		baseURL:    strings.TrimRight(config.BaseURL, "/"),
+		config:       config,
+		httpClient:   httpClient,
+		logger:       logger,
+		baseURL:      strings.TrimRight(config.BaseURL, "/"),
+		expiryMargin: margin,
+		now:          time.Now,
 	}
 }
 
-// GetAccessToken gets valid access token
+// GetAccessToken gets valid access token, renewing it shortly before it
+// expires or when the credentials are rotated. Concurrent callers share a
+// single authentication request.
 func (am *AuthManager) GetAccessToken(ctx context.Context) (string, error) {
+	version, err := am.config.CredentialsVersion(ctx, "bpay")
+	if err != nil {
+		return "", common.CredentialsError(err, "bpay")
+	}
+
 	am.authMutex.RLock()
-	if am.auth != nil && !am.isTokenExpired() {
+	if am.auth != nil && am.credentialsVersion == version && !am.isTokenExpired() {
 		token := am.auth.AccessToken
 		am.authMutex.RUnlock()
 		return token, nil
 	}
 	am.authMutex.RUnlock()
 
-	// Token expired or not available, authenticate
-	return am.authenticate(ctx)
+	am.authMutex.Lock()
+	defer am.authMutex.Unlock()
+
+	am.discardStale(version)
+
+	// Another caller may have renewed the token while we waited
+	if am.auth != nil && !am.isTokenExpired() {
+		return am.auth.AccessToken, nil
+	}
+
+	if am.canRefresh() {
+		err := am.refreshUnsafe(ctx, version)
+		if err == nil {
+			return am.auth.AccessToken, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		rimpay.ContextLogger(ctx, am.logger).Debug("B-PAY token refresh failed, authenticating", "error", err)
+	}
+	return am.authenticateUnsafe(ctx, version)
 }
 
 // RefreshToken refreshes the access token
 func (am *AuthManager) RefreshToken(ctx context.Context) error {
+	version, err := am.config.CredentialsVersion(ctx, "bpay")
+	if err != nil {
+		return common.CredentialsError(err, "bpay")
+	}
+
 	am.authMutex.Lock()
 	defer am.authMutex.Unlock()
 
-	if am.auth == nil || am.auth.RefreshToken == "" {
-		_, err := am.authenticateUnsafe(ctx)
+	am.discardStale(version)
+	if !am.canRefresh() {
+		_, err := am.authenticateUnsafe(ctx, version)
 		return err
 	}
+	return am.refreshUnsafe(ctx, version)
+}
+
+// discardStale drops a token obtained with credentials of another version
+func (am *AuthManager) discardStale(version string) {
+	if am.auth != nil && am.credentialsVersion != version {
+		am.logger.Info("B-PAY credentials rotated, discarding token")
+		am.auth = nil
+	}
+}
+
+// refreshUnsafe exchanges the refresh token without locking
+func (am *AuthManager) refreshUnsafe(ctx context.Context, version string) (err error) {
+	ctx, span := common.StartSpan(ctx, am.config.Tracer, "rimpay.bpay.refresh_token", common.AttrProvider, "bpay")
+	defer func() { common.EndSpan(span, err) }()
+
+	clientID, err := am.config.Credential(ctx, "bpay", "client_id")
+	if err != nil {
+		return common.CredentialsError(err, "bpay")
+	}
 
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", am.auth.RefreshToken)
-	data.Set("client_id", am.config.Credentials["client_id"])
+	data.Set("client_id", clientID)
 
 	req := &common.HTTPRequest{
 		Method: "POST",
@@ -69,17 +145,23 @@ func (am *AuthManager) RefreshToken(ctx context.Context) error {
 		Headers: map[string]string{
 			"Content-Type": "application/x-www-form-urlencoded",
 		},
-		Body:    []byte(data.Encode()),
-		Timeout: am.config.Timeout,
+		Body:       []byte(data.Encode()),
+		Timeout:    am.config.Timeout,
+		Idempotent: true,
 	}
 
-	resp, err := am.httpClient.Do(req)
+	resp, err := am.httpClient.Do(ctx, req)
 	if err != nil {
-		return fmt.Errorf("refresh token request failed: %w", err)
+		return common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError, "refresh token request failed", "bpay", true,
+		).WithCause(err), req, nil)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("refresh token failed with status: %d", resp.StatusCode)
+		return common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeAuthenticationFailed,
+			fmt.Sprintf("refresh token failed with status: %d", resp.StatusCode), "bpay", false,
+		), req, resp)
 	}
 
 	var authResp AuthResponse
@@ -87,27 +169,26 @@ func (am *AuthManager) RefreshToken(ctx context.Context) error {
 		return fmt.Errorf("failed to decode refresh response: %w", err)
 	}
 
-	am.auth = &authResp
-	am.logger.Debug("B-PAY token refreshed")
+	am.store(&authResp, version)
+	rimpay.ContextLogger(ctx, am.logger).Debug("B-PAY token refreshed")
 
 	return nil
 }
 
-// authenticate performs initial authentication
-func (am *AuthManager) authenticate(ctx context.Context) (string, error) {
-	am.authMutex.Lock()
-	defer am.authMutex.Unlock()
-
-	return am.authenticateUnsafe(ctx)
-}
-
 // authenticateUnsafe performs authentication without locking
-func (am *AuthManager) authenticateUnsafe(ctx context.Context) (string, error) {
+func (am *AuthManager) authenticateUnsafe(ctx context.Context, version string) (_ string, err error) {
+	ctx, span := common.StartSpan(ctx, am.config.Tracer, "rimpay.bpay.authenticate", common.AttrProvider, "bpay")
+	defer func() { common.EndSpan(span, err) }()
+
 	data := url.Values{}
 	data.Set("grant_type", "password")
-	data.Set("username", am.config.Credentials["username"])
-	data.Set("password", am.config.Credentials["password"])
-	data.Set("client_id", am.config.Credentials["client_id"])
+	for _, key := range []string{"username", "password", "client_id"} {
+		value, err := am.config.Credential(ctx, "bpay", key)
+		if err != nil {
+			return "", common.CredentialsError(err, "bpay")
+		}
+		data.Set(key, value)
+	}
 
 	req := &common.HTTPRequest{
 		Method: "POST",
@@ -115,19 +196,25 @@ func (am *AuthManager) authenticateUnsafe(ctx context.Context) (string, error) {
 		Headers: map[string]string{
 			"Content-Type": "application/x-www-form-urlencoded",
 		},
-		Body:    []byte(data.Encode()),
-		Timeout: am.config.Timeout,
+		Body:       []byte(data.Encode()),
+		Timeout:    am.config.Timeout,
+		Idempotent: true,
 	}
 
-	am.logger.Debug("Authenticating with B-PAY", "username", am.config.Credentials["username"])
+	rimpay.ContextLogger(ctx, am.logger).Debug("Authenticating with B-PAY")
 
-	resp, err := am.httpClient.Do(req)
+	resp, err := am.httpClient.Do(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("authentication request failed: %w", err)
+		return "", common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError, "authentication request failed", "bpay", true,
+		).WithCause(err), req, nil)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(resp.Body))
+		return "", common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeAuthenticationFailed,
+			fmt.Sprintf("authentication failed with status %d", resp.StatusCode), "bpay", false,
+		), req, resp), resp)
 	}
 
 	var authResp AuthResponse
@@ -135,20 +222,68 @@ func (am *AuthManager) authenticateUnsafe(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("failed to decode auth response: %w", err)
 	}
 
-	am.auth = &authResp
-	am.logger.Info("B-PAY authentication successful")
+	am.store(&authResp, version)
+	rimpay.ContextLogger(ctx, am.logger).Info("B-PAY authentication successful")
 
 	return authResp.AccessToken, nil
 }
 
-// isTokenExpired checks if current token is expired
+// Invalidate discards token so that the next GetAccessToken authenticates
+// again. It does nothing if the token has already been replaced, so callers
+// rejected with the same token trigger a single re-authentication.
+func (am *AuthManager) Invalidate(token string) {
+	am.authMutex.Lock()
+	defer am.authMutex.Unlock()
+	if am.auth != nil && am.auth.AccessToken == token {
+		am.auth = nil
+	}
+}
+
+// Clear discards the cached token and refresh token
+func (am *AuthManager) Clear() {
+	am.authMutex.Lock()
+	defer am.authMutex.Unlock()
+	am.auth = nil
+	am.expiresAt = time.Time{}
+	am.refreshExpiresAt = time.Time{}
+}
+
+// store records a new token, the version of the credentials it was obtained
+// with and when it and its refresh token expire
+func (am *AuthManager) store(auth *AuthResponse, version string) {
+	issuedAt := am.now()
+	am.auth = auth
+	am.credentialsVersion = version
+	am.expiresAt = expiryTime(issuedAt, auth.ExpiresIn)
+	am.refreshExpiresAt = expiryTime(issuedAt, auth.RefreshExpiresIn)
+}
+
+// expiryTime adds an expires_in value in seconds to issuedAt; it returns the
+// zero time when expiresIn is missing or invalid
+func expiryTime(issuedAt time.Time, expiresIn string) time.Time {
+	seconds, err := strconv.Atoi(strings.TrimSpace(expiresIn))
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return issuedAt.Add(time.Duration(seconds) * time.Second)
+}
+
+// isTokenExpired reports whether the token expires within the safety margin.
+// Tokens without an expires_in are kept until B-PAY rejects them.
 func (am *AuthManager) isTokenExpired() bool {
 	if am.auth == nil {
 		return true
 	}
+	if am.expiresAt.IsZero() {
+		return false
+	}
+	return !am.now().Add(am.expiryMargin).Before(am.expiresAt)
+}
 
-	// In a real implementation, you would track token issue time
-	// and use expires_in to determine expiration
-	// For simplicity, we assume token is valid for short time
-	return false
+// canRefresh reports whether the refresh token can still be exchanged
+func (am *AuthManager) canRefresh() bool {
+	if am.auth == nil || am.auth.RefreshToken == "" {
+		return false
+	}
+	return am.refreshExpiresAt.IsZero() || am.now().Before(am.refreshExpiresAt)
 }
@@ -0,0 +1,284 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// authStub issues numbered tokens and counts requests per grant type
+type authStub struct {
+	mu        sync.Mutex
+	grants    map[string]int
+	issued    int
+	expiresIn string
+	delay     time.Duration
+	lastForm  url.Values
+}
+
+func (s *authStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	time.Sleep(s.delay)
+	form, _ := url.ParseQuery(string(req.Body))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.grants == nil {
+		s.grants = make(map[string]int)
+	}
+	s.grants[form.Get("grant_type")]++
+	s.lastForm = form
+	s.issued++
+	body := fmt.Sprintf(`{"access_token":"token-%d","expires_in":%q,"refresh_token":"refresh-%d","refresh_expires_in":"1800"}`,
+		s.issued, s.expiresIn, s.issued)
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(body)}, nil
+}
+
+func (s *authStub) count(grant string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.grants[grant]
+}
+
+func newTestAuthManager(stub *authStub, now *time.Time) *AuthManager {
+	am := NewAuthManager(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     time.Second,
+	}, stub, passcodeTestLogger{})
+	am.now = func() time.Time { return *now }
+	return am
+}
+
+func mustToken(t *testing.T, am *AuthManager) string {
+	t.Helper()
+	token, err := am.GetAccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccessToken: %v", err)
+	}
+	return token
+}
+
+func TestAccessTokenRenewedBeforeExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	stub := &authStub{expiresIn: "300"}
+	am := newTestAuthManager(stub, &now)
+
+	if token := mustToken(t, am); token != "token-1" {
+		t.Fatalf("token = %s, want token-1", token)
+	}
+
+	now = now.Add(269 * time.Second)
+	if token := mustToken(t, am); token != "token-1" {
+		t.Errorf("token reused until the 30s margin: got %s", token)
+	}
+
+	now = now.Add(2 * time.Second)
+	if token := mustToken(t, am); token != "token-2" {
+		t.Errorf("token = %s, want token-2 within the margin", token)
+	}
+	if stub.count("password") != 1 || stub.count("refresh_token") != 1 {
+		t.Errorf("grants = %v, want one password and one refresh_token", stub.grants)
+	}
+}
+
+func TestExpiredRefreshTokenFallsBackToPassword(t *testing.T) {
+	now := time.Unix(1000, 0)
+	stub := &authStub{expiresIn: "300"}
+	am := newTestAuthManager(stub, &now)
+
+	mustToken(t, am)
+	now = now.Add(time.Hour)
+	mustToken(t, am)
+
+	if stub.count("password") != 2 || stub.count("refresh_token") != 0 {
+		t.Errorf("grants = %v, want two password grants", stub.grants)
+	}
+}
+
+func TestTokenWithoutExpiryIsKept(t *testing.T) {
+	now := time.Unix(1000, 0)
+	stub := &authStub{}
+	am := newTestAuthManager(stub, &now)
+
+	mustToken(t, am)
+	now = now.Add(24 * time.Hour)
+	if token := mustToken(t, am); token != "token-1" {
+		t.Errorf("token = %s, want token-1", token)
+	}
+}
+
+func TestConcurrentCallersShareAuthentication(t *testing.T) {
+	now := time.Unix(1000, 0)
+	stub := &authStub{expiresIn: "300", delay: 10 * time.Millisecond}
+	am := newTestAuthManager(stub, &now)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := am.GetAccessToken(context.Background()); err != nil {
+				t.Errorf("GetAccessToken: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := stub.count("password"); got != 1 {
+		t.Errorf("authentication requests = %d, want 1", got)
+	}
+}
+
+func TestTokenExpiryMarginOption(t *testing.T) {
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     time.Second,
+		Options:     map[string]interface{}{OptionTokenExpiryMargin: "-1s"},
+	}
+	if _, err := NewBPayProvider(config, passcodeTestLogger{}); err == nil {
+		t.Error("expected a negative margin to be rejected")
+	}
+
+	config.Options[OptionTokenExpiryMargin] = "1m"
+	provider, err := NewBPayProvider(config, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewBPayProvider: %v", err)
+	}
+	if provider.authManager.expiryMargin != time.Minute {
+		t.Errorf("expiryMargin = %v, want 1m", provider.authManager.expiryMargin)
+	}
+}
+
+// rotatingCredentials is a CredentialsProvider whose password changes with
+// every rotation
+type rotatingCredentials struct {
+	mu      sync.Mutex
+	version int
+	err     error
+}
+
+func (c *rotatingCredentials) GetCredential(_ context.Context, provider, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return "", c.err
+	}
+	if key == "password" {
+		return fmt.Sprintf("secret-v%d", c.version), nil
+	}
+	return provider + "-" + key, nil
+}
+
+func (c *rotatingCredentials) CredentialsVersion(context.Context, string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strconv.Itoa(c.version), nil
+}
+
+func (c *rotatingCredentials) rotate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+}
+
+func TestRotatedCredentialsDiscardToken(t *testing.T) {
+	stub := &authStub{expiresIn: "3600"}
+	credentials := &rotatingCredentials{version: 1}
+	config := rimpay.ProviderConfig{
+		BaseURL:             "https://bpay.test",
+		Timeout:             time.Second,
+		CredentialsProvider: credentials,
+	}
+	provider, err := NewBPayProvider(config, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewBPayProvider without static credentials: %v", err)
+	}
+	am := NewAuthManager(provider.config, stub, passcodeTestLogger{})
+
+	if token := mustToken(t, am); token != "token-1" {
+		t.Errorf("token = %q, want token-1", token)
+	}
+	if got := stub.lastForm.Get("password"); got != "secret-v1" {
+		t.Errorf("password = %q, want secret-v1", got)
+	}
+	if token := mustToken(t, am); token != "token-1" {
+		t.Errorf("token = %q, want the cached token-1", token)
+	}
+
+	credentials.rotate()
+	if token := mustToken(t, am); token != "token-2" {
+		t.Errorf("token after rotation = %q, want token-2", token)
+	}
+	if got := stub.lastForm.Get("password"); got != "secret-v2" {
+		t.Errorf("password after rotation = %q, want secret-v2", got)
+	}
+	if got := stub.count("refresh_token"); got != 0 {
+		t.Errorf("refresh requests = %d, want 0: the old refresh token must not be reused", got)
+	}
+}
+
+func TestMissingCredentialIsNotRetryable(t *testing.T) {
+	stub := &authStub{}
+	am := NewAuthManager(rimpay.ProviderConfig{
+		BaseURL:             "https://bpay.test",
+		Timeout:             time.Second,
+		CredentialsProvider: &rotatingCredentials{err: rimpay.ErrCredentialNotFound},
+	}, stub, passcodeTestLogger{})
+
+	_, err := am.GetAccessToken(context.Background())
+	if !errors.Is(err, rimpay.ErrCredentialNotFound) {
+		t.Fatalf("error = %v, want ErrCredentialNotFound", err)
+	}
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) || paymentErr.Retryable {
+		t.Errorf("error = %#v, want a non-retryable PaymentError", err)
+	}
+	if stub.issued != 0 {
+		t.Errorf("authentication requests = %d, want 0", stub.issued)
+	}
+}
+
+// failingAuthStub rejects authentication with a body echoing the credentials
+type failingAuthStub struct{}
+
+func (failingAuthStub) Do(context.Context, *common.HTTPRequest) (*common.HTTPResponse, error) {
+	return &common.HTTPResponse{
+		StatusCode: 401,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(`{"error":"invalid_grant","password":"p-secret"}`),
+	}, nil
+}
+
+func TestAuthenticationFailureKeepsBodyOutOfMessage(t *testing.T) {
+	am := NewAuthManager(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p-secret", "client_id": "e-bankily"},
+		Timeout:     time.Second,
+	}, failingAuthStub{}, passcodeTestLogger{})
+
+	_, err := am.GetAccessToken(context.Background())
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) || paymentErr.Code != rimpay.ErrorCodeAuthenticationFailed {
+		t.Fatalf("error = %v, want AUTHENTICATION_FAILED", err)
+	}
+	if paymentErr.Message != "authentication failed with status 401" {
+		t.Errorf("Message = %q", paymentErr.Message)
+	}
+	bundle, _ := paymentErr.SupportBundle()
+	if strings.Contains(string(bundle), "p-secret") {
+		t.Errorf("support bundle leaks the password: %s", bundle)
+	}
+	if !strings.Contains(string(bundle), "invalid_grant") {
+		t.Errorf("support bundle should keep the sanitized response: %s", bundle)
+	}
+}
@@ -0,0 +1,70 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// htmlPaymentStub authenticates and then answers payments with an HTML page,
+// as a misconfigured gateway would
+type htmlPaymentStub struct{}
+
+func (htmlPaymentStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{StatusCode: 200, Body: []byte(`{"access_token":"secret-token"}`)}, nil
+	}
+	return &common.HTTPResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "text/html"},
+		Body:       []byte("<html><body>Service Unavailable</body></html>"),
+	}, nil
+}
+
+func TestUndecodablePaymentResponseCarriesRawResponse(t *testing.T) {
+	provider, err := NewBPayProvider(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  htmlPaymentStub{},
+		Retry:       &rimpay.RetryConfig{MaxAttempts: 1, Multiplier: 1},
+	}, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewBPayProvider: %v", err)
+	}
+
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("NewPhone: %v", err)
+	}
+	_, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50, money.MRU),
+		Reference:   "REF-1",
+		Passcode:    "4321",
+	})
+
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) {
+		t.Fatalf("expected PaymentError, got %v", err)
+	}
+	raw, _ := paymentErr.Details[rimpay.DetailRawResponse].([]byte)
+	if !strings.Contains(string(raw), "Service Unavailable") {
+		t.Errorf("raw_response = %q", raw)
+	}
+
+	bundle, err := paymentErr.SupportBundle()
+	if err != nil {
+		t.Fatalf("SupportBundle: %v", err)
+	}
+	if !strings.Contains(string(bundle), "Service Unavailable") {
+		t.Errorf("support bundle lacks raw response: %s", bundle)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	_ "strings"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
-	"github.com/CatoSystems/rim-pay/internal/types"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -39,21 +39,37 @@ func NewBPayProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Provi
 		return nil, fmt.Errorf("invalid B-PAY configuration: %w", err)
 	}
 
-	// Create HTTP client
-	httpClient := common.NewHTTPClient(common.HTTPConfig{
-		Timeout:         config.Timeout,
-		MaxIdleConns:    10,
-		MaxConnsPerHost: 5,
-	})
+	logger = common.NewRedactingLogger(logger)
+
+	// Unknown keys are most likely typos; they are ignored, but not silently
+	if unknown := config.UnknownOptions(knownOptions...); len(unknown) > 0 {
+		logger.Warn("Ignoring unknown B-PAY options", "options", unknown, "supported", knownOptions)
+	}
+
+	// Use the shared HTTP client when injected by the rimpay client
+	baseClient, err := common.ResolveHTTPClient(config.HTTPClient, config.HTTP, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid B-PAY configuration: %w", err)
+	}
+	httpClient, err := common.RateLimitHTTPClient(
+		common.InstrumentHTTPClient(baseClient, config.Metrics, config.Tracer, "bpay"), config.RateLimit, "bpay")
+	if err != nil {
+		return nil, fmt.Errorf("invalid B-PAY configuration: %w", err)
+	}
 
 	// Create authentication manager
 	authManager := NewAuthManager(config, httpClient, logger)
 
 	// Create payment processor
-	paymentProcessor := NewPaymentProcessor(config, httpClient, authManager, logger)
+	paymentProcessor, err := NewPaymentProcessor(config, httpClient, authManager, logger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid B-PAY configuration: %w", err)
+	}
+	if len(paymentProcessor.statusOverrides) > 0 {
+		logger.Info("B-PAY status overrides configured", "overrides", paymentProcessor.statusOverrides)
+	}
 
-	// Create retry executor with default config
-	retryExecutor := common.NewRetryExecutor(common.DefaultRetryConfig())
+	retryExecutor := common.NewProviderRetryExecutor("bpay", config.Retry, config.Metrics, config.Tracer)
 
 	provider := &Provider{
 		name:             "bpay",
@@ -75,14 +91,23 @@ func (p *Provider) Name() string {
 
 // IsAvailable checks if the provider is available
 func (p *Provider) IsAvailable(ctx context.Context) bool {
+	return p.CheckHealth(ctx) == nil
+}
+
+// CheckHealth reports why the provider is unavailable, keeping TLS
+// certificate failures distinct from other errors
+func (p *Provider) CheckHealth(ctx context.Context) error {
 	_, err := p.authManager.GetAccessToken(ctx)
-	return err == nil
+	if transportErr, ok := common.AsTransportError(err, p.name); ok {
+		return transportErr
+	}
+	return err
 }
 
 // ProcessBPayPayment processes a B-PAY payment using provider-specific request
-func (p *Provider) ProcessBPayPayment(ctx context.Context, request *rimpay.BPayPaymentRequest) (*types.PaymentResponse, error) {
+func (p *Provider) ProcessBPayPayment(ctx context.Context, request *rimpay.BPayPaymentRequest) (*rimpay.PaymentResponse, error) {
 	if request == nil {
-		return nil, types.NewValidationError("request", "payment request cannot be nil")
+		return nil, rimpay.NewValidationError("request", "payment request cannot be nil")
 	}
 
 	if err := request.Validate(); err != nil {
@@ -95,9 +120,9 @@ func (p *Provider) ProcessBPayPayment(ctx context.Context, request *rimpay.BPayP
 	return p.ProcessPayment(ctx, genericRequest)
 }
 
-func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentRequest) (*types.PaymentResponse, error) {
+func (p *Provider) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
 	// Wrap the payment processing in a retryable function
-	retryablePayment := func() (*types.PaymentResponse, error) {
+	retryablePayment := func(ctx context.Context) (*rimpay.PaymentResponse, error) {
 		return p.paymentProcessor.ProcessPayment(ctx, request)
 	}
 
@@ -105,22 +130,80 @@ func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentReq
 	return p.retryExecutor.ExecutePayment(ctx, retryablePayment)
 }
 
+// Refund refunds all or part of a B-PAY payment with retry logic. It
+// returns ErrRefundNotSupported unless OptionRefundPath is set.
+func (p *Provider) Refund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	if p.paymentProcessor.refundPath == "" {
+		return nil, rimpay.ErrRefundNotSupported
+	}
+	return p.retryExecutor.ExecuteRefund(ctx, func(ctx context.Context) (*rimpay.RefundResponse, error) {
+		return p.paymentProcessor.ProcessRefund(ctx, request)
+	})
+}
+
 // GetPaymentStatus gets payment status
 func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
 	return p.paymentProcessor.CheckPaymentStatus(ctx, transactionID)
 }
 
+// HandleNotification maps a B-PAY status callback to a transaction status
+func (p *Provider) HandleNotification(notification *rimpay.BPayNotificationData) (*rimpay.TransactionStatus, error) {
+	return p.paymentProcessor.HandleNotification(notification)
+}
+
+// ProcessDisbursement credits a customer's wallet through a B-PAY transfer.
+// Transfers are not retried: B-PAY may have paid out before a timeout.
+func (p *Provider) ProcessDisbursement(ctx context.Context, request *rimpay.DisbursementRequest) (*rimpay.DisbursementResponse, error) {
+	return p.paymentProcessor.ProcessDisbursement(ctx, request)
+}
+
+// GetDisbursementStatus checks a transfer by its reference
+func (p *Provider) GetDisbursementStatus(ctx context.Context, reference string) (*rimpay.DisbursementResponse, error) {
+	return p.paymentProcessor.CheckDisbursementStatus(ctx, reference)
+}
+
+// DisbursementOperators returns the operators transfers can credit
+func (p *Provider) DisbursementOperators() []phone.Operator {
+	return p.paymentProcessor.disbursementOperators
+}
+
+// Close discards the cached access token and closes idle connections
+func (p *Provider) Close() error {
+	p.authManager.Clear()
+	common.CloseIdleConnections(p.httpClient)
+	return nil
+}
+
 // ValidateConfig validates provider configuration
 func (p *Provider) ValidateConfig() error {
 	return validateConfig(p.config)
 }
 
+// Capabilities describes B-PAY: the customer confirms with a passcode, and
+// payments can be queried, refunded and reported by callback
+func (p *Provider) Capabilities() rimpay.Capabilities {
+	return rimpay.Capabilities{
+		SupportsRefund:      p.paymentProcessor.refundPath != "",
+		SupportsStatusQuery: true,
+		SupportsWebhooks:    true,
+		SupportsPasscode:    true,
+	}
+}
+
+// knownOptions are the ProviderConfig.Options keys B-PAY reads
+var knownOptions = []string{
+	rimpay.OptionStatusOverrides, rimpay.OptionCallbackSecret, rimpay.OptionDefaultLanguage,
+	OptionAllowedOperations, OptionOperationTypes, OptionPasscodeLength, OptionTokenExpiryMargin,
+	OptionDisbursementOperators, OptionRefundPath,
+}
+
 // validateConfig validates B-PAY configuration
 func validateConfig(config rimpay.ProviderConfig) error {
 	requiredCredentials := []string{"username", "password", "client_id"}
 
+	// Credentials from a CredentialsProvider are only read when needed
 	for _, field := range requiredCredentials {
-		if config.Credentials[field] == "" {
+		if !config.HasCredentialsProvider() && config.Credentials[field] == "" {
 			return fmt.Errorf("missing required credential: %s", field)
 		}
 	}
@@ -133,5 +216,41 @@ func validateConfig(config rimpay.ProviderConfig) error {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if _, err := config.StatusMapOption(rimpay.OptionStatusOverrides); err != nil {
+		return err
+	}
+
+	if _, err := config.BPayOptions(); err != nil {
+		return err
+	}
+
+	if operations, err := allowedOperations(config); err != nil {
+		return err
+	} else if _, err := operationTypes(config, operations); err != nil {
+		return err
+	}
+
+	if _, err := passcodeLength(config); err != nil {
+		return err
+	}
+
+	if _, err := refundPath(config); err != nil {
+		return err
+	}
+
+	if _, err := disbursementOperators(config); err != nil {
+		return err
+	}
+
+	if margin, err := config.DurationOption(OptionTokenExpiryMargin, defaultTokenExpiryMargin); err != nil {
+		return err
+	} else if margin < 0 {
+		return fmt.Errorf("option %s cannot be negative", OptionTokenExpiryMargin)
+	}
+
+	if err := common.ValidateRetryConfig(config.Retry); err != nil {
+		return err
+	}
+
 	return nil
 }
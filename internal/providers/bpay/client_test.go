@@ -116,3 +116,22 @@ func TestConvertTransactionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestCapabilities(t *testing.T) {
+	provider, err := NewBPayProvider(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     time.Second,
+	}, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewBPayProvider: %v", err)
+	}
+
+	capabilities := provider.Capabilities()
+	assert.True(t, capabilities.Has(rimpay.CapabilityPasscode))
+	assert.False(t, capabilities.Has(rimpay.CapabilityRefund), "refunds need refund_path")
+	assert.True(t, capabilities.Has(rimpay.CapabilityStatusQuery))
+	assert.True(t, capabilities.Has(rimpay.CapabilityWebhooks))
+	assert.False(t, capabilities.Has(rimpay.CapabilityRedirect))
+	assert.False(t, capabilities.Has(rimpay.CapabilityCancel))
+}
@@ -0,0 +1,92 @@
+package bpay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// correlationServer is a B-PAY API that records the correlation ID of
+// every request
+type correlationServer struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (s *correlationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.ids = append(s.ids, r.Header.Get(rimpay.CorrelationIDHeader))
+	s.mu.Unlock()
+	if strings.Contains(r.URL.Path, "/authentification") {
+		_, _ = w.Write([]byte(`{"access_token":"test-token","expires_in":"3600"}`))
+		return
+	}
+	_, _ = w.Write([]byte(`{"errorCode":"0","errorMessage":"","transactionId":"TX-1"}`))
+}
+
+func correlationClient(t *testing.T, server *httptest.Server, logger rimpay.Logger) *rimpay.Client {
+	t.Helper()
+	config := operationsConfig(nil, nil)
+	config.Enabled = true
+	config.HTTPClient = nil
+	config.BaseURL = server.URL
+	config.HTTP = &rimpay.HTTPConfig{}
+
+	clientConfig := rimpay.DefaultConfig()
+	clientConfig.DefaultProvider = rimpay.ProviderBPay
+	clientConfig.Providers[rimpay.ProviderBPay] = config
+	client, err := rimpay.NewClient(clientConfig)
+	require.NoError(t, err)
+	client.WithLogger(logger)
+	require.NoError(t, client.AddBPayProvider(config))
+	return client
+}
+
+func TestCorrelationIDReachesProviderAndResponse(t *testing.T) {
+	api := &correlationServer{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	logger := &capturingLogger{}
+	client := correlationClient(t, server, logger)
+	logger.lines = nil
+
+	ctx := rimpay.WithCorrelationID(context.Background(), "corr-2316")
+	response, err := client.ProcessBPayPayment(ctx, operationRequest(t, ""))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"corr-2316", "corr-2316"}, api.ids, "authentication and payment carry the ID")
+	id, _ := response.GetMetadataString(rimpay.MetadataKeyCorrelationID)
+	assert.Equal(t, "corr-2316", id)
+
+	require.NotEmpty(t, logger.lines)
+	for _, line := range logger.lines {
+		assert.Contains(t, line, "correlation_id corr-2316", line)
+	}
+}
+
+func TestCorrelationIDIsGeneratedPerPayment(t *testing.T) {
+	api := &correlationServer{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	client := correlationClient(t, server, passcodeTestLogger{})
+
+	first, err := client.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+	second, err := client.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+
+	firstID, _ := first.GetMetadataString(rimpay.MetadataKeyCorrelationID)
+	secondID, _ := second.GetMetadataString(rimpay.MetadataKeyCorrelationID)
+	assert.True(t, common.IsValidUUID(firstID), firstID)
+	assert.NotEqual(t, firstID, secondID)
+	// The second payment reuses the cached token, so it makes one request
+	assert.Equal(t, []string{firstID, firstID, secondID}, api.ids)
+}
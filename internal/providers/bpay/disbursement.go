@@ -0,0 +1,178 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// OptionDisbursementOperators lists the operators whose wallets the
+// merchant contract can credit (list of strings, default every operator)
+const OptionDisbursementOperators = "disbursement_operators"
+
+// disbursementOperators reads OptionDisbursementOperators from config
+func disbursementOperators(config rimpay.ProviderConfig) ([]phone.Operator, error) {
+	var defaults []string
+	for _, op := range phone.AllOperators() {
+		defaults = append(defaults, string(op))
+	}
+	names, err := config.StringSliceOption(OptionDisbursementOperators, defaults)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("option %s cannot be empty", OptionDisbursementOperators)
+	}
+
+	operators := make([]phone.Operator, 0, len(names))
+	for _, name := range names {
+		op := phone.Operator(name)
+		if !isOperator(op) {
+			return nil, fmt.Errorf("option %s: unknown operator %q (supported: %v)",
+				OptionDisbursementOperators, name, phone.AllOperators())
+		}
+		operators = append(operators, op)
+	}
+	return operators, nil
+}
+
+func isOperator(op phone.Operator) bool {
+	for _, known := range phone.AllOperators() {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// disbursementStatus converts a checkTransaction status of a transfer
+func disbursementStatus(status string) rimpay.DisbursementStatus {
+	switch status {
+	case "TS":
+		return rimpay.DisbursementStatusCompleted
+	case "TF", "TE":
+		return rimpay.DisbursementStatusFailed
+	default:
+		return rimpay.DisbursementStatusPending
+	}
+}
+
+// ProcessDisbursement credits a customer's wallet from the merchant account.
+// A refusal for lack of funds concerns the merchant's balance here, so it
+// is reported as INSUFFICIENT_MERCHANT_BALANCE.
+func (pp *PaymentProcessor) ProcessDisbursement(ctx context.Context, request *rimpay.DisbursementRequest) (*rimpay.DisbursementResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	transferReq := &TransferRequest{
+		ClientPhone: request.PhoneNumber.ForProvider(false),
+		OperationID: request.Reference,
+		Amount:      request.Amount.ToProviderAmount(false),
+		Description: request.Description,
+	}
+
+	payload, err := json.Marshal(transferReq)
+	if err != nil {
+		return nil, rimpay.NewPaymentError(
+			rimpay.ErrorCodeInvalidRequest,
+			"failed to marshal transfer request",
+			"bpay",
+			false,
+		)
+	}
+
+	httpReq := &common.HTTPRequest{
+		Method: "POST",
+		URL:    pp.baseURL + "/transfer",
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body:    payload,
+		Timeout: pp.config.Timeout,
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("Making B-PAY transfer request",
+		"operation_id", transferReq.OperationID,
+		"amount", transferReq.Amount,
+	)
+
+	resp, err := pp.doAuthorized(ctx, httpReq, "transfer request")
+	if err != nil {
+		return nil, err
+	}
+
+	var transferResp TransferResponse
+	if err := json.Unmarshal(resp.Body, &transferResp); err != nil {
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to decode transfer response",
+			"bpay",
+			false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	if err := pp.businessError(httpReq, transferResp.ErrorCode, transferResp.ErrorMessage); err != nil {
+		if err.Code == rimpay.ErrorCodeInsufficientFunds {
+			err.Code = rimpay.ErrorCodeInsufficientMerchantBalance
+		}
+		return nil, err.WithDetail("reference", request.Reference)
+	}
+
+	status := rimpay.DisbursementStatusPending
+	if transferResp.ErrorCode == "0" {
+		status = rimpay.DisbursementStatusCompleted
+	}
+	response := &rimpay.DisbursementResponse{
+		DisbursementID: transferResp.TransactionID,
+		Reference:      request.Reference,
+		Status:         status,
+		Amount:         request.Amount,
+		Provider:       "bpay",
+		Message:        transferResp.ErrorMessage,
+		UpdatedAt:      time.Now(),
+		Metadata: map[string]interface{}{
+			rimpay.MetadataKeyErrorCode:    transferResp.ErrorCode,
+			rimpay.MetadataKeyErrorMessage: transferResp.ErrorMessage,
+		},
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("B-PAY transfer response received",
+		"disbursement_id", response.DisbursementID,
+		"status", response.Status,
+	)
+
+	return response, nil
+}
+
+// CheckDisbursementStatus checks a transfer by its operation ID
+func (pp *PaymentProcessor) CheckDisbursementStatus(ctx context.Context, reference string) (*rimpay.DisbursementResponse, error) {
+	status, err := pp.CheckPaymentStatus(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	// Status overrides are for payments; transfers use the raw status
+	raw, ok := status.AsBPayRaw()
+	if !ok {
+		raw = &rimpay.BPayRawResponse{}
+	}
+	return &rimpay.DisbursementResponse{
+		DisbursementID: status.TransactionID,
+		Reference:      reference,
+		Status:         disbursementStatus(raw.Status),
+		Provider:       "bpay",
+		Message:        status.Message,
+		UpdatedAt:      status.LastUpdated,
+		Metadata: map[string]interface{}{
+			rimpay.MetadataKeyErrorCode:    raw.ErrorCode,
+			rimpay.MetadataKeyErrorMessage: raw.ErrorMessage,
+			"status":                       raw.Status,
+		},
+	}, nil
+}
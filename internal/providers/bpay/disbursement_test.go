@@ -0,0 +1,108 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ rimpay.Disburser = (*Provider)(nil)
+
+func disbursementRequest(t *testing.T) *rimpay.DisbursementRequest {
+	t.Helper()
+	phoneNum, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &rimpay.DisbursementRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(75.00, money.MRU),
+		Reference:   "PAYOUT-1",
+		Description: "Cashback",
+	}
+}
+
+func TestProcessDisbursement(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.ProcessDisbursement(context.Background(), disbursementRequest(t))
+	require.NoError(t, err)
+
+	require.NotNil(t, stub.capturedPayment)
+	assert.Equal(t, "https://example.test/transfer", stub.capturedPayment.URL)
+	var sent TransferRequest
+	require.NoError(t, json.Unmarshal(stub.capturedPayment.Body, &sent))
+	assert.Equal(t, TransferRequest{
+		ClientPhone: "20000000",
+		OperationID: "PAYOUT-1",
+		Amount:      "75.00",
+		Description: "Cashback",
+	}, sent)
+
+	assert.Equal(t, "TX-1", resp.DisbursementID)
+	assert.Equal(t, "PAYOUT-1", resp.Reference)
+	assert.Equal(t, rimpay.DisbursementStatusCompleted, resp.Status)
+	assert.Equal(t, "bpay", resp.Provider)
+}
+
+func TestDisbursementInsufficientMerchantBalance(t *testing.T) {
+	stub := &businessErrorStub{errorCode: "1", errorMessage: "Solde insuffisant"}
+	config := operationsConfig(nil, nil)
+	config.HTTPClient = stub
+	provider, err := NewBPayProvider(config, passcodeTestLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.ProcessDisbursement(context.Background(), disbursementRequest(t))
+
+	var paymentErr *rimpay.PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, rimpay.ErrorCodeInsufficientMerchantBalance, paymentErr.Code)
+	assert.Equal(t, "PAYOUT-1", paymentErr.Details["reference"])
+}
+
+func TestDisbursementStatus(t *testing.T) {
+	tests := map[string]rimpay.DisbursementStatus{
+		"TS": rimpay.DisbursementStatusCompleted,
+		"TF": rimpay.DisbursementStatusFailed,
+		"TE": rimpay.DisbursementStatusFailed,
+		"TA": rimpay.DisbursementStatusPending,
+	}
+
+	for raw, want := range tests {
+		t.Run(raw, func(t *testing.T) {
+			// Payment status overrides do not apply to transfers
+			provider, err := NewBPayProvider(overridesConfig(&statusStub{status: raw},
+				map[string]interface{}{raw: "pending"}), passcodeTestLogger{})
+			require.NoError(t, err)
+
+			resp, err := provider.GetDisbursementStatus(context.Background(), "PAYOUT-1")
+			require.NoError(t, err)
+			assert.Equal(t, want, resp.Status)
+			assert.Equal(t, "TX-1", resp.DisbursementID)
+			assert.Equal(t, "PAYOUT-1", resp.Reference)
+		})
+	}
+}
+
+func TestDisbursementOperatorsOption(t *testing.T) {
+	config := operationsConfig(&routingStub{}, nil)
+	provider, err := NewBPayProvider(config, passcodeTestLogger{})
+	require.NoError(t, err)
+	assert.Equal(t, phone.AllOperators(), provider.DisbursementOperators())
+
+	config.Options = map[string]interface{}{OptionDisbursementOperators: []string{"mauritel"}}
+	provider, err = NewBPayProvider(config, passcodeTestLogger{})
+	require.NoError(t, err)
+	assert.Equal(t, []phone.Operator{phone.OperatorMauritel}, provider.DisbursementOperators())
+
+	config.Options = map[string]interface{}{OptionDisbursementOperators: []string{"orange"}}
+	_, err = NewBPayProvider(config, passcodeTestLogger{})
+	assert.Error(t, err)
+}
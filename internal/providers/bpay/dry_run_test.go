@@ -0,0 +1,41 @@
+package bpay
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunSendsNoRequests(t *testing.T) {
+	api := &correlationServer{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	config := operationsConfig(nil, nil)
+	config.Enabled = true
+	config.HTTPClient = nil
+	config.BaseURL = server.URL
+	clientConfig := rimpay.DefaultConfig()
+	clientConfig.DefaultProvider = rimpay.ProviderBPay
+	clientConfig.Providers[rimpay.ProviderBPay] = config
+	clientConfig.DryRun = true
+	client, err := rimpay.NewClient(clientConfig)
+	require.NoError(t, err)
+	require.NoError(t, client.AddBPayProvider(config))
+
+	resp, err := client.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, true, resp.Metadata[rimpay.MetadataSimulated])
+	assert.Equal(t, rimpay.PaymentStatusPending, resp.Status)
+
+	result, err := client.SimulatePayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors)
+	assert.Equal(t, rimpay.ProviderBPay, result.Provider)
+
+	assert.Empty(t, api.ids, "B-PAY was never called")
+}
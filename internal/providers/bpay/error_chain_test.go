@@ -0,0 +1,99 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStub authenticates and then answers payments with status, or
+// fails them in transport when err is set
+type failingStub struct {
+	status int
+	body   string
+	err    error
+}
+
+func (s *failingStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{StatusCode: 200, Body: []byte(`{"access_token":"test-token","expires_in":"3600"}`)}, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &common.HTTPResponse{StatusCode: s.status, Body: []byte(s.body)}, nil
+}
+
+func newErrorChainClient(t *testing.T, stub common.HTTPClient) *rimpay.Client {
+	t.Helper()
+	config := operationsConfig(nil, nil)
+	config.Enabled = true
+	config.HTTPClient = stub
+	config.Retry = &rimpay.RetryConfig{MaxAttempts: 1, Multiplier: 1}
+	clientConfig := rimpay.DefaultConfig()
+	clientConfig.DefaultProvider = rimpay.ProviderBPay
+	clientConfig.Providers[rimpay.ProviderBPay] = config
+	client, err := rimpay.NewClient(clientConfig)
+	require.NoError(t, err)
+	require.NoError(t, client.AddBPayProvider(config))
+	return client
+}
+
+func TestPaymentErrorsSupportIsAndAs(t *testing.T) {
+	errDial := errors.New("dial tcp: connection refused")
+
+	tests := []struct {
+		name      string
+		stub      *failingStub
+		sentinel  error
+		code      rimpay.ErrorCode
+		retryable bool
+		status    int
+		cause     error
+	}{
+		{"server error", &failingStub{status: 503}, rimpay.ErrProviderUnavailable,
+			rimpay.ErrorCodeProviderError, true, 503, nil},
+		{"rejected request", &failingStub{status: 400}, rimpay.ErrInvalidRequest,
+			rimpay.ErrorCodeInvalidRequest, false, 400, nil},
+		{"insufficient funds", &failingStub{status: 200, body: `{"errorCode":"1","errorMessage":"Solde insuffisant"}`},
+			rimpay.ErrPaymentFailed, rimpay.ErrorCodeInsufficientFunds, false, 0, nil},
+		{"network failure", &failingStub{err: errDial}, rimpay.ErrNetworkError,
+			rimpay.ErrorCodeNetworkError, true, 0, errDial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newErrorChainClient(t, tt.stub)
+
+			_, err := client.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+			require.Error(t, err)
+			wrapped := fmt.Errorf("checkout failed: %w", err)
+
+			var paymentErr *rimpay.PaymentError
+			require.True(t, errors.As(wrapped, &paymentErr))
+			assert.Equal(t, tt.code, paymentErr.Code)
+			assert.ErrorIs(t, wrapped, tt.sentinel)
+			assert.Equal(t, tt.code, rimpay.CodeOf(wrapped))
+			assert.Equal(t, tt.retryable, rimpay.IsRetryable(wrapped))
+			assert.Equal(t, tt.code == rimpay.ErrorCodeInvalidRequest, rimpay.IsValidation(wrapped))
+
+			var statusErr *rimpay.HTTPStatusError
+			if tt.status != 0 {
+				require.True(t, errors.As(wrapped, &statusErr))
+				assert.Equal(t, tt.status, statusErr.StatusCode)
+			} else {
+				assert.False(t, errors.As(wrapped, &statusErr))
+			}
+			if tt.cause != nil {
+				assert.ErrorIs(t, wrapped, tt.cause)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package bpay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// businessError describes how a documented B-PAY errorCode is reported
+type businessError struct {
+	code      rimpay.ErrorCode
+	retryable bool
+	meaning   string
+}
+
+// businessErrors maps the errorCode values B-PAY documents for payment and
+// refund responses; "0" is success and undocumented codes are pending
+var businessErrors = map[string]businessError{
+	"1": {rimpay.ErrorCodePaymentDeclined, false, "operation refused"},
+	"2": {rimpay.ErrorCodeAuthenticationFailed, true, "invalid token"},
+	"4": {rimpay.ErrorCodeInvalidRequest, false, "operation ID required"},
+}
+
+// insufficientFundsHints identify an "operation refused" caused by the
+// customer's balance, which B-PAY only reports in errorMessage
+var insufficientFundsHints = []string{"insuffisant", "insufficient", "solde"}
+
+// Metadata and PaymentError.Details keys carrying the raw B-PAY result
+const (
+	detailErrorCode    = "error_code"
+	detailErrorMessage = "error_message"
+)
+
+// businessErrorFor returns the PaymentError for a failed errorCode, or nil
+// when the code is success or undocumented
+func businessErrorFor(errorCode, errorMessage string) *rimpay.PaymentError {
+	mapped, ok := businessErrors[errorCode]
+	if !ok {
+		return nil
+	}
+
+	code := mapped.code
+	if errorCode == "1" && containsAny(strings.ToLower(errorMessage), insufficientFundsHints) {
+		code = rimpay.ErrorCodeInsufficientFunds
+	}
+
+	message := errorMessage
+	if message == "" {
+		message = mapped.meaning
+	}
+	return rimpay.NewPaymentError(
+		code,
+		fmt.Sprintf("B-PAY error %s: %s", errorCode, message),
+		"bpay",
+		mapped.retryable,
+	).WithDetail(detailErrorCode, errorCode).WithDetail(detailErrorMessage, errorMessage)
+}
+
+// businessError maps a failed errorCode for the request sent as req. An
+// invalid token is discarded so that a retry authenticates again.
+func (pp *PaymentProcessor) businessError(req *common.HTTPRequest, errorCode, errorMessage string) *rimpay.PaymentError {
+	err := businessErrorFor(errorCode, errorMessage)
+	if err != nil && err.Code == rimpay.ErrorCodeAuthenticationFailed {
+		pp.authManager.Invalidate(strings.TrimPrefix(req.Headers["Authorization"], "Bearer "))
+	}
+	return err
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+func TestBusinessErrorFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		errorCode string
+		message   string
+		want      rimpay.ErrorCode
+		retryable bool
+	}{
+		{"refused", "1", "Code incorrect", rimpay.ErrorCodePaymentDeclined, false},
+		{"blocked account", "1", "Compte bloqué", rimpay.ErrorCodePaymentDeclined, false},
+		{"insufficient balance", "1", "Solde insuffisant", rimpay.ErrorCodeInsufficientFunds, false},
+		{"insufficient balance in english", "1", "Insufficient balance", rimpay.ErrorCodeInsufficientFunds, false},
+		{"invalid token", "2", "", rimpay.ErrorCodeAuthenticationFailed, true},
+		{"operation id required", "4", "", rimpay.ErrorCodeInvalidRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := businessErrorFor(tt.errorCode, tt.message)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if err.Code != tt.want || err.IsRetryable() != tt.retryable {
+				t.Errorf("got %s (retryable %v), want %s (retryable %v)", err.Code, err.IsRetryable(), tt.want, tt.retryable)
+			}
+			if err.Details["error_code"] != tt.errorCode {
+				t.Errorf("error_code detail = %v, want %s", err.Details["error_code"], tt.errorCode)
+			}
+			if tt.message != "" && !strings.Contains(err.Message, tt.message) {
+				t.Errorf("message %q lacks provider message %q", err.Message, tt.message)
+			}
+		})
+	}
+
+	for _, code := range []string{"0", "", "99"} {
+		if err := businessErrorFor(code, "anything"); err != nil {
+			t.Errorf("businessErrorFor(%q) = %v, want nil", code, err)
+		}
+	}
+}
+
+// businessErrorStub answers payments with a fixed errorCode and message
+type businessErrorStub struct {
+	errorCode, errorMessage string
+	auths                   int
+}
+
+func (s *businessErrorStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		s.auths++
+		return &common.HTTPResponse{StatusCode: 200, Body: []byte(`{"access_token":"test-token"}`)}, nil
+	}
+	body := fmt.Sprintf(`{"errorCode":%q,"errorMessage":%q,"transactionId":"TX-9"}`, s.errorCode, s.errorMessage)
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(body)}, nil
+}
+
+func TestPaymentReturnsBusinessError(t *testing.T) {
+	stub := &businessErrorStub{errorCode: "1", errorMessage: "Solde insuffisant"}
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+	}
+	pp, err := NewPaymentProcessor(config, stub, NewAuthManager(config, stub, passcodeTestLogger{}), passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewPaymentProcessor: %v", err)
+	}
+
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("NewPhone: %v", err)
+	}
+	request := &rimpay.PaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50, money.MRU),
+		Reference:   "REF-1",
+		Passcode:    "4321",
+	}
+	resp, err := pp.ProcessPayment(context.Background(), request)
+	if resp != nil {
+		t.Errorf("expected no response, got %+v", resp)
+	}
+
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) {
+		t.Fatalf("expected PaymentError, got %v", err)
+	}
+	if paymentErr.Code != rimpay.ErrorCodeInsufficientFunds {
+		t.Errorf("code = %s, want INSUFFICIENT_FUNDS", paymentErr.Code)
+	}
+	if paymentErr.Details["transaction_id"] != "TX-9" {
+		t.Errorf("transaction_id detail = %v", paymentErr.Details["transaction_id"])
+	}
+
+	// An invalid token is discarded so the next call authenticates again
+	stub.errorCode = "2"
+	_, _ = pp.ProcessPayment(context.Background(), request)
+	_, _ = pp.ProcessPayment(context.Background(), request)
+	if stub.auths != 2 {
+		t.Errorf("authentications = %d, want 2", stub.auths)
+	}
+}
@@ -24,6 +24,21 @@ type PaymentRequest struct {
 	OperationID string `json:"operationId"`
 	Amount      string `json:"amount"`
 	Language    string `json:"language,omitempty"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+
+	OperationType string `json:"operationType,omitempty"`
+}
+
+// PushPaymentRequest represents B-PAY USSD push payment request; the
+// customer confirms on their handset, so no passcode is sent
+type PushPaymentRequest struct {
+	ClientPhone string `json:"clientPhone"`
+	OperationID string `json:"operationId"`
+	Amount      string `json:"amount"`
+	Language    string `json:"language,omitempty"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+
+	OperationType string `json:"operationType,omitempty"`
 }
 
 // PaymentResponse represents B-PAY payment response
@@ -33,6 +48,38 @@ type PaymentResponse struct {
 	TransactionID string `json:"transactionId"`
 }
 
+// RefundRequest represents B-PAY refund request; a full refund cancels the
+// original operation
+type RefundRequest struct {
+	OperationID   string `json:"operationId"`
+	TransactionID string `json:"transactionId"`
+	Amount        string `json:"amount"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// RefundResponse represents B-PAY refund response
+type RefundResponse struct {
+	ErrorCode     string `json:"errorCode"`
+	ErrorMessage  string `json:"errorMessage"`
+	TransactionID string `json:"transactionId"`
+}
+
+// TransferRequest represents B-PAY transfer request, which credits the
+// customer's wallet from the merchant account
+type TransferRequest struct {
+	ClientPhone string `json:"clientPhone"`
+	OperationID string `json:"operationId"`
+	Amount      string `json:"amount"`
+	Description string `json:"description,omitempty"`
+}
+
+// TransferResponse represents B-PAY transfer response
+type TransferResponse struct {
+	ErrorCode     string `json:"errorCode"`
+	ErrorMessage  string `json:"errorMessage"`
+	TransactionID string `json:"transactionId"`
+}
+
 // CheckTransactionRequest represents status check request
 type CheckTransactionRequest struct {
 	OperationID string `json:"operationID"`
@@ -62,6 +109,10 @@ func convertErrorCodeToStatus(errorCode string) rimpay.PaymentStatus {
 	}
 }
 
+// statusAwaitingConfirmation is the checkTransaction status of a USSD push
+// payment the customer has not confirmed yet
+const statusAwaitingConfirmation = "TC"
+
 // convertTransactionStatus converts B-PAY status to payment status
 func convertTransactionStatus(status string) rimpay.PaymentStatus {
 	switch status {
@@ -71,6 +122,12 @@ func convertTransactionStatus(status string) rimpay.PaymentStatus {
 		return rimpay.PaymentStatusFailed // Transaction failed
 	case "TA":
 		return rimpay.PaymentStatusPending // Transaction pending
+	case statusAwaitingConfirmation:
+		return rimpay.PaymentStatusPending // USSD push awaiting customer confirmation
+	case "TR":
+		return rimpay.PaymentStatusCancelled // USSD push rejected by the customer
+	case "TE":
+		return rimpay.PaymentStatusExpired // USSD push not confirmed in time
 	default:
 		return rimpay.PaymentStatusPending
 	}
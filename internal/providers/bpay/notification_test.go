@@ -0,0 +1,72 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackURLIsSent(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	request := operationRequest(t, "")
+	request.CallbackURL = "https://shop.test/bpay/callback"
+	_, err = provider.ProcessBPayPayment(context.Background(), request)
+	require.NoError(t, err)
+
+	var sent map[string]interface{}
+	require.NoError(t, json.Unmarshal(stub.capturedPayment.Body, &sent))
+	assert.Equal(t, "https://shop.test/bpay/callback", sent["callbackUrl"])
+
+	request.CallbackURL = ""
+	_, err = provider.ProcessBPayPayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stub.capturedPayment.Body), "callbackUrl")
+}
+
+func TestHandleNotification(t *testing.T) {
+	config := operationsConfig(&routingStub{}, nil)
+	config.Options = map[string]interface{}{rimpay.OptionStatusOverrides: map[string]interface{}{"TX": "failed"}}
+	provider, err := NewBPayProvider(config, passcodeTestLogger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		notification rimpay.BPayNotificationData
+		want         rimpay.PaymentStatus
+	}{
+		{"success", rimpay.BPayNotificationData{OperationID: "REF-1", TransactionID: "TX-1", Status: "TS"}, rimpay.PaymentStatusSuccess},
+		{"failure", rimpay.BPayNotificationData{OperationID: "REF-1", Status: "TF", ErrorMessage: "insufficient balance"}, rimpay.PaymentStatusFailed},
+		{"rejected push", rimpay.BPayNotificationData{OperationID: "REF-1", Status: "TR"}, rimpay.PaymentStatusCancelled},
+		{"override", rimpay.BPayNotificationData{OperationID: "REF-1", Status: "TX"}, rimpay.PaymentStatusFailed},
+		{"error code only", rimpay.BPayNotificationData{OperationID: "REF-1", ErrorCode: "0"}, rimpay.PaymentStatusSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := provider.HandleNotification(&tt.notification)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, status.Status)
+			assert.Equal(t, "REF-1", status.Reference)
+			events := status.Events()
+			require.Len(t, events, 1)
+			assert.Equal(t, rimpay.EventSourceWebhook, events[0].Source)
+		})
+	}
+
+	status, err := provider.HandleNotification(&tests[1].notification)
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient balance", status.Message)
+
+	_, err = provider.HandleNotification(&rimpay.BPayNotificationData{Status: "TS"})
+	assert.Error(t, err)
+	_, err = provider.HandleNotification(&rimpay.BPayNotificationData{OperationID: "REF-1"})
+	assert.Error(t, err)
+	_, err = provider.HandleNotification(nil)
+	assert.Error(t, err)
+}
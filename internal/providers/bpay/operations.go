@@ -0,0 +1,80 @@
+package bpay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// OptionAllowedOperations lists the operation types the merchant contract
+// permits (list of strings, default ["payment"])
+const OptionAllowedOperations = "allowed_operations"
+
+// OptionOperationTypes maps operation types to the value of B-PAY's
+// operationType field (map of strings). The B-PAY API specification gives no
+// values for it, so every allowed operation other than payment must be
+// mapped to the value in the merchant contract. Payments leave the field
+// unset unless mapped, which B-PAY treats as a customer payment.
+const OptionOperationTypes = "operation_types"
+
+// allowedOperations reads OptionAllowedOperations from config
+func allowedOperations(config rimpay.ProviderConfig) (map[rimpay.BPayOperationType]bool, error) {
+	names, err := config.StringSliceOption(OptionAllowedOperations, []string{string(rimpay.BPayOperationPayment)})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("option %s cannot be empty", OptionAllowedOperations)
+	}
+
+	allowed := make(map[rimpay.BPayOperationType]bool, len(names))
+	for _, name := range names {
+		op := rimpay.BPayOperationType(name)
+		if !op.IsValid() {
+			return nil, fmt.Errorf("option %s: unknown operation type %q (supported: %v)",
+				OptionAllowedOperations, name, rimpay.BPayOperationTypes())
+		}
+		allowed[op] = true
+	}
+	return allowed, nil
+}
+
+// operationTypes reads OptionOperationTypes from config and checks that
+// every operation in allowed other than payment has a value
+func operationTypes(config rimpay.ProviderConfig, allowed map[rimpay.BPayOperationType]bool) (map[rimpay.BPayOperationType]string, error) {
+	values, err := config.StringMapOption(OptionOperationTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	wire := make(map[rimpay.BPayOperationType]string, len(values))
+	for name, value := range values {
+		op := rimpay.BPayOperationType(name)
+		if !op.IsValid() {
+			return nil, fmt.Errorf("option %s: unknown operation type %q (supported: %v)",
+				OptionOperationTypes, name, rimpay.BPayOperationTypes())
+		}
+		wire[op] = strings.TrimSpace(value)
+	}
+	for _, op := range rimpay.BPayOperationTypes() {
+		if allowed[op] && op != rimpay.BPayOperationPayment && wire[op] == "" {
+			return nil, fmt.Errorf("option %s must give the B-PAY operationType for %q",
+				OptionOperationTypes, op)
+		}
+	}
+	return wire, nil
+}
+
+// checkOperation rejects operation types missing from the allowlist
+func (pp *PaymentProcessor) checkOperation(op rimpay.BPayOperationType) error {
+	if pp.allowedOperations[op] {
+		return nil
+	}
+	return rimpay.NewPaymentError(
+		rimpay.ErrorCodeInvalidRequest,
+		fmt.Sprintf("operation type %q is not allowed by the merchant contract", op),
+		"bpay",
+		false,
+	).WithDetail("field", "operation_type")
+}
@@ -0,0 +1,172 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contractOperationTypes stand in for the operationType values of a merchant
+// contract
+var contractOperationTypes = map[string]string{
+	"merchant_payment": "CONTRACT_MERCHANT",
+	"bill_payment":     "CONTRACT_BILL",
+}
+
+func operationsConfig(stub *routingStub, allowed interface{}) rimpay.ProviderConfig {
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://example.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  stub,
+	}
+	if allowed != nil {
+		config.Options = map[string]interface{}{
+			OptionAllowedOperations: allowed,
+			OptionOperationTypes:    contractOperationTypes,
+		}
+	}
+	return config
+}
+
+func operationRequest(t *testing.T, op rimpay.BPayOperationType) *rimpay.BPayPaymentRequest {
+	t.Helper()
+	phoneNum, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &rimpay.BPayPaymentRequest{
+		PhoneNumber:   phoneNum,
+		Amount:        money.FromFloat64(50.00, money.MRU),
+		Description:   "Order 1",
+		Reference:     "REF-1",
+		Passcode:      "1234",
+		OperationType: op,
+	}
+}
+
+func TestOperationTypeWireField(t *testing.T) {
+	allowed := []string{"payment", "merchant_payment", "bill_payment"}
+
+	tests := []struct {
+		op   rimpay.BPayOperationType
+		wire string
+		meta string
+	}{
+		{"", "", "payment"},
+		{rimpay.BPayOperationPayment, "", "payment"},
+		{rimpay.BPayOperationMerchantPayment, "CONTRACT_MERCHANT", "merchant_payment"},
+		{rimpay.BPayOperationBillPayment, "CONTRACT_BILL", "bill_payment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.op.OrDefault()), func(t *testing.T) {
+			stub := &routingStub{}
+			provider, err := NewBPayProvider(operationsConfig(stub, allowed), passcodeTestLogger{})
+			require.NoError(t, err)
+
+			resp, err := provider.ProcessBPayPayment(context.Background(), operationRequest(t, tt.op))
+			require.NoError(t, err)
+
+			require.NotNil(t, stub.capturedPayment)
+			var sent PaymentRequest
+			require.NoError(t, json.Unmarshal(stub.capturedPayment.Body, &sent))
+			assert.Equal(t, tt.wire, sent.OperationType)
+			if tt.wire == "" {
+				assert.NotContains(t, string(stub.capturedPayment.Body), "operationType")
+			}
+			assert.Equal(t, tt.meta, resp.Metadata["operation_type"])
+		})
+	}
+}
+
+func TestOperationTypeNotAllowed(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.ProcessBPayPayment(context.Background(), operationRequest(t, rimpay.BPayOperationBillPayment))
+
+	var paymentErr *rimpay.PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, rimpay.ErrorCodeInvalidRequest, paymentErr.Code)
+	assert.False(t, paymentErr.Retryable)
+	assert.Equal(t, "operation_type", paymentErr.Details["field"])
+	assert.Nil(t, stub.capturedPayment, "disallowed operations must not be sent")
+}
+
+func TestOperationTypeUnknownRejectedByValidate(t *testing.T) {
+	provider, err := NewBPayProvider(operationsConfig(&routingStub{}, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.ProcessBPayPayment(context.Background(), operationRequest(t, "refund"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown operation type "refund"`)
+}
+
+func TestAllowedOperationsOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed interface{}
+		wantErr string
+	}{
+		{"comma separated", "payment, bill_payment", ""},
+		{"decoded list", []interface{}{"merchant_payment"}, ""},
+		{"unknown type", []string{"payment", "refund"}, `unknown operation type "refund"`},
+		{"empty", []string{}, "cannot be empty"},
+		{"wrong type", 42, "must be a list of strings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBPayProvider(operationsConfig(&routingStub{}, tt.allowed), passcodeTestLogger{})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestOperationTypesOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   interface{}
+		wantErr string
+	}{
+		{"mapped", map[string]interface{}{"bill_payment": "CONTRACT_BILL"}, ""},
+		{"missing", nil, `option operation_types must give the B-PAY operationType for "bill_payment"`},
+		{"blank", map[string]string{"bill_payment": " "}, `for "bill_payment"`},
+		{"unknown type", map[string]string{"bill_payment": "B", "refund": "R"}, `unknown operation type "refund"`},
+		{"wrong type", []string{"BILL"}, "must be a map of strings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := operationsConfig(&routingStub{}, []string{"payment", "bill_payment"})
+			config.Options[OptionOperationTypes] = tt.types
+			if tt.types == nil {
+				delete(config.Options, OptionOperationTypes)
+			}
+
+			_, err := NewBPayProvider(config, passcodeTestLogger{})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			_, err = NewPaymentProcessor(config, &routingStub{}, nil, passcodeTestLogger{})
+			assert.Error(t, err, "NewPaymentProcessor must not fall back to defaults")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+func TestBPayWarnsAboutUnknownOptions(t *testing.T) {
+	logger := &capturingLogger{}
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://example.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		Options:     map[string]interface{}{"pascode_length": 6, OptionTokenExpiryMargin: "1m"},
+	}
+	if _, err := NewBPayProvider(config, logger); err != nil {
+		t.Fatalf("NewBPayProvider failed: %v", err)
+	}
+
+	var warned bool
+	for _, line := range logger.lines {
+		// Only the misspelled key is reported
+		warned = warned || strings.HasPrefix(line, "WARN") && strings.Contains(line, "options [pascode_length]")
+	}
+	if !warned {
+		t.Errorf("no warning about the misspelled option in %q", logger.lines)
+	}
+}
+
+func TestBPayTypedOptions(t *testing.T) {
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("failed to create phone: %v", err)
+	}
+
+	stub := &routingStub{}
+	logger := &capturingLogger{}
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://example.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  stub,
+	}.WithBPayOptions(rimpay.BPayOptions{
+		DefaultLanguage: rimpay.LanguageEnglish,
+		PasscodePolicy:  rimpay.BPayPasscodePolicy{Length: 6},
+		CallbackSecret:  "s3cret",
+	})
+	provider, err := NewBPayProvider(config, logger)
+	if err != nil {
+		t.Fatalf("NewBPayProvider failed: %v", err)
+	}
+	for _, line := range logger.lines {
+		if strings.HasPrefix(line, "WARN") {
+			t.Errorf("typed options were warned about: %q", line)
+		}
+	}
+
+	_, err = provider.ProcessBPayPayment(context.Background(), &rimpay.BPayPaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50.00, money.MRU),
+		Description: "Order 1",
+		Reference:   "REF-1",
+		Passcode:    "654321",
+	})
+	if err != nil {
+		t.Fatalf("ProcessBPayPayment failed: %v", err)
+	}
+	var sent PaymentRequest
+	if err := json.Unmarshal(stub.capturedPayment.Body, &sent); err != nil {
+		t.Fatalf("failed to decode sent body: %v", err)
+	}
+	if sent.Language != "EN" {
+		t.Errorf("language = %q, want the configured default EN", sent.Language)
+	}
+
+	config.Options[rimpay.OptionDefaultLanguage] = "english"
+	if _, err := NewBPayProvider(config, logger); err == nil {
+		t.Error("an invalid default language was accepted")
+	}
+}
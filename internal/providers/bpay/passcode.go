@@ -0,0 +1,40 @@
+package bpay
+
+import (
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// OptionPasscodeLength is the number of digits of the customer passcodes
+// the merchant contract uses (4 or 6, default 4)
+const OptionPasscodeLength = rimpay.OptionPasscodeLength
+
+const defaultPasscodeLength = 4
+
+// passcodeLength reads OptionPasscodeLength from config
+func passcodeLength(config rimpay.ProviderConfig) (int, error) {
+	length, err := config.IntOption(OptionPasscodeLength, defaultPasscodeLength)
+	if err != nil {
+		return 0, err
+	}
+	if length != 4 && length != 6 {
+		return 0, fmt.Errorf("option %s must be 4 or 6, got %d", OptionPasscodeLength, length)
+	}
+	return length, nil
+}
+
+// checkPasscode rejects passcodes that are not digits of the contract's
+// length. The passcode is never part of the error.
+func (pp *PaymentProcessor) checkPasscode(passcode string) error {
+	valid := len(passcode) == pp.passcodeLength
+	for _, c := range passcode {
+		valid = valid && c >= '0' && c <= '9'
+	}
+	if valid {
+		return nil
+	}
+	err := rimpay.NewValidationError("passcode", fmt.Sprintf("must be exactly %d digits", pp.passcodeLength))
+	err.Provider = "bpay"
+	return err
+}
@@ -3,6 +3,7 @@ package bpay
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -26,7 +27,7 @@ type routingStub struct {
 	capturedPayment *common.HTTPRequest
 }
 
-func (s *routingStub) Do(req *common.HTTPRequest) (*common.HTTPResponse, error) {
+func (s *routingStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
 	if strings.Contains(req.URL, "/authentification") {
 		return &common.HTTPResponse{
 			StatusCode: 200,
@@ -53,7 +54,10 @@ func TestBPayForwardsCallerPasscode(t *testing.T) {
 		Timeout:     5 * time.Second,
 	}
 	auth := NewAuthManager(config, stub, passcodeTestLogger{})
-	pp := NewPaymentProcessor(config, stub, auth, passcodeTestLogger{})
+	pp, err := NewPaymentProcessor(config, stub, auth, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewPaymentProcessor: %v", err)
+	}
 
 	const callerPasscode = "4321"
 	req := &rimpay.PaymentRequest{
@@ -85,4 +89,166 @@ func TestBPayForwardsCallerPasscode(t *testing.T) {
 	if _, leaked := resp.Metadata["passcode"]; leaked {
 		t.Error("passcode must not appear in response metadata")
 	}
+
+	if len(resp.Events) != 1 || resp.Events[0].Source != rimpay.EventSourceInitial {
+		t.Errorf("events = %+v, want a single initial event", resp.Events)
+	}
+}
+
+// capturingLogger records every message and field at every level
+type capturingLogger struct {
+	lines []string
+}
+
+func (l *capturingLogger) record(level, msg string, fields []interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", fields))
+}
+
+func (l *capturingLogger) Debug(msg string, fields ...interface{}) { l.record("DEBUG", msg, fields) }
+func (l *capturingLogger) Info(msg string, fields ...interface{})  { l.record("INFO", msg, fields) }
+func (l *capturingLogger) Warn(msg string, fields ...interface{})  { l.record("WARN", msg, fields) }
+func (l *capturingLogger) Error(msg string, fields ...interface{}) { l.record("ERROR", msg, fields) }
+
+func TestBPayLogsNeverContainSecrets(t *testing.T) {
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("failed to create phone: %v", err)
+	}
+
+	stub := &routingStub{}
+	logger := &capturingLogger{}
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://example.test",
+		Credentials: map[string]string{"username": "merchant-user", "password": "hunter2", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  stub,
+	}
+	provider, err := NewBPayProvider(config, logger)
+	if err != nil {
+		t.Fatalf("NewBPayProvider failed: %v", err)
+	}
+
+	const callerPasscode = "4321"
+	resp, err := provider.ProcessBPayPayment(context.Background(), &rimpay.BPayPaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50.00, money.MRU),
+		Description: "Order 1",
+		Reference:   "REF-1",
+		Passcode:    callerPasscode,
+	})
+	if err != nil {
+		t.Fatalf("ProcessBPayPayment failed: %v", err)
+	}
+	if _, err := provider.GetPaymentStatus(context.Background(), resp.TransactionID); err != nil {
+		t.Fatalf("GetPaymentStatus failed: %v", err)
+	}
+
+	// A careless debug line must be masked by the provider's logger too
+	provider.logger.Debug("Payment debug dump", "passcode", callerPasscode, "credentials", config.Credentials,
+		"access_token", "test-token")
+
+	if len(logger.lines) == 0 {
+		t.Fatal("expected the payment flow to log")
+	}
+	for _, line := range logger.lines {
+		for _, secret := range []string{callerPasscode, "merchant-user", "hunter2", "test-token"} {
+			if strings.Contains(line, secret) {
+				t.Errorf("log line %q contains %q", line, secret)
+			}
+		}
+	}
+}
+
+func TestBPayPasscodeLengthPolicy(t *testing.T) {
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("failed to create phone: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		length   interface{}
+		accepted string
+		rejected map[string]string // passcode to error
+	}{
+		{name: "default", length: nil, accepted: "4321", rejected: map[string]string{
+			"654321": "passcode: must be exactly 4 digits",
+			"43a1":   "passcode: must be 4 or 6 digits",
+		}},
+		{name: "six digits", length: 6, accepted: "654321", rejected: map[string]string{
+			"4321":   "passcode: must be exactly 6 digits",
+			"65432a": "passcode: must be 4 or 6 digits",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &routingStub{}
+			logger := &capturingLogger{}
+			config := rimpay.ProviderConfig{
+				BaseURL:     "https://example.test",
+				Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+				Timeout:     5 * time.Second,
+				HTTPClient:  stub,
+			}
+			if tt.length != nil {
+				config.Options = map[string]interface{}{OptionPasscodeLength: tt.length}
+			}
+			provider, err := NewBPayProvider(config, logger)
+			if err != nil {
+				t.Fatalf("NewBPayProvider failed: %v", err)
+			}
+			request := func(passcode string) *rimpay.BPayPaymentRequest {
+				return &rimpay.BPayPaymentRequest{
+					PhoneNumber: phoneNum,
+					Amount:      money.FromFloat64(50.00, money.MRU),
+					Description: "Order 1",
+					Reference:   "REF-1",
+					Passcode:    passcode,
+				}
+			}
+
+			for passcode, wantError := range tt.rejected {
+				_, err := provider.ProcessBPayPayment(context.Background(), request(passcode))
+				if err == nil || !strings.Contains(err.Error(), wantError) {
+					t.Fatalf("passcode %q: error = %v, want %q", passcode, err, wantError)
+				}
+				if strings.Contains(err.Error(), passcode) {
+					t.Errorf("error %q contains the passcode", err)
+				}
+				if stub.capturedPayment != nil {
+					t.Fatalf("passcode %q was sent to B-PAY", passcode)
+				}
+			}
+
+			if _, err := provider.ProcessBPayPayment(context.Background(), request(tt.accepted)); err != nil {
+				t.Fatalf("ProcessBPayPayment failed: %v", err)
+			}
+			var sent PaymentRequest
+			if err := json.Unmarshal(stub.capturedPayment.Body, &sent); err != nil {
+				t.Fatalf("failed to decode sent body: %v", err)
+			}
+			if sent.Passcode != tt.accepted {
+				t.Errorf("sent passcode = %q, want %q", sent.Passcode, tt.accepted)
+			}
+			for _, line := range logger.lines {
+				if strings.Contains(line, tt.accepted) {
+					t.Errorf("log line %q contains the passcode", line)
+				}
+			}
+		})
+	}
+}
+
+func TestBPayPasscodeLengthOptionValidation(t *testing.T) {
+	for _, length := range []interface{}{5, "eight", 6.5} {
+		config := rimpay.ProviderConfig{
+			BaseURL:     "https://example.test",
+			Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+			Timeout:     5 * time.Second,
+			Options:     map[string]interface{}{OptionPasscodeLength: length},
+		}
+		if _, err := NewBPayProvider(config, passcodeTestLogger{}); err == nil {
+			t.Errorf("passcode length %v was accepted", length)
+		}
+	}
 }
@@ -3,9 +3,11 @@ package bpay
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -16,50 +18,156 @@ type PaymentProcessor struct {
 	authManager *AuthManager
 	logger      rimpay.Logger
 	baseURL     string
+
+	// statusOverrides take precedence over convertTransactionStatus
+	statusOverrides map[string]rimpay.PaymentStatus
+
+	// allowedOperations is the merchant contract's operation type allowlist
+	allowedOperations map[rimpay.BPayOperationType]bool
+
+	// operationTypes are the operationType values sent for each operation
+	operationTypes map[rimpay.BPayOperationType]string
+
+	// passcodeLength is the merchant contract's passcode length
+	passcodeLength int
+
+	// refundPath is the refund endpoint; empty when refunds are unsupported
+	refundPath string
+
+	// defaultLanguage is used for payments that set no language
+	defaultLanguage rimpay.Language
+
+	// disbursementOperators are the operators transfers can credit
+	disbursementOperators []phone.Operator
 }
 
-// NewPaymentProcessor creates new payment processor
-func NewPaymentProcessor(config rimpay.ProviderConfig, httpClient common.HTTPClient, authManager *AuthManager, logger rimpay.Logger) *PaymentProcessor {
+// NewPaymentProcessor creates new payment processor. It returns an error
+// when the status overrides, operations, passcode length, refund path or
+// disbursement operators in config are invalid.
+func NewPaymentProcessor(config rimpay.ProviderConfig, httpClient common.HTTPClient, authManager *AuthManager, logger rimpay.Logger) (*PaymentProcessor, error) {
+	overrides, err := config.StatusMapOption(rimpay.OptionStatusOverrides)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := allowedOperations(config)
+	if err != nil {
+		return nil, err
+	}
+	wire, err := operationTypes(config, operations)
+	if err != nil {
+		return nil, err
+	}
+	length, err := passcodeLength(config)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := refundPath(config)
+	if err != nil {
+		return nil, err
+	}
+	typed, err := config.BPayOptions()
+	if err != nil {
+		return nil, err
+	}
+	disbursable, err := disbursementOperators(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &PaymentProcessor{
-		config:      config,
-		httpClient:  httpClient,
-		authManager: authManager,
-		logger:      logger,
-		baseURL:     config.BaseURL,
+		config:            config,
+		httpClient:        httpClient,
+		authManager:       authManager,
+		logger:            logger,
+		baseURL:           config.BaseURL,
+		statusOverrides:   overrides,
+		allowedOperations: operations,
+		operationTypes:    wire,
+		passcodeLength:    length,
+		refundPath:        refunds,
+		defaultLanguage:   typed.DefaultLanguage,
+
+		disbursementOperators: disbursable,
+	}, nil
+}
+
+// language returns the request's language, or the configured default
+func (pp *PaymentProcessor) language(request *rimpay.PaymentRequest) string {
+	if request.Language == "" && pp.defaultLanguage != "" {
+		return convertLanguage(pp.defaultLanguage)
+	}
+	return convertLanguage(request.GetLanguage())
+}
+
+// transactionStatus maps a checkTransaction status, applying any configured
+// overrides before the built-in mapping
+func (pp *PaymentProcessor) transactionStatus(status string) rimpay.PaymentStatus {
+	if override, ok := pp.statusOverrides[status]; ok {
+		return override
 	}
+	return convertTransactionStatus(status)
 }
 
 // ProcessPayment processes a payment request
-func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
-	// Get access token
-	token, err := pp.authManager.GetAccessToken(ctx)
-	if err != nil {
-		return nil, rimpay.NewPaymentError(
-			rimpay.ErrorCodeAuthenticationFailed,
-			"failed to get access token",
-			"bpay",
-			true,
-		)
+func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (_ *rimpay.PaymentResponse, err error) {
+	operation := request.OperationType.OrDefault()
+	mode := request.Mode.OrDefault()
+	ctx, span := common.StartSpan(ctx, pp.config.Tracer, "rimpay.bpay.payment",
+		common.AttrProvider, "bpay", common.AttrReference, request.Reference,
+		common.AttrAmount, request.Amount.String(), common.AttrOperationType, string(operation))
+	defer func() { common.EndSpan(span, err) }()
+
+	if err := pp.checkOperation(operation); err != nil {
+		return nil, err
 	}
 
-	// The passcode is the customer's Bankily verification code, supplied by the
-	// caller. The library must forward it verbatim and never generate one.
-	if request.Passcode == "" {
+	// Create B-PAY specific request
+	path := "/payment"
+	var bpayReq interface{}
+	switch mode {
+	case rimpay.BPayModePasscode:
+		// The passcode is the customer's Bankily verification code, supplied by
+		// the caller. The library must forward it verbatim and never generate one.
+		if request.Passcode == "" {
+			return nil, rimpay.NewPaymentError(
+				rimpay.ErrorCodeInvalidRequest,
+				"passcode is required",
+				"bpay",
+				false,
+			)
+		}
+		if err := pp.checkPasscode(request.Passcode); err != nil {
+			return nil, err
+		}
+		bpayReq = &PaymentRequest{
+			ClientPhone: request.PhoneNumber.ForProvider(false),
+			Passcode:    request.Passcode,
+			OperationID: request.Reference,
+			Amount:      request.Amount.ToProviderAmount(false),
+			Language:    pp.language(request),
+			CallbackURL: request.CallbackURL,
+
+			OperationType: pp.operationTypes[operation],
+		}
+	case rimpay.BPayModeUSSDPush:
+		// The customer confirms on their handset, so there is no passcode
+		path = "/ussdPayment"
+		bpayReq = &PushPaymentRequest{
+			ClientPhone: request.PhoneNumber.ForProvider(false),
+			OperationID: request.Reference,
+			Amount:      request.Amount.ToProviderAmount(false),
+			Language:    pp.language(request),
+			CallbackURL: request.CallbackURL,
+
+			OperationType: pp.operationTypes[operation],
+		}
+	default:
 		return nil, rimpay.NewPaymentError(
 			rimpay.ErrorCodeInvalidRequest,
-			"passcode is required",
+			fmt.Sprintf("unknown B-PAY mode %q", mode),
 			"bpay",
 			false,
-		)
-	}
-
-	// Create B-PAY specific request
-	bpayReq := &PaymentRequest{
-		ClientPhone: request.PhoneNumber.ForProvider(false),
-		Passcode:    request.Passcode,
-		OperationID: request.Reference,
-		Amount:      request.Amount.ToProviderAmount(false),
-		Language:    convertLanguage(request.GetLanguage()),
+		).WithDetail("field", "mode")
 	}
 
 	// Marshal request
@@ -76,44 +184,58 @@ func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.
 	// Create HTTP request
 	httpReq := &common.HTTPRequest{
 		Method: "POST",
-		URL:    pp.baseURL + "/payment",
+		URL:    pp.baseURL + path,
 		Headers: map[string]string{
-			"Content-Type":  "application/json",
-			"Authorization": "Bearer " + token,
+			"Content-Type": "application/json",
 		},
 		Body:    payload,
 		Timeout: pp.config.Timeout,
 	}
 
-	pp.logger.Info("Making B-PAY payment request",
-		"operation_id", bpayReq.OperationID,
-		"amount", bpayReq.Amount,
+	rimpay.ContextLogger(ctx, pp.logger).Info("Making B-PAY payment request",
+		"operation_id", request.Reference,
+		"operation_type", pp.operationTypes[operation],
+		"mode", string(mode),
+		"amount", request.Amount.ToProviderAmount(false),
 	)
 
 	// Execute request
-	resp, err := pp.httpClient.Do(httpReq)
+	resp, err := pp.doAuthorized(ctx, httpReq, "payment request")
 	if err != nil {
-		return nil, rimpay.NewPaymentError(
-			rimpay.ErrorCodeNetworkError,
-			"payment request failed",
-			"bpay",
-			true,
-		)
+		return nil, err
 	}
 
 	// Parse response
 	var bpayResp PaymentResponse
 	if err := json.Unmarshal(resp.Body, &bpayResp); err != nil {
-		return nil, rimpay.NewPaymentError(
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
 			rimpay.ErrorCodeProviderError,
 			"failed to decode payment response",
 			"bpay",
 			false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	if err := pp.businessError(httpReq, bpayResp.ErrorCode, bpayResp.ErrorMessage); err != nil {
+		rimpay.ContextLogger(ctx, pp.logger).Warn("B-PAY payment refused",
+			"operation_id", request.Reference,
+			"error_code", bpayResp.ErrorCode,
+			"code", err.Code,
 		)
+		return nil, err.WithDetail("transaction_id", bpayResp.TransactionID)
 	}
 
-	// Convert to standard response
+	// Convert to standard response. An accepted USSD push only means the
+	// prompt reached the customer, who has yet to confirm it.
 	status := convertErrorCodeToStatus(bpayResp.ErrorCode)
+	message := bpayResp.ErrorMessage
+	awaitingConfirmation := mode == rimpay.BPayModeUSSDPush && status == rimpay.PaymentStatusSuccess
+	if awaitingConfirmation {
+		status = rimpay.PaymentStatusPending
+		if message == "" {
+			message = "Awaiting customer confirmation"
+		}
+	}
 
 	response := &rimpay.PaymentResponse{
 		TransactionID: bpayResp.TransactionID,
@@ -124,14 +246,27 @@ func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 		Metadata: map[string]interface{}{
-			"error_code":         bpayResp.ErrorCode,
-			"error_message":      bpayResp.ErrorMessage,
-			"transaction_id":     bpayResp.TransactionID,
-			"provider_reference": bpayResp.TransactionID,
+			rimpay.MetadataKeyErrorCode:         bpayResp.ErrorCode,
+			rimpay.MetadataKeyErrorMessage:      bpayResp.ErrorMessage,
+			"transaction_id":                    bpayResp.TransactionID,
+			rimpay.MetadataKeyProviderReference: bpayResp.TransactionID,
+			"operation_type":                    string(operation),
+			"mode":                              string(mode),
+		},
+		Events: []rimpay.StatusEvent{
+			rimpay.NewStatusEvent(status, message, rimpay.EventSourceInitial),
 		},
+		Raw: &rimpay.BPayRawResponse{
+			ErrorCode:     bpayResp.ErrorCode,
+			ErrorMessage:  bpayResp.ErrorMessage,
+			TransactionID: bpayResp.TransactionID,
+		},
+	}
+	if awaitingConfirmation {
+		response.Metadata[rimpay.MetadataAwaitingConfirmation] = true
 	}
 
-	pp.logger.Info("B-PAY payment response received",
+	rimpay.ContextLogger(ctx, pp.logger).Info("B-PAY payment response received",
 		"transaction_id", response.TransactionID,
 		"status", response.Status,
 	)
@@ -139,19 +274,90 @@ func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.
 	return response, nil
 }
 
-// CheckPaymentStatus checks payment status
-func (pp *PaymentProcessor) CheckPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
-	// Get access token
-	token, err := pp.authManager.GetAccessToken(ctx)
+// ProcessRefund refunds all or part of a payment
+func (pp *PaymentProcessor) ProcessRefund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	refundReq := &RefundRequest{
+		OperationID:   request.Reference,
+		TransactionID: request.TransactionID,
+		Amount:        request.Amount.ToProviderAmount(false),
+		Reason:        request.Reason,
+	}
+
+	payload, err := json.Marshal(refundReq)
 	if err != nil {
 		return nil, rimpay.NewPaymentError(
-			rimpay.ErrorCodeAuthenticationFailed,
-			"failed to get access token",
+			rimpay.ErrorCodeInvalidRequest,
+			"failed to marshal refund request",
 			"bpay",
-			true,
+			false,
 		)
 	}
 
+	httpReq := &common.HTTPRequest{
+		Method: "POST",
+		URL:    pp.baseURL + pp.refundPath,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body:    payload,
+		Timeout: pp.config.Timeout,
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("Making B-PAY refund request",
+		"operation_id", refundReq.OperationID,
+		"transaction_id", refundReq.TransactionID,
+		"amount", refundReq.Amount,
+	)
+
+	resp, err := pp.doAuthorized(ctx, httpReq, "refund request")
+	if err != nil {
+		return nil, err
+	}
+
+	var refundResp RefundResponse
+	if err := json.Unmarshal(resp.Body, &refundResp); err != nil {
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to decode refund response",
+			"bpay",
+			false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	if err := pp.businessError(httpReq, refundResp.ErrorCode, refundResp.ErrorMessage); err != nil {
+		return nil, err.WithDetail("transaction_id", request.TransactionID)
+	}
+
+	response := &rimpay.RefundResponse{
+		RefundID:      refundResp.TransactionID,
+		TransactionID: request.TransactionID,
+		Status:        convertErrorCodeToStatus(refundResp.ErrorCode),
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      "bpay",
+		Partial:       request.IsPartial(),
+		CreatedAt:     time.Now(),
+		Metadata: map[string]interface{}{
+			rimpay.MetadataKeyErrorCode:    refundResp.ErrorCode,
+			rimpay.MetadataKeyErrorMessage: refundResp.ErrorMessage,
+			"reason":                       request.Reason,
+		},
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("B-PAY refund response received",
+		"refund_id", response.RefundID,
+		"status", response.Status,
+	)
+
+	return response, nil
+}
+
+// CheckPaymentStatus checks payment status
+func (pp *PaymentProcessor) CheckPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
 	// Create check request
 	checkReq := &CheckTransactionRequest{
 		OperationID: transactionID,
@@ -172,54 +378,116 @@ func (pp *PaymentProcessor) CheckPaymentStatus(ctx context.Context, transactionI
 		Method: "POST",
 		URL:    pp.baseURL + "/checkTransaction",
 		Headers: map[string]string{
-			"Content-Type":  "application/json",
-			"Authorization": "Bearer " + token,
+			"Content-Type": "application/json",
 		},
-		Body:    payload,
-		Timeout: pp.config.Timeout,
+		Body:       payload,
+		Timeout:    pp.config.Timeout,
+		Idempotent: true,
 	}
 
 	// Execute request
-	resp, err := pp.httpClient.Do(httpReq)
+	resp, err := pp.doAuthorized(ctx, httpReq, "status check")
 	if err != nil {
-		return nil, rimpay.NewPaymentError(
-			rimpay.ErrorCodeNetworkError,
-			"status check failed",
-			"bpay",
-			true,
-		)
+		return nil, err
 	}
 
 	// Parse response
 	var checkResp CheckTransactionResponse
 	if err := json.Unmarshal(resp.Body, &checkResp); err != nil {
-		return nil, rimpay.NewPaymentError(
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
 			rimpay.ErrorCodeProviderError,
 			"failed to decode status response",
 			"bpay",
 			false,
-		)
+		).WithCause(err), httpReq, resp), resp)
 	}
 
 	// Convert to standard response
 	status := &rimpay.TransactionStatus{
 		TransactionID:     checkResp.TransactionID,
-		Status:            convertTransactionStatus(checkResp.Status),
+		Status:            pp.transactionStatus(checkResp.Status),
 		Reference:         transactionID,
 		ProviderReference: checkResp.TransactionID,
 		Message:           checkResp.ErrorMessage,
 		LastUpdated:       time.Now(),
 		ProviderData: map[string]interface{}{
-			"error_code":     checkResp.ErrorCode,
-			"error_message":  checkResp.ErrorMessage,
-			"status":         checkResp.Status,
-			"transaction_id": checkResp.TransactionID,
+			rimpay.MetadataKeyErrorCode:    checkResp.ErrorCode,
+			rimpay.MetadataKeyErrorMessage: checkResp.ErrorMessage,
+			"status":                       checkResp.Status,
+			"transaction_id":               checkResp.TransactionID,
+		},
+		Raw: &rimpay.BPayRawResponse{
+			ErrorCode:     checkResp.ErrorCode,
+			ErrorMessage:  checkResp.ErrorMessage,
+			TransactionID: checkResp.TransactionID,
+			Status:        checkResp.Status,
 		},
 	}
+	if checkResp.Status == statusAwaitingConfirmation {
+		status.ProviderData[rimpay.MetadataAwaitingConfirmation] = true
+	}
+	status.AddEvent(status.Status, status.Message, rimpay.EventSourcePoll)
 
 	return status, nil
 }
 
+// HandleNotification maps a B-PAY status callback to a transaction status.
+// The raw status goes through the same mapping as checkTransaction; a
+// callback with only an error code uses the payment error codes.
+func (pp *PaymentProcessor) HandleNotification(notification *rimpay.BPayNotificationData) (*rimpay.TransactionStatus, error) {
+	if notification == nil {
+		return nil, rimpay.NewValidationError("notification", "is required")
+	}
+	if notification.OperationID == "" {
+		return nil, rimpay.NewValidationError("operationId", "is required")
+	}
+
+	var status rimpay.PaymentStatus
+	switch {
+	case notification.Status != "":
+		status = pp.transactionStatus(notification.Status)
+	case notification.ErrorCode != "":
+		status = convertErrorCodeToStatus(notification.ErrorCode)
+	default:
+		return nil, rimpay.NewValidationError("status", "is required")
+	}
+
+	message := notification.ErrorMessage
+	if message == "" {
+		message = "B-PAY callback received"
+	}
+
+	pp.logger.Info("B-PAY callback processed",
+		"reference", notification.OperationID,
+		"status", status,
+		"transaction_id", notification.TransactionID,
+	)
+
+	transactionStatus := &rimpay.TransactionStatus{
+		TransactionID:     notification.TransactionID,
+		Status:            status,
+		Reference:         notification.OperationID,
+		ProviderReference: notification.TransactionID,
+		Message:           message,
+		LastUpdated:       time.Now(),
+		ProviderData: map[string]interface{}{
+			rimpay.MetadataKeyErrorCode:    notification.ErrorCode,
+			rimpay.MetadataKeyErrorMessage: notification.ErrorMessage,
+			"status":                       notification.Status,
+			"transaction_id":               notification.TransactionID,
+			"timestamp":                    notification.Timestamp,
+		},
+		Raw: &rimpay.BPayRawResponse{
+			ErrorCode:     notification.ErrorCode,
+			ErrorMessage:  notification.ErrorMessage,
+			TransactionID: notification.TransactionID,
+			Status:        notification.Status,
+		},
+	}
+	transactionStatus.AddEvent(status, message, rimpay.EventSourceWebhook)
+	return transactionStatus, nil
+}
+
 // convertLanguage converts rimpay.Language to B-PAY format
 func convertLanguage(lang rimpay.Language) string {
 	switch lang {
@@ -0,0 +1,96 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timingStub authenticates and accepts every payment, recording when each
+// payment arrived
+type timingStub struct {
+	mu       sync.Mutex
+	payments []time.Time
+}
+
+func (s *timingStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(`{"access_token":"test-token","expires_in":"3600"}`),
+		}, nil
+	}
+	s.mu.Lock()
+	s.payments = append(s.payments, time.Now())
+	s.mu.Unlock()
+	return &common.HTTPResponse{
+		StatusCode: 200,
+		Body:       []byte(`{"errorCode":"0","errorMessage":"","transactionId":"TX-1"}`),
+	}, nil
+}
+
+func TestRateLimitPacesConcurrentPayments(t *testing.T) {
+	stub := &timingStub{}
+	config := operationsConfig(nil, nil)
+	config.Enabled = true
+	config.HTTPClient = stub
+	config.RateLimit = &rimpay.RateLimitConfig{Rate: 5}
+
+	clientConfig := rimpay.DefaultConfig()
+	clientConfig.DefaultProvider = rimpay.ProviderBPay
+	clientConfig.Providers[rimpay.ProviderBPay] = config
+	client, err := rimpay.NewClient(clientConfig)
+	require.NoError(t, err)
+	client.WithLogger(passcodeTestLogger{})
+	require.NoError(t, client.AddBPayProvider(config))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := operationRequest(t, "")
+			request.Reference = fmt.Sprintf("REF-%d", i)
+			_, err := client.ProcessBPayPayment(context.Background(), request)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Authentication takes the first token, so the payments need 20 more
+	require.Len(t, stub.payments, 20)
+	sort.Slice(stub.payments, func(i, j int) bool { return stub.payments[i].Before(stub.payments[j]) })
+	for i := 1; i < len(stub.payments); i++ {
+		gap := stub.payments[i].Sub(stub.payments[i-1])
+		assert.GreaterOrEqual(t, gap, 180*time.Millisecond, "payments %d and %d", i-1, i)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 3800*time.Millisecond)
+
+	// With no token before the deadline the call fails at once
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	callStart := time.Now()
+	_, err = client.ProcessBPayPayment(ctx, operationRequest(t, ""))
+	assert.Less(t, time.Since(callStart), 40*time.Millisecond)
+	var paymentErr *rimpay.PaymentError
+	require.True(t, errors.As(err, &paymentErr), "error %v", err)
+	assert.Equal(t, rimpay.ErrorCodeRateLimited, paymentErr.Code)
+	assert.Equal(t, "bpay", paymentErr.Provider)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
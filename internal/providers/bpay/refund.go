@@ -0,0 +1,25 @@
+package bpay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// OptionRefundPath is the refund endpoint path (string, optional). The B-PAY
+// API specification has no refund endpoint, so refunds return
+// ErrRefundNotSupported unless the merchant contract gives one.
+const OptionRefundPath = "refund_path"
+
+// refundPath reads OptionRefundPath from config
+func refundPath(config rimpay.ProviderConfig) (string, error) {
+	path, err := config.StringOption(OptionRefundPath, "")
+	if err != nil {
+		return "", err
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("option %s must start with / or be empty", OptionRefundPath)
+	}
+	return path, nil
+}
@@ -0,0 +1,102 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refundConfig sets the refund endpoint of a merchant contract
+func refundConfig(stub *routingStub) rimpay.ProviderConfig {
+	config := operationsConfig(stub, nil)
+	config.Options = map[string]interface{}{OptionRefundPath: "/refund"}
+	return config
+}
+
+func TestRefund(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    money.Money
+		wantPartial bool
+	}{
+		{"full refund", money.FromFloat64(50.00, money.MRU), false},
+		{"partial refund", money.FromFloat64(80.00, money.MRU), true},
+		{"original amount unknown", money.Money{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &routingStub{}
+			provider, err := NewBPayProvider(refundConfig(stub), passcodeTestLogger{})
+			require.NoError(t, err)
+
+			resp, err := provider.Refund(context.Background(), &rimpay.RefundRequest{
+				TransactionID:  "TX-9",
+				Amount:         money.FromFloat64(50.00, money.MRU),
+				OriginalAmount: tt.original,
+				Reason:         "customer request",
+				Reference:      "RF-REF-1",
+			})
+			require.NoError(t, err)
+
+			require.NotNil(t, stub.capturedPayment)
+			assert.Equal(t, "https://example.test/refund", stub.capturedPayment.URL)
+			var sent RefundRequest
+			require.NoError(t, json.Unmarshal(stub.capturedPayment.Body, &sent))
+			assert.Equal(t, RefundRequest{
+				OperationID:   "RF-REF-1",
+				TransactionID: "TX-9",
+				Amount:        "50.00",
+				Reason:        "customer request",
+			}, sent)
+
+			assert.Equal(t, "TX-1", resp.RefundID)
+			assert.Equal(t, "TX-9", resp.TransactionID)
+			assert.Equal(t, rimpay.PaymentStatusSuccess, resp.Status)
+			assert.Equal(t, tt.wantPartial, resp.Partial)
+		})
+	}
+}
+
+func TestRefundExceedsOriginalAmount(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(refundConfig(stub), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.Refund(context.Background(), &rimpay.RefundRequest{
+		TransactionID:  "TX-9",
+		Amount:         money.FromFloat64(60.00, money.MRU),
+		OriginalAmount: money.FromFloat64(50.00, money.MRU),
+		Reference:      "RF-REF-1",
+	})
+
+	var paymentErr *rimpay.PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, rimpay.ErrorCodeInvalidRequest, paymentErr.Code)
+	assert.Equal(t, "amount", paymentErr.Details["field"])
+	assert.Nil(t, stub.capturedPayment, "invalid refunds must not be sent")
+}
+
+func TestRefundNeedsRefundPath(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.Refund(context.Background(), &rimpay.RefundRequest{
+		TransactionID: "TX-9",
+		Amount:        money.FromFloat64(50.00, money.MRU),
+		Reference:     "RF-REF-1",
+	})
+	assert.ErrorIs(t, err, rimpay.ErrRefundNotSupported)
+	assert.Nil(t, stub.capturedPayment, "no refund endpoint is assumed")
+
+	provider, err = NewBPayProvider(refundConfig(stub), passcodeTestLogger{})
+	require.NoError(t, err)
+	assert.True(t, provider.Capabilities().Has(rimpay.CapabilityRefund))
+}
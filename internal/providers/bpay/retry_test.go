@@ -0,0 +1,65 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStub authenticates and then fails every payment with a network error
+type flakyStub struct {
+	payments int
+}
+
+func (s *flakyStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(`{"access_token":"test-token","expires_in":"3600"}`),
+		}, nil
+	}
+	s.payments++
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestRetryConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry *rimpay.RetryConfig
+		want  int
+	}{
+		{"retries disabled", &rimpay.RetryConfig{MaxAttempts: 1, Multiplier: 1}, 1},
+		{"custom attempts", &rimpay.RetryConfig{MaxAttempts: 4, InitialDelay: time.Millisecond, Multiplier: 1}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &flakyStub{}
+			config := operationsConfig(nil, nil)
+			config.HTTPClient = stub
+			config.Retry = tt.retry
+			provider, err := NewBPayProvider(config, passcodeTestLogger{})
+			require.NoError(t, err)
+
+			_, err = provider.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+			var paymentErr *rimpay.PaymentError
+			require.True(t, errors.As(err, &paymentErr))
+			assert.Equal(t, rimpay.ErrorCodeNetworkError, paymentErr.Code)
+			assert.Equal(t, tt.want, stub.payments)
+		})
+	}
+}
+
+func TestRetryConfigValidation(t *testing.T) {
+	config := operationsConfig(&routingStub{}, nil)
+	config.Retry = &rimpay.RetryConfig{MaxAttempts: 0, Multiplier: 2}
+	_, err := NewBPayProvider(config, passcodeTestLogger{})
+	assert.ErrorContains(t, err, "retry max_attempts must be at least 1")
+}
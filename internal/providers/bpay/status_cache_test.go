@@ -0,0 +1,52 @@
+package bpay
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStatusStub is a statusStub counting checkTransaction calls
+type countingStatusStub struct {
+	statusStub
+	checks int32
+}
+
+func (s *countingStatusStub) Do(ctx context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/checkTransaction") {
+		atomic.AddInt32(&s.checks, 1)
+	}
+	return s.statusStub.Do(ctx, req)
+}
+
+func TestStatusCacheSkipsCheckTransaction(t *testing.T) {
+	for _, raw := range []string{"TS", "TF"} {
+		t.Run(raw, func(t *testing.T) {
+			stub := &countingStatusStub{statusStub: statusStub{status: raw}}
+			config := overridesConfig(stub, nil)
+			config.Enabled = true
+			clientConfig := rimpay.DefaultConfig()
+			clientConfig.Providers[rimpay.ProviderBPay] = config
+			clientConfig.StatusCache = rimpay.StatusCacheConfig{MaxEntries: 100}
+			client, err := rimpay.NewClient(clientConfig)
+			require.NoError(t, err)
+			require.NoError(t, client.AddBPayProvider(config))
+
+			for i := 0; i < 3; i++ {
+				_, err := client.GetPaymentStatus(context.Background(), "TX-1")
+				require.NoError(t, err)
+			}
+			assert.Equal(t, int32(1), atomic.LoadInt32(&stub.checks), "final statuses are checked once")
+
+			_, err = client.GetPaymentStatus(rimpay.WithForceRefresh(context.Background()), "TX-1")
+			require.NoError(t, err)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&stub.checks))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bpay
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusStub answers checkTransaction with a fixed raw status
+type statusStub struct {
+	status string
+}
+
+func (s *statusStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(`{"access_token":"test-token","expires_in":"3600"}`),
+		}, nil
+	}
+	return &common.HTTPResponse{
+		StatusCode: 200,
+		Body:       []byte(`{"errorCode":"0","transactionId":"TX-1","status":"` + s.status + `"}`),
+	}, nil
+}
+
+func overridesConfig(stub common.HTTPClient, overrides interface{}) rimpay.ProviderConfig {
+	return rimpay.ProviderConfig{
+		BaseURL:     "https://example.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		Options:     map[string]interface{}{rimpay.OptionStatusOverrides: overrides},
+		HTTPClient:  stub,
+	}
+}
+
+func TestStatusOverridesWin(t *testing.T) {
+	overrides := map[string]interface{}{
+		"TP": "pending",
+		"TA": "failed", // contract addendum: treat TA as failed
+	}
+
+	tests := []struct {
+		raw      string
+		expected rimpay.PaymentStatus
+	}{
+		{"TP", rimpay.PaymentStatusPending},
+		{"TA", rimpay.PaymentStatusFailed},
+		{"TS", rimpay.PaymentStatusSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			provider, err := NewBPayProvider(overridesConfig(&statusStub{status: tt.raw}, overrides), passcodeTestLogger{})
+			require.NoError(t, err)
+
+			status, err := provider.GetPaymentStatus(context.Background(), "TX-1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, status.Status)
+		})
+	}
+}
+
+func TestStatusOverridesAcceptTypedMaps(t *testing.T) {
+	overrides := map[string]rimpay.PaymentStatus{"TX": rimpay.PaymentStatusExpired}
+	provider, err := NewBPayProvider(overridesConfig(&statusStub{status: "TX"}, overrides), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	status, err := provider.GetPaymentStatus(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusExpired, status.Status)
+}
+
+func TestStatusOverridesRejectUnknownTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides interface{}
+	}{
+		{"unknown status", map[string]string{"TP": "processing"}},
+		{"non-string target", map[string]interface{}{"TP": 1}},
+		{"not a map", "TP=pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBPayProvider(overridesConfig(&statusStub{}, tt.overrides), passcodeTestLogger{})
+			assert.Error(t, err)
+		})
+	}
+}
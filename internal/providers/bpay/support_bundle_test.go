@@ -0,0 +1,117 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// flakyPaymentStub authenticates successfully and then fails every payment call
+type flakyPaymentStub struct{}
+
+func (flakyPaymentStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(`{"access_token":"secret-token","expires_in":"3600"}`),
+		}, nil
+	}
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestSupportBundleForMultiAttemptFailure(t *testing.T) {
+	provider, err := NewBPayProvider(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "hunter2", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  flakyPaymentStub{},
+	}, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewBPayProvider: %v", err)
+	}
+	provider.retryExecutor = common.NewRetryExecutor(common.RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Millisecond,
+		Multiplier:   1,
+	})
+
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("NewPhone: %v", err)
+	}
+	_, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50, money.MRU),
+		Reference:   "REF-1",
+		Passcode:    "4321",
+	})
+
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) {
+		t.Fatalf("expected PaymentError, got %v", err)
+	}
+	bundle, err := paymentErr.SupportBundle()
+	if err != nil {
+		t.Fatalf("SupportBundle: %v", err)
+	}
+
+	for _, secret := range []string{"secret-token", "hunter2", "4321"} {
+		if strings.Contains(string(bundle), secret) {
+			t.Errorf("bundle leaks %q: %s", secret, bundle)
+		}
+	}
+
+	var report struct {
+		Code      string `json:"code"`
+		Provider  string `json:"provider"`
+		Retryable bool   `json:"retryable"`
+		Endpoint  string `json:"endpoint"`
+		Attempts  []struct {
+			Attempt    int    `json:"attempt"`
+			ErrorClass string `json:"error_class"`
+			Error      string `json:"error"`
+			DurationMS *int64 `json:"duration_ms"`
+		} `json:"attempts"`
+		Config struct {
+			RequestTimeout string                 `json:"request_timeout"`
+			RetryPolicy    map[string]interface{} `json:"retry_policy"`
+		} `json:"config"`
+		Cause string `json:"cause"`
+	}
+	if err := json.Unmarshal(bundle, &report); err != nil {
+		t.Fatalf("bundle is not valid JSON: %v", err)
+	}
+
+	if report.Code != "NETWORK_ERROR" || report.Provider != "bpay" || !report.Retryable {
+		t.Errorf("unexpected header fields: %+v", report)
+	}
+	if report.Endpoint != "POST https://bpay.test/payment" {
+		t.Errorf("endpoint = %q", report.Endpoint)
+	}
+	if len(report.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(report.Attempts))
+	}
+	for i, attempt := range report.Attempts {
+		if attempt.Attempt != i+1 || attempt.ErrorClass != "NETWORK_ERROR" || attempt.DurationMS == nil {
+			t.Errorf("attempt %d malformed: %+v", i, attempt)
+		}
+	}
+	if report.Config.RequestTimeout != "5s" {
+		t.Errorf("request_timeout = %q", report.Config.RequestTimeout)
+	}
+	if report.Config.RetryPolicy["max_attempts"] != float64(3) {
+		t.Errorf("retry_policy = %v", report.Config.RetryPolicy)
+	}
+	if !strings.Contains(report.Cause, "connection reset") {
+		t.Errorf("cause = %q", report.Cause)
+	}
+}
@@ -0,0 +1,159 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedSpan is a finished span kept by recordingTracer
+type recordedSpan struct {
+	tracer     *recordingTracer
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+// path names the span and its ancestors, outermost first
+func (s *recordedSpan) path() string {
+	if s.parent == nil {
+		return s.name
+	}
+	return s.parent.path() + " > " + s.name
+}
+
+type spanKey struct{}
+
+// recordingTracer keeps every span in memory, parented through the context
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, rimpay.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{tracer: t, name: name, parent: parent, attributes: make(map[string]interface{})}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// failOnceStub authenticates, fails the first payment with a network error
+// and accepts the second
+type failOnceStub struct {
+	payments int
+}
+
+func (s *failOnceStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(`{"access_token":"test-token","expires_in":"3600"}`),
+		}, nil
+	}
+	s.payments++
+	if s.payments == 1 {
+		return nil, errors.New("connection reset by peer")
+	}
+	return &common.HTTPResponse{
+		StatusCode: 200,
+		Body:       []byte(`{"errorCode":"0","errorMessage":"","transactionId":"TX-1"}`),
+	}, nil
+}
+
+func TestPaymentSpans(t *testing.T) {
+	config := operationsConfig(nil, nil)
+	config.Enabled = true
+	config.HTTPClient = &failOnceStub{}
+	config.Retry = &rimpay.RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond, Multiplier: 1}
+
+	clientConfig := rimpay.DefaultConfig()
+	clientConfig.DefaultProvider = rimpay.ProviderBPay
+	clientConfig.Providers[rimpay.ProviderBPay] = config
+	client, err := rimpay.NewClient(clientConfig)
+	require.NoError(t, err)
+
+	tracer := &recordingTracer{}
+	client.WithLogger(passcodeTestLogger{}).WithTracer(tracer)
+	require.NoError(t, client.AddBPayProvider(config))
+
+	_, err = client.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+
+	var paths []string
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, "span %s not ended", span.path())
+		paths = append(paths, span.path())
+	}
+	assert.Equal(t, []string{
+		"rimpay.payment",
+		"rimpay.payment > rimpay.attempt",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment > rimpay.bpay.authenticate",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment > rimpay.bpay.authenticate > rimpay.http",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment > rimpay.http",
+		"rimpay.payment > rimpay.attempt",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment",
+		"rimpay.payment > rimpay.attempt > rimpay.bpay.payment > rimpay.http",
+	}, paths)
+
+	payment := tracer.spans[0]
+	assert.Equal(t, "bpay", payment.attributes[common.AttrProvider])
+	assert.Equal(t, "REF-1", payment.attributes[common.AttrReference])
+	assert.Equal(t, "50.00 MRU", payment.attributes[common.AttrAmount])
+	assert.Equal(t, "TX-1", payment.attributes[common.AttrTransactionID])
+	assert.NoError(t, payment.err)
+
+	firstAttempt, secondAttempt := tracer.spans[1], tracer.spans[6]
+	assert.Equal(t, 1, firstAttempt.attributes[common.AttrAttempt])
+	assert.Equal(t, string(rimpay.ErrorCodeNetworkError), firstAttempt.attributes[common.AttrErrorCode])
+	assert.Error(t, firstAttempt.err)
+	assert.Equal(t, 2, secondAttempt.attributes[common.AttrAttempt])
+	assert.NoError(t, secondAttempt.err)
+
+	paymentCall := tracer.spans[8]
+	assert.Equal(t, "POST", paymentCall.attributes[common.AttrHTTPMethod])
+	assert.Equal(t, "/payment", paymentCall.attributes[common.AttrHTTPPath])
+	assert.Equal(t, 200, paymentCall.attributes[common.AttrHTTPStatusCode])
+
+	// Passcodes and credentials never become span attributes
+	for _, span := range tracer.spans {
+		for key, value := range span.attributes {
+			text := fmt.Sprint(value)
+			for _, secret := range []string{"1234", "test-token", "e-bankily"} {
+				assert.NotContains(t, text, secret, "span %s attribute %s", span.name, key)
+			}
+		}
+	}
+}
+
+func TestStartSpanDropsSensitiveAttributes(t *testing.T) {
+	tracer := &recordingTracer{}
+	_, span := common.StartSpan(context.Background(), tracer, "test",
+		"passcode", "1234", "password", "p", "client_secret", "s", common.AttrReference, "REF-1")
+	span.SetAttribute("access_token", "test-token")
+	common.EndSpan(span, nil)
+
+	assert.Equal(t, map[string]interface{}{common.AttrReference: "REF-1"}, tracer.spans[0].attributes)
+}
@@ -0,0 +1,94 @@
+package bpay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// doAuthorized sends req with a bearer token. When B-PAY rejects the token
+// with 401 or 403, for example after a password rotation, the token is
+// discarded and req is sent once more with a fresh one. Transport failures
+// and non-2xx responses are returned as PaymentErrors; action names the call
+// in their messages.
+func (pp *PaymentProcessor) doAuthorized(ctx context.Context, req *common.HTTPRequest, action string) (*common.HTTPResponse, error) {
+	var resp *common.HTTPResponse
+	for attempt := 1; attempt <= 2; attempt++ {
+		token, err := pp.authManager.GetAccessToken(ctx)
+		if err != nil {
+			if transportErr, ok := common.AsTransportError(err, "bpay"); ok {
+				return nil, transportErr
+			}
+			if credentialsErr, ok := common.AsCredentialsError(err); ok {
+				return nil, credentialsErr
+			}
+			return nil, rimpay.NewPaymentError(
+				rimpay.ErrorCodeAuthenticationFailed,
+				"failed to get access token",
+				"bpay",
+				true,
+			).WithCause(err)
+		}
+		req.Headers["Authorization"] = "Bearer " + token
+
+		resp, err = pp.httpClient.Do(ctx, req)
+		if err != nil {
+			if transportErr, ok := common.AsTransportError(err, "bpay"); ok {
+				return nil, transportErr
+			}
+			return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+				rimpay.ErrorCodeNetworkError,
+				action+" failed",
+				"bpay",
+				true,
+			).WithCause(err), req, nil)
+		}
+
+		if !isTokenRejected(resp.StatusCode) {
+			break
+		}
+		pp.authManager.Invalidate(token)
+		if attempt == 1 {
+			rimpay.ContextLogger(ctx, pp.logger).Warn("B-PAY rejected access token, re-authenticating", "status", resp.StatusCode)
+		}
+	}
+
+	if err := statusError(req, resp, action); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func isTokenRejected(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// statusError maps a non-2xx response to a PaymentError: a rejected token
+// to AUTHENTICATION_FAILED, 429 to PROVIDER_BUSY, other 4xx to
+// INVALID_REQUEST and 5xx to a retryable PROVIDER_ERROR
+func statusError(req *common.HTTPRequest, resp *common.HTTPResponse, action string) error {
+	status := resp.StatusCode
+	if status >= 200 && status < 300 {
+		return nil
+	}
+
+	code, retryable := rimpay.ErrorCodeProviderError, true
+	switch {
+	case isTokenRejected(status):
+		code, retryable = rimpay.ErrorCodeAuthenticationFailed, false
+	case status == http.StatusTooManyRequests:
+		code = rimpay.ErrorCodeProviderBusy
+	case status >= 400 && status < 500:
+		code, retryable = rimpay.ErrorCodeInvalidRequest, false
+	}
+
+	return common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+		code,
+		fmt.Sprintf("%s failed with status %d", action, status),
+		"bpay",
+		retryable,
+	), req, resp), resp)
+}
@@ -0,0 +1,133 @@
+package bpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// statusCodeStub issues numbered tokens and answers payments with the queued
+// HTTP statuses, then with success
+type statusCodeStub struct {
+	statuses []int
+	auths    int
+	tokens   []string
+}
+
+func (s *statusCodeStub) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if strings.Contains(req.URL, "/authentification") {
+		s.auths++
+		return &common.HTTPResponse{
+			StatusCode: 200,
+			Body:       []byte(fmt.Sprintf(`{"access_token":"token-%d"}`, s.auths)),
+		}, nil
+	}
+	s.tokens = append(s.tokens, req.Headers["Authorization"])
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+		return &common.HTTPResponse{StatusCode: status, Body: []byte("<html>error</html>")}, nil
+	}
+	return &common.HTTPResponse{
+		StatusCode: 200,
+		Body:       []byte(`{"errorCode":"0","errorMessage":"","transactionId":"TX-1"}`),
+	}, nil
+}
+
+func processWithStub(t *testing.T, stub *statusCodeStub) (*rimpay.PaymentResponse, error) {
+	t.Helper()
+	config := rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "u", "password": "p", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+	}
+	auth := NewAuthManager(config, stub, passcodeTestLogger{})
+	pp, err := NewPaymentProcessor(config, stub, auth, passcodeTestLogger{})
+	if err != nil {
+		t.Fatalf("NewPaymentProcessor: %v", err)
+	}
+
+	phoneNum, err := phone.NewPhone("+22220000000")
+	if err != nil {
+		t.Fatalf("NewPhone: %v", err)
+	}
+	return pp.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(50, money.MRU),
+		Reference:   "REF-1",
+		Passcode:    "4321",
+	})
+}
+
+func TestPaymentReauthenticatesOnceAfterRejectedToken(t *testing.T) {
+	stub := &statusCodeStub{statuses: []int{401}}
+	resp, err := processWithStub(t, stub)
+	if err != nil {
+		t.Fatalf("ProcessPayment: %v", err)
+	}
+	if resp.TransactionID != "TX-1" {
+		t.Errorf("TransactionID = %s, want TX-1", resp.TransactionID)
+	}
+	if stub.auths != 2 {
+		t.Errorf("authentications = %d, want 2", stub.auths)
+	}
+	want := []string{"Bearer token-1", "Bearer token-2"}
+	if fmt.Sprint(stub.tokens) != fmt.Sprint(want) {
+		t.Errorf("payment tokens = %v, want %v", stub.tokens, want)
+	}
+}
+
+func TestPaymentFailsAfterSecondRejectedToken(t *testing.T) {
+	stub := &statusCodeStub{statuses: []int{403, 401}}
+	_, err := processWithStub(t, stub)
+
+	var paymentErr *rimpay.PaymentError
+	if !errors.As(err, &paymentErr) {
+		t.Fatalf("expected PaymentError, got %v", err)
+	}
+	if paymentErr.Code != rimpay.ErrorCodeAuthenticationFailed || paymentErr.IsRetryable() {
+		t.Errorf("error = %v (retryable %v), want non-retryable AUTHENTICATION_FAILED", paymentErr, paymentErr.IsRetryable())
+	}
+	if len(stub.tokens) != 2 {
+		t.Errorf("payment attempts = %d, want exactly 2", len(stub.tokens))
+	}
+}
+
+func TestPaymentMapsHTTPStatusToErrorCode(t *testing.T) {
+	tests := []struct {
+		status    int
+		code      rimpay.ErrorCode
+		retryable bool
+	}{
+		{400, rimpay.ErrorCodeInvalidRequest, false},
+		{404, rimpay.ErrorCodeInvalidRequest, false},
+		{429, rimpay.ErrorCodeProviderBusy, true},
+		{500, rimpay.ErrorCodeProviderError, true},
+		{502, rimpay.ErrorCodeProviderError, true},
+		{503, rimpay.ErrorCodeProviderError, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			_, err := processWithStub(t, &statusCodeStub{statuses: []int{tt.status}})
+
+			var paymentErr *rimpay.PaymentError
+			if !errors.As(err, &paymentErr) {
+				t.Fatalf("expected PaymentError, got %v", err)
+			}
+			if paymentErr.Code != tt.code || paymentErr.IsRetryable() != tt.retryable {
+				t.Errorf("got %s (retryable %v), want %s (retryable %v)", paymentErr.Code, paymentErr.IsRetryable(), tt.code, tt.retryable)
+			}
+			if paymentErr.Details["http_status"] != tt.status {
+				t.Errorf("http_status = %v, want %d", paymentErr.Details["http_status"], tt.status)
+			}
+		})
+	}
+}
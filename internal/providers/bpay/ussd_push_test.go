@@ -0,0 +1,136 @@
+package bpay
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUSSDPushPayment(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	request := operationRequest(t, "")
+	request.Mode = rimpay.BPayModeUSSDPush
+	request.Passcode = ""
+	resp, err := provider.ProcessBPayPayment(context.Background(), request)
+	require.NoError(t, err)
+
+	require.NotNil(t, stub.capturedPayment)
+	assert.Equal(t, "https://example.test/ussdPayment", stub.capturedPayment.URL)
+	var sent map[string]interface{}
+	require.NoError(t, json.Unmarshal(stub.capturedPayment.Body, &sent))
+	assert.NotContains(t, sent, "passcode")
+	assert.Equal(t, "REF-1", sent["operationId"])
+	assert.Equal(t, "50.00", sent["amount"])
+
+	assert.Equal(t, rimpay.PaymentStatusPending, resp.Status, "the customer has yet to confirm")
+	assert.Equal(t, true, resp.Metadata[rimpay.MetadataAwaitingConfirmation])
+	assert.Equal(t, "ussd_push", resp.Metadata["mode"])
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, "Awaiting customer confirmation", resp.Events[0].Message)
+}
+
+func TestPasscodePaymentIsNotAwaitingConfirmation(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.test/payment", stub.capturedPayment.URL)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, resp.Status)
+	assert.NotContains(t, resp.Metadata, rimpay.MetadataAwaitingConfirmation)
+}
+
+func TestUnknownModeIsRejected(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	request := operationRequest(t, "").ToGenericRequest()
+	request.Mode = "sms"
+	_, err = provider.ProcessPayment(context.Background(), request)
+
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, rimpay.ErrorCodeInvalidRequest, paymentErr.Code)
+	assert.Equal(t, "mode", paymentErr.Details["field"])
+	assert.Nil(t, stub.capturedPayment)
+}
+
+func TestUSSDPushStatuses(t *testing.T) {
+	tests := []struct {
+		raw          string
+		expected     rimpay.PaymentStatus
+		awaitingFlag bool
+	}{
+		{"TC", rimpay.PaymentStatusPending, true},
+		{"TR", rimpay.PaymentStatusCancelled, false},
+		{"TE", rimpay.PaymentStatusExpired, false},
+		{"TS", rimpay.PaymentStatusSuccess, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			provider, err := NewBPayProvider(overridesConfig(&statusStub{status: tt.raw}, nil), passcodeTestLogger{})
+			require.NoError(t, err)
+
+			status, err := provider.GetPaymentStatus(context.Background(), "REF-1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, status.Status)
+			if tt.awaitingFlag {
+				assert.Equal(t, true, status.ProviderData[rimpay.MetadataAwaitingConfirmation])
+			} else {
+				assert.NotContains(t, status.ProviderData, rimpay.MetadataAwaitingConfirmation)
+			}
+		})
+	}
+}
+
+func TestPaymentResponseUsesCanonicalMetadataKeys(t *testing.T) {
+	stub := &routingStub{}
+	provider, err := NewBPayProvider(operationsConfig(stub, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+
+	code, ok := resp.GetMetadataString(rimpay.MetadataKeyErrorCode)
+	assert.True(t, ok)
+	assert.Equal(t, "0", code)
+	reference, ok := resp.GetMetadataString(rimpay.MetadataKeyProviderReference)
+	assert.True(t, ok)
+	assert.Equal(t, "TX-1", reference)
+	assert.Contains(t, resp.Metadata, rimpay.MetadataKeyErrorMessage)
+
+	_, ok = resp.Passcode()
+	assert.False(t, ok, "the customer's passcode is never echoed")
+	_, ok = resp.PaymentURLValue()
+	assert.False(t, ok, "B-PAY payments have no payment page")
+}
+
+func TestResponsesCarryRawBPayAnswer(t *testing.T) {
+	provider, err := NewBPayProvider(operationsConfig(&routingStub{}, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+	resp, err := provider.ProcessBPayPayment(context.Background(), operationRequest(t, ""))
+	require.NoError(t, err)
+	raw, ok := resp.AsBPayRaw()
+	require.True(t, ok)
+	assert.Equal(t, &rimpay.BPayRawResponse{ErrorCode: "0", TransactionID: "TX-1"}, raw)
+
+	provider, err = NewBPayProvider(overridesConfig(&statusStub{status: "TC"}, nil), passcodeTestLogger{})
+	require.NoError(t, err)
+	status, err := provider.GetPaymentStatus(context.Background(), "REF-1")
+	require.NoError(t, err)
+	raw, ok = status.AsBPayRaw()
+	require.True(t, ok)
+	assert.Equal(t, "TC", raw.Status)
+	_, ok = status.AsMasrviRaw()
+	assert.False(t, ok)
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
-	"github.com/CatoSystems/rim-pay/internal/types"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -34,14 +33,20 @@ func NewClickProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Prov
 		return nil, fmt.Errorf("invalid CLICK configuration: %w", err)
 	}
 
-	httpClient := common.NewHTTPClient(common.HTTPConfig{
-		Timeout:         config.Timeout,
-		MaxIdleConns:    10,
-		MaxConnsPerHost: 5,
-	})
+	logger = common.NewRedactingLogger(logger)
+
+	baseClient, err := common.ResolveHTTPClient(config.HTTPClient, config.HTTP, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLICK configuration: %w", err)
+	}
+	httpClient, err := common.RateLimitHTTPClient(
+		common.InstrumentHTTPClient(baseClient, config.Metrics, config.Tracer, "click"), config.RateLimit, "click")
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLICK configuration: %w", err)
+	}
 	sessionManager := NewSessionManager(config, httpClient, logger)
 	paymentProcessor := NewPaymentProcessor(config, httpClient, sessionManager, logger)
-	retryExecutor := common.NewRetryExecutor(common.DefaultRetryConfig())
+	retryExecutor := common.NewProviderRetryExecutor("click", config.Retry, config.Metrics, config.Tracer)
 
 	return &Provider{
 		name:             "click",
@@ -64,14 +69,23 @@ func (p *Provider) Name() string { return p.name }
 
 // IsAvailable checks if the provider can obtain a session.
 func (p *Provider) IsAvailable(ctx context.Context) bool {
+	return p.CheckHealth(ctx) == nil
+}
+
+// CheckHealth reports why the provider is unavailable, keeping TLS
+// certificate failures distinct from other errors
+func (p *Provider) CheckHealth(ctx context.Context) error {
 	_, err := p.sessionManager.GetSessionID(ctx)
-	return err == nil
+	if transportErr, ok := common.AsTransportError(err, p.name); ok {
+		return transportErr
+	}
+	return err
 }
 
 // ProcessClickPayment validates and processes a CLICK-specific request.
-func (p *Provider) ProcessClickPayment(ctx context.Context, request *rimpay.ClickPaymentRequest) (*types.PaymentResponse, error) {
+func (p *Provider) ProcessClickPayment(ctx context.Context, request *rimpay.ClickPaymentRequest) (*rimpay.PaymentResponse, error) {
 	if request == nil {
-		return nil, types.NewValidationError("request", "payment request cannot be nil")
+		return nil, rimpay.NewValidationError("request", "payment request cannot be nil")
 	}
 	if err := request.Validate(); err != nil {
 		return nil, err
@@ -80,8 +94,8 @@ func (p *Provider) ProcessClickPayment(ctx context.Context, request *rimpay.Clic
 }
 
 // ProcessPayment processes a generic request with retry.
-func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentRequest) (*types.PaymentResponse, error) {
-	return p.retryExecutor.ExecutePayment(ctx, func() (*types.PaymentResponse, error) {
+func (p *Provider) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
+	return p.retryExecutor.ExecutePayment(ctx, func(ctx context.Context) (*rimpay.PaymentResponse, error) {
 		return p.paymentProcessor.ProcessPayment(ctx, request)
 	})
 }
@@ -89,7 +103,7 @@ func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentReq
 // GetPaymentStatus is notification-driven for CLICK; returns a pending placeholder.
 func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
 	if transactionID == "" {
-		return nil, types.NewValidationError("transactionID", "transaction ID cannot be empty")
+		return nil, rimpay.NewValidationError("transactionID", "transaction ID cannot be empty")
 	}
 	return &rimpay.TransactionStatus{
 		TransactionID: transactionID,
@@ -100,10 +114,15 @@ func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (
 	}, nil
 }
 
+// Refund is not offered by CLICK; refunds go through the BNM back office.
+func (p *Provider) Refund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	return nil, rimpay.ErrRefundNotSupported
+}
+
 // HandleNotification converts a public notification into a TransactionStatus.
 func (p *Provider) HandleNotification(notification *rimpay.ClickNotificationData) (*rimpay.TransactionStatus, error) {
 	if notification == nil {
-		return nil, types.NewValidationError("notification", "is required")
+		return nil, rimpay.NewValidationError("notification", "is required")
 	}
 	return p.paymentProcessor.HandleNotification(&NotificationData{
 		Status:      notification.Status,
@@ -122,11 +141,28 @@ func (p *Provider) HandleNotification(notification *rimpay.ClickNotificationData
 	})
 }
 
+// Close discards cached session IDs and closes idle connections.
+func (p *Provider) Close() error {
+	p.sessionManager.Clear()
+	common.CloseIdleConnections(p.httpClient)
+	return nil
+}
+
 // ValidateConfig validates provider configuration.
 func (p *Provider) ValidateConfig() error { return validateConfig(p.config) }
 
+// Capabilities describes CLICK: the customer pays on the CLICK page and the
+// status only arrives by notification
+func (p *Provider) Capabilities() rimpay.Capabilities {
+	return rimpay.Capabilities{
+		SupportsWebhooks: true,
+		RequiresRedirect: true,
+	}
+}
+
 func validateConfig(config rimpay.ProviderConfig) error {
-	if config.Credentials["merchant_id"] == "" {
+	// Credentials from a CredentialsProvider are only read when needed
+	if !config.HasCredentialsProvider() && config.Credentials["merchant_id"] == "" {
 		return fmt.Errorf("missing required credential: merchant_id")
 	}
 	if config.BaseURL == "" {
@@ -135,5 +171,5 @@ func validateConfig(config rimpay.ProviderConfig) error {
 	if config.Timeout <= 0 {
 		return fmt.Errorf("timeout must be positive")
 	}
-	return nil
+	return common.ValidateRetryConfig(config.Retry)
 }
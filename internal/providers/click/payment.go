@@ -31,16 +31,26 @@ func NewPaymentProcessor(config rimpay.ProviderConfig, httpClient common.HTTPCli
 }
 
 // ProcessPayment creates a CLICK session and builds the order form.
-func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
-	sessionID, err := pp.sessionManager.GetSessionID(ctx)
+func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (_ *rimpay.PaymentResponse, err error) {
+	ctx, span := common.StartSpan(ctx, pp.config.Tracer, "rimpay.click.payment",
+		common.AttrProvider, "click", common.AttrReference, request.Reference, common.AttrAmount, request.Amount.String())
+	defer func() { common.EndSpan(span, err) }()
+
+	sessionID, merchantID, err := pp.sessionManager.session(ctx)
 	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "click"); ok {
+			return nil, transportErr
+		}
+		if credentialsErr, ok := common.AsCredentialsError(err); ok {
+			return nil, credentialsErr
+		}
 		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeProviderError, "failed to get session ID", "click", true)
 	}
 
-	formData := pp.createFormData(sessionID, request)
+	formData := pp.createFormData(sessionID, merchantID, request)
 	paymentURL := pp.baseURL + "/online/online.php"
 
-	pp.logger.Info("CLICK payment created",
+	rimpay.ContextLogger(ctx, pp.logger).Info("CLICK payment created",
 		"reference", request.Reference,
 		"amount", request.Amount.String(),
 	)
@@ -55,19 +65,22 @@ func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.
 		UpdatedAt:     time.Now(),
 		PaymentURL:    paymentURL,
 		Metadata: map[string]interface{}{
-			"session_id":  sessionID,
-			"form_data":   formData,
-			"payment_url": paymentURL,
-			"message":     "Payment initiated, redirect user to payment URL",
+			rimpay.MetadataKeySessionID:  sessionID,
+			rimpay.MetadataKeyFormData:   formData,
+			rimpay.MetadataKeyPaymentURL: paymentURL,
+			"message":                    "Payment initiated, redirect user to payment URL",
+		},
+		Events: []rimpay.StatusEvent{
+			rimpay.NewStatusEvent(rimpay.PaymentStatusPending, "Payment initiated", rimpay.EventSourceInitial),
 		},
 	}, nil
 }
 
 // createFormData builds the lowercase TagPay order form.
-func (pp *PaymentProcessor) createFormData(sessionID string, request *rimpay.PaymentRequest) url.Values {
+func (pp *PaymentProcessor) createFormData(sessionID, merchantID string, request *rimpay.PaymentRequest) url.Values {
 	form := url.Values{}
 	form.Set("sessionid", sessionID)
-	form.Set("merchantid", pp.config.Credentials["merchant_id"])
+	form.Set("merchantid", merchantID)
 	form.Set("amount", request.Amount.ToProviderAmount(true)) // cents
 	form.Set("currency", request.Amount.GetCurrencyCode())    // ISO 4217 numeric
 	form.Set("purchaseref", request.Reference)
@@ -139,6 +152,6 @@ func (pp *PaymentProcessor) HandleNotification(notification *NotificationData) (
 			"reason":      notification.Reason,
 		},
 	}
-	ts.AddEvent(status, message)
+	ts.AddEvent(status, message, rimpay.EventSourceWebhook)
 	return ts, nil
 }
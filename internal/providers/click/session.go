@@ -26,6 +26,10 @@ type SessionManager struct {
 type sessionCacheEntry struct {
 	sessionID string
 	expiresAt time.Time
+
+	// credentialsVersion is the version of the credentials the session was
+	// created with.
+	credentialsVersion string
 }
 
 // NewSessionManager creates a new CLICK session manager.
@@ -41,30 +45,55 @@ func NewSessionManager(config rimpay.ProviderConfig, httpClient common.HTTPClien
 
 // GetSessionID returns a valid (cached or fresh) session ID.
 func (sm *SessionManager) GetSessionID(ctx context.Context) (string, error) {
-	merchantID := sm.config.Credentials["merchant_id"]
+	sessionID, _, err := sm.session(ctx)
+	return sessionID, err
+}
+
+// session returns a valid session ID and the merchant ID it belongs to,
+// replacing sessions created with rotated credentials.
+func (sm *SessionManager) session(ctx context.Context) (sessionID, merchantID string, err error) {
+	merchantID, err = sm.config.Credential(ctx, "click", "merchant_id")
+	if err != nil {
+		return "", "", common.CredentialsError(err, "click")
+	}
+	version, err := sm.config.CredentialsVersion(ctx, "click")
+	if err != nil {
+		return "", "", common.CredentialsError(err, "click")
+	}
 
 	sm.cacheMutex.RLock()
-	if entry, ok := sm.sessionCache[merchantID]; ok && time.Now().Before(entry.expiresAt) {
+	if entry, ok := sm.sessionCache[merchantID]; ok && entry.credentialsVersion == version && time.Now().Before(entry.expiresAt) {
 		id := entry.sessionID
 		sm.cacheMutex.RUnlock()
-		return id, nil
+		return id, merchantID, nil
 	}
 	sm.cacheMutex.RUnlock()
 
-	return sm.createSession(ctx, merchantID)
+	sessionID, err = sm.createSession(ctx, merchantID, version)
+	return sessionID, merchantID, err
 }
 
-func (sm *SessionManager) createSession(ctx context.Context, merchantID string) (string, error) {
+// Clear discards every cached session ID.
+func (sm *SessionManager) Clear() {
+	sm.cacheMutex.Lock()
+	defer sm.cacheMutex.Unlock()
+	sm.sessionCache = make(map[string]*sessionCacheEntry)
+}
+
+func (sm *SessionManager) createSession(ctx context.Context, merchantID, version string) (_ string, err error) {
+	ctx, span := common.StartSpan(ctx, sm.config.Tracer, "rimpay.click.create_session", common.AttrProvider, "click")
+	defer func() { common.EndSpan(span, err) }()
+
 	sessionURL := fmt.Sprintf("%s/online/online.php?merchantid=%s", sm.baseURL, merchantID)
 
-	resp, err := sm.httpClient.Do(&common.HTTPRequest{
+	resp, err := sm.httpClient.Do(ctx, &common.HTTPRequest{
 		Method:  "GET",
 		URL:     sessionURL,
 		Headers: make(map[string]string),
 		Timeout: sm.config.Timeout,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", common.RedactError(err))
 	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("session creation failed with status: %d", resp.StatusCode)
@@ -80,11 +109,12 @@ func (sm *SessionManager) createSession(ctx context.Context, merchantID string)
 		}
 		sm.cacheMutex.Lock()
 		sm.sessionCache[merchantID] = &sessionCacheEntry{
-			sessionID: sessionID,
-			expiresAt: time.Now().Add(180 * time.Second), // spec default session timeout
+			sessionID:          sessionID,
+			expiresAt:          time.Now().Add(180 * time.Second), // spec default session timeout
+			credentialsVersion: version,
 		}
 		sm.cacheMutex.Unlock()
-		sm.logger.Info("CLICK session created", "merchant_id", merchantID)
+		rimpay.ContextLogger(ctx, sm.logger).Info("CLICK session created", "url", common.RedactURL(sessionURL))
 		return sessionID, nil
 	case strings.HasPrefix(raw, "NOK:"):
 		return "", fmt.Errorf("session refused: %s", strings.TrimPrefix(raw, "NOK:"))
@@ -15,7 +15,7 @@ type stubHTTP struct {
 	last *common.HTTPRequest
 }
 
-func (s *stubHTTP) Do(req *common.HTTPRequest) (*common.HTTPResponse, error) {
+func (s *stubHTTP) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
 	s.last = req
 	if s.err != nil {
 		return nil, s.err
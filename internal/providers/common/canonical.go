@@ -0,0 +1,34 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// CanonicalJSON re-encodes a JSON document with sorted object keys, no
+// insignificant whitespace and no HTML escaping, so semantically equal
+// payloads always produce identical bytes (for snapshots and signatures)
+func CanonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// CanonicalForm returns the URL form encoding of values with keys sorted
+func CanonicalForm(values url.Values) string {
+	return values.Encode()
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	got, err := CanonicalJSON([]byte(`{ "b": 1, "a": {"d": "x&y", "c": 12345678901234567890} }`))
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	want := `{"a":{"c":12345678901234567890,"d":"x&y"},"b":1}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if _, err := CanonicalJSON([]byte(`{`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestCanonicalForm(t *testing.T) {
+	values := url.Values{}
+	values.Set("z", "1")
+	values.Set("a", "2 3")
+	if got := CanonicalForm(values); got != "a=2+3&z=1" {
+		t.Errorf("got %q", got)
+	}
+}
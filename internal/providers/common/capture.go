@@ -0,0 +1,174 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// Re-export capture types from internal/types
+type (
+	HTTPExchange = types.HTTPExchange
+	HTTPRecorder = types.HTTPRecorder
+)
+
+// DefaultRawResponseLimit is the default cap on raw_response error details
+const DefaultRawResponseLimit = 2048
+
+var (
+	rawResponseMu    sync.RWMutex
+	rawResponseLimit = DefaultRawResponseLimit
+)
+
+// sensitiveCaptureKeys mask any header, JSON field or form field whose name
+// contains one of them, ignoring case, as do the sensitive query parameters
+var sensitiveCaptureKeys = []string{
+	"authorization", "cookie", "password", "passcode", "secret", "token",
+	"api_key", "apikey", "api-key", "username", "credential", "signature",
+}
+
+// SetRawResponseLimit caps the bytes of a response body attached to errors
+// as raw_response; 0 disables the detail
+func SetRawResponseLimit(limit int) {
+	rawResponseMu.Lock()
+	defer rawResponseMu.Unlock()
+	rawResponseLimit = limit
+}
+
+// AttachRawResponse records the sanitized body of resp, truncated to the
+// raw response limit, on err. It is meant for responses that could not be
+// decoded.
+func AttachRawResponse(err *types.PaymentError, resp *HTTPResponse) *types.PaymentError {
+	rawResponseMu.RLock()
+	limit := rawResponseLimit
+	rawResponseMu.RUnlock()
+
+	if resp == nil || limit <= 0 {
+		return err
+	}
+	raw := SanitizeBody(resp.Headers["Content-Type"], resp.Body)
+	if len(raw) > limit {
+		raw = raw[:limit]
+	}
+	return err.WithDetail(types.DetailRawResponse, raw)
+}
+
+func isSensitiveCaptureKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveCaptureKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	sensitiveParamsMu.RLock()
+	defer sensitiveParamsMu.RUnlock()
+	return isSensitiveQueryParam(key)
+}
+
+// SanitizeHeaders returns a copy of headers with credential headers masked
+func SanitizeHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	sanitized := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if isSensitiveCaptureKey(key) {
+			value = redactedValue
+		}
+		sanitized[key] = value
+	}
+	return sanitized
+}
+
+// SanitizeBody returns a copy of body with sensitive JSON or form field
+// values masked. Other bodies are copied unchanged.
+func SanitizeBody(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if json.Valid(trimmed) && (trimmed[0] == '{' || trimmed[0] == '[') {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err == nil {
+			if sanitized, err := json.Marshal(sanitizeJSONValue(value)); err == nil {
+				return sanitized
+			}
+		}
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for key := range values {
+				if isSensitiveCaptureKey(key) {
+					values[key] = []string{redactedValue}
+				}
+			}
+			return []byte(values.Encode())
+		}
+	}
+
+	return append([]byte(nil), body...)
+}
+
+func sanitizeJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if isSensitiveCaptureKey(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = sanitizeJSONValue(nested)
+			}
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = sanitizeJSONValue(nested)
+		}
+	}
+	return value
+}
+
+// RingRecorder is an HTTPRecorder that keeps the most recent exchanges,
+// for tests and debugging
+type RingRecorder struct {
+	mu        sync.Mutex
+	exchanges []HTTPExchange
+	next      int
+	full      bool
+}
+
+// NewRingRecorder creates a recorder holding up to size exchanges
+func NewRingRecorder(size int) *RingRecorder {
+	if size < 1 {
+		size = 1
+	}
+	return &RingRecorder{exchanges: make([]HTTPExchange, size)}
+}
+
+// Record stores exchange, evicting the oldest one when full
+func (r *RingRecorder) Record(exchange HTTPExchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges[r.next] = exchange
+	r.next = (r.next + 1) % len(r.exchanges)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Exchanges returns the recorded exchanges, oldest first
+func (r *RingRecorder) Exchanges() []HTTPExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]HTTPExchange(nil), r.exchanges[:r.next]...)
+	}
+	return append(append([]HTTPExchange(nil), r.exchanges[r.next:]...), r.exchanges[:r.next]...)
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+func TestSanitizeBodyMasksJSONFields(t *testing.T) {
+	body := []byte(`{"passcode":"4321","amount":"50.00","auth":{"access_token":"tok","expires_in":3600},"items":[{"password":"p"}]}`)
+	got := string(SanitizeBody("application/json", body))
+
+	for _, secret := range []string{"4321", `"tok"`, `"p"`} {
+		if strings.Contains(got, secret) {
+			t.Errorf("sanitized body leaks %s: %s", secret, got)
+		}
+	}
+	for _, kept := range []string{`"amount":"50.00"`, `"expires_in":3600`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("sanitized body lost %s: %s", kept, got)
+		}
+	}
+}
+
+func TestSanitizeBodyMasksFormFields(t *testing.T) {
+	got := string(SanitizeBody("application/x-www-form-urlencoded",
+		[]byte("grant_type=password&username=merchant&password=hunter2&client_id=e-bankily")))
+
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "merchant") {
+		t.Errorf("sanitized form leaks credentials: %s", got)
+	}
+	if !strings.Contains(got, "client_id=e-bankily") {
+		t.Errorf("sanitized form lost client_id: %s", got)
+	}
+}
+
+func TestSanitizeBodyKeepsOtherBodies(t *testing.T) {
+	body := []byte("<html>Bad Gateway</html>")
+	if got := SanitizeBody("text/html", body); string(got) != string(body) {
+		t.Errorf("SanitizeBody = %q, want %q", got, body)
+	}
+}
+
+func TestRingRecorderKeepsMostRecent(t *testing.T) {
+	recorder := NewRingRecorder(2)
+	for _, method := range []string{"GET", "POST", "PUT"} {
+		recorder.Record(HTTPExchange{Method: method})
+	}
+
+	exchanges := recorder.Exchanges()
+	if len(exchanges) != 2 || exchanges[0].Method != "POST" || exchanges[1].Method != "PUT" {
+		t.Errorf("Exchanges = %+v, want POST then PUT", exchanges)
+	}
+}
+
+func TestDoRecordsSanitizedExchange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"secret-token","errorCode":"0"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRingRecorder(10)
+	client := newTestHTTPClient(t, HTTPConfig{Recorder: recorder})
+	_, err := client.Do(context.Background(), &HTTPRequest{
+		Method: "POST",
+		URL:    server.URL + "/payment?merchantid=M123",
+		Headers: map[string]string{
+			"Content-Type":  "application/json",
+			"Authorization": "Bearer secret-token",
+		},
+		Body: []byte(`{"passcode":"4321","amount":"50.00"}`),
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	exchanges := recorder.Exchanges()
+	if len(exchanges) != 1 {
+		t.Fatalf("recorded %d exchanges, want 1", len(exchanges))
+	}
+	exchange := exchanges[0]
+	if exchange.StatusCode != http.StatusOK || exchange.Method != "POST" {
+		t.Errorf("exchange = %+v", exchange)
+	}
+	dump := exchange.URL + exchange.RequestHeaders["Authorization"] + string(exchange.RequestBody) + string(exchange.ResponseBody)
+	for _, secret := range []string{"M123", "secret-token", "4321"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("exchange leaks %q: %s", secret, dump)
+		}
+	}
+	if !strings.Contains(string(exchange.ResponseBody), `"errorCode":"0"`) {
+		t.Errorf("response body = %s", exchange.ResponseBody)
+	}
+}
+
+func TestAttachRawResponseCapsBody(t *testing.T) {
+	SetRawResponseLimit(8)
+	defer SetRawResponseLimit(DefaultRawResponseLimit)
+
+	err := AttachRawResponse(types.NewPaymentError(types.ErrorCodeProviderError, "failed to decode", "bpay", false),
+		&HTTPResponse{StatusCode: 502, Body: []byte("<html>Bad Gateway</html>")})
+	if got, _ := err.Details[types.DetailRawResponse].([]byte); string(got) != "<html>Ba" {
+		t.Errorf("raw_response = %q, want %q", got, "<html>Ba")
+	}
+}
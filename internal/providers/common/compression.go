@@ -0,0 +1,54 @@
+package common
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compressBody gzip-encodes a request body
+func compressBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressBody decodes a response body according to its Content-Encoding.
+// Unknown or identity encodings are returned unchanged.
+func decompressBody(encoding string, body []byte) ([]byte, bool, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// HTTP "deflate" is zlib-wrapped, but some servers send raw DEFLATE
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s response body: %w", encoding, err)
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s response body: %w", encoding, err)
+	}
+	return decoded, true, nil
+}
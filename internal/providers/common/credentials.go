@@ -0,0 +1,28 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// credentialsFailure is the message of errors returned by CredentialsError
+const credentialsFailure = "failed to load credentials"
+
+// CredentialsError reports credentials the provider's CredentialsProvider
+// could not supply. Retrying cannot fix them, so the error is not retryable.
+func CredentialsError(err error, provider string) *types.PaymentError {
+	return types.NewPaymentError(types.ErrorCodeAuthenticationFailed, credentialsFailure, provider, false).WithCause(err)
+}
+
+// AsCredentialsError returns the CredentialsError in err's chain, so that
+// callers wrapping session or token failures can report it unchanged
+func AsCredentialsError(err error) (*types.PaymentError, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		paymentErr, ok := err.(*types.PaymentError)
+		if ok && paymentErr.Code == types.ErrorCodeAuthenticationFailed && paymentErr.Message == credentialsFailure {
+			return paymentErr, true
+		}
+	}
+	return nil, false
+}
@@ -3,52 +3,70 @@ package common
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
-)
 
-// HTTPConfig represents HTTP client configuration
-type HTTPConfig struct {
-	Timeout         time.Duration
-	MaxIdleConns    int
-	MaxConnsPerHost int
-	UserAgent       string
-}
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
 
-// HTTPClient defines HTTP client interface
-type HTTPClient interface {
-	Do(req *HTTPRequest) (*HTTPResponse, error)
-}
+// Re-export HTTP types from internal/types so providers and the public
+// client share a single definition
+type (
+	HTTPConfig   = types.HTTPConfig
+	HTTPClient   = types.HTTPClient
+	HTTPRequest  = types.HTTPRequest
+	HTTPResponse = types.HTTPResponse
 
-// HTTPRequest represents an HTTP request
-type HTTPRequest struct {
-	Method  string
-	URL     string
-	Headers map[string]string
-	Body    []byte
-	Timeout time.Duration
-}
+	HTTPStatusError = types.HTTPStatusError
+)
 
-// HTTPResponse represents an HTTP response
-type HTTPResponse struct {
-	StatusCode int
-	Headers    map[string]string
-	Body       []byte
+// DefaultHTTPConfig returns the HTTP configuration used when neither a shared
+// client nor a per-provider override is supplied
+func DefaultHTTPConfig(timeout time.Duration) HTTPConfig {
+	return HTTPConfig{
+		Timeout:         timeout,
+		MaxIdleConns:    100,
+		MaxConnsPerHost: 10,
+	}
 }
 
 // DefaultHTTPClient implements HTTPClient using Go's http.Client
 type DefaultHTTPClient struct {
 	client *http.Client
+	config HTTPConfig
+	sleep  func(ctx context.Context, d time.Duration) error
+
+	recorderMu sync.RWMutex
+	recorder   HTTPRecorder
 }
 
-// NewHTTPClient creates a new HTTP client
-func NewHTTPClient(config HTTPConfig) HTTPClient {
+// NewHTTPClient creates a new HTTP client. It fails when config.ProxyURL is
+// invalid or the TLS certificates cannot be loaded.
+func NewHTTPClient(config HTTPConfig) (HTTPClient, error) {
+	proxy, err := config.Proxy()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
 	transport := &http.Transport{
 		MaxIdleConns:        config.MaxIdleConns,
 		MaxIdleConnsPerHost: config.MaxConnsPerHost,
 		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
 	}
 
 	client := &http.Client{
@@ -56,38 +74,169 @@ func NewHTTPClient(config HTTPConfig) HTTPClient {
 		Timeout:   config.Timeout,
 	}
 
-	return &DefaultHTTPClient{client: client}
+	return &DefaultHTTPClient{
+		client:   client,
+		config:   config,
+		sleep:    sleepContext,
+		recorder: config.Recorder,
+	}, nil
+}
+
+// ResolveHTTPClient picks the HTTP client a provider should use: an injected
+// shared client wins, then a per-provider override config, and finally a
+// dedicated client built from DefaultHTTPConfig with the provider timeout
+func ResolveHTTPClient(shared HTTPClient, override *HTTPConfig, timeout time.Duration) (HTTPClient, error) {
+	if shared != nil {
+		return shared, nil
+	}
+	if override != nil {
+		return NewHTTPClient(*override)
+	}
+	return NewHTTPClient(DefaultHTTPConfig(timeout))
+}
+
+// CloseIdleConnections closes connections kept open for reuse; connections
+// in use are not interrupted
+func (c *DefaultHTTPClient) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+// idleConnectionCloser is implemented by HTTP clients that keep idle
+// connections
+type idleConnectionCloser interface {
+	CloseIdleConnections()
 }
 
-// Do executes an HTTP request
-func (c *DefaultHTTPClient) Do(request *HTTPRequest) (*HTTPResponse, error) {
-	// Create context with timeout if specified
-	ctx := context.Background()
+// CloseIdleConnections closes the idle connections of client, if it keeps any
+func CloseIdleConnections(client HTTPClient) {
+	if closer, ok := client.(idleConnectionCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Config returns the configuration the client was built from
+func (c *DefaultHTTPClient) Config() HTTPConfig {
+	return c.config
+}
+
+// SetRecorder replaces the recorder that receives sanitized copies of every
+// exchange; nil stops recording
+func (c *DefaultHTTPClient) SetRecorder(recorder HTTPRecorder) {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	c.recorder = recorder
+}
+
+// record passes a sanitized copy of an attempt to the recorder, if any
+func (c *DefaultHTTPClient) record(request *HTTPRequest, start time.Time, resp *HTTPResponse, err error) {
+	c.recorderMu.RLock()
+	recorder := c.recorder
+	c.recorderMu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	exchange := HTTPExchange{
+		Time:           start,
+		Duration:       time.Since(start),
+		Method:         request.Method,
+		URL:            RedactURL(request.URL),
+		RequestHeaders: SanitizeHeaders(request.Headers),
+		RequestBody:    SanitizeBody(request.Headers["Content-Type"], request.Body),
+	}
+	if resp != nil {
+		exchange.StatusCode = resp.StatusCode
+		exchange.ResponseHeaders = SanitizeHeaders(resp.Headers)
+		exchange.ResponseBody = SanitizeBody(resp.Headers["Content-Type"], resp.Body)
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	recorder.Record(exchange)
+}
+
+// Do executes an HTTP request. Cancelling ctx aborts the request in flight;
+// request.Timeout, when set, further bounds it. Idempotent requests that fail
+// with a HTTPConfig.RetryOn status are resent up to HTTPConfig.MaxRetries
+// times, honouring Retry-After; when they still fail the error is an
+// *HTTPStatusError.
+func (c *DefaultHTTPClient) Do(ctx context.Context, request *HTTPRequest) (*HTTPResponse, error) {
 	if request.Timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, request.Timeout)
 		defer cancel()
 	}
 
-	// Create HTTP request
+	body := request.Body
+	compressed := request.Compress && len(body) > 0
+	if compressed {
+		var err error
+		if body, err = compressBody(body); err != nil {
+			return nil, fmt.Errorf("failed to compress request body: %w", err)
+		}
+	}
+
+	retries := 0
+	if isIdempotent(request) {
+		retries = c.config.MaxRetries
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := c.do(ctx, request, body, compressed)
+		c.record(request, start, resp, err)
+		if err != nil || !c.retryableStatus(resp.StatusCode) || retries == 0 {
+			return resp, err
+		}
+
+		delay, ok := httpRetryDelay(attempt, resp.Headers["Retry-After"], time.Now())
+		if attempt > retries || !ok {
+			return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Attempts: attempt, Body: resp.Body}
+		}
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("HTTP request failed: %w", err)
+		}
+	}
+}
+
+// do sends a single attempt of request with the prepared body
+func (c *DefaultHTTPClient) do(ctx context.Context, request *HTTPRequest, body []byte, compressed bool) (*HTTPResponse, error) {
 	var bodyReader io.Reader
-	if request.Body != nil {
-		bodyReader = bytes.NewReader(request.Body)
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, request.Method, request.URL, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", RedactError(err))
 	}
 
-	// Set headers
+	// Set headers: configured defaults first, so that the user agent and the
+	// provider's own headers win
+	for key, value := range c.config.DefaultHeaders {
+		req.Header.Set(key, value)
+	}
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
 	for key, value := range request.Headers {
 		req.Header.Set(key, value)
 	}
+	if id := types.CorrelationIDFromContext(ctx); id != "" && req.Header.Get(types.CorrelationIDHeader) == "" {
+		req.Header.Set(types.CorrelationIDHeader, id)
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
 	// Execute request
 	resp, err := c.client.Do(req)
 	if err != nil {
+		// Redact first: TLS errors keep err as their cause
+		err = RedactError(err)
+		if tlsErr, ok := classifyTLSError(err, req.URL.Host); ok {
+			return nil, tlsErr
+		}
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer func(Body io.ReadCloser) {
@@ -97,11 +246,21 @@ func (c *DefaultHTTPClient) Do(request *HTTPRequest) (*HTTPResponse, error) {
 	}(resp.Body)
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	// net/http only decompresses transparently when it added Accept-Encoding
+	// itself; decode explicitly when a caller or intermediary asked for it
+	decoded := false
+	if !resp.Uncompressed {
+		respBody, decoded, err = decompressBody(resp.Header.Get("Content-Encoding"), respBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Extract response headers
 	headers := make(map[string]string)
 	for key, values := range resp.Header {
@@ -109,10 +268,115 @@ func (c *DefaultHTTPClient) Do(request *HTTPRequest) (*HTTPResponse, error) {
 			headers[key] = values[0]
 		}
 	}
+	if decoded {
+		delete(headers, "Content-Encoding")
+		delete(headers, "Content-Length")
+	}
 
 	return &HTTPResponse{
 		StatusCode: resp.StatusCode,
 		Headers:    headers,
-		Body:       body,
+		Body:       respBody,
 	}, nil
 }
+
+const (
+	httpRetryInitialDelay = 200 * time.Millisecond
+	httpRetryMaxDelay     = 5 * time.Second
+	// httpRetryMaxAfter is the longest Retry-After honoured; a server asking
+	// for more gets the status returned instead
+	httpRetryMaxAfter = 30 * time.Second
+)
+
+// isIdempotent reports whether request may be resent without side effects
+func isIdempotent(request *HTTPRequest) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return request.Idempotent
+}
+
+// retryableStatus reports whether status is in the configured RetryOn list
+func (c *DefaultHTTPClient) retryableStatus(status int) bool {
+	retryOn := c.config.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = types.DefaultHTTPRetryOn
+	}
+	for _, code := range retryOn {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// httpRetryDelay returns how long to wait before resending after attempt:
+// the Retry-After header, given in seconds or as an HTTP date, or capped
+// exponential backoff. It reports false when Retry-After is too far away.
+func httpRetryDelay(attempt int, retryAfter string, now time.Time) (time.Duration, bool) {
+	if retryAfter != "" {
+		var delay time.Duration
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			delay = at.Sub(now)
+		} else {
+			return httpBackoff(attempt), true
+		}
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, delay <= httpRetryMaxAfter
+	}
+	return httpBackoff(attempt), true
+}
+
+func httpBackoff(attempt int) time.Duration {
+	delay := httpRetryInitialDelay << uint(attempt-1)
+	if delay > httpRetryMaxDelay || delay <= 0 {
+		delay = httpRetryMaxDelay
+	}
+	return delay
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// AnnotateRequest records the endpoint, request timeout and, when resp is
+// non-nil, the HTTP status on err so that SupportBundle can report them.
+// Query strings are dropped from the endpoint since they may carry
+// merchant identifiers. A failing status without a cause becomes an
+// *HTTPStatusError cause, for errors.As.
+func AnnotateRequest(err *types.PaymentError, req *HTTPRequest, resp *HTTPResponse) *types.PaymentError {
+	endpoint := req.URL
+	if u, parseErr := url.Parse(req.URL); parseErr == nil {
+		u.RawQuery = ""
+		u.User = nil
+		endpoint = u.String()
+	}
+
+	err.WithDetail(types.DetailEndpoint, req.Method+" "+endpoint)
+	if req.Timeout > 0 {
+		err.WithDetail(types.DetailRequestTimeout, req.Timeout)
+	}
+	var statusErr *HTTPStatusError
+	if resp != nil {
+		err.WithDetail(types.DetailHTTPStatus, resp.StatusCode)
+		if err.Cause == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+			err.Cause = &HTTPStatusError{StatusCode: resp.StatusCode, Attempts: 1}
+		}
+	} else if errors.As(err.Cause, &statusErr) {
+		err.WithDetail(types.DetailHTTPStatus, statusErr.StatusCode)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// instrumentedHTTPClient reports every call to a metrics collector and
+// records it as a span
+type instrumentedHTTPClient struct {
+	client   HTTPClient
+	metrics  types.MetricsCollector
+	tracer   types.Tracer
+	provider string
+}
+
+// InstrumentHTTPClient wraps client so that every call is reported to
+// metrics and traced with tracer under provider; it returns client
+// unchanged when both are nil
+func InstrumentHTTPClient(client HTTPClient, metrics types.MetricsCollector, tracer types.Tracer, provider string) HTTPClient {
+	if metrics == nil && tracer == nil {
+		return client
+	}
+	return &instrumentedHTTPClient{client: client, metrics: metrics, tracer: tracer, provider: provider}
+}
+
+// Do executes request with the wrapped client and reports its outcome
+func (c *instrumentedHTTPClient) Do(ctx context.Context, request *HTTPRequest) (*HTTPResponse, error) {
+	path := endpoint(request.URL)
+	ctx, span := StartSpan(ctx, c.tracer, "rimpay.http",
+		AttrProvider, c.provider, AttrHTTPMethod, request.Method, AttrHTTPPath, path)
+
+	start := time.Now()
+	resp, err := c.client.Do(ctx, request)
+
+	code := 0
+	var statusErr *HTTPStatusError
+	if resp != nil {
+		code = resp.StatusCode
+	} else if errors.As(err, &statusErr) {
+		code = statusErr.StatusCode
+	}
+	if c.metrics != nil {
+		c.metrics.ObserveHTTP(c.provider, path, code, time.Since(start))
+	}
+
+	if code != 0 {
+		span.SetAttribute(AttrHTTPStatusCode, code)
+	}
+	EndSpan(span, err)
+
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped client
+func (c *instrumentedHTTPClient) CloseIdleConnections() {
+	CloseIdleConnections(c.client)
+}
+
+// endpoint is the path of rawURL, which keeps metric labels free of hosts,
+// credentials and query values
+func endpoint(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Path == "" {
+		return "/"
+	}
+	return parsed.Path
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// RedactedLogValue replaces the values of sensitive log fields
+const RedactedLogValue = "****"
+
+var (
+	sensitiveLogKeysMu sync.RWMutex
+	// sensitiveLogKeys mask fields named after one of them, or ending in
+	// one as their last words, ignoring case: "passcode", "Client_Secret" and
+	// "refreshToken" are caught, "token_expires" is not
+	sensitiveLogKeys = []string{
+		"password", "passcode", "secret", "token", "api_key", "apikey", "username",
+		"authorization", "signing_key", "encryption_key", "credential", "credentials",
+	}
+)
+
+// RegisterSensitiveLogKeys adds field names whose values are masked in all
+// log output, along with those of fields ending in them
+func RegisterSensitiveLogKeys(keys ...string) {
+	sensitiveLogKeysMu.Lock()
+	defer sensitiveLogKeysMu.Unlock()
+	for _, key := range keys {
+		sensitiveLogKeys = append(sensitiveLogKeys, normalizeLogKey(key))
+	}
+}
+
+// normalizeLogKey lowercases key and separates its words with underscores,
+// so "refreshToken", "Refresh-Token" and "refresh_token" compare equal
+func normalizeLogKey(key string) string {
+	var b strings.Builder
+	var prev rune
+	for i, r := range key {
+		switch {
+		case r == '-' || r == '.' || r == ' ':
+			r = '_'
+		case i > 0 && unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+	return b.String()
+}
+
+func isSensitiveLogKey(key string) bool {
+	key = normalizeLogKey(key)
+	sensitiveLogKeysMu.RLock()
+	defer sensitiveLogKeysMu.RUnlock()
+	for _, sensitive := range sensitiveLogKeys {
+		if key == sensitive || strings.HasSuffix(key, "_"+sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRedactingLogger wraps next so that the values of sensitive fields, such
+// as passcodes, passwords, tokens and credential maps, are replaced with
+// RedactedLogValue before next sees them. Map values are masked by key too,
+// and strings and errors have secrets in their text masked. Providers wrap
+// every logger they are given, so none of this reaches the caller's sink.
+func NewRedactingLogger(next types.Logger) types.Logger {
+	if next == nil {
+		return nil
+	}
+	if _, ok := next.(*redactingLogger); ok {
+		return next
+	}
+	return &redactingLogger{next: next}
+}
+
+type redactingLogger struct {
+	next types.Logger
+}
+
+func (l *redactingLogger) Debug(msg string, fields ...interface{}) {
+	l.next.Debug(msg, redactFields(fields)...)
+}
+
+func (l *redactingLogger) Info(msg string, fields ...interface{}) {
+	l.next.Info(msg, redactFields(fields)...)
+}
+
+func (l *redactingLogger) Warn(msg string, fields ...interface{}) {
+	l.next.Warn(msg, redactFields(fields)...)
+}
+
+func (l *redactingLogger) Error(msg string, fields ...interface{}) {
+	l.next.Error(msg, redactFields(fields)...)
+}
+
+// redactFields returns a copy of the key-value pairs in fields with the
+// values of sensitive keys masked
+func redactFields(fields []interface{}) []interface{} {
+	redacted := make([]interface{}, len(fields))
+	copy(redacted, fields)
+	for i := 0; i+1 < len(redacted); i += 2 {
+		if key, ok := redacted[i].(string); ok && isSensitiveLogKey(key) {
+			redacted[i+1] = RedactedLogValue
+			continue
+		}
+		redacted[i+1] = redactValue(redacted[i+1])
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]string:
+		masked := make(map[string]string, len(v))
+		for key, val := range v {
+			if isSensitiveLogKey(key) {
+				val = RedactedLogValue
+			}
+			masked[key] = val
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			if isSensitiveLogKey(key) {
+				masked[key] = RedactedLogValue
+			} else {
+				masked[key] = redactValue(val)
+			}
+		}
+		return masked
+	case string:
+		return types.RedactText(v)
+	case error:
+		if text := types.RedactText(v.Error()); text != v.Error() {
+			return text
+		}
+		return value
+	default:
+		return value
+	}
+}
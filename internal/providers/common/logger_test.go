@@ -0,0 +1,33 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitiveLogKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"token", true},
+		{"access_token", true},
+		{"refreshToken", true},
+		{"X-Auth-Token", true},
+		{"Client_Secret", true},
+		{"APIKey", true},
+		{"credentials", true},
+		{"token_expires", false},
+		{"tokenExpiry", false},
+		{"secretary", false},
+		{"passcode_length", false},
+		{"operation_id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSensitiveLogKey(tt.key))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// RateLimitConfig limits the outbound requests of one provider
+type RateLimitConfig = types.RateLimitConfig
+
+// TokenBucket is a token bucket rate limiter refilling rate tokens per second
+// up to burst. It is safe for concurrent use; a nil bucket is unlimited.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewTokenBucket creates a full bucket, or nil when rate is not positive.
+// burst defaults to 1.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// SetClock replaces the bucket's time source, for tests
+func (b *TokenBucket) SetClock(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// refill tops up tokens for the time elapsed since the last call; callers hold mu
+func (b *TokenBucket) refill() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// TryTake takes a token if one is available
+func (b *TokenBucket) TryTake() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Reserve takes a token, possibly going into debt so that later callers
+// queue behind this one, and returns how long the caller must wait before
+// using it
+func (b *TokenBucket) Reserve() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Unreserve returns a token taken by Reserve that was not used
+func (b *TokenBucket) Unreserve() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// RateLimiter paces the requests of one provider with a TokenBucket. It is
+// safe for concurrent use. Its bucket is not shared with the client's
+// priority lanes, which take their own tokens per call.
+type RateLimiter struct {
+	bucket   *TokenBucket
+	failFast bool
+	provider string
+}
+
+// NewRateLimiter creates a limiter for provider that starts with a full
+// bucket
+func NewRateLimiter(config RateLimitConfig, provider string) *RateLimiter {
+	return &RateLimiter{
+		bucket:   NewTokenBucket(config.Rate, config.Burst),
+		failFast: config.FailFast,
+		provider: provider,
+	}
+}
+
+// Wait takes a token, blocking until one is available. It fails at once
+// with RATE_LIMITED in fail-fast mode, or when the token would only become
+// available after ctx's deadline; that error also matches
+// context.DeadlineExceeded. Cancelling ctx while waiting returns the token.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait := l.bucket.Reserve()
+	if wait <= 0 {
+		return nil
+	}
+	if l.failFast {
+		l.bucket.Unreserve()
+		return l.limitedError(wait, nil)
+	}
+	if deadline, ok := ctx.Deadline(); ok && wait > time.Until(deadline) {
+		l.bucket.Unreserve()
+		return l.limitedError(wait, context.DeadlineExceeded)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.bucket.Unreserve()
+		return ctx.Err()
+	}
+}
+
+// limitedError is the RATE_LIMITED error for a token available after wait
+func (l *RateLimiter) limitedError(wait time.Duration, cause error) error {
+	err := types.NewPaymentError(
+		types.ErrorCodeRateLimited,
+		fmt.Sprintf("rate limit reached, next request allowed in %s", wait.Round(time.Millisecond)),
+		l.provider,
+		false,
+	).WithDetail("retry_after", wait.String())
+	if cause != nil {
+		err.WithCause(cause)
+	}
+	return err
+}
+
+// rateLimitedHTTPClient waits for the limiter before every request
+type rateLimitedHTTPClient struct {
+	client  HTTPClient
+	limiter *RateLimiter
+}
+
+// RateLimitHTTPClient wraps client so that every request of provider waits
+// for a token of a limiter built from config; it returns client unchanged
+// when config is nil
+func RateLimitHTTPClient(client HTTPClient, config *RateLimitConfig, provider string) (HTTPClient, error) {
+	if config == nil {
+		return client, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &rateLimitedHTTPClient{client: client, limiter: NewRateLimiter(*config, provider)}, nil
+}
+
+// Do waits for the limiter, then executes request with the wrapped client
+func (c *rateLimitedHTTPClient) Do(ctx context.Context, request *HTTPRequest) (*HTTPResponse, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.Do(ctx, request)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped client
+func (c *rateLimitedHTTPClient) CloseIdleConnections() {
+	CloseIdleConnections(c.client)
+}
@@ -0,0 +1,150 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+func requireRateLimited(t *testing.T, err error) *types.PaymentError {
+	t.Helper()
+	var paymentErr *types.PaymentError
+	if !errors.As(err, &paymentErr) || paymentErr.Code != types.ErrorCodeRateLimited {
+		t.Fatalf("expected RATE_LIMITED, got %v", err)
+	}
+	return paymentErr
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 5, Burst: 2, FailFast: true}, "bpay")
+	limiter.bucket.SetClock(func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("request %d within the burst: %v", i+1, err)
+		}
+	}
+	paymentErr := requireRateLimited(t, limiter.Wait(context.Background()))
+	if paymentErr.Provider != "bpay" || paymentErr.Details["retry_after"] != "200ms" {
+		t.Errorf("error = %v, details %v", paymentErr, paymentErr.Details)
+	}
+	if paymentErr.IsRetryable() {
+		t.Error("fail-fast errors must not be retried by the provider")
+	}
+
+	now = now.Add(200 * time.Millisecond)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("after refilling one token: %v", err)
+	}
+}
+
+func TestRateLimiterRespectsContextDeadline(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 5}, "bpay")
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next token is 200ms away, past this deadline: fail without waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	requireRateLimited(t, err)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v should match context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Millisecond {
+		t.Errorf("waited %v for a token it could never get", elapsed)
+	}
+
+	// A cancelled wait gives its token back
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("deadline far enough: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > 350*time.Millisecond {
+		t.Errorf("token granted after %v, want about 200ms", elapsed)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewTokenBucket(2, 1)
+	b.SetClock(func() time.Time { return now })
+
+	if wait := b.Reserve(); wait != 0 {
+		t.Errorf("first Reserve waits %v", wait)
+	}
+	if wait := b.Reserve(); wait != 500*time.Millisecond {
+		t.Errorf("second Reserve waits %v, want 500ms", wait)
+	}
+	b.Unreserve()
+	if b.TryTake() {
+		t.Error("TryTake succeeded on an empty bucket")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !b.TryTake() {
+		t.Error("TryTake failed after refilling one token")
+	}
+
+	var unlimited *TokenBucket
+	if NewTokenBucket(0, 5) != nil || !unlimited.TryTake() || unlimited.Reserve() != 0 {
+		t.Error("a bucket without a rate must be unlimited")
+	}
+}
+
+func TestRateLimitHTTPClientIsPerProvider(t *testing.T) {
+	stub := &stubHTTPClient{resp: &HTTPResponse{StatusCode: 200}}
+	config := &RateLimitConfig{Rate: 1, FailFast: true}
+	bpay, err := RateLimitHTTPClient(stub, config, "bpay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	masrvi, err := RateLimitHTTPClient(stub, config, "masrvi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &HTTPRequest{Method: "GET", URL: "https://provider.test"}
+	for _, client := range []HTTPClient{bpay, masrvi} {
+		if _, err := client.Do(context.Background(), request); err != nil {
+			t.Fatalf("first request: %v", err)
+		}
+	}
+	_, err = bpay.Do(context.Background(), request)
+	if paymentErr := requireRateLimited(t, err); paymentErr.Provider != "bpay" {
+		t.Errorf("Provider = %q", paymentErr.Provider)
+	}
+
+	if got, _ := RateLimitHTTPClient(stub, nil, "bpay"); got != HTTPClient(stub) {
+		t.Error("nil config must return the client unchanged")
+	}
+	if _, err := RateLimitHTTPClient(stub, &RateLimitConfig{}, "bpay"); err == nil {
+		t.Error("expected a zero rate to be rejected")
+	}
+}
+
+func TestAsTransportErrorFindsRateLimit(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 1, FailFast: true}, "")
+	_ = limiter.Wait(context.Background())
+	if _, ok := AsTransportError(errors.New("connection reset"), "bpay"); ok {
+		t.Error("found a transport error in an unrelated error")
+	}
+	wrapped := fmt.Errorf("failed to get access token: %w", limiter.Wait(context.Background()))
+	found, ok := AsTransportError(wrapped, "sedad")
+	if !ok || found.Code != types.ErrorCodeRateLimited || found.Provider != "sedad" {
+		t.Errorf("AsTransportError = %v, %v", found, ok)
+	}
+}
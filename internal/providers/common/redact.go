@@ -0,0 +1,91 @@
+package common
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces sensitive query parameter values
+const redactedValue = "REDACTED"
+
+var (
+	sensitiveParamsMu sync.RWMutex
+	// sensitiveQueryParams are masked in every URL that is logged or
+	// formatted into an error; matching ignores case
+	sensitiveQueryParams = []string{
+		"merchantid", "merchant_id", "password", "passcode", "token", "access_token",
+		"secret", "client_secret", "api_key", "apikey", "signature",
+	}
+)
+
+// RegisterSensitiveQueryParams adds query parameter names to be masked by
+// RedactURL and RedactError
+func RegisterSensitiveQueryParams(names ...string) {
+	sensitiveParamsMu.Lock()
+	defer sensitiveParamsMu.Unlock()
+	for _, name := range names {
+		if !isSensitiveQueryParam(name) {
+			sensitiveQueryParams = append(sensitiveQueryParams, name)
+		}
+	}
+}
+
+// SensitiveQueryParams returns the query parameter names currently masked
+func SensitiveQueryParams() []string {
+	sensitiveParamsMu.RLock()
+	defer sensitiveParamsMu.RUnlock()
+	return append([]string(nil), sensitiveQueryParams...)
+}
+
+// RedactURL masks the values of sensitive query parameters in raw, along with
+// any userinfo password. Unparseable URLs lose their whole query string.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if i := strings.IndexByte(raw, '?'); i >= 0 {
+			return raw[:i] + "?" + redactedValue
+		}
+		return raw
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	sensitiveParamsMu.RLock()
+	defer sensitiveParamsMu.RUnlock()
+	query := u.Query()
+	for key := range query {
+		if isSensitiveQueryParam(key) {
+			query[key] = []string{redactedValue}
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// RedactError masks sensitive query parameters in the URL of any *url.Error
+// in err's chain, which net/http includes verbatim in its error messages.
+// The error is modified in place and returned for convenience.
+func RedactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return err
+}
+
+// isSensitiveQueryParam must be called with sensitiveParamsMu held
+func isSensitiveQueryParam(key string) bool {
+	for _, name := range sensitiveQueryParams {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
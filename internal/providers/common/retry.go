@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"math"
 	"math/rand"
 	"time"
@@ -9,31 +10,60 @@ import (
 	"github.com/CatoSystems/rim-pay/internal/types"
 )
 
-type RetryConfig struct {
-	MaxAttempts  int           `json:"max_attempts"`
-	InitialDelay time.Duration `json:"initial_delay"`
-	MaxDelay     time.Duration `json:"max_delay"`
-	Multiplier   float64       `json:"multiplier"`
-	EnableJitter bool          `json:"enable_jitter"`
-}
+// RetryConfig is the retry policy of a RetryExecutor
+type RetryConfig = types.RetryConfig
 
 // DefaultRetryConfig returns default retry configuration
 func DefaultRetryConfig() RetryConfig {
-	return RetryConfig{
-		MaxAttempts:  3,
-		InitialDelay: 1 * time.Second,
-		MaxDelay:     30 * time.Second,
-		Multiplier:   2.0,
-		EnableJitter: true,
+	return types.DefaultRetryConfig()
+}
+
+// ResolveRetryConfig picks the retry policy a provider should use: the
+// override when it has settings, otherwise DefaultRetryConfig with the
+// override's OnRetry
+func ResolveRetryConfig(override *RetryConfig) RetryConfig {
+	if override == nil {
+		return DefaultRetryConfig()
 	}
+	if override.IsZero() {
+		config := DefaultRetryConfig()
+		config.OnRetry = override.OnRetry
+		return config
+	}
+	return *override
+}
+
+// ValidateRetryConfig checks a provider's retry override; nil and overrides
+// without settings stand for the default policy and are valid
+func ValidateRetryConfig(override *RetryConfig) error {
+	if override == nil || override.IsZero() {
+		return nil
+	}
+	return override.Validate()
+}
+
+// NewProviderRetryExecutor creates the retry executor of provider from its
+// retry override, as resolved by ResolveRetryConfig, counting retries in
+// metrics and recording attempts with tracer; either may be nil
+func NewProviderRetryExecutor(provider string, override *RetryConfig, metrics types.MetricsCollector, tracer types.Tracer) *RetryExecutor {
+	return NewRetryExecutor(ResolveRetryConfig(override)).
+		WithMetrics(metrics, provider).WithTracer(tracer, provider)
 }
 
-// RetryablePaymentFunc represents a payment function that can be retried
-type RetryablePaymentFunc func() (*types.PaymentResponse, error)
+// RetryablePaymentFunc represents a payment function that can be retried;
+// ctx carries the span of the current attempt
+type RetryablePaymentFunc func(ctx context.Context) (*types.PaymentResponse, error)
 
 // RetryExecutor handles retry logic
 type RetryExecutor struct {
 	config RetryConfig
+
+	// metrics, when set, counts retries under provider
+	metrics  types.MetricsCollector
+	provider string
+
+	// tracer, when set, records a span per attempt
+	tracer types.Tracer
 }
 
 // NewRetryExecutor creates a new retry executor
@@ -43,30 +73,81 @@ func NewRetryExecutor(config RetryConfig) *RetryExecutor {
 	}
 }
 
-// ExecutePayment executes a payment function with retry logic
+// WithMetrics counts the executor's retries in metrics under provider; nil
+// disables counting
+func (re *RetryExecutor) WithMetrics(metrics types.MetricsCollector, provider string) *RetryExecutor {
+	re.metrics = metrics
+	re.provider = provider
+	return re
+}
+
+// WithTracer records a span for every attempt with tracer under provider;
+// nil disables tracing
+func (re *RetryExecutor) WithTracer(tracer types.Tracer, provider string) *RetryExecutor {
+	re.tracer = tracer
+	re.provider = provider
+	return re
+}
+
+// ExecutePayment executes a payment function with retry logic. When it gives
+// up, a PaymentError result carries the attempt history and retry policy for
+// PaymentError.SupportBundle.
 func (re *RetryExecutor) ExecutePayment(ctx context.Context, fn RetryablePaymentFunc) (*types.PaymentResponse, error) {
+	var resp *types.PaymentResponse
+	err := re.execute(ctx, func(ctx context.Context) (err error) {
+		resp, err = fn(ctx)
+		return err
+	})
+	if err != nil && err == ctx.Err() {
+		return nil, err
+	}
+	return resp, err
+}
+
+// ExecuteRefund executes a refund function with the same retry logic as
+// ExecutePayment
+func (re *RetryExecutor) ExecuteRefund(ctx context.Context, fn func(ctx context.Context) (*types.RefundResponse, error)) (*types.RefundResponse, error) {
+	var resp *types.RefundResponse
+	err := re.execute(ctx, func(ctx context.Context) (err error) {
+		resp, err = fn(ctx)
+		return err
+	})
+	if err != nil && err == ctx.Err() {
+		return nil, err
+	}
+	return resp, err
+}
+
+// execute calls fn until it succeeds, fails with a non-retryable
+// PaymentError, runs out of attempts or ctx is done
+func (re *RetryExecutor) execute(ctx context.Context, fn func(ctx context.Context) error) error {
 	var lastErr error
-	var lastResp *types.PaymentResponse
+	var attempts []types.AttemptRecord
+	var totalDelay time.Duration
 
 	for attempt := 1; attempt <= re.config.MaxAttempts; attempt++ {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
-		resp, err := fn()
+		start := time.Now()
+		attemptCtx, span := StartSpan(ctx, re.tracer, "rimpay.attempt", AttrProvider, re.provider, AttrAttempt, attempt)
+		err := fn(attemptCtx)
+		EndSpan(span, err)
 		if err == nil {
-			return resp, nil
+			return nil
 		}
+		attempts = append(attempts, newAttemptRecord(attempt, time.Since(start), err))
 
 		lastErr = err
-		lastResp = resp
 
 		// Check if error is retryable
 		if paymentErr, ok := err.(*types.PaymentError); ok {
 			if !paymentErr.IsRetryable() {
-				return lastResp, err
+				re.annotate(err, attempts, totalDelay)
+				return err
 			}
 		}
 
@@ -76,14 +157,63 @@ func (re *RetryExecutor) ExecutePayment(ctx context.Context, fn RetryablePayment
 		}
 
 		delay := re.calculateDelay(attempt)
+		attempts[len(attempts)-1].Delay = delay
+		totalDelay += delay
+		if re.config.OnRetry != nil {
+			re.config.OnRetry(attempt, err, delay)
+		}
+		if re.metrics != nil {
+			re.metrics.IncRetry(re.provider)
+		}
+
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		case <-time.After(delay):
 		}
 	}
 
-	return lastResp, lastErr
+	re.annotate(lastErr, attempts, totalDelay)
+	return lastErr
+}
+
+// annotate attaches the attempt history, totals and retry policy to a
+// PaymentError
+func (re *RetryExecutor) annotate(err error, attempts []types.AttemptRecord, totalDelay time.Duration) {
+	var paymentErr *types.PaymentError
+	if !errors.As(err, &paymentErr) {
+		return
+	}
+	paymentErr.WithDetail(types.DetailAttempts, attempts)
+	paymentErr.WithDetail(types.DetailAttemptCount, len(attempts))
+	paymentErr.WithDetail(types.DetailTotalRetryDelay, totalDelay.Milliseconds())
+	paymentErr.WithDetail(types.DetailRetryPolicy, map[string]interface{}{
+		"max_attempts":  re.config.MaxAttempts,
+		"initial_delay": re.config.InitialDelay.String(),
+		"max_delay":     re.config.MaxDelay.String(),
+		"multiplier":    re.config.Multiplier,
+		"jitter":        re.config.EnableJitter,
+	})
+}
+
+// newAttemptRecord summarises a failed attempt
+func newAttemptRecord(attempt int, duration time.Duration, err error) types.AttemptRecord {
+	record := types.AttemptRecord{
+		Attempt:    attempt,
+		Duration:   duration,
+		ErrorClass: "error",
+		Error:      err.Error(),
+	}
+
+	var paymentErr *types.PaymentError
+	if errors.As(err, &paymentErr) {
+		record.ErrorClass = string(paymentErr.Code)
+		record.Error = paymentErr.Message
+		if status, ok := paymentErr.Details[types.DetailHTTPStatus].(int); ok {
+			record.HTTPStatus = status
+		}
+	}
+	return record
 }
 
 // calculateDelay calculates the delay for the next retry attempt
@@ -97,7 +227,7 @@ func (re *RetryExecutor) calculateDelay(attempt int) time.Duration {
 	}
 
 	// Apply jitter if enabled
-	if re.config.EnableJitter && delay > 0 {
+	if re.config.EnableJitter && delay > 1 {
 		jitter := time.Duration(rand.Int63n(int64(delay / 2)))
 		delay = delay/2 + jitter
 	}
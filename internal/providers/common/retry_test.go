@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -29,7 +30,7 @@ func testSuccessOnFirstAttempt(t *testing.T) {
 	executor := NewRetryExecutor(config)
 	attemptCount := 0
 
-	mockFunc := func() (*types.PaymentResponse, error) {
+	mockFunc := func(context.Context) (*types.PaymentResponse, error) {
 		attemptCount++
 		amount := money.New(decimal.NewFromInt(1000), "MRU")
 		return &types.PaymentResponse{
@@ -68,7 +69,7 @@ func testSuccessAfterRetries(t *testing.T) {
 	executor := NewRetryExecutor(config)
 	attemptCount := 0
 
-	mockFunc := func() (*types.PaymentResponse, error) {
+	mockFunc := func(context.Context) (*types.PaymentResponse, error) {
 		attemptCount++
 		if attemptCount == 3 {
 			amount := money.New(decimal.NewFromInt(1000), "MRU")
@@ -115,7 +116,7 @@ func testNonRetryableErrorFailsImmediately(t *testing.T) {
 	executor := NewRetryExecutor(config)
 	attemptCount := 0
 
-	mockFunc := func() (*types.PaymentResponse, error) {
+	mockFunc := func(context.Context) (*types.PaymentResponse, error) {
 		attemptCount++
 		return nil, types.NewPaymentError(
 			types.ErrorCodeNetworkError,
@@ -147,7 +148,7 @@ func TestRetryExecutorContextCancellation(t *testing.T) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	mockFunc := func() (*types.PaymentResponse, error) {
+	mockFunc := func(context.Context) (*types.PaymentResponse, error) {
 		return nil, types.NewPaymentError(
 			types.ErrorCodeNetworkError,
 			networkErrorMsg,
@@ -189,3 +190,129 @@ func TestDefaultRetryConfig(t *testing.T) {
 		t.Error("Expected EnableJitter=true")
 	}
 }
+
+func TestResolveRetryConfig(t *testing.T) {
+	if got := ResolveRetryConfig(nil); got.MaxAttempts != 3 || got.OnRetry != nil {
+		t.Errorf("nil override = %+v, want DefaultRetryConfig", got)
+	}
+
+	calls := 0
+	hookOnly := &RetryConfig{OnRetry: func(int, error, time.Duration) { calls++ }}
+	got := ResolveRetryConfig(hookOnly)
+	if got.MaxAttempts != 3 || got.Multiplier != 2.0 || got.OnRetry == nil {
+		t.Errorf("hook-only override = %+v, want DefaultRetryConfig with the hook", got)
+	}
+	got.OnRetry(1, errors.New("boom"), time.Second)
+	if calls != 1 {
+		t.Errorf("OnRetry calls = %d, want 1", calls)
+	}
+	if err := ValidateRetryConfig(hookOnly); err != nil {
+		t.Errorf("ValidateRetryConfig(hook only) = %v, want nil", err)
+	}
+
+	override := &RetryConfig{MaxAttempts: 1, Multiplier: 1}
+	if got := ResolveRetryConfig(override); got.MaxAttempts != 1 {
+		t.Errorf("override = %+v, want MaxAttempts 1", got)
+	}
+	if err := ValidateRetryConfig(&RetryConfig{MaxAttempts: 2}); err == nil {
+		t.Error("ValidateRetryConfig must reject a multiplier below 1")
+	}
+}
+
+func TestRetryExecutorExecuteRefund(t *testing.T) {
+	executor := NewRetryExecutor(RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     10 * time.Millisecond,
+		Multiplier:   2.0,
+	})
+	attemptCount := 0
+
+	resp, err := executor.ExecuteRefund(context.Background(), func(context.Context) (*types.RefundResponse, error) {
+		attemptCount++
+		if attemptCount < 2 {
+			return nil, types.NewPaymentError(types.ErrorCodeNetworkError, networkErrorMsg, "test", true)
+		}
+		return &types.RefundResponse{RefundID: "RF-1", Status: types.PaymentStatusSuccess}, nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if attemptCount != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attemptCount)
+	}
+	if resp == nil || resp.RefundID != "RF-1" {
+		t.Errorf("Expected refund RF-1, got %+v", resp)
+	}
+}
+
+func TestRetryExecutorOnRetryAndAttemptDetails(t *testing.T) {
+	type retryCall struct {
+		attempt   int
+		nextDelay time.Duration
+	}
+	var calls []retryCall
+
+	executor := NewRetryExecutor(RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: 2 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2.0,
+		OnRetry: func(attempt int, err error, nextDelay time.Duration) {
+			if err == nil {
+				t.Error("OnRetry called without an error")
+			}
+			calls = append(calls, retryCall{attempt, nextDelay})
+		},
+	})
+
+	_, err := executor.ExecutePayment(context.Background(), func(context.Context) (*types.PaymentResponse, error) {
+		return nil, types.NewPaymentError(types.ErrorCodeNetworkError, networkErrorMsg, "test", true)
+	})
+
+	want := []retryCall{{1, 2 * time.Millisecond}, {2, 4 * time.Millisecond}}
+	if len(calls) != len(want) {
+		t.Fatalf("OnRetry calls = %v, want %v (none after the final attempt)", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("OnRetry call %d = %v, want %v", i, calls[i], want[i])
+		}
+	}
+
+	paymentErr, ok := err.(*types.PaymentError)
+	if !ok {
+		t.Fatalf("expected *PaymentError, got %T", err)
+	}
+	if got := paymentErr.Details[types.DetailAttemptCount]; got != 3 {
+		t.Errorf("attempt_count = %v, want 3", got)
+	}
+	if got := paymentErr.Details[types.DetailTotalRetryDelay]; got != int64(6) {
+		t.Errorf("total_retry_delay_ms = %v, want 6", got)
+	}
+	attempts := paymentErr.Details[types.DetailAttempts].([]types.AttemptRecord)
+	if attempts[0].ErrorClass != string(types.ErrorCodeNetworkError) || attempts[1].Delay != 4*time.Millisecond || attempts[2].Delay != 0 {
+		t.Errorf("unexpected attempt history: %+v", attempts)
+	}
+}
+
+func TestRetryExecutorOnRetrySkipsNonRetryable(t *testing.T) {
+	called := false
+	executor := NewRetryExecutor(RetryConfig{
+		MaxAttempts: 3,
+		Multiplier:  1,
+		OnRetry:     func(int, error, time.Duration) { called = true },
+	})
+
+	_, err := executor.ExecutePayment(context.Background(), func(context.Context) (*types.PaymentResponse, error) {
+		return nil, types.NewPaymentError(types.ErrorCodeInvalidRequest, "bad request", "test", false)
+	})
+
+	if called {
+		t.Error("OnRetry must not be called for non-retryable errors")
+	}
+	if got := err.(*types.PaymentError).Details[types.DetailAttemptCount]; got != 1 {
+		t.Errorf("attempt_count = %v, want 1", got)
+	}
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// classifyTLSError converts a TLS handshake or certificate verification
+// failure into a non-retryable PROVIDER_TLS_ERROR. Retrying cannot fix a bad
+// certificate chain, so these are reported as configuration errors with the
+// details needed to act on them. ok is false for any other error.
+func classifyTLSError(err error, host string) (*types.PaymentError, bool) {
+	var (
+		cert    *x509.Certificate
+		failure string
+		hint    string
+	)
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var systemRoots x509.SystemRootsError
+	var recordHeader tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		cert = unknownAuthority.Cert
+		failure = unknownAuthority.Error()
+		hint = "the certificate chain does not lead to a trusted root; the provider may be " +
+			"missing an intermediate certificate, or its CA must be added to the trust store"
+	case errors.As(err, &invalid):
+		cert = invalid.Cert
+		failure = invalid.Error()
+		if invalid.Reason == x509.Expired {
+			hint = "the provider's certificate has expired or is not yet valid; check the " +
+				"provider's renewal and the system clock"
+		} else {
+			hint = "the provider's certificate is not valid for server authentication"
+		}
+	case errors.As(err, &hostname):
+		cert = hostname.Certificate
+		failure = hostname.Error()
+		hint = "the certificate does not cover this host; check the provider base URL"
+	case errors.As(err, &systemRoots):
+		failure = systemRoots.Error()
+		hint = "no system root certificates are available; install a CA bundle"
+	case errors.As(err, &recordHeader):
+		failure = recordHeader.Error()
+		hint = "the server did not answer with TLS; check the provider base URL scheme and port"
+	case err != nil && strings.Contains(err.Error(), "tls: "):
+		failure = err.Error()
+		hint = "the TLS handshake failed; check the provider's TLS configuration"
+	default:
+		return nil, false
+	}
+
+	message := fmt.Sprintf("TLS certificate verification failed for %s: %s", host, failure)
+	paymentErr := types.NewPaymentError(types.ErrorCodeProviderTLSError, message, "", false).
+		WithCause(err).
+		WithDetail("host", host).
+		WithDetail("tls_failure", failure).
+		WithDetail("hint", hint)
+
+	if cert != nil {
+		paymentErr.WithDetail("certificate_subject", cert.Subject.String()).
+			WithDetail("certificate_issuer", cert.Issuer.String()).
+			WithDetail("certificate_expiry", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return paymentErr, true
+}
+
+// AsTLSError finds a PROVIDER_TLS_ERROR in err's chain and returns a copy
+// attributed to provider. Providers use it to keep TLS failures visible
+// instead of folding them into generic network or authentication errors.
+func AsTLSError(err error, provider string) (*types.PaymentError, bool) {
+	return findCodedError(err, provider, types.ErrorCodeProviderTLSError)
+}
+
+// AsTransportError is AsTLSError for every error the HTTP layer raises
+// itself: PROVIDER_TLS_ERROR and the RATE_LIMITED of a provider rate limit
+func AsTransportError(err error, provider string) (*types.PaymentError, bool) {
+	return findCodedError(err, provider, types.ErrorCodeProviderTLSError, types.ErrorCodeRateLimited)
+}
+
+// findCodedError returns a copy, attributed to provider, of the first
+// PaymentError in err's chain with one of codes
+func findCodedError(err error, provider string, codes ...types.ErrorCode) (*types.PaymentError, bool) {
+	// Walk the chain by hand: errors.As would stop at an outer PaymentError
+	// that merely wraps the failure
+	for ; err != nil; err = errors.Unwrap(err) {
+		paymentErr, ok := err.(*types.PaymentError)
+		if !ok {
+			continue
+		}
+		for _, code := range codes {
+			if paymentErr.Code == code {
+				found := *paymentErr
+				found.Provider = provider
+				if paymentErr.Details != nil {
+					found.Details = make(map[string]interface{}, len(paymentErr.Details))
+					for key, value := range paymentErr.Details {
+						found.Details[key] = value
+					}
+				}
+				return &found, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// TLSConfig configures certificate verification and client certificates
+type TLSConfig = types.TLSConfig
+
+// newTLSConfig builds the client TLS configuration for config, loading its
+// CA bundle and client certificate. A nil config requires TLS 1.2 and
+// verifies against the system roots.
+func newTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	minVersion, err := config.MinTLSVersion()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{MinVersion: minVersion}
+	if config == nil {
+		return tlsConfig, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig.InsecureSkipVerify = config.InsecureSkipVerify
+
+	caPEM, err := pemSource(config.CAFile, config.CAPEM, "ca_file")
+	if err != nil {
+		return nil, err
+	}
+	if len(caPEM) > 0 {
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("tls CA bundle contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	certPEM, err := pemSource(config.CertFile, config.CertPEM, "cert_file")
+	if err != nil {
+		return nil, err
+	}
+	if len(certPEM) > 0 {
+		keyPEM, err := pemSource(config.KeyFile, config.KeyPEM, "key_file")
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// pemSource returns the contents of file, or inline when no file is set
+func pemSource(file, inline, key string) ([]byte, error) {
+	if file == "" {
+		return []byte(inline), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading tls %s: %w", key, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,353 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+func requireTLSError(t *testing.T, err error) *types.PaymentError {
+	t.Helper()
+	var paymentErr *types.PaymentError
+	if !errors.As(err, &paymentErr) {
+		t.Fatalf("expected *PaymentError, got %T: %v", err, err)
+	}
+	if paymentErr.Code != types.ErrorCodeProviderTLSError {
+		t.Fatalf("expected %s, got %s", types.ErrorCodeProviderTLSError, paymentErr.Code)
+	}
+	if paymentErr.IsRetryable() {
+		t.Error("TLS errors must not be retryable")
+	}
+	return paymentErr
+}
+
+func TestDoClassifiesUntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := newTestHTTPClient(t, DefaultHTTPConfig(5*time.Second))
+	_, err := client.Do(context.Background(), &HTTPRequest{Method: "GET", URL: server.URL})
+
+	paymentErr := requireTLSError(t, err)
+	if !strings.Contains(paymentErr.Message, "unknown authority") {
+		t.Errorf("message should name the x509 failure, got %q", paymentErr.Message)
+	}
+	if subject, _ := paymentErr.Details["certificate_subject"].(string); subject == "" {
+		t.Error("expected certificate_subject detail")
+	}
+	if paymentErr.Details["certificate_expiry"] == nil {
+		t.Error("expected certificate_expiry detail")
+	}
+	if !strings.Contains(paymentErr.Details["hint"].(string), "intermediate") {
+		t.Errorf("unexpected hint %q", paymentErr.Details["hint"])
+	}
+}
+
+func TestDoRedactsTLSErrorCause(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := newTestHTTPClient(t, DefaultHTTPConfig(5*time.Second))
+	_, err := client.Do(context.Background(), &HTTPRequest{
+		Method: "GET",
+		URL:    server.URL + "/online/status.php?merchantid=MERCHANT-SECRET&purchaseref=ORDER-1",
+	})
+
+	paymentErr := requireTLSError(t, err)
+	if paymentErr.Cause == nil {
+		t.Fatal("expected the transport error as cause")
+	}
+	bundle, bundleErr := paymentErr.SupportBundle()
+	if bundleErr != nil {
+		t.Fatalf("SupportBundle failed: %v", bundleErr)
+	}
+	for _, text := range []string{paymentErr.Cause.Error(), paymentErr.Error(), string(bundle)} {
+		if strings.Contains(text, "MERCHANT-SECRET") {
+			t.Errorf("merchant ID leaked: %s", text)
+		}
+	}
+	if !strings.Contains(paymentErr.Cause.Error(), "purchaseref=ORDER-1") {
+		t.Errorf("cause should keep harmless parameters, got %q", paymentErr.Cause.Error())
+	}
+}
+
+func TestDoClassifiesExpiredCertificate(t *testing.T) {
+	notAfter := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cert, leaf := selfSignedCert(t, "expired.provider.test", notAfter.Add(-24*time.Hour), notAfter)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	// Trust the certificate so that expiry is the only failure
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	client := &DefaultHTTPClient{client: &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}}
+
+	_, err := client.Do(context.Background(), &HTTPRequest{Method: "GET", URL: server.URL})
+
+	paymentErr := requireTLSError(t, err)
+	if !strings.Contains(paymentErr.Message, "expired") {
+		t.Errorf("message should mention expiry, got %q", paymentErr.Message)
+	}
+	if got := paymentErr.Details["certificate_subject"]; got != "CN=expired.provider.test" {
+		t.Errorf("certificate_subject = %v", got)
+	}
+	if got := paymentErr.Details["certificate_expiry"]; got != "2020-01-02T03:04:05Z" {
+		t.Errorf("certificate_expiry = %v", got)
+	}
+}
+
+func TestDoLeavesOtherErrorsUnclassified(t *testing.T) {
+	client := newTestHTTPClient(t, DefaultHTTPConfig(time.Second))
+	_, err := client.Do(context.Background(), &HTTPRequest{Method: "GET", URL: "http://127.0.0.1:1"})
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+	if _, ok := AsTLSError(err, "bpay"); ok {
+		t.Errorf("connection refused should not be a TLS error: %v", err)
+	}
+}
+
+func TestAsTLSErrorAttributesProvider(t *testing.T) {
+	original := types.NewPaymentError(types.ErrorCodeProviderTLSError, "bad cert", "", false)
+	wrapped := fmt.Errorf("failed to create session: %w", original)
+
+	tlsErr, ok := AsTLSError(wrapped, "masrvi")
+	if !ok {
+		t.Fatal("expected TLS error to be found in chain")
+	}
+	if tlsErr.Provider != "masrvi" {
+		t.Errorf("Provider = %q", tlsErr.Provider)
+	}
+	if original.Provider != "" {
+		t.Error("AsTLSError must not modify the original error")
+	}
+
+	original.WithDetail("host", "masrvi.test")
+	tlsErr, _ = AsTLSError(wrapped, "masrvi")
+	tlsErr.WithDetail("hint", "changed")
+	if _, shared := original.Details["hint"]; shared {
+		t.Error("AsTLSError must not share Details with the original error")
+	}
+}
+
+func selfSignedCert(t *testing.T, commonName string, notBefore, notAfter time.Time) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+// testPKI is a private CA with a server and a client certificate it issued
+type testPKI struct {
+	caPEM                       []byte
+	serverCert                  tls.Certificate
+	clientCertPEM, clientKeyPEM []byte
+	roots                       *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "RimPay Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("issue certificate: %v", err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatalf("marshal key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	pki := &testPKI{caPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})}
+	serverCertPEM, serverKeyPEM := issue(2, "gateway.masrvi.test", x509.ExtKeyUsageServerAuth)
+	if pki.serverCert, err = tls.X509KeyPair(serverCertPEM, serverKeyPEM); err != nil {
+		t.Fatal(err)
+	}
+	pki.clientCertPEM, pki.clientKeyPEM = issue(3, "merchant-42", x509.ExtKeyUsageClientAuth)
+	pki.roots = x509.NewCertPool()
+	pki.roots.AddCert(ca)
+	return pki
+}
+
+// mutualTLSServer requires a client certificate issued by the test CA and
+// answers with its common name
+func mutualTLSServer(t *testing.T, pki *testPKI, maxVersion uint16) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.roots,
+		MaxVersion:   maxVersion,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	server := mutualTLSServer(t, pki, 0)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	for file, data := range map[string][]byte{caFile: pki.caPEM, certFile: pki.clientCertPEM, keyFile: pki.clientKeyPEM} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs := map[string]*TLSConfig{
+		"pem":   {CAPEM: string(pki.caPEM), CertPEM: string(pki.clientCertPEM), KeyPEM: string(pki.clientKeyPEM)},
+		"files": {CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+	}
+	for name, tlsConfig := range configs {
+		t.Run(name, func(t *testing.T) {
+			client := newTestHTTPClient(t, HTTPConfig{TLS: tlsConfig})
+			resp, err := client.Do(context.Background(), &HTTPRequest{Method: http.MethodGet, URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(resp.Body) != "merchant-42" {
+				t.Errorf("server saw client certificate %q", resp.Body)
+			}
+		})
+	}
+
+	// Trusting the CA is not enough without the client certificate
+	client := newTestHTTPClient(t, HTTPConfig{TLS: &TLSConfig{CAPEM: string(pki.caPEM)}})
+	if _, err := client.Do(context.Background(), &HTTPRequest{Method: http.MethodGet, URL: server.URL}); err == nil {
+		t.Error("expected the handshake to fail without a client certificate")
+	}
+}
+
+func TestNewHTTPClientTLSVersionAndVerification(t *testing.T) {
+	pki := newTestPKI(t)
+	tls12 := mutualTLSServer(t, pki, tls.VersionTLS12)
+	withCert := TLSConfig{CAPEM: string(pki.caPEM), CertPEM: string(pki.clientCertPEM), KeyPEM: string(pki.clientKeyPEM)}
+
+	client := newTestHTTPClient(t, HTTPConfig{TLS: &withCert})
+	if _, err := client.Do(context.Background(), &HTTPRequest{Method: http.MethodGet, URL: tls12.URL}); err != nil {
+		t.Errorf("TLS 1.2 server with the default minimum: %v", err)
+	}
+
+	tls13 := withCert
+	tls13.MinVersion = "1.3"
+	client = newTestHTTPClient(t, HTTPConfig{TLS: &tls13})
+	_, err := client.Do(context.Background(), &HTTPRequest{Method: http.MethodGet, URL: tls12.URL})
+	requireTLSError(t, err)
+
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+	client = newTestHTTPClient(t, HTTPConfig{TLS: &TLSConfig{InsecureSkipVerify: true}})
+	if _, err := client.Do(context.Background(), &HTTPRequest{Method: http.MethodGet, URL: untrusted.URL}); err != nil {
+		t.Errorf("InsecureSkipVerify: %v", err)
+	}
+
+	client = newTestHTTPClient(t, HTTPConfig{})
+	transport := client.client.Transport.(*http.Transport)
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("default MinVersion = %x, want TLS 1.2", transport.TLSClientConfig.MinVersion)
+	}
+}
+
+func TestNewHTTPClientRejectsInvalidTLSConfig(t *testing.T) {
+	pki := newTestPKI(t)
+	tests := map[string]TLSConfig{
+		"certificate without key": {CertPEM: string(pki.clientCertPEM)},
+		"key without certificate": {KeyFile: "client.key"},
+		"unknown min version":     {MinVersion: "1.1"},
+		"ca file and pem":         {CAFile: "ca.pem", CAPEM: string(pki.caPEM)},
+		"missing ca file":         {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"ca without certificates": {CAPEM: "not a certificate"},
+		"mismatched key":          {CertPEM: string(pki.clientCertPEM), KeyPEM: string(pki.caPEM)},
+	}
+	for name, tlsConfig := range tests {
+		tlsConfig := tlsConfig
+		if _, err := NewHTTPClient(HTTPConfig{TLS: &tlsConfig}); err == nil {
+			t.Errorf("%s: NewHTTPClient succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// Span attribute keys
+const (
+	AttrProvider       = "rimpay.provider"
+	AttrReference      = "rimpay.reference"
+	AttrAmount         = "rimpay.amount"
+	AttrTransactionID  = "rimpay.transaction_id"
+	AttrOperationType  = "rimpay.operation_type"
+	AttrStatus         = "rimpay.status"
+	AttrErrorCode      = "rimpay.error_code"
+	AttrAttempt        = "rimpay.attempt"
+	AttrCorrelationID  = "rimpay.correlation_id"
+	AttrHTTPMethod     = "http.method"
+	AttrHTTPPath       = "http.path"
+	AttrHTTPStatusCode = "http.status_code"
+)
+
+// StartSpan starts a span named name with tracer and sets attributes from
+// key-value pairs, like logger fields. Attributes whose key names a
+// credential, passcode or token are dropped. With a nil tracer it returns
+// ctx and a span that does nothing.
+func StartSpan(ctx context.Context, tracer types.Tracer, name string, attributes ...interface{}) (context.Context, types.Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, name)
+	safe := safeSpan{span}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if key, ok := attributes[i].(string); ok {
+			safe.SetAttribute(key, attributes[i+1])
+		}
+	}
+	return ctx, safe
+}
+
+// EndSpan ends span, first recording err and its error code, if any
+func EndSpan(span types.Span, err error) {
+	if err != nil {
+		var paymentErr *types.PaymentError
+		if errors.As(err, &paymentErr) {
+			span.SetAttribute(AttrErrorCode, string(paymentErr.Code))
+		}
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// safeSpan keeps sensitive attributes off a span
+type safeSpan struct {
+	types.Span
+}
+
+func (s safeSpan) SetAttribute(key string, value interface{}) {
+	if isSensitiveCaptureKey(key) {
+		return
+	}
+	s.Span.SetAttribute(key, value)
+}
+
+// noopSpan is returned when tracing is disabled
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
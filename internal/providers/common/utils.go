@@ -14,28 +14,72 @@ import (
 	"unicode"
 )
 
-func GenerateTransactionID(prefix string) string {
-	timestamp := time.Now().Unix()
-	randomBytes := make([]byte, 6)
-	rand.Read(randomBytes)
-	randomHex := hex.EncodeToString(randomBytes)
+// IDFormat is the shape of a generated ID: Prefix, the Unix time and
+// RandomLength random hex digits, joined by underscores. Empty parts are
+// left out, so that IDs fit providers capping references at 20 characters.
+type IDFormat struct {
+	Prefix string
+	// RandomLength is the number of random hex digits; 0 means 12
+	RandomLength int
+	// NoTimestamp leaves out the Unix time
+	NoTimestamp bool
+	// Uppercase writes the random digits in upper case
+	Uppercase bool
+}
 
-	if prefix != "" {
-		return fmt.Sprintf("%s_%d_%s", prefix, timestamp, randomHex)
+// Default ID formats of GenerateTransactionID and GenerateReference
+var (
+	TransactionIDFormat = IDFormat{Prefix: "TXN", RandomLength: 12}
+	ReferenceFormat     = IDFormat{Prefix: "REF", RandomLength: 12, Uppercase: true}
+)
+
+func (f IDFormat) randomLength() int {
+	if f.RandomLength <= 0 {
+		return 12
 	}
-	return fmt.Sprintf("TXN_%d_%s", timestamp, randomHex)
+	return f.RandomLength
 }
 
-func GenerateReference(prefix string) string {
-	timestamp := time.Now().Unix()
-	randomBytes := make([]byte, 4)
-	rand.Read(randomBytes)
-	randomHex := strings.ToUpper(hex.EncodeToString(randomBytes))
+// Generate returns a new ID, drawing its random digits from crypto/rand
+func (f IDFormat) Generate() (string, error) {
+	n := f.randomLength()
+	randomBytes := make([]byte, (n+1)/2)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("generate ID: %w", err)
+	}
+	random := hex.EncodeToString(randomBytes)[:n]
+	if f.Uppercase {
+		random = strings.ToUpper(random)
+	}
 
+	parts := make([]string, 0, 3)
+	if f.Prefix != "" {
+		parts = append(parts, f.Prefix)
+	}
+	if !f.NoTimestamp {
+		parts = append(parts, strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	return strings.Join(append(parts, random), "_"), nil
+}
+
+// GenerateTransactionID returns a TransactionIDFormat ID, with prefix in
+// place of TXN when set
+func GenerateTransactionID(prefix string) (string, error) {
+	format := TransactionIDFormat
 	if prefix != "" {
-		return fmt.Sprintf("%s_%d_%s", prefix, timestamp, randomHex)
+		format.Prefix = prefix
 	}
-	return fmt.Sprintf("REF_%d_%s", timestamp, randomHex)
+	return format.Generate()
+}
+
+// GenerateReference returns a ReferenceFormat reference, with prefix in
+// place of REF when set
+func GenerateReference(prefix string) (string, error) {
+	format := ReferenceFormat
+	if prefix != "" {
+		format.Prefix = prefix
+	}
+	return format.Generate()
 }
 
 // SanitizeString removes or replaces potentially dangerous characters
@@ -125,7 +169,11 @@ func ParseDuration(duration string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
-// ParseAmount parses amount string to float64
+// ParseAmount parses amount string to float64. Commas, spaces and currency
+// symbols are dropped, so "$1,250" is 1250.
+//
+// Deprecated: float64 cannot represent money exactly, and commas are always
+// read as thousands separators. Use money.Parse.
 func ParseAmount(amountStr string) (float64, error) {
 	if amountStr == "" {
 		return 0, fmt.Errorf("amount string is empty")
@@ -156,11 +204,13 @@ func HashWithSalt(input, salt string) string {
 	return Hash(input + salt)
 }
 
-// GenerateSalt generates a random salt
-func GenerateSalt(length int) string {
+// GenerateSalt returns length random bytes from crypto/rand, base64 encoded
+func GenerateSalt(length int) (string, error) {
 	bytes := make([]byte, length)
-	rand.Read(bytes)
-	return base64.URLEncoding.EncodeToString(bytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
 // ToJSON converts interface to JSON string
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"encoding/base64"
+	"regexp"
+	"testing"
+)
+
+func assertUnique(t *testing.T, generate func() (string, error)) {
+	t.Helper()
+	seen := make(map[string]struct{}, 100000)
+	for i := 0; i < 100000; i++ {
+		id, err := generate()
+		if err != nil {
+			t.Fatalf("generate failed: %v", err)
+		}
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate ID %s after %d IDs", id, i)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestGenerateTransactionIDUnique(t *testing.T) {
+	assertUnique(t, func() (string, error) { return GenerateTransactionID("") })
+}
+
+func TestGenerateReferenceUnique(t *testing.T) {
+	assertUnique(t, func() (string, error) { return GenerateReference("ORD") })
+}
+
+func TestGenerateIDFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func() (string, error)
+		pattern  string
+	}{
+		{"transaction ID", func() (string, error) { return GenerateTransactionID("") }, `^TXN_\d+_[0-9a-f]{12}$`},
+		{"transaction ID prefix", func() (string, error) { return GenerateTransactionID("SCH") }, `^SCH_\d+_[0-9a-f]{12}$`},
+		{"reference", func() (string, error) { return GenerateReference("") }, `^REF_\d+_[0-9A-F]{12}$`},
+		{"odd length", IDFormat{Prefix: "R", RandomLength: 7}.Generate, `^R_\d+_[0-9a-f]{7}$`},
+		{"random only", IDFormat{RandomLength: 20, NoTimestamp: true, Uppercase: true}.Generate, `^[0-9A-F]{20}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.generate()
+			if err != nil {
+				t.Fatalf("generate failed: %v", err)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(id) {
+				t.Errorf("%q does not match %s", id, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestGenerateSalt(t *testing.T) {
+	salt, err := GenerateSalt(16)
+	if err != nil {
+		t.Fatalf("GenerateSalt failed: %v", err)
+	}
+	decoded, err := base64.URLEncoding.DecodeString(salt)
+	if err != nil || len(decoded) != 16 {
+		t.Errorf("salt %q decodes to %d bytes (%v), want 16", salt, len(decoded), err)
+	}
+
+	other, err := GenerateSalt(16)
+	if err != nil {
+		t.Fatalf("GenerateSalt failed: %v", err)
+	}
+	if salt == other {
+		t.Error("two salts are equal")
+	}
+}
+
+func TestParseAmountKeepsLegacyFormats(t *testing.T) {
+	tests := map[string]float64{
+		"$1,250":     1250,
+		"1 250.50":   1250.5,
+		"MRU 100":    100,
+		"€2,000,000": 2000000,
+		"12.5":       12.5,
+	}
+	for input, want := range tests {
+		got, err := ParseAmount(input)
+		if err != nil {
+			t.Errorf("ParseAmount(%q) failed: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseAmount(%q) = %v, want %v", input, got, want)
+		}
+	}
+	if _, err := ParseAmount(""); err == nil {
+		t.Error("ParseAmount(\"\") should fail")
+	}
+}
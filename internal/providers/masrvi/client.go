@@ -2,11 +2,10 @@ package masrvi
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
-	"github.com/CatoSystems/rim-pay/internal/types"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -39,21 +38,29 @@ func NewMasrviProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Pro
 		return nil, fmt.Errorf("invalid MASRVI configuration: %w", err)
 	}
 
-	// Create HTTP client
-	httpClient := common.NewHTTPClient(common.HTTPConfig{
-		Timeout:         config.Timeout,
-		MaxIdleConns:    10,
-		MaxConnsPerHost: 5,
-	})
+	logger = common.NewRedactingLogger(logger)
+
+	// Use the shared HTTP client when injected by the rimpay client
+	baseClient, err := common.ResolveHTTPClient(config.HTTPClient, config.HTTP, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MASRVI configuration: %w", err)
+	}
+	httpClient, err := common.RateLimitHTTPClient(
+		common.InstrumentHTTPClient(baseClient, config.Metrics, config.Tracer, "masrvi"), config.RateLimit, "masrvi")
+	if err != nil {
+		return nil, fmt.Errorf("invalid MASRVI configuration: %w", err)
+	}
 
 	// Create session manager
 	sessionManager := NewSessionManager(config, httpClient, logger)
+	if sessionManager.options.keepWarm {
+		sessionManager.startRefresher()
+	}
 
 	// Create payment processor
 	paymentProcessor := NewPaymentProcessor(config, httpClient, sessionManager, logger)
 
-	// Create retry executor with default config
-	retryExecutor := common.NewRetryExecutor(common.DefaultRetryConfig())
+	retryExecutor := common.NewProviderRetryExecutor("masrvi", config.Retry, config.Metrics, config.Tracer)
 
 	provider := &Provider{
 		name:             "masrvi",
@@ -75,14 +82,30 @@ func (p *Provider) Name() string {
 
 // IsAvailable checks if the provider is available
 func (p *Provider) IsAvailable(ctx context.Context) bool {
+	return p.CheckHealth(ctx) == nil
+}
+
+// CheckHealth reports why the provider is unavailable, keeping TLS
+// certificate failures distinct from other errors. Without a default
+// merchant, as merchant overrides allow, there is no session to try and the
+// provider is reported healthy.
+func (p *Provider) CheckHealth(ctx context.Context) error {
+	if p.paymentProcessor.options.requireMerchantOverride {
+		if _, err := p.config.Credential(ctx, p.name, rimpay.CredentialMerchantID); errors.Is(err, rimpay.ErrCredentialNotFound) {
+			return nil
+		}
+	}
 	_, err := p.sessionManager.GetSessionID(ctx)
-	return err == nil
+	if transportErr, ok := common.AsTransportError(err, p.name); ok {
+		return transportErr
+	}
+	return err
 }
 
 // ProcessMasrviPayment processes a MASRVI payment using provider-specific request
-func (p *Provider) ProcessMasrviPayment(ctx context.Context, request *rimpay.MasrviPaymentRequest) (*types.PaymentResponse, error) {
+func (p *Provider) ProcessMasrviPayment(ctx context.Context, request *rimpay.MasrviPaymentRequest) (*rimpay.PaymentResponse, error) {
 	if request == nil {
-		return nil, types.NewValidationError("request", "payment request cannot be nil")
+		return nil, rimpay.NewValidationError("request", "payment request cannot be nil")
 	}
 
 	if err := request.Validate(); err != nil {
@@ -96,9 +119,9 @@ func (p *Provider) ProcessMasrviPayment(ctx context.Context, request *rimpay.Mas
 }
 
 // ProcessPayment processes a payment with retry logic
-func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentRequest) (*types.PaymentResponse, error) {
+func (p *Provider) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
 	// Wrap the payment processing in a retryable function
-	retryablePayment := func() (*types.PaymentResponse, error) {
+	retryablePayment := func(ctx context.Context) (*rimpay.PaymentResponse, error) {
 		return p.paymentProcessor.ProcessPayment(ctx, request)
 	}
 
@@ -106,26 +129,46 @@ func (p *Provider) ProcessPayment(ctx context.Context, request *types.PaymentReq
 	return p.retryExecutor.ExecutePayment(ctx, retryablePayment)
 }
 
-// GetPaymentStatus retrieves payment status for MASRVI
-func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
-	if transactionID == "" {
-		return nil, types.NewValidationError("transactionID", "transaction ID cannot be empty")
+// Refund refunds all or part of a MASRVI payment with retry logic. It
+// returns ErrRefundNotSupported unless OptionRefundPath is set.
+func (p *Provider) Refund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	if p.paymentProcessor.options.refundPath == "" {
+		return nil, rimpay.ErrRefundNotSupported
 	}
+	return p.retryExecutor.ExecuteRefund(ctx, func(ctx context.Context) (*rimpay.RefundResponse, error) {
+		return p.paymentProcessor.ProcessRefund(ctx, request)
+	})
+}
 
-	// For now, return a basic status since we don't have the full implementation
-	status := &rimpay.TransactionStatus{
-		TransactionID: transactionID,
-		Status:        rimpay.PaymentStatusPending,
-		Reference:     transactionID,
-		Message:       "MASRVI payment status check",
-		LastUpdated:   time.Now(),
+// GetPaymentStatus queries MASRVI's transaction status endpoint. It returns
+// an error wrapping ErrStatusNotSupported when the status_path option is
+// empty or the endpoint does not exist.
+func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
+	if transactionID == "" {
+		return nil, rimpay.NewValidationError("transactionID", "transaction ID cannot be empty")
 	}
 
-	return status, nil
+	return p.paymentProcessor.CheckPaymentStatus(ctx, transactionID)
 }
 
-// HandleNotification processes MASRVI webhook notifications
+// HandleNotification processes MASRVI webhook notifications. With the
+// notification options set it first verifies the signature, timestamp and
+// sender, returning a *rimpay.NotificationRejectedError for notifications
+// that fail.
 func (p *Provider) HandleNotification(notification *rimpay.MasrviNotificationData) (*rimpay.TransactionStatus, error) {
+	notification, err := p.paymentProcessor.options.notifications.Verify(notification)
+	if err != nil {
+		if rejected, ok := err.(*rimpay.NotificationRejectedError); ok {
+			p.logger.Warn("MASRVI notification rejected", "reason", rejected.Reason, "error", rejected.Err)
+		}
+		return nil, err
+	}
+	if notification.Amount.IsZero() {
+		if notification.Amount, err = p.paymentProcessor.notificationAmount(notification); err != nil {
+			return nil, err
+		}
+	}
+
 	// Convert to internal notification format
 	internalNotification := &NotificationData{
 		Status:      notification.Status,
@@ -133,19 +176,59 @@ func (p *Provider) HandleNotification(notification *rimpay.MasrviNotificationDat
 		PurchaseRef: notification.Reference,
 		PaymentRef:  notification.TransactionID,
 		Timestamp:   notification.Timestamp,
+		ClientID:    notificationExtra(notification, "client_id"),
+		ClientName:  notificationExtra(notification, "customer_name"),
+		PayID:       notificationExtra(notification, "pay_id"),
+		IPAddress:   notificationExtra(notification, "ip_address"),
+		Error:       notificationExtra(notification, "error"),
+		Amount:      notification.Amount,
 	}
 
 	return p.paymentProcessor.HandleNotification(internalNotification)
 }
 
+// notificationExtra returns an optional notification parameter
+func notificationExtra(notification *rimpay.MasrviNotificationData, key string) string {
+	value, _ := notification.Data[key].(string)
+	return value
+}
+
+// Close stops the session refresher, discards cached session IDs and
+// closes idle connections
+func (p *Provider) Close() error {
+	p.sessionManager.stop()
+	p.sessionManager.Clear()
+	common.CloseIdleConnections(p.httpClient)
+	return nil
+}
+
 // ValidateConfig validates provider configuration
 func (p *Provider) ValidateConfig() error {
 	return validateConfig(p.config)
 }
 
+// Capabilities describes MASRVI: the customer pays on MASRVI's page, and
+// payments can be refunded and are reported by webhook. Status queries
+// need the status_path option.
+func (p *Provider) Capabilities() rimpay.Capabilities {
+	return rimpay.Capabilities{
+		SupportsRefund:      p.paymentProcessor.options.refundPath != "",
+		SupportsStatusQuery: p.paymentProcessor.options.statusPath != "",
+		SupportsWebhooks:    true,
+		RequiresRedirect:    true,
+	}
+}
+
 // validateConfig validates MASRVI configuration
 func validateConfig(config rimpay.ProviderConfig) error {
-	if config.Credentials["merchant_id"] == "" {
+	opts, err := parseOptions(config)
+	if err != nil {
+		return err
+	}
+
+	// Credentials from a CredentialsProvider are only read when needed, and
+	// payments name their merchant when overrides are required
+	if !config.HasCredentialsProvider() && !opts.requireMerchantOverride && config.Credentials[rimpay.CredentialMerchantID] == "" {
 		return fmt.Errorf("missing required credential: merchant_id")
 	}
 
@@ -157,5 +240,9 @@ func validateConfig(config rimpay.ProviderConfig) error {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if err := common.ValidateRetryConfig(config.Retry); err != nil {
+		return err
+	}
+
 	return nil
 }
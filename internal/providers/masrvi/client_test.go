@@ -65,6 +65,9 @@ func TestNotificationToPaymentStatus(t *testing.T) {
 	}{
 		{"Ok", rimpay.PaymentStatusSuccess},
 		{"NOK", rimpay.PaymentStatusFailed},
+		{" ok ", rimpay.PaymentStatusSuccess},
+		{"CANCEL", rimpay.PaymentStatusCancelled},
+		{"Annule", rimpay.PaymentStatusCancelled},
 		{"UNKNOWN", rimpay.PaymentStatusPending},
 	}
 
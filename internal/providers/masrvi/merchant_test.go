@@ -0,0 +1,154 @@
+package masrvi
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// merchantSessions answers each merchant with a session ID of its own
+type merchantSessions struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (s *merchantSessions) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	merchant := u.Query().Get("merchantid")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = make(map[string]int)
+	}
+	s.requests[merchant]++
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte("SESSION-" + merchant)}, nil
+}
+
+func merchantRequest(t *testing.T, merchantID string) *rimpay.MasrviPaymentRequest {
+	t.Helper()
+	phoneNumber, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &rimpay.MasrviPaymentRequest{
+		PhoneNumber: phoneNumber,
+		Amount:      money.NewMRU(10000),
+		Description: "Order",
+		Reference:   "ORDER-" + merchantID,
+		CallbackURL: "https://shop.test/callback",
+		ReturnURL:   "https://shop.test/return",
+		MerchantID:  merchantID,
+	}
+}
+
+func merchantPayment(t *testing.T, provider *Provider, merchantID string) url.Values {
+	t.Helper()
+	resp, err := provider.ProcessMasrviPayment(context.Background(), merchantRequest(t, merchantID))
+	require.NoError(t, err)
+	return resp.Metadata[rimpay.MetadataKeyFormData].(url.Values)
+}
+
+func TestMerchantOverridesKeepSeparateSessions(t *testing.T) {
+	server := &merchantSessions{}
+	provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		seller1 := merchantPayment(t, provider, "SELLER-1")
+		assert.Equal(t, "SELLER-1", seller1.Get("merchantid"))
+		assert.Equal(t, "SESSION-SELLER-1", seller1.Get("sessionid"))
+
+		seller2 := merchantPayment(t, provider, "SELLER-2")
+		assert.Equal(t, "SELLER-2", seller2.Get("merchantid"))
+		assert.Equal(t, "SESSION-SELLER-2", seller2.Get("sessionid"))
+
+		platform := merchantPayment(t, provider, "")
+		assert.Equal(t, "M1", platform.Get("merchantid"), "without an override the configured merchant is used")
+		assert.Equal(t, "SESSION-M1", platform.Get("sessionid"))
+	}
+	assert.Equal(t, map[string]int{"SELLER-1": 1, "SELLER-2": 1, "M1": 1}, server.requests, "each merchant's session is cached")
+
+	resp, err := provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:              money.NewMRU(10000),
+		Reference:           "ORDER-3",
+		CredentialOverrides: map[string]string{rimpay.CredentialMerchantID: "SELLER-3"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SELLER-3", resp.Metadata[rimpay.MetadataKeyFormData].(url.Values).Get("merchantid"))
+}
+
+func TestRequiredMerchantOverride(t *testing.T) {
+	server := &merchantSessions{}
+	config := optionsConfig(server, map[string]interface{}{OptionRequireMerchantOverride: true})
+	config.Credentials = nil
+	provider, err := NewMasrviProvider(config, nopLogger{})
+	require.NoError(t, err, "no default merchant_id is needed")
+	assert.NoError(t, provider.CheckHealth(context.Background()))
+
+	_, err = provider.ProcessMasrviPayment(context.Background(), merchantRequest(t, ""))
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, rimpay.ErrorCodeValidationError, paymentErr.Code)
+	assert.Empty(t, server.requests)
+
+	assert.Equal(t, "SELLER-1", merchantPayment(t, provider, "SELLER-1").Get("merchantid"))
+
+	_, err = NewMasrviProvider(optionsConfig(server, nil).WithMasrviOptions(rimpay.MasrviOptions{
+		RequireMerchantOverride: true,
+		KeepSessionWarm:         true,
+	}), nopLogger{})
+	assert.ErrorContains(t, err, OptionKeepSessionWarm)
+}
+
+func TestPaymentPageFieldsReachFormData(t *testing.T) {
+	provider, err := NewMasrviProvider(optionsConfig(&merchantSessions{}, map[string]interface{}{OptionBrandName: "Cato Shop"}), nopLogger{})
+	require.NoError(t, err)
+
+	request := merchantRequest(t, "")
+	request.Brand = "Cato Marketplace"
+	request.CustomerName = "Aïcha Mint Ahmed"
+	request.Text = "Retrait en boutique après paiement"
+	resp, err := provider.ProcessMasrviPayment(context.Background(), request)
+	require.NoError(t, err)
+	form := resp.Metadata[rimpay.MetadataKeyFormData].(url.Values)
+	assert.Equal(t, "Cato Marketplace", form.Get("brand"), "the request's brand wins")
+	assert.Equal(t, "Aïcha Mint Ahmed", form.Get("cname"))
+	assert.Equal(t, "Retrait en boutique après paiement", form.Get("text"))
+
+	form = merchantPayment(t, provider, "")
+	assert.Equal(t, "Cato Shop", form.Get("brand"), "the configured brand applies")
+	assert.NotContains(t, form, "cname")
+	assert.NotContains(t, form, "text")
+
+	resp, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:    money.NewMRU(10000),
+		Reference: "ORDER-4",
+		Metadata:  map[string]interface{}{rimpay.MasrviMetadataCustomerName: "Sidi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Sidi", resp.Metadata[rimpay.MetadataKeyFormData].(url.Values).Get("cname"))
+
+	// The caller's own metadata is not shown on the payment page
+	resp, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:    money.NewMRU(10000),
+		Reference: "ORDER-5",
+		Metadata: map[string]interface{}{
+			"brand": "Internal SKU brand", "customer_name": "crm-8812", "text": "fraud score 0.93",
+		},
+	})
+	require.NoError(t, err)
+	form = resp.Metadata[rimpay.MetadataKeyFormData].(url.Values)
+	assert.Equal(t, "Cato Shop", form.Get("brand"))
+	assert.NotContains(t, form, "cname")
+	assert.NotContains(t, form, "text")
+}
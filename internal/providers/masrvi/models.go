@@ -1,6 +1,11 @@
 package masrvi
 
-import "github.com/CatoSystems/rim-pay/pkg/rimpay"
+import (
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
 
 type SessionResponse struct {
 	SessionID string `json:"session_id"`
@@ -34,15 +39,43 @@ type NotificationData struct {
 	Timestamp   string `json:"timestamp"`
 	IPAddress   string `json:"ipaddr"`
 	Error       string `json:"error,omitempty"`
+
+	// Amount is what the customer paid, zero when not reported
+	Amount money.Money `json:"-"`
+}
+
+// raw returns the notification as its exported type
+func (n *NotificationData) raw() *rimpay.MasrviRawNotification {
+	return &rimpay.MasrviRawNotification{
+		Status:      n.Status,
+		ClientID:    n.ClientID,
+		ClientName:  n.ClientName,
+		Mobile:      n.Mobile,
+		PurchaseRef: n.PurchaseRef,
+		PaymentRef:  n.PaymentRef,
+		PayID:       n.PayID,
+		Timestamp:   n.Timestamp,
+		IPAddress:   n.IPAddress,
+		Error:       n.Error,
+		Amount:      n.Amount,
+	}
 }
 
 // ToPaymentStatus converts notification status to payment status
 func (nd *NotificationData) ToPaymentStatus() rimpay.PaymentStatus {
-	switch nd.Status {
-	case "Ok":
+	return convertStatus(nd.Status)
+}
+
+// convertStatus maps a MASRVI status string, as sent in notifications and
+// status responses, to a payment status; unknown values are pending
+func convertStatus(status string) rimpay.PaymentStatus {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "OK":
 		return rimpay.PaymentStatusSuccess
 	case "NOK":
 		return rimpay.PaymentStatusFailed
+	case "CANCEL", "CANCELLED", "ANNULE":
+		return rimpay.PaymentStatusCancelled
 	default:
 		return rimpay.PaymentStatusPending
 	}
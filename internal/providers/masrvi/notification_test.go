@@ -0,0 +1,195 @@
+package masrvi
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const notificationSecret = "shared-secret"
+
+var notificationNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newNotificationProvider(t *testing.T, opts map[string]interface{}) *Provider {
+	t.Helper()
+	provider, err := NewMasrviProvider(optionsConfig(&sessionServer{}, opts), nopLogger{})
+	require.NoError(t, err)
+	provider.paymentProcessor.options.notifications.Now = func() time.Time { return notificationNow }
+	return provider
+}
+
+// signedNotification parses params signed with the shared secret as they
+// would arrive from addr
+func signedNotification(t *testing.T, params url.Values, addr string) *rimpay.MasrviNotificationData {
+	t.Helper()
+	params.Set(rimpay.MasrviSignatureParam, rimpay.SignMasrviNotification(params, notificationSecret))
+	notification, err := rimpay.ParseMasrviParams(params)
+	require.NoError(t, err)
+	notification.RemoteAddr = addr
+	return notification
+}
+
+func okParams(at time.Time) url.Values {
+	return url.Values{
+		"status":      {"OK"},
+		"purchaseref": {"ORDER-1"},
+		"paymentref":  {"PAY-1"},
+		"payid":       {"42"},
+		"amount":      {"15050"},
+		"timestamp":   {strconv.FormatInt(at.Unix(), 10)},
+	}
+}
+
+func assertRejected(t *testing.T, err error, reason rimpay.NotificationRejectReason) {
+	t.Helper()
+	require.ErrorIs(t, err, rimpay.ErrNotificationRejected)
+	var rejected *rimpay.NotificationRejectedError
+	require.True(t, errors.As(err, &rejected))
+	assert.Equal(t, reason, rejected.Reason)
+	assert.Equal(t, "masrvi", rejected.Provider)
+}
+
+func TestHandleNotificationAcceptsSignedNotification(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{OptionNotificationSecret: notificationSecret})
+
+	status, err := provider.HandleNotification(signedNotification(t, okParams(notificationNow), "203.0.113.7:4711"))
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, status.Status)
+	assert.Equal(t, "ORDER-1", status.Reference)
+	assert.Equal(t, "42", status.TransactionID)
+}
+
+func TestHandleNotificationRejectsTamperedAmount(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{OptionNotificationSecret: notificationSecret})
+
+	notification := signedNotification(t, okParams(notificationNow), "")
+	notification.Params.Set("amount", "1")
+	status, err := provider.HandleNotification(notification)
+	assertRejected(t, err, rimpay.NotificationBadSignature)
+	assert.Nil(t, status)
+
+	notification = signedNotification(t, okParams(notificationNow), "")
+	notification.Params.Del(rimpay.MasrviSignatureParam)
+	_, err = provider.HandleNotification(notification)
+	assertRejected(t, err, rimpay.NotificationBadSignature)
+
+	_, err = provider.HandleNotification(&rimpay.MasrviNotificationData{Status: "OK", Reference: "ORDER-1"})
+	assertRejected(t, err, rimpay.NotificationBadSignature)
+}
+
+func TestHandleNotificationTrustsOnlySignedParams(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{OptionNotificationSecret: notificationSecret})
+
+	params := okParams(notificationNow)
+	params.Set("status", "NOK")
+	notification := signedNotification(t, params, "")
+	notification.Status = "OK"
+
+	status, err := provider.HandleNotification(notification)
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusFailed, status.Status, "fields changed after signing are ignored")
+}
+
+func TestHandleNotificationRejectsReplayedTimestamp(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{OptionNotificationSecret: notificationSecret})
+
+	replayed := signedNotification(t, okParams(notificationNow.Add(-time.Hour)), "")
+	status, err := provider.HandleNotification(replayed)
+	assertRejected(t, err, rimpay.NotificationStaleTimestamp)
+	assert.Nil(t, status)
+
+	future := signedNotification(t, okParams(notificationNow.Add(10*time.Minute)), "")
+	_, err = provider.HandleNotification(future)
+	assertRejected(t, err, rimpay.NotificationStaleTimestamp)
+
+	params := okParams(notificationNow)
+	params.Del("timestamp")
+	_, err = provider.HandleNotification(signedNotification(t, params, ""))
+	assertRejected(t, err, rimpay.NotificationStaleTimestamp)
+
+	recent := signedNotification(t, okParams(notificationNow.Add(-4*time.Minute)), "")
+	_, err = provider.HandleNotification(recent)
+	assert.NoError(t, err, "within the default 5m window")
+}
+
+func TestHandleNotificationChecksSourceAddress(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{
+		OptionNotificationSecret:     notificationSecret,
+		OptionNotificationAllowedIPs: "203.0.113.0/24, 198.51.100.9",
+	})
+
+	for _, addr := range []string{"203.0.113.7:4711", "198.51.100.9", ""} {
+		_, err := provider.HandleNotification(signedNotification(t, okParams(notificationNow), addr))
+		assert.NoError(t, err, addr)
+	}
+
+	_, err := provider.HandleNotification(signedNotification(t, okParams(notificationNow), "192.0.2.1:80"))
+	assertRejected(t, err, rimpay.NotificationBadSource)
+}
+
+func TestHandleNotificationWithoutVerification(t *testing.T) {
+	provider := newNotificationProvider(t, nil)
+
+	status, err := provider.HandleNotification(&rimpay.MasrviNotificationData{Status: "OK", Reference: "ORDER-1", Timestamp: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, status.Status)
+
+	_, err = provider.HandleNotification(nil)
+	assert.Error(t, err)
+}
+
+func TestNotificationOptionsValidation(t *testing.T) {
+	_, err := NewMasrviProvider(optionsConfig(&sessionServer{}, map[string]interface{}{
+		OptionNotificationAllowedIPs: []string{"not-an-ip"},
+	}), nopLogger{})
+	assert.ErrorContains(t, err, `invalid allowed IP or CIDR "not-an-ip"`)
+
+	_, err = NewMasrviProvider(optionsConfig(&sessionServer{}, map[string]interface{}{
+		OptionNotificationMaxAge: "-1m",
+	}), nopLogger{})
+	assert.ErrorContains(t, err, "notification_max_age cannot be negative")
+}
+
+func TestHandleNotificationReportsAmount(t *testing.T) {
+	provider := newNotificationProvider(t, map[string]interface{}{OptionNotificationSecret: notificationSecret})
+
+	params := okParams(notificationNow)
+	params.Set("currency", "929")
+	status, err := provider.HandleNotification(signedNotification(t, params, ""))
+	require.NoError(t, err)
+	assert.Equal(t, money.FromCents(15050, money.MRU), status.Amount)
+	raw, ok := status.AsMasrviRaw()
+	require.True(t, ok)
+	assert.Equal(t, "OK", raw.Status)
+	assert.Equal(t, "ORDER-1", raw.PurchaseRef)
+	assert.Equal(t, "42", raw.PayID)
+	assert.Equal(t, status.Amount, raw.Amount)
+
+	params.Set("currency", "EUR")
+	_, err = provider.HandleNotification(signedNotification(t, params, ""))
+	assert.Error(t, err)
+
+	params = okParams(notificationNow)
+	params.Set("amount", "150.50")
+	_, err = provider.HandleNotification(signedNotification(t, params, ""))
+	assert.Error(t, err, "amounts are in cents by default")
+
+	provider = newNotificationProvider(t, map[string]interface{}{OptionAmountInCents: false})
+	status, err = provider.HandleNotification(&rimpay.MasrviNotificationData{
+		Status: "OK", Reference: "ORDER-1", Timestamp: "1",
+		Data: map[string]interface{}{"amount": "150.50", "currency": "MRU"},
+	})
+	require.NoError(t, err)
+	assert.True(t, status.Amount.Equals(money.FromFloat64(150.50, money.MRU)))
+
+	status, err = provider.HandleNotification(&rimpay.MasrviNotificationData{Status: "OK", Reference: "ORDER-1", Timestamp: "1"})
+	require.NoError(t, err)
+	assert.True(t, status.Amount.IsZero(), "no amount is reported as zero")
+}
@@ -0,0 +1,205 @@
+package masrvi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/shopspring/decimal"
+)
+
+// Supported ProviderConfig.Options keys for MASRVI
+const (
+	// OptionSessionTTL is how long a session ID is reused (duration, default 5m)
+	OptionSessionTTL = rimpay.OptionSessionTTL
+	// OptionSessionRefreshMargin replaces sessions this close to expiry, so
+	// none expires while the customer is redirected (duration, default 30s
+	// or half the session_ttl when shorter)
+	OptionSessionRefreshMargin = rimpay.OptionSessionRefreshMargin
+	// OptionKeepSessionWarm refreshes the session in the background before
+	// it enters the refresh margin (bool, default false)
+	OptionKeepSessionWarm = rimpay.OptionKeepSessionWarm
+	// OptionPaymentPath is the gateway endpoint path (string, default /online/online.php)
+	OptionPaymentPath = "payment_path"
+	// OptionAmountInCents sends amounts in cents rather than units (bool, default true)
+	OptionAmountInCents = "amount_in_cents"
+	// OptionBrandName is shown on the hosted payment page (string, optional)
+	OptionBrandName = rimpay.OptionBrandName
+	// OptionRefundPath is the refund endpoint path (string, optional). The
+	// MASRVI API specification has no refund endpoint, so refunds return
+	// ErrRefundNotSupported unless the merchant contract gives one.
+	OptionRefundPath = "refund_path"
+	// OptionStatusPath is the transaction status endpoint path (string,
+	// default /online/status.php); empty disables status queries
+	OptionStatusPath = "status_path"
+	// OptionNotificationSecret is the shared secret notifications are signed
+	// with (string, optional); HandleNotification rejects unsigned ones
+	OptionNotificationSecret = rimpay.OptionNotificationSecret
+	// OptionNotificationMaxAge rejects notification timestamps further than
+	// this from now (duration, default 5m with a notification_secret, else
+	// no check)
+	OptionNotificationMaxAge = "notification_max_age"
+	// OptionNotificationAllowedIPs lists the addresses and CIDR ranges
+	// notifications may come from (list, optional)
+	OptionNotificationAllowedIPs = "notification_allowed_ips"
+	// OptionDefaultURLs holds the redirect URLs of payments that set none
+	// (map of success_url, failure_url and cancel_url, optional)
+	OptionDefaultURLs = rimpay.OptionDefaultURLs
+	// OptionRequireMerchantOverride makes every payment name its merchant
+	// in PaymentRequest.CredentialOverrides, so no default merchant_id is
+	// needed (bool, default false)
+	OptionRequireMerchantOverride = rimpay.OptionRequireMerchantOverride
+	// OptionAmountTolerance is how far the amount of a successful
+	// notification may be from the payment's before the payment is failed
+	// (decimal in currency units, default 0)
+	OptionAmountTolerance = rimpay.OptionAmountTolerance
+)
+
+const (
+	defaultSessionTTL           = 5 * time.Minute
+	defaultSessionRefreshMargin = 30 * time.Second
+	defaultPaymentPath          = "/online/online.php"
+	defaultStatusPath           = "/online/status.php"
+
+	defaultNotificationMaxAge = 5 * time.Minute
+)
+
+// options holds the resolved MASRVI tunables
+type options struct {
+	sessionTTL    time.Duration
+	refreshMargin time.Duration
+	keepWarm      bool
+	paymentPath   string
+	amountInCents bool
+	brandName     string
+	refundPath    string
+	statusPath    string
+	defaultURLs   rimpay.MasrviURLs
+	notifications rimpay.MasrviNotificationVerifier
+
+	// requireMerchantOverride rejects payments without a merchant_id override
+	requireMerchantOverride bool
+}
+
+func defaultOptions() options {
+	return options{
+		sessionTTL:    defaultSessionTTL,
+		refreshMargin: defaultSessionRefreshMargin,
+		paymentPath:   defaultPaymentPath,
+		amountInCents: true,
+		statusPath:    defaultStatusPath,
+	}
+}
+
+// resolveOptions is parseOptions for constructors that cannot fail;
+// NewMasrviProvider has already rejected invalid options
+func resolveOptions(config rimpay.ProviderConfig) options {
+	opts, err := parseOptions(config)
+	if err != nil {
+		return defaultOptions()
+	}
+	return opts
+}
+
+// parseOptions reads and validates the MASRVI options in config
+func parseOptions(config rimpay.ProviderConfig) (options, error) {
+	opts := defaultOptions()
+
+	if err := config.CheckOptions(OptionSessionTTL, OptionSessionRefreshMargin, OptionKeepSessionWarm,
+		OptionPaymentPath, OptionAmountInCents, OptionBrandName, OptionRefundPath, OptionStatusPath,
+		OptionNotificationSecret, OptionNotificationMaxAge, OptionNotificationAllowedIPs, OptionDefaultURLs,
+		OptionRequireMerchantOverride, OptionAmountTolerance); err != nil {
+		return opts, err
+	}
+
+	typed, err := config.MasrviOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.defaultURLs = typed.DefaultURLs
+	opts.requireMerchantOverride = typed.RequireMerchantOverride
+	if opts.requireMerchantOverride && typed.KeepSessionWarm {
+		// The refresher only warms the default merchant's session
+		return opts, fmt.Errorf("option %s cannot be combined with %s", OptionKeepSessionWarm, OptionRequireMerchantOverride)
+	}
+
+	if opts.sessionTTL, err = config.DurationOption(OptionSessionTTL, defaultSessionTTL); err != nil {
+		return opts, err
+	}
+	if opts.sessionTTL <= 0 {
+		return opts, fmt.Errorf("option %s must be positive", OptionSessionTTL)
+	}
+
+	margin := defaultSessionRefreshMargin
+	if margin > opts.sessionTTL/2 {
+		margin = opts.sessionTTL / 2
+	}
+	if opts.refreshMargin, err = config.DurationOption(OptionSessionRefreshMargin, margin); err != nil {
+		return opts, err
+	}
+	if opts.refreshMargin < 0 || opts.refreshMargin >= opts.sessionTTL {
+		return opts, fmt.Errorf("option %s must be at least 0 and less than %s", OptionSessionRefreshMargin, OptionSessionTTL)
+	}
+	if opts.keepWarm, err = config.BoolOption(OptionKeepSessionWarm, false); err != nil {
+		return opts, err
+	}
+
+	if opts.paymentPath, err = config.StringOption(OptionPaymentPath, defaultPaymentPath); err != nil {
+		return opts, err
+	}
+	if !strings.HasPrefix(opts.paymentPath, "/") {
+		return opts, fmt.Errorf("option %s must start with /", OptionPaymentPath)
+	}
+
+	if opts.amountInCents, err = config.BoolOption(OptionAmountInCents, true); err != nil {
+		return opts, err
+	}
+
+	if opts.brandName, err = config.StringOption(OptionBrandName, ""); err != nil {
+		return opts, err
+	}
+
+	if opts.refundPath, err = config.StringOption(OptionRefundPath, ""); err != nil {
+		return opts, err
+	}
+	if opts.refundPath != "" && !strings.HasPrefix(opts.refundPath, "/") {
+		return opts, fmt.Errorf("option %s must start with / or be empty", OptionRefundPath)
+	}
+
+	if opts.statusPath, err = config.StringOption(OptionStatusPath, defaultStatusPath); err != nil {
+		return opts, err
+	}
+	if opts.statusPath != "" && !strings.HasPrefix(opts.statusPath, "/") {
+		return opts, fmt.Errorf("option %s must start with / or be empty", OptionStatusPath)
+	}
+
+	if opts.notifications.SigningKey, err = config.StringOption(OptionNotificationSecret, ""); err != nil {
+		return opts, err
+	}
+	maxAge := time.Duration(0)
+	if opts.notifications.SigningKey != "" {
+		maxAge = defaultNotificationMaxAge
+	}
+	if opts.notifications.MaxAge, err = config.DurationOption(OptionNotificationMaxAge, maxAge); err != nil {
+		return opts, err
+	}
+	if opts.notifications.MaxAge < 0 {
+		return opts, fmt.Errorf("option %s cannot be negative", OptionNotificationMaxAge)
+	}
+	if opts.notifications.AllowedIPs, err = config.StringSliceOption(OptionNotificationAllowedIPs, nil); err != nil {
+		return opts, err
+	}
+	if err := opts.notifications.Validate(); err != nil {
+		return opts, fmt.Errorf("option %s: %w", OptionNotificationAllowedIPs, err)
+	}
+
+	// The client applies the tolerance; it is only checked here
+	if tolerance, err := config.DecimalOption(OptionAmountTolerance, decimal.Zero); err != nil {
+		return opts, err
+	} else if tolerance.IsNegative() {
+		return opts, fmt.Errorf("option %s cannot be negative", OptionAmountTolerance)
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,176 @@
+package masrvi
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// sessionServer answers every request with a fixed session ID
+type sessionServer struct {
+	urls []string
+}
+
+func (s *sessionServer) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	s.urls = append(s.urls, req.URL)
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte("SESSION-1")}, nil
+}
+
+func optionsConfig(http common.HTTPClient, opts map[string]interface{}) rimpay.ProviderConfig {
+	return rimpay.ProviderConfig{
+		BaseURL:     "https://masrvi.test",
+		Credentials: map[string]string{"merchant_id": "M1"},
+		Timeout:     time.Second,
+		Options:     opts,
+		HTTPClient:  http,
+	}
+}
+
+func processTestPayment(t *testing.T, opts map[string]interface{}) (*rimpay.PaymentResponse, *sessionServer) {
+	t.Helper()
+	server := &sessionServer{}
+	provider, err := NewMasrviProvider(optionsConfig(server, opts), nopLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:    money.FromFloat64(150.50, money.MRU),
+		Reference: "ORDER-1",
+	})
+	require.NoError(t, err)
+	return resp, server
+}
+
+func TestSessionTTLOption(t *testing.T) {
+	server := &sessionServer{}
+	sm := NewSessionManager(optionsConfig(server, map[string]interface{}{OptionSessionTTL: "30s", OptionSessionRefreshMargin: "0s"}), server, nopLogger{})
+	now := time.Unix(1000, 0)
+	sm.now = func() time.Time { return now }
+
+	_, err := sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+
+	now = now.Add(29 * time.Second)
+	_, err = sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, server.urls, 1, "session should still be cached")
+
+	now = now.Add(2 * time.Second)
+	_, err = sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, server.urls, 2, "session should expire after the configured TTL")
+}
+
+func TestPaymentExpiresWithSession(t *testing.T) {
+	server := &sessionServer{}
+	provider, err := NewMasrviProvider(optionsConfig(server, map[string]interface{}{OptionSessionTTL: "2m"}), nopLogger{})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	provider.paymentProcessor.sessionManager.now = func() time.Time { return now }
+
+	resp, err := provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:    money.FromFloat64(150.50, money.MRU),
+		Reference: "ORDER-1",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.Equal(t, now.Add(2*time.Minute), *resp.ExpiresAt)
+}
+
+func TestPaymentPathOption(t *testing.T) {
+	resp, server := processTestPayment(t, map[string]interface{}{OptionPaymentPath: "/v2/pay.php"})
+
+	assert.Equal(t, "https://masrvi.test/v2/pay.php", resp.PaymentURL)
+	require.Len(t, server.urls, 1)
+	assert.Equal(t, "https://masrvi.test/v2/pay.php?merchantid=M1", server.urls[0])
+}
+
+func TestAmountInCentsOption(t *testing.T) {
+	resp, _ := processTestPayment(t, nil)
+	assert.Equal(t, "15050", resp.Metadata["form_data"].(url.Values).Get("amount"))
+
+	resp, _ = processTestPayment(t, map[string]interface{}{OptionAmountInCents: false})
+	assert.Equal(t, "150.50", resp.Metadata["form_data"].(url.Values).Get("amount"))
+}
+
+func TestBrandNameOption(t *testing.T) {
+	resp, _ := processTestPayment(t, map[string]interface{}{OptionBrandName: "Cato Shop"})
+	assert.Equal(t, "Cato Shop", resp.Metadata["form_data"].(url.Values).Get("brand"))
+}
+
+func TestTypedOptions(t *testing.T) {
+	server := &sessionServer{}
+	config := optionsConfig(server, nil).WithMasrviOptions(rimpay.MasrviOptions{
+		BrandName:   "Cato Shop",
+		SessionTTL:  time.Minute,
+		DefaultURLs: rimpay.MasrviURLs{SuccessURL: "https://shop.test/ok", FailureURL: "https://shop.test/ko"},
+	})
+	provider, err := NewMasrviProvider(config, nopLogger{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, provider.paymentProcessor.options.sessionTTL)
+
+	resp, err := provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{
+		Amount:     money.FromFloat64(150.50, money.MRU),
+		Reference:  "ORDER-1",
+		FailureURL: "https://shop.test/order-1/ko",
+	})
+	require.NoError(t, err)
+	form := resp.Metadata["form_data"].(url.Values)
+	assert.Equal(t, "Cato Shop", form.Get("brand"))
+	assert.Equal(t, "https://shop.test/ok", form.Get("accepturl"), "the default applies")
+	assert.Equal(t, "https://shop.test/order-1/ko", form.Get("declineurl"), "the request's URL wins")
+	assert.NotContains(t, form, "cancelurl")
+}
+
+func TestInvalidOptionsRejected(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown key":         {"sesion_ttl": "1m"},
+		"bad duration":        {OptionSessionTTL: "soon"},
+		"non-positive ttl":    {OptionSessionTTL: "0s"},
+		"margin over ttl":     {OptionSessionTTL: "1m", OptionSessionRefreshMargin: "1m"},
+		"negative margin":     {OptionSessionRefreshMargin: "-1s"},
+		"relative path":       {OptionPaymentPath: "pay.php"},
+		"relative status":     {OptionStatusPath: "status.php"},
+		"non-bool cents":      {OptionAmountInCents: 1},
+		"non-string branding": {OptionBrandName: 7},
+		"unknown default url": {OptionDefaultURLs: map[string]string{"ok_url": "https://shop.test"}},
+		"negative tolerance":  {OptionAmountTolerance: "-0.01"},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMasrviProvider(optionsConfig(&sessionServer{}, opts), nopLogger{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPaymentResponseUsesCanonicalMetadataKeys(t *testing.T) {
+	resp, _ := processTestPayment(t, nil)
+
+	paymentURL, ok := resp.GetMetadataString(rimpay.MetadataKeyPaymentURL)
+	assert.True(t, ok)
+	assert.Equal(t, resp.PaymentURL, paymentURL)
+	value, ok := resp.PaymentURLValue()
+	assert.True(t, ok)
+	assert.Equal(t, resp.PaymentURL, value)
+
+	sessionID, ok := resp.GetMetadataString(rimpay.MetadataKeySessionID)
+	assert.True(t, ok)
+	assert.NotEmpty(t, sessionID)
+	assert.IsType(t, url.Values{}, resp.Metadata[rimpay.MetadataKeyFormData])
+	_, ok = resp.Passcode()
+	assert.False(t, ok)
+}
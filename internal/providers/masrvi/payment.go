@@ -2,10 +2,15 @@ package masrvi
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -16,6 +21,7 @@ type PaymentProcessor struct {
 	sessionManager *SessionManager
 	logger         rimpay.Logger
 	baseURL        string
+	options        options
 }
 
 // NewPaymentProcessor creates new payment processor
@@ -26,29 +32,47 @@ func NewPaymentProcessor(config rimpay.ProviderConfig, httpClient common.HTTPCli
 		sessionManager: sessionManager,
 		logger:         logger,
 		baseURL:        config.BaseURL,
+		options:        resolveOptions(config),
 	}
 }
 
 // ProcessPayment processes a payment request
-func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
+func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (_ *rimpay.PaymentResponse, err error) {
+	ctx, span := common.StartSpan(ctx, pp.config.Tracer, "rimpay.masrvi.payment",
+		common.AttrProvider, "masrvi", common.AttrReference, request.Reference, common.AttrAmount, request.Amount.String())
+	defer func() { common.EndSpan(span, err) }()
+
+	merchantID := request.CredentialOverrides[rimpay.CredentialMerchantID]
+	if merchantID == "" && pp.options.requireMerchantOverride {
+		err := rimpay.NewValidationError("credential_overrides", "must set merchant_id")
+		err.Provider = "masrvi"
+		return nil, err
+	}
+
 	// Get session ID
-	sessionID, err := pp.sessionManager.GetSessionID(ctx)
+	sessionID, merchantID, err := pp.sessionManager.session(ctx, merchantID)
 	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "masrvi"); ok {
+			return nil, transportErr
+		}
+		if credentialsErr, ok := common.AsCredentialsError(err); ok {
+			return nil, credentialsErr
+		}
 		return nil, rimpay.NewPaymentError(
 			rimpay.ErrorCodeProviderError,
 			"failed to get session ID",
 			"masrvi",
 			true,
-		)
+		).WithCause(err)
 	}
 
 	// Create form data
-	formData := pp.createFormData(sessionID, request)
+	formData := pp.createFormData(sessionID, merchantID, request)
 
 	// Create payment URL
-	paymentURL := pp.baseURL + "/online/online.php"
+	paymentURL := pp.baseURL + pp.options.paymentPath
 
-	pp.logger.Info("MASRVI payment created",
+	rimpay.ContextLogger(ctx, pp.logger).Info("MASRVI payment created",
 		"reference", request.Reference,
 		"session_id", sessionID,
 		"amount", request.Amount.String(),
@@ -65,22 +89,241 @@ func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.
 		UpdatedAt:     time.Now(),
 		PaymentURL:    paymentURL,
 		Metadata: map[string]interface{}{
-			"session_id":  sessionID,
-			"form_data":   formData,
-			"payment_url": paymentURL,
-			"message":     "Payment initiated, redirect user to payment URL",
+			rimpay.MetadataKeySessionID:  sessionID,
+			rimpay.MetadataKeyFormData:   formData,
+			rimpay.MetadataKeyPaymentURL: paymentURL,
+			"message":                    "Payment initiated, redirect user to payment URL",
+		},
+		Events: []rimpay.StatusEvent{
+			rimpay.NewStatusEvent(rimpay.PaymentStatusPending, "Payment initiated", rimpay.EventSourceInitial),
 		},
 	}
+	// The payment form only works while its session lasts
+	if expiresAt := pp.sessionManager.expiry(merchantID); !expiresAt.IsZero() {
+		response.ExpiresAt = &expiresAt
+	}
 
 	return response, nil
 }
 
+// ProcessRefund refunds all or part of a payment. MASRVI answers "OK" or
+// "NOK", optionally followed by ":" and a refund ID or error message.
+func (pp *PaymentProcessor) ProcessRefund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	sessionID, merchantID, err := pp.sessionManager.session(ctx, "")
+	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "masrvi"); ok {
+			return nil, transportErr
+		}
+		if credentialsErr, ok := common.AsCredentialsError(err); ok {
+			return nil, credentialsErr
+		}
+		return nil, rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to get session ID",
+			"masrvi",
+			true,
+		).WithCause(err)
+	}
+
+	formData := url.Values{}
+	formData.Set("sessionid", sessionID)
+	formData.Set("merchantid", merchantID)
+	formData.Set("paymentref", request.TransactionID)
+	formData.Set("refundref", request.Reference)
+	formData.Set("amount", request.Amount.ToProviderAmount(pp.options.amountInCents))
+	formData.Set("currency", request.Amount.GetCurrencyCode())
+	if request.Reason != "" {
+		formData.Set("reason", request.Reason)
+	}
+
+	httpReq := &common.HTTPRequest{
+		Method:  "POST",
+		URL:     pp.baseURL + pp.options.refundPath,
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    []byte(formData.Encode()),
+		Timeout: pp.config.Timeout,
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("Making MASRVI refund request",
+		"reference", request.Reference,
+		"payment_ref", request.TransactionID,
+		"amount", request.Amount.String(),
+	)
+
+	resp, err := pp.httpClient.Do(ctx, httpReq)
+	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "masrvi"); ok {
+			return nil, transportErr
+		}
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError, "refund request failed", "masrvi", true,
+		).WithCause(err), httpReq, nil)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			fmt.Sprintf("refund failed with status: %d", resp.StatusCode), "masrvi", resp.StatusCode >= 500,
+		), httpReq, resp)
+	}
+
+	body := strings.TrimSpace(string(resp.Body))
+	result, detail, _ := strings.Cut(body, ":")
+
+	response := &rimpay.RefundResponse{
+		TransactionID: request.TransactionID,
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      "masrvi",
+		Partial:       request.IsPartial(),
+		CreatedAt:     time.Now(),
+		Metadata: map[string]interface{}{
+			rimpay.MetadataKeySessionID: sessionID,
+			"reason":                    request.Reason,
+		},
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(result)) {
+	case "OK":
+		response.Status = rimpay.PaymentStatusSuccess
+		response.RefundID = strings.TrimSpace(detail)
+	case "NOK":
+		response.Status = rimpay.PaymentStatusFailed
+		response.Metadata[rimpay.MetadataKeyErrorMessage] = strings.TrimSpace(detail)
+	default:
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			fmt.Sprintf("invalid refund response: %s", body), "masrvi", false,
+		), httpReq, resp)
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("MASRVI refund response received",
+		"reference", request.Reference,
+		"status", response.Status,
+	)
+
+	return response, nil
+}
+
+// CheckPaymentStatus queries the status of the payment whose purchase
+// reference is transactionID. MASRVI answers with form-encoded fields named
+// like those of a notification. Without a status endpoint the error wraps
+// ErrStatusNotSupported.
+func (pp *PaymentProcessor) CheckPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
+	if pp.options.statusPath == "" {
+		return nil, fmt.Errorf("masrvi status for %s: %w", transactionID, rimpay.ErrStatusNotSupported)
+	}
+
+	sessionID, merchantID, err := pp.sessionManager.session(ctx, "")
+	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "masrvi"); ok {
+			return nil, transportErr
+		}
+		if credentialsErr, ok := common.AsCredentialsError(err); ok {
+			return nil, credentialsErr
+		}
+		return nil, rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to get session ID",
+			"masrvi",
+			true,
+		).WithCause(err)
+	}
+
+	formData := url.Values{}
+	formData.Set("sessionid", sessionID)
+	formData.Set("merchantid", merchantID)
+	formData.Set("purchaseref", transactionID)
+
+	httpReq := &common.HTTPRequest{
+		Method:     "POST",
+		URL:        pp.baseURL + pp.options.statusPath,
+		Headers:    map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:       []byte(formData.Encode()),
+		Timeout:    pp.config.Timeout,
+		Idempotent: true,
+	}
+
+	resp, err := pp.httpClient.Do(ctx, httpReq)
+	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "masrvi"); ok {
+			return nil, transportErr
+		}
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError, "status check failed", "masrvi", true,
+		).WithCause(err), httpReq, nil)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return nil, fmt.Errorf("masrvi status for %s: endpoint answered %d: %w",
+			transactionID, resp.StatusCode, rimpay.ErrStatusNotSupported)
+	case resp.StatusCode != http.StatusOK:
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			fmt.Sprintf("status check failed with status: %d", resp.StatusCode), "masrvi", resp.StatusCode >= 500,
+		), httpReq, resp)
+	}
+
+	fields, err := url.ParseQuery(strings.TrimSpace(string(resp.Body)))
+	if err != nil || fields.Get("status") == "" {
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError, "invalid status response", "masrvi", false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	result := &NotificationData{
+		Status:      fields.Get("status"),
+		ClientID:    fields.Get("clientid"),
+		ClientName:  fields.Get("cname"),
+		Mobile:      fields.Get("mobile"),
+		PurchaseRef: fields.Get("purchaseref"),
+		PaymentRef:  fields.Get("paymentref"),
+		PayID:       fields.Get("payid"),
+		Timestamp:   fields.Get("timestamp"),
+		Error:       fields.Get("error"),
+	}
+	if result.PurchaseRef == "" {
+		result.PurchaseRef = transactionID
+	}
+
+	status := result.ToPaymentStatus()
+	message := "MASRVI status: " + result.Status
+	if result.Error != "" {
+		message = result.Error
+	}
+
+	transactionStatus := &rimpay.TransactionStatus{
+		TransactionID:     transactionID,
+		Status:            status,
+		Reference:         result.PurchaseRef,
+		ProviderReference: result.PaymentRef,
+		Message:           message,
+		LastUpdated:       time.Now(),
+		ProviderData: map[string]interface{}{
+			"status":      result.Status,
+			"payment_ref": result.PaymentRef,
+			"pay_id":      result.PayID,
+			"mobile":      result.Mobile,
+			"timestamp":   result.Timestamp,
+			"error":       result.Error,
+		},
+	}
+	transactionStatus.AddEvent(status, message, rimpay.EventSourcePoll)
+
+	return transactionStatus, nil
+}
+
 // createFormData creates form data for MASRVI
-func (pp *PaymentProcessor) createFormData(sessionID string, request *rimpay.PaymentRequest) url.Values {
+func (pp *PaymentProcessor) createFormData(sessionID, merchantID string, request *rimpay.PaymentRequest) url.Values {
 	formData := url.Values{}
 	formData.Set("sessionid", sessionID)
-	formData.Set("merchantid", pp.config.Credentials["merchant_id"])
-	formData.Set("amount", request.Amount.ToProviderAmount(true)) // MASRVI uses cents
+	formData.Set("merchantid", merchantID)
+	formData.Set("amount", request.Amount.ToProviderAmount(pp.options.amountInCents))
 	formData.Set("currency", request.Amount.GetCurrencyCode())
 	formData.Set("purchaseref", request.Reference)
 	formData.Set("description", request.Description)
@@ -90,21 +333,24 @@ func (pp *PaymentProcessor) createFormData(sessionID string, request *rimpay.Pay
 		formData.Set("phonenumber", request.PhoneNumber.LocalFormat())
 	}
 
-	if request.SuccessURL != "" {
-		formData.Set("accepturl", request.SuccessURL)
-	}
+	// Redirect URLs fall back to the configured defaults
+	setURL(formData, "accepturl", request.SuccessURL, pp.options.defaultURLs.SuccessURL)
+	setURL(formData, "declineurl", request.FailureURL, pp.options.defaultURLs.FailureURL)
+	setURL(formData, "cancelurl", request.CancelURL, pp.options.defaultURLs.CancelURL)
 
-	if request.FailureURL != "" {
-		formData.Set("declineurl", request.FailureURL)
+	// The request's brand wins over the configured brand name
+	brand, _ := request.Metadata[rimpay.MasrviMetadataBrand].(string)
+	if brand == "" {
+		brand = pp.options.brandName
 	}
-
-	if request.CancelURL != "" {
-		formData.Set("cancelurl", request.CancelURL)
+	if brand != "" {
+		formData.Set("brand", brand)
 	}
-
-	// Brand name from config or request metadata
-	if brandName, exists := pp.config.Options["brand_name"].(string); exists {
-		formData.Set("brand", brandName)
+	if customerName, _ := request.Metadata[rimpay.MasrviMetadataCustomerName].(string); customerName != "" {
+		formData.Set("cname", customerName)
+	}
+	if text, _ := request.Metadata[rimpay.MasrviMetadataText].(string); text != "" {
+		formData.Set("text", text)
 	}
 
 	return formData
@@ -132,6 +378,7 @@ func (pp *PaymentProcessor) HandleNotification(notification *NotificationData) (
 	transactionStatus := &rimpay.TransactionStatus{
 		TransactionID:     notification.PayID,
 		Status:            status,
+		Amount:            notification.Amount,
 		Reference:         notification.PurchaseRef,
 		ProviderReference: notification.PaymentRef,
 		Message:           message,
@@ -146,10 +393,55 @@ func (pp *PaymentProcessor) HandleNotification(notification *NotificationData) (
 			"ip_address":  notification.IPAddress,
 			"status":      notification.Status,
 		},
+		Raw: notification.raw(),
 	}
 
 	// Add status event
-	transactionStatus.AddEvent(status, message)
+	transactionStatus.AddEvent(status, message, rimpay.EventSourceWebhook)
 
 	return transactionStatus, nil
 }
+
+// setURL sets key to value, or to def when value is empty; a key with
+// neither is left out
+func setURL(formData url.Values, key, value, def string) {
+	if value == "" {
+		value = def
+	}
+	if value != "" {
+		formData.Set(key, value)
+	}
+}
+
+// notificationAmount reads the amount and currency parameters of a
+// notification, in the unit payments are sent in. It is zero when MASRVI
+// reports no amount.
+func (pp *PaymentProcessor) notificationAmount(notification *rimpay.MasrviNotificationData) (money.Money, error) {
+	amount := strings.TrimSpace(notificationExtra(notification, "amount"))
+	if amount == "" {
+		return money.Money{}, nil
+	}
+
+	var currency money.Currency
+	switch code := strings.ToUpper(strings.TrimSpace(notificationExtra(notification, "currency"))); code {
+	case "", "929", string(money.MRU):
+		currency = money.MRU
+	case "478", string(money.MRO):
+		currency = money.MRO
+	default:
+		return money.Money{}, rimpay.NewValidationError("currency", fmt.Sprintf("unknown currency %q", code))
+	}
+
+	if pp.options.amountInCents {
+		cents, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil {
+			return money.Money{}, rimpay.NewValidationError("amount", "must be a whole number of cents")
+		}
+		return money.FromCents(cents, currency), nil
+	}
+	value, err := money.FromString(amount, currency)
+	if err != nil {
+		return money.Money{}, rimpay.NewValidationError("amount", "must be a decimal number")
+	}
+	return value, nil
+}
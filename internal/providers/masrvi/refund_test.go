@@ -0,0 +1,120 @@
+package masrvi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refundServer hands out a session ID and answers refunds with body
+type refundServer struct {
+	status int
+	body   string
+	refund *common.HTTPRequest
+}
+
+func (s *refundServer) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if req.Method == "GET" {
+		return &common.HTTPResponse{StatusCode: 200, Body: []byte("SESSION-1")}, nil
+	}
+	s.refund = req
+	return &common.HTTPResponse{StatusCode: s.status, Body: []byte(s.body)}, nil
+}
+
+func refundRequest() *rimpay.RefundRequest {
+	return &rimpay.RefundRequest{
+		TransactionID:  "PAY-7",
+		Amount:         money.FromFloat64(20.00, money.MRU),
+		OriginalAmount: money.FromFloat64(150.50, money.MRU),
+		Reason:         "damaged",
+		Reference:      "RF-1",
+	}
+}
+
+func TestRefund(t *testing.T) {
+	server := &refundServer{status: 200, body: "OK:RF-9001"}
+	provider, err := NewMasrviProvider(optionsConfig(server, map[string]interface{}{
+		OptionRefundPath: "/online/refund.php",
+	}), nopLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.Refund(context.Background(), refundRequest())
+	require.NoError(t, err)
+
+	require.NotNil(t, server.refund)
+	assert.Equal(t, "https://masrvi.test/online/refund.php", server.refund.URL)
+	form, err := url.ParseQuery(string(server.refund.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-1", form.Get("sessionid"))
+	assert.Equal(t, "M1", form.Get("merchantid"))
+	assert.Equal(t, "PAY-7", form.Get("paymentref"))
+	assert.Equal(t, "RF-1", form.Get("refundref"))
+	assert.Equal(t, "2000", form.Get("amount"))
+	assert.Equal(t, "damaged", form.Get("reason"))
+
+	assert.Equal(t, "RF-9001", resp.RefundID)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, resp.Status)
+	assert.True(t, resp.Partial)
+}
+
+func TestRefundResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus rimpay.PaymentStatus
+		wantCode   rimpay.ErrorCode
+	}{
+		{"declined", 200, "NOK:already refunded", rimpay.PaymentStatusFailed, ""},
+		{"unexpected body", 200, "<html>", "", rimpay.ErrorCodeProviderError},
+		{"client error", 400, "", "", rimpay.ErrorCodeProviderError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &refundServer{status: tt.status, body: tt.body}
+			provider, err := NewMasrviProvider(optionsConfig(server, map[string]interface{}{
+				OptionRefundPath: "/api/refund",
+			}), nopLogger{})
+			require.NoError(t, err)
+
+			resp, err := provider.Refund(context.Background(), refundRequest())
+			assert.True(t, strings.HasSuffix(server.refund.URL, "/api/refund"))
+			if tt.wantCode == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, resp.Status)
+				assert.Equal(t, "already refunded", resp.Metadata["error_message"])
+				return
+			}
+
+			var paymentErr *rimpay.PaymentError
+			require.True(t, errors.As(err, &paymentErr))
+			assert.Equal(t, tt.wantCode, paymentErr.Code)
+			assert.False(t, paymentErr.Retryable)
+		})
+	}
+}
+
+func TestRefundNeedsRefundPath(t *testing.T) {
+	server := &refundServer{status: 200, body: "OK:RF-9001"}
+	provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.Refund(context.Background(), refundRequest())
+	assert.ErrorIs(t, err, rimpay.ErrRefundNotSupported)
+	assert.Nil(t, server.refund, "no refund endpoint is assumed")
+	assert.False(t, provider.Capabilities().Has(rimpay.CapabilityRefund))
+
+	_, err = NewMasrviProvider(optionsConfig(server, map[string]interface{}{
+		OptionRefundPath: "refund.php",
+	}), nopLogger{})
+	assert.Error(t, err)
+}
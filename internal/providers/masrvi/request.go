@@ -4,9 +4,9 @@ import (
 	"net/url"
 	"regexp"
 
-	"github.com/CatoSystems/rim-pay/internal/types"
 	"github.com/CatoSystems/rim-pay/pkg/money"
 	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
 // MasrviPaymentRequest represents a MASRVI specific payment request
@@ -17,9 +17,9 @@ type MasrviPaymentRequest struct {
 	Reference   string       `json:"reference"`
 
 	// Optional fields
-	Language    types.Language `json:"language,omitempty"`
-	Description string         `json:"description,omitempty"`
-	Brand       string         `json:"brand,omitempty"` // MASRVI specific: Payment brand
+	Language    rimpay.Language `json:"language,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Brand       string          `json:"brand,omitempty"` // MASRVI specific: Payment brand
 
 	// MASRVI specific URL fields
 	SuccessURL  string `json:"success_url,omitempty"`  // Redirect on success
@@ -48,25 +48,25 @@ func (r *MasrviPaymentRequest) Validate() error {
 // validateBasicFields validates amount and reference
 func (r *MasrviPaymentRequest) validateBasicFields() error {
 	if r.Amount.IsZero() {
-		return types.NewValidationError("amount", "cannot be zero")
+		return rimpay.NewValidationError("amount", "cannot be zero")
 	}
 
 	if r.Amount.IsNegative() {
-		return types.NewValidationError("amount", "cannot be negative")
+		return rimpay.NewValidationError("amount", "cannot be negative")
 	}
 
 	if r.Reference == "" {
-		return types.NewValidationError("reference", "is required")
+		return rimpay.NewValidationError("reference", "is required")
 	}
 
 	if len(r.Reference) > 50 {
-		return types.NewValidationError("reference", "too long (max 50 characters)")
+		return rimpay.NewValidationError("reference", "too long (max 50 characters)")
 	}
 
 	// Validate reference format (alphanumeric, dashes, underscores)
 	validRefRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 	if !validRefRegex.MatchString(r.Reference) {
-		return types.NewValidationError("reference", "invalid format (use only letters, numbers, dashes, underscores)")
+		return rimpay.NewValidationError("reference", "invalid format (use only letters, numbers, dashes, underscores)")
 	}
 
 	return nil
@@ -75,15 +75,15 @@ func (r *MasrviPaymentRequest) validateBasicFields() error {
 // validateStringLengths validates length constraints
 func (r *MasrviPaymentRequest) validateStringLengths() error {
 	if len(r.Description) > 255 {
-		return types.NewValidationError("description", "too long (max 255 characters)")
+		return rimpay.NewValidationError("description", "too long (max 255 characters)")
 	}
 
 	if len(r.CustomerName) > 100 {
-		return types.NewValidationError("customer_name", "too long (max 100 characters)")
+		return rimpay.NewValidationError("customer_name", "too long (max 100 characters)")
 	}
 
 	if len(r.Text) > 500 {
-		return types.NewValidationError("text", "too long (max 500 characters)")
+		return rimpay.NewValidationError("text", "too long (max 500 characters)")
 	}
 
 	return nil
@@ -93,16 +93,20 @@ func (r *MasrviPaymentRequest) validateStringLengths() error {
 func (r *MasrviPaymentRequest) validateURLs() error {
 	const invalidURLMsg = "invalid URL format"
 
-	urls := map[string]string{
-		"success_url":  r.SuccessURL,
-		"failure_url":  r.FailureURL,
-		"cancel_url":   r.CancelURL,
-		"callback_url": r.CallbackURL,
+	// Checked in a fixed order so the reported field is deterministic
+	urls := []struct {
+		field string
+		url   string
+	}{
+		{"success_url", r.SuccessURL},
+		{"failure_url", r.FailureURL},
+		{"cancel_url", r.CancelURL},
+		{"callback_url", r.CallbackURL},
 	}
 
-	for field, url := range urls {
-		if url != "" && !isValidURL(url) {
-			return types.NewValidationError(field, invalidURLMsg)
+	for _, u := range urls {
+		if u.url != "" && !isValidURL(u.url) {
+			return rimpay.NewValidationError(u.field, invalidURLMsg)
 		}
 	}
 
@@ -110,16 +114,16 @@ func (r *MasrviPaymentRequest) validateURLs() error {
 }
 
 // GetLanguage returns the language with fallback to French
-func (r *MasrviPaymentRequest) GetLanguage() types.Language {
+func (r *MasrviPaymentRequest) GetLanguage() rimpay.Language {
 	if r.Language == "" {
-		return types.LanguageFrench
+		return rimpay.LanguageFrench
 	}
 	return r.Language
 }
 
 // ToGenericRequest converts to the internal generic payment request
-func (r *MasrviPaymentRequest) ToGenericRequest() *types.PaymentRequest {
-	return &types.PaymentRequest{
+func (r *MasrviPaymentRequest) ToGenericRequest() *rimpay.PaymentRequest {
+	request := &rimpay.PaymentRequest{
 		Amount:      r.Amount,
 		PhoneNumber: r.PhoneNumber,
 		Reference:   r.Reference,
@@ -130,6 +134,20 @@ func (r *MasrviPaymentRequest) ToGenericRequest() *types.PaymentRequest {
 		CancelURL:   r.CancelURL,
 		CallbackURL: r.CallbackURL,
 	}
+	for key, value := range map[string]string{
+		rimpay.MasrviMetadataBrand:        r.Brand,
+		rimpay.MasrviMetadataCustomerName: r.CustomerName,
+		rimpay.MasrviMetadataText:         r.Text,
+	} {
+		if value == "" {
+			continue
+		}
+		if request.Metadata == nil {
+			request.Metadata = make(map[string]interface{})
+		}
+		request.Metadata[key] = value
+	}
+	return request
 }
 
 // isValidURL validates URL format
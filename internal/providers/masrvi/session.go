@@ -2,6 +2,7 @@ package masrvi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -18,15 +19,34 @@ type SessionManager struct {
 	httpClient common.HTTPClient
 	logger     rimpay.Logger
 	baseURL    string
+	options    options
+	now        func() time.Time
+	after      func(time.Duration) <-chan time.Time
 
 	// Session cache
 	sessionCache map[string]*sessionCacheEntry
 	cacheMutex   sync.RWMutex
+
+	// stopRefresher and refresherDone are set while the background
+	// refresher runs
+	stopRefresher context.CancelFunc
+	refresherDone chan struct{}
 }
 
+// sessionRetryInterval is how long the background refresher waits after
+// failing to create a session
+const sessionRetryInterval = 5 * time.Second
+
+// errSessionRejected is the cause of the error for a NOK session response
+var errSessionRejected = errors.New("session request rejected")
+
 type sessionCacheEntry struct {
 	sessionID string
 	expiresAt time.Time
+
+	// credentialsVersion is the version of the credentials the session was
+	// created with
+	credentialsVersion string
 }
 
 // NewSessionManager creates new session manager
@@ -36,31 +56,129 @@ func NewSessionManager(config rimpay.ProviderConfig, httpClient common.HTTPClien
 		httpClient:   httpClient,
 		logger:       logger,
 		baseURL:      strings.TrimRight(config.BaseURL, "/"),
+		options:      resolveOptions(config),
+		now:          time.Now,
+		after:        time.After,
 		sessionCache: make(map[string]*sessionCacheEntry),
 	}
 }
 
-// GetSessionID gets a valid session ID
+// GetSessionID gets a valid session ID for the configured merchant
 func (sm *SessionManager) GetSessionID(ctx context.Context) (string, error) {
-	merchantID := sm.config.Credentials["merchant_id"]
+	sessionID, _, err := sm.session(ctx, "")
+	return sessionID, err
+}
+
+// session returns a valid session ID and the merchant ID it belongs to:
+// merchantID, or the configured merchant when empty. Sessions are cached
+// per merchant. A session created with rotated credentials, or within the
+// refresh margin of its expiry, is replaced. A NOK session response is
+// retried once.
+func (sm *SessionManager) session(ctx context.Context, merchantID string) (_, _ string, err error) {
+	if merchantID == "" {
+		if merchantID, err = sm.config.Credential(ctx, "masrvi", rimpay.CredentialMerchantID); err != nil {
+			return "", "", common.CredentialsError(err, "masrvi")
+		}
+	}
+	version, err := sm.config.CredentialsVersion(ctx, "masrvi")
+	if err != nil {
+		return "", "", common.CredentialsError(err, "masrvi")
+	}
 
 	// Check cache first
 	sm.cacheMutex.RLock()
-	if entry, exists := sm.sessionCache[merchantID]; exists && time.Now().Before(entry.expiresAt) {
+	if entry, exists := sm.sessionCache[merchantID]; exists && entry.credentialsVersion == version &&
+		sm.now().Add(sm.options.refreshMargin).Before(entry.expiresAt) {
 		sessionID := entry.sessionID
 		sm.cacheMutex.RUnlock()
-		sm.logger.Debug("Using cached session ID", "session_id", sessionID)
-		return sessionID, nil
+		rimpay.ContextLogger(ctx, sm.logger).Debug("Using cached session ID", "session_id", sessionID)
+		return sessionID, merchantID, nil
 	}
 	sm.cacheMutex.RUnlock()
 
 	// Get new session
-	return sm.createSession(ctx, merchantID)
+	sessionID, err := sm.createSession(ctx, merchantID, version)
+	if errors.Is(err, errSessionRejected) {
+		rimpay.ContextLogger(ctx, sm.logger).Warn("MASRVI rejected the session request, retrying once")
+		sessionID, err = sm.createSession(ctx, merchantID, version)
+	}
+	return sessionID, merchantID, err
+}
+
+// expiry returns when the cached session of merchantID expires, or the zero
+// time when none is cached
+func (sm *SessionManager) expiry(merchantID string) time.Time {
+	sm.cacheMutex.RLock()
+	defer sm.cacheMutex.RUnlock()
+	if entry, ok := sm.sessionCache[merchantID]; ok {
+		return entry.expiresAt
+	}
+	return time.Time{}
+}
+
+// startRefresher keeps a session cached in the background until Close,
+// replacing it as it enters the refresh margin, so payments never wait for
+// one
+func (sm *SessionManager) startRefresher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sm.stopRefresher = cancel
+	sm.refresherDone = make(chan struct{})
+
+	go func() {
+		defer close(sm.refresherDone)
+		for {
+			wait := sm.refresh(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-sm.after(wait):
+			}
+		}
+	}()
+}
+
+// refresh makes sure a session is cached and returns how long until it
+// needs replacing
+func (sm *SessionManager) refresh(ctx context.Context) time.Duration {
+	_, merchantID, err := sm.session(ctx, "")
+	if err != nil {
+		if ctx.Err() == nil {
+			sm.logger.Warn("Failed to refresh MASRVI session", "error", err)
+		}
+		return sessionRetryInterval
+	}
+
+	sm.cacheMutex.RLock()
+	defer sm.cacheMutex.RUnlock()
+	entry, ok := sm.sessionCache[merchantID]
+	if !ok {
+		return sessionRetryInterval
+	}
+	return entry.expiresAt.Sub(sm.now().Add(sm.options.refreshMargin))
+}
+
+// stop stops the background refresher, if running, and waits for it
+func (sm *SessionManager) stop() {
+	if sm.stopRefresher == nil {
+		return
+	}
+	sm.stopRefresher()
+	<-sm.refresherDone
+}
+
+// Clear discards every cached session ID
+func (sm *SessionManager) Clear() {
+	sm.cacheMutex.Lock()
+	defer sm.cacheMutex.Unlock()
+	sm.sessionCache = make(map[string]*sessionCacheEntry)
 }
 
 // createSession creates a new session
-func (sm *SessionManager) createSession(ctx context.Context, merchantID string) (string, error) {
-	sessionURL := fmt.Sprintf("%s/online/online.php?merchantid=%s", sm.baseURL, merchantID)
+func (sm *SessionManager) createSession(ctx context.Context, merchantID, version string) (_ string, err error) {
+	ctx, span := common.StartSpan(ctx, sm.config.Tracer, "rimpay.masrvi.create_session", common.AttrProvider, "masrvi")
+	defer func() { common.EndSpan(span, err) }()
+
+	sessionURL := fmt.Sprintf("%s%s?merchantid=%s", sm.baseURL, sm.options.paymentPath, merchantID)
 
 	req := &common.HTTPRequest{
 		Method:  "GET",
@@ -69,31 +187,49 @@ func (sm *SessionManager) createSession(ctx context.Context, merchantID string)
 		Timeout: sm.config.Timeout,
 	}
 
-	sm.logger.Debug("Creating MASRVI session", "merchant_id", merchantID)
+	rimpay.ContextLogger(ctx, sm.logger).Debug("Creating MASRVI session", "url", common.RedactURL(sessionURL))
 
-	resp, err := sm.httpClient.Do(req)
+	resp, err := sm.httpClient.Do(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return "", common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError, "failed to create session", "masrvi", true,
+		).WithCause(common.RedactError(err)), req, nil)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("session creation failed with status: %d", resp.StatusCode)
+		return "", common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			fmt.Sprintf("session creation failed with status: %d", resp.StatusCode), "masrvi", true,
+		), req, resp)
 	}
 
 	sessionID := strings.TrimSpace(string(resp.Body))
-	if sessionID == "" || sessionID == "NOK" {
-		return "", fmt.Errorf("invalid session response: %s", sessionID)
+	if sessionID == "NOK" {
+		// Drop the cached session so nothing reuses it
+		sm.cacheMutex.Lock()
+		delete(sm.sessionCache, merchantID)
+		sm.cacheMutex.Unlock()
+		return "", common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError, "invalid session response: NOK", "masrvi", false,
+		).WithCause(errSessionRejected), req, resp)
+	}
+	if sessionID == "" {
+		return "", common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			fmt.Sprintf("invalid session response: %s", sessionID), "masrvi", false,
+		), req, resp)
 	}
 
-	// Cache the session (TTL: 5 minutes)
+	// Cache the session for the configured TTL
 	sm.cacheMutex.Lock()
 	sm.sessionCache[merchantID] = &sessionCacheEntry{
-		sessionID: sessionID,
-		expiresAt: time.Now().Add(5 * time.Minute),
+		sessionID:          sessionID,
+		expiresAt:          sm.now().Add(sm.options.sessionTTL),
+		credentialsVersion: version,
 	}
 	sm.cacheMutex.Unlock()
 
-	sm.logger.Info("MASRVI session created", "session_id", sessionID)
+	rimpay.ContextLogger(ctx, sm.logger).Info("MASRVI session created", "session_id", sessionID)
 
 	return sessionID, nil
 }
@@ -0,0 +1,305 @@
+package masrvi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secretMerchantID = "MERCHANT-SECRET-42"
+
+// capturingLogger renders every log line, fields included
+type capturingLogger struct {
+	lines []string
+}
+
+func (l *capturingLogger) log(msg string, fields []interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(append([]interface{}{msg}, fields...)...))
+}
+
+func (l *capturingLogger) Debug(msg string, fields ...interface{}) { l.log(msg, fields) }
+func (l *capturingLogger) Info(msg string, fields ...interface{})  { l.log(msg, fields) }
+func (l *capturingLogger) Warn(msg string, fields ...interface{})  { l.log(msg, fields) }
+func (l *capturingLogger) Error(msg string, fields ...interface{}) { l.log(msg, fields) }
+
+// failingTransport fails like net/http does, quoting the full request URL
+type failingTransport struct{}
+
+func (failingTransport) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	return nil, &url.Error{Op: "Get", URL: req.URL, Err: errors.New("connection refused")}
+}
+
+// errorChainText renders err, every error it wraps and its support bundle
+func errorChainText(t *testing.T, err error) string {
+	t.Helper()
+	var text []string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		text = append(text, e.Error())
+	}
+
+	var paymentErr *rimpay.PaymentError
+	if errors.As(err, &paymentErr) {
+		bundle, bundleErr := paymentErr.SupportBundle()
+		require.NoError(t, bundleErr)
+		text = append(text, string(bundle))
+	}
+	return strings.Join(text, "\n")
+}
+
+func TestSessionFailuresDoNotLeakMerchantID(t *testing.T) {
+	closed := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+	defaultClient, err := common.NewHTTPClient(common.HTTPConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		client  common.HTTPClient
+	}{
+		{"custom client transport error", "https://masrvi.test", failingTransport{}},
+		{"default client connection refused", closedURL, defaultClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &capturingLogger{}
+			config := optionsConfig(tt.client, nil)
+			config.BaseURL = tt.baseURL
+			config.Credentials["merchant_id"] = secretMerchantID
+			sm := NewSessionManager(config, tt.client, logger)
+
+			_, err := sm.GetSessionID(context.Background())
+			require.Error(t, err)
+
+			assert.NotContains(t, errorChainText(t, err), secretMerchantID)
+			assert.NotContains(t, strings.Join(logger.lines, "\n"), secretMerchantID)
+			assert.NotEmpty(t, logger.lines)
+		})
+	}
+}
+
+func TestPaymentSessionFailureDoesNotLeakMerchantID(t *testing.T) {
+	logger := &capturingLogger{}
+	config := optionsConfig(failingTransport{}, nil)
+	config.Credentials["merchant_id"] = secretMerchantID
+	provider, err := NewMasrviProvider(config, logger)
+	require.NoError(t, err)
+
+	_, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{Reference: "ORDER-1"})
+	require.Error(t, err)
+
+	assert.NotContains(t, errorChainText(t, err), secretMerchantID)
+	assert.NotContains(t, strings.Join(logger.lines, "\n"), secretMerchantID)
+}
+
+// sessionCounter hands out a new session ID on every request
+type sessionCounter struct {
+	sessions int
+}
+
+func (s *sessionCounter) Do(context.Context, *common.HTTPRequest) (*common.HTTPResponse, error) {
+	s.sessions++
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(fmt.Sprintf("SESSION-%d", s.sessions))}, nil
+}
+
+func TestCloseClearsSessionCache(t *testing.T) {
+	server := &sessionCounter{}
+	provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		id, err := provider.sessionManager.GetSessionID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "SESSION-1", id)
+	}
+
+	require.NoError(t, provider.Close())
+	require.NoError(t, provider.Close())
+
+	id, err := provider.sessionManager.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-2", id)
+}
+
+// versionedCredentials serves merchant IDs from a map and reports version
+type versionedCredentials struct {
+	version     string
+	credentials map[string]string
+}
+
+func (c *versionedCredentials) GetCredential(ctx context.Context, provider, key string) (string, error) {
+	return rimpay.StaticCredentials(c.credentials).GetCredential(ctx, provider, key)
+}
+
+func (c *versionedCredentials) CredentialsVersion(context.Context, string) (string, error) {
+	return c.version, nil
+}
+
+func TestRotatedCredentialsReplaceSession(t *testing.T) {
+	server := &sessionCounter{}
+	credentials := &versionedCredentials{version: "1", credentials: map[string]string{"merchant_id": "M1"}}
+	config := optionsConfig(server, nil)
+	config.Credentials = nil
+	config.CredentialsProvider = credentials
+	provider, err := NewMasrviProvider(config, nopLogger{})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		id, err := provider.sessionManager.GetSessionID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "SESSION-1", id)
+	}
+
+	credentials.version = "2"
+	id, err := provider.sessionManager.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-2", id, "a session of the previous credentials is not reused")
+
+	credentials.credentials = map[string]string{}
+	_, err = provider.ProcessPayment(context.Background(), &rimpay.PaymentRequest{Reference: "ORDER-1"})
+	require.ErrorIs(t, err, rimpay.ErrCredentialNotFound)
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.False(t, paymentErr.Retryable)
+}
+
+// scriptedSessions answers session requests with bodies in turn, then with
+// numbered session IDs
+type scriptedSessions struct {
+	mu       sync.Mutex
+	bodies   []string
+	requests int
+}
+
+func (s *scriptedSessions) Do(context.Context, *common.HTTPRequest) (*common.HTTPResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	body := fmt.Sprintf("SESSION-%d", s.requests)
+	if len(s.bodies) > 0 {
+		body, s.bodies = s.bodies[0], s.bodies[1:]
+	}
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(body)}, nil
+}
+
+func (s *scriptedSessions) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// fakeClock is a clock tests move by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newFakeClockSessions(server *scriptedSessions, opts map[string]interface{}) (*SessionManager, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	sm := NewSessionManager(optionsConfig(server, opts), server, nopLogger{})
+	sm.now = clock.Now
+	return sm, clock
+}
+
+func TestSessionRefreshMargin(t *testing.T) {
+	server := &scriptedSessions{}
+	sm, clock := newFakeClockSessions(server, nil)
+
+	id, err := sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-1", id)
+
+	clock.advance(5*time.Minute - 31*time.Second)
+	id, err = sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-1", id, "the session is outside the default 30s margin")
+
+	clock.advance(2 * time.Second)
+	id, err = sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-2", id, "a session about to expire is replaced before use")
+}
+
+func TestSessionNOKIsRetriedOnce(t *testing.T) {
+	server := &scriptedSessions{bodies: []string{"NOK"}}
+	sm, clock := newFakeClockSessions(server, nil)
+
+	id, err := sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-2", id)
+	assert.Equal(t, 2, server.count())
+
+	clock.advance(5 * time.Minute)
+	server.bodies = []string{"NOK", "NOK"}
+	_, err = sm.GetSessionID(context.Background())
+	require.ErrorIs(t, err, errSessionRejected)
+	assert.Equal(t, 4, server.count(), "only one retry")
+	assert.Empty(t, sm.sessionCache, "the rejected session is dropped")
+}
+
+func TestSessionRefresherKeepsSessionWarm(t *testing.T) {
+	server := &scriptedSessions{}
+	sm, clock := newFakeClockSessions(server, map[string]interface{}{OptionKeepSessionWarm: true})
+	waits := make(chan time.Duration)
+	ticks := make(chan time.Time)
+	sm.after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return ticks
+	}
+
+	sm.startRefresher()
+	assert.Equal(t, 4*time.Minute+30*time.Second, <-waits, "it wakes as the session enters the margin")
+	assert.Equal(t, 1, server.count())
+
+	clock.advance(4*time.Minute + 30*time.Second)
+	ticks <- clock.Now()
+	assert.Equal(t, 4*time.Minute+30*time.Second, <-waits)
+	assert.Equal(t, 2, server.count(), "the session was replaced in the background")
+
+	id, err := sm.GetSessionID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "SESSION-2", id)
+	assert.Equal(t, 2, server.count(), "payments use the warm session")
+
+	sm.stop()
+	sm.stop()
+}
+
+func TestProviderStartsAndStopsSessionRefresher(t *testing.T) {
+	server := &scriptedSessions{}
+	provider, err := NewMasrviProvider(optionsConfig(server, map[string]interface{}{OptionKeepSessionWarm: "true"}), nopLogger{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return server.count() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, provider.Close())
+	require.NoError(t, provider.Close())
+	assert.Empty(t, provider.sessionManager.sessionCache)
+}
@@ -0,0 +1,140 @@
+package masrvi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusServer hands out a session ID and answers status queries with body
+type statusServer struct {
+	status int
+	body   string
+	query  *common.HTTPRequest
+}
+
+func (s *statusServer) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	if req.Method == "GET" {
+		return &common.HTTPResponse{StatusCode: 200, Body: []byte("SESSION-1")}, nil
+	}
+	s.query = req
+	return &common.HTTPResponse{StatusCode: s.status, Body: []byte(s.body)}, nil
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    rimpay.PaymentStatus
+		message string
+	}{
+		{"success", "status=Ok&purchaseref=ORD-1&paymentref=PAY-7&payid=99", rimpay.PaymentStatusSuccess, "MASRVI status: Ok"},
+		{"failed", "status=NOK&purchaseref=ORD-1&paymentref=PAY-7&error=solde+insuffisant", rimpay.PaymentStatusFailed, "solde insuffisant"},
+		{"cancelled", "status=CANCEL&purchaseref=ORD-1&paymentref=PAY-7", rimpay.PaymentStatusCancelled, "MASRVI status: CANCEL"},
+		{"pending", "status=PENDING&paymentref=PAY-7", rimpay.PaymentStatusPending, "MASRVI status: PENDING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &statusServer{status: 200, body: tt.body}
+			provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+			require.NoError(t, err)
+
+			status, err := provider.GetPaymentStatus(context.Background(), "ORD-1")
+			require.NoError(t, err)
+
+			require.NotNil(t, server.query)
+			assert.Equal(t, "https://masrvi.test/online/status.php", server.query.URL)
+			assert.True(t, server.query.Idempotent)
+			form, err := url.ParseQuery(string(server.query.Body))
+			require.NoError(t, err)
+			assert.Equal(t, "SESSION-1", form.Get("sessionid"))
+			assert.Equal(t, "M1", form.Get("merchantid"))
+			assert.Equal(t, "ORD-1", form.Get("purchaseref"))
+
+			assert.Equal(t, tt.want, status.Status)
+			assert.Equal(t, "ORD-1", status.TransactionID)
+			assert.Equal(t, "ORD-1", status.Reference)
+			assert.Equal(t, "PAY-7", status.ProviderReference)
+			assert.Equal(t, tt.message, status.Message)
+			assert.Equal(t, "PAY-7", status.ProviderData["payment_ref"])
+			require.NotNil(t, status.LatestEvent())
+			assert.Equal(t, rimpay.EventSourcePoll, status.LatestEvent().Source)
+		})
+	}
+}
+
+func TestGetPaymentStatusNotSupported(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		server := &statusServer{status: 200, body: "status=Ok"}
+		provider, err := NewMasrviProvider(optionsConfig(server, map[string]interface{}{
+			OptionStatusPath: "",
+		}), nopLogger{})
+		require.NoError(t, err)
+
+		_, err = provider.GetPaymentStatus(context.Background(), "ORD-1")
+		assert.True(t, errors.Is(err, rimpay.ErrStatusNotSupported))
+		assert.Nil(t, server.query)
+	})
+
+	for _, code := range []int{404, 501} {
+		server := &statusServer{status: code}
+		provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+		require.NoError(t, err)
+
+		_, err = provider.GetPaymentStatus(context.Background(), "ORD-1")
+		assert.True(t, errors.Is(err, rimpay.ErrStatusNotSupported), "status %d", code)
+	}
+}
+
+func TestGetPaymentStatusErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+	}{
+		{"unparseable body", 200, "<html>", false},
+		{"server error", 502, "", true},
+		{"client error", 400, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &statusServer{status: tt.status, body: tt.body}
+			provider, err := NewMasrviProvider(optionsConfig(server, nil), nopLogger{})
+			require.NoError(t, err)
+
+			_, err = provider.GetPaymentStatus(context.Background(), "ORD-1")
+			var paymentErr *rimpay.PaymentError
+			require.True(t, errors.As(err, &paymentErr))
+			assert.Equal(t, rimpay.ErrorCodeProviderError, paymentErr.Code)
+			assert.Equal(t, tt.retryable, paymentErr.Retryable)
+			assert.False(t, errors.Is(err, rimpay.ErrStatusNotSupported))
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	provider, err := NewMasrviProvider(optionsConfig(&statusServer{}, nil), nopLogger{})
+	require.NoError(t, err)
+	capabilities := provider.Capabilities()
+	assert.True(t, capabilities.Has(rimpay.CapabilityRedirect))
+	assert.False(t, capabilities.Has(rimpay.CapabilityRefund), "refunds need refund_path")
+	assert.True(t, capabilities.Has(rimpay.CapabilityWebhooks))
+	assert.True(t, capabilities.Has(rimpay.CapabilityStatusQuery))
+	assert.False(t, capabilities.Has(rimpay.CapabilityPasscode))
+	assert.False(t, capabilities.SameFlow(rimpay.Capabilities{SupportsPasscode: true}))
+
+	provider, err = NewMasrviProvider(optionsConfig(&statusServer{}, map[string]interface{}{
+		OptionStatusPath: "",
+	}), nopLogger{})
+	require.NoError(t, err)
+	assert.False(t, provider.Capabilities().Has(rimpay.CapabilityStatusQuery), "status queries need status_path")
+}
@@ -0,0 +1,144 @@
+package mock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// Supported ProviderConfig.Options keys for the mock provider
+const (
+	// OptionLatency delays every call (duration, default 0)
+	OptionLatency = "latency"
+	// OptionSettleAfter is how long a payment stays pending before its status
+	// becomes final (duration, default 0)
+	OptionSettleAfter = "settle_after"
+	// OptionOutcomes maps reference prefixes to outcomes, adding to or
+	// replacing the default prefixes (map of strings)
+	OptionOutcomes = "outcomes"
+	// OptionDefaultOutcome applies to references matching no prefix (string,
+	// default success)
+	OptionDefaultOutcome = "default_outcome"
+	// OptionTransactionIDPrefix starts every transaction ID, followed by a
+	// sequence number (string, default MOCK-)
+	OptionTransactionIDPrefix = "transaction_id_prefix"
+	// OptionAvailable is what IsAvailable reports (bool, default true)
+	OptionAvailable = "available"
+)
+
+// Outcomes a payment can be scripted to have
+const (
+	// OutcomeSuccess creates a pending payment that settles as successful
+	OutcomeSuccess = "success"
+	// OutcomeFailed creates a pending payment that settles as failed
+	OutcomeFailed = "failed"
+	// OutcomeCancelled creates a pending payment that settles as cancelled
+	OutcomeCancelled = "cancelled"
+	// OutcomePending creates a payment that never settles
+	OutcomePending = "pending"
+	// OutcomeInsufficientFunds rejects the payment for insufficient funds
+	OutcomeInsufficientFunds = "insufficient_funds"
+	// OutcomeDeclined rejects the payment as declined
+	OutcomeDeclined = "declined"
+	// OutcomeProviderError fails every attempt with a retryable provider error
+	OutcomeProviderError = "provider_error"
+	// OutcomeFlaky fails the first attempt for a reference with a retryable
+	// provider error and succeeds afterwards
+	OutcomeFlaky = "flaky"
+	// OutcomeTimeout blocks until the context ends or the provider timeout
+	// elapses, then fails with a timeout
+	OutcomeTimeout = "timeout"
+)
+
+// defaultOutcomes are the reference prefixes scripted out of the box
+var defaultOutcomes = map[string]string{
+	"FAIL-":    OutcomeInsufficientFunds,
+	"DECLINE-": OutcomeDeclined,
+	"REJECT-":  OutcomeFailed,
+	"CANCEL-":  OutcomeCancelled,
+	"PENDING-": OutcomePending,
+	"ERROR-":   OutcomeProviderError,
+	"FLAKY-":   OutcomeFlaky,
+	"TIMEOUT-": OutcomeTimeout,
+}
+
+var knownOutcomes = map[string]bool{
+	OutcomeSuccess: true, OutcomeFailed: true, OutcomeCancelled: true, OutcomePending: true,
+	OutcomeInsufficientFunds: true, OutcomeDeclined: true, OutcomeProviderError: true,
+	OutcomeFlaky: true, OutcomeTimeout: true,
+}
+
+// options holds the resolved mock tunables
+type options struct {
+	latency        time.Duration
+	settleAfter    time.Duration
+	outcomes       map[string]string
+	defaultOutcome string
+	idPrefix       string
+	available      bool
+}
+
+// parseOptions reads and validates the mock options in config
+func parseOptions(config rimpay.ProviderConfig) (options, error) {
+	opts := options{outcomes: make(map[string]string, len(defaultOutcomes))}
+	for prefix, outcome := range defaultOutcomes {
+		opts.outcomes[prefix] = outcome
+	}
+
+	if err := config.CheckOptions(OptionLatency, OptionSettleAfter, OptionOutcomes, OptionDefaultOutcome,
+		OptionTransactionIDPrefix, OptionAvailable); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if opts.latency, err = config.DurationOption(OptionLatency, 0); err != nil {
+		return opts, err
+	}
+	if opts.settleAfter, err = config.DurationOption(OptionSettleAfter, 0); err != nil {
+		return opts, err
+	}
+	if opts.latency < 0 || opts.settleAfter < 0 {
+		return opts, fmt.Errorf("options %s and %s cannot be negative", OptionLatency, OptionSettleAfter)
+	}
+
+	outcomes, err := config.StringMapOption(OptionOutcomes)
+	if err != nil {
+		return opts, err
+	}
+	for prefix, outcome := range outcomes {
+		if !knownOutcomes[outcome] {
+			return opts, fmt.Errorf("option %s: unknown outcome %q for prefix %q", OptionOutcomes, outcome, prefix)
+		}
+		opts.outcomes[prefix] = outcome
+	}
+
+	if opts.defaultOutcome, err = config.StringOption(OptionDefaultOutcome, OutcomeSuccess); err != nil {
+		return opts, err
+	}
+	if !knownOutcomes[opts.defaultOutcome] {
+		return opts, fmt.Errorf("option %s: unknown outcome %q", OptionDefaultOutcome, opts.defaultOutcome)
+	}
+
+	if opts.idPrefix, err = config.StringOption(OptionTransactionIDPrefix, "MOCK-"); err != nil {
+		return opts, err
+	}
+
+	if opts.available, err = config.BoolOption(OptionAvailable, true); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// outcome returns the outcome of the longest prefix matching reference
+func (o options) outcome(reference string) string {
+	outcome, matched := o.defaultOutcome, -1
+	for prefix, candidate := range o.outcomes {
+		if strings.HasPrefix(reference, prefix) && len(prefix) > matched {
+			outcome, matched = candidate, len(prefix)
+		}
+	}
+	return outcome
+}
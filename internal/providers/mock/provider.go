@@ -0,0 +1,288 @@
+// Package mock implements an in-process sandbox payment provider. Payments
+// follow outcomes scripted by reference prefix, so applications can test the
+// rimpay Client end to end without credentials or network access.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// Register the mock provider with the client and the default registry
+func init() {
+	factory := func(config rimpay.ProviderConfig, logger rimpay.Logger) (rimpay.PaymentProvider, error) {
+		return NewMockProvider(config, logger)
+	}
+	rimpay.RegisterMockProvider(factory)
+	rimpay.DefaultRegistry.Register(rimpay.ProviderMock, factory)
+}
+
+// payment is a payment created by the mock provider
+type payment struct {
+	transactionID string
+	reference     string
+	amount        money.Money
+	createdAt     time.Time
+	outcome       string
+}
+
+// Provider implements the mock payment provider
+type Provider struct {
+	config        rimpay.ProviderConfig
+	options       options
+	retryExecutor *common.RetryExecutor
+	logger        rimpay.Logger
+	now           func() time.Time
+
+	mu         sync.Mutex
+	sequence   int
+	payments   map[string]*payment
+	references map[string]string
+	attempts   map[string]int
+}
+
+// NewMockProvider creates a mock provider from the options in config
+func NewMockProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Provider, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid mock configuration: %w", err)
+	}
+	opts, _ := parseOptions(config)
+	retryExecutor := common.NewProviderRetryExecutor(rimpay.ProviderMock, config.Retry, config.Metrics, config.Tracer)
+
+	return &Provider{
+		config:        config,
+		options:       opts,
+		retryExecutor: retryExecutor,
+		logger:        common.NewRedactingLogger(logger),
+		now:           time.Now,
+		payments:      make(map[string]*payment),
+		references:    make(map[string]string),
+		attempts:      make(map[string]int),
+	}, nil
+}
+
+// Name returns provider name
+func (p *Provider) Name() string {
+	return rimpay.ProviderMock
+}
+
+// IsAvailable reports the available option
+func (p *Provider) IsAvailable(ctx context.Context) bool {
+	return p.options.available
+}
+
+// ValidateConfig validates provider configuration
+func (p *Provider) ValidateConfig() error {
+	return validateConfig(p.config)
+}
+
+// Capabilities describes the mock provider, which answers status queries
+// and refunds from memory
+func (p *Provider) Capabilities() rimpay.Capabilities {
+	return rimpay.Capabilities{
+		SupportsRefund:      true,
+		SupportsStatusQuery: true,
+	}
+}
+
+func validateConfig(config rimpay.ProviderConfig) error {
+	if _, err := parseOptions(config); err != nil {
+		return err
+	}
+	return common.ValidateRetryConfig(config.Retry)
+}
+
+// ProcessPayment creates a payment with the outcome scripted for its
+// reference, retrying retryable failures like the real providers do
+func (p *Provider) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return p.retryExecutor.ExecutePayment(ctx, func(ctx context.Context) (*rimpay.PaymentResponse, error) {
+		return p.pay(ctx, request)
+	})
+}
+
+// pay makes a single payment attempt
+func (p *Provider) pay(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
+	if err := p.wait(ctx, p.options.latency); err != nil {
+		return nil, err
+	}
+
+	outcome := p.options.outcome(request.Reference)
+	switch outcome {
+	case OutcomeInsufficientFunds:
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeInsufficientFunds, "insufficient funds", rimpay.ProviderMock, false)
+	case OutcomeDeclined:
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodePaymentDeclined, "payment declined", rimpay.ProviderMock, false)
+	case OutcomeProviderError:
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeProviderError, "mock provider error", rimpay.ProviderMock, true)
+	case OutcomeFlaky:
+		p.mu.Lock()
+		p.attempts[request.Reference]++
+		first := p.attempts[request.Reference] == 1
+		p.mu.Unlock()
+		if first {
+			return nil, rimpay.NewPaymentError(rimpay.ErrorCodeProviderError, "mock provider error", rimpay.ProviderMock, true)
+		}
+	case OutcomeTimeout:
+		timeout := p.config.Timeout
+		if timeout <= 0 {
+			timeout = time.Hour
+		}
+		if err := p.wait(ctx, timeout); err != nil {
+			return nil, err
+		}
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeTimeout, "mock payment timed out", rimpay.ProviderMock, true)
+	}
+
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sequence++
+	response := &rimpay.PaymentResponse{
+		TransactionID: fmt.Sprintf("%s%06d", p.options.idPrefix, p.sequence),
+		Status:        rimpay.PaymentStatusPending,
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      rimpay.ProviderMock,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Metadata: map[string]interface{}{
+			"outcome": outcome,
+		},
+		Events: []rimpay.StatusEvent{
+			rimpay.NewStatusEvent(rimpay.PaymentStatusPending, "Payment initiated", rimpay.EventSourceInitial),
+		},
+	}
+	p.payments[response.TransactionID] = &payment{
+		transactionID: response.TransactionID,
+		reference:     request.Reference,
+		amount:        request.Amount,
+		createdAt:     now,
+		outcome:       outcome,
+	}
+	p.references[request.Reference] = response.TransactionID
+
+	rimpay.ContextLogger(ctx, p.logger).Info("Mock payment created",
+		"transaction_id", response.TransactionID,
+		"reference", request.Reference,
+		"outcome", outcome,
+	)
+
+	return response, nil
+}
+
+// wait sleeps for d, failing with a retryable timeout when ctx ends first
+func (p *Provider) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return rimpay.NewPaymentError(rimpay.ErrorCodeTimeout, "mock call interrupted", rimpay.ProviderMock, true).
+			WithCause(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// lookup returns the payment with transactionID, else with that reference
+func (p *Provider) lookup(transactionID string) (*payment, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pay, ok := p.payments[transactionID]; ok {
+		return pay, true
+	}
+	pay, ok := p.payments[p.references[transactionID]]
+	return pay, ok
+}
+
+// status returns the status of pay: pending until settle_after has passed,
+// then the final status of its outcome
+func (p *Provider) status(pay *payment) rimpay.PaymentStatus {
+	if pay.outcome == OutcomePending || p.now().Before(pay.createdAt.Add(p.options.settleAfter)) {
+		return rimpay.PaymentStatusPending
+	}
+	switch pay.outcome {
+	case OutcomeFailed:
+		return rimpay.PaymentStatusFailed
+	case OutcomeCancelled:
+		return rimpay.PaymentStatusCancelled
+	default:
+		return rimpay.PaymentStatusSuccess
+	}
+}
+
+// GetPaymentStatus returns the status of the payment with transactionID or
+// with that reference
+func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
+	if transactionID == "" {
+		return nil, rimpay.NewValidationError("transactionID", "transaction ID cannot be empty")
+	}
+	if err := p.wait(ctx, p.options.latency); err != nil {
+		return nil, err
+	}
+
+	pay, ok := p.lookup(transactionID)
+	if !ok {
+		return nil, fmt.Errorf("mock transaction %s: %w", transactionID, rimpay.ErrTransactionNotFound)
+	}
+
+	status := p.status(pay)
+	result := &rimpay.TransactionStatus{
+		TransactionID:     pay.transactionID,
+		Amount:            pay.amount,
+		Reference:         pay.reference,
+		ProviderReference: pay.transactionID,
+		Message:           "Mock payment " + string(status),
+		ProviderData: map[string]interface{}{
+			"outcome": pay.outcome,
+		},
+	}
+	result.AddEvent(status, result.Message, rimpay.EventSourcePoll)
+	return result, nil
+}
+
+// Refund refunds a successful payment
+func (p *Provider) Refund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if err := p.wait(ctx, p.options.latency); err != nil {
+		return nil, err
+	}
+
+	pay, ok := p.lookup(request.TransactionID)
+	if !ok {
+		return nil, fmt.Errorf("mock transaction %s: %w", request.TransactionID, rimpay.ErrTransactionNotFound)
+	}
+	if p.status(pay) != rimpay.PaymentStatusSuccess {
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeInvalidRequest,
+			"only successful payments can be refunded", rimpay.ProviderMock, false)
+	}
+
+	p.mu.Lock()
+	p.sequence++
+	refundID := fmt.Sprintf("%sR%06d", p.options.idPrefix, p.sequence)
+	p.mu.Unlock()
+
+	return &rimpay.RefundResponse{
+		RefundID:      refundID,
+		TransactionID: pay.transactionID,
+		Status:        rimpay.PaymentStatusSuccess,
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      rimpay.ProviderMock,
+		Partial:       request.IsPartial(),
+		CreatedAt:     p.now(),
+	}, nil
+}
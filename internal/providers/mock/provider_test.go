@@ -0,0 +1,268 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+func mockConfig(options map[string]interface{}) rimpay.ProviderConfig {
+	return rimpay.ProviderConfig{
+		Enabled: true,
+		Timeout: time.Second,
+		Options: options,
+		Retry: &rimpay.RetryConfig{
+			MaxAttempts:  3,
+			InitialDelay: time.Millisecond,
+			MaxDelay:     time.Millisecond,
+			Multiplier:   1,
+		},
+	}
+}
+
+func newMockClient(t *testing.T, options map[string]interface{}) *rimpay.Client {
+	t.Helper()
+	config := rimpay.DefaultConfig()
+	config.DefaultProvider = rimpay.ProviderMock
+	config.Providers[rimpay.ProviderMock] = mockConfig(options)
+	client, err := rimpay.NewClient(config)
+	require.NoError(t, err)
+	client.WithLogger(nopLogger{})
+	require.NoError(t, client.AddMockProvider(config.Providers[rimpay.ProviderMock]))
+	return client
+}
+
+func paymentRequest(t *testing.T, reference string) *rimpay.PaymentRequest {
+	t.Helper()
+	phoneNum, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &rimpay.PaymentRequest{
+		PhoneNumber: phoneNum,
+		Amount:      money.FromFloat64(100, money.MRU),
+		Reference:   reference,
+	}
+}
+
+func TestClientEndToEnd(t *testing.T) {
+	client := newMockClient(t, nil)
+	ctx := context.Background()
+
+	first, err := client.ProcessPayment(ctx, paymentRequest(t, "ORDER-1"))
+	require.NoError(t, err)
+	second, err := client.ProcessPayment(ctx, paymentRequest(t, "ORDER-2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "MOCK-000001", first.TransactionID)
+	assert.Equal(t, "MOCK-000002", second.TransactionID)
+	assert.Equal(t, rimpay.PaymentStatusPending, first.Status)
+	assert.Equal(t, rimpay.ProviderMock, first.Provider)
+
+	status, err := client.GetPaymentStatus(ctx, first.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, status.Status)
+	assert.Equal(t, "ORDER-1", status.Reference)
+
+	byReference, err := client.GetPaymentStatus(ctx, "ORDER-2")
+	require.NoError(t, err)
+	assert.Equal(t, second.TransactionID, byReference.TransactionID)
+
+	_, err = client.GetPaymentStatus(ctx, "MOCK-999999")
+	assert.True(t, errors.Is(err, rimpay.ErrTransactionNotFound))
+}
+
+func TestScriptedOutcomes(t *testing.T) {
+	tests := []struct {
+		reference string
+		code      rimpay.ErrorCode
+		attempts  int
+	}{
+		{"FAIL-1", rimpay.ErrorCodeInsufficientFunds, 1},
+		{"DECLINE-1", rimpay.ErrorCodePaymentDeclined, 1},
+		{"ERROR-1", rimpay.ErrorCodeProviderError, 3},
+		{"CUSTOM-1", rimpay.ErrorCodePaymentDeclined, 1},
+		{"FAIL-OVERRIDE-1", rimpay.ErrorCodeProviderError, 3},
+	}
+
+	client := newMockClient(t, map[string]interface{}{
+		OptionOutcomes: map[string]interface{}{
+			"CUSTOM-":        OutcomeDeclined,
+			"FAIL-OVERRIDE-": OutcomeProviderError,
+		},
+	})
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			_, err := client.ProcessPayment(context.Background(), paymentRequest(t, tt.reference))
+			var paymentErr *rimpay.PaymentError
+			require.True(t, errors.As(err, &paymentErr), "error %v", err)
+			assert.Equal(t, tt.code, paymentErr.Code)
+			assert.Equal(t, tt.attempts, paymentErr.Details[rimpay.DetailAttemptCount])
+		})
+	}
+}
+
+func TestFlakyOutcomeSucceedsOnRetry(t *testing.T) {
+	client := newMockClient(t, nil)
+
+	resp, err := client.ProcessPayment(context.Background(), paymentRequest(t, "FLAKY-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "MOCK-000001", resp.TransactionID)
+}
+
+func TestTimeoutOutcome(t *testing.T) {
+	provider, err := NewMockProvider(mockConfig(nil), nopLogger{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = provider.ProcessPayment(ctx, paymentRequest(t, "TIMEOUT-1"))
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
+
+func TestStatusSettlesAfterDelay(t *testing.T) {
+	provider, err := NewMockProvider(mockConfig(map[string]interface{}{
+		OptionSettleAfter: "30s",
+	}), nopLogger{})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	provider.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expected := map[string]rimpay.PaymentStatus{
+		"ORDER-1":   rimpay.PaymentStatusSuccess,
+		"REJECT-1":  rimpay.PaymentStatusFailed,
+		"CANCEL-1":  rimpay.PaymentStatusCancelled,
+		"PENDING-1": rimpay.PaymentStatusPending,
+	}
+	for reference := range expected {
+		_, err := provider.ProcessPayment(ctx, paymentRequest(t, reference))
+		require.NoError(t, err)
+
+		status, err := provider.GetPaymentStatus(ctx, reference)
+		require.NoError(t, err)
+		assert.Equal(t, rimpay.PaymentStatusPending, status.Status, reference)
+	}
+
+	now = now.Add(30 * time.Second)
+	for reference, want := range expected {
+		status, err := provider.GetPaymentStatus(ctx, reference)
+		require.NoError(t, err)
+		assert.Equal(t, want, status.Status, reference)
+		assert.Equal(t, rimpay.EventSourcePoll, status.LatestEvent().Source)
+	}
+}
+
+func TestFailoverToMock(t *testing.T) {
+	config := rimpay.DefaultConfig()
+	config.DefaultProvider = rimpay.ProviderMock
+	config.Providers[rimpay.ProviderMock] = mockConfig(nil)
+	primaryConfig := mockConfig(map[string]interface{}{OptionDefaultOutcome: OutcomeProviderError})
+	primaryConfig.BaseURL = "https://primary.test"
+	config.Providers["primary"] = primaryConfig
+	config.Failover = rimpay.FailoverPolicy{Providers: []string{"primary", rimpay.ProviderMock}}
+	client, err := rimpay.NewClient(config)
+	require.NoError(t, err)
+	client.WithLogger(nopLogger{})
+
+	primary, err := NewMockProvider(primaryConfig, nopLogger{})
+	require.NoError(t, err)
+	require.NoError(t, client.AddProvider("primary", primary))
+	require.NoError(t, client.AddMockProvider(config.Providers[rimpay.ProviderMock]))
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), paymentRequest(t, "ORDER-1"))
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.ProviderMock, resp.Provider)
+	assert.Equal(t, []string{"primary", rimpay.ProviderMock}, resp.Metadata[rimpay.MetadataFailoverPath])
+}
+
+func TestRefund(t *testing.T) {
+	client := newMockClient(t, nil)
+	ctx := context.Background()
+	resp, err := client.ProcessPayment(ctx, paymentRequest(t, "ORDER-1"))
+	require.NoError(t, err)
+
+	refund, err := client.Refund(ctx, &rimpay.RefundRequest{
+		TransactionID:  resp.TransactionID,
+		Amount:         money.FromFloat64(40, money.MRU),
+		OriginalAmount: resp.Amount,
+		Reference:      "RF-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rimpay.PaymentStatusSuccess, refund.Status)
+	assert.True(t, refund.Partial)
+}
+
+func TestInvalidOptionsRejected(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown key":      {"latancy": "1s"},
+		"negative latency": {OptionLatency: "-1s"},
+		"unknown outcome":  {OptionOutcomes: map[string]string{"X-": "explode"}},
+		"bad default":      {OptionDefaultOutcome: "maybe"},
+		"non-bool":         {OptionAvailable: 3},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMockProvider(mockConfig(opts), nopLogger{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+// recordingMetrics keeps every measurement it receives
+type recordingMetrics struct {
+	mu       sync.Mutex
+	payments []string
+	retries  []string
+}
+
+func (m *recordingMetrics) ObservePayment(provider, status string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.payments = append(m.payments, provider+":"+status)
+}
+
+func (m *recordingMetrics) IncRetry(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, provider)
+}
+
+func (m *recordingMetrics) ObserveHTTP(string, string, int, time.Duration) {}
+
+func TestClientReportsMetrics(t *testing.T) {
+	config := rimpay.DefaultConfig()
+	config.DefaultProvider = rimpay.ProviderMock
+	config.Providers[rimpay.ProviderMock] = mockConfig(nil)
+	client, err := rimpay.NewClient(config)
+	require.NoError(t, err)
+
+	metrics := &recordingMetrics{}
+	client.WithLogger(nopLogger{}).WithMetrics(metrics)
+	require.NoError(t, client.AddMockProvider(config.Providers[rimpay.ProviderMock]))
+
+	_, err = client.ProcessPayment(context.Background(), paymentRequest(t, "ORDER-1"))
+	require.NoError(t, err)
+	_, err = client.ProcessPayment(context.Background(), paymentRequest(t, "FLAKY-1"))
+	require.NoError(t, err)
+	_, err = client.ProcessPayment(context.Background(), paymentRequest(t, "DECLINE-1"))
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"mock:pending", "mock:pending", "mock:PAYMENT_DECLINED"}, metrics.payments)
+	assert.Equal(t, []string{"mock"}, metrics.retries)
+}
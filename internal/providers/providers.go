@@ -3,6 +3,7 @@ package providers
 import (
 	"github.com/CatoSystems/rim-pay/internal/providers/bpay"
 	"github.com/CatoSystems/rim-pay/internal/providers/masrvi"
+	"github.com/CatoSystems/rim-pay/internal/providers/sedad"
 	"github.com/CatoSystems/rim-pay/pkg/rimpay"
 )
 
@@ -17,4 +18,9 @@ func RegisterAll(registry *rimpay.ProviderRegistry) {
 	registry.Register("masrvi", func(config rimpay.ProviderConfig, logger rimpay.Logger) (rimpay.PaymentProvider, error) {
 		return masrvi.NewProvider(config, logger)
 	})
+
+	// Register Sedad provider
+	registry.Register("sedad", func(config rimpay.ProviderConfig, logger rimpay.Logger) (rimpay.PaymentProvider, error) {
+		return sedad.NewProvider(config, logger)
+	})
 }
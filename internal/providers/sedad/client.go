@@ -0,0 +1,178 @@
+package sedad
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// Register the Sedad provider with the client
+func init() {
+	rimpay.RegisterSedadProvider(func(config rimpay.ProviderConfig, logger rimpay.Logger) (rimpay.PaymentProvider, error) {
+		return NewSedadProvider(config, logger)
+	})
+}
+
+// Supported ProviderConfig.Options keys for Sedad
+const (
+	// OptionBillerCode is the biller of generic payment requests, which carry
+	// no biller_code metadata (string, optional)
+	OptionBillerCode = "biller_code"
+	// OptionCallbackURL is the default webhook URL sent with payments
+	// (string, optional)
+	OptionCallbackURL = "callback_url"
+)
+
+// Provider implements the Sedad payment provider
+type Provider struct {
+	name             string
+	config           rimpay.ProviderConfig
+	httpClient       common.HTTPClient
+	paymentProcessor *PaymentProcessor
+	retryExecutor    *common.RetryExecutor
+	logger           rimpay.Logger
+}
+
+// NewProvider creates a new Sedad provider (alias for registry use)
+func NewProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Provider, error) {
+	return NewSedadProvider(config, logger)
+}
+
+// NewSedadProvider creates a new Sedad provider
+func NewSedadProvider(config rimpay.ProviderConfig, logger rimpay.Logger) (*Provider, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid Sedad configuration: %w", err)
+	}
+
+	logger = common.NewRedactingLogger(logger)
+
+	baseClient, err := common.ResolveHTTPClient(config.HTTPClient, config.HTTP, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sedad configuration: %w", err)
+	}
+	httpClient, err := common.RateLimitHTTPClient(
+		common.InstrumentHTTPClient(baseClient, config.Metrics, config.Tracer, "sedad"), config.RateLimit, "sedad")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sedad configuration: %w", err)
+	}
+	paymentProcessor := NewPaymentProcessor(config, httpClient, logger)
+	retryExecutor := common.NewProviderRetryExecutor("sedad", config.Retry, config.Metrics, config.Tracer)
+
+	return &Provider{
+		name:             "sedad",
+		config:           config,
+		httpClient:       httpClient,
+		paymentProcessor: paymentProcessor,
+		retryExecutor:    retryExecutor,
+		logger:           logger,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// IsAvailable checks if the provider is available
+func (p *Provider) IsAvailable(ctx context.Context) bool {
+	return p.CheckHealth(ctx) == nil
+}
+
+// CheckHealth reports why the provider is unavailable, keeping TLS
+// certificate failures distinct from other errors
+func (p *Provider) CheckHealth(ctx context.Context) error {
+	err := p.paymentProcessor.Ping(ctx)
+	if transportErr, ok := common.AsTransportError(err, p.name); ok {
+		return transportErr
+	}
+	return err
+}
+
+// ProcessSedadPayment processes a Sedad payment using provider-specific request
+func (p *Provider) ProcessSedadPayment(ctx context.Context, request *rimpay.SedadPaymentRequest) (*rimpay.PaymentResponse, error) {
+	if request == nil {
+		return nil, rimpay.NewValidationError("request", "payment request cannot be nil")
+	}
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p.ProcessPayment(ctx, request.ToGenericRequest())
+}
+
+// ProcessPayment processes a generic payment request with retry
+func (p *Provider) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) {
+	return p.retryExecutor.ExecutePayment(ctx, func(ctx context.Context) (*rimpay.PaymentResponse, error) {
+		return p.paymentProcessor.ProcessPayment(ctx, request)
+	})
+}
+
+// GetPaymentStatus queries the status of the Sedad payment transactionID
+func (p *Provider) GetPaymentStatus(ctx context.Context, transactionID string) (*rimpay.TransactionStatus, error) {
+	return p.paymentProcessor.CheckPaymentStatus(ctx, transactionID)
+}
+
+// Refund is not offered by the Sedad API; refunds go through the biller
+func (p *Provider) Refund(ctx context.Context, request *rimpay.RefundRequest) (*rimpay.RefundResponse, error) {
+	return nil, rimpay.ErrRefundNotSupported
+}
+
+// HandleNotification converts a Sedad webhook notification into a
+// TransactionStatus
+func (p *Provider) HandleNotification(notification *rimpay.SedadNotificationData) (*rimpay.TransactionStatus, error) {
+	return p.paymentProcessor.HandleNotification(notification)
+}
+
+// Close closes idle connections
+func (p *Provider) Close() error {
+	common.CloseIdleConnections(p.httpClient)
+	return nil
+}
+
+// ValidateConfig validates provider configuration
+func (p *Provider) ValidateConfig() error {
+	return validateConfig(p.config)
+}
+
+// Capabilities describes Sedad: invoices can be queried and are reported by
+// webhook, but not refunded
+func (p *Provider) Capabilities() rimpay.Capabilities {
+	return rimpay.Capabilities{
+		SupportsStatusQuery: true,
+		SupportsWebhooks:    true,
+	}
+}
+
+// validateConfig validates Sedad configuration
+func validateConfig(config rimpay.ProviderConfig) error {
+	requiredCredentials := []string{"api_key", "merchant_id"}
+
+	// Credentials from a CredentialsProvider are only read when needed
+	for _, field := range requiredCredentials {
+		if !config.HasCredentialsProvider() && config.Credentials[field] == "" {
+			return fmt.Errorf("missing required credential: %s", field)
+		}
+	}
+
+	if config.BaseURL == "" {
+		return fmt.Errorf("base_url is required")
+	}
+
+	if config.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	if err := config.CheckOptions(OptionBillerCode, OptionCallbackURL); err != nil {
+		return err
+	}
+	for _, option := range []string{OptionBillerCode, OptionCallbackURL} {
+		if _, err := config.StringOption(option, ""); err != nil {
+			return err
+		}
+	}
+
+	return common.ValidateRetryConfig(config.Retry)
+}
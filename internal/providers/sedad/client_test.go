@@ -0,0 +1,81 @@
+package sedad
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderImplementsInterface(t *testing.T) {
+	var _ rimpay.SedadProvider = (*Provider)(nil)
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(config *rimpay.ProviderConfig)
+		wantError string
+	}{
+		{"valid config", func(*rimpay.ProviderConfig) {}, ""},
+		{"missing api key", func(c *rimpay.ProviderConfig) { delete(c.Credentials, "api_key") }, "missing required credential: api_key"},
+		{"missing merchant id", func(c *rimpay.ProviderConfig) { delete(c.Credentials, "merchant_id") }, "missing required credential: merchant_id"},
+		{"credentials provider", func(c *rimpay.ProviderConfig) {
+			c.Credentials = nil
+			c.CredentialsProvider = rimpay.StaticCredentials{"api_key": "key", "merchant_id": "M-1"}
+		}, ""},
+		{"missing base URL", func(c *rimpay.ProviderConfig) { c.BaseURL = "" }, "base_url is required"},
+		{"invalid timeout", func(c *rimpay.ProviderConfig) { c.Timeout = 0 }, "timeout must be positive"},
+		{"unknown option", func(c *rimpay.ProviderConfig) { c.Options = map[string]interface{}{"biller": "X"} }, "biller"},
+		{"biller code not a string", func(c *rimpay.ProviderConfig) { c.Options = map[string]interface{}{OptionBillerCode: 7} }, "option biller_code must be a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(&stubHTTP{})
+			tt.modify(&config)
+			err := validateConfig(config)
+			if tt.wantError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	stub := &stubHTTP{}
+	provider, err := NewSedadProvider(testConfig(stub), nopLogger{})
+	require.NoError(t, err)
+
+	assert.True(t, provider.IsAvailable(context.Background()))
+	assert.Equal(t, "https://sedad.test/v1/ping", stub.requests[0].URL)
+
+	stub.statuses = []int{401}
+	err = provider.CheckHealth(context.Background())
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, rimpay.ErrorCodeAuthenticationFailed, paymentErr.Code)
+}
+
+func TestRefundNotSupported(t *testing.T) {
+	provider, err := NewSedadProvider(testConfig(&stubHTTP{}), nopLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.Refund(context.Background(), &rimpay.RefundRequest{TransactionID: "PAY-1"})
+	assert.ErrorIs(t, err, rimpay.ErrRefundNotSupported)
+}
+
+func testConfig(stub *stubHTTP) rimpay.ProviderConfig {
+	return rimpay.ProviderConfig{
+		BaseURL:     "https://sedad.test/",
+		Credentials: map[string]string{"api_key": "sk_test_123", "merchant_id": "M-100"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  stub,
+	}
+}
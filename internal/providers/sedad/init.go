@@ -0,0 +1,10 @@
+package sedad
+
+import "github.com/CatoSystems/rim-pay/pkg/rimpay"
+
+// init registers the sedad provider with the default registry
+func init() {
+	rimpay.DefaultRegistry.Register("sedad", func(config rimpay.ProviderConfig, logger rimpay.Logger) (rimpay.PaymentProvider, error) {
+		return NewProvider(config, logger)
+	})
+}
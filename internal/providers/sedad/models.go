@@ -0,0 +1,101 @@
+package sedad
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// PaymentRequest represents Sedad payment initiation request
+type PaymentRequest struct {
+	MerchantID    string `json:"merchantId"`
+	BillerCode    string `json:"billerCode"`
+	InvoiceNumber string `json:"invoiceNumber"`
+	Reference     string `json:"reference"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	CustomerPhone string `json:"customerPhone"`
+	Description   string `json:"description,omitempty"`
+	CallbackURL   string `json:"callbackUrl,omitempty"`
+}
+
+// PaymentResponse represents Sedad payment initiation response
+type PaymentResponse struct {
+	ResultCode    string `json:"resultCode"`
+	ResultMessage string `json:"resultMessage"`
+	PaymentID     string `json:"paymentId"`
+	Status        string `json:"status"`
+}
+
+// StatusResponse represents Sedad payment status response
+type StatusResponse struct {
+	ResultCode    string `json:"resultCode"`
+	ResultMessage string `json:"resultMessage"`
+	PaymentID     string `json:"paymentId"`
+	Reference     string `json:"reference"`
+	InvoiceNumber string `json:"invoiceNumber"`
+	Status        string `json:"status"`
+	Amount        string `json:"amount"`
+	PaidAt        string `json:"paidAt,omitempty"`
+}
+
+// resultSuccess is the resultCode of an accepted request
+const resultSuccess = "00"
+
+// convertStatus converts a Sedad payment status to payment status
+func convertStatus(status string) rimpay.PaymentStatus {
+	switch strings.ToUpper(status) {
+	case "PAID":
+		return rimpay.PaymentStatusSuccess
+	case "FAILED", "REJECTED":
+		return rimpay.PaymentStatusFailed
+	case "CANCELLED":
+		return rimpay.PaymentStatusCancelled
+	case "EXPIRED":
+		return rimpay.PaymentStatusExpired
+	default:
+		return rimpay.PaymentStatusPending // INITIATED, PENDING
+	}
+}
+
+// businessError describes how a documented Sedad resultCode is reported
+type businessError struct {
+	code      rimpay.ErrorCode
+	retryable bool
+	meaning   string
+}
+
+// businessErrors maps the resultCode values Sedad documents; "00" is
+// success and undocumented codes are reported as provider errors
+var businessErrors = map[string]businessError{
+	"01": {rimpay.ErrorCodePaymentDeclined, false, "payment refused"},
+	"12": {rimpay.ErrorCodeInvalidRequest, false, "unknown invoice or biller"},
+	"14": {rimpay.ErrorCodeInvalidRequest, false, "invoice already paid"},
+	"51": {rimpay.ErrorCodeInsufficientFunds, false, "insufficient funds"},
+	"96": {rimpay.ErrorCodeProviderError, true, "system error"},
+}
+
+// businessErrorFor returns the PaymentError for a failed resultCode, or nil
+// on success
+func businessErrorFor(resultCode, resultMessage string) *rimpay.PaymentError {
+	if resultCode == resultSuccess {
+		return nil
+	}
+
+	mapped, ok := businessErrors[resultCode]
+	if !ok {
+		mapped = businessError{rimpay.ErrorCodeProviderError, false, "undocumented result code"}
+	}
+
+	message := resultMessage
+	if message == "" {
+		message = mapped.meaning
+	}
+	return rimpay.NewPaymentError(
+		mapped.code,
+		fmt.Sprintf("Sedad error %s: %s", resultCode, message),
+		"sedad",
+		mapped.retryable,
+	).WithDetail("result_code", resultCode).WithDetail("result_message", resultMessage)
+}
@@ -0,0 +1,57 @@
+package sedad
+
+import (
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   rimpay.PaymentStatus
+	}{
+		{"PAID", rimpay.PaymentStatusSuccess},
+		{"paid", rimpay.PaymentStatusSuccess},
+		{"FAILED", rimpay.PaymentStatusFailed},
+		{"REJECTED", rimpay.PaymentStatusFailed},
+		{"CANCELLED", rimpay.PaymentStatusCancelled},
+		{"EXPIRED", rimpay.PaymentStatusExpired},
+		{"INITIATED", rimpay.PaymentStatusPending},
+		{"PENDING", rimpay.PaymentStatusPending},
+		{"", rimpay.PaymentStatusPending},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, convertStatus(tt.status), "status %q", tt.status)
+	}
+}
+
+func TestBusinessErrorFor(t *testing.T) {
+	assert.Nil(t, businessErrorFor("00", ""))
+
+	tests := []struct {
+		resultCode    string
+		resultMessage string
+		wantCode      rimpay.ErrorCode
+		wantRetryable bool
+		wantMessage   string
+	}{
+		{"01", "", rimpay.ErrorCodePaymentDeclined, false, "Sedad error 01: payment refused"},
+		{"12", "Facture inconnue", rimpay.ErrorCodeInvalidRequest, false, "Sedad error 12: Facture inconnue"},
+		{"51", "", rimpay.ErrorCodeInsufficientFunds, false, "Sedad error 51: insufficient funds"},
+		{"96", "", rimpay.ErrorCodeProviderError, true, "Sedad error 96: system error"},
+		{"77", "", rimpay.ErrorCodeProviderError, false, "Sedad error 77: undocumented result code"},
+	}
+
+	for _, tt := range tests {
+		err := businessErrorFor(tt.resultCode, tt.resultMessage)
+		if assert.NotNil(t, err, "result code %s", tt.resultCode) {
+			assert.Equal(t, tt.wantCode, err.Code)
+			assert.Equal(t, tt.wantRetryable, err.Retryable)
+			assert.Equal(t, tt.wantMessage, err.Message)
+			assert.Equal(t, tt.resultCode, err.Details["result_code"])
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package sedad
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// PaymentProcessor handles Sedad payment operations
+type PaymentProcessor struct {
+	config     rimpay.ProviderConfig
+	httpClient common.HTTPClient
+	logger     rimpay.Logger
+	baseURL    string
+
+	// billerCode and callbackURL are the OptionBillerCode and
+	// OptionCallbackURL defaults
+	billerCode  string
+	callbackURL string
+}
+
+// NewPaymentProcessor creates new payment processor
+func NewPaymentProcessor(config rimpay.ProviderConfig, httpClient common.HTTPClient, logger rimpay.Logger) *PaymentProcessor {
+	// NewSedadProvider has already rejected options that are not strings
+	billerCode, _ := config.StringOption(OptionBillerCode, "")
+	callbackURL, _ := config.StringOption(OptionCallbackURL, "")
+
+	return &PaymentProcessor{
+		config:      config,
+		httpClient:  httpClient,
+		logger:      logger,
+		baseURL:     strings.TrimRight(config.BaseURL, "/"),
+		billerCode:  billerCode,
+		callbackURL: callbackURL,
+	}
+}
+
+// ProcessPayment initiates a Sedad invoice payment. The invoice number and
+// biller code come from the request metadata set by SedadPaymentRequest,
+// falling back to the reference and OptionBillerCode.
+func (pp *PaymentProcessor) ProcessPayment(ctx context.Context, request *rimpay.PaymentRequest) (_ *rimpay.PaymentResponse, err error) {
+	ctx, span := common.StartSpan(ctx, pp.config.Tracer, "rimpay.sedad.payment",
+		common.AttrProvider, "sedad", common.AttrReference, request.Reference, common.AttrAmount, request.Amount.String())
+	defer func() { common.EndSpan(span, err) }()
+
+	if request.PhoneNumber == nil {
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeInvalidRequest, "phone number is required", "sedad", false)
+	}
+	billerCode := metadataString(request.Metadata, "biller_code", pp.billerCode)
+	if billerCode == "" {
+		return nil, rimpay.NewPaymentError(rimpay.ErrorCodeInvalidRequest, "biller code is required", "sedad", false)
+	}
+
+	merchantID, err := pp.config.Credential(ctx, "sedad", "merchant_id")
+	if err != nil {
+		return nil, common.CredentialsError(err, "sedad")
+	}
+
+	sedadReq := &PaymentRequest{
+		MerchantID:    merchantID,
+		BillerCode:    billerCode,
+		InvoiceNumber: metadataString(request.Metadata, "invoice_number", request.Reference),
+		Reference:     request.Reference,
+		Amount:        request.Amount.ToProviderAmount(false),
+		Currency:      string(request.Amount.Currency()),
+		CustomerPhone: request.PhoneNumber.ForProvider(false),
+		Description:   request.Description,
+		CallbackURL:   metadataString(request.Metadata, "callback_url", pp.callbackURL),
+	}
+
+	payload, err := json.Marshal(sedadReq)
+	if err != nil {
+		return nil, rimpay.NewPaymentError(
+			rimpay.ErrorCodeInvalidRequest,
+			"failed to marshal payment request",
+			"sedad",
+			false,
+		)
+	}
+
+	httpReq := &common.HTTPRequest{
+		Method: "POST",
+		URL:    pp.baseURL + "/v1/payments",
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body:    payload,
+		Timeout: pp.config.Timeout,
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("Making Sedad payment request",
+		"reference", sedadReq.Reference,
+		"invoice_number", sedadReq.InvoiceNumber,
+		"biller_code", sedadReq.BillerCode,
+		"amount", sedadReq.Amount,
+	)
+
+	resp, err := pp.doAuthenticated(ctx, httpReq, "payment request")
+	if err != nil {
+		return nil, err
+	}
+
+	var sedadResp PaymentResponse
+	if err := json.Unmarshal(resp.Body, &sedadResp); err != nil {
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to decode payment response",
+			"sedad",
+			false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	if err := businessErrorFor(sedadResp.ResultCode, sedadResp.ResultMessage); err != nil {
+		rimpay.ContextLogger(ctx, pp.logger).Warn("Sedad payment refused",
+			"reference", sedadReq.Reference,
+			"result_code", sedadResp.ResultCode,
+			"code", err.Code,
+		)
+		return nil, err.WithDetail("transaction_id", sedadResp.PaymentID)
+	}
+
+	status := convertStatus(sedadResp.Status)
+	response := &rimpay.PaymentResponse{
+		TransactionID: sedadResp.PaymentID,
+		Status:        status,
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      "sedad",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		Metadata: map[string]interface{}{
+			"result_code":                       sedadResp.ResultCode,
+			"result_message":                    sedadResp.ResultMessage,
+			"payment_id":                        sedadResp.PaymentID,
+			rimpay.MetadataKeyProviderReference: sedadResp.PaymentID,
+			"invoice_number":                    sedadReq.InvoiceNumber,
+			"biller_code":                       sedadReq.BillerCode,
+		},
+		Events: []rimpay.StatusEvent{
+			rimpay.NewStatusEvent(status, sedadResp.ResultMessage, rimpay.EventSourceInitial),
+		},
+	}
+
+	rimpay.ContextLogger(ctx, pp.logger).Info("Sedad payment response received",
+		"transaction_id", response.TransactionID,
+		"status", response.Status,
+	)
+
+	return response, nil
+}
+
+// CheckPaymentStatus queries the status of the Sedad payment paymentID
+func (pp *PaymentProcessor) CheckPaymentStatus(ctx context.Context, paymentID string) (*rimpay.TransactionStatus, error) {
+	if paymentID == "" {
+		return nil, rimpay.NewValidationError("transactionID", "transaction ID cannot be empty")
+	}
+
+	httpReq := &common.HTTPRequest{
+		Method:  "GET",
+		URL:     pp.baseURL + "/v1/payments/" + url.PathEscape(paymentID),
+		Headers: make(map[string]string),
+		Timeout: pp.config.Timeout,
+	}
+
+	resp, err := pp.doAuthenticated(ctx, httpReq, "status check")
+	if err != nil {
+		return nil, err
+	}
+
+	var statusResp StatusResponse
+	if err := json.Unmarshal(resp.Body, &statusResp); err != nil {
+		return nil, common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeProviderError,
+			"failed to decode status response",
+			"sedad",
+			false,
+		).WithCause(err), httpReq, resp), resp)
+	}
+
+	if err := businessErrorFor(statusResp.ResultCode, statusResp.ResultMessage); err != nil {
+		return nil, err.WithDetail("transaction_id", paymentID)
+	}
+
+	status := &rimpay.TransactionStatus{
+		TransactionID:     paymentID,
+		Status:            convertStatus(statusResp.Status),
+		Reference:         statusResp.Reference,
+		ProviderReference: statusResp.PaymentID,
+		Message:           statusResp.ResultMessage,
+		LastUpdated:       time.Now(),
+		ProviderData: map[string]interface{}{
+			"result_code":    statusResp.ResultCode,
+			"result_message": statusResp.ResultMessage,
+			"status":         statusResp.Status,
+			"invoice_number": statusResp.InvoiceNumber,
+			"paid_at":        statusResp.PaidAt,
+		},
+	}
+	status.AddEvent(status.Status, status.Message, rimpay.EventSourcePoll)
+
+	return status, nil
+}
+
+// Ping checks that Sedad is reachable and accepts the API key
+func (pp *PaymentProcessor) Ping(ctx context.Context) error {
+	_, err := pp.doAuthenticated(ctx, &common.HTTPRequest{
+		Method:  "GET",
+		URL:     pp.baseURL + "/v1/ping",
+		Headers: make(map[string]string),
+		Timeout: pp.config.Timeout,
+	}, "health check")
+	return err
+}
+
+// HandleNotification converts a Sedad webhook notification into a
+// TransactionStatus
+func (pp *PaymentProcessor) HandleNotification(notification *rimpay.SedadNotificationData) (*rimpay.TransactionStatus, error) {
+	if notification == nil {
+		return nil, rimpay.NewValidationError("notification", "is required")
+	}
+	if notification.PaymentID == "" {
+		return nil, rimpay.NewValidationError("paymentId", "is required")
+	}
+
+	status := convertStatus(notification.Status)
+	message := "Payment notification received"
+	if status != rimpay.PaymentStatusSuccess && notification.ResultMessage != "" {
+		message = notification.ResultMessage
+	}
+
+	pp.logger.Info("Sedad notification processed",
+		"reference", notification.Reference,
+		"status", status,
+		"payment_id", notification.PaymentID,
+	)
+
+	ts := &rimpay.TransactionStatus{
+		TransactionID:     notification.PaymentID,
+		Status:            status,
+		Reference:         notification.Reference,
+		ProviderReference: notification.PaymentID,
+		Message:           message,
+		LastUpdated:       time.Now(),
+		ProviderData: map[string]interface{}{
+			"invoice_number": notification.InvoiceNumber,
+			"biller_code":    notification.BillerCode,
+			"status":         notification.Status,
+			"amount":         notification.Amount,
+			"currency":       notification.Currency,
+			"customer_phone": notification.CustomerPhone,
+			"paid_at":        notification.PaidAt,
+			"result_code":    notification.ResultCode,
+			"result_message": notification.ResultMessage,
+		},
+	}
+	ts.AddEvent(status, message, rimpay.EventSourceWebhook)
+	return ts, nil
+}
+
+// metadataString returns metadata[key] when it is a non-empty string, or def
+func metadataString(metadata map[string]interface{}, key, def string) string {
+	if value, ok := metadata[key].(string); ok && value != "" {
+		return value
+	}
+	return def
+}
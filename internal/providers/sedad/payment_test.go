@@ -0,0 +1,255 @@
+package sedad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubHTTP records requests and answers them with the queued HTTP statuses,
+// then with paymentBody or statusBody
+type stubHTTP struct {
+	statuses    []int
+	paymentBody string
+	statusBody  string
+	requests    []*common.HTTPRequest
+}
+
+func (s *stubHTTP) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	s.requests = append(s.requests, req)
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+		return &common.HTTPResponse{StatusCode: status, Body: []byte(`{"error":"rejected"}`)}, nil
+	}
+
+	body := `{"status":"ok"}`
+	switch {
+	case req.Method == "POST" && strings.HasSuffix(req.URL, "/v1/payments"):
+		body = s.paymentBody
+		if body == "" {
+			body = `{"resultCode":"00","resultMessage":"Paiement initie","paymentId":"PAY-1","status":"PENDING"}`
+		}
+	case req.Method == "GET" && strings.Contains(req.URL, "/v1/payments/"):
+		body = s.statusBody
+	}
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(body)}, nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+func newTestProvider(t *testing.T, stub *stubHTTP) *Provider {
+	t.Helper()
+	provider, err := NewSedadProvider(testConfig(stub), nopLogger{})
+	require.NoError(t, err)
+	return provider
+}
+
+func sedadRequest(t *testing.T) *rimpay.SedadPaymentRequest {
+	t.Helper()
+	phoneNum, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &rimpay.SedadPaymentRequest{
+		PhoneNumber:   phoneNum,
+		Amount:        money.FromFloat64(1250.00, money.MRU),
+		Description:   "Electricity bill",
+		Reference:     "ORDER-1",
+		InvoiceNumber: "INV-2024-0042",
+		BillerCode:    "SOMELEC",
+		CallbackURL:   "https://shop.test/sedad",
+	}
+}
+
+func TestProcessSedadPayment(t *testing.T) {
+	stub := &stubHTTP{}
+	provider := newTestProvider(t, stub)
+
+	resp, err := provider.ProcessSedadPayment(context.Background(), sedadRequest(t))
+	require.NoError(t, err)
+
+	require.Len(t, stub.requests, 1)
+	sent := stub.requests[0]
+	assert.Equal(t, "https://sedad.test/v1/payments", sent.URL)
+	assert.Equal(t, "sk_test_123", sent.Headers["X-API-Key"])
+	var body PaymentRequest
+	require.NoError(t, json.Unmarshal(sent.Body, &body))
+	assert.Equal(t, PaymentRequest{
+		MerchantID:    "M-100",
+		BillerCode:    "SOMELEC",
+		InvoiceNumber: "INV-2024-0042",
+		Reference:     "ORDER-1",
+		Amount:        "1250.00",
+		Currency:      "MRU",
+		CustomerPhone: "20000000",
+		Description:   "Electricity bill",
+		CallbackURL:   "https://shop.test/sedad",
+	}, body)
+
+	assert.Equal(t, "PAY-1", resp.TransactionID)
+	assert.Equal(t, rimpay.PaymentStatusPending, resp.Status)
+	assert.Equal(t, "sedad", resp.Provider)
+	assert.Equal(t, "INV-2024-0042", resp.Metadata["invoice_number"])
+}
+
+func TestProcessSedadPaymentValidates(t *testing.T) {
+	stub := &stubHTTP{}
+	provider := newTestProvider(t, stub)
+
+	request := sedadRequest(t)
+	request.InvoiceNumber = ""
+	_, err := provider.ProcessSedadPayment(context.Background(), request)
+
+	var errs rimpay.ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, map[string]string{"invoice_number": "cannot be empty"}, errs.Fields())
+	assert.Empty(t, stub.requests, "invalid payments must not be sent")
+}
+
+func TestGenericPaymentUsesBillerCodeOption(t *testing.T) {
+	stub := &stubHTTP{}
+	config := testConfig(stub)
+	config.Options = map[string]interface{}{OptionBillerCode: "MAURITEL"}
+	provider, err := NewSedadProvider(config, nopLogger{})
+	require.NoError(t, err)
+
+	request := sedadRequest(t).ToGenericRequest()
+	request.Metadata = nil
+	_, err = provider.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+
+	var body PaymentRequest
+	require.NoError(t, json.Unmarshal(stub.requests[0].Body, &body))
+	assert.Equal(t, "MAURITEL", body.BillerCode)
+	assert.Equal(t, "ORDER-1", body.InvoiceNumber, "the reference is the default invoice number")
+
+	provider = newTestProvider(t, stub)
+	_, err = provider.ProcessPayment(context.Background(), request)
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, "biller code is required", paymentErr.Message)
+}
+
+func TestPaymentReturnsBusinessError(t *testing.T) {
+	stub := &stubHTTP{paymentBody: `{"resultCode":"51","resultMessage":"Solde insuffisant","paymentId":"PAY-2","status":"FAILED"}`}
+	provider := newTestProvider(t, stub)
+
+	_, err := provider.ProcessSedadPayment(context.Background(), sedadRequest(t))
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, rimpay.ErrorCodeInsufficientFunds, paymentErr.Code)
+	assert.Equal(t, "PAY-2", paymentErr.Details["transaction_id"])
+	assert.Len(t, stub.requests, 1, "business errors are not retried")
+}
+
+func TestPaymentHTTPStatusErrors(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantCode      rimpay.ErrorCode
+		wantRetryable bool
+	}{
+		{401, rimpay.ErrorCodeAuthenticationFailed, false},
+		{403, rimpay.ErrorCodeAuthenticationFailed, false},
+		{422, rimpay.ErrorCodeInvalidRequest, false},
+		{429, rimpay.ErrorCodeProviderBusy, true},
+		{502, rimpay.ErrorCodeProviderError, true},
+	}
+
+	for _, tt := range tests {
+		err := statusError(&common.HTTPRequest{Method: "POST", URL: "https://sedad.test/v1/payments"},
+			&common.HTTPResponse{StatusCode: tt.status}, "payment request")
+
+		var paymentErr *rimpay.PaymentError
+		require.ErrorAs(t, err, &paymentErr, "status %d", tt.status)
+		assert.Equal(t, tt.wantCode, paymentErr.Code, "status %d", tt.status)
+		assert.Equal(t, tt.wantRetryable, paymentErr.Retryable, "status %d", tt.status)
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	stub := &stubHTTP{statuses: []int{503}}
+	config := testConfig(stub)
+	config.Retry = &rimpay.RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond, Multiplier: 1}
+	provider, err := NewSedadProvider(config, nopLogger{})
+	require.NoError(t, err)
+
+	resp, err := provider.ProcessSedadPayment(context.Background(), sedadRequest(t))
+	require.NoError(t, err)
+	assert.Equal(t, "PAY-1", resp.TransactionID)
+	assert.Len(t, stub.requests, 2)
+}
+
+func TestMissingAPIKeyIsNotRetryable(t *testing.T) {
+	stub := &stubHTTP{}
+	config := testConfig(stub)
+	config.Credentials = nil
+	config.CredentialsProvider = rimpay.StaticCredentials{"merchant_id": "M-100"}
+	provider, err := NewSedadProvider(config, nopLogger{})
+	require.NoError(t, err)
+
+	_, err = provider.ProcessSedadPayment(context.Background(), sedadRequest(t))
+	var paymentErr *rimpay.PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, rimpay.ErrorCodeAuthenticationFailed, paymentErr.Code)
+	assert.False(t, paymentErr.Retryable)
+	assert.True(t, errors.Is(err, rimpay.ErrCredentialNotFound))
+	assert.Empty(t, stub.requests)
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	stub := &stubHTTP{statusBody: `{"resultCode":"00","resultMessage":"Paye","paymentId":"PAY 1","reference":"ORDER-1",` +
+		`"invoiceNumber":"INV-2024-0042","status":"PAID","amount":"1250.00","paidAt":"2024-05-02T10:00:00Z"}`}
+	provider := newTestProvider(t, stub)
+
+	status, err := provider.GetPaymentStatus(context.Background(), "PAY 1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "GET", stub.requests[0].Method)
+	assert.Equal(t, "https://sedad.test/v1/payments/PAY%201", stub.requests[0].URL)
+	assert.Equal(t, "sk_test_123", stub.requests[0].Headers["X-API-Key"])
+	assert.Equal(t, rimpay.PaymentStatusSuccess, status.Status)
+	assert.Equal(t, "ORDER-1", status.Reference)
+	assert.Equal(t, "INV-2024-0042", status.ProviderData["invoice_number"])
+	require.Len(t, status.Events(), 1)
+	assert.Equal(t, rimpay.EventSourcePoll, status.Events()[0].Source)
+
+	_, err = provider.GetPaymentStatus(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestHandleNotification(t *testing.T) {
+	provider := newTestProvider(t, &stubHTTP{})
+
+	var notification rimpay.SedadNotificationData
+	require.NoError(t, json.Unmarshal([]byte(`{"paymentId":"PAY-1","reference":"ORDER-1","invoiceNumber":"INV-2024-0042",`+
+		`"billerCode":"SOMELEC","status":"CANCELLED","amount":"1250.00","currency":"MRU","resultMessage":"Annule par le client"}`), &notification))
+
+	status, err := provider.HandleNotification(&notification)
+	require.NoError(t, err)
+	assert.Equal(t, "PAY-1", status.TransactionID)
+	assert.Equal(t, "ORDER-1", status.Reference)
+	assert.Equal(t, rimpay.PaymentStatusCancelled, status.Status)
+	assert.Equal(t, "Annule par le client", status.Message)
+	assert.Equal(t, "SOMELEC", status.ProviderData["biller_code"])
+	require.Len(t, status.Events(), 1)
+	assert.Equal(t, rimpay.EventSourceWebhook, status.Events()[0].Source)
+
+	_, err = provider.HandleNotification(&rimpay.SedadNotificationData{Status: "PAID"})
+	assert.Error(t, err, "notifications without a payment ID are rejected")
+	_, err = provider.HandleNotification(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package sedad
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// apiKeyHeader carries the merchant's API key on every Sedad request
+const apiKeyHeader = "X-API-Key"
+
+// doAuthenticated sends req with the API key. Transport failures and non-2xx
+// responses are returned as PaymentErrors; action names the call in their
+// messages.
+func (pp *PaymentProcessor) doAuthenticated(ctx context.Context, req *common.HTTPRequest, action string) (*common.HTTPResponse, error) {
+	apiKey, err := pp.config.Credential(ctx, "sedad", "api_key")
+	if err != nil {
+		return nil, common.CredentialsError(err, "sedad")
+	}
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	req.Headers[apiKeyHeader] = apiKey
+	req.Headers["Accept"] = "application/json"
+
+	resp, err := pp.httpClient.Do(ctx, req)
+	if err != nil {
+		if transportErr, ok := common.AsTransportError(err, "sedad"); ok {
+			return nil, transportErr
+		}
+		return nil, common.AnnotateRequest(rimpay.NewPaymentError(
+			rimpay.ErrorCodeNetworkError,
+			action+" failed",
+			"sedad",
+			true,
+		).WithCause(err), req, nil)
+	}
+
+	if err := statusError(req, resp, action); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// statusError maps a non-2xx response to a PaymentError: a rejected API key
+// to AUTHENTICATION_FAILED, 429 to PROVIDER_BUSY, other 4xx to
+// INVALID_REQUEST and 5xx to a retryable PROVIDER_ERROR
+func statusError(req *common.HTTPRequest, resp *common.HTTPResponse, action string) error {
+	status := resp.StatusCode
+	if status >= 200 && status < 300 {
+		return nil
+	}
+
+	code, retryable := rimpay.ErrorCodeProviderError, true
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		code, retryable = rimpay.ErrorCodeAuthenticationFailed, false
+	case status == http.StatusTooManyRequests:
+		code = rimpay.ErrorCodeProviderBusy
+	case status >= 400 && status < 500:
+		code, retryable = rimpay.ErrorCodeInvalidRequest, false
+	}
+
+	return common.AttachRawResponse(common.AnnotateRequest(rimpay.NewPaymentError(
+		code,
+		fmt.Sprintf("%s failed with status %d", action, status),
+		"sedad",
+		retryable,
+	), req, resp), resp)
+}
@@ -0,0 +1,268 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// readFormat reads the first copy of the format information, returning the
+// level and mask after checking its BCH code
+func readFormat(t *testing.T, c *Code) (level, mask int) {
+	t.Helper()
+	bits := 0
+	for i := 0; i <= 5; i++ {
+		bits = setBit(bits, i, c.Dark(8, i))
+	}
+	bits = setBit(bits, 6, c.Dark(8, 7))
+	bits = setBit(bits, 7, c.Dark(8, 8))
+	bits = setBit(bits, 8, c.Dark(7, 8))
+	for i := 9; i < 15; i++ {
+		bits = setBit(bits, i, c.Dark(14-i, 8))
+	}
+	bits ^= 0x5412
+
+	rem := bits
+	for i := 14; i >= 10; i-- {
+		if rem>>uint(i)&1 == 1 {
+			rem ^= 0x537 << uint(i-10)
+		}
+	}
+	if rem != 0 {
+		t.Fatalf("format bits %015b fail their BCH check", bits)
+	}
+	return bits >> 13, bits >> 10 & 7
+}
+
+func setBit(x, i int, v bool) int {
+	if v {
+		x |= 1 << uint(i)
+	}
+	return x
+}
+
+// decode reads the symbol back as a reader would: it unmasks the modules,
+// checks every block's Reed-Solomon syndromes and parses the byte segment
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	level, mask := readFormat(t, c)
+	if level != 0 {
+		t.Fatalf("level = %d, want M (0)", level)
+	}
+
+	var stream []byte
+	var cur byte
+	n := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if c.reserve[y][x] {
+					continue
+				}
+				cur = cur<<1 | boolByte(c.Dark(x, y) != masked(mask, x, y))
+				if n++; n%8 == 0 {
+					stream = append(stream, cur)
+				}
+			}
+		}
+	}
+
+	numBlocks, eccLen := eccBlocks[c.version], eccCodewordsPerBlock[c.version]
+	raw := rawDataModules(c.version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortData := raw/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for j := range blocks {
+			if i < shortData || j >= numShort {
+				blocks[j] = append(blocks[j], stream[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], stream[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for j, block := range blocks {
+		root := byte(1)
+		for i := 0; i < eccLen; i++ {
+			var syndrome byte
+			for _, b := range block {
+				syndrome = gfMultiply(syndrome, root) ^ b
+			}
+			if syndrome != 0 {
+				t.Fatalf("block %d has a non-zero syndrome %d", j, i)
+			}
+			root = gfMultiply(root, 2)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	var bits bitBuffer
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(pos, width int) int {
+		v := 0
+		for i := 0; i < width; i++ {
+			v = v<<1 | int(boolByte(bits[pos+i]))
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 4 {
+		t.Fatalf("mode = %d, want byte mode", mode)
+	}
+	length := read(4, countBits(c.version))
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = byte(read(4+countBits(c.version)+8*i, 8))
+	}
+	return out
+}
+
+func boolByte(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		data    string
+		version int
+	}{
+		{"", 1},
+		{"https://pay.mr", 1},
+		{"https://pay.mr/x", 2},
+		{"https://api.masrvi.mr/online/online.php?sessionid=SESSION-1&purchaseref=ORDER-7", 5},
+		{"https://pay.mr/?" + strings.Repeat("q", 180), 10},
+		{strings.Repeat("A", 2331), 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(tt.data), err)
+		}
+		if c.Version() != tt.version {
+			t.Errorf("Encode(%d bytes) version = %d, want %d", len(tt.data), c.Version(), tt.version)
+		}
+		if got := string(decode(t, c)); got != tt.data {
+			t.Errorf("decoded %q, want %q", got, tt.data)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 2332)); err != ErrTooLong {
+		t.Errorf("Encode(2332 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	c, err := Encode([]byte("https://api.masrvi.mr/online/online.php?sessionid=SESSION-1&purchaseref=ORDER-7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder := []string{
+		"#######.",
+		"#.....#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.....#.",
+		"#######.",
+		"........",
+	}
+	for y, row := range finder {
+		for x, module := range row {
+			if c.Dark(x, y) != (module == '#') {
+				t.Fatalf("finder module (%d, %d) = %v", x, y, c.Dark(x, y))
+			}
+		}
+	}
+	if !c.Dark(8, c.Size()-8) {
+		t.Error("the dark module is light")
+	}
+	for i := 8; i < c.Size()-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("timing pattern broken at %d", i)
+		}
+	}
+}
+
+func TestEncodeVersionInformation(t *testing.T) {
+	c, err := Encode(make([]byte, Capacity(6)+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version() != 7 || c.Size() != 45 {
+		t.Fatalf("version %d, size %d, want 7 and 45", c.Version(), c.Size())
+	}
+	bits := 0
+	for i := 0; i < 18; i++ {
+		bits = setBit(bits, i, c.Dark(c.Size()-11+i%3, i/3))
+		if c.Dark(i/3, c.Size()-11+i%3) != c.Dark(c.Size()-11+i%3, i/3) {
+			t.Fatalf("the version information copies differ at bit %d", i)
+		}
+	}
+	if bits != 0x07C94 {
+		t.Errorf("version information = %018b, want 000111110010010100", bits)
+	}
+	for _, pos := range []int{6, 22, 38} {
+		if !c.Dark(22, pos) || c.Dark(21, pos) {
+			t.Errorf("no alignment pattern centre at (22, %d)", pos)
+		}
+	}
+	if !c.Dark(38, 38) || c.Dark(37, 38) || !c.Dark(36, 38) {
+		t.Error("alignment pattern at (38, 38) is broken")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	c, err := Encode([]byte("https://pay.mr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.PNG(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 256 {
+		t.Fatalf("image is %v, want 256x256", b)
+	}
+
+	scale := 256 / (c.Size() + 2*quietZone)
+	offset := (256 - scale*c.Size()) / 2
+	for y := 0; y < c.Size(); y++ {
+		for x := 0; x < c.Size(); x++ {
+			r, _, _, _ := img.At(offset+x*scale+scale/2, offset+y*scale+scale/2).RGBA()
+			if (r == 0) != c.Dark(x, y) {
+				t.Fatalf("pixel of module (%d, %d) does not match", x, y)
+			}
+		}
+	}
+	if r, _, _, _ := img.At(offset-1, offset-1).RGBA(); r == 0 {
+		t.Error("the quiet zone is not light")
+	}
+
+	if _, err := c.PNG(20); err == nil {
+		t.Error("PNG(20) succeeded below the minimum size")
+	}
+}
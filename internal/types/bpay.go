@@ -0,0 +1,105 @@
+package types
+
+// BPayOperationType selects the kind of B-PAY operation a payment is sent as
+type BPayOperationType string
+
+const (
+	// BPayOperationPayment is a customer paying a merchant (the default)
+	BPayOperationPayment BPayOperationType = "payment"
+	// BPayOperationMerchantPayment is a debit initiated by the merchant
+	BPayOperationMerchantPayment BPayOperationType = "merchant_payment"
+	// BPayOperationBillPayment is the payment of a bill
+	BPayOperationBillPayment BPayOperationType = "bill_payment"
+)
+
+// BPayOperationTypes returns every defined operation type
+func BPayOperationTypes() []BPayOperationType {
+	return []BPayOperationType{BPayOperationPayment, BPayOperationMerchantPayment, BPayOperationBillPayment}
+}
+
+// OrDefault returns t, or BPayOperationPayment when t is empty
+func (t BPayOperationType) OrDefault() BPayOperationType {
+	if t == "" {
+		return BPayOperationPayment
+	}
+	return t
+}
+
+// IsValid returns true if t is one of the defined operation types
+func (t BPayOperationType) IsValid() bool {
+	for _, known := range BPayOperationTypes() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// BPayMode selects how the customer authorizes a B-PAY payment
+type BPayMode string
+
+const (
+	// BPayModePasscode forwards the verification code the customer gave the
+	// merchant (the default)
+	BPayModePasscode BPayMode = "passcode"
+	// BPayModeUSSDPush sends the customer a USSD prompt to confirm the
+	// payment on their handset; no passcode is involved
+	BPayModeUSSDPush BPayMode = "ussd_push"
+)
+
+// BPayModes returns every defined mode
+func BPayModes() []BPayMode {
+	return []BPayMode{BPayModePasscode, BPayModeUSSDPush}
+}
+
+// OrDefault returns m, or BPayModePasscode when m is empty
+func (m BPayMode) OrDefault() BPayMode {
+	if m == "" {
+		return BPayModePasscode
+	}
+	return m
+}
+
+// IsValid returns true if m is one of the defined modes
+func (m BPayMode) IsValid() bool {
+	for _, known := range BPayModes() {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns string representation
+func (m BPayMode) String() string {
+	return string(m)
+}
+
+// Label returns the operation name printed on receipts in lang, falling
+// back to French like the rest of the library
+func (t BPayOperationType) Label(lang Language) string {
+	labels, ok := bpayOperationLabels[t.OrDefault()]
+	if !ok {
+		return string(t)
+	}
+	switch lang {
+	case LanguageEnglish:
+		return labels[0]
+	case LanguageArabic:
+		return labels[2]
+	default:
+		return labels[1]
+	}
+}
+
+// bpayOperationLabels holds the English, French and Arabic receipt labels
+var bpayOperationLabels = map[BPayOperationType][3]string{
+	BPayOperationPayment:         {"Payment", "Paiement", "دفع"},
+	BPayOperationMerchantPayment: {"Merchant payment", "Paiement marchand", "دفع التاجر"},
+	BPayOperationBillPayment:     {"Bill payment", "Paiement de facture", "دفع الفاتورة"},
+}
+
+// String returns string representation
+func (t BPayOperationType) String() string {
+	return string(t)
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// HTTPExchange is a sanitized copy of one HTTP attempt: credentials,
+// passcodes and tokens are masked in the URL, headers and bodies
+type HTTPExchange struct {
+	Time            time.Time         `json:"time"`
+	Duration        time.Duration     `json:"duration"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     []byte            `json:"request_body,omitempty"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    []byte            `json:"response_body,omitempty"`
+	// Error is set when no response was received
+	Error string `json:"error,omitempty"`
+}
+
+// HTTPRecorder receives every HTTP exchange with a provider, for debugging
+// and audits. Record is called synchronously and must be safe for
+// concurrent use.
+type HTTPRecorder interface {
+	Record(exchange HTTPExchange)
+}
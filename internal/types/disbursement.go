@@ -0,0 +1,75 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// DisbursementStatus is the state of a disbursement
+type DisbursementStatus string
+
+const (
+	// DisbursementStatusPending was accepted but not yet credited
+	DisbursementStatusPending DisbursementStatus = "pending"
+	// DisbursementStatusCompleted was credited to the customer's wallet
+	DisbursementStatusCompleted DisbursementStatus = "completed"
+	// DisbursementStatusFailed was not credited
+	DisbursementStatusFailed DisbursementStatus = "failed"
+)
+
+// IsFinal reports whether the status can no longer change
+func (s DisbursementStatus) IsFinal() bool {
+	return s == DisbursementStatusCompleted || s == DisbursementStatusFailed
+}
+
+// DisbursementRequest sends money from the merchant account to a
+// customer's wallet, for payouts and refunds to a wallet
+type DisbursementRequest struct {
+	PhoneNumber *phone.Phone `json:"phone_number"`
+	Amount      money.Money  `json:"amount"`
+	// Reference identifies the disbursement and must be unique
+	Reference   string `json:"reference"`
+	Description string `json:"description,omitempty"`
+	// Provider sends the disbursement; empty means the default provider
+	Provider string `json:"provider,omitempty"`
+}
+
+// Validate validates the disbursement request, returning ValidationErrors
+// with every failed field
+func (r *DisbursementRequest) Validate() error {
+	if r == nil {
+		return NewValidationError("request", "is required")
+	}
+
+	var errs ValidationErrors
+	if r.PhoneNumber == nil {
+		errs.Add("phone_number", "is required")
+	}
+	if !r.Amount.IsPositive() {
+		errs.Add("amount", "must be positive")
+	}
+	if strings.TrimSpace(r.Reference) == "" {
+		errs.Add("reference", "is required")
+	}
+	if len(r.Description) > 255 {
+		errs.Add("description", "too long (max 255 characters)")
+	}
+	return errs.Err()
+}
+
+// DisbursementResponse is the state of a disbursement, as returned when it
+// is sent and by status checks
+type DisbursementResponse struct {
+	// DisbursementID is the provider's transaction ID
+	DisbursementID string                 `json:"disbursement_id"`
+	Reference      string                 `json:"reference"`
+	Status         DisbursementStatus     `json:"status"`
+	Amount         money.Money            `json:"amount,omitempty"`
+	Provider       string                 `json:"provider"`
+	Message        string                 `json:"message,omitempty"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
@@ -1,6 +1,10 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/internal/errors"
+)
 
 // ErrorCode represents specific error codes
 type ErrorCode string
@@ -24,6 +28,16 @@ const (
 	ErrorCodeValidationError ErrorCode = "VALIDATION_ERROR"
 	// ErrorCodePaymentExpired indicates payment expired
 	ErrorCodePaymentExpired ErrorCode = "PAYMENT_EXPIRED"
+	// ErrorCodeProviderBusy indicates low priority work was shed under load
+	ErrorCodeProviderBusy ErrorCode = "PROVIDER_BUSY"
+	// ErrorCodeProviderTLSError indicates the provider's TLS certificate could not be verified
+	ErrorCodeProviderTLSError ErrorCode = "PROVIDER_TLS_ERROR"
+	// ErrorCodeRateLimited indicates the provider's configured rate limit left no capacity in time
+	ErrorCodeRateLimited ErrorCode = "RATE_LIMITED"
+	// ErrorCodeTooManyRequests indicates the provider already had its configured maximum of payments in flight
+	ErrorCodeTooManyRequests ErrorCode = "TOO_MANY_REQUESTS"
+	// ErrorCodeInsufficientMerchantBalance indicates the merchant account cannot fund a disbursement
+	ErrorCodeInsufficientMerchantBalance ErrorCode = "INSUFFICIENT_MERCHANT_BALANCE"
 )
 
 // PaymentError represents a payment-related error
@@ -38,6 +52,9 @@ type PaymentError struct {
 
 // Error implements the error interface
 func (e *PaymentError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
 	if e.Provider != "" {
 		return fmt.Sprintf("[%s] %s: %s", e.Provider, e.Code, e.Message)
 	}
@@ -46,12 +63,55 @@ func (e *PaymentError) Error() string {
 
 // Unwrap returns the underlying error
 func (e *PaymentError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
 	return e.Cause
 }
 
+// codeSentinels are the sentinel errors a PaymentError matches with
+// errors.Is, by code; every ErrorCode has one. PROVIDER_ERROR also covers
+// undecodable and rejected responses, so it matches ErrProviderUnavailable
+// only for a 5xx response; see Is.
+var codeSentinels = map[ErrorCode]error{
+	ErrorCodeInvalidRequest:              errors.ErrInvalidRequest,
+	ErrorCodeValidationError:             errors.ErrInvalidRequest,
+	ErrorCodeAuthenticationFailed:        errors.ErrAuthenticationFailed,
+	ErrorCodeInsufficientFunds:           errors.ErrPaymentFailed,
+	ErrorCodeInsufficientMerchantBalance: errors.ErrPaymentFailed,
+	ErrorCodePaymentDeclined:             errors.ErrPaymentFailed,
+	ErrorCodePaymentExpired:              errors.ErrPaymentFailed,
+	ErrorCodeNetworkError:                errors.ErrNetworkError,
+	ErrorCodeTimeout:                     errors.ErrTimeout,
+	ErrorCodeProviderError:               errors.ErrProviderUnavailable,
+	ErrorCodeProviderTLSError:            errors.ErrProviderUnavailable,
+	ErrorCodeProviderBusy:                errors.ErrProviderUnavailable,
+	ErrorCodeRateLimited:                 errors.ErrRateLimitExceeded,
+	ErrorCodeTooManyRequests:             errors.ErrRateLimitExceeded,
+}
+
+// Is reports whether target is the sentinel error of e's code, so that
+// errors.Is(err, ErrTimeout) holds for a TIMEOUT PaymentError
+func (e *PaymentError) Is(target error) bool {
+	if e == nil || target == nil {
+		return false
+	}
+	sentinel, ok := codeSentinels[e.Code]
+	if !ok || sentinel != target {
+		return false
+	}
+	return e.Code != ErrorCodeProviderError || e.serverError()
+}
+
+// serverError reports whether the DetailHTTPStatus of e is a 5xx status
+func (e *PaymentError) serverError() bool {
+	status, ok := e.Details[DetailHTTPStatus].(int)
+	return ok && status >= 500 && status < 600
+}
+
 // IsRetryable returns whether the error is retryable
 func (e *PaymentError) IsRetryable() bool {
-	return e.Retryable
+	return e != nil && e.Retryable
 }
 
 // NewPaymentError creates a new payment error
@@ -65,14 +125,20 @@ func NewPaymentError(code ErrorCode, message string, provider string, retryable
 	}
 }
 
-// WithCause adds a cause to the payment error
+// WithCause adds a cause to the payment error; it is a no-op on nil
 func (e *PaymentError) WithCause(cause error) *PaymentError {
+	if e == nil {
+		return nil
+	}
 	e.Cause = cause
 	return e
 }
 
-// WithDetail adds a detail to the payment error
+// WithDetail adds a detail to the payment error; it is a no-op on nil
 func (e *PaymentError) WithDetail(key string, value interface{}) *PaymentError {
+	if e == nil {
+		return nil
+	}
 	if e.Details == nil {
 		e.Details = make(map[string]interface{})
 	}
@@ -92,9 +158,11 @@ func NewValidationError(field, message string) *PaymentError {
 // IsRetryableError determines if error code is retryable
 func IsRetryableError(code ErrorCode) bool {
 	retryableCodes := map[ErrorCode]bool{
-		ErrorCodeNetworkError:  true,
-		ErrorCodeTimeout:       true,
-		ErrorCodeProviderError: true,
+		ErrorCodeNetworkError:    true,
+		ErrorCodeTimeout:         true,
+		ErrorCodeProviderError:   true,
+		ErrorCodeProviderBusy:    true,
+		ErrorCodeTooManyRequests: true,
 	}
 	return retryableCodes[code]
 }
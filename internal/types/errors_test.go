@@ -0,0 +1,55 @@
+package types
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// declaredErrorCodes returns the ErrorCode constants declared in errors.go
+func declaredErrorCodes(t *testing.T) []ErrorCode {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	require.NoError(t, err)
+
+	var codes []ErrorCode
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "ErrorCode" {
+				continue
+			}
+			for _, v := range value.Values {
+				code, err := strconv.Unquote(v.(*ast.BasicLit).Value)
+				require.NoError(t, err)
+				codes = append(codes, ErrorCode(code))
+			}
+		}
+	}
+	return codes
+}
+
+func TestEveryErrorCodeHasSentinel(t *testing.T) {
+	codes := declaredErrorCodes(t)
+	require.Contains(t, codes, ErrorCodeInsufficientMerchantBalance)
+
+	for _, code := range codes {
+		t.Run(string(code), func(t *testing.T) {
+			sentinel, ok := codeSentinels[code]
+			require.True(t, ok, "add %s to codeSentinels", code)
+
+			err := NewPaymentError(code, "failed", "bpay", false).WithDetail(DetailHTTPStatus, 503)
+			assert.True(t, errors.Is(err, sentinel))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package types
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// HTTPConfig represents HTTP client configuration
+type HTTPConfig struct {
+	Timeout         time.Duration `json:"timeout"`
+	MaxIdleConns    int           `json:"max_idle_conns"`
+	MaxConnsPerHost int           `json:"max_conns_per_host"`
+	UserAgent       string        `json:"user_agent"`
+
+	// ProxyURL routes provider traffic through an http, https or socks5
+	// proxy; empty connects directly
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// DefaultHeaders are sent with every request. Headers a provider sets
+	// itself, such as Content-Type and Authorization, take precedence.
+	DefaultHeaders map[string]string `json:"default_headers,omitempty"`
+	// TLS configures certificate verification and client certificates;
+	// nil verifies against the system roots with TLS 1.2 or later
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// MaxRetries resends idempotent requests, such as GETs and
+	// authentication, that fail with a RetryOn status (0 = never). Payment
+	// submissions are never resent at this level.
+	MaxRetries int `json:"max_retries"`
+	// RetryOn lists the statuses that are retried (default 429, 502, 503
+	// and 504)
+	RetryOn []int `json:"retry_on,omitempty"`
+
+	// Recorder, when set, receives a sanitized copy of every request and
+	// response
+	Recorder HTTPRecorder `json:"-"`
+}
+
+// Validate checks the retry and proxy settings
+func (c HTTPConfig) Validate() error {
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("http max_retries cannot be negative")
+	}
+	if _, err := c.Proxy(); err != nil {
+		return err
+	}
+	if c.TLS != nil {
+		return c.TLS.Validate()
+	}
+	return nil
+}
+
+// Proxy returns the parsed ProxyURL, or nil when there is none. Errors never
+// include the URL, which may carry proxy credentials.
+func (c HTTPConfig) Proxy() (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return nil, nil
+	}
+	proxy, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("http proxy_url is not a valid URL")
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("http proxy_url scheme must be http, https or socks5")
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("http proxy_url must include a host")
+	}
+	return proxy, nil
+}
+
+// TLSConfig configures the TLS connections to a provider. Files and PEM
+// strings are alternatives; set at most one of each.
+type TLSConfig struct {
+	// CAFile or CAPEM adds a PEM CA bundle to the system roots, for
+	// endpoints behind a gateway with a private CA
+	CAFile string `json:"ca_file,omitempty"`
+	CAPEM  string `json:"ca_pem,omitempty"`
+
+	// CertFile and KeyFile, or CertPEM and KeyPEM, are the client
+	// certificate and key presented for mutual TLS
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	CertPEM  string `json:"cert_pem,omitempty"`
+	KeyPEM   string `json:"key_pem,omitempty"`
+
+	// InsecureSkipVerify disables certificate verification. It is for
+	// sandboxes only and is refused in production.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+
+	// MinVersion is "1.2" (the default) or "1.3"
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// Validate checks that the certificate settings are complete and the
+// minimum version is known
+func (c *TLSConfig) Validate() error {
+	if c.CAFile != "" && c.CAPEM != "" {
+		return fmt.Errorf("tls ca_file and ca_pem are mutually exclusive")
+	}
+	if c.CertFile != "" && c.CertPEM != "" {
+		return fmt.Errorf("tls cert_file and cert_pem are mutually exclusive")
+	}
+	if c.KeyFile != "" && c.KeyPEM != "" {
+		return fmt.Errorf("tls key_file and key_pem are mutually exclusive")
+	}
+	hasCert, hasKey := c.CertFile != "" || c.CertPEM != "", c.KeyFile != "" || c.KeyPEM != ""
+	if hasCert && !hasKey {
+		return fmt.Errorf("tls client certificate requires a key")
+	}
+	if hasKey && !hasCert {
+		return fmt.Errorf("tls key requires a client certificate")
+	}
+	_, err := c.MinTLSVersion()
+	return err
+}
+
+// MinTLSVersion returns MinVersion as a crypto/tls version
+func (c *TLSConfig) MinTLSVersion() (uint16, error) {
+	if c == nil {
+		return tls.VersionTLS12, nil
+	}
+	switch c.MinVersion {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("tls min_version must be 1.2 or 1.3, got %q", c.MinVersion)
+	}
+}
+
+// DefaultHTTPRetryOn is the RetryOn used when none is configured
+var DefaultHTTPRetryOn = []int{429, 502, 503, 504}
+
+// HTTPClient defines the HTTP client interface used by providers
+type HTTPClient interface {
+	Do(ctx context.Context, req *HTTPRequest) (*HTTPResponse, error)
+}
+
+// HTTPRequest represents an HTTP request
+type HTTPRequest struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    []byte
+	Timeout time.Duration
+
+	// Compress gzip-encodes Body and sets Content-Encoding, for large
+	// payloads sent to endpoints that accept compressed requests
+	Compress bool
+
+	// Idempotent marks a request that is safe to resend, such as
+	// authentication, so HTTPConfig.MaxRetries applies to it. GET, HEAD and
+	// OPTIONS requests always are.
+	Idempotent bool
+}
+
+// HTTPStatusError is returned when an idempotent request still fails with a
+// retryable status after HTTPConfig.MaxRetries, and is the cause of
+// PaymentErrors for other failing statuses. Body is only set in the first
+// case.
+type HTTPStatusError struct {
+	StatusCode int
+	Attempts   int
+	Body       []byte
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d after %d attempts", e.StatusCode, e.Attempts)
+}
+
+// HTTPResponse represents an HTTP response
+type HTTPResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+}
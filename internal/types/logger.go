@@ -0,0 +1,9 @@
+package types
+
+// Logger is the structured logger used by the client and providers
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Well-known PaymentResponse.Metadata keys, which every provider uses for
+// the same information
+const (
+	// MetadataKeyPaymentURL holds the URL the customer pays at
+	MetadataKeyPaymentURL = "payment_url"
+	// MetadataKeyFormData holds the url.Values to post to the payment URL
+	MetadataKeyFormData = "form_data"
+	// MetadataKeyPasscode holds a code the provider issues for the customer
+	// to confirm the payment with. The customer's own B-PAY passcode is
+	// never stored.
+	MetadataKeyPasscode = "passcode"
+	// MetadataKeySessionID holds the provider session the payment belongs to
+	MetadataKeySessionID = "session_id"
+	// MetadataKeyErrorCode holds the provider's raw error code
+	MetadataKeyErrorCode = "error_code"
+	// MetadataKeyErrorMessage holds the provider's error message
+	MetadataKeyErrorMessage = "error_message"
+	// MetadataKeyProviderReference holds the provider's own payment ID
+	MetadataKeyProviderReference = "provider_reference"
+	// MetadataKeyCorrelationID holds the correlation ID of the call that
+	// created the payment
+	MetadataKeyCorrelationID = "correlation_id"
+)
+
+// GetMetadataString returns Metadata[key] when it is a string
+func (r *PaymentResponse) GetMetadataString(key string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	s, ok := r.Metadata[key].(string)
+	return s, ok
+}
+
+// GetMetadataInt returns Metadata[key] when it is an integer, including the
+// whole float64 or json.Number a JSON round trip leaves
+func (r *PaymentResponse) GetMetadataInt(key string) (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	switch v := r.Metadata[key].(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// GetMetadataTime returns Metadata[key] when it is a time or an RFC 3339
+// string
+func (r *PaymentResponse) GetMetadataTime(key string) (time.Time, bool) {
+	if r == nil {
+		return time.Time{}, false
+	}
+	switch v := r.Metadata[key].(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false
+		}
+		return *v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Passcode returns the MetadataKeyPasscode code, if the provider issued one
+func (r *PaymentResponse) Passcode() (string, bool) {
+	passcode, ok := r.GetMetadataString(MetadataKeyPasscode)
+	return passcode, ok && passcode != ""
+}
+
+// PaymentURLValue returns PaymentURL, or the MetadataKeyPaymentURL of
+// responses that only carry it there
+func (r *PaymentResponse) PaymentURLValue() (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	if r.PaymentURL != "" {
+		return r.PaymentURL, true
+	}
+	paymentURL, ok := r.GetMetadataString(MetadataKeyPaymentURL)
+	return paymentURL, ok && paymentURL != ""
+}
@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// MetricsCollector receives measurements of payments, retries and provider
+// HTTP calls. Implementations must be safe for concurrent use.
+type MetricsCollector interface {
+	// ObservePayment records a payment attempt through provider. status is
+	// the resulting PaymentStatus, or the ErrorCode of a failed payment
+	// ("error" when the failure has no code).
+	ObservePayment(provider, status string, duration time.Duration)
+
+	// IncRetry records a provider call being retried
+	IncRetry(provider string)
+
+	// ObserveHTTP records an HTTP call to a provider endpoint, the URL path
+	// without query. code is 0 when no response was received.
+	ObserveHTTP(provider, endpoint string, code int, duration time.Duration)
+}
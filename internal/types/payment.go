@@ -1,3 +1,7 @@
+// Package types holds the one definition of the payment types. pkg/rimpay
+// re-exports them as aliases, so a *rimpay.PaymentResponse is a
+// *types.PaymentResponse; the provider packages use the rimpay names, and
+// only packages pkg/rimpay imports, such as providers/common, use these.
 package types
 
 import (
@@ -37,18 +41,28 @@ const (
 
 // PaymentRequest represents a payment request
 type PaymentRequest struct {
-	Amount      money.Money            `json:"amount"`
-	PhoneNumber *phone.Phone           `json:"phone_number"`
-	Reference   string                 `json:"reference"`
-	Description string                 `json:"description,omitempty"`
-	Language    Language               `json:"language,omitempty"`
-	Passcode    string                 `json:"passcode,omitempty"`
-	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`
+	Amount      money.Money  `json:"amount"`
+	PhoneNumber *phone.Phone `json:"phone_number"`
+	Reference   string       `json:"reference"`
+	Description string       `json:"description,omitempty"`
+	Language    Language     `json:"language,omitempty"`
+	Passcode    string       `json:"passcode,omitempty"`
+	// OperationType applies to B-PAY only; empty means BPayOperationPayment
+	OperationType BPayOperationType `json:"operation_type,omitempty"`
+	// Mode applies to B-PAY only; empty means BPayModePasscode
+	Mode      BPayMode   `json:"mode,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// NotBefore is the earliest time the payment may be executed
+	NotBefore   *time.Time             `json:"not_before,omitempty"`
 	SuccessURL  string                 `json:"success_url,omitempty"`
 	FailureURL  string                 `json:"failure_url,omitempty"`
 	CancelURL   string                 `json:"cancel_url,omitempty"`
 	CallbackURL string                 `json:"callback_url,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	// CredentialOverrides replaces provider credentials for this payment
+	// only, keyed like ProviderConfig.Credentials. MASRVI honours
+	// merchant_id, so one client can take payments for many merchants.
+	CredentialOverrides map[string]string `json:"credential_overrides,omitempty"`
 }
 
 // PaymentResponse represents a payment response
@@ -63,6 +77,35 @@ type PaymentResponse struct {
 	PaymentURL    string                 `json:"payment_url,omitempty"`
 	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	// Events starts the status trail with the initial event
+	Events []StatusEvent `json:"events,omitempty"`
+	// Raw is the provider's answer as an exported type such as
+	// *BPayRawResponse; see AsBPayRaw. It is nil for providers that
+	// export none.
+	Raw interface{} `json:"-"`
+}
+
+// Sources of a StatusEvent
+const (
+	// EventSourceInitial marks the status returned when a payment is created
+	EventSourceInitial = "initial"
+	// EventSourcePoll marks a status read from the provider's status API
+	EventSourcePoll = "poll"
+	// EventSourceWebhook marks a status delivered by a provider notification
+	EventSourceWebhook = "webhook"
+)
+
+// StatusEvent records a status observed for a transaction
+type StatusEvent struct {
+	Status  PaymentStatus `json:"status"`
+	Message string        `json:"message,omitempty"`
+	At      time.Time     `json:"at"`
+	Source  string        `json:"source"`
+}
+
+// NewStatusEvent creates an event observed now
+func NewStatusEvent(status PaymentStatus, message, source string) StatusEvent {
+	return StatusEvent{Status: status, Message: message, At: time.Now(), Source: source}
 }
 
 // IsSuccessful returns true if payment was successful
@@ -80,6 +123,11 @@ func (ps PaymentStatus) IsCompleted() bool {
 	return ps == PaymentStatusSuccess || ps == PaymentStatusFailed || ps == PaymentStatusCancelled || ps == PaymentStatusExpired
 }
 
+// IsValid returns true if ps is one of the defined statuses
+func (ps PaymentStatus) IsValid() bool {
+	return ps == PaymentStatusPending || ps.IsCompleted()
+}
+
 // String returns string representation
 func (ps PaymentStatus) String() string {
 	return string(ps)
@@ -87,7 +135,7 @@ func (ps PaymentStatus) String() string {
 
 // GetLanguage returns language with default fallback
 func (pr *PaymentRequest) GetLanguage() Language {
-	if pr.Language == "" {
+	if pr == nil || pr.Language == "" {
 		return LanguageFrench // Default for Mauritania
 	}
 	return pr.Language
@@ -95,24 +143,40 @@ func (pr *PaymentRequest) GetLanguage() Language {
 
 // IsExpired returns true if payment request has expired
 func (pr *PaymentRequest) IsExpired() bool {
-	if pr.ExpiresAt == nil {
+	return pr.ExpiredAt(time.Now())
+}
+
+// ExpiredAt returns true if payment request has expired at now
+func (pr *PaymentRequest) ExpiredAt(now time.Time) bool {
+	if pr == nil || pr.ExpiresAt == nil {
 		return false
 	}
-	return time.Now().After(*pr.ExpiresAt)
+	return now.After(*pr.ExpiresAt)
+}
+
+// DueAt returns true unless NotBefore is after now
+func (pr *PaymentRequest) DueAt(now time.Time) bool {
+	if pr == nil || pr.NotBefore == nil {
+		return true
+	}
+	return !now.Before(*pr.NotBefore)
 }
 
 // IsCompleted returns true if payment is completed
 func (pr *PaymentResponse) IsCompleted() bool {
-	return pr.Status.IsCompleted()
+	return pr != nil && pr.Status.IsCompleted()
 }
 
 // IsSuccessful returns true if payment was successful
 func (pr *PaymentResponse) IsSuccessful() bool {
-	return pr.Status.IsSuccessful()
+	return pr != nil && pr.Status.IsSuccessful()
 }
 
 // Validate validates payment request
 func (pr *PaymentRequest) Validate() error {
+	if pr == nil {
+		return NewValidationError("request", "is required")
+	}
 	if pr.Amount.IsZero() || pr.Amount.IsNegative() {
 		return NewValidationError("amount", "must be positive")
 	}
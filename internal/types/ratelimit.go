@@ -0,0 +1,26 @@
+package types
+
+import "fmt"
+
+// RateLimitConfig limits the outbound requests of one provider, including
+// authentication and session calls, with a token bucket. It is independent
+// of the client's priority lane rates, which count calls rather than requests.
+type RateLimitConfig struct {
+	// Rate is the sustained number of requests per second
+	Rate float64 `json:"rate"`
+	// Burst is the number of requests that may be sent at once (defaults to 1)
+	Burst int `json:"burst"`
+	// FailFast returns a RATE_LIMITED error instead of waiting for capacity
+	FailFast bool `json:"fail_fast"`
+}
+
+// Validate checks that the rate is positive and the burst is not negative
+func (c RateLimitConfig) Validate() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate_limit rate must be positive")
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("rate_limit burst cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package types
+
+import "github.com/CatoSystems/rim-pay/pkg/money"
+
+// BPayRawResponse is the B-PAY answer behind a PaymentResponse or a status
+// check, for fields the generic types do not carry
+type BPayRawResponse struct {
+	// ErrorCode is "0" on success
+	ErrorCode     string `json:"error_code"`
+	ErrorMessage  string `json:"error_message,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	// Status is the checkTransaction status (TS, TF, TA, ...), empty for
+	// payment responses
+	Status string `json:"status,omitempty"`
+}
+
+// MasrviRawNotification is the MASRVI notification behind a
+// TransactionStatus. It carries no signature or unparsed parameters.
+type MasrviRawNotification struct {
+	// Status is OK or NOK
+	Status      string `json:"status"`
+	ClientID    string `json:"client_id,omitempty"`
+	ClientName  string `json:"client_name,omitempty"`
+	Mobile      string `json:"mobile,omitempty"`
+	PurchaseRef string `json:"purchase_ref"`
+	PaymentRef  string `json:"payment_ref,omitempty"`
+	PayID       string `json:"pay_id,omitempty"`
+	Timestamp   string `json:"timestamp,omitempty"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Amount is what the customer paid, zero when not reported
+	Amount money.Money `json:"amount"`
+}
+
+// AsBPayRaw returns the B-PAY response behind r, if r came from B-PAY
+func (r *PaymentResponse) AsBPayRaw() (*BPayRawResponse, bool) {
+	if r == nil {
+		return nil, false
+	}
+	raw, ok := r.Raw.(*BPayRawResponse)
+	return raw, ok && raw != nil
+}
@@ -0,0 +1,162 @@
+package types
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/CatoSystems/rim-pay/internal/errors"
+	"github.com/CatoSystems/rim-pay/internal/qrcode"
+)
+
+// RedirectInfo describes where to send the customer to pay: a GET of URL,
+// or a POST of Fields to it
+type RedirectInfo struct {
+	URL    string
+	Method string
+	Fields url.Values
+}
+
+// RedirectInfo returns the payment URL and form fields of providers that
+// redirect the customer, such as MASRVI and CLICK. It reads
+// PaymentURLValue and the MetadataKeyFormData metadata.
+func (r *PaymentResponse) RedirectInfo() (*RedirectInfo, bool) {
+	paymentURL, ok := r.PaymentURLValue()
+	if !ok {
+		return nil, false
+	}
+
+	info := &RedirectInfo{URL: paymentURL, Method: http.MethodGet}
+	if fields := formFields(r.Metadata[MetadataKeyFormData]); len(fields) > 0 {
+		info.Method = http.MethodPost
+		info.Fields = fields
+	}
+	return info, true
+}
+
+// formFields converts form data stored as url.Values, or as a map after
+// a JSON round trip, to url.Values
+func formFields(value interface{}) url.Values {
+	switch v := value.(type) {
+	case url.Values:
+		return v
+	case map[string][]string:
+		return url.Values(v)
+	case map[string]string:
+		fields := make(url.Values, len(v))
+		for key, value := range v {
+			fields.Set(key, value)
+		}
+		return fields
+	case map[string]interface{}:
+		fields := make(url.Values, len(v))
+		for key, value := range v {
+			switch value := value.(type) {
+			case []interface{}:
+				for _, item := range value {
+					fields.Add(key, fmt.Sprint(item))
+				}
+			case []string:
+				fields[key] = value
+			default:
+				fields.Set(key, fmt.Sprint(value))
+			}
+		}
+		return fields
+	default:
+		return nil
+	}
+}
+
+// autoSubmitForm posts the fields as soon as the page loads, with a button
+// for browsers without JavaScript. html/template escapes every value.
+var autoSubmitForm = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Redirecting to payment</title></head>
+<body onload="document.forms[0].submit()">
+<form method="{{.Method}}" action="{{.URL}}">
+{{- range .Fields}}
+<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{- end}}
+<noscript><button type="submit">Continue to payment</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+type formField struct {
+	Name  string
+	Value string
+}
+
+// RenderAutoSubmitForm writes a minimal HTML page that sends the customer
+// to the payment page, posting the form fields when there are any
+func (r *PaymentResponse) RenderAutoSubmitForm(w io.Writer) error {
+	info, ok := r.RedirectInfo()
+	if !ok {
+		return errors.ErrNoRedirect
+	}
+
+	keys := make([]string, 0, len(info.Fields))
+	for key := range info.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var fields []formField
+	for _, key := range keys {
+		for _, value := range info.Fields[key] {
+			fields = append(fields, formField{Name: key, Value: value})
+		}
+	}
+
+	return autoSubmitForm.Execute(w, struct {
+		Method string
+		URL    string
+		Fields []formField
+	}{
+		Method: info.Method,
+		URL:    info.URL,
+		Fields: fields,
+	})
+}
+
+// LinkURL returns the payment URL as a single GET link, for QR codes and
+// shared links. For providers taking form fields it carries them as a
+// query string.
+func (r *PaymentResponse) LinkURL() (string, error) {
+	info, ok := r.RedirectInfo()
+	if !ok {
+		return "", errors.ErrNoRedirect
+	}
+	if len(info.Fields) == 0 {
+		return info.URL, nil
+	}
+	parsed, err := url.Parse(info.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid payment URL: %w", err)
+	}
+	query := parsed.Query()
+	for key, values := range info.Fields {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// PaymentQRCode returns a size×size PNG QR code of LinkURL, for
+// point-of-sale displays
+func (r *PaymentResponse) PaymentQRCode(size int) ([]byte, error) {
+	target, err := r.LinkURL()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.Encode([]byte(target))
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(size)
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// RefundRequest reverses all or part of a successful payment
+type RefundRequest struct {
+	// TransactionID is the provider transaction ID of the payment
+	TransactionID string `json:"transaction_id"`
+	// Amount to refund; less than the original amount makes a partial refund
+	Amount money.Money `json:"amount"`
+	// OriginalAmount is the amount of the payment. When set, refunds above
+	// it are rejected before reaching the provider.
+	OriginalAmount money.Money `json:"original_amount,omitempty"`
+	Reason         string      `json:"reason,omitempty"`
+	// Reference identifies the refund itself and must be unique
+	Reference string `json:"reference"`
+	// Provider that processed the payment; empty means the default provider
+	Provider string `json:"provider,omitempty"`
+}
+
+// RefundResponse represents the outcome of a refund
+type RefundResponse struct {
+	RefundID      string                 `json:"refund_id"`
+	TransactionID string                 `json:"transaction_id"`
+	Status        PaymentStatus          `json:"status"`
+	Amount        money.Money            `json:"amount"`
+	Reference     string                 `json:"reference"`
+	Provider      string                 `json:"provider"`
+	Partial       bool                   `json:"partial"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// IsPartial returns true if the refund is for less than OriginalAmount
+func (r *RefundRequest) IsPartial() bool {
+	if r == nil || r.OriginalAmount.IsZero() {
+		return false
+	}
+	less, err := r.Amount.LessThan(r.OriginalAmount)
+	return err == nil && less
+}
+
+// Validate validates the refund request. A refund larger than the original
+// amount fails with ErrorCodeInvalidRequest.
+func (r *RefundRequest) Validate() error {
+	if r == nil {
+		return NewValidationError("request", "is required")
+	}
+	if r.TransactionID == "" {
+		return NewValidationError("transaction_id", "is required")
+	}
+	if r.Reference == "" {
+		return NewValidationError("reference", "is required")
+	}
+	if !r.Amount.IsPositive() {
+		return NewValidationError("amount", "must be positive")
+	}
+	if r.OriginalAmount.IsZero() {
+		return nil
+	}
+
+	exceeds, err := r.Amount.GreaterThan(r.OriginalAmount)
+	if err != nil {
+		return NewValidationError("amount", fmt.Sprintf("currency %s does not match original %s",
+			r.Amount.Currency(), r.OriginalAmount.Currency()))
+	}
+	if exceeds {
+		return NewPaymentError(ErrorCodeInvalidRequest,
+			fmt.Sprintf("refund of %s exceeds the original amount of %s", r.Amount, r.OriginalAmount),
+			r.Provider, false).WithDetail("field", "amount")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryConfig controls how provider calls are retried on retryable errors.
+// MaxAttempts counts the first call, so 1 disables retries.
+type RetryConfig struct {
+	MaxAttempts  int           `json:"max_attempts"`
+	InitialDelay time.Duration `json:"initial_delay"`
+	MaxDelay     time.Duration `json:"max_delay"`
+	Multiplier   float64       `json:"multiplier"`
+	EnableJitter bool          `json:"enable_jitter"`
+
+	// OnRetry, when set, is called after each failed attempt that will be
+	// retried, before sleeping for nextDelay. It is not called after the
+	// final attempt or for non-retryable errors.
+	OnRetry func(attempt int, err error, nextDelay time.Duration) `json:"-"`
+}
+
+// DefaultRetryConfig returns default retry configuration
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2.0,
+		EnableJitter: true,
+	}
+}
+
+// IsZero reports whether no retry settings were given. OnRetry is not a
+// setting: a config with only OnRetry uses the default policy.
+func (c RetryConfig) IsZero() bool {
+	return c.MaxAttempts == 0 && c.InitialDelay == 0 && c.MaxDelay == 0 &&
+		c.Multiplier == 0 && !c.EnableJitter
+}
+
+// Validate checks that at least one attempt is made and delays do not shrink
+func (c RetryConfig) Validate() error {
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("retry max_attempts must be at least 1")
+	}
+	if c.Multiplier < 1 {
+		return fmt.Errorf("retry multiplier must be at least 1.0")
+	}
+	if c.InitialDelay < 0 || c.MaxDelay < 0 {
+		return fmt.Errorf("retry delays cannot be negative")
+	}
+	return nil
+}
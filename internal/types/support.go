@@ -0,0 +1,169 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Detail keys populated by the HTTP and retry layers and read by SupportBundle
+const (
+	DetailEndpoint       = "endpoint"
+	DetailHTTPStatus     = "http_status"
+	DetailRequestTimeout = "request_timeout"
+	DetailAttempts       = "attempts"
+	DetailRetryPolicy    = "retry_policy"
+	// DetailAttemptCount is the number of attempts made, as an int
+	DetailAttemptCount = "attempt_count"
+	// DetailTotalRetryDelay is the time slept between attempts in
+	// milliseconds, as an int64
+	DetailTotalRetryDelay = "total_retry_delay_ms"
+	// DetailRawResponse is the sanitized, truncated body of a response that
+	// could not be decoded, as []byte
+	DetailRawResponse = "raw_response"
+)
+
+// AttemptRecord describes one attempt in a PaymentError's retry history
+type AttemptRecord struct {
+	Attempt    int           `json:"attempt"`
+	HTTPStatus int           `json:"http_status,omitempty"`
+	Duration   time.Duration `json:"-"`
+	ErrorClass string        `json:"error_class,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// Delay is the wait before the next attempt, zero for the last one
+	Delay time.Duration `json:"-"`
+}
+
+// MarshalJSON renders Duration and Delay in milliseconds for readability in
+// tickets
+func (a AttemptRecord) MarshalJSON() ([]byte, error) {
+	type plain AttemptRecord
+	return json.Marshal(struct {
+		plain
+		DurationMS int64 `json:"duration_ms"`
+		DelayMS    int64 `json:"delay_ms,omitempty"`
+	}{plain(a), a.Duration.Milliseconds(), a.Delay.Milliseconds()})
+}
+
+// SupportReport is the schema of PaymentError.SupportBundle
+type SupportReport struct {
+	Code      ErrorCode              `json:"code"`
+	Message   string                 `json:"message"`
+	Provider  string                 `json:"provider,omitempty"`
+	Retryable bool                   `json:"retryable"`
+	Endpoint  string                 `json:"endpoint,omitempty"`
+	Attempts  []AttemptRecord        `json:"attempts"`
+	Config    SupportConfig          `json:"config"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Cause     string                 `json:"cause,omitempty"`
+}
+
+// SupportConfig is the configuration snapshot included in a SupportReport
+type SupportConfig struct {
+	RequestTimeout string                 `json:"request_timeout,omitempty"`
+	RetryPolicy    map[string]interface{} `json:"retry_policy,omitempty"`
+}
+
+// sensitiveDetailKeys are redacted wherever they appear in a support bundle
+var sensitiveDetailKeys = []string{"password", "passcode", "token", "secret", "authorization", "api_key"}
+
+// redactedText replaces sensitive values in support bundles and log text
+const redactedText = "[REDACTED]"
+
+// sensitiveText matches key=value, key: value and "key":"value" pairs whose
+// key looks sensitive, as found in error messages, URLs and response bodies
+var sensitiveText = regexp.MustCompile(`(?i)("?[\w-]*(?:password|passcode|token|secret|authorization|api_?key|merchant_?id|signature)[\w-]*"?\s*[:=]\s*)(?:(?:bearer|basic)\s+)?("[^"]*"|[^\s&,;"}]+)`)
+
+// RedactText masks the values of sensitive key=value, key: value and JSON
+// pairs in free text such as error messages. SupportBundle and the
+// redacting logger apply it.
+func RedactText(text string) string {
+	return sensitiveText.ReplaceAllString(text, "${1}"+redactedText)
+}
+
+// SupportBundle returns a redacted JSON summary of the error for support
+// tickets: provider, endpoint, per-attempt outcomes and the timeout and retry
+// configuration in effect. Details of PaymentErrors in the Cause chain are
+// merged in, with outer values taking precedence.
+func (e *PaymentError) SupportBundle() ([]byte, error) {
+	if e == nil {
+		return nil, errors.New("support bundle requested for nil PaymentError")
+	}
+	details := make(map[string]interface{})
+	for err := error(e); err != nil; err = errors.Unwrap(err) {
+		paymentErr, ok := err.(*PaymentError)
+		if !ok {
+			continue
+		}
+		for key, value := range paymentErr.Details {
+			if _, exists := details[key]; !exists {
+				details[key] = value
+			}
+		}
+	}
+
+	report := SupportReport{
+		Code:      e.Code,
+		Message:   RedactText(e.Message),
+		Provider:  e.Provider,
+		Retryable: e.Retryable,
+		Attempts:  []AttemptRecord{},
+	}
+
+	if endpoint, ok := details[DetailEndpoint].(string); ok {
+		report.Endpoint = endpoint
+	}
+	if attempts, ok := details[DetailAttempts].([]AttemptRecord); ok {
+		report.Attempts = attempts
+	}
+	if timeout, ok := details[DetailRequestTimeout].(time.Duration); ok {
+		report.Config.RequestTimeout = timeout.String()
+	}
+	if policy, ok := details[DetailRetryPolicy].(map[string]interface{}); ok {
+		report.Config.RetryPolicy = policy
+	}
+	for _, key := range []string{DetailEndpoint, DetailAttempts, DetailRequestTimeout, DetailRetryPolicy} {
+		delete(details, key)
+	}
+
+	if raw, ok := details[DetailRawResponse].([]byte); ok {
+		details[DetailRawResponse] = string(raw)
+	}
+
+	if len(details) > 0 {
+		report.Details = redactDetails(details)
+	}
+	if e.Cause != nil {
+		report.Cause = RedactText(e.Cause.Error())
+	}
+
+	return json.Marshal(report)
+}
+
+// redactDetails copies details, masking values whose key looks sensitive
+func redactDetails(details map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(details))
+	for key, value := range details {
+		if isSensitiveKey(key) {
+			redacted[key] = redactedText
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = redactDetails(nested)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveDetailKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
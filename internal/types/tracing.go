@@ -0,0 +1,23 @@
+package types
+
+import "context"
+
+// Tracer starts the spans recorded while processing payments. An
+// OpenTelemetry tracer can be adapted to it in a few lines.
+type Tracer interface {
+	// Start starts a span named name as a child of the span in ctx, if
+	// any, and returns a context carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a unit of traced work
+type Span interface {
+	// SetAttribute sets a string, int or bool attribute
+	SetAttribute(key string, value interface{})
+
+	// RecordError marks the span as failed with err
+	RecordError(err error)
+
+	// End completes the span
+	End()
+}
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/internal/errors"
+)
+
+// FieldError is the validation failure of a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every validation failure of a request, so that
+// callers can report all of them at once. errors.As also accepts a
+// *PaymentError target, which receives a VALIDATION_ERROR describing the
+// failures, as returned before failures were collected.
+type ValidationErrors []FieldError
+
+// Add records a failure of field
+func (e *ValidationErrors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Err returns e, or nil when no failure was recorded
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	return fmt.Sprintf("%s: %s", ErrorCodeValidationError, e.message())
+}
+
+// message joins the failures as "field: message; field: message"
+func (e ValidationErrors) message() string {
+	failures := make([]string, len(e))
+	for i, failure := range e {
+		failures[i] = fmt.Sprintf("%s: %s", failure.Field, failure.Message)
+	}
+	return strings.Join(failures, "; ")
+}
+
+// Fields maps each failed field to its message, for example to return a
+// per-field error map from an HTTP API. Messages of a field that failed
+// more than once are joined with "; ".
+func (e ValidationErrors) Fields() map[string]string {
+	fields := make(map[string]string, len(e))
+	for _, failure := range e {
+		if message, ok := fields[failure.Field]; ok {
+			fields[failure.Field] = message + "; " + failure.Message
+			continue
+		}
+		fields[failure.Field] = failure.Message
+	}
+	return fields
+}
+
+// Is reports whether target is ErrInvalidRequest, which every validation
+// failure matches
+func (e ValidationErrors) Is(target error) bool {
+	return len(e) > 0 && target == errors.ErrInvalidRequest
+}
+
+// As converts e into a VALIDATION_ERROR PaymentError. Its "field" detail is
+// the first failed field and, with several failures, "fields" holds Fields.
+func (e ValidationErrors) As(target interface{}) bool {
+	paymentErr, ok := target.(**PaymentError)
+	if !ok || len(e) == 0 {
+		return false
+	}
+	if len(e) == 1 {
+		*paymentErr = NewValidationError(e[0].Field, e[0].Message)
+		return true
+	}
+	*paymentErr = &PaymentError{
+		Code:    ErrorCodeValidationError,
+		Message: e.message(),
+		Details: map[string]interface{}{"field": e[0].Field, "fields": e.Fields()},
+	}
+	return true
+}
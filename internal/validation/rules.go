@@ -1 +1,167 @@
 package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// ExpiryWindow bounds how far after submission a payment's ExpiresAt may
+// fall
+type ExpiryWindow struct {
+	// Min is the least time ExpiresAt must leave the customer to pay
+	Min time.Duration
+	// Max is the furthest ahead ExpiresAt may be; 0 means no limit
+	Max time.Duration
+}
+
+// DefaultExpiryWindow leaves at least a minute to pay and rejects expiries
+// more than 30 days ahead
+var DefaultExpiryWindow = ExpiryWindow{Min: time.Minute, Max: 30 * 24 * time.Hour}
+
+// Check returns a VALIDATION_ERROR on expires_at unless expiresAt is nil or
+// within the window from now
+func (w ExpiryWindow) Check(expiresAt *time.Time, now time.Time) error {
+	if message := w.Failure(expiresAt, now); message != "" {
+		return types.NewValidationError("expires_at", message)
+	}
+	return nil
+}
+
+// Failure describes why expiresAt is outside the window from now, or
+// returns ""
+func (w ExpiryWindow) Failure(expiresAt *time.Time, now time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	if !expiresAt.After(now) {
+		return "is in the past"
+	}
+	if left := expiresAt.Sub(now); left < w.Min {
+		return fmt.Sprintf("leaves %s to pay; at least %s is required", left.Round(time.Second), w.Min)
+	}
+	if w.Max > 0 && expiresAt.After(now.Add(w.Max)) {
+		return fmt.Sprintf("is more than %s ahead", w.Max)
+	}
+	return ""
+}
+
+// Charset is the set of characters a provider accepts in payment
+// descriptions
+type Charset string
+
+const (
+	// CharsetAny accepts every character
+	CharsetAny Charset = ""
+	// CharsetSafe accepts letters and digits of any script, spaces and
+	// ASCII punctuation, but not Arabic punctuation such as ، or ؟
+	CharsetSafe Charset = "safe"
+	// CharsetLatin accepts printable ASCII and Latin-1 letters only
+	CharsetLatin Charset = "latin"
+)
+
+// IsValid reports whether c is a known charset
+func (c Charset) IsValid() bool {
+	switch c {
+	case CharsetAny, CharsetSafe, CharsetLatin:
+		return true
+	}
+	return false
+}
+
+func (c Charset) allows(r rune) bool {
+	switch c {
+	case CharsetSafe:
+		return r == ' ' || r < unicode.MaxASCII && unicode.IsPrint(r) ||
+			unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+	case CharsetLatin:
+		return r == ' ' || r < unicode.MaxASCII && unicode.IsPrint(r) ||
+			r <= unicode.MaxLatin1 && unicode.IsLetter(r)
+	}
+	return true
+}
+
+func (c Charset) describe() string {
+	switch c {
+	case CharsetSafe:
+		return "letters, digits, spaces and ASCII punctuation"
+	case CharsetLatin:
+		return "Latin letters, digits, spaces and ASCII punctuation"
+	}
+	return "any character"
+}
+
+// Check returns a VALIDATION_ERROR on field naming the first character of
+// value outside the charset and its position
+func (c Charset) Check(field, value string) error {
+	if message := c.Failure(value); message != "" {
+		return types.NewValidationError(field, message)
+	}
+	return nil
+}
+
+// Failure names the first character of value outside the charset and its
+// position, or returns ""
+func (c Charset) Failure(value string) string {
+	position := 0
+	for _, r := range value {
+		position++
+		if !c.allows(r) {
+			return fmt.Sprintf("contains %q (U+%04X) at position %d; only %s are accepted", r, r, position, c.describe())
+		}
+	}
+	return ""
+}
+
+// Sanitize makes value pass the charset: it applies common.SanitizeString,
+// transliterates Arabic punctuation, typographic quotes and, for
+// CharsetLatin, Arabic letters and digits, then drops what is still
+// rejected
+func (c Charset) Sanitize(value string) string {
+	value = common.SanitizeString(value)
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range value {
+		if c.allows(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if replacement, ok := punctuation[r]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+		if c == CharsetLatin {
+			if replacement, ok := latin[r]; ok {
+				b.WriteString(replacement)
+				continue
+			}
+			if r >= '٠' && r <= '٩' {
+				b.WriteRune('0' + r - '٠')
+				continue
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// punctuation transliterates the punctuation providers reject
+var punctuation = map[rune]string{
+	'،': ",", '؛': ";", '؟': "?", '٪': "%", '٫': ",", '٬': ",", '۔': ".",
+	'«': "\"", '»': "\"", '“': "\"", '”': "\"", '„': "\"", '‘': "'", '’': "'",
+	'–': "-", '—': "-", '…': "...", 'ـ': "", '\u00a0': " ",
+}
+
+// latin transliterates Arabic letters and the ligatures outside Latin-1
+var latin = map[rune]string{
+	'ا': "a", 'أ': "a", 'إ': "i", 'آ': "a", 'ء': "'", 'ؤ': "u", 'ئ': "i",
+	'ب': "b", 'ت': "t", 'ث': "th", 'ج': "j", 'ح': "h", 'خ': "kh", 'د': "d",
+	'ذ': "dh", 'ر': "r", 'ز': "z", 'س': "s", 'ش': "sh", 'ص': "s", 'ض': "d",
+	'ط': "t", 'ظ': "z", 'ع': "'", 'غ': "gh", 'ف': "f", 'ق': "q", 'ك': "k",
+	'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'و': "w", 'ي': "y", 'ى': "a",
+	'ة': "a",
+	'Œ': "OE", 'œ': "oe", 'Ÿ': "Y",
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"regexp"
+	"time"
 
 	"github.com/CatoSystems/rim-pay/internal/types"
 	"github.com/CatoSystems/rim-pay/pkg/money"
@@ -11,83 +12,121 @@ import (
 type Validator struct {
 	emailRegex *regexp.Regexp
 	urlRegex   *regexp.Regexp
+	options    Options
+}
+
+// Options configures the checks of a Validator
+type Options struct {
+	// Expiry bounds ExpiresAt; the zero value means DefaultExpiryWindow
+	Expiry ExpiryWindow
+	// Charset restricts descriptions (default CharsetAny)
+	Charset Charset
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
 }
 
 const errInvalidURLFormat = "invalid URL format"
 
 // NewValidator creates a new validator
 func NewValidator() *Validator {
+	return NewValidatorWithOptions(Options{})
+}
+
+// NewValidatorWithOptions creates a validator with the given expiry window,
+// description charset and clock
+func NewValidatorWithOptions(options Options) *Validator {
+	if options.Expiry == (ExpiryWindow{}) {
+		options.Expiry = DefaultExpiryWindow
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
 	return &Validator{
 		emailRegex: regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`),
 		urlRegex:   regexp.MustCompile(`^[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`),
+		options:    options,
 	}
 }
 
-// ValidatePaymentRequest validates a payment request
+// ValidatePaymentRequest validates a payment request, returning
+// types.ValidationErrors with every failed field
 func (v *Validator) ValidatePaymentRequest(request *types.PaymentRequest) error {
 	if request == nil {
 		return types.NewValidationError("request", "cannot be nil")
 	}
 
+	var errs types.ValidationErrors
+
 	// Validate amount
-	if err := v.ValidateAmount(request.Amount); err != nil {
-		return err
+	if message := amountFailure(request.Amount); message != "" {
+		errs.Add("amount", message)
 	}
 
 	// Validate phone number
 	if request.PhoneNumber == nil {
-		return types.NewValidationError("phone_number", "is required")
+		errs.Add("phone_number", "is required")
 	}
 
 	// Validate reference
-	if err := v.validateReference(request.Reference); err != nil {
-		return err
-	}
-	if request.SuccessURL != "" && !v.isValidURL(request.SuccessURL) {
-		return types.NewValidationError("success_url", errInvalidURLFormat)
+	if message := referenceFailure(request.Reference); message != "" {
+		errs.Add("reference", message)
 	}
 
-	if request.FailureURL != "" && !v.isValidURL(request.FailureURL) {
-		return types.NewValidationError("failure_url", errInvalidURLFormat)
+	urls := []struct{ field, value string }{
+		{"success_url", request.SuccessURL},
+		{"failure_url", request.FailureURL},
+		{"cancel_url", request.CancelURL},
+		{"callback_url", request.CallbackURL},
 	}
-
-	if request.CancelURL != "" && !v.isValidURL(request.CancelURL) {
-		return types.NewValidationError("cancel_url", errInvalidURLFormat)
+	for _, u := range urls {
+		if u.value != "" && !v.isValidURL(u.value) {
+			errs.Add(u.field, errInvalidURLFormat)
+		}
 	}
 
-	if request.CallbackURL != "" && !v.isValidURL(request.CallbackURL) {
-		return types.NewValidationError("callback_url", errInvalidURLFormat)
+	// Validate description length and characters
+	if len(request.Description) > 255 {
+		errs.Add("description", "too long (max 255 characters)")
+	} else if message := v.options.Charset.Failure(request.Description); message != "" {
+		errs.Add("description", message)
 	}
 
-	// Validate description length
-	if len(request.Description) > 255 {
-		return types.NewValidationError("description", "too long (max 255 characters)")
+	if message := v.options.Expiry.Failure(request.ExpiresAt, v.options.Now()); message != "" {
+		errs.Add("expires_at", message)
 	}
 
-	return nil
+	return errs.Err()
 }
 
 // ValidateAmount validates a monetary amount
 func (v *Validator) ValidateAmount(amount money.Money) error {
+	if message := amountFailure(amount); message != "" {
+		return types.NewValidationError("amount", message)
+	}
+	return nil
+}
+
+// amountFailure describes why amount is invalid, or returns ""
+func amountFailure(amount money.Money) string {
 	if amount.IsZero() {
-		return types.NewValidationError("amount", "cannot be zero")
+		return "cannot be zero"
 	}
 
 	if amount.IsNegative() {
-		return types.NewValidationError("amount", "cannot be negative")
+		return "cannot be negative"
 	}
 
 	// Validate currency
 	if err := amount.Validate(); err != nil {
-		return types.NewValidationError("amount", err.Error())
+		return err.Error()
 	}
 
 	// Check reasonable limits (adjust based on business requirements)
 	if amount.Float64() > 10000000 { // 10 million
-		return types.NewValidationError("amount", "exceeds maximum allowed amount")
+		return "exceeds maximum allowed amount"
 	}
 
-	return nil
+	return ""
 }
 
 // ValidatePhoneNumber validates a phone number string
@@ -105,22 +144,23 @@ func (v *Validator) ValidatePhoneNumber(phoneStr string) error {
 	return nil
 }
 
-// validateReference validates payment reference
-func (v *Validator) validateReference(reference string) error {
+// referenceFailure describes why a payment reference is invalid, or
+// returns ""
+func referenceFailure(reference string) string {
 	if reference == "" {
-		return types.NewValidationError("reference", "is required")
+		return "is required"
 	}
 
 	if len(reference) > 50 {
-		return types.NewValidationError("reference", "too long (max 50 characters)")
+		return "too long (max 50 characters)"
 	}
 
 	// Check for valid characters (alphanumeric, dashes, underscores)
 	validRefRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]+`)
 	if !validRefRegex.MatchString(reference) {
-		return types.NewValidationError("reference", "invalid reference format")
+		return "invalid reference format"
 	}
-	return nil
+	return ""
 }
 
 // isValidURL validates URL format
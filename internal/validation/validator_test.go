@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+var validatorNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func validatorRequest(t *testing.T) *types.PaymentRequest {
+	t.Helper()
+	number, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &types.PaymentRequest{
+		PhoneNumber: number,
+		Amount:      money.NewMRU(5000),
+		Reference:   "REF-1",
+		Description: "دفع فاتورة الكهرباء",
+	}
+}
+
+func fieldMessages(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var errs types.ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	messages := make(map[string]string)
+	for _, fieldErr := range errs {
+		messages[fieldErr.Field] = fieldErr.Message
+	}
+	return messages
+}
+
+func TestValidateExpiresAt(t *testing.T) {
+	validator := NewValidatorWithOptions(Options{
+		Expiry: ExpiryWindow{Min: 5 * time.Minute, Max: 24 * time.Hour},
+		Now:    func() time.Time { return validatorNow },
+	})
+	at := func(d time.Duration) *time.Time {
+		expiresAt := validatorNow.Add(d)
+		return &expiresAt
+	}
+
+	for name, tc := range map[string]struct {
+		expiresAt *time.Time
+		message   string
+	}{
+		"unset":      {nil, ""},
+		"in window":  {at(time.Hour), ""},
+		"past":       {at(-time.Minute), "is in the past"},
+		"now":        {at(0), "is in the past"},
+		"too soon":   {at(2 * time.Minute), "leaves 2m0s to pay; at least 5m0s is required"},
+		"far future": {at(48 * time.Hour), "is more than 24h0m0s ahead"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			request := validatorRequest(t)
+			request.ExpiresAt = tc.expiresAt
+			err := validator.ValidatePaymentRequest(request)
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.message, fieldMessages(t, err)["expires_at"])
+		})
+	}
+}
+
+func TestDefaultValidatorRejectsPastExpiry(t *testing.T) {
+	request := validatorRequest(t)
+	past := time.Now().Add(-time.Hour)
+	request.ExpiresAt = &past
+	assert.Equal(t, "is in the past", fieldMessages(t, NewValidator().ValidatePaymentRequest(request))["expires_at"])
+
+	future := time.Now().Add(time.Hour)
+	request.ExpiresAt = &future
+	assert.NoError(t, NewValidator().ValidatePaymentRequest(request), "any description passes by default")
+}
+
+func TestValidateDescriptionCharset(t *testing.T) {
+	validator := NewValidatorWithOptions(Options{Charset: CharsetSafe})
+
+	request := validatorRequest(t)
+	assert.NoError(t, validator.ValidatePaymentRequest(request), "Arabic letters are safe")
+
+	request.Description = "فاتورة رقم ١٢، شهر مارس"
+	assert.Equal(t, "contains '،' (U+060C) at position 14; only letters, digits, spaces and ASCII punctuation are accepted",
+		fieldMessages(t, validator.ValidatePaymentRequest(request))["description"])
+
+	request.Description = "Facture n°12 réglée"
+	assert.Equal(t, "contains '°' (U+00B0) at position 10; only letters, digits, spaces and ASCII punctuation are accepted",
+		fieldMessages(t, validator.ValidatePaymentRequest(request))["description"])
+
+	assert.Equal(t, "contains 'ف' (U+0641) at position 1; only Latin letters, digits, spaces and ASCII punctuation are accepted",
+		CharsetLatin.Failure("فاتورة"))
+	assert.Empty(t, CharsetLatin.Failure("Facture réglée (mars)"))
+}
+
+func TestSanitizeDescription(t *testing.T) {
+	for _, tc := range []struct {
+		charset  Charset
+		input    string
+		expected string
+	}{
+		{CharsetSafe, "هل تم الدفع؟  «نعم»، شكرا", `هل تم الدفع? "نعم", شكرا`},
+		{CharsetSafe, "Facture\tn°12 – mars…", "Facture n12 - mars..."},
+		{CharsetLatin, "فاتورة ١٢", "fatwra 12"},
+		{CharsetLatin, "Cœur réglé ✓", "Coeur réglé"},
+		{CharsetAny, "« tout » ✓", "« tout » ✓"},
+	} {
+		sanitized := tc.charset.Sanitize(tc.input)
+		assert.Equal(t, tc.expected, sanitized, tc.input)
+		assert.Empty(t, tc.charset.Failure(sanitized), tc.input)
+	}
+}
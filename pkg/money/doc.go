@@ -31,6 +31,47 @@ Currently supports:
   - MRU: Mauritanian Ouguiya (current since January 1, 2018; 1 MRU = 100 cents)
 
 Note: The old MRO currency was replaced by MRU on January 1, 2018, at a rate of 10 MRO = 1 MRU.
+Legacy systems that still send MRO amounts can be converted with ConvertToMRU or
+ConvertCurrency; results are rounded to two decimal places.
+
+# Parsing
+
+FromString accepts only plain decimals. Parse also reads amounts formatted
+for people, as returned in some provider notifications:
+
+	money.Parse("1,250.00", money.MRU)     // 1250.00 MRU
+	money.Parse("1 250,00 MRU", money.MRU) // 1250.00 MRU
+	money.Parse("1,250", money.MRU)        // error: the comma is ambiguous
+	money.Parse("1.250", money.MRU)        // error: so is the dot
+	money.Parse("1.255", money.MRU)        // error: more than 2 decimal places
+
+# Receipt Formatting
+
+String is meant for logs. Receipts shown to customers use Format, which
+applies the separators and currency symbol of the customer's language:
+
+	amount1.Format(money.LanguageFrench)  // "100,50 UM"
+	amount1.Format(money.LanguageArabic)  // "100,50 أوقية"
+	money.Formatter{Language: money.LanguageFrench, HideCurrency: true}.Format(amount1) // "100,50"
+
+# Amounts in words
+
+Invoices in Mauritania spell the total out in words. InWords renders MRU
+amounts in French or Arabic, naming the integer part in ouguiyas and the
+decimals in khoums:
+
+	words, _ := amount1.InWords(money.LanguageFrench)
+	// "cent ouguiyas et cinquante khoums"
+
+# Fees
+
+FeeSchedule computes a percentage plus a fixed fee in decimal, rounding the
+percentage once with RoundHalfUp, RoundHalfEven or RoundCeiling, so that
+Net + Fee always equals Gross:
+
+	schedule := money.FeeSchedule{Percentage: decimal.RequireFromString("1.5"), Fixed: money.NewMRU(200)}
+	fees, _ := schedule.OnTop(money.NewMRU(10000)) // Gross 103.50, Fee 3.50, Net 100.00
+	fees, _ = schedule.Included(money.NewMRU(10000)) // Gross 100.00, Fee 3.50, Net 96.50
 
 # Precision
 
@@ -0,0 +1,140 @@
+package money
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode says how a fee is rounded to whole khoums
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero: 0.015 is 0.02
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the even khoums: 0.015 is 0.02 and
+	// 0.045 is 0.04
+	RoundHalfEven
+	// RoundCeiling rounds up: 0.011 is 0.02
+	RoundCeiling
+)
+
+// String returns the mode's name
+func (r RoundingMode) String() string {
+	switch r {
+	case RoundHalfUp:
+		return "half_up"
+	case RoundHalfEven:
+		return "half_even"
+	case RoundCeiling:
+		return "ceiling"
+	default:
+		return fmt.Sprintf("RoundingMode(%d)", int(r))
+	}
+}
+
+// round rounds d to two decimal places
+func (r RoundingMode) round(d decimal.Decimal) decimal.Decimal {
+	switch r {
+	case RoundHalfEven:
+		return d.RoundBank(2)
+	case RoundCeiling:
+		return d.RoundCeil(2)
+	default:
+		return d.Round(2)
+	}
+}
+
+var hundred = decimal.NewFromInt(100)
+
+// ApplyPercentage returns pct percent of m, rounded to khoums with mode:
+// 1.5 percent of 100.10 MRU is 1.50 MRU half-up and 1.51 MRU ceiling
+func ApplyPercentage(m Money, pct decimal.Decimal, mode RoundingMode) Money {
+	return New(mode.round(m.amount.Mul(pct).Div(hundred)), m.currency)
+}
+
+// AddFixed returns m plus a fixed fee in the same currency
+func AddFixed(m, fee Money) (Money, error) {
+	if m.currency != fee.currency {
+		return Money{}, fmt.Errorf("cannot add a %s fee to a %s amount", fee.currency, m.currency)
+	}
+	return m.Add(fee)
+}
+
+// FeeSchedule is a percentage plus a fixed fee, such as 1.5% + 2 MRU.
+// Fees are computed in decimal and rounded once, so the amounts of a
+// FeeBreakdown always add up.
+type FeeSchedule struct {
+	// Percentage is the percentage charged, 1.5 for 1.5%
+	Percentage decimal.Decimal
+	// Fixed is charged on every payment; the zero Money charges nothing
+	Fixed Money
+	// Rounding rounds the percentage part (default RoundHalfUp)
+	Rounding RoundingMode
+}
+
+// FeeBreakdown is what the customer pays (Gross), the fee, and what the
+// merchant keeps (Net); Net + Fee always equals Gross
+type FeeBreakdown struct {
+	Gross Money
+	Fee   Money
+	Net   Money
+}
+
+// Validate rejects negative fees and unknown rounding modes
+func (s FeeSchedule) Validate() error {
+	if s.Percentage.IsNegative() {
+		return fmt.Errorf("fee percentage cannot be negative")
+	}
+	if s.Fixed.IsNegative() {
+		return fmt.Errorf("fixed fee cannot be negative")
+	}
+	if s.Rounding < RoundHalfUp || s.Rounding > RoundCeiling {
+		return fmt.Errorf("unknown rounding mode %v", s.Rounding)
+	}
+	return nil
+}
+
+// Fee returns the fee charged on base
+func (s FeeSchedule) Fee(base Money) (Money, error) {
+	if err := s.Validate(); err != nil {
+		return Money{}, err
+	}
+	fee := ApplyPercentage(base, s.Percentage, s.Rounding)
+	if s.Fixed.IsZero() {
+		return fee, nil
+	}
+	return AddFixed(fee, s.Fixed)
+}
+
+// OnTop charges the fee on top of net, the amount the merchant wants:
+// 100 MRU at 1.5% + 2 MRU is a gross of 103.50 MRU
+func (s FeeSchedule) OnTop(net Money) (FeeBreakdown, error) {
+	fee, err := s.Fee(net)
+	if err != nil {
+		return FeeBreakdown{}, err
+	}
+	gross, err := net.Add(fee)
+	if err != nil {
+		return FeeBreakdown{}, err
+	}
+	return FeeBreakdown{Gross: gross, Fee: fee, Net: net}, nil
+}
+
+// Included deducts the fee from gross, the amount the customer pays:
+// 100 MRU at 1.5% + 2 MRU leaves a net of 96.50 MRU. The fee never exceeds
+// gross.
+func (s FeeSchedule) Included(gross Money) (FeeBreakdown, error) {
+	fee, err := s.Fee(gross)
+	if err != nil {
+		return FeeBreakdown{}, err
+	}
+	if cmp, _ := fee.Compare(gross); cmp > 0 {
+		return FeeBreakdown{}, fmt.Errorf("fee %s exceeds the amount %s", fee, gross)
+	}
+	net, err := gross.Subtract(fee)
+	if err != nil {
+		return FeeBreakdown{}, err
+	}
+	return FeeBreakdown{Gross: gross, Fee: fee, Net: net}, nil
+}
@@ -0,0 +1,118 @@
+package money
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allRoundingModes = []RoundingMode{RoundHalfUp, RoundHalfEven, RoundCeiling}
+
+func TestApplyPercentageRounding(t *testing.T) {
+	pct := decimal.RequireFromString("1.5")
+	tests := []struct {
+		amount  int64 // cents
+		halfUp  string
+		even    string
+		ceiling string
+	}{
+		{10010, "1.50", "1.50", "1.51"}, // 1.5015
+		{100, "0.02", "0.02", "0.02"},   // 0.015
+		{300, "0.05", "0.04", "0.05"},   // 0.045
+		{10000, "1.50", "1.50", "1.50"}, // exact
+		{1, "0.00", "0.00", "0.01"},     // 0.00015
+	}
+	for _, tt := range tests {
+		amount := NewMRU(tt.amount)
+		for mode, want := range map[RoundingMode]string{RoundHalfUp: tt.halfUp, RoundHalfEven: tt.even, RoundCeiling: tt.ceiling} {
+			fee := ApplyPercentage(amount, pct, mode)
+			assert.Equal(t, want, fee.Amount().StringFixed(2), "%s of %s", mode, amount)
+			assert.Equal(t, MRU, fee.Currency())
+		}
+	}
+}
+
+func TestAddFixed(t *testing.T) {
+	total, err := AddFixed(NewMRU(10000), NewMRU(200))
+	require.NoError(t, err)
+	assert.Equal(t, "102.00 MRU", total.String())
+
+	_, err = AddFixed(NewMRU(10000), FromCents(200, MRO))
+	assert.EqualError(t, err, "cannot add a MRO fee to a MRU amount")
+}
+
+func TestFeeSchedule(t *testing.T) {
+	schedule := FeeSchedule{Percentage: decimal.RequireFromString("1.5"), Fixed: NewMRU(200)}
+
+	onTop, err := schedule.OnTop(NewMRU(10000))
+	require.NoError(t, err)
+	assert.Equal(t, "103.50 MRU", onTop.Gross.String())
+	assert.Equal(t, "3.50 MRU", onTop.Fee.String())
+	assert.Equal(t, "100.00 MRU", onTop.Net.String())
+
+	included, err := schedule.Included(NewMRU(10000))
+	require.NoError(t, err)
+	assert.Equal(t, "100.00 MRU", included.Gross.String())
+	assert.Equal(t, "96.50 MRU", included.Net.String())
+
+	_, err = schedule.Included(NewMRU(100))
+	assert.EqualError(t, err, "fee 2.02 MRU exceeds the amount 1.00 MRU")
+
+	percentOnly := FeeSchedule{Percentage: decimal.NewFromInt(2)}
+	fee, err := percentOnly.Fee(FromCents(5000, MRO))
+	require.NoError(t, err)
+	assert.Equal(t, "1.00 MRO", fee.String(), "a zero fixed fee takes the amount's currency")
+
+	_, err = schedule.Fee(FromCents(5000, MRO))
+	assert.Error(t, err, "the fixed fee's currency must match")
+}
+
+func TestFeeScheduleValidate(t *testing.T) {
+	assert.NoError(t, FeeSchedule{}.Validate())
+	assert.EqualError(t, FeeSchedule{Percentage: decimal.NewFromInt(-1)}.Validate(), "fee percentage cannot be negative")
+	assert.EqualError(t, FeeSchedule{Fixed: NewMRU(-1)}.Validate(), "fixed fee cannot be negative")
+	assert.EqualError(t, FeeSchedule{Rounding: 7}.Validate(), "unknown rounding mode RoundingMode(7)")
+}
+
+func TestFeeBreakdownAddsUp(t *testing.T) {
+	r := rand.New(rand.NewSource(2313))
+	for _, mode := range allRoundingModes {
+		for i := 0; i < 3000; i++ {
+			schedule := FeeSchedule{
+				Percentage: decimal.New(r.Int63n(1000), -2), // 0.00% to 9.99%
+				Fixed:      NewMRU(r.Int63n(1000)),
+				Rounding:   mode,
+			}
+			amount := NewMRU(r.Int63n(100_000_000) + 1)
+
+			onTop, err := schedule.OnTop(amount)
+			require.NoError(t, err)
+			assertAddsUp(t, onTop, mode)
+			assert.True(t, onTop.Net.Equals(amount))
+
+			included, err := schedule.Included(amount)
+			if err != nil {
+				continue // the fee exceeds a small amount
+			}
+			assertAddsUp(t, included, mode)
+			assert.True(t, included.Gross.Equals(amount))
+		}
+	}
+}
+
+func assertAddsUp(t *testing.T, b FeeBreakdown, mode RoundingMode) {
+	t.Helper()
+	sum, err := b.Net.Add(b.Fee)
+	require.NoError(t, err)
+	if !sum.Equals(b.Gross) {
+		t.Fatalf("%s: net %s + fee %s = %s, want gross %s", mode, b.Net, b.Fee, sum, b.Gross)
+	}
+	for _, m := range []Money{b.Gross, b.Fee, b.Net} {
+		if !m.Amount().Equal(m.Amount().Truncate(2)) {
+			t.Fatalf("%s: %s has fractions of a khoums", mode, m.Amount())
+		}
+	}
+}
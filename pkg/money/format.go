@@ -0,0 +1,73 @@
+package money
+
+import "strings"
+
+// numberFormat holds the separators and currency symbol of a receipt locale
+type numberFormat struct {
+	thousands string
+	decimal   string
+	symbol    string
+}
+
+// Receipt conventions per language. French uses the local "UM" symbol with a
+// space between thousands; Arabic follows the Maghreb convention of Latin
+// digits with "." between thousands and "," before decimals.
+var numberFormats = map[Language]numberFormat{
+	LanguageEnglish: {thousands: ",", decimal: ".", symbol: "MRU"},
+	LanguageFrench:  {thousands: " ", decimal: ",", symbol: "UM"},
+	LanguageArabic:  {thousands: ".", decimal: ",", symbol: "أوقية"},
+}
+
+// Formatter renders amounts for customer-facing receipts. Unknown languages
+// are formatted as English.
+type Formatter struct {
+	Language Language
+	// HideCurrency omits the currency symbol, for tables with a currency column
+	HideCurrency bool
+}
+
+// Format renders m with the formatter's separators and currency symbol:
+// "1 234 567,89 UM" in French, "1,234,567.89 MRU" in English
+func (f Formatter) Format(m Money) string {
+	format, ok := numberFormats[f.Language]
+	if !ok {
+		format = numberFormats[LanguageEnglish]
+	}
+
+	fixed := m.amount.Abs().StringFixed(2)
+	integer, fraction := fixed[:len(fixed)-3], fixed[len(fixed)-2:]
+
+	var b strings.Builder
+	if m.amount.IsNegative() {
+		b.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(format.thousands)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(format.decimal)
+	b.WriteString(fraction)
+
+	if !f.HideCurrency {
+		b.WriteString(" ")
+		b.WriteString(currencySymbol(format, m.currency))
+	}
+	return b.String()
+}
+
+// currencySymbol returns the localised ouguiya symbol, or the currency code
+// for other currencies
+func currencySymbol(format numberFormat, currency Currency) string {
+	if currency == MRU {
+		return format.symbol
+	}
+	return string(currency)
+}
+
+// Format renders the amount for a receipt in lang, with its currency symbol.
+// Use String for logs and Formatter to omit the symbol.
+func (m Money) Format(lang Language) string {
+	return Formatter{Language: lang}.Format(m)
+}
@@ -0,0 +1,47 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount string
+		lang   Language
+		want   string
+	}{
+		{"100.5", LanguageFrench, "100,50 UM"},
+		{"1234567.89", LanguageFrench, "1 234 567,89 UM"},
+		{"123456", LanguageFrench, "123 456,00 UM"},
+		{"0", LanguageFrench, "0,00 UM"},
+		{"100.5", LanguageEnglish, "100.50 MRU"},
+		{"1234567.89", LanguageEnglish, "1,234,567.89 MRU"},
+		{"1000", LanguageEnglish, "1,000.00 MRU"},
+		{"100.5", LanguageArabic, "100,50 أوقية"},
+		{"1234567.89", LanguageArabic, "1.234.567,89 أوقية"},
+		{"-1234.5", LanguageFrench, "-1 234,50 UM"},
+		{"1234.5", Language("ES"), "1,234.50 MRU"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.lang)+" "+tt.amount, func(t *testing.T) {
+			m, err := FromString(tt.amount, MRU)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Format(tt.lang))
+		})
+	}
+}
+
+func TestFormatterHideCurrency(t *testing.T) {
+	m := FromFloat64(1234567.89, MRU)
+
+	assert.Equal(t, "1 234 567,89", Formatter{Language: LanguageFrench, HideCurrency: true}.Format(m))
+	assert.Equal(t, "1.234.567,89", Formatter{Language: LanguageArabic, HideCurrency: true}.Format(m))
+}
+
+func TestFormatOtherCurrency(t *testing.T) {
+	assert.Equal(t, "10 050,00 MRO", FromFloat64(10050, MRO).Format(LanguageFrench))
+}
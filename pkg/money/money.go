@@ -3,6 +3,7 @@ package money
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/shopspring/decimal"
 )
@@ -11,8 +12,12 @@ type Currency string
 
 const (
 	MRU Currency = "MRU" // Mauritanian Ouguiya (current, since Jan 1, 2018)
+	MRO Currency = "MRO" // Legacy Ouguiya, replaced by MRU at 10 MRO = 1 MRU
 )
 
+// mroPerMRU is the 2018 redenomination ratio
+var mroPerMRU = decimal.NewFromInt(10)
+
 type Money struct {
 	amount   decimal.Decimal
 	currency Currency
@@ -63,6 +68,44 @@ func (m Money) Add(other Money) (Money, error) {
 	return New(m.amount.Add(other.amount), m.currency), nil
 }
 
+// Subtract returns m minus other, which must have the same currency
+func (m Money) Subtract(other Money) (Money, error) {
+	if m.currency != other.currency {
+		return Money{}, fmt.Errorf("currency mismatch")
+	}
+	return New(m.amount.Sub(other.amount), m.currency), nil
+}
+
+// Equals reports whether both values have the same currency and amount;
+// 10.5 MRU equals 10.50 MRU
+func (m Money) Equals(other Money) bool {
+	return m.currency == other.currency && m.amount.Equal(other.amount)
+}
+
+// Compare returns -1, 0 or 1 as m is less than, equal to or greater than other
+func (m Money) Compare(other Money) (int, error) {
+	if m.currency != other.currency {
+		return 0, fmt.Errorf("currency mismatch")
+	}
+	return m.amount.Cmp(other.amount), nil
+}
+
+func (m Money) GreaterThan(other Money) (bool, error) {
+	cmp, err := m.Compare(other)
+	if err != nil {
+		return false, err
+	}
+	return cmp > 0, nil
+}
+
+func (m Money) LessThan(other Money) (bool, error) {
+	cmp, err := m.Compare(other)
+	if err != nil {
+		return false, err
+	}
+	return cmp < 0, nil
+}
+
 func (m Money) ToProviderAmount(inCents bool) string {
 	if inCents {
 		return fmt.Sprintf("%d", m.Cents())
@@ -71,9 +114,44 @@ func (m Money) ToProviderAmount(inCents bool) string {
 }
 
 func (m Money) GetCurrencyCode() string {
+	if m.currency == MRO {
+		return "478" // legacy MRO currency code (ISO 4217)
+	}
 	return "929" // MRU currency code (ISO 4217)
 }
 
+// ConvertToMRU converts a legacy MRO amount to MRU, rounding to the nearest
+// khoums: 10.05 MRO is 1.01 MRU
+func (m Money) ConvertToMRU() (Money, error) {
+	if m.currency != MRO {
+		return Money{}, fmt.Errorf("cannot convert %s to MRU: source must be MRO", m.currency)
+	}
+	return New(m.amount.Div(mroPerMRU), MRU), nil
+}
+
+// ConvertToMRO converts an MRU amount to legacy MRO
+func (m Money) ConvertToMRO() (Money, error) {
+	if m.currency != MRU {
+		return Money{}, fmt.Errorf("cannot convert %s to MRO: source must be MRU", m.currency)
+	}
+	return New(m.amount.Mul(mroPerMRU), MRO), nil
+}
+
+// ConvertCurrency converts m to target; converting to the same currency
+// returns m unchanged
+func ConvertCurrency(m Money, target Currency) (Money, error) {
+	switch {
+	case m.currency == target:
+		return m, nil
+	case target == MRU:
+		return m.ConvertToMRU()
+	case target == MRO:
+		return m.ConvertToMRO()
+	default:
+		return Money{}, fmt.Errorf("unsupported conversion from %s to %s", m.currency, target)
+	}
+}
+
 func (m Money) Validate() error {
 	if m.amount.IsNegative() {
 		return fmt.Errorf("amount cannot be negative")
@@ -84,6 +162,31 @@ func (m Money) Validate() error {
 	return nil
 }
 
+// ValidateCurrency is Validate that also requires the currency to be one of
+// allowed; with no allowed currencies it is Validate
+func (m Money) ValidateCurrency(allowed ...Currency) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, currency := range allowed {
+		if m.currency == currency {
+			return nil
+		}
+	}
+	return fmt.Errorf("currency %s is not allowed; want %s", m.currency, joinCurrencies(allowed))
+}
+
+func joinCurrencies(currencies []Currency) string {
+	names := make([]string, len(currencies))
+	for i, currency := range currencies {
+		names[i] = string(currency)
+	}
+	return strings.Join(names, ", ")
+}
+
 func (m Money) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]interface{}{
 		"amount":   m.amount.String(),
@@ -92,6 +195,9 @@ func (m Money) MarshalJSON() ([]byte, error) {
 }
 
 func (m *Money) UnmarshalJSON(data []byte) error {
+	if m == nil {
+		return fmt.Errorf("money: UnmarshalJSON on nil pointer")
+	}
 	var obj map[string]interface{}
 	if err := json.Unmarshal(data, &obj); err != nil {
 		return err
@@ -95,6 +95,14 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateCurrency(t *testing.T) {
+	assert.NoError(t, FromFloat64(10, MRU).ValidateCurrency(MRU))
+	assert.NoError(t, FromFloat64(10, "USD").ValidateCurrency(), "no whitelist allows any currency")
+	assert.EqualError(t, FromFloat64(10, MRO).ValidateCurrency(MRU), "currency MRO is not allowed; want MRU")
+	assert.EqualError(t, FromFloat64(10, "USD").ValidateCurrency(MRU, MRO), "currency USD is not allowed; want MRU, MRO")
+	assert.EqualError(t, FromFloat64(-10, MRU).ValidateCurrency(MRU), "amount cannot be negative")
+}
+
 func TestAdd(t *testing.T) {
 	money1 := FromFloat64(10.50, MRU)
 	money2 := FromFloat64(5.25, MRU)
@@ -115,6 +123,107 @@ func TestAddDifferentCurrencies(t *testing.T) {
 	assert.Contains(t, err.Error(), "currency mismatch")
 }
 
+func TestEquals(t *testing.T) {
+	a, err := FromString("10.5", MRU)
+	require.NoError(t, err)
+	b, err := FromString("10.50", MRU)
+	require.NoError(t, err)
+
+	assert.True(t, a.Equals(b))
+	assert.False(t, a.Equals(FromFloat64(10.51, MRU)))
+	assert.False(t, a.Equals(Money{amount: decimal.NewFromFloat(10.5), currency: "USD"}))
+}
+
+func TestCompare(t *testing.T) {
+	small := FromFloat64(10.5, MRU)
+	large := FromFloat64(20, MRU)
+
+	cmp, err := small.Compare(large)
+	require.NoError(t, err)
+	assert.Equal(t, -1, cmp)
+
+	cmp, err = small.Compare(New(decimal.RequireFromString("10.50"), MRU))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+
+	greater, err := large.GreaterThan(small)
+	require.NoError(t, err)
+	assert.True(t, greater)
+
+	less, err := large.LessThan(small)
+	require.NoError(t, err)
+	assert.False(t, less)
+}
+
+func TestCompareDifferentCurrencies(t *testing.T) {
+	mru := FromFloat64(10.50, MRU)
+	usd := Money{amount: decimal.NewFromFloat(10.50), currency: "USD"}
+
+	_, err := mru.Compare(usd)
+	assert.Contains(t, err.Error(), "currency mismatch")
+
+	_, err = mru.GreaterThan(usd)
+	assert.Error(t, err)
+
+	_, err = mru.LessThan(usd)
+	assert.Error(t, err)
+}
+
+func TestConvertToMRU(t *testing.T) {
+	tests := []struct {
+		mro      string
+		expected string
+	}{
+		{"1000", "100"},
+		{"10.05", "1.01"},
+		{"10.04", "1"},
+		{"0.05", "0.01"},
+		{"0.04", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mro, func(t *testing.T) {
+			mro, err := FromString(tt.mro, MRO)
+			require.NoError(t, err)
+
+			mru, err := mro.ConvertToMRU()
+			require.NoError(t, err)
+			assert.Equal(t, MRU, mru.Currency())
+			assert.Equal(t, tt.expected, mru.Amount().String())
+		})
+	}
+}
+
+func TestConvertToMRO(t *testing.T) {
+	mro, err := FromFloat64(1.01, MRU).ConvertToMRO()
+	require.NoError(t, err)
+
+	assert.Equal(t, MRO, mro.Currency())
+	assert.Equal(t, "10.1", mro.Amount().String())
+	assert.Equal(t, "478", mro.GetCurrencyCode())
+}
+
+func TestConvertWrongSourceCurrency(t *testing.T) {
+	_, err := FromFloat64(10, MRU).ConvertToMRU()
+	assert.Error(t, err)
+
+	_, err = FromFloat64(10, MRO).ConvertToMRO()
+	assert.Error(t, err)
+}
+
+func TestConvertCurrency(t *testing.T) {
+	mru, err := ConvertCurrency(FromFloat64(10.05, MRO), MRU)
+	require.NoError(t, err)
+	assert.Equal(t, "1.01 MRU", mru.String())
+
+	same, err := ConvertCurrency(mru, MRU)
+	require.NoError(t, err)
+	assert.True(t, same.Equals(mru))
+
+	_, err = ConvertCurrency(mru, "EUR")
+	assert.Error(t, err)
+}
+
 func TestJSONMarshaling(t *testing.T) {
 	money := FromFloat64(10.50, MRU)
 
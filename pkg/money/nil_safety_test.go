@@ -0,0 +1,44 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestZeroValueMoney calls every exported method on the zero Money, as
+// produced by a PaymentResponse that never had its Amount set
+func TestZeroValueMoney(t *testing.T) {
+	var m Money
+
+	assert.NotPanics(t, func() {
+		assert.True(t, m.Amount().IsZero())
+		assert.Equal(t, Currency(""), m.Currency())
+		assert.Equal(t, "0.00 ", m.String())
+		assert.Equal(t, int64(0), m.Cents())
+		assert.Equal(t, 0.0, m.Float64())
+		assert.True(t, m.IsZero())
+		assert.False(t, m.IsPositive())
+		assert.False(t, m.IsNegative())
+		assert.Equal(t, "0", m.ToProviderAmount(true))
+		assert.Equal(t, "0.00", m.ToProviderAmount(false))
+		assert.Equal(t, "929", m.GetCurrencyCode())
+		assert.Error(t, m.Validate())
+
+		sum, err := m.Add(Money{})
+		assert.NoError(t, err)
+		assert.True(t, sum.IsZero())
+
+		data, err := json.Marshal(m)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"amount":"0","currency":""}`, string(data))
+	})
+}
+
+func TestNilMoneyUnmarshal(t *testing.T) {
+	var m *Money
+	assert.NotPanics(t, func() {
+		assert.Error(t, m.UnmarshalJSON([]byte(`{"amount":"1","currency":"MRU"}`)))
+	})
+}
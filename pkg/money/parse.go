@@ -0,0 +1,128 @@
+package money
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+var plainDigits = regexp.MustCompile(`^\d+$`)
+
+// groupedDigits match integer parts grouped in thousands, keyed by the
+// thousands separator in use besides spaces
+var groupedDigits = map[string]*regexp.Regexp{
+	"":  regexp.MustCompile(`^\d{1,3}(?:\p{Zs}\d{3})+$`),
+	",": regexp.MustCompile(`^\d{1,3}(?:[\p{Zs},]\d{3})+$`),
+	".": regexp.MustCompile(`^\d{1,3}(?:[\p{Zs}.]\d{3})+$`),
+}
+
+// Parse reads a human-formatted amount such as "1,250.00", "1 250,00 MRU" or
+// "1.250,00". Whitespace and thousands separators (comma, dot or space) are
+// removed, an optional trailing currency code must match currency, and either
+// a dot or a comma may mark the decimals. Inputs where a lone comma or dot
+// could be either separator, like "1,250" or "1.250", are rejected rather
+// than guessed, as are amounts with more than two decimal places, like
+// "1.255", instead of being rounded.
+func Parse(s string, currency Currency) (Money, error) {
+	number := strings.TrimSpace(s)
+	if number == "" {
+		return Money{}, fmt.Errorf("invalid amount: empty string")
+	}
+
+	// Optional trailing currency code
+	if i := strings.LastIndexFunc(number, func(r rune) bool { return !unicode.IsLetter(r) }); i < len(number)-1 {
+		code := number[i+1:]
+		if !strings.EqualFold(code, string(currency)) {
+			return Money{}, fmt.Errorf("invalid amount %q: currency %s does not match %s", s, code, currency)
+		}
+		number = strings.TrimSpace(number[:i+1])
+	}
+
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	normalized, err := normalizeNumber(number)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+
+	amount, err := decimal.NewFromString(sign + normalized)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if amount.Exponent() < -2 {
+		return Money{}, fmt.Errorf("invalid amount %q: more than 2 decimal places", s)
+	}
+	return New(amount, currency), nil
+}
+
+// normalizeNumber converts a grouped number to a plain decimal string
+func normalizeNumber(number string) (string, error) {
+	spaced := strings.IndexFunc(number, unicode.IsSpace) >= 0
+
+	commas, dots := strings.Count(number, ","), strings.Count(number, ".")
+	var thousands, decimalSep string
+	switch {
+	case commas > 0 && dots > 0:
+		// Whichever comes last marks the decimals
+		if strings.LastIndex(number, ",") > strings.LastIndex(number, ".") {
+			thousands, decimalSep = ".", ","
+		} else {
+			thousands, decimalSep = ",", "."
+		}
+	case commas > 1:
+		thousands = ","
+	case dots > 1:
+		thousands = "."
+	case commas == 1:
+		if ambiguousSeparator(number, ",", spaced) {
+			return "", fmt.Errorf("ambiguous separator: use a decimal point or two decimal places")
+		}
+		decimalSep = ","
+	case dots == 1:
+		if ambiguousSeparator(number, ".", spaced) {
+			return "", fmt.Errorf("ambiguous separator: use a decimal comma or two decimal places")
+		}
+		decimalSep = "."
+	}
+
+	integer, fraction := number, ""
+	if decimalSep != "" {
+		i := strings.LastIndex(number, decimalSep)
+		integer, fraction = number[:i], number[i+1:]
+		if !plainDigits.MatchString(fraction) {
+			return "", fmt.Errorf("invalid decimal places")
+		}
+	}
+
+	if !plainDigits.MatchString(integer) {
+		if !groupedDigits[thousands].MatchString(integer) {
+			return "", fmt.Errorf("misplaced thousands separator")
+		}
+	}
+
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, integer)
+	if fraction != "" {
+		normalized += "." + fraction
+	}
+	return normalized, nil
+}
+
+// ambiguousSeparator reports whether the only sep in number could group
+// thousands as well as mark decimals. With spaces grouping the thousands,
+// or a leading zero, it can only be decimal; otherwise three trailing digits
+// leave it ambiguous.
+func ambiguousSeparator(number, sep string, spaced bool) bool {
+	i := strings.Index(number, sep)
+	return !spaced && len(number)-i-1 == 3 && number[:i] != "0"
+}
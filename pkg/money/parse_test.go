@@ -0,0 +1,72 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1250", "1250"},
+		{"1250.5", "1250.5"},
+		{"1,250.00", "1250"},
+		{"1 250,00 MRU", "1250"},
+		{"1 250,00 mru", "1250"},
+		{"1 250,50", "1250.5"},
+		{"1 234 567,89", "1234567.89"},
+		{"1.250,75", "1250.75"},
+		{"1,234,567.89", "1234567.89"},
+		{"1.234.567", "1234567"},
+		{"1 250", "1250"},
+		{"12,5", "12.5"},
+		{"  -1,250.00 MRU ", "-1250"},
+		{"1 250.5", "1250.5"},
+		{"0.25", "0.25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := Parse(tt.input, MRU)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m.Amount().String())
+			assert.Equal(t, MRU, m.Currency())
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"ambiguous comma", "1,250"},
+		{"ambiguous comma with currency", "12,500 MRU"},
+		{"ambiguous dot", "1.250"},
+		{"ambiguous dot with currency", "12.500 MRU"},
+		{"three decimal places", "1.255 MRU"},
+		{"three decimal places after comma", "0,125"},
+		{"three decimal places after grouping", "1,250.255"},
+		{"three zero decimal places", "1 250,000"},
+		{"empty", ""},
+		{"currency only", "MRU"},
+		{"other currency", "1 250,00 EUR"},
+		{"misplaced grouping", "12,50,000.00"},
+		{"short group", "1 25"},
+		{"double separator", "1,,250.00"},
+		{"separator after decimals", "1250.00,5"},
+		{"letters", "12a5"},
+		{"missing integer", ",50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input, MRU)
+			assert.Error(t, err)
+		})
+	}
+}
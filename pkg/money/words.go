@@ -0,0 +1,44 @@
+package money
+
+import "fmt"
+
+// Language selects the language used by InWords and Format. The values match
+// rimpay.Language, so a request language converts with money.Language(lang).
+type Language string
+
+const (
+	LanguageEnglish Language = "EN"
+	LanguageFrench  Language = "FR"
+	LanguageArabic  Language = "AR"
+)
+
+// maxInWords is the exclusive upper bound of amounts InWords can spell
+const maxInWords = 1_000_000_000_000
+
+// InWords spells the amount out in words, as required on Mauritanian
+// invoices. The integer part is named in ouguiyas and the two decimal places
+// in khoums, e.g. 100.50 MRU is "cent ouguiyas et cinquante khoums". Amounts
+// must be non-negative, in MRU and below one trillion.
+func (m Money) InWords(lang Language) (string, error) {
+	if m.currency != MRU {
+		return "", fmt.Errorf("amount in words is not supported for currency %q", m.currency)
+	}
+	if m.amount.IsNegative() {
+		return "", fmt.Errorf("amount in words requires a non-negative amount")
+	}
+
+	units := m.amount.IntPart()
+	if units >= maxInWords {
+		return "", fmt.Errorf("amount %s is too large to write in words", m.amount.StringFixed(2))
+	}
+	subunits := m.Cents() - units*100
+
+	switch lang {
+	case LanguageFrench:
+		return frenchAmount(units, subunits), nil
+	case LanguageArabic:
+		return arabicAmount(units, subunits), nil
+	default:
+		return "", fmt.Errorf("unsupported language for amount in words: %q", lang)
+	}
+}
@@ -0,0 +1,157 @@
+package money
+
+import "strings"
+
+// arabicNoun holds the forms a counted noun takes in Arabic
+type arabicNoun struct {
+	singular string // 1, 11 and above, and round hundreds
+	dual     string // exactly 2
+	plural   string // 3 to 10
+	feminine bool   // decides the gender of the numerals counting it
+}
+
+var (
+	arabicOuguiya  = arabicNoun{singular: "أوقية", dual: "أوقيتان", plural: "أوقيات", feminine: true}
+	arabicKhoums   = arabicNoun{singular: "خمس", dual: "خمسان", plural: "أخماس"}
+	arabicThousand = arabicNoun{singular: "ألف", dual: "ألفان", plural: "آلاف"}
+	arabicMillion  = arabicNoun{singular: "مليون", dual: "مليونان", plural: "ملايين"}
+	arabicBillion  = arabicNoun{singular: "مليار", dual: "ملياران", plural: "مليارات"}
+)
+
+// Units for counting masculine nouns; 3 to 10 take the feminine-looking form
+// by the Arabic rule of gender polarity
+var arabicUnitsMasculine = [...]string{
+	"", "واحد", "اثنان", "ثلاثة", "أربعة", "خمسة", "ستة", "سبعة", "ثمانية", "تسعة", "عشرة",
+}
+
+// Units for counting feminine nouns
+var arabicUnitsFeminine = [...]string{
+	"", "واحدة", "اثنتان", "ثلاث", "أربع", "خمس", "ست", "سبع", "ثماني", "تسع", "عشر",
+}
+
+var arabicTens = [...]string{
+	"", "", "عشرون", "ثلاثون", "أربعون", "خمسون", "ستون", "سبعون", "ثمانون", "تسعون",
+}
+
+var arabicHundreds = [...]string{
+	"", "مائة", "مائتان", "ثلاثمائة", "أربعمائة", "خمسمائة", "ستمائة", "سبعمائة", "ثمانمائة", "تسعمائة",
+}
+
+// arabicAmount renders units ouguiyas and subunits khoums in Arabic
+func arabicAmount(units, subunits int64) string {
+	if units == 0 && subunits == 0 {
+		return "صفر " + arabicOuguiya.singular
+	}
+
+	var parts []string
+	if units > 0 {
+		parts = append(parts, arabicCounted(units, arabicOuguiya))
+	}
+	if subunits > 0 {
+		parts = append(parts, arabicCounted(subunits, arabicKhoums))
+	}
+	return strings.Join(parts, " و")
+}
+
+// arabicCounted spells n followed by noun in the form the count requires:
+// "أوقية واحدة", "أوقيتان", "ثلاث أوقيات", "أحد عشر ألف"
+func arabicCounted(n int64, noun arabicNoun) string {
+	switch n {
+	case 1:
+		// The noun comes first and the numeral follows as an adjective
+		if noun.feminine {
+			return noun.singular + " " + arabicUnitsFeminine[1]
+		}
+		return noun.singular + " " + arabicUnitsMasculine[1]
+	case 2:
+		return noun.dual
+	}
+
+	words := arabicNumber(n, noun.feminine)
+	if rest := n % 100; rest >= 3 && rest <= 10 {
+		return words + " " + noun.plural
+	}
+	return words + " " + noun.singular
+}
+
+// arabicNumber spells n (0 < n < 10^12). feminine sets the gender of the
+// final group, which agrees with the counted noun; the thousand, million and
+// billion multipliers are masculine nouns.
+func arabicNumber(n int64, feminine bool) string {
+	var parts []string
+	if g := n / 1_000_000_000; g > 0 {
+		parts = append(parts, arabicScale(g, arabicBillion))
+	}
+	if g := n / 1_000_000 % 1000; g > 0 {
+		parts = append(parts, arabicScale(g, arabicMillion))
+	}
+	if g := n / 1000 % 1000; g > 0 {
+		parts = append(parts, arabicScale(g, arabicThousand))
+	}
+	if g := n % 1000; g > 0 {
+		parts = append(parts, arabicHundredsGroup(g, feminine))
+	}
+	return strings.Join(parts, " و")
+}
+
+// arabicScale spells a count of thousands, millions or billions
+func arabicScale(g int64, noun arabicNoun) string {
+	switch g {
+	case 1:
+		return noun.singular
+	case 2:
+		return noun.dual
+	}
+	return arabicCounted(g, noun)
+}
+
+// arabicHundredsGroup spells 1 <= g < 1000
+func arabicHundredsGroup(g int64, feminine bool) string {
+	var parts []string
+	if h := g / 100; h > 0 {
+		parts = append(parts, arabicHundreds[h])
+	}
+	if rest := g % 100; rest > 0 {
+		parts = append(parts, arabicTensAndUnits(rest, feminine))
+	}
+	return strings.Join(parts, " و")
+}
+
+// arabicTensAndUnits spells 1 <= n < 100. Arabic reads units before tens:
+// 25 is "خمسة وعشرون", literally five and twenty.
+func arabicTensAndUnits(n int64, feminine bool) string {
+	units := arabicUnitsMasculine
+	if feminine {
+		units = arabicUnitsFeminine
+	}
+
+	switch {
+	case n <= 10:
+		return units[n]
+	case n == 11:
+		if feminine {
+			return "إحدى عشرة"
+		}
+		return "أحد عشر"
+	case n == 12:
+		if feminine {
+			return "اثنتا عشرة"
+		}
+		return "اثنا عشر"
+	case n < 20:
+		// The unit keeps polarity while "ten" agrees with the noun
+		if feminine {
+			return units[n-10] + " عشرة"
+		}
+		return units[n-10] + " عشر"
+	}
+
+	tens, unit := n/10, n%10
+	switch {
+	case unit == 0:
+		return arabicTens[tens]
+	case unit == 1 && feminine:
+		return "إحدى و" + arabicTens[tens]
+	}
+	return units[unit] + " و" + arabicTens[tens]
+}
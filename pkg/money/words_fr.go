@@ -0,0 +1,134 @@
+package money
+
+import "strings"
+
+var frenchUnits = [...]string{
+	"zéro", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf",
+	"dix", "onze", "douze", "treize", "quatorze", "quinze", "seize",
+	"dix-sept", "dix-huit", "dix-neuf",
+}
+
+var frenchTens = [...]string{
+	"", "", "vingt", "trente", "quarante", "cinquante", "soixante",
+}
+
+// frenchAmount renders units ouguiyas and subunits khoums in French
+func frenchAmount(units, subunits int64) string {
+	if units == 0 && subunits == 0 {
+		return "zéro ouguiya"
+	}
+
+	var parts []string
+	if units > 0 {
+		currency := "ouguiyas"
+		if units == 1 {
+			currency = "ouguiya"
+		}
+		// Round millions and milliards are nouns and take "de":
+		// "un million d'ouguiyas"
+		separator := " "
+		if units%1_000_000 == 0 {
+			separator = " d'"
+		}
+		parts = append(parts, frenchNumber(units)+separator+currency)
+	}
+	if subunits > 0 {
+		parts = append(parts, frenchNumber(subunits)+" khoums")
+	}
+	return strings.Join(parts, " et ")
+}
+
+// frenchNumber spells n (0 <= n < 10^12) using traditional spelling
+func frenchNumber(n int64) string {
+	if n == 0 {
+		return frenchUnits[0]
+	}
+
+	var parts []string
+	if g := n / 1_000_000_000; g > 0 {
+		parts = append(parts, frenchScale(g, "milliard"))
+	}
+	if g := n / 1_000_000 % 1000; g > 0 {
+		parts = append(parts, frenchScale(g, "million"))
+	}
+	if g := n / 1000 % 1000; g > 0 {
+		if g == 1 {
+			parts = append(parts, "mille")
+		} else {
+			// mille is an adjective: "deux cent mille", "quatre-vingt mille"
+			parts = append(parts, frenchHundreds(g, false)+" mille")
+		}
+	}
+	if g := n % 1000; g > 0 {
+		parts = append(parts, frenchHundreds(g, true))
+	}
+	return strings.Join(parts, " ")
+}
+
+// frenchScale spells a count of millions or milliards; these are nouns, so
+// cents and vingts before them keep their plural s
+func frenchScale(g int64, noun string) string {
+	if g == 1 {
+		return "un " + noun
+	}
+	return frenchHundreds(g, true) + " " + noun + "s"
+}
+
+// frenchHundreds spells 1 <= g < 1000. final reports whether g ends the
+// number (or precedes a noun), which decides "deux cents" vs "deux cent".
+func frenchHundreds(g int64, final bool) string {
+	hundreds, rest := g/100, g%100
+
+	var parts []string
+	switch {
+	case hundreds == 1:
+		parts = append(parts, "cent")
+	case hundreds > 1:
+		word := frenchUnits[hundreds] + " cent"
+		if rest == 0 && final {
+			word += "s"
+		}
+		parts = append(parts, word)
+	}
+	if rest > 0 {
+		parts = append(parts, frenchTensAndUnits(rest, final))
+	}
+	return strings.Join(parts, " ")
+}
+
+// frenchTensAndUnits spells 1 <= n < 100
+func frenchTensAndUnits(n int64, final bool) string {
+	if n < 20 {
+		return frenchUnits[n]
+	}
+
+	tens, unit := n/10, n%10
+	switch tens {
+	case 7:
+		// soixante-dix, soixante et onze, soixante-douze...
+		if unit == 1 {
+			return "soixante et onze"
+		}
+		return "soixante-" + frenchUnits[10+unit]
+	case 8:
+		// quatre-vingts, quatre-vingt-un: no "et" and the s only when final
+		if unit == 0 {
+			if final {
+				return "quatre-vingts"
+			}
+			return "quatre-vingt"
+		}
+		return "quatre-vingt-" + frenchUnits[unit]
+	case 9:
+		return "quatre-vingt-" + frenchUnits[10+unit]
+	}
+
+	switch unit {
+	case 0:
+		return frenchTens[tens]
+	case 1:
+		return frenchTens[tens] + " et un"
+	default:
+		return frenchTens[tens] + "-" + frenchUnits[unit]
+	}
+}
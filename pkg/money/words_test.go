@@ -0,0 +1,106 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInWords_French(t *testing.T) {
+	tests := []struct {
+		amount   string
+		expected string
+	}{
+		{"0", "zéro ouguiya"},
+		{"0.50", "cinquante khoums"},
+		{"1", "un ouguiya"},
+		{"1.01", "un ouguiya et un khoums"},
+		{"17", "dix-sept ouguiyas"},
+		{"21", "vingt et un ouguiyas"},
+		{"71", "soixante et onze ouguiyas"},
+		{"80", "quatre-vingts ouguiyas"},
+		{"81", "quatre-vingt-un ouguiyas"},
+		{"91", "quatre-vingt-onze ouguiyas"},
+		{"100.50", "cent ouguiyas et cinquante khoums"},
+		{"200", "deux cents ouguiyas"},
+		{"201", "deux cent un ouguiyas"},
+		{"1000", "mille ouguiyas"},
+		{"80000", "quatre-vingt mille ouguiyas"},
+		{"200000", "deux cent mille ouguiyas"},
+		{"1000000", "un million d'ouguiyas"},
+		{"2000000", "deux millions d'ouguiyas"},
+		{"2000001", "deux millions un ouguiyas"},
+		{"3000000000", "trois milliards d'ouguiyas"},
+		{"123456789.25", "cent vingt-trois millions quatre cent cinquante-six mille sept cent quatre-vingt-neuf ouguiyas et vingt-cinq khoums"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			m, err := FromString(tt.amount, MRU)
+			require.NoError(t, err)
+
+			words, err := m.InWords(LanguageFrench)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, words)
+		})
+	}
+}
+
+func TestInWords_Arabic(t *testing.T) {
+	tests := []struct {
+		amount   string
+		expected string
+	}{
+		{"0", "صفر أوقية"},
+		{"1", "أوقية واحدة"},
+		{"2", "أوقيتان"},
+		{"3", "ثلاث أوقيات"},
+		{"11", "إحدى عشرة أوقية"},
+		{"15", "خمس عشرة أوقية"},
+		{"21", "إحدى وعشرون أوقية"},
+		{"100", "مائة أوقية"},
+		{"2.50", "أوقيتان وخمسون خمس"},
+		{"1000", "ألف أوقية"},
+		{"3000", "ثلاثة آلاف أوقية"},
+		{"21000", "واحد وعشرون ألف أوقية"},
+		{"2000000", "مليونان أوقية"},
+		{"3000000000", "ثلاثة مليارات أوقية"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			m, err := FromString(tt.amount, MRU)
+			require.NoError(t, err)
+
+			words, err := m.InWords(LanguageArabic)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, words)
+		})
+	}
+}
+
+func TestInWords_Errors(t *testing.T) {
+	t.Run("negative amount", func(t *testing.T) {
+		_, err := FromFloat64(-1, MRU).InWords(LanguageFrench)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported currency", func(t *testing.T) {
+		_, err := FromFloat64(1, "EUR").InWords(LanguageFrench)
+		assert.Error(t, err)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		m, err := FromString("1000000000000", MRU)
+		require.NoError(t, err)
+
+		_, err = m.InWords(LanguageArabic)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported language", func(t *testing.T) {
+		_, err := FromFloat64(1, MRU).InWords(Language("EN"))
+		assert.Error(t, err)
+	})
+}
@@ -2,7 +2,7 @@
 Package phone provides Mauritanian phone number validation and formatting.
 
 This package validates phone numbers according to Mauritanian numbering standards,
-supporting the three main mobile operators with prefixes 2, 3, and 4.
+supporting the three mobile operators and their allocated prefixes.
 
 # Usage
 
@@ -23,25 +23,51 @@ supporting the three main mobile operators with prefixes 2, 3, and 4.
 The following phone number formats are accepted:
   - +22233445566 (international format)
   - 22233445566 (national format)
-  - 33445566 (local format - prefix 2, 3, or 4 assumed)
+  - 33445566 (local format)
+
+NewPhone also drops spaces, dots, dashes and parentheses. For numbers typed
+into forms, ParseLoose strips any whitespace and Unicode dash as well, and
+Normalize returns the canonical "+222XXXXXXXX" string directly:
+
+	number, err := phone.Normalize("+222 22-33-44-55") // +22222334455
 
 # Valid Prefixes
 
 Mauritanian mobile numbers use the following prefixes:
-  - 2: Mauritel (legacy)
+  - 2: Mauritel
   - 3: Chinguitel
   - 4: Mattel
+  - 5: Chinguitel
+
+RegisterPrefix allocates further ranges at runtime. Prefixes may have several
+digits, and the longest matching prefix decides the operator:
+
+	phone.RegisterPrefix("46", phone.OperatorChinguitel) // 46XXXXXX only
+
+# Operators and Portability
+
+Operator derives the operator from the prefix. Numbers ported to another
+operator need a PortabilityResolver; ResolveOperator consults it and falls
+back to the prefix when the number is not ported or the lookup fails.
+NewCachedResolver caches lookups for a TTL.
+
+# Serialization
+
+Phone marshals to JSON as its canonical "+222XXXXXXXX" string and implements
+sql.Scanner and driver.Valuer, so payment requests can be queued or stored
+directly. Decoding and scanning apply the same validation as NewPhone; an
+empty string or a NULL column is an error.
 
 # Validation Rules
 
 Phone numbers must:
   - Start with country code +222 (optional in input)
-  - Have a valid prefix (2, 3, or 4)
+  - Have a prefix allocated to an operator
   - Be exactly 8 digits after the country code
   - Contain only numeric characters
 
 Invalid examples:
-  - +22255667788 (prefix 5 not supported)
+  - +22266778899 (prefix 6 not allocated)
   - +222334455 (too short)
   - +2223344556677 (too long)
   - +22233445abc (contains letters)
@@ -0,0 +1,72 @@
+package phone
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes the number in its canonical "+222XXXXXXXX" form; a nil
+// or zero Phone encodes as null
+func (mp *Phone) MarshalJSON() ([]byte, error) {
+	if mp == nil || mp.number == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(mp.String())
+}
+
+// UnmarshalJSON accepts any format NewPhone does and applies the same
+// validation. null leaves the Phone unchanged; an empty string is an error.
+func (mp *Phone) UnmarshalJSON(data []byte) error {
+	if mp == nil {
+		return fmt.Errorf("phone: UnmarshalJSON on nil pointer")
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var number string
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("phone number must be a JSON string: %w", err)
+	}
+	return mp.set(number)
+}
+
+// Scan implements sql.Scanner for string and []byte columns, applying the
+// same validation as NewPhone. NULL is rejected; scan nullable columns into
+// a sql.NullString instead.
+func (mp *Phone) Scan(src interface{}) error {
+	if mp == nil {
+		return fmt.Errorf("phone: Scan on nil pointer")
+	}
+	switch v := src.(type) {
+	case string:
+		return mp.set(v)
+	case []byte:
+		return mp.set(string(v))
+	case nil:
+		return fmt.Errorf("phone number required")
+	default:
+		return fmt.Errorf("cannot scan %T into phone number", src)
+	}
+}
+
+// Value implements driver.Valuer, storing the canonical "+222XXXXXXXX" form;
+// a nil or zero Phone is stored as NULL
+func (mp *Phone) Value() (driver.Value, error) {
+	if mp == nil || mp.number == "" {
+		return nil, nil
+	}
+	return mp.String(), nil
+}
+
+// set replaces mp with the validated number
+func (mp *Phone) set(number string) error {
+	p, err := NewPhone(number)
+	if err != nil {
+		return err
+	}
+	*mp = *p
+	return nil
+}
@@ -0,0 +1,87 @@
+package phone
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queuedPayment struct {
+	Reference   string `json:"reference"`
+	PhoneNumber *Phone `json:"phone_number"`
+}
+
+func TestPhoneJSONRoundTrip(t *testing.T) {
+	p, err := NewPhone("33 44 55 66")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(queuedPayment{Reference: "REF-1", PhoneNumber: p})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"reference":"REF-1","phone_number":"+22233445566"}`, string(data))
+
+	var decoded queuedPayment
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NotNil(t, decoded.PhoneNumber)
+	assert.Equal(t, p.Number(), decoded.PhoneNumber.Number())
+	assert.Equal(t, OperatorChinguitel, decoded.PhoneNumber.Operator())
+}
+
+func TestPhoneJSONNull(t *testing.T) {
+	data, err := json.Marshal(queuedPayment{Reference: "REF-1"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"reference":"REF-1","phone_number":null}`, string(data))
+
+	var decoded queuedPayment
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Nil(t, decoded.PhoneNumber)
+}
+
+func TestPhoneUnmarshalJSONValidates(t *testing.T) {
+	_, newErr := NewPhone("12345678")
+	require.Error(t, newErr)
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"empty string", `{"phone_number":""}`, "phone number required"},
+		{"invalid number", `{"phone_number":"12345678"}`, newErr.Error()},
+		{"not a string", `{"phone_number":22334455}`, "phone number must be a JSON string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded queuedPayment
+			err := json.Unmarshal([]byte(tt.json), &decoded)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestPhoneScanAndValue(t *testing.T) {
+	var p Phone
+	require.NoError(t, p.Scan("+22244556677"))
+	assert.Equal(t, "44556677", p.Number())
+
+	require.NoError(t, p.Scan([]byte("22334455")))
+	assert.Equal(t, "22334455", p.Number())
+
+	value, err := p.Value()
+	require.NoError(t, err)
+	assert.Equal(t, driver.Value("+22222334455"), value)
+
+	assert.EqualError(t, p.Scan(nil), "phone number required")
+	assert.EqualError(t, p.Scan(42), "cannot scan int into phone number")
+	assert.Error(t, p.Scan("12345678"))
+	assert.Equal(t, "22334455", p.Number(), "failed scans leave the number unchanged")
+
+	var nilPhone *Phone
+	value, err = nilPhone.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
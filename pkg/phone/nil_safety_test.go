@@ -0,0 +1,36 @@
+package phone
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNilPhone calls every exported method on a nil *Phone, as happens when
+// an optional phone number is logged
+func TestNilPhone(t *testing.T) {
+	var p *Phone
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, "", p.Number())
+		assert.Equal(t, "", p.String())
+		assert.Equal(t, "", p.LocalFormat())
+		assert.Equal(t, "", p.InternationalFormat())
+		assert.Equal(t, "", p.ForProvider(true))
+		assert.Equal(t, "", p.ForProvider(false))
+		assert.Equal(t, "phone=", fmt.Sprintf("phone=%s", p))
+	})
+}
+
+func TestZeroValuePhone(t *testing.T) {
+	var p Phone
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, "", p.Number())
+		assert.Equal(t, "", p.String())
+		assert.Equal(t, "", p.LocalFormat())
+		assert.Equal(t, "", p.InternationalFormat())
+		assert.Equal(t, "", p.ForProvider(true))
+	})
+}
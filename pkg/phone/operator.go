@@ -0,0 +1,106 @@
+package phone
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Operator is a Mauritanian mobile network operator
+type Operator string
+
+const (
+	OperatorUnknown    Operator = ""
+	OperatorMauritel   Operator = "mauritel"
+	OperatorChinguitel Operator = "chinguitel"
+	OperatorMattel     Operator = "mattel"
+)
+
+// AllOperators returns the known operators, in a stable order
+func AllOperators() []Operator {
+	return []Operator{OperatorMauritel, OperatorChinguitel, OperatorMattel}
+}
+
+// String returns the operator's display name, such as "Mauritel"
+func (o Operator) String() string {
+	switch o {
+	case OperatorUnknown:
+		return "Unknown"
+	case OperatorMauritel:
+		return "Mauritel"
+	case OperatorChinguitel:
+		return "Chinguitel"
+	case OperatorMattel:
+		return "Mattel"
+	}
+	return string(o)
+}
+
+// Operator returns the operator the number was allocated to, derived from its
+// prefix. With number portability a customer may have moved to another
+// operator since; use ResolveOperator when a PortabilityResolver is available.
+func (mp *Phone) Operator() Operator {
+	if mp == nil {
+		return OperatorUnknown
+	}
+	return determineOperator(mp.number)
+}
+
+// maxPrefixLength is the longest prefix RegisterPrefix accepts, leaving at
+// least one digit for the subscriber
+const maxPrefixLength = localNumberLength - 1
+
+// prefixes maps number prefixes to the operators they are allocated to;
+// the longest matching prefix wins
+var prefixes = struct {
+	sync.RWMutex
+	operators map[string]Operator
+}{operators: map[string]Operator{
+	"2": OperatorMauritel,
+	"3": OperatorChinguitel,
+	"4": OperatorMattel,
+	"5": OperatorChinguitel,
+}}
+
+// RegisterPrefix allocates the local numbers starting with prefix to op, so
+// that they validate and Operator returns op. It replaces the operator of an
+// equal prefix, and a longer prefix takes precedence over a shorter one:
+// registering "46" moves 46XXXXXX numbers without affecting the rest of "4".
+// RegisterPrefix panics when prefix is not 1 to 7 digits or op is
+// OperatorUnknown.
+func RegisterPrefix(prefix string, op Operator) {
+	if len(prefix) == 0 || len(prefix) > maxPrefixLength || !isDigits(prefix) {
+		panic(fmt.Sprintf("phone: invalid prefix %q", prefix))
+	}
+	if op == OperatorUnknown {
+		panic(fmt.Sprintf("phone: prefix %q registered without an operator", prefix))
+	}
+	prefixes.Lock()
+	defer prefixes.Unlock()
+	prefixes.operators[prefix] = op
+}
+
+// determineOperator maps a local number to the operator of its longest
+// registered prefix
+func determineOperator(number string) Operator {
+	prefixes.RLock()
+	defer prefixes.RUnlock()
+	n := len(number)
+	if n > maxPrefixLength {
+		n = maxPrefixLength
+	}
+	for ; n > 0; n-- {
+		if op, ok := prefixes.operators[number[:n]]; ok {
+			return op
+		}
+	}
+	return OperatorUnknown
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
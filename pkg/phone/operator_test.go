@@ -0,0 +1,89 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerPrefixForTest registers prefix for the rest of the test
+func registerPrefixForTest(t *testing.T, prefix string, op Operator) {
+	t.Helper()
+	prefixes.RLock()
+	previous, existed := prefixes.operators[prefix]
+	prefixes.RUnlock()
+	RegisterPrefix(prefix, op)
+	t.Cleanup(func() {
+		prefixes.Lock()
+		defer prefixes.Unlock()
+		if existed {
+			prefixes.operators[prefix] = previous
+		} else {
+			delete(prefixes.operators, prefix)
+		}
+	})
+}
+
+func TestAllocatedRanges(t *testing.T) {
+	tests := []struct {
+		number   string
+		operator Operator
+	}{
+		{"20000000", OperatorMauritel},
+		{"29999999", OperatorMauritel},
+		{"30000000", OperatorChinguitel},
+		{"39999999", OperatorChinguitel},
+		{"40000000", OperatorMattel},
+		{"49999999", OperatorMattel},
+		{"50000000", OperatorChinguitel},
+		{"59999999", OperatorChinguitel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			for _, format := range []string{tt.number, "+222" + tt.number, "00222" + tt.number} {
+				p, err := NewPhone(format)
+				require.NoError(t, err, format)
+				assert.Equal(t, tt.operator, p.Operator(), format)
+			}
+		})
+	}
+
+	for _, number := range []string{"00000000", "12345678", "60000000", "70000000", "80000000", "99999999"} {
+		_, err := NewPhone(number)
+		assert.Error(t, err, "%s has no operator", number)
+		assert.False(t, IsValidMauritanianNumber(number))
+	}
+}
+
+func TestRegisterPrefix(t *testing.T) {
+	assert.False(t, IsValidMauritanianNumber("71234567"))
+	registerPrefixForTest(t, "7", OperatorMattel)
+	assert.Equal(t, OperatorMattel, mustPhone(t, "71234567").Operator())
+
+	registerPrefixForTest(t, "46", OperatorChinguitel)
+	assert.Equal(t, OperatorChinguitel, mustPhone(t, "46123456").Operator(), "the longer prefix wins")
+	assert.Equal(t, OperatorMattel, mustPhone(t, "45123456").Operator())
+	assert.Equal(t, OperatorMattel, mustPhone(t, "40612345").Operator())
+
+	registerPrefixForTest(t, "4612", OperatorMauritel)
+	assert.Equal(t, OperatorMauritel, mustPhone(t, "46123456").Operator())
+	assert.Equal(t, OperatorChinguitel, mustPhone(t, "46999999").Operator())
+}
+
+func TestRegisterPrefixRejectsInvalidInput(t *testing.T) {
+	assert.Panics(t, func() { RegisterPrefix("", OperatorMattel) })
+	assert.Panics(t, func() { RegisterPrefix("4a", OperatorMattel) })
+	assert.Panics(t, func() { RegisterPrefix("12345678", OperatorMattel) })
+	assert.Panics(t, func() { RegisterPrefix("7", OperatorUnknown) })
+}
+
+func TestOperatorNames(t *testing.T) {
+	var names []string
+	for _, op := range AllOperators() {
+		names = append(names, op.String())
+	}
+	assert.Equal(t, []string{"Mauritel", "Chinguitel", "Mattel"}, names)
+	assert.Equal(t, "Unknown", OperatorUnknown.String())
+	assert.Equal(t, "mattel", string(OperatorMattel), "the value is unchanged")
+}
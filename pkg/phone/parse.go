@@ -0,0 +1,51 @@
+package phone
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseLoose parses a number typed by a person, such as "+222 22-33-44-55"
+// or "(222) 22.33.44.55". It drops whitespace, dots, dashes and parentheses
+// wherever they appear and then validates what remains like NewPhone, so
+// letters, other symbols and foreign country codes are still rejected.
+func ParseLoose(input string) (*Phone, error) {
+	local, err := parseLooseLocal(input)
+	if err != nil {
+		return nil, err
+	}
+	return &Phone{number: local}, nil
+}
+
+// Normalize returns the canonical +222XXXXXXXX form of a number ParseLoose
+// accepts, without constructing a Phone
+func Normalize(input string) (string, error) {
+	local, err := parseLooseLocal(input)
+	if err != nil {
+		return "", err
+	}
+	return "+222" + local, nil
+}
+
+// parseLooseLocal returns the local number of a loosely typed number
+func parseLooseLocal(input string) (string, error) {
+	stripped := stripPunctuation(input)
+	if stripped == "" {
+		return "", fmt.Errorf("phone number required")
+	}
+	if !IsValidMauritanianNumber(stripped) {
+		return "", fmt.Errorf("invalid Mauritanian phone number: %s", input)
+	}
+	return extractLocalNumber(stripped), nil
+}
+
+// stripPunctuation removes the separators people type inside numbers
+func stripPunctuation(input string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '.' || r == '(' || r == ')' || unicode.Is(unicode.Pd, r) {
+			return -1
+		}
+		return r
+	}, input)
+}
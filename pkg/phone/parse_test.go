@@ -0,0 +1,115 @@
+package phone
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLoose(t *testing.T) {
+	valid := []string{
+		"+222 22-33-44-55",
+		"00222 22334455",
+		"22.33.44.55",
+		"(+222) 22 33 44 55",
+		"+222\t22 33–44—55",
+		"222-2233-4455",
+	}
+	for _, input := range valid {
+		p, err := ParseLoose(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, "22334455", p.Number(), input)
+
+		normalized, err := Normalize(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, "+22222334455", normalized, input)
+	}
+
+	invalid := []string{
+		"",
+		" - . ",
+		"22 33 44 5a",
+		"+221 22 33 44 55",
+		"+33 6 12 34 56 78",
+		"22/33/44/55",
+		"+222 12 34 56 78",
+		"22 33 44 5",
+		"22 33 44 55 66",
+	}
+	for _, input := range invalid {
+		_, err := ParseLoose(input)
+		assert.Error(t, err, "%q", input)
+		_, err = Normalize(input)
+		assert.Error(t, err, "%q", input)
+	}
+}
+
+// punctuate inserts random separators between the characters of number
+func punctuate(rng *rand.Rand, number string) string {
+	separators := []string{" ", "  ", ".", "-", "(", ")", "\t", "–"}
+	var b strings.Builder
+	for _, c := range number {
+		for rng.Intn(3) == 0 {
+			b.WriteString(separators[rng.Intn(len(separators))])
+		}
+		b.WriteRune(c)
+	}
+	if rng.Intn(2) == 0 {
+		b.WriteString(separators[rng.Intn(len(separators))])
+	}
+	return b.String()
+}
+
+func TestParseLooseRandomPunctuation(t *testing.T) {
+	rng := rand.New(rand.NewSource(2301))
+	prefixes := []string{"", "+222", "00222", "222"}
+	for i := 0; i < 5000; i++ {
+		local := string(rune('2'+rng.Intn(4))) + randomDigits(rng, 7)
+		input := punctuate(rng, prefixes[rng.Intn(len(prefixes))]+local)
+
+		normalized, err := Normalize(input)
+		require.NoError(t, err, "%q", input)
+		assert.Equal(t, "+222"+local, normalized, "%q", input)
+
+		letter := punctuate(rng, local[:4]+"x"+local[5:])
+		_, err = Normalize(letter)
+		assert.Error(t, err, "%q", letter)
+
+		foreign := punctuate(rng, "+221"+local)
+		_, err = Normalize(foreign)
+		assert.Error(t, err, "%q", foreign)
+	}
+}
+
+func randomDigits(rng *rand.Rand, n int) string {
+	digits := make([]byte, n)
+	for i := range digits {
+		digits[i] = byte('0' + rng.Intn(10))
+	}
+	return string(digits)
+}
+
+var canonicalNumber = regexp.MustCompile(`^\+222\d{8}$`)
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range []string{"+222 22-33-44-55", "00222 22334455", "22.33.44.55", "(222) 3344 5566", "abc"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		normalized, err := Normalize(input)
+		if err != nil {
+			return
+		}
+		if !canonicalNumber.MatchString(normalized) {
+			t.Fatalf("Normalize(%q) = %q, not canonical", input, normalized)
+		}
+		p, err := NewPhone(normalized)
+		if err != nil || p.String() != normalized {
+			t.Fatalf("Normalize(%q) = %q, which NewPhone does not round-trip: %v", input, normalized, err)
+		}
+	})
+}
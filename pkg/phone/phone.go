@@ -10,7 +10,10 @@ type Phone struct {
 	number string
 }
 
-var mauritanianPattern = regexp.MustCompile(`^(\+222|00222|222)?([234]\d{7})$`)
+// localNumberLength is the number of digits after the country code
+const localNumberLength = 8
+
+var mauritanianPattern = regexp.MustCompile(`^(\+222|00222|222)?(\d{8})$`)
 
 func NewPhone(number string) (*Phone, error) {
 	if number == "" {
@@ -49,19 +52,56 @@ func extractLocalNumber(number string) string {
 	return ""
 }
 
+// IsValidMauritanianNumber reports whether number is a Mauritanian number
+// whose prefix is allocated to an operator
 func IsValidMauritanianNumber(number string) bool {
-	cleaned := cleanPhoneNumber(number)
-	return mauritanianPattern.MatchString(cleaned)
+	matches := mauritanianPattern.FindStringSubmatch(cleanPhoneNumber(number))
+	return len(matches) == 3 && determineOperator(matches[2]) != OperatorUnknown
+}
+
+// Phone methods are nil-safe: a nil or zero Phone formats as an empty string
+// so that logging an optional phone number never panics.
+
+func (mp *Phone) Number() string {
+	if mp == nil {
+		return ""
+	}
+	return mp.number
+}
+
+func (mp *Phone) String() string {
+	if mp == nil || mp.number == "" {
+		return ""
+	}
+	return fmt.Sprintf("+222%s", mp.number)
+}
+
+func (mp *Phone) LocalFormat() string { return mp.Number() }
+
+// Key returns the canonical +222 form, for use as a map key; it is empty
+// for a nil or zero Phone
+func (mp *Phone) Key() string { return mp.String() }
+
+// Equal reports whether both phones hold the same number. A nil or zero
+// Phone equals nothing, not even another nil Phone.
+func (mp *Phone) Equal(other *Phone) bool {
+	if mp.Number() == "" {
+		return false
+	}
+	return mp.Number() == other.Number()
 }
 
-func (mp *Phone) Number() string      { return mp.number }
-func (mp *Phone) String() string      { return fmt.Sprintf("+222%s", mp.number) }
-func (mp *Phone) LocalFormat() string { return mp.number }
 func (mp *Phone) InternationalFormat() string {
+	if mp == nil || len(mp.number) != 8 {
+		return mp.String()
+	}
 	return fmt.Sprintf("+222 %s %s %s", mp.number[:2], mp.number[2:5], mp.number[5:])
 }
 
 func (mp *Phone) ForProvider(includeCountryCode bool) string {
+	if mp == nil || mp.number == "" {
+		return ""
+	}
 	if includeCountryCode {
 		return fmt.Sprintf("222%s", mp.number)
 	}
@@ -93,3 +93,31 @@ func TestIsValidMauritanianNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestPhoneEqualAndKey(t *testing.T) {
+	international, err := NewPhone("+22222334455")
+	require.NoError(t, err)
+	local, err := NewPhone("22334455")
+	require.NoError(t, err)
+	other, err := NewPhone("33445566")
+	require.NoError(t, err)
+
+	assert.True(t, international.Equal(local))
+	assert.True(t, local.Equal(international))
+	assert.False(t, international.Equal(other))
+	assert.Equal(t, "+22222334455", international.Key())
+
+	beneficiaries := map[string]*Phone{}
+	for _, p := range []*Phone{international, local, other} {
+		beneficiaries[p.Key()] = p
+	}
+	assert.Len(t, beneficiaries, 2)
+
+	var nilPhone *Phone
+	assert.False(t, international.Equal(nil))
+	assert.False(t, nilPhone.Equal(international))
+	assert.False(t, nilPhone.Equal(nil))
+	assert.False(t, (&Phone{}).Equal(&Phone{}))
+	assert.Empty(t, nilPhone.Key())
+	assert.Empty(t, (&Phone{}).String())
+}
@@ -0,0 +1,84 @@
+package phone
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PortabilityResolver looks up the operator currently serving a number, for
+// numbers ported away from the operator their prefix was allocated to
+type PortabilityResolver interface {
+	// Lookup returns the serving operator; ok is false when the number has
+	// not been ported and the prefix-derived operator applies
+	Lookup(ctx context.Context, p *Phone) (op Operator, ok bool, err error)
+}
+
+// ResolveOperator returns the operator serving p, consulting resolver for
+// ported numbers. A nil resolver uses prefix detection. If the lookup fails
+// the prefix-derived operator is returned together with the error, so callers
+// can log it and carry on.
+func ResolveOperator(ctx context.Context, resolver PortabilityResolver, p *Phone) (Operator, error) {
+	if resolver == nil || p == nil {
+		return p.Operator(), nil
+	}
+
+	op, ok, err := resolver.Lookup(ctx, p)
+	if err != nil {
+		return p.Operator(), err
+	}
+	if !ok {
+		return p.Operator(), nil
+	}
+	return op, nil
+}
+
+type cachedLookup struct {
+	operator Operator
+	ported   bool
+	expires  time.Time
+}
+
+// CachedResolver caches the lookups of another PortabilityResolver for a
+// fixed TTL. Failed lookups are not cached.
+type CachedResolver struct {
+	resolver PortabilityResolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedLookup
+}
+
+// NewCachedResolver wraps resolver with a cache holding each lookup for ttl
+func NewCachedResolver(resolver PortabilityResolver, ttl time.Duration) *CachedResolver {
+	return &CachedResolver{
+		resolver: resolver,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cachedLookup),
+	}
+}
+
+// Lookup returns the cached result for p or asks the wrapped resolver
+func (c *CachedResolver) Lookup(ctx context.Context, p *Phone) (Operator, bool, error) {
+	key := p.Number()
+	now := c.now()
+
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	c.mu.Unlock()
+	if found && now.Before(entry.expires) {
+		return entry.operator, entry.ported, nil
+	}
+
+	op, ok, err := c.resolver.Lookup(ctx, p)
+	if err != nil {
+		return OperatorUnknown, false, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cachedLookup{operator: op, ported: ok, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return op, ok, nil
+}
@@ -0,0 +1,105 @@
+package phone
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver reports the numbers in ported as moved to another operator
+type fakeResolver struct {
+	ported map[string]Operator
+	err    error
+	calls  int
+}
+
+func (r *fakeResolver) Lookup(_ context.Context, p *Phone) (Operator, bool, error) {
+	r.calls++
+	if r.err != nil {
+		return OperatorUnknown, false, r.err
+	}
+	op, ok := r.ported[p.Number()]
+	return op, ok, nil
+}
+
+func mustPhone(t *testing.T, number string) *Phone {
+	t.Helper()
+	p, err := NewPhone(number)
+	require.NoError(t, err)
+	return p
+}
+
+func TestOperatorFromPrefix(t *testing.T) {
+	assert.Equal(t, OperatorMauritel, mustPhone(t, "22334455").Operator())
+	assert.Equal(t, OperatorChinguitel, mustPhone(t, "33445566").Operator())
+	assert.Equal(t, OperatorMattel, mustPhone(t, "44556677").Operator())
+
+	var p *Phone
+	assert.Equal(t, OperatorUnknown, p.Operator())
+}
+
+func TestResolveOperator(t *testing.T) {
+	ctx := context.Background()
+	resolver := &fakeResolver{ported: map[string]Operator{"22334455": OperatorMattel}}
+
+	op, err := ResolveOperator(ctx, resolver, mustPhone(t, "22334455"))
+	require.NoError(t, err)
+	assert.Equal(t, OperatorMattel, op, "ported number uses the resolver")
+
+	op, err = ResolveOperator(ctx, resolver, mustPhone(t, "33445566"))
+	require.NoError(t, err)
+	assert.Equal(t, OperatorChinguitel, op, "unported number uses the prefix")
+
+	op, err = ResolveOperator(ctx, nil, mustPhone(t, "22334455"))
+	require.NoError(t, err)
+	assert.Equal(t, OperatorMauritel, op)
+}
+
+func TestResolveOperatorFallsBackOnError(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("registry unavailable")}
+
+	op, err := ResolveOperator(context.Background(), resolver, mustPhone(t, "44556677"))
+	assert.Error(t, err)
+	assert.Equal(t, OperatorMattel, op)
+}
+
+func TestCachedResolver(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	inner := &fakeResolver{ported: map[string]Operator{"22334455": OperatorChinguitel}}
+	cached := NewCachedResolver(inner, time.Minute)
+	cached.now = func() time.Time { return now }
+	p := mustPhone(t, "22334455")
+
+	for i := 0; i < 3; i++ {
+		op, ok, err := cached.Lookup(ctx, p)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, OperatorChinguitel, op)
+	}
+	assert.Equal(t, 1, inner.calls)
+
+	now = now.Add(time.Minute)
+	_, _, err := cached.Lookup(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls, "expired entries are looked up again")
+}
+
+func TestCachedResolverDoesNotCacheErrors(t *testing.T) {
+	inner := &fakeResolver{err: errors.New("timeout")}
+	cached := NewCachedResolver(inner, time.Minute)
+	p := mustPhone(t, "22334455")
+
+	_, _, err := cached.Lookup(context.Background(), p)
+	assert.Error(t, err)
+
+	inner.err = nil
+	_, ok, err := cached.Lookup(context.Background(), p)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, inner.calls)
+}
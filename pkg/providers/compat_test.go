@@ -0,0 +1,190 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/bpay"
+	"github.com/CatoSystems/rim-pay/internal/providers/click"
+	"github.com/CatoSystems/rim-pay/internal/providers/masrvi"
+	"github.com/CatoSystems/rim-pay/internal/providers/mock"
+	"github.com/CatoSystems/rim-pay/internal/providers/sedad"
+	"github.com/CatoSystems/rim-pay/internal/types"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// Compatibility checks pin the public API: they fail to compile when a
+// provider stops implementing its interfaces, when a rimpay type stops
+// being the internal/types definition, or when a signature the examples
+// use changes.
+var (
+	_ rimpay.PaymentProvider = (*bpay.Provider)(nil)
+	_ rimpay.BPayProvider    = (*bpay.Provider)(nil)
+	_ rimpay.Disburser       = (*bpay.Provider)(nil)
+	_ rimpay.PaymentProvider = (*masrvi.Provider)(nil)
+	_ rimpay.MasrviProvider  = (*masrvi.Provider)(nil)
+	_ rimpay.PaymentProvider = (*click.Provider)(nil)
+	_ rimpay.ClickProvider   = (*click.Provider)(nil)
+	_ rimpay.PaymentProvider = (*sedad.Provider)(nil)
+	_ rimpay.SedadProvider   = (*sedad.Provider)(nil)
+	_ rimpay.PaymentProvider = (*mock.Provider)(nil)
+
+	_ *types.PaymentRequest       = (*rimpay.PaymentRequest)(nil)
+	_ *types.PaymentResponse      = (*rimpay.PaymentResponse)(nil)
+	_ *types.RefundRequest        = (*rimpay.RefundRequest)(nil)
+	_ *types.RefundResponse       = (*rimpay.RefundResponse)(nil)
+	_ *types.PaymentError         = (*rimpay.PaymentError)(nil)
+	_ *types.DisbursementRequest  = (*rimpay.DisbursementRequest)(nil)
+	_ *types.DisbursementResponse = (*rimpay.DisbursementResponse)(nil)
+	_ types.PaymentStatus         = rimpay.PaymentStatusSuccess
+
+	_ func() *rimpay.Config                                                                                  = rimpay.DefaultConfig
+	_ func(*rimpay.Config) (*rimpay.Client, error)                                                           = rimpay.NewClient
+	_ func(*rimpay.Client, rimpay.ProviderConfig) error                                                      = (*rimpay.Client).AddBPayProvider
+	_ func(*rimpay.Client, rimpay.ProviderConfig) error                                                      = (*rimpay.Client).AddMasrviProvider
+	_ func(*rimpay.Client, rimpay.ProviderConfig) error                                                      = (*rimpay.Client).AddClickProvider
+	_ func(*rimpay.Client, rimpay.ProviderConfig) error                                                      = (*rimpay.Client).AddSedadProvider
+	_ func(*rimpay.Client, string, rimpay.PaymentProvider) error                                             = (*rimpay.Client).AddProvider
+	_ func(*rimpay.Client, context.Context, *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error)         = (*rimpay.Client).ProcessPayment
+	_ func(*rimpay.Client, context.Context, string, *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error) = (*rimpay.Client).ProcessPaymentWithProvider
+	_ func(*rimpay.Client, context.Context, *rimpay.PaymentRequest) (*rimpay.PaymentResponse, error)         = (*rimpay.Client).ProcessPaymentWithFailover
+	_ func(*rimpay.Client, context.Context, *rimpay.BPayPaymentRequest) (*rimpay.PaymentResponse, error)     = (*rimpay.Client).ProcessBPayPayment
+	_ func(*rimpay.Client, context.Context, *rimpay.MasrviPaymentRequest) (*rimpay.PaymentResponse, error)   = (*rimpay.Client).ProcessMasrviPayment
+	_ func(*rimpay.Client, context.Context, *rimpay.ClickPaymentRequest) (*rimpay.PaymentResponse, error)    = (*rimpay.Client).ProcessClickPayment
+	_ func(*rimpay.Client, context.Context, *rimpay.SedadPaymentRequest) (*rimpay.PaymentResponse, error)    = (*rimpay.Client).ProcessSedadPayment
+	_ func(*rimpay.Client, context.Context, string) (*rimpay.TransactionStatus, error)                       = (*rimpay.Client).GetPaymentStatus
+	_ func(*rimpay.Client, context.Context, *rimpay.RefundRequest) (*rimpay.RefundResponse, error)           = (*rimpay.Client).Refund
+	_ func(*rimpay.Client, *rimpay.ClickNotificationData) (*rimpay.TransactionStatus, error)                 = (*rimpay.Client).HandleClickNotification
+	_ func(*rimpay.Client) []string                                                                          = (*rimpay.Client).ListProviders
+	_ func(*rimpay.Client, context.Context) error                                                            = (*rimpay.Client).Close
+	_ func(*rimpay.Client, rimpay.MetricsCollector) *rimpay.Client                                           = (*rimpay.Client).WithMetrics
+)
+
+// TestCompatJSON pins the JSON form of the public types in
+// testdata/compat/types.golden; regenerate it after an intentional change
+// with:
+//
+//	go test ./pkg/providers -run TestCompatJSON -update
+func TestCompatJSON(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := at.Add(time.Hour)
+	amount := money.NewMRU(15000)
+
+	values := []struct {
+		name  string
+		value interface{}
+		empty interface{}
+	}{
+		{"PaymentRequest", &rimpay.PaymentRequest{
+			Amount:      amount,
+			PhoneNumber: contractPhone(t),
+			Reference:   "REF-1",
+			Description: "Facture mars",
+			Language:    rimpay.LanguageArabic,
+			Mode:        rimpay.BPayModeUSSDPush,
+			ExpiresAt:   &expiresAt,
+			CallbackURL: "https://shop.test/callback",
+			Metadata:    map[string]interface{}{"order": "42"},
+		}, &rimpay.PaymentRequest{}},
+		{"PaymentResponse", &rimpay.PaymentResponse{
+			TransactionID: "TX-1",
+			Status:        rimpay.PaymentStatusPending,
+			Amount:        amount,
+			Reference:     "REF-1",
+			Provider:      rimpay.ProviderBPay,
+			CreatedAt:     at,
+			UpdatedAt:     at,
+			ExpiresAt:     &expiresAt,
+			Metadata:      map[string]interface{}{rimpay.MetadataKeySessionID: "S-1"},
+			Events:        []rimpay.StatusEvent{{Status: rimpay.PaymentStatusPending, Message: "Created", At: at, Source: rimpay.EventSourceInitial}},
+		}, &rimpay.PaymentResponse{}},
+		{"TransactionStatus", &rimpay.TransactionStatus{
+			TransactionID:     "TX-1",
+			Status:            rimpay.PaymentStatusSuccess,
+			Amount:            amount,
+			Reference:         "REF-1",
+			ProviderReference: "BP-9",
+			LastUpdated:       at,
+			EventLog:          []rimpay.StatusEvent{{Status: rimpay.PaymentStatusSuccess, At: at, Source: rimpay.EventSourcePoll}},
+		}, &rimpay.TransactionStatus{}},
+		{"RefundRequest", &rimpay.RefundRequest{
+			TransactionID:  "TX-1",
+			Amount:         money.NewMRU(5000),
+			OriginalAmount: amount,
+			Reason:         "returned",
+			Reference:      "RF-1",
+		}, &rimpay.RefundRequest{}},
+		{"RefundResponse", &rimpay.RefundResponse{
+			RefundID:      "RF-TX-1",
+			TransactionID: "TX-1",
+			Status:        rimpay.PaymentStatusSuccess,
+			Amount:        money.NewMRU(5000),
+			Reference:     "RF-1",
+			Provider:      rimpay.ProviderMasrvi,
+			Partial:       true,
+			CreatedAt:     at,
+		}, &rimpay.RefundResponse{}},
+		{"DisbursementResponse", &rimpay.DisbursementResponse{
+			DisbursementID: "TR-1",
+			Reference:      "PAYOUT-1",
+			Status:         rimpay.DisbursementStatusCompleted,
+			Amount:         amount,
+			Provider:       rimpay.ProviderBPay,
+			UpdatedAt:      at,
+		}, &rimpay.DisbursementResponse{}},
+		{"PaymentError", rimpay.NewPaymentError(rimpay.ErrorCodeInsufficientFunds, "balance too low", rimpay.ProviderBPay, false),
+			&rimpay.PaymentError{}},
+	}
+
+	var b strings.Builder
+	for i, v := range values {
+		data, err := json.Marshal(v.value)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", v.name, err)
+		}
+		if err := json.Unmarshal(data, v.empty); err != nil {
+			t.Fatalf("unmarshal %s: %v", v.name, err)
+		}
+		again, err := json.Marshal(v.empty)
+		if err != nil {
+			t.Fatalf("marshal decoded %s: %v", v.name, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("%s does not round-trip:\n%s\n%s", v.name, data, again)
+		}
+
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(v.name + "\n" + string(data) + "\n")
+	}
+	got := b.String()
+
+	path := filepath.Join("testdata", "compat", "types.golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file %s: %v\nrun `go test ./pkg/providers -run TestCompatJSON -update` to create it", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("JSON form of the public types changed.\n\n--- want\n%s\n\n--- got\n%s\n\n"+
+			"If the change is intentional, regenerate the golden file with:\n"+
+			"\tgo test ./pkg/providers -run TestCompatJSON -update\n"+
+			"and review the diff before committing.", want, got)
+	}
+}
@@ -0,0 +1,243 @@
+package providers_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	_ "github.com/CatoSystems/rim-pay/pkg/providers"
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+)
+
+// Contract tests pin the exact wire shape of every outbound provider request.
+// Golden files live in testdata/contract; regenerate them after an intentional
+// change with:
+//
+//	go test ./pkg/providers -run TestContract -update
+var update = flag.Bool("update", false, "rewrite contract golden files")
+
+// redactedHeaders are replaced in snapshots because they carry secrets
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+}
+
+// capturingHTTPClient records every request and answers with canned bodies
+// keyed by URL path
+type capturingHTTPClient struct {
+	responses map[string]string
+	requests  []*common.HTTPRequest
+}
+
+func (c *capturingHTTPClient) Do(_ context.Context, req *common.HTTPRequest) (*common.HTTPResponse, error) {
+	c.requests = append(c.requests, req)
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &common.HTTPResponse{StatusCode: 200, Body: []byte(c.responses[u.Path])}, nil
+}
+
+func newContractClient(t *testing.T) *rimpay.Client {
+	t.Helper()
+	config := rimpay.DefaultConfig()
+	config.Providers["bpay"] = rimpay.ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := rimpay.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func contractPhone(t *testing.T) *phone.Phone {
+	t.Helper()
+	p, err := phone.NewPhone("+22222334455")
+	if err != nil {
+		t.Fatalf("NewPhone: %v", err)
+	}
+	return p
+}
+
+func TestContractBPay(t *testing.T) {
+	capture := &capturingHTTPClient{responses: map[string]string{
+		"/authentification": `{"access_token":"token-123","expires_in":"3600","refresh_token":"refresh-123"}`,
+		"/payment":          `{"errorCode":"0","errorMessage":"","transactionId":"TX-1"}`,
+		"/checkTransaction": `{"errorCode":"0","errorMessage":"","transactionId":"TX-1","status":"TS"}`,
+	}}
+
+	client := newContractClient(t)
+	err := client.AddBPayProvider(rimpay.ProviderConfig{
+		BaseURL:     "https://bpay.test",
+		Credentials: map[string]string{"username": "merchant", "password": "secret", "client_id": "e-bankily"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  capture,
+	})
+	if err != nil {
+		t.Fatalf("AddBPayProvider: %v", err)
+	}
+
+	_, err = client.ProcessBPayPayment(context.Background(), &rimpay.BPayPaymentRequest{
+		PhoneNumber: contractPhone(t),
+		Amount:      money.FromFloat64(150.50, money.MRU),
+		Description: "Contract order",
+		Reference:   "ORDER-1",
+		Passcode:    "1234",
+	})
+	if err != nil {
+		t.Fatalf("ProcessBPayPayment: %v", err)
+	}
+
+	provider, err := client.GetBPayProvider()
+	if err != nil {
+		t.Fatalf("GetBPayProvider: %v", err)
+	}
+	if _, err := provider.GetPaymentStatus(context.Background(), "ORDER-1"); err != nil {
+		t.Fatalf("GetPaymentStatus: %v", err)
+	}
+
+	assertContract(t, "bpay", capture.requests, nil)
+}
+
+func TestContractMasrvi(t *testing.T) {
+	capture := &capturingHTTPClient{responses: map[string]string{
+		"/online/online.php": "SESSION-123",
+	}}
+
+	client := newContractClient(t)
+	err := client.AddMasrviProvider(rimpay.ProviderConfig{
+		BaseURL:     "https://masrvi.test",
+		Credentials: map[string]string{"merchant_id": "MERCHANT-1"},
+		Timeout:     5 * time.Second,
+		HTTPClient:  capture,
+	})
+	if err != nil {
+		t.Fatalf("AddMasrviProvider: %v", err)
+	}
+
+	resp, err := client.ProcessMasrviPayment(context.Background(), &rimpay.MasrviPaymentRequest{
+		PhoneNumber: contractPhone(t),
+		Amount:      money.FromFloat64(150.50, money.MRU),
+		Description: "Contract order",
+		Reference:   "ORDER-1",
+		CallbackURL: "https://shop.test/notify",
+		ReturnURL:   "https://shop.test/return",
+	})
+	if err != nil {
+		t.Fatalf("ProcessMasrviPayment: %v", err)
+	}
+
+	// The purchase form is posted by the customer's browser rather than by
+	// us, but it is part of the wire contract all the same.
+	form, ok := resp.Metadata["form_data"].(url.Values)
+	if !ok {
+		t.Fatalf("form_data missing from response metadata")
+	}
+	browserPost := &common.HTTPRequest{
+		Method:  "POST",
+		URL:     resp.PaymentURL,
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    []byte(form.Encode()),
+	}
+
+	assertContract(t, "masrvi", capture.requests, browserPost)
+}
+
+// assertContract compares the canonical snapshot of requests with the golden file
+func assertContract(t *testing.T, name string, requests []*common.HTTPRequest, extra *common.HTTPRequest) {
+	t.Helper()
+	if extra != nil {
+		requests = append(requests, extra)
+	}
+
+	var b strings.Builder
+	for i, req := range requests {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		b.WriteString(snapshotRequest(t, req))
+	}
+	got := b.String()
+
+	path := filepath.Join("testdata", "contract", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file %s: %v\nrun `go test ./pkg/providers -run TestContract -update` to create it", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s wire payload changed.\n\n--- want\n%s\n\n--- got\n%s\n\n"+
+			"If the provider contract changed intentionally, regenerate the golden files with:\n"+
+			"\tgo test ./pkg/providers -run TestContract -update\n"+
+			"and review the diff before committing.", name, want, got)
+	}
+}
+
+// snapshotRequest renders one request in a stable, reviewable text form
+func snapshotRequest(t *testing.T, req *common.HTTPRequest) string {
+	t.Helper()
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		t.Fatalf("parse URL %q: %v", req.URL, err)
+	}
+
+	var b strings.Builder
+	target := u.Path
+	if u.RawQuery != "" {
+		target += "?" + common.CanonicalForm(u.Query())
+	}
+	fmt.Fprintf(&b, "%s %s\n", req.Method, target)
+
+	keys := make([]string, 0, len(req.Headers))
+	for k := range req.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := req.Headers[k]
+		if redactedHeaders[k] {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", k, value)
+	}
+
+	if len(req.Body) > 0 {
+		b.WriteString("\n")
+		b.WriteString(canonicalBody(t, req))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func canonicalBody(t *testing.T, req *common.HTTPRequest) string {
+	t.Helper()
+	if strings.HasPrefix(req.Headers["Content-Type"], "application/json") {
+		body, err := common.CanonicalJSON(req.Body)
+		if err != nil {
+			t.Fatalf("canonicalize JSON body: %v", err)
+		}
+		return string(body)
+	}
+	values, err := url.ParseQuery(string(req.Body))
+	if err != nil {
+		return string(req.Body)
+	}
+	return common.CanonicalForm(values)
+}
@@ -9,4 +9,6 @@ import (
 	_ "github.com/CatoSystems/rim-pay/internal/providers/bpay"
 	_ "github.com/CatoSystems/rim-pay/internal/providers/click"
 	_ "github.com/CatoSystems/rim-pay/internal/providers/masrvi"
+	_ "github.com/CatoSystems/rim-pay/internal/providers/mock"
+	_ "github.com/CatoSystems/rim-pay/internal/providers/sedad"
 )
@@ -0,0 +1,72 @@
+package rimpay
+
+import (
+	"sync"
+	"time"
+)
+
+// sloAlerter logs a Warn when a provider breaches the configured latency or
+// error rate thresholds, at most once per window per provider and kind
+type sloAlerter struct {
+	config AlertsConfig
+	window time.Duration
+	logger Logger
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newSLOAlerter(config AlertsConfig, window time.Duration, logger Logger) *sloAlerter {
+	if config.LatencyThreshold <= 0 && config.ErrorRateThreshold <= 0 {
+		return nil
+	}
+	return &sloAlerter{
+		config: config,
+		window: window,
+		logger: logger,
+		last:   make(map[string]time.Time),
+	}
+}
+
+// observe checks one provider call against the thresholds
+func (a *sloAlerter) observe(provider string, latency time.Duration, stats windowStats, now time.Time) {
+	if a == nil {
+		return
+	}
+
+	if a.config.LatencyThreshold > 0 && latency > a.config.LatencyThreshold && a.allow(provider+"/latency", now) {
+		a.logger.Warn("Provider response time exceeded SLO",
+			"provider", provider,
+			"latency", latency,
+			"threshold", a.config.LatencyThreshold,
+			"window", a.window,
+			"calls", stats.Calls,
+			"avg_latency", stats.AvgLatency,
+			"max_latency", stats.MaxLatency,
+		)
+	}
+
+	if a.config.ErrorRateThreshold > 0 && stats.Calls >= a.config.MinCalls &&
+		stats.ErrorRate >= a.config.ErrorRateThreshold && a.allow(provider+"/errors", now) {
+		a.logger.Warn("Provider error rate exceeded SLO",
+			"provider", provider,
+			"error_rate", stats.ErrorRate,
+			"threshold", a.config.ErrorRateThreshold,
+			"window", a.window,
+			"calls", stats.Calls,
+			"errors", stats.Errors,
+		)
+	}
+}
+
+// allow reports whether an alert for key may fire, recording it if so
+func (a *sloAlerter) allow(key string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if last, ok := a.last[key]; ok && now.Sub(last) < a.window {
+		return false
+	}
+	a.last[key] = now
+	return true
+}
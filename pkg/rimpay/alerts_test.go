@@ -0,0 +1,170 @@
+package rimpay
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for window-based tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// recordingLogger captures Warn messages.
+type recordingLogger struct {
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *recordingLogger) Debug(string, ...interface{}) {}
+func (l *recordingLogger) Info(string, ...interface{})  {}
+func (l *recordingLogger) Error(string, ...interface{}) {}
+
+func (l *recordingLogger) Warn(msg string, _ ...interface{}) {
+	l.mu.Lock()
+	l.warns = append(l.warns, msg)
+	l.mu.Unlock()
+}
+
+func (l *recordingLogger) count(msg string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, w := range l.warns {
+		if w == msg {
+			n++
+		}
+	}
+	return n
+}
+
+// clockedProvider advances the fake clock by latency on every status check.
+type clockedProvider struct {
+	clock   *fakeClock
+	latency time.Duration
+	err     error
+}
+
+func (p *clockedProvider) Name() string                     { return "clocked" }
+func (p *clockedProvider) IsAvailable(context.Context) bool { return true }
+func (p *clockedProvider) ValidateConfig() error            { return nil }
+func (p *clockedProvider) Capabilities() Capabilities       { return Capabilities{} }
+
+func (p *clockedProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	return nil, ErrPaymentFailed
+}
+
+func (p *clockedProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	p.clock.Advance(p.latency)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &TransactionStatus{TransactionID: transactionID}, nil
+}
+
+func (p *clockedProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func newAlertTestClient(t *testing.T, alerts AlertsConfig, provider *clockedProvider) (*Client, *recordingLogger) {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Alerts = alerts
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	logger := &recordingLogger{}
+	client.logger = logger
+	client.alerter.logger = logger
+	client.stats.now = provider.clock.Now
+	require.NoError(t, client.AddProvider("clocked", provider))
+	return client, logger
+}
+
+func TestLatencyAlertFiresOncePerWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	provider := &clockedProvider{clock: clock, latency: 3 * time.Second}
+	client, logger := newAlertTestClient(t, AlertsConfig{
+		LatencyThreshold: 2 * time.Second,
+		Window:           time.Minute,
+	}, provider)
+
+	for i := 0; i < 10; i++ {
+		_, err := client.GetPaymentStatus(context.Background(), "TX")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, logger.count("Provider response time exceeded SLO"))
+
+	clock.Advance(time.Minute)
+	_, err := client.GetPaymentStatus(context.Background(), "TX")
+	require.NoError(t, err)
+	assert.Equal(t, 2, logger.count("Provider response time exceeded SLO"))
+}
+
+func TestLatencyAlertSilentWithinThreshold(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	provider := &clockedProvider{clock: clock, latency: time.Second}
+	client, logger := newAlertTestClient(t, AlertsConfig{LatencyThreshold: 2 * time.Second}, provider)
+
+	_, err := client.GetPaymentStatus(context.Background(), "TX")
+	require.NoError(t, err)
+	assert.Equal(t, 0, logger.count("Provider response time exceeded SLO"))
+}
+
+func TestErrorRateAlertFiresOncePerWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	provider := &clockedProvider{clock: clock, latency: 10 * time.Millisecond, err: ErrNetworkError}
+	client, logger := newAlertTestClient(t, AlertsConfig{
+		ErrorRateThreshold: 0.5,
+		MinCalls:           3,
+		Window:             time.Minute,
+	}, provider)
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.GetPaymentStatus(context.Background(), "TX")
+	}
+	assert.Equal(t, 0, logger.count("Provider error rate exceeded SLO"), "must wait for MinCalls")
+
+	for i := 0; i < 20; i++ {
+		_, _ = client.GetPaymentStatus(context.Background(), "TX")
+	}
+	assert.Equal(t, 1, logger.count("Provider error rate exceeded SLO"))
+
+	stats := client.stats.snapshot("clocked")
+	assert.Equal(t, 22, stats.Calls)
+	assert.Equal(t, 22, stats.Errors)
+}
+
+func TestStatsWindowDropsOldSamples(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	stats := newStatsCollector(time.Minute, 0)
+	stats.now = clock.Now
+
+	stats.record("bpay", time.Second, ErrNetworkError)
+	stats.record("bpay", time.Second, NewValidationError("amount", "must be positive"))
+	snapshot := stats.snapshot("bpay")
+	assert.Equal(t, 2, snapshot.Calls)
+	assert.Equal(t, 1, snapshot.Errors, "validation errors are not provider failures")
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, 0, stats.snapshot("bpay").Calls)
+}
@@ -0,0 +1,232 @@
+package rimpay
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Defaults for AsyncConfig fields left unset
+const (
+	defaultAsyncWorkers   = 4
+	defaultAsyncQueueSize = 64
+)
+
+// AsyncClosePolicy decides what Client.Close does with asynchronous
+// payments that are queued or running
+type AsyncClosePolicy string
+
+const (
+	// AsyncCloseDrain lets queued and running payments finish before the
+	// client closes (the default)
+	AsyncCloseDrain AsyncClosePolicy = "drain"
+	// AsyncCloseCancel cancels them, delivering ErrClientClosed
+	AsyncCloseCancel AsyncClosePolicy = "cancel"
+)
+
+// PaymentResult is the outcome of an asynchronous payment
+type PaymentResult struct {
+	Response *PaymentResponse
+	Err      error
+}
+
+// ProcessPaymentAsync queues request for ProcessPayment on the client's
+// worker pool and returns a channel that receives exactly one result and
+// is then closed. ctx bounds the payment, including its time in the queue:
+// once it is done the result is ctx.Err(), so it should outlive the HTTP
+// request that started the payment. A full queue is rejected at once with
+// a retryable PROVIDER_BUSY error.
+func (c *Client) ProcessPaymentAsync(ctx context.Context, request *PaymentRequest) (<-chan PaymentResult, error) {
+	results := make(chan PaymentResult, 1)
+	err := c.ProcessPaymentCallback(ctx, request, func(resp *PaymentResponse, err error) {
+		results <- PaymentResult{Response: resp, Err: err}
+		close(results)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ProcessPaymentCallback is ProcessPaymentAsync delivering the result to
+// callback, which is called exactly once from a worker goroutine and
+// should not block
+func (c *Client) ProcessPaymentCallback(ctx context.Context, request *PaymentRequest, callback func(*PaymentResponse, error)) error {
+	if request == nil || callback == nil {
+		return ErrInvalidRequest
+	}
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	return c.async.submit(c, &asyncJob{ctx: ctx, request: request, deliver: callback})
+}
+
+// asyncJob is a queued asynchronous payment
+type asyncJob struct {
+	ctx     context.Context
+	request *PaymentRequest
+	deliver func(*PaymentResponse, error)
+
+	// queued is set to 0 by whoever settles the job first: a worker taking
+	// it, which then closes taken, or its cancellation while queued
+	queued uint32
+	taken  chan struct{}
+}
+
+// take claims job for a worker, reporting false when it was cancelled
+// while queued
+func (j *asyncJob) take() bool {
+	if !atomic.CompareAndSwapUint32(&j.queued, 1, 0) {
+		return false
+	}
+	close(j.taken)
+	return true
+}
+
+// asyncPool runs asynchronous payments on a fixed number of workers, which
+// are started by the first payment
+type asyncPool struct {
+	mu      sync.Mutex
+	jobs    chan *asyncJob
+	closing bool
+
+	// stop is closed to cancel queued and running payments
+	stop     chan struct{}
+	stopOnce sync.Once
+	workers  sync.WaitGroup
+}
+
+func newAsyncPool() *asyncPool {
+	return &asyncPool{stop: make(chan struct{})}
+}
+
+// submit queues job, starting the workers on first use
+func (p *asyncPool) submit(c *Client, job *asyncJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closing {
+		return ErrClientClosed
+	}
+
+	if p.jobs == nil {
+		config := c.currentConfig().Async
+		workers, size := config.Workers, config.QueueSize
+		if workers <= 0 {
+			workers = defaultAsyncWorkers
+		}
+		if size <= 0 {
+			size = defaultAsyncQueueSize
+		}
+		p.jobs = make(chan *asyncJob, size)
+		for i := 0; i < workers; i++ {
+			p.workers.Add(1)
+			go func() {
+				defer p.workers.Done()
+				for job := range p.jobs {
+					if job.take() {
+						p.run(c, job)
+					}
+				}
+			}()
+		}
+	}
+
+	job.queued, job.taken = 1, make(chan struct{})
+	select {
+	case p.jobs <- job:
+	default:
+		return NewPaymentError(ErrorCodeProviderBusy, "async payment queue is full", "", true)
+	}
+
+	// A queued payment may wait behind slow ones; its cancellation is
+	// delivered without waiting for a worker
+	go func() {
+		var err error
+		select {
+		case <-job.taken:
+			return
+		case <-job.ctx.Done():
+			err = job.ctx.Err()
+		case <-p.stop:
+			err = ErrClientClosed
+		}
+		if atomic.CompareAndSwapUint32(&job.queued, 1, 0) {
+			job.deliver(nil, err)
+		}
+	}()
+	return nil
+}
+
+// run processes job and delivers its result, or the cancellation that
+// came first
+func (p *asyncPool) run(c *Client, job *asyncJob) {
+	if err := job.ctx.Err(); err != nil {
+		job.deliver(nil, err)
+		return
+	}
+	select {
+	case <-p.stop:
+		job.deliver(nil, ErrClientClosed)
+		return
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(job.ctx)
+	defer cancel()
+	done := make(chan struct{})
+	var resp *PaymentResponse
+	var err error
+	go func() {
+		defer close(done)
+		resp, err = c.ProcessPayment(ctx, job.request)
+	}()
+
+	// The worker stays busy until the payment returns, keeping the pool
+	// bounded, but the caller hears of a cancellation at once
+	select {
+	case <-done:
+		job.deliver(resp, err)
+	case <-job.ctx.Done():
+		cancel()
+		job.deliver(nil, job.ctx.Err())
+		<-done
+	case <-p.stop:
+		cancel()
+		job.deliver(nil, ErrClientClosed)
+		<-done
+	}
+}
+
+// shutdown stops accepting payments and drains or cancels the rest under
+// policy. If ctx is done first, the remaining payments are cancelled and
+// ctx.Err() is returned.
+func (p *asyncPool) shutdown(ctx context.Context, policy AsyncClosePolicy) error {
+	p.mu.Lock()
+	if !p.closing {
+		p.closing = true
+		if p.jobs != nil {
+			close(p.jobs)
+		}
+	}
+	p.mu.Unlock()
+	if policy == AsyncCloseCancel {
+		p.cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *asyncPool) cancel() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
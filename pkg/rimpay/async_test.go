@@ -0,0 +1,221 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heldProvider holds payments until release is closed; with ignoreCtx
+// it does not return early on cancellation either
+type heldProvider struct {
+	namedProvider
+	started   chan string
+	release   chan struct{}
+	ignoreCtx bool
+}
+
+func newHeldProvider() *heldProvider {
+	return &heldProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		started:       make(chan string, 100),
+		release:       make(chan struct{}),
+	}
+}
+
+func (p *heldProvider) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	p.started <- request.Reference
+	if p.ignoreCtx {
+		<-p.release
+	} else {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &PaymentResponse{TransactionID: "TX-" + request.Reference, Status: PaymentStatusSuccess}, nil
+}
+
+func newAsyncTestClient(t *testing.T, provider PaymentProvider, config AsyncConfig) *Client {
+	t.Helper()
+	client := newFailoverTestClient(t, nil, provider)
+	client.config.Async = config
+	return client
+}
+
+func asyncRequest(reference string) *PaymentRequest {
+	request := failoverRequest()
+	request.Reference = reference
+	return request
+}
+
+func receive(t *testing.T, results <-chan PaymentResult) PaymentResult {
+	t.Helper()
+	select {
+	case result, ok := <-results:
+		require.True(t, ok, "channel closed without a result")
+		_, more := <-results
+		assert.False(t, more, "channel must be closed after the result")
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result delivered")
+		return PaymentResult{}
+	}
+}
+
+func TestProcessPaymentAsync(t *testing.T) {
+	provider := newHeldProvider()
+	close(provider.release)
+	client := newAsyncTestClient(t, provider, AsyncConfig{})
+
+	results, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-1"))
+	require.NoError(t, err)
+
+	result := receive(t, results)
+	require.NoError(t, result.Err)
+	assert.Equal(t, "TX-REF-1", result.Response.TransactionID)
+}
+
+func TestProcessPaymentAsyncRejectsWhenSaturated(t *testing.T) {
+	provider := newHeldProvider()
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 1, QueueSize: 1})
+
+	running, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "REF-1", <-provider.started)
+	queued, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-2"))
+	require.NoError(t, err)
+
+	_, err = client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-3"))
+	require.Error(t, err)
+	assert.Equal(t, ErrorCodeProviderBusy, CodeOf(err))
+	assert.True(t, IsRetryable(err))
+
+	close(provider.release)
+	assert.NoError(t, receive(t, running).Err)
+	assert.NoError(t, receive(t, queued).Err)
+}
+
+func TestProcessPaymentAsyncCancellation(t *testing.T) {
+	provider := newHeldProvider()
+	provider.ignoreCtx = true
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 1, QueueSize: 1})
+	defer close(provider.release)
+
+	runningCtx, cancelRunning := context.WithCancel(context.Background())
+	running, err := client.ProcessPaymentAsync(runningCtx, asyncRequest("REF-1"))
+	require.NoError(t, err)
+	<-provider.started
+
+	queuedCtx, cancelQueued := context.WithCancel(context.Background())
+	queued, err := client.ProcessPaymentAsync(queuedCtx, asyncRequest("REF-2"))
+	require.NoError(t, err)
+
+	// The provider ignores the cancellation, but the caller hears of it
+	cancelRunning()
+	assert.ErrorIs(t, receive(t, running).Err, context.Canceled)
+
+	cancelQueued()
+	assert.ErrorIs(t, receive(t, queued).Err, context.Canceled)
+	assert.Len(t, provider.started, 0, "cancelled payments are not sent")
+}
+
+func TestProcessPaymentCallbackDeliversExactlyOnce(t *testing.T) {
+	provider := newHeldProvider()
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 3, QueueSize: 100})
+
+	const calls = 60
+	var counts [calls]int32
+	var delivered sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		if i%3 == 0 {
+			cancel()
+		}
+		defer cancel()
+
+		i := i
+		delivered.Add(1)
+		err := client.ProcessPaymentCallback(ctx, asyncRequest(fmt.Sprintf("REF-%d", i)), func(*PaymentResponse, error) {
+			atomic.AddInt32(&counts[i], 1)
+			delivered.Done()
+		})
+		require.NoError(t, err)
+	}
+	close(provider.release)
+
+	delivered.Wait()
+	require.NoError(t, client.Close(context.Background()))
+	for i := range counts {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&counts[i]), "payment %d", i)
+	}
+}
+
+func TestCloseDrainsAsyncPayments(t *testing.T) {
+	provider := newHeldProvider()
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 1})
+
+	running, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-1"))
+	require.NoError(t, err)
+	queued, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-2"))
+	require.NoError(t, err)
+	<-provider.started
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(provider.release)
+	}()
+	require.NoError(t, client.Close(context.Background()))
+
+	assert.NoError(t, receive(t, running).Err)
+	assert.NoError(t, receive(t, queued).Err)
+	_, err = client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-3"))
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestCloseCancelsAsyncPayments(t *testing.T) {
+	provider := newHeldProvider()
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 1, OnClose: AsyncCloseCancel})
+
+	running, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-1"))
+	require.NoError(t, err)
+	queued, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-2"))
+	require.NoError(t, err)
+	<-provider.started
+
+	require.NoError(t, client.Close(context.Background()))
+
+	assert.ErrorIs(t, receive(t, running).Err, ErrClientClosed)
+	assert.ErrorIs(t, receive(t, queued).Err, ErrClientClosed)
+	assert.Len(t, provider.started, 0, "queued payments are not sent")
+}
+
+func TestCloseCancelsAsyncPaymentsWhenContextEnds(t *testing.T) {
+	provider := newHeldProvider()
+	client := newAsyncTestClient(t, provider, AsyncConfig{Workers: 1})
+
+	running, err := client.ProcessPaymentAsync(context.Background(), asyncRequest("REF-1"))
+	require.NoError(t, err)
+	<-provider.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, receive(t, running).Err, ErrClientClosed)
+}
+
+func TestValidateRejectsInvalidAsyncConfig(t *testing.T) {
+	for _, async := range []AsyncConfig{{Workers: -1}, {QueueSize: -1}, {OnClose: "later"}} {
+		config := DefaultConfig()
+		config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+		config.Async = async
+		assert.Error(t, config.Validate(), "%+v", async)
+	}
+}
@@ -0,0 +1,177 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultBatchConcurrency is the worker count when BatchConfig.Concurrency is unset
+const defaultBatchConcurrency = 4
+
+// BatchCancelPolicy decides what happens to in-flight batch items when the
+// batch context is cancelled. Items not yet dispatched are always skipped.
+type BatchCancelPolicy string
+
+const (
+	// BatchCancelWait lets in-flight items run to completion (the default)
+	BatchCancelWait BatchCancelPolicy = "wait"
+	// BatchCancelAbort cancels in-flight items along with the batch
+	BatchCancelAbort BatchCancelPolicy = "abort"
+)
+
+// BatchItem is one payment in a batch; set exactly one of BPay or Masrvi
+type BatchItem struct {
+	BPay   *BPayPaymentRequest
+	Masrvi *MasrviPaymentRequest
+}
+
+// provider returns the provider the item is addressed to
+func (i BatchItem) provider() (string, error) {
+	switch {
+	case i.BPay != nil && i.Masrvi != nil:
+		return "", fmt.Errorf("%w: batch item sets both BPay and Masrvi", ErrInvalidRequest)
+	case i.BPay != nil:
+		return ProviderBPay, nil
+	case i.Masrvi != nil:
+		return ProviderMasrvi, nil
+	default:
+		return "", fmt.Errorf("%w: batch item has no request", ErrInvalidRequest)
+	}
+}
+
+// BatchItemResult is the outcome of the batch item at Index
+type BatchItemResult struct {
+	Index    int
+	Provider string
+	Response *PaymentResponse
+	Err      error
+	// Skipped is set when the item was never dispatched because the batch
+	// context was cancelled
+	Skipped bool
+}
+
+// Success returns true if the item was processed without error
+func (r BatchItemResult) Success() bool {
+	return r.Err == nil && !r.Skipped
+}
+
+// PaymentError returns Err as a *PaymentError, or nil if it is not one
+func (r BatchItemResult) PaymentError() *PaymentError {
+	var paymentErr *PaymentError
+	if errors.As(r.Err, &paymentErr) {
+		return paymentErr
+	}
+	return nil
+}
+
+// BatchResult holds per-item results in input order and aggregate counts
+type BatchResult struct {
+	Items     []BatchItemResult
+	Succeeded int
+	Failed    int
+	Skipped   int
+}
+
+// ProcessBatch processes items concurrently using Config.Batch. A failed item
+// does not stop the others. When ctx is cancelled no further items are
+// dispatched and the partial result is returned together with ctx.Err().
+func (c *Client) ProcessBatch(ctx context.Context, items []BatchItem) (*BatchResult, error) {
+	config := c.currentConfig().Batch
+	workers := config.Concurrency
+	if workers <= 0 {
+		workers = defaultBatchConcurrency
+	}
+
+	results := make([]BatchItemResult, len(items))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = c.processBatchItem(ctx, config, i, items[i])
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for dispatched < len(items) && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case indexes <- dispatched:
+			dispatched++
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := dispatched; i < len(items); i++ {
+		provider, _ := items[i].provider()
+		results[i] = BatchItemResult{Index: i, Provider: provider, Err: ctx.Err(), Skipped: true}
+	}
+
+	result := &BatchResult{Items: results}
+	for _, item := range results {
+		switch {
+		case item.Skipped:
+			result.Skipped++
+		case item.Err != nil:
+			result.Failed++
+		default:
+			result.Succeeded++
+		}
+	}
+
+	if dispatched < len(items) {
+		c.logger.Warn("Batch cancelled", "dispatched", dispatched, "skipped", result.Skipped)
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// processBatchItem runs a single item under the cancel policy and item timeout
+func (c *Client) processBatchItem(ctx context.Context, config BatchConfig, index int, item BatchItem) BatchItemResult {
+	result := BatchItemResult{Index: index}
+
+	provider, err := item.provider()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Provider = provider
+
+	if config.OnCancel != BatchCancelAbort {
+		ctx = detachedContext{ctx}
+	}
+	if config.ItemTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ItemTimeout)
+		defer cancel()
+	}
+
+	switch provider {
+	case ProviderBPay:
+		result.Response, result.Err = c.ProcessBPayPayment(ctx, item.BPay)
+	case ProviderMasrvi:
+		result.Response, result.Err = c.ProcessMasrviPayment(ctx, item.Masrvi)
+	}
+	return result
+}
+
+// detachedContext keeps the values of its parent but not its cancellation,
+// so in-flight batch items can finish after the batch is cancelled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
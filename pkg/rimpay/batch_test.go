@@ -0,0 +1,168 @@
+package rimpay
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchProvider runs pay for every B-PAY payment
+type batchProvider struct {
+	namedProvider
+	pay func(ctx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error)
+}
+
+func (p *batchProvider) ProcessBPayPayment(ctx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+	return p.pay(ctx, request)
+}
+
+func echoPayment(_ context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+	return &PaymentResponse{TransactionID: request.Reference}, nil
+}
+
+func newBatchTestClient(t *testing.T, batch BatchConfig, pay func(context.Context, *BPayPaymentRequest) (*PaymentResponse, error)) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Batch = batch
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderBPay, &batchProvider{namedProvider{name: ProviderBPay}, pay}))
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &notifyingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}))
+	return client
+}
+
+func bpayItems(refs ...string) []BatchItem {
+	items := make([]BatchItem, len(refs))
+	for i, ref := range refs {
+		items[i] = BatchItem{BPay: &BPayPaymentRequest{Reference: ref, Amount: money.NewMRU(1000)}}
+	}
+	return items
+}
+
+func TestProcessBatchPartialFailureKeepsOrder(t *testing.T) {
+	declined := NewPaymentError(ErrorCodePaymentDeclined, "declined", ProviderBPay, false)
+	client := newBatchTestClient(t, BatchConfig{Concurrency: 3}, func(ctx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+		if request.Reference == "B" {
+			return nil, declined
+		}
+		return echoPayment(ctx, request)
+	})
+
+	items := append(bpayItems("A", "B", "C"),
+		BatchItem{Masrvi: &MasrviPaymentRequest{Reference: "M", Amount: money.NewMRU(1000)}},
+		BatchItem{},
+	)
+	result, err := client.ProcessBatch(context.Background(), items)
+	require.NoError(t, err)
+	require.Len(t, result.Items, 5)
+
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	assert.Zero(t, result.Skipped)
+
+	for i, item := range result.Items {
+		assert.Equal(t, i, item.Index)
+	}
+	assert.Equal(t, "A", result.Items[0].Response.TransactionID)
+	assert.Equal(t, "C", result.Items[2].Response.TransactionID)
+
+	assert.False(t, result.Items[1].Success())
+	assert.Same(t, declined, result.Items[1].PaymentError())
+	assert.Equal(t, ProviderMasrvi, result.Items[3].Provider)
+	assert.ErrorIs(t, result.Items[3].Err, ErrPaymentFailed)
+	assert.ErrorIs(t, result.Items[4].Err, ErrInvalidRequest)
+	assert.Nil(t, result.Items[4].PaymentError())
+}
+
+func TestProcessBatchBoundsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	client := newBatchTestClient(t, BatchConfig{Concurrency: 2}, func(ctx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return echoPayment(ctx, request)
+	})
+
+	result, err := client.ProcessBatch(context.Background(), bpayItems("1", "2", "3", "4", "5", "6", "7", "8"))
+	require.NoError(t, err)
+	assert.Equal(t, 8, result.Succeeded)
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+}
+
+func TestProcessBatchItemTimeout(t *testing.T) {
+	client := newBatchTestClient(t, BatchConfig{ItemTimeout: 10 * time.Millisecond}, func(ctx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+		if request.Reference == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return echoPayment(ctx, request)
+	})
+
+	result, err := client.ProcessBatch(context.Background(), bpayItems("slow", "fast"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, result.Items[0].Err, context.DeadlineExceeded)
+	assert.True(t, result.Items[1].Success())
+}
+
+func TestProcessBatchCancellation(t *testing.T) {
+	tests := []struct {
+		policy      BatchCancelPolicy
+		wantFirst   bool
+		wantFailure error
+	}{
+		{BatchCancelWait, true, nil},
+		{BatchCancelAbort, false, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			client := newBatchTestClient(t, BatchConfig{Concurrency: 1, OnCancel: tt.policy}, func(itemCtx context.Context, request *BPayPaymentRequest) (*PaymentResponse, error) {
+				cancel()
+				select {
+				case <-itemCtx.Done():
+					return nil, itemCtx.Err()
+				case <-time.After(20 * time.Millisecond):
+					return echoPayment(itemCtx, request)
+				}
+			})
+
+			result, err := client.ProcessBatch(ctx, bpayItems("first", "second", "third"))
+			assert.ErrorIs(t, err, context.Canceled)
+
+			assert.Equal(t, tt.wantFirst, result.Items[0].Success())
+			if tt.wantFailure != nil {
+				assert.ErrorIs(t, result.Items[0].Err, tt.wantFailure)
+			}
+			for _, item := range result.Items[1:] {
+				assert.True(t, item.Skipped)
+				assert.Equal(t, ProviderBPay, item.Provider)
+				assert.ErrorIs(t, item.Err, context.Canceled)
+			}
+			assert.Equal(t, 2, result.Skipped)
+		})
+	}
+}
+
+func TestValidateRejectsInvalidBatchConfig(t *testing.T) {
+	for _, batch := range []BatchConfig{{Concurrency: -1}, {ItemTimeout: -time.Second}, {OnCancel: "later"}} {
+		config := DefaultConfig()
+		config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+		config.Batch = batch
+		assert.Error(t, config.Validate(), "%+v", batch)
+	}
+}
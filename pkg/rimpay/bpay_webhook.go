@@ -0,0 +1,117 @@
+package rimpay
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// BPaySecretHeader carries the shared secret of B-PAY status callbacks
+const BPaySecretHeader = "X-Callback-Secret"
+
+// maxBPayNotificationSize bounds the body of a B-PAY callback
+const maxBPayNotificationSize = 64 << 10
+
+// BPayWebhookOptions configures NewBPayWebhookHandler
+type BPayWebhookOptions struct {
+	// Handle receives every authenticated callback, whatever its status;
+	// Client.HandleBPayNotification maps it to a TransactionStatus. An error
+	// makes the handler answer 500 so B-PAY re-delivers it.
+	Handle func(ctx context.Context, notification *BPayNotificationData) error
+	// Secret must equal the BPaySecretHeader of every callback. It is
+	// usually the OptionCallbackSecret of the B-PAY ProviderConfig.
+	Secret string
+	// Logger receives rejected callbacks (optional)
+	Logger Logger
+	// Dispatcher, when set, runs Handle once per unique callback and one
+	// callback per payment at a time (optional)
+	Dispatcher *NotificationDispatcher
+}
+
+type bpayWebhook struct {
+	opts BPayWebhookOptions
+}
+
+// NewBPayWebhookHandler returns an http.Handler for B-PAY status callbacks.
+// It checks the shared secret, decodes the JSON body, passes it to
+// opts.Handle, through opts.Dispatcher when set, and answers "OK".
+func NewBPayWebhookHandler(opts BPayWebhookOptions) http.Handler {
+	if opts.Handle == nil {
+		panic("rimpay: BPayWebhookOptions.Handle is required")
+	}
+	if opts.Secret == "" {
+		panic("rimpay: BPayWebhookOptions.Secret is required")
+	}
+	return &bpayWebhook{opts: opts}
+}
+
+func (h *bpayWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	secret := r.Header.Get(BPaySecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(h.opts.Secret)) != 1 {
+		h.warn("Rejected B-PAY callback", "reason", "bad secret", "remote_addr", r.RemoteAddr)
+		http.Error(w, "invalid notification", http.StatusUnauthorized)
+		return
+	}
+
+	notification, err := ParseBPayNotification(io.LimitReader(r.Body, maxBPayNotificationSize))
+	if err != nil {
+		h.warn("Malformed B-PAY callback", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, duplicate, err := h.opts.Dispatcher.dispatch(r.Context(), inboundNotification{
+		provider:  ProviderBPay,
+		reference: notification.OperationID,
+		verify: func(context.Context) (*TransactionStatus, string, error) {
+			return nil, bpayNotificationKey(notification), nil
+		},
+		handle: func(ctx context.Context, _ *TransactionStatus) error {
+			return h.opts.Handle(ctx, notification)
+		},
+	})
+	if err != nil {
+		if h.opts.Logger != nil {
+			h.opts.Logger.Error("B-PAY callback handling failed", "reference", notification.OperationID, "error", err)
+		}
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	}
+	if duplicate && h.opts.Logger != nil {
+		h.opts.Logger.Debug("Duplicate B-PAY callback acknowledged", "reference", notification.OperationID)
+	}
+	writeWebhookOK(w)
+}
+
+func (h *bpayWebhook) warn(msg string, fields ...interface{}) {
+	if h.opts.Logger != nil {
+		h.opts.Logger.Warn(msg, fields...)
+	}
+}
+
+// ParseBPayNotification decodes the JSON body of a B-PAY status callback,
+// which must name the operation and carry a status or error code
+func ParseBPayNotification(body io.Reader) (*BPayNotificationData, error) {
+	var notification BPayNotificationData
+	if err := json.NewDecoder(body).Decode(&notification); err != nil {
+		return nil, NewValidationError("notification", "invalid JSON body")
+	}
+	notification.OperationID = strings.TrimSpace(notification.OperationID)
+	notification.Status = strings.TrimSpace(notification.Status)
+	notification.ErrorCode = strings.TrimSpace(notification.ErrorCode)
+	if notification.OperationID == "" {
+		return nil, NewValidationError("operationId", "is required")
+	}
+	if notification.Status == "" && notification.ErrorCode == "" {
+		return nil, NewValidationError("status", "is required")
+	}
+	return &notification, nil
+}
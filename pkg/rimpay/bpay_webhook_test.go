@@ -0,0 +1,88 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCallbackSecret = "callback-secret"
+
+// serveBPay posts body to a handler built from opts and records the
+// callbacks passed to Handle
+func serveBPay(t *testing.T, opts BPayWebhookOptions, secret, body string) (*httptest.ResponseRecorder, []*BPayNotificationData) {
+	t.Helper()
+	var received []*BPayNotificationData
+	handle := opts.Handle
+	opts.Handle = func(ctx context.Context, n *BPayNotificationData) error {
+		received = append(received, n)
+		if handle != nil {
+			return handle(ctx, n)
+		}
+		return nil
+	}
+	opts.Secret = testCallbackSecret
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/bpay", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(BPaySecretHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	NewBPayWebhookHandler(opts).ServeHTTP(rec, req)
+	return rec, received
+}
+
+func TestBPayWebhookHandlerDeliversCallbacks(t *testing.T) {
+	rec, received := serveBPay(t, BPayWebhookOptions{}, testCallbackSecret,
+		`{"operationId":"REF-1","transactionId":"TX-1","status":"TS","amount":"50.00"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "OK", rec.Body.String())
+	require.Len(t, received, 1)
+	assert.Equal(t, BPayNotificationData{OperationID: "REF-1", TransactionID: "TX-1", Status: "TS", Amount: "50.00"}, *received[0])
+
+	rec, received = serveBPay(t, BPayWebhookOptions{}, testCallbackSecret,
+		`{"operationId":"REF-2","status":"TF","errorCode":"1","errorMessage":"insufficient balance"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, "failed payments are delivered too")
+	require.Len(t, received, 1)
+	assert.Equal(t, "TF", received[0].Status)
+	assert.Equal(t, "insufficient balance", received[0].ErrorMessage)
+}
+
+func TestBPayWebhookHandlerRejectsBadSecret(t *testing.T) {
+	for _, secret := range []string{"", "wrong"} {
+		logger := &recordingLogger{}
+		rec, received := serveBPay(t, BPayWebhookOptions{Logger: logger}, secret, `{"operationId":"REF-1","status":"TS"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, received)
+		assert.Equal(t, 1, logger.count("Rejected B-PAY callback"))
+	}
+}
+
+func TestBPayWebhookHandlerRejectsMalformedBody(t *testing.T) {
+	for _, body := range []string{`{"operationId":`, `[]`, `{"status":"TS"}`, `{"operationId":"REF-1"}`} {
+		rec, received := serveBPay(t, BPayWebhookOptions{}, testCallbackSecret, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Empty(t, received)
+	}
+}
+
+func TestBPayWebhookHandlerCallbackError(t *testing.T) {
+	rec, received := serveBPay(t, BPayWebhookOptions{
+		Handle: func(context.Context, *BPayNotificationData) error { return errors.New("db down") },
+	}, testCallbackSecret, `{"operationId":"REF-1","status":"TS"}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Len(t, received, 1)
+}
+
+func TestNewBPayWebhookHandlerRequiresSecret(t *testing.T) {
+	assert.Panics(t, func() {
+		NewBPayWebhookHandler(BPayWebhookOptions{Handle: func(context.Context, *BPayNotificationData) error { return nil }})
+	})
+}
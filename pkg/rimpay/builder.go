@@ -0,0 +1,280 @@
+package rimpay
+
+import (
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// DefaultPaymentExpiry is the expiry ExpiresIn(0) gives a built request
+const DefaultPaymentExpiry = 15 * time.Minute
+
+// builderReferencePrefix starts the references builders generate
+const builderReferencePrefix = "REF"
+
+// paymentBuilder holds the fields the payment builders share
+type paymentBuilder struct {
+	phone       *phone.Phone
+	amount      money.Money
+	description string
+	reference   string
+	language    Language
+	metadata    map[string]interface{}
+	expires     bool
+	expiresIn   time.Duration
+	now         func() time.Time
+}
+
+func newPaymentBuilder() paymentBuilder {
+	return paymentBuilder{now: time.Now}
+}
+
+func (b *paymentBuilder) setMetadata(key string, value interface{}) {
+	if b.metadata == nil {
+		b.metadata = make(map[string]interface{})
+	}
+	b.metadata[key] = value
+}
+
+func (b *paymentBuilder) setExpiresIn(d time.Duration) {
+	if d <= 0 {
+		d = DefaultPaymentExpiry
+	}
+	b.expires = true
+	b.expiresIn = d
+}
+
+// defaults returns the language, reference, expiry and a copy of the
+// metadata of a request built for provider
+func (b *paymentBuilder) defaults(provider string) (Language, string, *time.Time, map[string]interface{}, error) {
+	language := b.language
+	if language == "" {
+		language = LanguageFrench
+	}
+
+	reference := b.reference
+	if reference == "" {
+		generated, err := generateReference(DefaultReferencePolicy(provider), provider, builderReferencePrefix)
+		if err != nil {
+			return "", "", nil, nil, err
+		}
+		reference = generated
+	}
+
+	var expiresAt *time.Time
+	if b.expires {
+		at := b.now().Add(b.expiresIn)
+		expiresAt = &at
+	}
+
+	var metadata map[string]interface{}
+	if b.metadata != nil {
+		metadata = make(map[string]interface{}, len(b.metadata))
+		for key, value := range b.metadata {
+			metadata[key] = value
+		}
+	}
+	return language, reference, expiresAt, metadata, nil
+}
+
+// BPayPaymentBuilder builds a BPayPaymentRequest. Setters return the
+// builder, so calls chain; Build may be called any number of times.
+type BPayPaymentBuilder struct {
+	paymentBuilder
+	passcode      string
+	mode          BPayMode
+	operationType BPayOperationType
+}
+
+// NewBPayPayment starts a B-PAY payment request
+func NewBPayPayment() *BPayPaymentBuilder {
+	return &BPayPaymentBuilder{paymentBuilder: newPaymentBuilder()}
+}
+
+// Amount sets the amount
+func (b *BPayPaymentBuilder) Amount(amount money.Money) *BPayPaymentBuilder {
+	b.amount = amount
+	return b
+}
+
+// Phone sets the customer's phone number
+func (b *BPayPaymentBuilder) Phone(number *phone.Phone) *BPayPaymentBuilder {
+	b.phone = number
+	return b
+}
+
+// Reference sets the reference; without one Build generates it
+func (b *BPayPaymentBuilder) Reference(reference string) *BPayPaymentBuilder {
+	b.reference = reference
+	return b
+}
+
+// Description sets the description
+func (b *BPayPaymentBuilder) Description(description string) *BPayPaymentBuilder {
+	b.description = description
+	return b
+}
+
+// Passcode sets the customer's passcode
+func (b *BPayPaymentBuilder) Passcode(passcode string) *BPayPaymentBuilder {
+	b.passcode = passcode
+	return b
+}
+
+// Mode sets the B-PAY mode
+func (b *BPayPaymentBuilder) Mode(mode BPayMode) *BPayPaymentBuilder {
+	b.mode = mode
+	return b
+}
+
+// OperationType sets the B-PAY operation type
+func (b *BPayPaymentBuilder) OperationType(operationType BPayOperationType) *BPayPaymentBuilder {
+	b.operationType = operationType
+	return b
+}
+
+// Language sets the language; it defaults to French
+func (b *BPayPaymentBuilder) Language(language Language) *BPayPaymentBuilder {
+	b.language = language
+	return b
+}
+
+// Metadata sets a metadata entry
+func (b *BPayPaymentBuilder) Metadata(key string, value interface{}) *BPayPaymentBuilder {
+	b.setMetadata(key, value)
+	return b
+}
+
+// ExpiresIn makes built requests expire d after Build, or
+// DefaultPaymentExpiry after it when d is 0. Requests do not expire
+// otherwise.
+func (b *BPayPaymentBuilder) ExpiresIn(d time.Duration) *BPayPaymentBuilder {
+	b.setExpiresIn(d)
+	return b
+}
+
+// Build fills in the defaults and returns a new validated request
+func (b *BPayPaymentBuilder) Build() (*BPayPaymentRequest, error) {
+	language, reference, expiresAt, metadata, err := b.defaults(ProviderBPay)
+	if err != nil {
+		return nil, err
+	}
+	request := &BPayPaymentRequest{
+		PhoneNumber:   b.phone,
+		Amount:        b.amount,
+		Description:   b.description,
+		Reference:     reference,
+		Passcode:      b.passcode,
+		Metadata:      metadata,
+		OperationType: b.operationType,
+		Mode:          b.mode,
+		Language:      language,
+		ExpiresAt:     expiresAt,
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// MasrviPaymentBuilder builds a MasrviPaymentRequest. Setters return the
+// builder, so calls chain; Build may be called any number of times.
+type MasrviPaymentBuilder struct {
+	paymentBuilder
+	callbackURL string
+	returnURL   string
+}
+
+// NewMasrviPayment starts a MASRVI payment request
+func NewMasrviPayment() *MasrviPaymentBuilder {
+	return &MasrviPaymentBuilder{paymentBuilder: newPaymentBuilder()}
+}
+
+// Amount sets the amount
+func (b *MasrviPaymentBuilder) Amount(amount money.Money) *MasrviPaymentBuilder {
+	b.amount = amount
+	return b
+}
+
+// Phone sets the customer's phone number
+func (b *MasrviPaymentBuilder) Phone(number *phone.Phone) *MasrviPaymentBuilder {
+	b.phone = number
+	return b
+}
+
+// Reference sets the reference; without one Build generates it
+func (b *MasrviPaymentBuilder) Reference(reference string) *MasrviPaymentBuilder {
+	b.reference = reference
+	return b
+}
+
+// Description sets the description
+func (b *MasrviPaymentBuilder) Description(description string) *MasrviPaymentBuilder {
+	b.description = description
+	return b
+}
+
+// CallbackURL sets the webhook URL
+func (b *MasrviPaymentBuilder) CallbackURL(callbackURL string) *MasrviPaymentBuilder {
+	b.callbackURL = callbackURL
+	return b
+}
+
+// ReturnURL sets the URL the customer returns to after paying
+func (b *MasrviPaymentBuilder) ReturnURL(returnURL string) *MasrviPaymentBuilder {
+	b.returnURL = returnURL
+	return b
+}
+
+// Language sets the language; it defaults to French
+func (b *MasrviPaymentBuilder) Language(language Language) *MasrviPaymentBuilder {
+	b.language = language
+	return b
+}
+
+// Metadata sets a metadata entry
+func (b *MasrviPaymentBuilder) Metadata(key string, value interface{}) *MasrviPaymentBuilder {
+	b.setMetadata(key, value)
+	return b
+}
+
+// ExpiresIn makes built requests expire d after Build, or
+// DefaultPaymentExpiry after it when d is 0. Requests do not expire
+// otherwise.
+func (b *MasrviPaymentBuilder) ExpiresIn(d time.Duration) *MasrviPaymentBuilder {
+	b.setExpiresIn(d)
+	return b
+}
+
+// Build fills in the defaults and returns a new validated request
+func (b *MasrviPaymentBuilder) Build() (*MasrviPaymentRequest, error) {
+	language, reference, expiresAt, metadata, err := b.defaults(ProviderMasrvi)
+	if err != nil {
+		return nil, err
+	}
+	request := &MasrviPaymentRequest{
+		PhoneNumber: b.phone,
+		Amount:      b.amount,
+		Description: b.description,
+		Reference:   reference,
+		CallbackURL: b.callbackURL,
+		ReturnURL:   b.returnURL,
+		Metadata:    metadata,
+		Language:    language,
+		ExpiresAt:   expiresAt,
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// copyTime returns a copy of t, or nil
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	at := *t
+	return &at
+}
@@ -0,0 +1,124 @@
+package rimpay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builderPhone(t *testing.T) *phone.Phone {
+	t.Helper()
+	p, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return p
+}
+
+func TestBPayPaymentBuilderDefaults(t *testing.T) {
+	request, err := NewBPayPayment().
+		Amount(money.NewMRU(5000)).
+		Phone(builderPhone(t)).
+		Description("Order 7").
+		Passcode("1234").
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, LanguageFrench, request.Language)
+	assert.Regexp(t, `^REF_[0-9A-F]{12}$`, request.Reference)
+	assert.Nil(t, request.ExpiresAt, "requests do not expire unless asked to")
+	assert.Nil(t, request.Metadata)
+
+	generic := request.ToGenericRequest()
+	assert.Equal(t, LanguageFrench, generic.Language)
+	assert.Equal(t, request.Reference, generic.Reference)
+}
+
+func TestBPayPaymentBuilderExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	builder := NewBPayPayment().
+		Amount(money.NewMRU(5000)).
+		Phone(builderPhone(t)).
+		Reference("ORDER-7").
+		Description("Order 7").
+		Mode(BPayModeUSSDPush).
+		Language(LanguageArabic).
+		ExpiresIn(0)
+	builder.now = func() time.Time { return now }
+
+	request, err := builder.Build()
+	require.NoError(t, err)
+	require.NotNil(t, request.ExpiresAt)
+	assert.Equal(t, now.Add(15*time.Minute), *request.ExpiresAt)
+	assert.Equal(t, "ORDER-7", request.Reference)
+	assert.Equal(t, LanguageArabic, request.Language)
+
+	request, err = builder.ExpiresIn(time.Hour).Build()
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), *request.ExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), *request.ToGenericRequest().ExpiresAt)
+}
+
+func TestBPayPaymentBuilderBuildsIndependentRequests(t *testing.T) {
+	builder := NewBPayPayment().
+		Amount(money.NewMRU(5000)).
+		Phone(builderPhone(t)).
+		Description("Order 7").
+		Passcode("1234").
+		Metadata("order", "7").
+		ExpiresIn(time.Minute)
+
+	first, err := builder.Build()
+	require.NoError(t, err)
+	second, err := builder.Build()
+	require.NoError(t, err)
+
+	first.Metadata["order"] = "changed"
+	*first.ExpiresAt = first.ExpiresAt.Add(time.Hour)
+	assert.Equal(t, "7", second.Metadata["order"])
+	assert.True(t, second.ExpiresAt.Before(*first.ExpiresAt))
+	assert.NotEqual(t, first.Reference, second.Reference, "each build generates its own reference")
+
+	builder.Metadata("order", "8")
+	assert.Equal(t, "7", second.Metadata["order"], "later setters do not reach built requests")
+}
+
+func TestBPayPaymentBuilderValidation(t *testing.T) {
+	_, err := NewBPayPayment().
+		Amount(money.NewMRU(5000)).
+		Reference("ORDER #7").
+		Passcode("12").
+		Build()
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, map[string]string{
+		"phone_number": "is required",
+		"description":  "cannot be empty",
+		"reference":    `contains ' '; bpay accepts only letters, digits, - and _`,
+		"passcode":     "must be 4 or 6 digits",
+	}, errs.Fields())
+}
+
+func TestMasrviPaymentBuilder(t *testing.T) {
+	builder := NewMasrviPayment().
+		Amount(money.NewMRU(5000)).
+		Phone(builderPhone(t)).
+		Description("Order 7").
+		CallbackURL("https://shop.test/callback")
+
+	_, err := builder.Build()
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, map[string]string{"return_url": "cannot be empty"}, errs.Fields())
+
+	request, err := builder.ReturnURL("https://shop.test/done").Build()
+	require.NoError(t, err)
+	assert.Equal(t, "https://shop.test/callback", request.CallbackURL)
+	assert.Equal(t, "https://shop.test/done", request.ReturnURL)
+	assert.Equal(t, LanguageFrench, request.Language)
+	assert.Regexp(t, `^REF_[0-9A-F]{12}$`, request.Reference)
+	assert.NoError(t, request.Validate())
+}
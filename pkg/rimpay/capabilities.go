@@ -0,0 +1,96 @@
+package rimpay
+
+import "sort"
+
+// Capabilities describes what a provider supports, so callers need not try
+// an operation and check for a not-supported error
+type Capabilities struct {
+	// SupportsRefund is set when Refund can reverse a payment
+	SupportsRefund bool `json:"supports_refund"`
+	// SupportsCancel is set when a pending payment can be cancelled
+	SupportsCancel bool `json:"supports_cancel"`
+	// SupportsStatusQuery is set when GetPaymentStatus asks the provider
+	SupportsStatusQuery bool `json:"supports_status_query"`
+	// SupportsWebhooks is set when the provider sends status notifications
+	SupportsWebhooks bool `json:"supports_webhooks"`
+	// RequiresRedirect is set when the customer pays on the provider's page
+	RequiresRedirect bool `json:"requires_redirect"`
+	// SupportsPasscode is set when the customer confirms with a passcode
+	SupportsPasscode bool `json:"supports_passcode"`
+}
+
+// Capability names one of the Capabilities flags
+type Capability string
+
+const (
+	// CapabilityRefund is Capabilities.SupportsRefund
+	CapabilityRefund Capability = "refund"
+	// CapabilityCancel is Capabilities.SupportsCancel
+	CapabilityCancel Capability = "cancel"
+	// CapabilityStatusQuery is Capabilities.SupportsStatusQuery
+	CapabilityStatusQuery Capability = "status_query"
+	// CapabilityWebhooks is Capabilities.SupportsWebhooks
+	CapabilityWebhooks Capability = "webhooks"
+	// CapabilityRedirect is Capabilities.RequiresRedirect
+	CapabilityRedirect Capability = "redirect"
+	// CapabilityPasscode is Capabilities.SupportsPasscode
+	CapabilityPasscode Capability = "passcode"
+)
+
+// Has reports whether the capability is set; unknown capabilities are not
+func (c Capabilities) Has(capability Capability) bool {
+	switch capability {
+	case CapabilityRefund:
+		return c.SupportsRefund
+	case CapabilityCancel:
+		return c.SupportsCancel
+	case CapabilityStatusQuery:
+		return c.SupportsStatusQuery
+	case CapabilityWebhooks:
+		return c.SupportsWebhooks
+	case CapabilityRedirect:
+		return c.RequiresRedirect
+	case CapabilityPasscode:
+		return c.SupportsPasscode
+	default:
+		return false
+	}
+}
+
+// SameFlow reports whether the customer completes payments of both
+// providers the same way: on a redirect page, with a passcode, or neither.
+// Failover only moves a payment between providers with the same flow.
+func (c Capabilities) SameFlow(other Capabilities) bool {
+	return c.RequiresRedirect == other.RequiresRedirect && c.SupportsPasscode == other.SupportsPasscode
+}
+
+// accepts reports whether the provider can take request; a payment carrying
+// a passcode needs a provider that supports one
+func (c Capabilities) accepts(request *PaymentRequest) bool {
+	return request.Passcode == "" || c.SupportsPasscode
+}
+
+// ProviderCapabilities returns the capabilities of the named provider
+func (c *Client) ProviderCapabilities(name string) (Capabilities, error) {
+	provider, err := c.provider(name)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return provider.Capabilities(), nil
+}
+
+// ProvidersWithCapability returns the names of the registered providers
+// that have capability, sorted
+func (c *Client) ProvidersWithCapability(capability Capability) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var names []string
+	for name, provider := range c.providers {
+		if provider.Capabilities().Has(capability) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,97 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	passcodeFlow = Capabilities{SupportsRefund: true, SupportsStatusQuery: true, SupportsWebhooks: true, SupportsPasscode: true}
+	redirectFlow = Capabilities{SupportsRefund: true, SupportsWebhooks: true, RequiresRedirect: true}
+)
+
+func TestCapabilitiesHas(t *testing.T) {
+	assert.True(t, passcodeFlow.Has(CapabilityPasscode))
+	assert.True(t, passcodeFlow.Has(CapabilityStatusQuery))
+	assert.False(t, passcodeFlow.Has(CapabilityRedirect))
+	assert.False(t, passcodeFlow.Has(CapabilityCancel))
+	assert.True(t, redirectFlow.Has(CapabilityRedirect))
+	assert.False(t, redirectFlow.Has(Capability("teleport")))
+
+	assert.False(t, passcodeFlow.SameFlow(redirectFlow))
+	assert.True(t, redirectFlow.SameFlow(Capabilities{RequiresRedirect: true}), "only the customer flow is compared")
+}
+
+func TestClientProviderCapabilities(t *testing.T) {
+	client := newFailoverTestClient(t, nil,
+		&namedProvider{name: ProviderBPay, capabilities: passcodeFlow},
+		&namedProvider{name: ProviderMasrvi, capabilities: redirectFlow},
+		&namedProvider{name: ProviderClick, capabilities: Capabilities{RequiresRedirect: true}},
+	)
+
+	capabilities, err := client.ProviderCapabilities(ProviderMasrvi)
+	require.NoError(t, err)
+	assert.Equal(t, redirectFlow, capabilities)
+
+	_, err = client.ProviderCapabilities("unknown")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+
+	assert.Equal(t, []string{ProviderClick, ProviderMasrvi}, client.ProvidersWithCapability(CapabilityRedirect))
+	assert.Equal(t, []string{ProviderBPay, ProviderMasrvi}, client.ProvidersWithCapability(CapabilityRefund))
+	assert.Empty(t, client.ProvidersWithCapability(CapabilityCancel))
+}
+
+func TestFailoverKeepsThePaymentFlow(t *testing.T) {
+	masrvi := &failingProvider{
+		namedProvider: namedProvider{name: ProviderMasrvi, capabilities: redirectFlow},
+		err:           NewPaymentError(ErrorCodeProviderError, "service unavailable", ProviderMasrvi, true),
+	}
+	bpay := &failingProvider{namedProvider: namedProvider{name: ProviderBPay, capabilities: passcodeFlow}}
+	click := &namedProvider{name: ProviderClick, capabilities: Capabilities{RequiresRedirect: true, SupportsWebhooks: true}}
+	client := newFailoverTestClient(t, []string{ProviderMasrvi, ProviderBPay, ProviderClick}, masrvi, bpay, click)
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, ProviderClick, resp.Provider)
+	assert.Zero(t, bpay.calls, "a redirect payment never fails over to a passcode flow")
+	assert.Equal(t, []string{ProviderMasrvi, ProviderClick}, resp.Metadata[MetadataFailoverPath])
+	assert.Equal(t, []string{ProviderBPay}, resp.Metadata[MetadataFailoverIncompatible])
+
+	client = newFailoverTestClient(t, []string{ProviderMasrvi, ProviderBPay}, masrvi, bpay)
+	_, err = client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, []string{ProviderBPay}, paymentErr.Details[MetadataFailoverIncompatible])
+	assert.Zero(t, bpay.calls)
+}
+
+func TestPasscodePaymentsOnlyGoToPasscodeProviders(t *testing.T) {
+	request := failoverRequest()
+	request.Passcode = "1234"
+
+	masrvi := &failingProvider{namedProvider: namedProvider{name: ProviderMasrvi, capabilities: redirectFlow}}
+	client := newFailoverTestClient(t, []string{ProviderMasrvi, ProviderBPay}, masrvi,
+		&namedProvider{name: ProviderBPay, capabilities: passcodeFlow})
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderBPay, resp.Provider)
+	assert.Zero(t, masrvi.calls)
+
+	var seen []string
+	strategy := strategyFunc(func(_ context.Context, _ *PaymentRequest, available []string) (string, error) {
+		seen = available
+		return ProviderBPay, nil
+	})
+	client = newStrategyTestClient(t, strategy,
+		&namedProvider{name: ProviderBPay, capabilities: passcodeFlow},
+		&namedProvider{name: ProviderMasrvi, capabilities: redirectFlow},
+	)
+	routed := routedRequest(t, "22334455", 1000)
+	routed.Passcode = "1234"
+	_, err = client.ProcessRouted(context.Background(), routed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ProviderBPay}, seen)
+}
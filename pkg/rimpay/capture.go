@@ -0,0 +1,52 @@
+package rimpay
+
+import (
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// HTTPExchange is a sanitized copy of one request to a provider and its
+// response, as passed to an HTTPRecorder
+type HTTPExchange = types.HTTPExchange
+
+// HTTPRecorder receives every provider HTTP exchange once set with
+// Client.WithHTTPRecorder or HTTPConfig.Recorder
+type HTTPRecorder = types.HTTPRecorder
+
+// RingRecorder is an HTTPRecorder that keeps the most recent exchanges
+type RingRecorder = common.RingRecorder
+
+// NewRingRecorder creates a RingRecorder holding up to size exchanges
+func NewRingRecorder(size int) *RingRecorder {
+	return common.NewRingRecorder(size)
+}
+
+// SetRawResponseLimit caps the bytes of an undecodable provider response
+// attached to errors as the DetailRawResponse detail (default 2048); 0
+// disables the detail
+func SetRawResponseLimit(limit int) {
+	common.SetRawResponseLimit(limit)
+}
+
+// recorderSetter is implemented by HTTP clients that support capture
+type recorderSetter interface {
+	SetRecorder(HTTPRecorder)
+}
+
+// WithHTTPRecorder passes sanitized copies of every provider request and
+// response to recorder: URLs, headers and bodies have credentials, tokens
+// and passcodes masked. It applies to the shared HTTP client immediately and
+// to providers with their own HTTP config when they are created; nil stops
+// recording.
+func (c *Client) WithHTTPRecorder(recorder HTTPRecorder) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Copy the configuration, which readers use without holding the lock
+	config := *c.config
+	config.HTTP.Recorder = recorder
+	c.config = &config
+	if setter, ok := c.httpClient.(recorderSetter); ok {
+		setter.SetRecorder(recorder)
+	}
+	return c
+}
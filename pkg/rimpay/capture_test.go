@@ -0,0 +1,40 @@
+package rimpay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWithHTTPRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errorCode":"0"}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: server.URL, Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	recorder := NewRingRecorder(4)
+	client.WithHTTPRecorder(recorder)
+
+	_, err = client.httpClient.Do(context.Background(), &HTTPRequest{Method: "GET", URL: server.URL + "/status"})
+	require.NoError(t, err)
+	require.Len(t, recorder.Exchanges(), 1)
+	assert.Equal(t, http.StatusOK, recorder.Exchanges()[0].StatusCode)
+
+	override := client.withSharedHTTP(ProviderConfig{HTTP: &HTTPConfig{Timeout: time.Second}})
+	assert.Equal(t, recorder, override.HTTP.Recorder, "providers with their own HTTP config record too")
+
+	client.WithHTTPRecorder(nil)
+	_, err = client.httpClient.Do(context.Background(), &HTTPRequest{Method: "GET", URL: server.URL + "/status"})
+	require.NoError(t, err)
+	assert.Len(t, recorder.Exchanges(), 1)
+}
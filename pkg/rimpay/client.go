@@ -2,8 +2,13 @@ package rimpay
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
 )
 
 // Provider constants
@@ -11,6 +16,9 @@ const (
 	ProviderBPay   = "bpay"
 	ProviderMasrvi = "masrvi"
 	ProviderClick  = "click"
+	ProviderSedad  = "sedad"
+	// ProviderMock is the in-process sandbox provider for integration tests
+	ProviderMock = "mock"
 
 	// Error message constants
 	providerNotAvailableMsg = "provider %s not available"
@@ -21,6 +29,8 @@ var (
 	createBPayProvider   func(ProviderConfig, Logger) (PaymentProvider, error)
 	createMasrviProvider func(ProviderConfig, Logger) (PaymentProvider, error)
 	createClickProvider  func(ProviderConfig, Logger) (PaymentProvider, error)
+	createSedadProvider  func(ProviderConfig, Logger) (PaymentProvider, error)
+	createMockProvider   func(ProviderConfig, Logger) (PaymentProvider, error)
 )
 
 // RegisterBPayProvider registers the B-PAY provider factory
@@ -38,12 +48,72 @@ func RegisterClickProvider(factory func(ProviderConfig, Logger) (PaymentProvider
 	createClickProvider = factory
 }
 
-// Client represents the main payment client
+// RegisterSedadProvider registers the Sedad provider factory
+func RegisterSedadProvider(factory func(ProviderConfig, Logger) (PaymentProvider, error)) {
+	createSedadProvider = factory
+}
+
+// RegisterMockProvider registers the mock provider factory
+func RegisterMockProvider(factory func(ProviderConfig, Logger) (PaymentProvider, error)) {
+	createMockProvider = factory
+}
+
+// RegisterSensitiveQueryParams adds query parameter names whose values are
+// masked wherever provider URLs are logged or included in errors. Merchant
+// IDs, tokens, secrets and passwords are masked by default.
+func RegisterSensitiveQueryParams(names ...string) {
+	common.RegisterSensitiveQueryParams(names...)
+}
+
+// Client represents the main payment client. Create it with NewClient; the
+// zero value and a nil *Client are not usable and their methods panic.
 type Client struct {
 	providers map[string]PaymentProvider
 	config    *Config
-	logger    Logger
-	mu        sync.RWMutex
+	// configs holds the ProviderConfig given to AddXProvider, which takes
+	// precedence over the matching section of config
+	configs    map[string]ProviderConfig
+	logger     Logger
+	httpClient HTTPClient
+	limiter    *priorityLimiters
+	stats      *statsCollector
+	alerter    *sloAlerter
+	operators  phone.PortabilityResolver
+	health     *healthCache
+	statuses   *statusCache
+	store      TransactionStore
+	metrics    MetricsCollector
+	tracer     Tracer
+	mu         sync.RWMutex
+
+	// credentials is set with WithCredentialsProvider
+	credentials CredentialsProvider
+
+	// amountLookup is set with WithAmountLookup
+	amountLookup AmountLookupFunc
+
+	// schedules keeps payments scheduled with SchedulePayment, which the
+	// scheduler runs once StartScheduler is called
+	schedules ScheduleStore
+	scheduler *paymentScheduler
+
+	// links keeps payment links made with CreatePaymentLink
+	links PaymentLinkStore
+
+	// subscriptions are charged by the scheduler too
+	subscriptions *subscriptionBook
+
+	// async runs ProcessPaymentAsync and ProcessPaymentCallback payments
+	async *asyncPool
+
+	// reloadMu serializes ReloadConfig calls
+	reloadMu sync.Mutex
+
+	// closed is set atomically by Close
+	closed uint32
+
+	// logSamples counts the payments considered for log sampling
+	logSamples uint32
 }
 
 // NewClient creates a new payment client
@@ -56,38 +126,106 @@ func NewClient(config *Config) (*Client, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	// Create a default logger if none provided
-	logger := newDefaultLogger(config.Logging)
+	logger, err := newDefaultLogger(config.Logging)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging config: %w", err)
+	}
+
+	httpClient, err := common.NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http config: %w", err)
+	}
+
+	stats := newStatsCollector(config.Alerts.Window, config.Alerts.StatsSamples)
 
 	return &Client{
-		providers: make(map[string]PaymentProvider),
-		config:    config,
-		logger:    logger,
+		providers:  make(map[string]PaymentProvider),
+		config:     config,
+		configs:    make(map[string]ProviderConfig),
+		logger:     logger,
+		httpClient: httpClient,
+		limiter:    newPriorityLimiters(config.Priority),
+		stats:      stats,
+		alerter:    newSLOAlerter(config.Alerts, stats.window, logger),
+		operators:  newOperatorResolver(config.Portability),
+		health:     newHealthCache(config.Health),
+		statuses:   newStatusCache(config.StatusCache),
+		schedules:  NewMemoryScheduleStore(),
+		scheduler:  newPaymentScheduler(),
+		links:      NewMemoryLinkStore(),
+
+		subscriptions: newSubscriptionBook(),
+		async:         newAsyncPool(),
 	}, nil
 }
 
-// newDefaultLogger creates a default logger
-func newDefaultLogger(config LoggingConfig) Logger {
-	return &simpleLogger{}
-}
+// invoke runs a provider call under the priority limiter and records its
+// latency and outcome for stats and SLO alerts
+func (c *Client) invoke(ctx context.Context, provider string, call func() error) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
 
-// Simple logger implementation
-type simpleLogger struct{}
+	release, err := c.limiter.acquire(ctx, provider)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-func (l *simpleLogger) Debug(msg string, fields ...interface{}) {
-	fmt.Printf("[DEBUG] %s %v\n", msg, fields)
-}
+	start := c.stats.now()
+	err = call()
+	latency := c.stats.now().Sub(start)
+
+	c.stats.record(provider, latency, err)
+	if c.alerter != nil {
+		c.alerter.observe(provider, latency, c.stats.snapshot(provider), c.stats.now())
+	}
 
-func (l *simpleLogger) Info(msg string, fields ...interface{}) {
-	fmt.Printf("[INFO] %s %v\n", msg, fields)
+	return err
 }
 
-func (l *simpleLogger) Warn(msg string, fields ...interface{}) {
-	fmt.Printf("[WARN] %s %v\n", msg, fields)
+// withSharedHTTP injects the client-wide HTTP client into a provider config
+// unless the caller supplied its own client or a per-provider HTTP or TLS
+// override, the provider TLS override into its HTTP config,
+// the client-wide HTTP recorder into such an override, the client-wide
+// retry policy unless the provider overrides it, and the metrics collector,
+// tracer and credentials provider unless the caller supplied them
+func (c *Client) withSharedHTTP(config ProviderConfig) ProviderConfig {
+	return c.withShared(c.currentConfig(), config)
 }
 
-func (l *simpleLogger) Error(msg string, fields ...interface{}) {
-	fmt.Printf("[ERROR] %s %v\n", msg, fields)
+// withShared is withSharedHTTP for the client configuration clientConfig
+func (c *Client) withShared(clientConfig *Config, config ProviderConfig) ProviderConfig {
+	if config.CredentialsProvider == nil {
+		config.CredentialsProvider = c.credentialsProvider()
+	}
+	if config.Metrics == nil {
+		config.Metrics = c.metricsCollector()
+	}
+	if config.Tracer == nil {
+		config.Tracer = c.spanTracer()
+	}
+	if config.TLS != nil && config.HTTPClient == nil {
+		override := clientConfig.HTTP
+		if config.HTTP != nil {
+			override = *config.HTTP
+		}
+		override.TLS = config.TLS
+		config.HTTP = &override
+	}
+	if config.HTTPClient == nil && config.HTTP == nil {
+		config.HTTPClient = c.httpClient
+	}
+	if config.HTTP != nil && config.HTTP.Recorder == nil && clientConfig.HTTP.Recorder != nil {
+		override := *config.HTTP
+		override.Recorder = clientConfig.HTTP.Recorder
+		config.HTTP = &override
+	}
+	if config.Retry == nil && (!clientConfig.Retry.IsZero() || clientConfig.Retry.OnRetry != nil) {
+		retry := clientConfig.Retry
+		config.Retry = &retry
+	}
+	return config
 }
 
 // ProcessBPayPayment processes a payment using B-PAY provider
@@ -96,7 +234,7 @@ func (c *Client) ProcessBPayPayment(ctx context.Context, request *BPayPaymentReq
 		return nil, ErrInvalidRequest
 	}
 
-	provider, ok := c.providers[ProviderBPay]
+	provider, ok := c.registered(ProviderBPay)
 	if !ok {
 		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderBPay)
 	}
@@ -106,35 +244,127 @@ func (c *Client) ProcessBPayPayment(ctx context.Context, request *BPayPaymentReq
 		return nil, fmt.Errorf("provider %s does not implement BPayProvider interface", ProviderBPay)
 	}
 
-	return bpayProvider.ProcessBPayPayment(ctx, request)
+	if err := c.checkLimits(ProviderBPay, request.Amount, request.Reference); err != nil {
+		return nil, err
+	}
+
+	description, err := c.checkDetails(ProviderBPay, request.Description, request.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if description != request.Description {
+		sanitized := *request
+		sanitized.Description = description
+		request = &sanitized
+	}
+
+	result, err := c.invokePayment(ctx, ProviderBPay, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return bpayProvider.ProcessBPayPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
 }
 
 // ProcessMasrviPayment processes a payment using MASRVI provider
 func (c *Client) ProcessMasrviPayment(ctx context.Context, request *MasrviPaymentRequest) (*PaymentResponse, error) {
+	masrviProvider, request, err := c.checkMasrviPayment(request)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.invokePayment(ctx, ProviderMasrvi, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return masrviProvider.ProcessMasrviPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
+}
+
+// checkMasrviPayment returns the MASRVI provider and request, with its
+// description sanitized, after checking the request against the client's
+// limits
+func (c *Client) checkMasrviPayment(request *MasrviPaymentRequest) (MasrviProvider, *MasrviPaymentRequest, error) {
 	if request == nil {
-		return nil, ErrInvalidRequest
+		return nil, nil, ErrInvalidRequest
 	}
 
-	provider, ok := c.providers[ProviderMasrvi]
+	provider, ok := c.registered(ProviderMasrvi)
 	if !ok {
-		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderMasrvi)
+		return nil, nil, fmt.Errorf(providerNotAvailableMsg, ProviderMasrvi)
 	}
 
 	masrviProvider, ok := provider.(MasrviProvider)
 	if !ok {
-		return nil, fmt.Errorf("provider %s does not implement MasrviProvider interface", ProviderMasrvi)
+		return nil, nil, fmt.Errorf("provider %s does not implement MasrviProvider interface", ProviderMasrvi)
+	}
+
+	if err := c.checkLimits(ProviderMasrvi, request.Amount, request.Reference); err != nil {
+		return nil, nil, err
 	}
 
-	return masrviProvider.ProcessMasrviPayment(ctx, request)
+	description, err := c.checkDetails(ProviderMasrvi, request.Description, request.ExpiresAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if description != request.Description {
+		sanitized := *request
+		sanitized.Description = description
+		request = &sanitized
+	}
+	return masrviProvider, request, nil
+}
+
+// HandleBPayNotification maps a B-PAY status callback to a transaction
+// status and records it
+func (c *Client) HandleBPayNotification(notification *BPayNotificationData) (*TransactionStatus, error) {
+	status, err := c.verifyBPayNotification(notification)
+	if err == nil {
+		c.recordStatus(context.Background(), status)
+	}
+	return status, err
+}
+
+// verifyBPayNotification maps a B-PAY status callback without recording it
+func (c *Client) verifyBPayNotification(notification *BPayNotificationData) (*TransactionStatus, error) {
+	if notification == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	provider, ok := c.registered(ProviderBPay)
+	if !ok {
+		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderBPay)
+	}
+
+	handler, ok := provider.(BPayNotificationHandler)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not implement BPayNotificationHandler interface", ProviderBPay)
+	}
+	return handler.HandleNotification(notification)
 }
 
 // HandleMasrviNotification handles MASRVI webhook notifications
 func (c *Client) HandleMasrviNotification(notification *MasrviNotificationData) (*TransactionStatus, error) {
+	ctx := context.Background()
+	status, err := c.verifyMasrviNotification(ctx, notification)
+	// A mismatched or unverified amount fails the payment or keeps it
+	// pending, which is recorded as such
+	if err == nil || errors.Is(err, ErrAmountMismatch) || errors.Is(err, ErrAmountUnverified) {
+		c.recordStatus(ctx, status)
+	}
+	return status, err
+}
+
+// verifyMasrviNotification has the provider verify a MASRVI notification
+// and checks its amount, without recording it
+func (c *Client) verifyMasrviNotification(ctx context.Context, notification *MasrviNotificationData) (*TransactionStatus, error) {
 	if notification == nil {
 		return nil, ErrInvalidRequest
 	}
 
-	provider, ok := c.providers[ProviderMasrvi]
+	provider, ok := c.registered(ProviderMasrvi)
 	if !ok {
 		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderMasrvi)
 	}
@@ -144,7 +374,11 @@ func (c *Client) HandleMasrviNotification(notification *MasrviNotificationData)
 		return nil, fmt.Errorf("provider %s does not implement MasrviProvider interface", ProviderMasrvi)
 	}
 
-	return masrviProvider.HandleNotification(notification)
+	status, err := masrviProvider.HandleNotification(notification)
+	if err != nil {
+		return status, err
+	}
+	return status, c.checkNotificationAmount(ctx, ProviderMasrvi, status)
 }
 
 // ProcessClickPayment processes a payment using the CLICK provider
@@ -153,7 +387,7 @@ func (c *Client) ProcessClickPayment(ctx context.Context, request *ClickPaymentR
 		return nil, ErrInvalidRequest
 	}
 
-	provider, ok := c.providers[ProviderClick]
+	provider, ok := c.registered(ProviderClick)
 	if !ok {
 		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderClick)
 	}
@@ -163,7 +397,27 @@ func (c *Client) ProcessClickPayment(ctx context.Context, request *ClickPaymentR
 		return nil, fmt.Errorf("provider %s does not implement ClickProvider interface", ProviderClick)
 	}
 
-	return clickProvider.ProcessClickPayment(ctx, request)
+	if err := c.checkLimits(ProviderClick, request.Amount, request.Reference); err != nil {
+		return nil, err
+	}
+
+	description, err := c.checkDetails(ProviderClick, request.Description, nil)
+	if err != nil {
+		return nil, err
+	}
+	if description != request.Description {
+		sanitized := *request
+		sanitized.Description = description
+		request = &sanitized
+	}
+
+	result, err := c.invokePayment(ctx, ProviderClick, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return clickProvider.ProcessClickPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
 }
 
 // HandleClickNotification handles CLICK server-to-server notifications
@@ -172,7 +426,7 @@ func (c *Client) HandleClickNotification(notification *ClickNotificationData) (*
 		return nil, ErrInvalidRequest
 	}
 
-	provider, ok := c.providers[ProviderClick]
+	provider, ok := c.registered(ProviderClick)
 	if !ok {
 		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderClick)
 	}
@@ -182,80 +436,280 @@ func (c *Client) HandleClickNotification(notification *ClickNotificationData) (*
 		return nil, fmt.Errorf("provider %s does not implement ClickProvider interface", ProviderClick)
 	}
 
-	return clickProvider.HandleNotification(notification)
+	status, err := clickProvider.HandleNotification(notification)
+	if err == nil {
+		c.recordStatus(context.Background(), status)
+	}
+	return status, err
 }
 
-// ProcessPayment processes a payment using the generic interface (deprecated)
-func (c *Client) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+// ProcessSedadPayment processes a payment using the Sedad provider
+func (c *Client) ProcessSedadPayment(ctx context.Context, request *SedadPaymentRequest) (*PaymentResponse, error) {
 	if request == nil {
 		return nil, ErrInvalidRequest
 	}
 
-	// For backward compatibility, use the first available provider
-	c.mu.RLock()
-	var provider PaymentProvider
-	for _, p := range c.providers {
-		provider = p
-		break
+	provider, ok := c.registered(ProviderSedad)
+	if !ok {
+		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderSedad)
+	}
+
+	sedadProvider, ok := provider.(SedadProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not implement SedadProvider interface", ProviderSedad)
+	}
+
+	if err := c.checkLimits(ProviderSedad, request.Amount, request.Reference); err != nil {
+		return nil, err
+	}
+
+	description, err := c.checkDetails(ProviderSedad, request.Description, nil)
+	if err != nil {
+		return nil, err
+	}
+	if description != request.Description {
+		sanitized := *request
+		sanitized.Description = description
+		request = &sanitized
 	}
-	c.mu.RUnlock()
 
+	result, err := c.invokePayment(ctx, ProviderSedad, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return sedadProvider.ProcessSedadPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
+}
+
+// HandleSedadNotification handles Sedad webhook notifications
+func (c *Client) HandleSedadNotification(notification *SedadNotificationData) (*TransactionStatus, error) {
+	if notification == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	provider, ok := c.registered(ProviderSedad)
+	if !ok {
+		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderSedad)
+	}
+
+	sedadProvider, ok := provider.(SedadProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not implement SedadProvider interface", ProviderSedad)
+	}
+
+	status, err := sedadProvider.HandleNotification(notification)
+	if err == nil {
+		c.recordStatus(context.Background(), status)
+	}
+	return status, err
+}
+
+// ProcessPayment processes a payment using the generic interface (deprecated)
+func (c *Client) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	// For backward compatibility, use the default provider unless routing
+	// by operator is enabled
+	provider := c.routedProvider(ctx, request)
 	if provider == nil {
 		return nil, ErrProviderNotFound
 	}
 
-	// Check provider availability
-	if !provider.IsAvailable(ctx) {
+	return c.processPayment(ctx, provider, request)
+}
+
+// ProcessPaymentWithProvider processes a payment with the named provider.
+// The provider is looked up per call, so concurrent calls may target
+// different providers safely; unknown names return ErrProviderNotFound.
+func (c *Client) ProcessPaymentWithProvider(ctx context.Context, providerName string, request *PaymentRequest) (*PaymentResponse, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	provider, err := c.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkLimits(providerName, request.Amount, request.Reference); err != nil {
+		return nil, err
+	}
+
+	return c.processPayment(ctx, provider, request)
+}
+
+// processPayment sends request to provider once it is available
+func (c *Client) processPayment(ctx context.Context, provider PaymentProvider, request *PaymentRequest) (*PaymentResponse, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	request, err := c.prepareRequest(provider.Name(), request)
+	if err != nil {
+		return nil, err
+	}
+
+	// A dry run does not probe the provider either
+	if !c.currentConfig().DryRun && !provider.IsAvailable(ctx) {
 		return nil, fmt.Errorf("provider %s is not available", provider.Name())
 	}
 
-	// Process payment
-	return provider.ProcessPayment(ctx, request)
+	result, err := c.invokePayment(ctx, provider.Name(), request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return provider.ProcessPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
 }
 
-// GetPaymentStatus retrieves payment status from the first available provider
+// GetPaymentStatus retrieves payment status from the default provider
 func (c *Client) GetPaymentStatus(ctx context.Context, transactionID string) (*TransactionStatus, error) {
 	if transactionID == "" {
 		return nil, ErrInvalidRequest
 	}
 
-	c.mu.RLock()
-	var provider PaymentProvider
-	for _, p := range c.providers {
-		provider = p
-		break
-	}
-	c.mu.RUnlock()
-
+	provider := c.defaultProvider()
 	if provider == nil {
 		return nil, ErrProviderNotFound
 	}
 
-	return provider.GetPaymentStatus(ctx, transactionID)
+	return c.paymentStatus(ctx, provider, transactionID)
+}
+
+// GetPaymentStatusWithProvider retrieves payment status from the named
+// provider; like ProcessPaymentWithProvider it is safe for concurrent use
+// with different providers
+func (c *Client) GetPaymentStatusWithProvider(ctx context.Context, providerName, transactionID string) (*TransactionStatus, error) {
+	if transactionID == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	provider, err := c.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.paymentStatus(ctx, provider, transactionID)
+}
+
+// paymentStatus queries provider, unless the status cache holds the
+// transaction, and records the result in the store
+func (c *Client) paymentStatus(ctx context.Context, provider PaymentProvider, transactionID string) (*TransactionStatus, error) {
+	if c.statuses != nil && !forceRefresh(ctx) {
+		if cached, ok := c.statuses.get(provider.Name(), transactionID, c.scheduler.now()); ok {
+			return cached, nil
+		}
+	}
+
+	var result *TransactionStatus
+	err := c.invoke(ctx, provider.Name(), func() (err error) {
+		result, err = provider.GetPaymentStatus(ctx, transactionID)
+		return err
+	})
+	if err == nil {
+		c.recordStatus(ctx, result)
+		if c.statuses != nil {
+			c.statuses.put(provider.Name(), transactionID, result, c.scheduler.now())
+		}
+	}
+	return result, err
 }
 
 // AddProvider adds a payment provider to the client
 func (c *Client) AddProvider(name string, provider PaymentProvider) error {
+	return c.addProvider(name, provider, nil)
+}
+
+// addProvider registers provider under name together with the config it was
+// created from; a nil config defers to the client's Config
+func (c *Client) addProvider(name string, provider PaymentProvider, config *ProviderConfig) error {
 	if provider == nil {
 		return ErrInvalidProvider
 	}
 
 	c.mu.Lock()
 	c.providers[name] = provider
+	if config != nil {
+		c.configs[name] = *config
+	} else {
+		delete(c.configs, name)
+	}
 	c.mu.Unlock()
 
 	c.logger.Info("Provider added", "name", name, "provider", provider.Name())
 	return nil
 }
 
-// ListProviders returns list of available providers
+// ListProviders returns the names of the registered providers, sorted
 func (c *Client) ListProviders() []string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var providers []string
-	for name := range c.providers {
-		providers = append(providers, name)
+	return sortedProviderNames(c.providers)
+}
+
+// provider returns the registered provider with the given name
+func (c *Client) provider(name string) (PaymentProvider, error) {
+	provider, ok := c.registered(name)
+	if !ok {
+		return nil, fmt.Errorf("provider %q: %w", name, ErrProviderNotFound)
+	}
+	return provider, nil
+}
+
+// registered returns the provider registered under name, if any
+func (c *Client) registered(name string) (PaymentProvider, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	provider, ok := c.providers[name]
+	return provider, ok
+}
+
+// providerConfig returns the settings of the provider registered under
+// name: the config given to AddXProvider, otherwise its section of the
+// client's Config
+func (c *Client) providerConfig(name string) (ProviderConfig, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if config, ok := c.configs[name]; ok {
+		return config, true
+	}
+	return c.config.GetProviderConfig(name)
+}
+
+// currentConfig returns the configuration in effect, which ReloadConfig
+// replaces as a whole; callers must not modify it
+func (c *Client) currentConfig() *Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// defaultProvider returns the configured default provider when it is
+// registered, otherwise the first provider by name, so that the generic
+// ProcessPayment and GetPaymentStatus always pick the same provider
+func (c *Client) defaultProvider() PaymentProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if provider, ok := c.providers[c.config.DefaultProvider]; ok {
+		return provider
+	}
+	if names := sortedProviderNames(c.providers); len(names) > 0 {
+		return c.providers[names[0]]
+	}
+	return nil
+}
+
+func sortedProviderNames(providers map[string]PaymentProvider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
 	}
-	return providers
+	sort.Strings(names)
+	return names
 }
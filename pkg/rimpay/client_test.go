@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
 	"github.com/CatoSystems/rim-pay/pkg/money"
 	phone "github.com/CatoSystems/rim-pay/pkg/phone"
 	"github.com/stretchr/testify/assert"
@@ -146,3 +147,170 @@ func TestPaymentRequestValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestClientSharesHTTPClientAcrossProviders(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	var captured []ProviderConfig
+	capture := func(cfg ProviderConfig, _ Logger) (PaymentProvider, error) {
+		captured = append(captured, cfg)
+		return nil, ErrInvalidProvider
+	}
+
+	prevBPay, prevMasrvi := createBPayProvider, createMasrviProvider
+	defer func() { createBPayProvider, createMasrviProvider = prevBPay, prevMasrvi }()
+	createBPayProvider, createMasrviProvider = capture, capture
+
+	_ = client.AddBPayProvider(ProviderConfig{})
+	_ = client.AddMasrviProvider(ProviderConfig{})
+	_ = client.AddMasrviProvider(ProviderConfig{HTTP: &HTTPConfig{MaxIdleConns: 1}})
+
+	assert.Len(t, captured, 3)
+	assert.NotNil(t, captured[0].HTTPClient)
+	assert.Same(t, captured[0].HTTPClient, captured[1].HTTPClient)
+	shared, ok := captured[0].HTTPClient.(*common.DefaultHTTPClient)
+	assert.True(t, ok)
+	assert.Equal(t, config.HTTP, shared.Config())
+	assert.Nil(t, captured[2].HTTPClient, "per-provider override must not receive the shared client")
+}
+
+func TestClientRetryConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Retry = RetryConfig{MaxAttempts: 5, InitialDelay: time.Second, Multiplier: 1.5}
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	var captured []ProviderConfig
+	capture := func(cfg ProviderConfig, _ Logger) (PaymentProvider, error) {
+		captured = append(captured, cfg)
+		return nil, ErrInvalidProvider
+	}
+
+	prevBPay, prevMasrvi := createBPayProvider, createMasrviProvider
+	defer func() { createBPayProvider, createMasrviProvider = prevBPay, prevMasrvi }()
+	createBPayProvider, createMasrviProvider = capture, capture
+
+	noRetries := &RetryConfig{MaxAttempts: 1, Multiplier: 1}
+	_ = client.AddBPayProvider(ProviderConfig{Retry: noRetries})
+	_ = client.AddMasrviProvider(ProviderConfig{})
+
+	assert.Len(t, captured, 2)
+	assert.Same(t, noRetries, captured[0].Retry, "provider override wins over the global config")
+	assert.Equal(t, &config.Retry, captured[1].Retry)
+}
+
+func TestRetryConfigValidation(t *testing.T) {
+	newConfig := func() *Config {
+		config := DefaultConfig()
+		config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+		return config
+	}
+
+	config := newConfig()
+	config.Retry = RetryConfig{}
+	assert.NoError(t, config.Validate(), "zero retry config means defaults")
+
+	config = newConfig()
+	config.Retry = RetryConfig{OnRetry: func(int, error, time.Duration) {}}
+	bpay := config.Providers["bpay"]
+	bpay.Retry = &RetryConfig{OnRetry: func(int, error, time.Duration) {}}
+	config.Providers["bpay"] = bpay
+	assert.NoError(t, config.Validate(), "a retry hook alone keeps the default policy")
+
+	config = newConfig()
+	config.Retry.MaxAttempts = 0
+	assert.EqualError(t, config.Validate(), "retry max_attempts must be at least 1")
+
+	config = newConfig()
+	config.Retry.Multiplier = 0.5
+	assert.EqualError(t, config.Validate(), "retry multiplier must be at least 1.0")
+
+	config = newConfig()
+	bpay = config.Providers["bpay"]
+	bpay.Retry = &RetryConfig{MaxAttempts: 1, Multiplier: 0}
+	config.Providers["bpay"] = bpay
+	assert.EqualError(t, config.Validate(), "invalid config for provider 'bpay': retry multiplier must be at least 1.0")
+}
+
+func TestHTTPProxyConfigValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.HTTP.ProxyURL = "http://egress.internal:3128"
+	config.HTTP.DefaultHeaders = map[string]string{"X-Org-Id": "org-42"}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+	assert.Equal(t, config.HTTP, client.httpClient.(*common.DefaultHTTPClient).Config())
+
+	config.HTTP.ProxyURL = "ftp://egress.internal"
+	_, err = NewClient(config)
+	assert.EqualError(t, err, "invalid config: http proxy_url scheme must be http, https or socks5")
+
+	config.HTTP.ProxyURL = ""
+	bpay := config.Providers["bpay"]
+	bpay.HTTP = &HTTPConfig{ProxyURL: "http://"}
+	config.Providers["bpay"] = bpay
+	assert.EqualError(t, config.Validate(), "invalid config for provider 'bpay': http proxy_url must include a host")
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	newConfig := func(env Environment) *Config {
+		config := DefaultConfig()
+		config.Environment = env
+		config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+		return config
+	}
+
+	config := newConfig(EnvironmentSandbox)
+	config.HTTP.TLS = &TLSConfig{InsecureSkipVerify: true}
+	assert.NoError(t, config.Validate(), "sandboxes may skip verification")
+
+	config.Environment = EnvironmentProduction
+	assert.EqualError(t, config.Validate(), "tls insecure_skip_verify is not allowed in production")
+
+	config = newConfig(EnvironmentProduction)
+	bpay := config.Providers["bpay"]
+	bpay.TLS = &TLSConfig{InsecureSkipVerify: true}
+	config.Providers["bpay"] = bpay
+	assert.EqualError(t, config.Validate(), "invalid config for provider 'bpay': tls insecure_skip_verify is not allowed in production")
+
+	bpay.TLS = &TLSConfig{CertFile: "merchant.pem"}
+	config.Providers["bpay"] = bpay
+	assert.EqualError(t, config.Validate(), "invalid config for provider 'bpay': tls client certificate requires a key")
+
+	prevBPay := createBPayProvider
+	defer func() { createBPayProvider = prevBPay }()
+	createBPayProvider = func(ProviderConfig, Logger) (PaymentProvider, error) {
+		t.Fatal("the provider must not be created")
+		return nil, nil
+	}
+
+	config = newConfig(EnvironmentProduction)
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+	err = client.AddBPayProvider(ProviderConfig{HTTP: &HTTPConfig{TLS: &TLSConfig{InsecureSkipVerify: true}}})
+	assert.EqualError(t, err, "tls insecure_skip_verify is not allowed in production")
+}
+
+func TestProviderTLSOverrideGetsOwnHTTPConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.HTTP.UserAgent = "shop/1.0"
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+
+	bpayTLS := &TLSConfig{CertFile: "bpay.pem", KeyFile: "bpay.key"}
+	shared := client.withSharedHTTP(ProviderConfig{})
+	bpay := client.withSharedHTTP(ProviderConfig{TLS: bpayTLS})
+
+	assert.NotNil(t, shared.HTTPClient)
+	assert.Nil(t, bpay.HTTPClient, "a TLS override needs its own connection pool")
+	if assert.NotNil(t, bpay.HTTP) {
+		assert.Same(t, bpayTLS, bpay.HTTP.TLS)
+		assert.Equal(t, "shop/1.0", bpay.HTTP.UserAgent, "other settings come from Config.HTTP")
+	}
+	assert.Nil(t, config.HTTP.TLS, "the client-wide config is not modified")
+}
@@ -0,0 +1,60 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+)
+
+// Close releases the client's resources: it closes every provider that
+// implements io.Closer, which discards cached tokens and sessions, and
+// closes idle connections of the shared HTTP client. Asynchronous payments
+// are drained or cancelled first, under Config.Async.OnClose. Payments,
+// status queries and refunds made after Close fail with ErrClientClosed.
+// Closing a closed client does nothing. If ctx is done before every
+// provider is closed, Close returns its error.
+func (c *Client) Close(ctx context.Context) error {
+	// Drained payments still need an open client
+	if err := c.async.shutdown(ctx, c.currentConfig().Async.OnClose); err != nil {
+		c.logger.Warn("Cancelled asynchronous payments still running at close", "error", err)
+	}
+	if !atomic.CompareAndSwapUint32(&c.closed, 0, 1) {
+		return nil
+	}
+
+	c.mu.RLock()
+	providers := make(map[string]PaymentProvider, len(c.providers))
+	for name, provider := range c.providers {
+		providers[name] = provider
+	}
+	c.mu.RUnlock()
+
+	var firstErr error
+	for _, name := range sortedProviderNames(providers) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		closer, ok := providers[name].(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			c.logger.Warn("Failed to close provider", "provider", name, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("close provider %s: %w", name, err)
+			}
+		}
+	}
+
+	common.CloseIdleConnections(c.httpClient)
+	c.logger.Info("Client closed")
+	return firstErr
+}
+
+// isClosed reports whether Close has been called
+func (c *Client) isClosed() bool {
+	return atomic.LoadUint32(&c.closed) == 1
+}
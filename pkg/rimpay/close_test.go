@@ -0,0 +1,63 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingProvider counts Close calls and fails them with err
+type closingProvider struct {
+	namedProvider
+	closes int
+	err    error
+}
+
+func (p *closingProvider) Close() error {
+	p.closes++
+	return p.err
+}
+
+func TestClose(t *testing.T) {
+	bpay := &closingProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	masrvi := &closingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}
+	click := &namedProvider{name: ProviderClick}
+	client := newFailoverTestClient(t, nil, bpay, masrvi, click)
+
+	_, err := client.ProcessPayment(context.Background(), failoverRequest())
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close(context.Background()))
+	require.NoError(t, client.Close(context.Background()))
+	assert.Equal(t, 1, bpay.closes)
+	assert.Equal(t, 1, masrvi.closes)
+
+	for i := 0; i < 5; i++ {
+		_, err = client.ProcessPayment(context.Background(), failoverRequest())
+		assert.ErrorIs(t, err, ErrClientClosed)
+	}
+	_, err = client.ProcessPaymentWithProvider(context.Background(), ProviderClick, failoverRequest())
+	assert.ErrorIs(t, err, ErrClientClosed)
+	_, err = client.GetPaymentStatus(context.Background(), "TX-1")
+	assert.ErrorIs(t, err, ErrClientClosed)
+	_, err = client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestCloseReportsProviderErrors(t *testing.T) {
+	failure := errors.New("flush failed")
+	bpay := &closingProvider{namedProvider: namedProvider{name: ProviderBPay}, err: failure}
+	masrvi := &closingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}
+	client := newFailoverTestClient(t, nil, bpay, masrvi)
+
+	err := client.Close(context.Background())
+	assert.ErrorIs(t, err, failure)
+	assert.Contains(t, err.Error(), "close provider bpay")
+	assert.Equal(t, 1, masrvi.closes)
+
+	_, err = client.ProcessPayment(context.Background(), failoverRequest())
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
@@ -0,0 +1,165 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyConfig caps the payments one provider processes at once.
+// Payments over the cap queue for a free slot, or fail with
+// TOO_MANY_REQUESTS.
+type ConcurrencyConfig struct {
+	// MaxInFlight is the number of payments sent to the provider at once
+	MaxInFlight int `json:"max_in_flight"`
+	// MaxQueueWait is how long a payment waits for a free slot (0 fails at once)
+	MaxQueueWait time.Duration `json:"max_queue_wait"`
+	// MaxQueued is the number of payments that may wait at once (0 = unlimited)
+	MaxQueued int `json:"max_queued"`
+}
+
+// Validate checks that MaxInFlight is positive and the queue limits are not
+// negative
+func (c ConcurrencyConfig) Validate() error {
+	if c.MaxInFlight <= 0 {
+		return fmt.Errorf("concurrency max_in_flight must be positive")
+	}
+	if c.MaxQueueWait < 0 || c.MaxQueued < 0 {
+		return fmt.Errorf("concurrency max_queue_wait and max_queued cannot be negative")
+	}
+	return nil
+}
+
+// ProviderStats is a snapshot of the payments a provider is processing and
+// of its calls over the AlertsConfig.Window sliding window
+type ProviderStats struct {
+	// InFlight is the number of payments sent to the provider and not yet answered
+	InFlight int
+	// Queued is the number of payments waiting for a concurrency slot
+	Queued int
+	// MaxInFlight is the configured cap, 0 when the provider has none
+	MaxInFlight int
+
+	// Window is the period Calls, Errors and the latencies cover
+	Window time.Duration
+	// Calls counts the provider calls in the window, status checks and
+	// refunds included
+	Calls int
+	// Errors counts the failed calls; validation errors are the caller's
+	// and do not count
+	Errors    int
+	ErrorRate float64
+	// P50Latency and P95Latency are the median and 95th percentile
+	// response times in the window
+	P50Latency time.Duration
+	P95Latency time.Duration
+}
+
+// Stats returns the current load and recent calls of every registered
+// provider, for dashboards. The load counts payments only.
+func (c *Client) Stats() map[string]ProviderStats {
+	stats := make(map[string]ProviderStats)
+	for _, name := range c.ListProviders() {
+		stats[name] = c.providerStats(name)
+	}
+	return stats
+}
+
+// providerStats returns the load and recent calls of the named provider
+func (c *Client) providerStats(name string) ProviderStats {
+	stats := c.limiter.snapshot(name)
+	window := c.stats.percentiles(name)
+	stats.Window = c.stats.window
+	stats.Calls = window.Calls
+	stats.Errors = window.Errors
+	stats.ErrorRate = window.ErrorRate
+	stats.P50Latency = window.P50Latency
+	stats.P95Latency = window.P95Latency
+	return stats
+}
+
+// acquirePayment reserves one of the provider's payment slots and returns
+// the function releasing it
+func (c *Client) acquirePayment(ctx context.Context, provider string) (func(), error) {
+	var config ConcurrencyConfig
+	if providerConfig, ok := c.providerConfig(provider); ok && providerConfig.Concurrency != nil {
+		config = *providerConfig.Concurrency
+	}
+	return c.limiter.acquirePayment(ctx, provider, config)
+}
+
+// acquirePayment takes a payment slot, waiting up to MaxQueueWait when all
+// are in use. A config differing from the current one, as after
+// ReloadConfig, replaces the slots; payments holding one of the old slots
+// release it there.
+func (l *priorityLimiter) acquirePayment(ctx context.Context, config ConcurrencyConfig) (func(), error) {
+	l.mu.Lock()
+	if config != l.concurrency {
+		l.concurrency = config
+		l.payments = nil
+		if config.MaxInFlight > 0 {
+			l.payments = make(chan struct{}, config.MaxInFlight)
+		}
+	}
+	payments := l.payments
+	l.mu.Unlock()
+
+	if payments == nil {
+		return l.hold(payments), nil
+	}
+
+	select {
+	case payments <- struct{}{}:
+		return l.hold(payments), nil
+	default:
+	}
+
+	if config.MaxQueueWait <= 0 {
+		return nil, errTooManyRequests(l.provider, "no free slot")
+	}
+	if queued := atomic.AddInt64(&l.queued, 1); config.MaxQueued > 0 && queued > int64(config.MaxQueued) {
+		atomic.AddInt64(&l.queued, -1)
+		return nil, errTooManyRequests(l.provider, "queue full")
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+
+	timer := time.NewTimer(config.MaxQueueWait)
+	defer timer.Stop()
+	select {
+	case payments <- struct{}{}:
+		return l.hold(payments), nil
+	case <-timer.C:
+		return nil, errTooManyRequests(l.provider, "queue wait exceeded")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// hold counts a payment in flight until the returned function is called,
+// which also frees its slot of payments
+func (l *priorityLimiter) hold(payments chan struct{}) func() {
+	atomic.AddInt64(&l.inFlight, 1)
+	return func() {
+		atomic.AddInt64(&l.inFlight, -1)
+		if payments != nil {
+			<-payments
+		}
+	}
+}
+
+func (l *priorityLimiter) snapshot() ProviderStats {
+	l.mu.Lock()
+	maxInFlight := l.concurrency.MaxInFlight
+	l.mu.Unlock()
+	return ProviderStats{
+		InFlight:    int(atomic.LoadInt64(&l.inFlight)),
+		Queued:      int(atomic.LoadInt64(&l.queued)),
+		MaxInFlight: maxInFlight,
+	}
+}
+
+func errTooManyRequests(provider, reason string) error {
+	return NewPaymentError(ErrorCodeTooManyRequests,
+		fmt.Sprintf("too many payments in flight: %s", reason), provider, true)
+}
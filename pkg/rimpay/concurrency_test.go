@@ -0,0 +1,201 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowProvider takes delay to answer each payment, or waits for release
+// when delay is zero, and records the most payments it saw at once
+type slowProvider struct {
+	namedProvider
+	delay   time.Duration
+	release chan struct{}
+
+	inFlight, peak int32
+}
+
+func (p *slowProvider) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	n := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, n) {
+			break
+		}
+	}
+
+	if p.delay > 0 {
+		time.Sleep(p.delay)
+	} else {
+		<-p.release
+	}
+	return &PaymentResponse{TransactionID: request.Reference, Reference: request.Reference, Status: PaymentStatusPending}, nil
+}
+
+func newConcurrencyTestClient(t *testing.T, concurrency ConcurrencyConfig, provider *slowProvider) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = "slow"
+	config.Providers["slow"] = ProviderConfig{Enabled: true, BaseURL: "https://slow.test", Timeout: time.Second, Concurrency: &concurrency}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	require.NoError(t, client.AddProvider("slow", provider))
+	return client
+}
+
+func slowPayment(reference string) *PaymentRequest {
+	return &PaymentRequest{Reference: reference, Amount: money.NewMRU(1000)}
+}
+
+func TestConcurrencyLimitIsNeverExceeded(t *testing.T) {
+	provider := &slowProvider{namedProvider: namedProvider{name: "slow"}, delay: 5 * time.Millisecond}
+	client := newConcurrencyTestClient(t, ConcurrencyConfig{MaxInFlight: 4, MaxQueueWait: 10 * time.Second}, provider)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := client.ProcessPaymentWithProvider(context.Background(), "slow", slowPayment(fmt.Sprintf("REF-%d", i)))
+			errs <- err
+		}(i)
+	}
+
+	var sawQueue bool
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-done:
+			waiting = false
+		case <-time.After(time.Millisecond):
+			stats := client.Stats()["slow"]
+			assert.LessOrEqual(t, stats.InFlight, 4)
+			sawQueue = sawQueue || stats.Queued > 0
+		}
+	}
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&provider.peak))
+	assert.True(t, sawQueue, "payments over the cap queue")
+	stats := client.Stats()["slow"]
+	assert.Equal(t, 0, stats.InFlight)
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, 4, stats.MaxInFlight)
+}
+
+func TestConcurrencyLimitFailsFastAndSparesStatusChecks(t *testing.T) {
+	provider := &slowProvider{namedProvider: namedProvider{name: "slow"}, release: make(chan struct{})}
+	client := newConcurrencyTestClient(t, ConcurrencyConfig{MaxInFlight: 1}, provider)
+
+	first := make(chan error)
+	go func() {
+		_, err := client.ProcessPaymentWithProvider(context.Background(), "slow", slowPayment("REF-1"))
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return client.Stats()["slow"].InFlight == 1 }, time.Second, time.Millisecond)
+
+	_, err := client.ProcessPaymentWithProvider(context.Background(), "slow", slowPayment("REF-2"))
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, ErrorCodeTooManyRequests, paymentErr.Code)
+	assert.Equal(t, "slow", paymentErr.Provider)
+	assert.True(t, paymentErr.IsRetryable())
+
+	status, err := client.GetPaymentStatusWithProvider(context.Background(), "slow", "REF-1")
+	require.NoError(t, err, "status checks do not take a payment slot")
+	assert.Equal(t, "slow", status.TransactionID)
+
+	close(provider.release)
+	require.NoError(t, <-first)
+	assert.Zero(t, client.Stats()["slow"].InFlight)
+}
+
+func TestConcurrencyLimitFromAddBPayProvider(t *testing.T) {
+	provider := &slowProvider{namedProvider: namedProvider{name: ProviderBPay}, release: make(chan struct{})}
+	prevBPay := createBPayProvider
+	defer func() { createBPayProvider = prevBPay }()
+	createBPayProvider = func(ProviderConfig, Logger) (PaymentProvider, error) {
+		return provider, nil
+	}
+
+	// The Config section sets no cap; the one given to AddBPayProvider applies
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	require.NoError(t, client.AddBPayProvider(ProviderConfig{
+		Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Concurrency: &ConcurrencyConfig{MaxInFlight: 1},
+	}))
+
+	first := make(chan error)
+	go func() {
+		_, err := client.ProcessPaymentWithProvider(context.Background(), ProviderBPay, slowPayment("REF-1"))
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return client.Stats()[ProviderBPay].InFlight == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 1, client.Stats()[ProviderBPay].MaxInFlight)
+
+	_, err = client.ProcessPaymentWithProvider(context.Background(), ProviderBPay, slowPayment("REF-2"))
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, ErrorCodeTooManyRequests, paymentErr.Code)
+
+	close(provider.release)
+	require.NoError(t, <-first)
+}
+
+func TestConcurrencyQueueLimits(t *testing.T) {
+	provider := &slowProvider{namedProvider: namedProvider{name: "slow"}, release: make(chan struct{})}
+	client := newConcurrencyTestClient(t, ConcurrencyConfig{MaxInFlight: 1, MaxQueueWait: time.Second, MaxQueued: 1}, provider)
+
+	results := make(chan error, 2)
+	for _, reference := range []string{"REF-1", "REF-2"} {
+		go func(reference string) {
+			_, err := client.ProcessPaymentWithProvider(context.Background(), "slow", slowPayment(reference))
+			results <- err
+		}(reference)
+	}
+	require.Eventually(t, func() bool {
+		stats := client.Stats()["slow"]
+		return stats.InFlight == 1 && stats.Queued == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := client.ProcessPaymentWithProvider(context.Background(), "slow", slowPayment("REF-3"))
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, ErrorCodeTooManyRequests, paymentErr.Code, "the queue is full")
+
+	close(provider.release)
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+}
+
+func TestConcurrencyConfigValidation(t *testing.T) {
+	assert.NoError(t, ConcurrencyConfig{MaxInFlight: 10, MaxQueueWait: time.Second}.Validate())
+	assert.Error(t, ConcurrencyConfig{}.Validate())
+	assert.Error(t, ConcurrencyConfig{MaxInFlight: 1, MaxQueueWait: -time.Second}.Validate())
+	assert.Error(t, ConcurrencyConfig{MaxInFlight: 1, MaxQueued: -1}.Validate())
+
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second, Concurrency: &ConcurrencyConfig{}}
+	assert.ErrorContains(t, config.Validate(), "max_in_flight")
+}
@@ -3,6 +3,11 @@ package rimpay
 import (
 	"fmt"
 	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/internal/types"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
 )
 
 type Environment string
@@ -20,8 +25,25 @@ type Config struct {
 	DefaultProvider string                    `json:"default_provider"`
 	Providers       map[string]ProviderConfig `json:"providers"`
 	HTTP            HTTPConfig                `json:"http"`
+	Retry           RetryConfig               `json:"retry"`
 	Logging         LoggingConfig             `json:"logging"`
 	Security        SecurityConfig            `json:"security"`
+	Priority        PriorityConfig            `json:"priority"`
+	Alerts          AlertsConfig              `json:"alerts"`
+	Portability     PortabilityConfig         `json:"portability"`
+	Webhooks        WebhookConfig             `json:"webhooks"`
+	Routing         RoutingConfig             `json:"routing"`
+	Batch           BatchConfig               `json:"batch"`
+	Async           AsyncConfig               `json:"async"`
+	Failover        FailoverPolicy            `json:"failover"`
+	Health          HealthConfig              `json:"health"`
+	Validation      ValidationConfig          `json:"validation"`
+	StatusCache     StatusCacheConfig         `json:"status_cache"`
+
+	// DryRun makes payments return a pending response with
+	// Metadata["simulated"] set instead of calling the provider, for
+	// staging environments. Limits and reference policies still apply.
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // ProviderConfig represents provider configuration
@@ -31,14 +53,78 @@ type ProviderConfig struct {
 	Credentials map[string]string      `json:"credentials"`
 	Timeout     time.Duration          `json:"timeout"`
 	Options     map[string]interface{} `json:"options"`
+
+	// MinAmount and MaxAmount bound the payment amount in cents (khoums);
+	// 0 leaves that side unlimited
+	MinAmount int64 `json:"min_amount,omitempty"`
+	MaxAmount int64 `json:"max_amount,omitempty"`
+
+	// HTTP overrides Config.HTTP for this provider only. When nil the
+	// provider shares the client-wide connection pool.
+	HTTP *HTTPConfig `json:"http,omitempty"`
+
+	// TLS overrides the HTTP TLS settings for this provider only, for
+	// example to present a B-PAY mTLS certificate. The provider then gets
+	// its own connection pool.
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Retry overrides Config.Retry for this provider only, for example
+	// MaxAttempts 1 to never resubmit a payment
+	Retry *RetryConfig `json:"retry,omitempty"`
+
+	// RateLimit paces the provider's outbound requests, including
+	// authentication and session calls, for providers that throttle
+	// merchants. Every call through the provider shares one limit.
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+
+	// Concurrency caps the payments the Client sends to this provider at
+	// once. Status checks are not counted.
+	Concurrency *ConcurrencyConfig `json:"concurrency,omitempty"`
+
+	// Reference replaces the provider's DefaultReferencePolicy
+	Reference *ReferencePolicy `json:"reference,omitempty"`
+
+	// Description replaces the provider's DefaultDescriptionPolicy
+	Description *DescriptionPolicy `json:"description,omitempty"`
+
+	// Fees is what the provider charges, as reported by SimulatePayment
+	Fees *money.FeeSchedule `json:"fees,omitempty"`
+
+	// HTTPClient is the HTTP client the provider sends requests with. The
+	// Client injects its shared client here; set it to supply your own.
+	HTTPClient HTTPClient `json:"-"`
+
+	// Metrics receives the provider's retry and HTTP measurements. The
+	// Client injects the collector set with WithMetrics.
+	Metrics MetricsCollector `json:"-"`
+
+	// Tracer records the provider's spans. The Client injects the tracer
+	// set with WithTracer.
+	Tracer Tracer `json:"-"`
+
+	// CredentialsProvider supplies the provider's credentials in place of
+	// Credentials. The Client injects the one set with
+	// WithCredentialsProvider.
+	CredentialsProvider CredentialsProvider `json:"-"`
 }
 
 // HTTPConfig represents HTTP configuration
-type HTTPConfig struct {
-	Timeout         time.Duration `json:"timeout"`
-	MaxIdleConns    int           `json:"max_idle_conns"`
-	MaxConnsPerHost int           `json:"max_conns_per_host"`
-	UserAgent       string        `json:"user_agent"`
+type HTTPConfig = types.HTTPConfig
+
+// RateLimitConfig limits the request rate of one provider
+type RateLimitConfig = types.RateLimitConfig
+
+// TLSConfig configures the CA bundle, client certificate and minimum
+// version of provider connections
+type TLSConfig = types.TLSConfig
+
+// RetryConfig configures retries of provider calls. The zero value in
+// Config.Retry means DefaultRetryConfig.
+type RetryConfig = types.RetryConfig
+
+// DefaultRetryConfig returns the retry policy used when none is configured
+func DefaultRetryConfig() RetryConfig {
+	return types.DefaultRetryConfig()
 }
 
 // LoggingConfig represents logging configuration
@@ -46,6 +132,11 @@ type LoggingConfig struct {
 	Level  string `json:"level"`
 	Format string `json:"format"`
 	Output string `json:"output"`
+
+	// SampleSuccessRate logs the Info lines of 1 in N payments; 0 and 1 log
+	// every payment. Warn and Error lines, including the one logged for
+	// every failed payment, are never sampled.
+	SampleSuccessRate int `json:"sample_success_rate,omitempty"`
 }
 
 // SecurityConfig represents security configuration
@@ -55,6 +146,125 @@ type SecurityConfig struct {
 	TokenTTL      time.Duration `json:"token_ttl"`
 }
 
+// PriorityConfig configures priority lanes for outbound provider calls.
+// The zero value disables all limits. The lane rates are kept by the client
+// per provider call, separately from ProviderConfig.RateLimit, which the
+// provider's HTTP client applies to every request; a call must pass both, so
+// the lower limit wins.
+type PriorityConfig struct {
+	// MaxConcurrent caps the in-flight calls to each provider across both
+	// lanes (0 = unlimited)
+	MaxConcurrent int `json:"max_concurrent"`
+	// ReservedHigh is the number of MaxConcurrent slots only high priority calls may use
+	ReservedHigh int `json:"reserved_high"`
+	// HighRate and LowRate are the request rates per second of each lane of
+	// each provider (0 = unlimited)
+	HighRate float64 `json:"high_rate"`
+	LowRate  float64 `json:"low_rate"`
+	// Burst is the token bucket size for each rate-limited lane (defaults to 1)
+	Burst int `json:"burst"`
+}
+
+// AlertsConfig configures Warn-level SLO alerts logged for provider calls.
+// The zero value disables alerting.
+type AlertsConfig struct {
+	// LatencyThreshold alerts when a single provider call takes longer (0 = off)
+	LatencyThreshold time.Duration `json:"latency_threshold"`
+	// ErrorRateThreshold alerts when the failed fraction of calls in the
+	// window reaches this value, between 0 and 1 (0 = off)
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	// MinCalls is the number of calls required before the error rate is judged
+	MinCalls int `json:"min_calls"`
+	// Window is the sliding window for stats and alert rate limiting (default 1m)
+	Window time.Duration `json:"window"`
+	// StatsSamples is the number of calls per provider kept for the window
+	// (default 1024); older calls are dropped from busier windows
+	StatsSamples int `json:"stats_samples,omitempty"`
+}
+
+// PortabilityConfig configures operator lookups for ported phone numbers.
+// Without a Resolver the operator is derived from the number prefix.
+type PortabilityConfig struct {
+	// Resolver looks up the operator currently serving a number
+	Resolver phone.PortabilityResolver `json:"-"`
+	// CacheTTL is how long lookups are cached (default 1h)
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+// WebhookConfig configures overload protection for notification webhooks.
+// The zero value accepts every notification.
+type WebhookConfig struct {
+	// MaxInFlight caps notifications processed at once; further notifications
+	// are answered 503 so the provider re-delivers them later (0 = unlimited)
+	MaxInFlight int `json:"max_in_flight"`
+	// RetryAfter is advertised with 503 responses (default 30s)
+	RetryAfter time.Duration `json:"retry_after"`
+}
+
+// RoutingConfig configures provider selection. Unrouted payments use
+// DefaultProvider.
+type RoutingConfig struct {
+	// Operators maps an operator to the name of its preferred provider
+	Operators map[phone.Operator]string `json:"operators"`
+	// RouteByOperator makes ProcessPayment use the operator's preferred
+	// provider instead of DefaultProvider
+	RouteByOperator bool `json:"route_by_operator"`
+	// Strategy chooses providers for ProcessRouted; when nil it routes by
+	// Operators
+	Strategy RoutingStrategy `json:"-"`
+}
+
+// BatchConfig configures Client.ProcessBatch
+type BatchConfig struct {
+	// Concurrency is the number of items processed at once (default 4)
+	Concurrency int `json:"concurrency"`
+	// ItemTimeout bounds each item (0 = no timeout beyond the batch context)
+	ItemTimeout time.Duration `json:"item_timeout"`
+	// OnCancel decides whether in-flight items finish or are cancelled when
+	// the batch context is cancelled (default BatchCancelWait)
+	OnCancel BatchCancelPolicy `json:"on_cancel"`
+}
+
+// AsyncConfig configures the worker pool of Client.ProcessPaymentAsync and
+// Client.ProcessPaymentCallback
+type AsyncConfig struct {
+	// Workers is the number of payments processed at once (default 4)
+	Workers int `json:"workers"`
+	// QueueSize is the number of payments that can wait for a worker
+	// before new ones are rejected (default 64)
+	QueueSize int `json:"queue_size"`
+	// OnClose decides whether Client.Close waits for queued and running
+	// payments or cancels them (default AsyncCloseDrain)
+	OnClose AsyncClosePolicy `json:"on_close"`
+}
+
+// HealthConfig configures Client.HealthCheck
+type HealthConfig struct {
+	// ProbeTimeout bounds each provider probe (default 5s)
+	ProbeTimeout time.Duration `json:"probe_timeout"`
+	// CacheTTL is how long probe results are reused (default 30s)
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+// ValidationConfig bounds a payment's ExpiresAt at submission
+type ValidationConfig struct {
+	// MinExpiry is the least time ExpiresAt must leave the customer to pay
+	// (default 1m)
+	MinExpiry time.Duration `json:"min_expiry"`
+	// MaxExpiry is the furthest ahead ExpiresAt may be (default 30 days)
+	MaxExpiry time.Duration `json:"max_expiry"`
+}
+
+// StatusCacheConfig configures the GetPaymentStatus cache. Final statuses
+// are reused until evicted; pending ones for PendingTTL.
+type StatusCacheConfig struct {
+	// MaxEntries bounds the cache, evicting the least recently used
+	// statuses; 0 turns caching off
+	MaxEntries int `json:"max_entries"`
+	// PendingTTL is how long a pending status is reused (default 5s)
+	PendingTTL time.Duration `json:"pending_ttl"`
+}
+
 // DefaultConfig returns default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -67,6 +277,7 @@ func DefaultConfig() *Config {
 			MaxConnsPerHost: 10,
 			UserAgent:       "RimPay/1.0",
 		},
+		Retry: DefaultRetryConfig(),
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
@@ -80,6 +291,9 @@ func DefaultConfig() *Config {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	if c == nil {
+		return ErrInvalidConfig
+	}
 	if c.Environment != EnvironmentSandbox && c.Environment != EnvironmentProduction {
 		return fmt.Errorf("invalid environment: %s", c.Environment)
 	}
@@ -92,6 +306,93 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("default provider '%s' not found in providers", c.DefaultProvider)
 	}
 
+	if c.Priority.ReservedHigh < 0 || c.Priority.MaxConcurrent < 0 {
+		return fmt.Errorf("priority limits cannot be negative")
+	}
+
+	if c.Priority.MaxConcurrent > 0 && c.Priority.ReservedHigh >= c.Priority.MaxConcurrent {
+		return fmt.Errorf("priority reserved_high must be less than max_concurrent")
+	}
+
+	if c.Alerts.ErrorRateThreshold < 0 || c.Alerts.ErrorRateThreshold > 1 {
+		return fmt.Errorf("alerts error_rate_threshold must be between 0 and 1")
+	}
+
+	if c.Alerts.StatsSamples < 0 {
+		return fmt.Errorf("alerts stats_samples cannot be negative")
+	}
+
+	if c.Webhooks.MaxInFlight < 0 || c.Webhooks.RetryAfter < 0 {
+		return fmt.Errorf("webhooks max_in_flight and retry_after cannot be negative")
+	}
+
+	if err := c.HTTP.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.checkTLS(c.HTTP.TLS); err != nil {
+		return err
+	}
+
+	if !c.Retry.IsZero() {
+		if err := c.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+
+	if c.Batch.Concurrency < 0 || c.Batch.ItemTimeout < 0 {
+		return fmt.Errorf("batch concurrency and item_timeout cannot be negative")
+	}
+
+	if c.Batch.OnCancel != "" && c.Batch.OnCancel != BatchCancelWait && c.Batch.OnCancel != BatchCancelAbort {
+		return fmt.Errorf("invalid batch on_cancel policy: %s", c.Batch.OnCancel)
+	}
+
+	if c.Async.Workers < 0 || c.Async.QueueSize < 0 {
+		return fmt.Errorf("async workers and queue_size cannot be negative")
+	}
+
+	if c.Async.OnClose != "" && c.Async.OnClose != AsyncCloseDrain && c.Async.OnClose != AsyncCloseCancel {
+		return fmt.Errorf("invalid async on_close policy: %s", c.Async.OnClose)
+	}
+
+	if c.Health.ProbeTimeout < 0 || c.Health.CacheTTL < 0 {
+		return fmt.Errorf("health probe_timeout and cache_ttl cannot be negative")
+	}
+
+	if c.Validation.MinExpiry < 0 || c.Validation.MaxExpiry < 0 {
+		return fmt.Errorf("validation min_expiry and max_expiry cannot be negative")
+	}
+
+	if c.Validation.MaxExpiry > 0 && c.Validation.MinExpiry > c.Validation.MaxExpiry {
+		return fmt.Errorf("validation min_expiry cannot exceed max_expiry")
+	}
+
+	if c.StatusCache.MaxEntries < 0 || c.StatusCache.PendingTTL < 0 {
+		return fmt.Errorf("status_cache max_entries and pending_ttl cannot be negative")
+	}
+
+	if c.Portability.CacheTTL < 0 {
+		return fmt.Errorf("portability cache_ttl cannot be negative")
+	}
+
+	for op, name := range c.Routing.Operators {
+		if !isKnownOperator(op) {
+			return fmt.Errorf("routing: unknown operator '%s'", op)
+		}
+		if _, exists := c.Providers[name]; !exists {
+			return fmt.Errorf("routing: provider '%s' for operator '%s' not found in providers", name, string(op))
+		}
+	}
+
+	if err := c.Failover.Validate(c.Providers); err != nil {
+		return err
+	}
+
 	for name, provider := range c.Providers {
 		if err := c.validateProviderConfig(name, provider); err != nil {
 			return fmt.Errorf("invalid config for provider '%s': %w", name, err)
@@ -107,7 +408,8 @@ func (c *Config) validateProviderConfig(name string, config ProviderConfig) erro
 		return nil
 	}
 
-	if config.BaseURL == "" {
+	// The mock provider makes no HTTP calls
+	if config.BaseURL == "" && name != ProviderMock {
 		return fmt.Errorf("base_url is required")
 	}
 
@@ -115,16 +417,96 @@ func (c *Config) validateProviderConfig(name string, config ProviderConfig) erro
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if config.MinAmount < 0 || config.MaxAmount < 0 {
+		return fmt.Errorf("min_amount and max_amount cannot be negative")
+	}
+
+	if config.MaxAmount > 0 && config.MaxAmount < config.MinAmount {
+		return fmt.Errorf("max_amount must not be less than min_amount")
+	}
+
+	if config.Fees != nil {
+		if err := config.Fees.Validate(); err != nil {
+			return fmt.Errorf("fees: %w", err)
+		}
+	}
+
+	if config.HTTP != nil {
+		if err := config.HTTP.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.TLS != nil {
+		if err := config.TLS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.checkProviderTLS(config); err != nil {
+		return err
+	}
+
+	if err := common.ValidateRetryConfig(config.Retry); err != nil {
+		return err
+	}
+
+	if config.RateLimit != nil {
+		if err := config.RateLimit.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.Concurrency != nil {
+		if err := config.Concurrency.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.Reference != nil {
+		if err := config.Reference.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.Description != nil {
+		if err := config.Description.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTLS refuses InsecureSkipVerify in production
+func (c *Config) checkTLS(tls *TLSConfig) error {
+	if tls != nil && tls.InsecureSkipVerify && c.IsProduction() {
+		return fmt.Errorf("tls insecure_skip_verify is not allowed in production")
+	}
+	return nil
+}
+
+// checkProviderTLS applies checkTLS to the TLS overrides of a provider
+func (c *Config) checkProviderTLS(config ProviderConfig) error {
+	if err := c.checkTLS(config.TLS); err != nil {
+		return err
+	}
+	if config.HTTP != nil {
+		return c.checkTLS(config.HTTP.TLS)
+	}
 	return nil
 }
 
 // GetProviderConfig returns provider configuration
 func (c *Config) GetProviderConfig(name string) (ProviderConfig, bool) {
+	if c == nil {
+		return ProviderConfig{}, false
+	}
 	config, exists := c.Providers[name]
 	return config, exists
 }
 
 // IsProduction returns true if production environment
 func (c *Config) IsProduction() bool {
-	return c.Environment == EnvironmentProduction
+	return c != nil && c.Environment == EnvironmentProduction
 }
@@ -0,0 +1,540 @@
+package rimpay
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvPrefix prefixes the environment variables read by
+// LoadConfigFromEnv when no prefix is given
+const DefaultEnvPrefix = "RIMPAY"
+
+// builtinProviders are looked up in the environment even when no
+// configuration file mentions them
+var builtinProviders = []string{ProviderBPay, ProviderMasrvi, ProviderClick, ProviderSedad, ProviderMock}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadConfig reads a JSON (.json) or YAML (.yaml, .yml) configuration file
+// over DefaultConfig and validates the result. Keys are the json names of
+// the Config fields; durations are strings such as "30s" or "2m". Errors
+// name the offending key, for example providers.bpay.timeout.
+func LoadConfig(path string) (*Config, error) {
+	config := DefaultConfig()
+	if err := loadConfigFile(path, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// LoadConfigFromEnv builds a configuration over DefaultConfig from
+// environment variables named after the Config fields, such as
+// RIMPAY_DEFAULT_PROVIDER, RIMPAY_HTTP_TIMEOUT or RIMPAY_RETRY_MAX_ATTEMPTS,
+// and validates it. prefix replaces RIMPAY when not empty. Provider fields
+// are read from <PREFIX>_<PROVIDER>_<FIELD>, credentials from
+// <PREFIX>_<PROVIDER>_CRED_<KEY> and options from
+// <PREFIX>_<PROVIDER>_OPT_<KEY>, for the built-in providers and those listed
+// in <PREFIX>_PROVIDERS. Lists are comma-separated.
+func LoadConfigFromEnv(prefix string) (*Config, error) {
+	config := DefaultConfig()
+	if err := applyEnv(prefix, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config from environment: %w", err)
+	}
+	return config, nil
+}
+
+// LoadConfigWithOverrides reads the configuration file at path like
+// LoadConfig, then applies environment variables like LoadConfigFromEnv, so
+// that secrets can stay out of the file. Environment values win.
+func LoadConfigWithOverrides(path, prefix string) (*Config, error) {
+	config := DefaultConfig()
+	if err := loadConfigFile(path, config); err != nil {
+		return nil, err
+	}
+	if err := applyEnv(prefix, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// loadConfigFile decodes the file at path into config
+func loadConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config %s: unsupported format %q, want .json, .yaml or .yml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	if err := decodeConfigValue("", raw, reflect.ValueOf(config).Elem()); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// configFields maps the json names of the settable fields of a struct type
+// to their index
+func configFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// joinPath appends key to a dotted key path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// decodeConfigValue stores a decoded JSON or YAML value in v, which keeps
+// any value the input does not mention
+func decodeConfigValue(path string, raw interface{}, v reflect.Value) error {
+	if raw == nil {
+		return nil
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := parseConfigDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeConfigValue(path, raw, v.Elem())
+	case v.Kind() == reflect.Interface:
+		v.Set(reflect.ValueOf(normalizeConfigValue(raw)))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		object, ok := asConfigObject(raw)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", path, raw)
+		}
+		fields := configFields(v.Type())
+		for _, key := range sortedConfigKeys(object) {
+			index, ok := fields[key]
+			if !ok {
+				return fmt.Errorf("%s: unknown key", joinPath(path, key))
+			}
+			if err := decodeConfigValue(joinPath(path, key), object[key], v.Field(index)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		object, ok := asConfigObject(raw)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", path, raw)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, key := range sortedConfigKeys(object) {
+			mapKey := reflect.New(v.Type().Key()).Elem()
+			mapKey.SetString(key)
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(mapKey); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := decodeConfigValue(joinPath(path, key), object[key], elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(mapKey, elem)
+		}
+		return nil
+
+	case reflect.Slice:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", path, raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := decodeConfigValue(fmt.Sprintf("%s[%d]", path, i), item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if err := setConfigScalar(v, raw); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// asConfigObject accepts the map types produced by the JSON and YAML decoders
+func asConfigObject(raw interface{}) (map[string]interface{}, bool) {
+	switch object := raw.(type) {
+	case map[string]interface{}:
+		return object, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			converted[fmt.Sprint(key)] = value
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// normalizeConfigValue converts YAML maps with non-string keys, so that
+// provider options read the same from JSON and YAML
+func normalizeConfigValue(raw interface{}) interface{} {
+	switch value := raw.(type) {
+	case map[interface{}]interface{}, map[string]interface{}:
+		object, _ := asConfigObject(value)
+		for key, item := range object {
+			object[key] = normalizeConfigValue(item)
+		}
+		return object
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeConfigValue(item)
+		}
+		return value
+	}
+	return raw
+}
+
+func sortedConfigKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseConfigDuration accepts strings such as "30s" and, as encoding/json
+// produces for time.Duration, integer nanoseconds
+func parseConfigDuration(raw interface{}) (time.Duration, error) {
+	switch value := raw.(type) {
+	case string:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return d, nil
+	case int:
+		return time.Duration(value), nil
+	case float64:
+		if value == math.Trunc(value) {
+			return time.Duration(value), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %v, want a string such as \"30s\"", raw)
+}
+
+// setConfigScalar stores a decoded string, bool or number in v
+func setConfigScalar(v reflect.Value, raw interface{}) error {
+	if s, ok := raw.(string); ok {
+		return setConfigString(v, s)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if b, ok := raw.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := raw.(type) {
+		case int:
+			v.SetInt(int64(n))
+			return nil
+		case float64:
+			if n == math.Trunc(n) {
+				v.SetInt(int64(n))
+				return nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := raw.(type) {
+		case int:
+			v.SetFloat(float64(n))
+			return nil
+		case float64:
+			v.SetFloat(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot use %v (%T) as %s", raw, raw, v.Type())
+}
+
+// setConfigString parses s into v, as read from a file or the environment
+func setConfigString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []interface{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setConfigString(slice.Index(i), item.(string)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(s))
+	default:
+		return fmt.Errorf("cannot set %s from a string", v.Type())
+	}
+	return nil
+}
+
+// envSource is a snapshot of the environment variables under a prefix
+type envSource struct {
+	prefix string
+	vars   map[string]string
+}
+
+func newEnvSource(prefix string) *envSource {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && strings.HasPrefix(name, prefix+"_") {
+			vars[name] = value
+		}
+	}
+	return &envSource{prefix: prefix, vars: vars}
+}
+
+// hasPrefix reports whether any variable starts with name_
+func (e *envSource) hasPrefix(name string) bool {
+	for key := range e.vars {
+		if strings.HasPrefix(key, name+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the lowercased suffixes of the variables starting with name_
+func (e *envSource) keys(name string) []string {
+	var keys []string
+	for key := range e.vars {
+		if strings.HasPrefix(key, name+"_") {
+			keys = append(keys, strings.ToLower(strings.TrimPrefix(key, name+"_")))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envName is the variable name of a json key below name
+func envName(name, key string) string {
+	return name + "_" + strings.ToUpper(key)
+}
+
+// applyEnv overrides config with the environment variables under prefix
+func applyEnv(prefix string, config *Config) error {
+	env := newEnvSource(prefix)
+
+	if err := env.apply(env.prefix, reflect.ValueOf(config).Elem(), "providers"); err != nil {
+		return err
+	}
+
+	names := append([]string(nil), builtinProviders...)
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	if listed, ok := env.vars[envName(env.prefix, "providers")]; ok {
+		for _, name := range strings.Split(listed, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		varName := envName(env.prefix, name)
+		if !env.hasPrefix(varName) {
+			continue
+		}
+		provider := config.Providers[name]
+		if err := env.applyProvider(varName, &provider); err != nil {
+			return err
+		}
+		if config.Providers == nil {
+			config.Providers = make(map[string]ProviderConfig)
+		}
+		config.Providers[name] = provider
+	}
+	return nil
+}
+
+// applyProvider reads the fields, credentials and options of a provider
+func (e *envSource) applyProvider(name string, provider *ProviderConfig) error {
+	for _, key := range e.keys(name + "_CRED") {
+		if provider.Credentials == nil {
+			provider.Credentials = make(map[string]string)
+		}
+		provider.Credentials[key] = e.vars[envName(name+"_CRED", key)]
+	}
+	for _, key := range e.keys(name + "_OPT") {
+		if provider.Options == nil {
+			provider.Options = make(map[string]interface{})
+		}
+		provider.Options[key] = e.vars[envName(name+"_OPT", key)]
+	}
+	return e.apply(name, reflect.ValueOf(provider).Elem(), "credentials", "options")
+}
+
+// apply sets the fields of struct v from the variables named after them
+// below name, skipping the json keys in skip
+func (e *envSource) apply(name string, v reflect.Value, skip ...string) error {
+	fields := configFields(v.Type())
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+fields:
+	for _, key := range keys {
+		for _, skipped := range skip {
+			if key == skipped {
+				continue fields
+			}
+		}
+
+		field := v.Field(fields[key])
+		varName := envName(name, key)
+
+		switch {
+		case field.Type() == durationType:
+		case field.Kind() == reflect.Struct:
+			if err := e.apply(varName, field); err != nil {
+				return err
+			}
+			continue
+		case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct:
+			if !e.hasPrefix(varName) {
+				continue
+			}
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			if err := e.apply(varName, field.Elem()); err != nil {
+				return err
+			}
+			continue
+		case field.Kind() == reflect.Map:
+			if field.Type().Key().Kind() != reflect.String {
+				continue
+			}
+			for _, mapKey := range e.keys(varName) {
+				if field.IsNil() {
+					field.Set(reflect.MakeMap(field.Type()))
+				}
+				keyValue := reflect.New(field.Type().Key()).Elem()
+				keyValue.SetString(mapKey)
+				elem := reflect.New(field.Type().Elem()).Elem()
+				if err := setConfigString(elem, e.vars[envName(varName, mapKey)]); err != nil {
+					return fmt.Errorf("%s: %w", envName(varName, mapKey), err)
+				}
+				field.SetMapIndex(keyValue, elem)
+			}
+			continue
+		}
+
+		value, ok := e.vars[varName]
+		if !ok {
+			continue
+		}
+		if err := setConfigString(field, value); err != nil {
+			return fmt.Errorf("%s: %w", varName, err)
+		}
+	}
+	return nil
+}
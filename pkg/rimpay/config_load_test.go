@@ -0,0 +1,211 @@
+package rimpay
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const yamlConfig = `
+environment: production
+default_provider: bpay
+http:
+  timeout: 15s
+  retry_on: [502, 503]
+retry:
+  max_attempts: 5
+  initial_delay: 500ms
+  max_delay: 2m
+  multiplier: 1.5
+routing:
+  operators:
+    mauritel: bpay
+providers:
+  bpay:
+    enabled: true
+    base_url: https://bpay.example.mr
+    timeout: 30s
+    credentials:
+      username: merchant
+    options:
+      allowed_operations: [payment, refund]
+    http:
+      max_retries: 2
+    tls:
+      cert_file: /etc/rimpay/bpay.pem
+      key_file: /etc/rimpay/bpay.key
+      min_version: "1.3"
+`
+
+func TestLoadConfigYAML(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "rimpay.yaml", yamlConfig))
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvironmentProduction, config.Environment)
+	assert.Equal(t, 15*time.Second, config.HTTP.Timeout)
+	assert.Equal(t, []int{502, 503}, config.HTTP.RetryOn)
+	assert.Equal(t, 100, config.HTTP.MaxIdleConns, "defaults are kept")
+	assert.Equal(t, 5, config.Retry.MaxAttempts)
+	assert.Equal(t, 500*time.Millisecond, config.Retry.InitialDelay)
+	assert.Equal(t, 2*time.Minute, config.Retry.MaxDelay)
+	assert.Equal(t, "bpay", config.Routing.Operators[phone.OperatorMauritel])
+
+	bpay := config.Providers[ProviderBPay]
+	assert.Equal(t, "https://bpay.example.mr", bpay.BaseURL)
+	assert.Equal(t, 30*time.Second, bpay.Timeout)
+	assert.Equal(t, "merchant", bpay.Credentials["username"])
+	assert.Equal(t, []interface{}{"payment", "refund"}, bpay.Options["allowed_operations"])
+	require.NotNil(t, bpay.HTTP)
+	assert.Equal(t, 2, bpay.HTTP.MaxRetries)
+	require.NotNil(t, bpay.TLS)
+	assert.Equal(t, TLSConfig{CertFile: "/etc/rimpay/bpay.pem", KeyFile: "/etc/rimpay/bpay.key", MinVersion: "1.3"}, *bpay.TLS)
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "rimpay.json", `{
+		"default_provider": "mock",
+		"providers": {"mock": {"enabled": true, "timeout": "5s"}},
+		"batch": {"concurrency": 4, "item_timeout": 1000000000}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvironmentSandbox, config.Environment)
+	assert.Equal(t, 5*time.Second, config.Providers[ProviderMock].Timeout)
+	assert.Equal(t, 4, config.Batch.Concurrency)
+	assert.Equal(t, time.Second, config.Batch.ItemTimeout, "integer durations are nanoseconds")
+}
+
+func TestLoadConfigErrorsNameTheKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "invalid duration",
+			file:    "rimpay.yaml",
+			content: "providers:\n  bpay:\n    timeout: soon\n",
+			wantErr: `providers.bpay.timeout: invalid duration "soon"`,
+		},
+		{
+			name:    "unknown key",
+			file:    "rimpay.json",
+			content: `{"http": {"timeout": "1s", "max_conns": 3}}`,
+			wantErr: "http.max_conns: unknown key",
+		},
+		{
+			name:    "wrong type",
+			file:    "rimpay.yml",
+			content: "retry:\n  max_attempts: many\n",
+			wantErr: `retry.max_attempts: invalid integer "many"`,
+		},
+		{
+			name:    "list element",
+			file:    "rimpay.yml",
+			content: "http:\n  retry_on: [502, x]\n",
+			wantErr: `http.retry_on[1]: invalid integer "x"`,
+		},
+		{
+			name:    "unsupported format",
+			file:    "rimpay.toml",
+			content: "",
+			wantErr: `unsupported format ".toml"`,
+		},
+		{
+			name:    "validation",
+			file:    "rimpay.yaml",
+			content: "default_provider: click\n",
+			wantErr: "invalid config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigFile(t, tt.file, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("RIMPAY_ENVIRONMENT", "production")
+	t.Setenv("RIMPAY_DEFAULT_PROVIDER", "masrvi")
+	t.Setenv("RIMPAY_HTTP_TIMEOUT", "45s")
+	t.Setenv("RIMPAY_HTTP_RETRY_ON", "502, 503")
+	t.Setenv("RIMPAY_RETRY_MAX_ATTEMPTS", "4")
+	t.Setenv("RIMPAY_ROUTING_OPERATORS_CHINGUITEL", "masrvi")
+	t.Setenv("RIMPAY_MASRVI_ENABLED", "true")
+	t.Setenv("RIMPAY_MASRVI_BASE_URL", "https://masrvi.example.mr")
+	t.Setenv("RIMPAY_MASRVI_TIMEOUT", "2m")
+	t.Setenv("RIMPAY_MASRVI_CRED_MERCHANT_ID", "M-1")
+	t.Setenv("RIMPAY_MASRVI_OPT_LANGUAGE", "fr")
+
+	config, err := LoadConfigFromEnv("")
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvironmentProduction, config.Environment)
+	assert.Equal(t, "masrvi", config.DefaultProvider)
+	assert.Equal(t, 45*time.Second, config.HTTP.Timeout)
+	assert.Equal(t, []int{502, 503}, config.HTTP.RetryOn)
+	assert.Equal(t, 4, config.Retry.MaxAttempts)
+	assert.Equal(t, "masrvi", config.Routing.Operators[phone.OperatorChinguitel])
+
+	masrvi := config.Providers[ProviderMasrvi]
+	assert.True(t, masrvi.Enabled)
+	assert.Equal(t, "https://masrvi.example.mr", masrvi.BaseURL)
+	assert.Equal(t, 2*time.Minute, masrvi.Timeout)
+	assert.Equal(t, map[string]string{"merchant_id": "M-1"}, masrvi.Credentials)
+	assert.Equal(t, "fr", masrvi.Options["language"])
+	assert.NotContains(t, config.Providers, ProviderBPay, "providers without variables are not added")
+}
+
+func TestLoadConfigFromEnvCustomPrefixAndProvider(t *testing.T) {
+	t.Setenv("PAY_DEFAULT_PROVIDER", "acme")
+	t.Setenv("PAY_PROVIDERS", "acme")
+	t.Setenv("PAY_ACME_ENABLED", "true")
+	t.Setenv("PAY_ACME_BASE_URL", "https://acme.test")
+	t.Setenv("PAY_ACME_TIMEOUT", "10s")
+
+	config, err := LoadConfigFromEnv("PAY")
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.test", config.Providers["acme"].BaseURL)
+}
+
+func TestLoadConfigFromEnvInvalidValue(t *testing.T) {
+	t.Setenv("RIMPAY_MOCK_TIMEOUT", "fast")
+
+	_, err := LoadConfigFromEnv("")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `RIMPAY_MOCK_TIMEOUT: invalid duration "fast"`)
+}
+
+func TestLoadConfigWithOverrides(t *testing.T) {
+	path := writeConfigFile(t, "rimpay.yaml", yamlConfig)
+	t.Setenv("RIMPAY_BPAY_BASE_URL", "https://bpay.override.mr")
+	t.Setenv("RIMPAY_BPAY_CRED_PASSWORD", "secret")
+	t.Setenv("RIMPAY_RETRY_MAX_DELAY", "10s")
+
+	config, err := LoadConfigWithOverrides(path, "")
+	require.NoError(t, err)
+
+	bpay := config.Providers[ProviderBPay]
+	assert.Equal(t, "https://bpay.override.mr", bpay.BaseURL)
+	assert.Equal(t, 30*time.Second, bpay.Timeout, "file values without overrides are kept")
+	assert.Equal(t, map[string]string{"username": "merchant", "password": "secret"}, bpay.Credentials)
+	assert.Equal(t, 10*time.Second, config.Retry.MaxDelay)
+	assert.Equal(t, 5, config.Retry.MaxAttempts)
+}
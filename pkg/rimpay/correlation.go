@@ -0,0 +1,79 @@
+package rimpay
+
+import (
+	"context"
+
+	"github.com/CatoSystems/rim-pay/internal/types"
+)
+
+// CorrelationIDHeader is the header that carries the correlation ID of a
+// call to providers
+const CorrelationIDHeader = types.CorrelationIDHeader
+
+// WithCorrelationID returns a context whose payment calls use id as their
+// correlation ID instead of generating one. Webhook handlers can pass the
+// ID of the original payment to continue its chain.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return types.WithCorrelationID(ctx, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by ctx, or ""
+func CorrelationIDFromContext(ctx context.Context) string {
+	return types.CorrelationIDFromContext(ctx)
+}
+
+// ensureCorrelationID returns ctx carrying a correlation ID, generating one
+// when ctx has none
+func ensureCorrelationID(ctx context.Context) (context.Context, string, error) {
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		return ctx, id, nil
+	}
+	id, err := types.NewCorrelationID()
+	if err != nil {
+		return ctx, "", err
+	}
+	return WithCorrelationID(ctx, id), id, nil
+}
+
+// ContextLogger returns logger with the correlation ID of ctx added to
+// every line, or logger itself when ctx carries none. Within a payment it
+// also adds the provider, reference and environment, and applies
+// LoggingConfig.SampleSuccessRate to Info lines.
+func ContextLogger(ctx context.Context, logger Logger) Logger {
+	if logger == nil {
+		return logger
+	}
+	if log := paymentLogFrom(ctx); log != nil {
+		logger = &paymentLogger{next: logger, log: log}
+	}
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		logger = &correlationLogger{next: logger, id: id}
+	}
+	return logger
+}
+
+// correlationLogger adds a correlation_id field to every line
+type correlationLogger struct {
+	next Logger
+	id   string
+}
+
+func (l *correlationLogger) fields(fields []interface{}) []interface{} {
+	return append([]interface{}{"correlation_id", l.id}, fields...)
+}
+
+func (l *correlationLogger) Debug(msg string, fields ...interface{}) {
+	l.next.Debug(msg, l.fields(fields)...)
+}
+
+func (l *correlationLogger) Info(msg string, fields ...interface{}) {
+	l.next.Info(msg, l.fields(fields)...)
+}
+
+func (l *correlationLogger) Warn(msg string, fields ...interface{}) {
+	l.next.Warn(msg, l.fields(fields)...)
+}
+
+func (l *correlationLogger) Error(msg string, fields ...interface{}) {
+	l.next.Error(msg, l.fields(fields)...)
+}
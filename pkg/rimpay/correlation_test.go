@@ -0,0 +1,47 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fieldLogger keeps the fields of the last line
+type fieldLogger struct {
+	fields []interface{}
+}
+
+func (l *fieldLogger) Debug(_ string, fields ...interface{}) { l.fields = fields }
+func (l *fieldLogger) Info(_ string, fields ...interface{})  { l.fields = fields }
+func (l *fieldLogger) Warn(_ string, fields ...interface{})  { l.fields = fields }
+func (l *fieldLogger) Error(_ string, fields ...interface{}) { l.fields = fields }
+
+func TestContextLogger(t *testing.T) {
+	base := &fieldLogger{}
+	assert.Same(t, base, ContextLogger(context.Background(), base), "no ID, no wrapper")
+
+	ctx := WithCorrelationID(context.Background(), "corr-1")
+	assert.Equal(t, "corr-1", CorrelationIDFromContext(ctx))
+
+	logger := ContextLogger(ctx, base)
+	logger.Warn("payment failed", "reference", "REF-1")
+	assert.Equal(t, []interface{}{"correlation_id", "corr-1", "reference", "REF-1"}, base.fields)
+	logger.Info("done")
+	assert.Equal(t, []interface{}{"correlation_id", "corr-1"}, base.fields)
+}
+
+func TestEnsureCorrelationID(t *testing.T) {
+	ctx, id, err := ensureCorrelationID(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, id, 36)
+	assert.Equal(t, id, CorrelationIDFromContext(ctx))
+
+	kept, same, err := ensureCorrelationID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, id, same)
+	assert.Equal(t, ctx, kept)
+
+	assert.Empty(t, CorrelationIDFromContext(context.Background()))
+}
@@ -0,0 +1,90 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+)
+
+// CredentialMerchantID is the credential naming the merchant account, which
+// PaymentRequest.CredentialOverrides may replace for MASRVI
+const CredentialMerchantID = "merchant_id"
+
+// CredentialsProvider supplies provider credentials when a provider needs
+// them, so that they can be kept in a secret manager and rotated without
+// restarting the client. provider is the provider name, such as "bpay", and
+// key the credential name, such as "password". Implementations must be safe
+// for concurrent use.
+type CredentialsProvider interface {
+	GetCredential(ctx context.Context, provider, key string) (string, error)
+}
+
+// CredentialsVersioner is implemented by credentials providers that rotate
+// credentials. Providers discard the tokens and sessions they obtained with
+// credentials of another version.
+type CredentialsVersioner interface {
+	CredentialsVersion(ctx context.Context, provider string) (string, error)
+}
+
+// StaticCredentials serves the credentials of a single provider from a map.
+// It is the CredentialsProvider of a ProviderConfig without one, serving
+// ProviderConfig.Credentials.
+type StaticCredentials map[string]string
+
+// GetCredential returns the credential named key, or ErrCredentialNotFound
+func (s StaticCredentials) GetCredential(_ context.Context, provider, key string) (string, error) {
+	value, ok := s[key]
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s credential %s: %w", provider, key, ErrCredentialNotFound)
+	}
+	return value, nil
+}
+
+// WithCredentialsProvider makes providers read their credentials from
+// credentials at call time instead of ProviderConfig.Credentials. It applies
+// to providers created afterwards that do not set their own
+// CredentialsProvider, so call it before adding providers; nil restores the
+// static credentials.
+func (c *Client) WithCredentialsProvider(credentials CredentialsProvider) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = credentials
+	return c
+}
+
+// credentialsProvider returns the provider set with WithCredentialsProvider
+func (c *Client) credentialsProvider() CredentialsProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.credentials
+}
+
+// credentialsSource returns the CredentialsProvider, or the static
+// Credentials when none is set
+func (p ProviderConfig) credentialsSource() CredentialsProvider {
+	if p.CredentialsProvider != nil {
+		return p.CredentialsProvider
+	}
+	return StaticCredentials(p.Credentials)
+}
+
+// Credential returns the credential named key of the provider named
+// provider from the CredentialsProvider, or from Credentials when none is set
+func (p ProviderConfig) Credential(ctx context.Context, provider, key string) (string, error) {
+	return p.credentialsSource().GetCredential(ctx, provider, key)
+}
+
+// CredentialsVersion returns the current version of the provider's
+// credentials, or "" when the CredentialsProvider does not rotate them
+func (p ProviderConfig) CredentialsVersion(ctx context.Context, provider string) (string, error) {
+	versioner, ok := p.credentialsSource().(CredentialsVersioner)
+	if !ok {
+		return "", nil
+	}
+	return versioner.CredentialsVersion(ctx, provider)
+}
+
+// HasCredentialsProvider reports whether credentials are read at call time
+// rather than from Credentials, which providers cannot check up front
+func (p ProviderConfig) HasCredentialsProvider() bool {
+	return p.CredentialsProvider != nil
+}
@@ -0,0 +1,70 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultCredentials stands in for a secret manager backend
+type vaultCredentials struct {
+	secrets map[string]string
+	version string
+}
+
+func (v *vaultCredentials) GetCredential(_ context.Context, provider, key string) (string, error) {
+	value, ok := v.secrets[provider+"/"+key]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return value, nil
+}
+
+func (v *vaultCredentials) CredentialsVersion(context.Context, string) (string, error) {
+	return v.version, nil
+}
+
+func TestProviderConfigCredentialDefaultsToStaticMap(t *testing.T) {
+	config := ProviderConfig{Credentials: map[string]string{"username": "merchant"}}
+
+	value, err := config.Credential(context.Background(), ProviderBPay, "username")
+	require.NoError(t, err)
+	assert.Equal(t, "merchant", value)
+
+	_, err = config.Credential(context.Background(), ProviderBPay, "password")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	assert.Contains(t, err.Error(), "bpay credential password")
+
+	version, err := config.CredentialsVersion(context.Background(), ProviderBPay)
+	require.NoError(t, err)
+	assert.Empty(t, version, "static credentials never rotate")
+	assert.False(t, config.HasCredentialsProvider())
+}
+
+func TestClientWithCredentialsProvider(t *testing.T) {
+	vault := &vaultCredentials{secrets: map[string]string{"bpay/password": "from-vault"}, version: "7"}
+
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.WithCredentialsProvider(vault)
+
+	injected := client.withSharedHTTP(ProviderConfig{Credentials: map[string]string{"password": "plaintext"}})
+	value, err := injected.Credential(context.Background(), ProviderBPay, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "from-vault", value)
+	version, err := injected.CredentialsVersion(context.Background(), ProviderBPay)
+	require.NoError(t, err)
+	assert.Equal(t, "7", version)
+
+	own := StaticCredentials{"password": "own"}
+	kept := client.withSharedHTTP(ProviderConfig{CredentialsProvider: own})
+	assert.Equal(t, own, kept.CredentialsProvider, "a provider's own credentials provider is kept")
+
+	client.WithCredentialsProvider(nil)
+	assert.False(t, client.withSharedHTTP(ProviderConfig{}).HasCredentialsProvider())
+}
@@ -0,0 +1,115 @@
+package rimpay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/validation"
+)
+
+// DescriptionCharset is the set of characters a provider accepts in
+// payment descriptions
+type DescriptionCharset = validation.Charset
+
+const (
+	// DescriptionCharsetAny accepts every character
+	DescriptionCharsetAny = validation.CharsetAny
+	// DescriptionCharsetSafe accepts letters and digits of any script,
+	// spaces and ASCII punctuation, but not Arabic punctuation such as ؟
+	DescriptionCharsetSafe = validation.CharsetSafe
+	// DescriptionCharsetLatin accepts printable ASCII and Latin-1 letters
+	DescriptionCharsetLatin = validation.CharsetLatin
+)
+
+// DescriptionPolicy is what a provider accepts as payment description
+type DescriptionPolicy struct {
+	Charset DescriptionCharset `json:"charset,omitempty"`
+	// Sanitize rewrites descriptions the charset rejects, transliterating
+	// what it can and dropping the rest, instead of failing the payment
+	Sanitize bool `json:"sanitize,omitempty"`
+}
+
+// DefaultDescriptionPolicy returns the description rules of a built-in
+// provider, which apply unless ProviderConfig.Description replaces them.
+// B-PAY rejects Arabic punctuation; other providers have no rules.
+func DefaultDescriptionPolicy(provider string) DescriptionPolicy {
+	if provider == ProviderBPay {
+		return DescriptionPolicy{Charset: DescriptionCharsetSafe}
+	}
+	return DescriptionPolicy{}
+}
+
+// Validate checks the policy itself
+func (p DescriptionPolicy) Validate() error {
+	if !p.Charset.IsValid() {
+		return fmt.Errorf("unknown description charset %q", p.Charset)
+	}
+	return nil
+}
+
+// descriptionPolicy returns the configured description policy of provider
+func (c *Client) descriptionPolicy(provider string) DescriptionPolicy {
+	if config, ok := c.providerConfig(provider); ok && config.Description != nil {
+		return *config.Description
+	}
+	return DefaultDescriptionPolicy(provider)
+}
+
+// expiryWindow returns the configured ExpiresAt bounds
+func (c *Client) expiryWindow() validation.ExpiryWindow {
+	window := validation.DefaultExpiryWindow
+	config := c.currentConfig().Validation
+	if config.MinExpiry > 0 {
+		window.Min = config.MinExpiry
+	}
+	if config.MaxExpiry > 0 {
+		window.Max = config.MaxExpiry
+	}
+	return window
+}
+
+// checkDetails applies the expiry window and the description policy of
+// provider, returning the description to send
+func (c *Client) checkDetails(provider, description string, expiresAt *time.Time) (string, error) {
+	var errs ValidationErrors
+	c.checkExpiry(&errs, expiresAt)
+	description = c.checkDescription(&errs, provider, description)
+	return description, errs.Err()
+}
+
+// checkExpiry records an ExpiresAt outside the configured window
+func (c *Client) checkExpiry(errs *ValidationErrors, expiresAt *time.Time) {
+	if message := c.expiryWindow().Failure(expiresAt, c.scheduler.now()); message != "" {
+		errs.Add("expires_at", message)
+	}
+}
+
+// checkDescription records a description that breaks the description
+// policy of provider, or sanitizes it when the policy says so
+func (c *Client) checkDescription(errs *ValidationErrors, provider, description string) string {
+	policy := c.descriptionPolicy(provider)
+	message := policy.Charset.Failure(description)
+	switch {
+	case message == "":
+	case policy.Sanitize:
+		description = policy.Charset.Sanitize(description)
+	default:
+		errs.Add("description", fmt.Sprintf("%s by %s", message, provider))
+	}
+	return description
+}
+
+// prepareRequest applies checkDetails to request, copying it when its
+// description is sanitized
+func (c *Client) prepareRequest(provider string, request *PaymentRequest) (*PaymentRequest, error) {
+	description, err := c.checkDetails(provider, request.Description, request.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if description != request.Description {
+		sanitized := *request
+		sanitized.Description = description
+		request = &sanitized
+	}
+	return request, nil
+}
@@ -0,0 +1,108 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// describedProvider records the description of the last payment it received
+type describedProvider struct {
+	namedProvider
+	description string
+}
+
+func (p *describedProvider) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	p.description = request.Description
+	return p.namedProvider.ProcessPayment(ctx, request)
+}
+
+func newDescriptionTestClient(t *testing.T, policy *DescriptionPolicy) (*Client, *describedProvider) {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second, Description: policy}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	provider := &describedProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+	return client, provider
+}
+
+func TestBPayRejectsArabicPunctuation(t *testing.T) {
+	client, provider := newDescriptionTestClient(t, nil)
+
+	request := failoverRequest()
+	request.Description = "دفع فاتورة الكهرباء"
+	_, err := client.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "دفع فاتورة الكهرباء", provider.description)
+
+	request.Description = "هل تم الدفع؟"
+	_, err = client.ProcessPayment(context.Background(), request)
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Equal(t, "description: contains '؟' (U+061F) at position 12; only letters, digits, spaces and ASCII punctuation are accepted by bpay",
+		paymentErr.Message)
+}
+
+func TestSanitizedDescriptionIsSent(t *testing.T) {
+	client, provider := newDescriptionTestClient(t, &DescriptionPolicy{Charset: DescriptionCharsetSafe, Sanitize: true})
+
+	request := failoverRequest()
+	request.Description = "هل تم الدفع؟  «نعم»"
+	_, err := client.ProcessPaymentWithProvider(context.Background(), ProviderBPay, request)
+	require.NoError(t, err)
+	assert.Equal(t, `هل تم الدفع? "نعم"`, provider.description)
+	assert.Equal(t, "هل تم الدفع؟  «نعم»", request.Description, "the caller's request is left alone")
+}
+
+func TestExpiresAtWindow(t *testing.T) {
+	client, _ := newDescriptionTestClient(t, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client.scheduler.now = func() time.Time { return now }
+
+	request := failoverRequest()
+	past := now.Add(-time.Minute)
+	request.ExpiresAt = &past
+	_, err := client.ProcessPayment(context.Background(), request)
+	assert.ErrorContains(t, err, "expires_at: is in the past")
+	assert.True(t, IsValidation(err))
+
+	far := now.Add(31 * 24 * time.Hour)
+	request.ExpiresAt = &far
+	_, err = client.ProcessPayment(context.Background(), request)
+	assert.ErrorContains(t, err, "expires_at: is more than 720h0m0s ahead")
+
+	client.config.Validation.MaxExpiry = 60 * 24 * time.Hour
+	_, err = client.ProcessPayment(context.Background(), request)
+	assert.NoError(t, err)
+
+	bpayRequest := newValidBPayRequest()
+	bpayRequest.Description = "Facture؛ mars"
+	bpayRequest.ExpiresAt = &past
+	result, err := client.SimulatePayment(context.Background(), bpayRequest)
+	require.NoError(t, err)
+	var messages []string
+	for _, err := range result.Errors {
+		messages = append(messages, err.Error())
+	}
+	assert.Contains(t, messages, "VALIDATION_ERROR: expires_at: is in the past")
+	assert.Contains(t, messages, "VALIDATION_ERROR: description: contains '؛' (U+061B) at position 8; "+
+		"only letters, digits, spaces and ASCII punctuation are accepted by bpay")
+}
+
+func TestDescriptionPolicyConfigValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Description: &DescriptionPolicy{Charset: "emoji"}}
+	assert.ErrorContains(t, config.Validate(), `unknown description charset "emoji"`)
+
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Validation = ValidationConfig{MinExpiry: time.Hour, MaxExpiry: time.Minute}
+	assert.ErrorContains(t, config.Validate(), "min_expiry cannot exceed max_expiry")
+}
@@ -0,0 +1,104 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Diagnosis reports the health of a single provider
+type Diagnosis struct {
+	Provider string
+	Healthy  bool
+	// Error explains why the provider is unhealthy
+	Error error
+	// Hint suggests a fix when Error is a known configuration fault
+	Hint string
+}
+
+// String returns a one-line summary suitable for logs and CLIs
+func (d Diagnosis) String() string {
+	switch {
+	case d.Healthy:
+		return fmt.Sprintf("%s: ok", d.Provider)
+	case d.Hint != "":
+		return fmt.Sprintf("%s: %v (hint: %s)", d.Provider, d.Error, d.Hint)
+	default:
+		return fmt.Sprintf("%s: %v", d.Provider, d.Error)
+	}
+}
+
+// IsConfigurationFault reports whether the diagnosis needs operator action
+// rather than a retry, such as an untrusted or expired TLS certificate
+func (d Diagnosis) IsConfigurationFault() bool {
+	var paymentErr *PaymentError
+	return errors.As(d.Error, &paymentErr) && paymentErr.Code == ErrorCodeProviderTLSError
+}
+
+// Diagnose checks every registered provider. Configuration faults are listed
+// first, then other failures, then healthy providers.
+func (c *Client) Diagnose(ctx context.Context) []Diagnosis {
+	c.mu.RLock()
+	providers := make(map[string]PaymentProvider, len(c.providers))
+	for name, provider := range c.providers {
+		providers[name] = provider
+	}
+	c.mu.RUnlock()
+
+	diagnoses := make([]Diagnosis, 0, len(providers))
+	for name, provider := range providers {
+		diagnoses = append(diagnoses, diagnoseProvider(ctx, name, provider))
+	}
+
+	sort.Slice(diagnoses, func(i, j int) bool {
+		ri, rj := diagnosisRank(diagnoses[i]), diagnosisRank(diagnoses[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return diagnoses[i].Provider < diagnoses[j].Provider
+	})
+
+	for _, d := range diagnoses {
+		if d.IsConfigurationFault() {
+			c.logger.Error("Provider configuration fault", "provider", d.Provider, "error", d.Error, "hint", d.Hint)
+		}
+	}
+
+	return diagnoses
+}
+
+func diagnoseProvider(ctx context.Context, name string, provider PaymentProvider) Diagnosis {
+	d := Diagnosis{Provider: name}
+
+	checker, ok := provider.(HealthChecker)
+	if !ok {
+		d.Healthy = provider.IsAvailable(ctx)
+		if !d.Healthy {
+			d.Error = fmt.Errorf(providerNotAvailableMsg, name)
+		}
+		return d
+	}
+
+	d.Error = checker.CheckHealth(ctx)
+	d.Healthy = d.Error == nil
+
+	var paymentErr *PaymentError
+	if errors.As(d.Error, &paymentErr) {
+		if hint, ok := paymentErr.Details["hint"].(string); ok {
+			d.Hint = hint
+		}
+	}
+	return d
+}
+
+func diagnosisRank(d Diagnosis) int {
+	switch {
+	case d.IsConfigurationFault():
+		return 0
+	case !d.Healthy:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,63 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkedProvider is a provider whose health check returns a fixed error
+type checkedProvider struct {
+	name string
+	err  error
+}
+
+func (p *checkedProvider) Name() string                          { return p.name }
+func (p *checkedProvider) IsAvailable(ctx context.Context) bool  { return p.err == nil }
+func (p *checkedProvider) CheckHealth(ctx context.Context) error { return p.err }
+func (p *checkedProvider) ValidateConfig() error                 { return nil }
+func (p *checkedProvider) Capabilities() Capabilities            { return Capabilities{} }
+func (p *checkedProvider) GetPaymentStatus(context.Context, string) (*TransactionStatus, error) {
+	return nil, p.err
+}
+
+func (p *checkedProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+func (p *checkedProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	return nil, p.err
+}
+
+func TestDiagnoseListsTLSFaultsFirst(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+
+	tlsErr := NewPaymentError(ErrorCodeProviderTLSError, "TLS certificate verification failed", "masrvi", false).
+		WithDetail("hint", "the provider may be missing an intermediate certificate")
+
+	require.NoError(t, client.AddProvider("bpay", &checkedProvider{name: "bpay"}))
+	require.NoError(t, client.AddProvider("click", &checkedProvider{name: "click", err: ErrNetworkError}))
+	require.NoError(t, client.AddProvider("masrvi", &checkedProvider{name: "masrvi", err: tlsErr}))
+
+	diagnoses := client.Diagnose(context.Background())
+	require.Len(t, diagnoses, 3)
+
+	assert.Equal(t, "masrvi", diagnoses[0].Provider)
+	assert.True(t, diagnoses[0].IsConfigurationFault())
+	assert.Equal(t, "the provider may be missing an intermediate certificate", diagnoses[0].Hint)
+	assert.Contains(t, diagnoses[0].String(), "PROVIDER_TLS_ERROR")
+
+	assert.Equal(t, "click", diagnoses[1].Provider)
+	assert.False(t, diagnoses[1].Healthy)
+	assert.False(t, diagnoses[1].IsConfigurationFault())
+
+	assert.Equal(t, "bpay", diagnoses[2].Provider)
+	assert.True(t, diagnoses[2].Healthy)
+	assert.Equal(t, "bpay: ok", diagnoses[2].String())
+}
@@ -0,0 +1,117 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// ProcessDisbursement sends money from the merchant account to the wallet
+// of request.PhoneNumber through request.Provider, or the default provider
+// when it is empty. Providers that do not implement Disburser return
+// ErrDisbursementNotSupported, and wallets of operators the provider cannot
+// credit fail validation on phone_number.
+func (c *Client) ProcessDisbursement(ctx context.Context, request *DisbursementRequest) (*DisbursementResponse, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	name, disburser, err := c.disburser(request.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	op := c.ResolveOperator(ctx, request.PhoneNumber)
+	if !supportsOperator(disburser.DisbursementOperators(), op) {
+		return nil, NewValidationError("phone_number",
+			fmt.Sprintf("%s cannot disburse to %s wallets", name, operatorName(op)))
+	}
+	if err := c.checkCurrency(name, request.Amount); err != nil {
+		return nil, err
+	}
+	if err := c.checkReference(name, request.Reference); err != nil {
+		return nil, err
+	}
+
+	ctx, _, err = ensureCorrelationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result *DisbursementResponse
+	err = c.invoke(ctx, name, func() (err error) {
+		result, err = disburser.ProcessDisbursement(ctx, request)
+		return err
+	})
+	return result, err
+}
+
+// GetDisbursementStatus checks a disbursement sent through providerName,
+// or the default provider when it is empty
+func (c *Client) GetDisbursementStatus(ctx context.Context, providerName, reference string) (*DisbursementResponse, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	if reference == "" {
+		return nil, NewValidationError("reference", "is required")
+	}
+
+	name, disburser, err := c.disburser(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *DisbursementResponse
+	err = c.invoke(ctx, name, func() (err error) {
+		result, err = disburser.GetDisbursementStatus(ctx, reference)
+		return err
+	})
+	return result, err
+}
+
+// disburser returns the named provider, or the default one, as a Disburser
+func (c *Client) disburser(name string) (string, Disburser, error) {
+	var provider PaymentProvider
+	if name == "" {
+		provider = c.defaultProvider()
+	} else {
+		p, ok := c.registered(name)
+		if !ok {
+			return "", nil, fmt.Errorf(providerNotAvailableMsg, name)
+		}
+		provider = p
+	}
+	if provider == nil {
+		return "", nil, ErrProviderNotFound
+	}
+
+	disburser, ok := provider.(Disburser)
+	if !ok {
+		return "", nil, fmt.Errorf("%s: %w", provider.Name(), ErrDisbursementNotSupported)
+	}
+	return provider.Name(), disburser, nil
+}
+
+// supportsOperator reports whether op is one of operators
+func supportsOperator(operators []phone.Operator, op phone.Operator) bool {
+	for _, supported := range operators {
+		if op == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// operatorName names op in error messages
+func operatorName(op phone.Operator) string {
+	if op == phone.OperatorUnknown {
+		return "unknown operator"
+	}
+	return string(op)
+}
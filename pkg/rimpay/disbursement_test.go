@@ -0,0 +1,121 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// disbursingProvider records the disbursements that reach it
+type disbursingProvider struct {
+	namedProvider
+	operators     []phone.Operator
+	disbursements []*DisbursementRequest
+}
+
+func (p *disbursingProvider) ProcessDisbursement(_ context.Context, request *DisbursementRequest) (*DisbursementResponse, error) {
+	p.disbursements = append(p.disbursements, request)
+	return &DisbursementResponse{DisbursementID: "DB-" + p.name, Reference: request.Reference,
+		Status: DisbursementStatusCompleted, Provider: p.name}, nil
+}
+
+func (p *disbursingProvider) GetDisbursementStatus(_ context.Context, reference string) (*DisbursementResponse, error) {
+	return &DisbursementResponse{Reference: reference, Status: DisbursementStatusPending, Provider: p.name}, nil
+}
+
+func (p *disbursingProvider) DisbursementOperators() []phone.Operator {
+	return p.operators
+}
+
+func newDisbursementTestClient(t *testing.T) (*Client, *disbursingProvider) {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderBPay
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	bpay := &disbursingProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		operators:     []phone.Operator{phone.OperatorMauritel},
+	}
+	require.NoError(t, client.AddProvider(ProviderBPay, bpay))
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &namedProvider{name: ProviderMasrvi}))
+	return client, bpay
+}
+
+func disbursementTo(t *testing.T, number string) *DisbursementRequest {
+	t.Helper()
+	p, err := phone.NewPhone(number)
+	require.NoError(t, err)
+	return &DisbursementRequest{PhoneNumber: p, Amount: money.NewMRU(5000), Reference: "DB-1"}
+}
+
+func TestClientProcessDisbursement(t *testing.T) {
+	client, bpay := newDisbursementTestClient(t)
+	request := disbursementTo(t, "22334455")
+
+	resp, err := client.ProcessDisbursement(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "DB-bpay", resp.DisbursementID)
+	assert.Equal(t, DisbursementStatusCompleted, resp.Status)
+	assert.Equal(t, []*DisbursementRequest{request}, bpay.disbursements)
+
+	status, err := client.GetDisbursementStatus(context.Background(), "", "DB-1")
+	require.NoError(t, err)
+	assert.Equal(t, DisbursementStatusPending, status.Status)
+}
+
+func TestClientProcessDisbursementErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  func(t *testing.T) *DisbursementRequest
+		wantErr  string
+		wantIs   error
+		wantCode ErrorCode
+	}{
+		{"nil request", func(*testing.T) *DisbursementRequest { return nil }, "", ErrInvalidRequest, ""},
+		{"missing phone", func(*testing.T) *DisbursementRequest {
+			return &DisbursementRequest{Amount: money.NewMRU(100), Reference: "DB-1"}
+		}, "phone_number: is required", nil, ""},
+		{"unsupported operator", func(t *testing.T) *DisbursementRequest {
+			return disbursementTo(t, "44556677")
+		}, "bpay cannot disburse to mattel wallets", nil, ErrorCodeValidationError},
+		{"unsupported provider", func(t *testing.T) *DisbursementRequest {
+			request := disbursementTo(t, "22334455")
+			request.Provider = ProviderMasrvi
+			return request
+		}, "", ErrDisbursementNotSupported, ""},
+		{"unknown provider", func(t *testing.T) *DisbursementRequest {
+			request := disbursementTo(t, "22334455")
+			request.Provider = "sedad"
+			return request
+		}, "provider sedad not available", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, bpay := newDisbursementTestClient(t)
+
+			_, err := client.ProcessDisbursement(context.Background(), tt.request(t))
+			require.Error(t, err)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			} else {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			if tt.wantCode != "" {
+				var paymentErr *PaymentError
+				require.ErrorAs(t, err, &paymentErr)
+				assert.Equal(t, tt.wantCode, paymentErr.Code)
+			}
+			assert.Empty(t, bpay.disbursements)
+		})
+	}
+}
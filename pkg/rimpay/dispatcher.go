@@ -0,0 +1,257 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// NotificationDispatcher runs a handler once per unique notification. Each
+// notification is claimed in a NotificationDeduplicator before its status
+// is recorded or the handler runs, so re-deliveries are acknowledged without
+// side effects. Notifications for the same payment are processed one at a
+// time, so a duplicate arriving while the first is handled waits for its
+// outcome. A handler error releases the claim, and the provider's
+// re-delivery is processed.
+//
+// One dispatcher should serve all the webhook entry points of a client:
+// NotificationDispatcher.NewMasrviWebhookHandler, NewWebhookRouter with
+// WithNotificationDispatcher, and the Dispatcher of MasrviWebhookOptions and
+// BPayWebhookOptions. Create it with Client.NewNotificationDispatcher.
+type NotificationDispatcher struct {
+	client *Client
+	dedup  NotificationDeduplicator
+
+	mu    sync.Mutex
+	locks map[string]*referenceLock
+}
+
+// referenceLock serializes the notifications of one payment
+type referenceLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+// NewNotificationDispatcher creates a dispatcher remembering notifications
+// in dedup, which several instances may share, or in a MemoryDeduplicator
+// with DefaultNotificationTTL when nil
+func (c *Client) NewNotificationDispatcher(dedup NotificationDeduplicator) *NotificationDispatcher {
+	if dedup == nil {
+		dedup = NewMemoryDeduplicator(0)
+	}
+	return newNotificationDispatcher(c, dedup)
+}
+
+// newNotificationDispatcher creates a dispatcher; a nil dedup only
+// serializes notifications
+func newNotificationDispatcher(c *Client, dedup NotificationDeduplicator) *NotificationDispatcher {
+	return &NotificationDispatcher{
+		client: c,
+		dedup:  dedup,
+		locks:  make(map[string]*referenceLock),
+	}
+}
+
+// errNotificationShed is returned for notifications refused by
+// inboundNotification.admit
+var errNotificationShed = errors.New("notification shed")
+
+// processingError wraps the deduplication or handler failure of a verified
+// notification, which the provider should re-deliver
+type processingError struct {
+	err error
+}
+
+func (e *processingError) Error() string {
+	return e.err.Error()
+}
+
+func (e *processingError) Unwrap() error {
+	return e.err
+}
+
+// inboundNotification is a notification on its way through a dispatcher
+type inboundNotification struct {
+	provider  string
+	reference string
+	// admit is called under the payment's lock before verify; false sheds
+	// the notification. release gives back what it took.
+	admit func() (release func(), ok bool)
+	// verify authenticates the notification without side effects and
+	// returns its status and deduplication key. A status returned with an
+	// ErrAmountMismatch error is dispatched.
+	verify func(ctx context.Context) (*TransactionStatus, string, error)
+	// record stores the claimed status in the TransactionStore
+	record bool
+	handle NotificationFunc
+}
+
+// Dispatch records status, a notification from provider that was already
+// verified, and runs handle for it unless it was dispatched before.
+// duplicate reports a notification acknowledged without side effects.
+func (d *NotificationDispatcher) Dispatch(ctx context.Context, provider string, status *TransactionStatus, handle NotificationFunc) (duplicate bool, err error) {
+	if status == nil || handle == nil {
+		return false, ErrInvalidRequest
+	}
+	_, duplicate, err = d.dispatch(ctx, d.verified(provider, status, handle))
+	return duplicate, err
+}
+
+// DispatchMasrvi verifies a MASRVI notification as
+// Client.HandleMasrviNotification does and dispatches it. A notification
+// with the wrong amount is dispatched with its failed status, and
+// ErrAmountMismatch is returned.
+func (d *NotificationDispatcher) DispatchMasrvi(ctx context.Context, notification *MasrviNotificationData, handle NotificationFunc) (status *TransactionStatus, duplicate bool, err error) {
+	if notification == nil || handle == nil {
+		return nil, false, ErrInvalidRequest
+	}
+	return d.dispatch(ctx, d.masrvi(notification, handle))
+}
+
+// DispatchBPay maps a B-PAY callback as Client.HandleBPayNotification does
+// and dispatches it. The callback's secret must already be checked.
+func (d *NotificationDispatcher) DispatchBPay(ctx context.Context, notification *BPayNotificationData, handle NotificationFunc) (status *TransactionStatus, duplicate bool, err error) {
+	if notification == nil || handle == nil {
+		return nil, false, ErrInvalidRequest
+	}
+	return d.dispatch(ctx, d.bpay(notification, handle))
+}
+
+// verified describes a status that needs no verification
+func (d *NotificationDispatcher) verified(provider string, status *TransactionStatus, handle NotificationFunc) inboundNotification {
+	return inboundNotification{
+		provider:  provider,
+		reference: status.Reference,
+		verify: func(context.Context) (*TransactionStatus, string, error) {
+			return status, dispatchKey(provider, status), nil
+		},
+		record: true,
+		handle: handle,
+	}
+}
+
+// masrvi describes a MASRVI notification verified by the provider
+func (d *NotificationDispatcher) masrvi(notification *MasrviNotificationData, handle NotificationFunc) inboundNotification {
+	return inboundNotification{
+		provider:  ProviderMasrvi,
+		reference: notification.Reference,
+		verify: func(ctx context.Context) (*TransactionStatus, string, error) {
+			status, err := d.client.verifyMasrviNotification(ctx, notification)
+			return status, notificationKey(notification), err
+		},
+		record: true,
+		handle: handle,
+	}
+}
+
+// bpay describes a B-PAY callback mapped by the provider
+func (d *NotificationDispatcher) bpay(notification *BPayNotificationData, handle NotificationFunc) inboundNotification {
+	return inboundNotification{
+		provider:  ProviderBPay,
+		reference: notification.OperationID,
+		verify: func(context.Context) (*TransactionStatus, string, error) {
+			status, err := d.client.verifyBPayNotification(notification)
+			return status, bpayNotificationKey(notification), err
+		},
+		record: true,
+		handle: handle,
+	}
+}
+
+// dispatch verifies n under its payment's lock, claims it and then records
+// its status and runs its handler. On a nil dispatcher it only verifies and
+// handles n. Deduplication and handler failures are processingErrors.
+func (d *NotificationDispatcher) dispatch(ctx context.Context, n inboundNotification) (status *TransactionStatus, duplicate bool, err error) {
+	if d != nil {
+		unlock := d.lock(n.provider + ":" + n.reference)
+		defer unlock()
+	}
+
+	if n.admit != nil {
+		release, ok := n.admit()
+		if !ok {
+			return nil, false, errNotificationShed
+		}
+		defer release()
+	}
+
+	status, key, verifyErr := n.verify(ctx)
+	if verifyErr != nil && !errors.Is(verifyErr, ErrAmountMismatch) {
+		return status, false, verifyErr
+	}
+	if d == nil {
+		if err := n.handle(ctx, status); err != nil {
+			return status, false, &processingError{err: err}
+		}
+		return status, false, verifyErr
+	}
+
+	if d.dedup != nil {
+		claimed, err := d.dedup.Claim(ctx, key)
+		if err != nil {
+			return status, false, &processingError{err: err}
+		}
+		if !claimed {
+			return status, true, verifyErr
+		}
+	}
+
+	if n.record {
+		d.client.recordStatus(ctx, status)
+	}
+	if err := n.handle(ctx, status); err != nil {
+		if d.dedup != nil {
+			if err := d.dedup.Release(ctx, key); err != nil {
+				d.client.logger.Warn("Notification claim not released", "provider", n.provider, "reference", n.reference, "error", err)
+			}
+		}
+		return status, false, &processingError{err: err}
+	}
+
+	if d.dedup != nil {
+		if err := d.dedup.Complete(ctx, key); err != nil {
+			// The handler ran; a re-delivery may run it again
+			d.client.logger.Warn("Notification processed but not recorded", "provider", n.provider, "reference", n.reference, "error", err)
+		}
+	}
+	return status, false, verifyErr
+}
+
+// lock takes the lock of key and returns its release
+func (d *NotificationDispatcher) lock(key string) func() {
+	d.mu.Lock()
+	l, ok := d.locks[key]
+	if !ok {
+		l = &referenceLock{}
+		d.locks[key] = l
+	}
+	l.waiters++
+	d.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		d.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(d.locks, key)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// dispatchKey identifies a verified notification by provider, provider
+// reference and status, falling back to the payment reference
+func dispatchKey(provider string, status *TransactionStatus) string {
+	ref := status.ProviderReference
+	if ref == "" {
+		ref = status.Reference
+	}
+	return provider + ":" + ref + ":" + string(status.Status)
+}
+
+// bpayNotificationKey identifies a B-PAY callback for deduplication
+func bpayNotificationKey(n *BPayNotificationData) string {
+	return ProviderBPay + ":" + n.OperationID + ":" + n.TransactionID + ":" + strings.ToUpper(n.Status) + ":" + n.ErrorCode
+}
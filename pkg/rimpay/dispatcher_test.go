@@ -0,0 +1,200 @@
+package rimpay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the statuses recorded
+type countingStore struct {
+	*MemoryTransactionStore
+	updates int64
+}
+
+func (s *countingStore) UpdateStatus(ctx context.Context, status *TransactionStatus) error {
+	atomic.AddInt64(&s.updates, 1)
+	return s.MemoryTransactionStore.UpdateStatus(ctx, status)
+}
+
+func masrviStatusRequest(reference, status string) *http.Request {
+	form := url.Values{"status": {status}, "purchaseref": {reference}, "paymentref": {"PAY-" + reference}}
+	return httptest.NewRequest(http.MethodPost, "/webhook?"+form.Encode(), nil)
+}
+
+func TestNotificationDispatcherRunsHandlerOnce(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	store := &countingStore{MemoryTransactionStore: NewMemoryTransactionStore()}
+	client.WithTransactionStore(store)
+	var calls int64
+	handler := client.NewNotificationDispatcher(nil).NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(10 * time.Millisecond) // slow enough for duplicates to overlap
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	for _, code := range codes {
+		assert.Equal(t, http.StatusOK, code, "duplicates are acknowledged")
+	}
+	assert.Equal(t, int64(1), atomic.LoadInt64(&store.updates), "duplicates are not recorded")
+	assert.Empty(t, handler.dispatcher.locks, "reference locks are released")
+}
+
+func TestNotificationDispatcherSkipsSideEffectsOfDuplicates(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	store := &countingStore{MemoryTransactionStore: NewMemoryTransactionStore()}
+	client.WithTransactionStore(store)
+	var statuses []PaymentStatus
+	handler := client.NewMasrviWebhookHandler(func(_ context.Context, status *TransactionStatus) error {
+		statuses = append(statuses, status.Status)
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	for _, req := range []*http.Request{
+		masrviStatusRequest("ORDER-1", "OK"),
+		masrviStatusRequest("ORDER-1", "NOK"),
+		masrviStatusRequest("ORDER-1", "OK"),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.Equal(t, []PaymentStatus{PaymentStatusSuccess, PaymentStatusPending}, statuses)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&store.updates), "the re-delivered success is not recorded again")
+	stored, err := store.GetByReference(context.Background(), "ORDER-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusPending, stored.Status)
+}
+
+func TestNotificationDispatcherWaitingDuplicateHoldsNoSlot(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{MaxInFlight: 2})
+	entered := make(chan string, 3)
+	unblock := make(chan struct{})
+	handler := client.NewMasrviWebhookHandler(func(_ context.Context, status *TransactionStatus) error {
+		entered <- status.Reference
+		if status.Reference == "ORDER-1" {
+			<-unblock
+		}
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	var wg sync.WaitGroup
+	serve := func(reference string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(rec, notificationRequest(reference))
+		}()
+		return rec
+	}
+
+	first := serve("ORDER-1")
+	assert.Equal(t, "ORDER-1", <-entered)
+	duplicate := serve("ORDER-1")
+	require.Eventually(t, func() bool {
+		handler.dispatcher.mu.Lock()
+		defer handler.dispatcher.mu.Unlock()
+		l, ok := handler.dispatcher.locks[ProviderMasrvi+":ORDER-1"]
+		return ok && l.waiters == 2
+	}, time.Second, time.Millisecond)
+
+	other := httptest.NewRecorder()
+	handler.ServeHTTP(other, notificationRequest("ORDER-2"))
+	assert.Equal(t, http.StatusOK, other.Code, "the waiting duplicate leaves the second slot free")
+	assert.Equal(t, "ORDER-2", <-entered)
+	assert.Zero(t, handler.Shed())
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, duplicate.Code)
+	assert.Empty(t, entered, "the duplicate is not handled")
+}
+
+func TestNotificationDispatcherSharedByEntryPoints(t *testing.T) {
+	client := newRouterTestClient(t)
+	dispatcher := client.NewNotificationDispatcher(nil)
+	var calls int
+	masrvi := dispatcher.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		calls++
+		return nil
+	})
+	router, received := newTestRouter(t, client, WithNotificationDispatcher(dispatcher))
+
+	rec := httptest.NewRecorder()
+	masrvi.ServeHTTP(rec, notificationRequest("ORDER-1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req := notificationRequest("ORDER-1")
+	req.URL.Path = "/webhooks/masrvi"
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *received, "the router acknowledges the notification the handler processed")
+}
+
+func TestWebhookRouterSkipsDuplicates(t *testing.T) {
+	client := newRouterTestClient(t)
+	router, received := newTestRouter(t, client)
+
+	custom := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/acme?ref=ORDER-3", nil)
+		req.Header.Set("X-Token", "acme-token")
+		return req
+	}
+	for i := 0; i < 3; i++ {
+		for _, req := range []*http.Request{
+			bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS"}`),
+			custom(),
+		} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	}
+	assert.Equal(t, []routedNotification{{ProviderBPay, "ORDER-2"}, {"acme", "ORDER-3"}}, *received)
+}
+
+func TestBPayWebhookHandlerDispatcherSkipsDuplicates(t *testing.T) {
+	client := newRouterTestClient(t)
+	opts := BPayWebhookOptions{Dispatcher: client.NewNotificationDispatcher(nil)}
+	var received []*BPayNotificationData
+	opts.Handle = func(_ context.Context, n *BPayNotificationData) error {
+		received = append(received, n)
+		return nil
+	}
+	opts.Secret = testCallbackSecret
+	handler := NewBPayWebhookHandler(opts)
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS"}`))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Len(t, received, 1)
+}
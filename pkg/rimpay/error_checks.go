@@ -0,0 +1,38 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+)
+
+// CodeOf returns the code of the outermost PaymentError in err's chain, or
+// "" when there is none. ValidationErrors have the VALIDATION_ERROR code.
+func CodeOf(err error) ErrorCode {
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) && paymentErr != nil {
+		return paymentErr.Code
+	}
+	return ""
+}
+
+// IsRetryable reports whether sending the request again may succeed: a
+// PaymentError says so itself, and other errors are retryable when they
+// are network failures or timeouts
+func IsRetryable(err error) bool {
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) && paymentErr != nil {
+		return paymentErr.IsRetryable()
+	}
+	return errors.Is(err, ErrNetworkError) || errors.Is(err, ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsValidation reports whether err rejects the request itself, so that
+// sending it again unchanged cannot succeed
+func IsValidation(err error) bool {
+	switch CodeOf(err) {
+	case ErrorCodeValidationError, ErrorCodeInvalidRequest:
+		return true
+	}
+	return errors.Is(err, ErrInvalidRequest)
+}
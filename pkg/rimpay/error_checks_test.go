@@ -0,0 +1,74 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorChecks(t *testing.T) {
+	deep := func(err error) error {
+		return fmt.Errorf("checkout: %w", fmt.Errorf("order 42: %w", err))
+	}
+	validation := ValidationErrors{{Field: "amount", Message: "must be positive"}}
+
+	tests := []struct {
+		name       string
+		err        error
+		code       ErrorCode
+		retryable  bool
+		validation bool
+		sentinel   error
+	}{
+		{"nil", nil, "", false, false, nil},
+		{"timeout", deep(NewPaymentError(ErrorCodeTimeout, "timed out", ProviderBPay, true)),
+			ErrorCodeTimeout, true, false, ErrTimeout},
+		{"declined", deep(NewPaymentError(ErrorCodePaymentDeclined, "refused", ProviderBPay, false)),
+			ErrorCodePaymentDeclined, false, false, ErrPaymentFailed},
+		{"merchant balance", deep(NewPaymentError(ErrorCodeInsufficientMerchantBalance, "balance too low", ProviderBPay, false)),
+			ErrorCodeInsufficientMerchantBalance, false, false, ErrPaymentFailed},
+		{"busy", deep(NewPaymentError(ErrorCodeTooManyRequests, "full", ProviderBPay, true)),
+			ErrorCodeTooManyRequests, true, false, ErrRateLimitExceeded},
+		{"validation errors", deep(validation), ErrorCodeValidationError, false, true, ErrInvalidRequest},
+		{"validation error", deep(NewValidationError("reference", "is required")),
+			ErrorCodeValidationError, false, true, ErrInvalidRequest},
+		{"invalid request sentinel", deep(ErrInvalidRequest), "", false, true, ErrInvalidRequest},
+		{"network sentinel", deep(ErrNetworkError), "", true, false, ErrNetworkError},
+		{"deadline", deep(context.DeadlineExceeded), "", true, false, context.DeadlineExceeded},
+		{"plain error", deep(errors.New("boom")), "", false, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, CodeOf(tt.err))
+			assert.Equal(t, tt.retryable, IsRetryable(tt.err))
+			assert.Equal(t, tt.validation, IsValidation(tt.err))
+			if tt.sentinel != nil {
+				assert.ErrorIs(t, tt.err, tt.sentinel)
+			}
+		})
+	}
+}
+
+func TestPaymentErrorMatchesOnlyItsSentinel(t *testing.T) {
+	err := NewPaymentError(ErrorCodeProviderError, "down", ProviderBPay, true).WithDetail("http_status", 503)
+
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+	assert.NotErrorIs(t, err, ErrTimeout)
+	assert.NotErrorIs(t, err, ErrInvalidRequest)
+	assert.NotErrorIs(t, NewPaymentError("MYSTERY", "?", ProviderBPay, false), ErrPaymentFailed)
+}
+
+func TestProviderErrorUnavailableOnlyForServerErrors(t *testing.T) {
+	assert.ErrorIs(t, NewPaymentError(ErrorCodeProviderBusy, "busy", ProviderBPay, true), ErrProviderUnavailable)
+	assert.ErrorIs(t, NewPaymentError(ErrorCodeProviderTLSError, "bad certificate", ProviderBPay, false), ErrProviderUnavailable)
+
+	decode := NewPaymentError(ErrorCodeProviderError, "failed to parse response", ProviderBPay, false).
+		WithDetail("http_status", 200)
+	assert.NotErrorIs(t, decode, ErrProviderUnavailable, "a response that cannot be decoded")
+	assert.NotErrorIs(t, NewPaymentError(ErrorCodeProviderError, "invalid session response: NOK", ProviderMasrvi, false),
+		ErrProviderUnavailable, "an error without a status")
+}
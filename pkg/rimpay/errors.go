@@ -7,8 +7,16 @@ import (
 
 // Re-export types from internal/types for public API
 type (
-	ErrorCode    = types.ErrorCode
-	PaymentError = types.PaymentError
+	ErrorCode     = types.ErrorCode
+	PaymentError  = types.PaymentError
+	AttemptRecord = types.AttemptRecord
+	SupportReport = types.SupportReport
+	SupportConfig = types.SupportConfig
+
+	// ValidationErrors is returned by request Validate methods with every
+	// failed field
+	ValidationErrors = types.ValidationErrors
+	FieldError       = types.FieldError
 )
 
 // Re-export constants
@@ -22,6 +30,22 @@ const (
 	ErrorCodeProviderError        = types.ErrorCodeProviderError
 	ErrorCodeValidationError      = types.ErrorCodeValidationError
 	ErrorCodePaymentExpired       = types.ErrorCodePaymentExpired
+	ErrorCodeProviderBusy         = types.ErrorCodeProviderBusy
+	ErrorCodeProviderTLSError     = types.ErrorCodeProviderTLSError
+	ErrorCodeRateLimited          = types.ErrorCodeRateLimited
+	ErrorCodeTooManyRequests      = types.ErrorCodeTooManyRequests
+
+	ErrorCodeInsufficientMerchantBalance = types.ErrorCodeInsufficientMerchantBalance
+)
+
+// PaymentError.Details keys set by the retry and HTTP layers
+const (
+	DetailAttempts        = types.DetailAttempts
+	DetailAttemptCount    = types.DetailAttemptCount
+	DetailTotalRetryDelay = types.DetailTotalRetryDelay
+	// DetailRawResponse holds the sanitized, truncated body of a provider
+	// response that could not be decoded, as []byte
+	DetailRawResponse = types.DetailRawResponse
 )
 
 // Re-export constructor functions
@@ -41,4 +65,35 @@ var (
 	ErrAuthenticationFailed = errors.ErrAuthenticationFailed
 	ErrNetworkError         = errors.ErrNetworkError
 	ErrTimeout              = errors.ErrTimeout
+	ErrReturnMismatch       = errors.ErrReturnMismatch
+	ErrPollingExhausted     = errors.ErrPollingExhausted
+	ErrNoRoute              = errors.ErrNoRoute
+	ErrRefundNotSupported   = errors.ErrRefundNotSupported
+	ErrStatusNotSupported   = errors.ErrStatusNotSupported
+	ErrTransactionNotFound  = errors.ErrTransactionNotFound
+	ErrClientClosed         = errors.ErrClientClosed
+	ErrCredentialNotFound   = errors.ErrCredentialNotFound
+
+	ErrScheduledPaymentNotFound = errors.ErrScheduledPaymentNotFound
+	ErrScheduleConflict         = errors.ErrScheduleConflict
+	ErrSchedulerRunning         = errors.ErrSchedulerRunning
+	ErrSubscriptionNotFound     = errors.ErrSubscriptionNotFound
+	ErrSubscriptionState        = errors.ErrSubscriptionState
+	ErrNotificationRejected     = errors.ErrNotificationRejected
+	ErrNoRedirect               = errors.ErrNoRedirect
+	ErrIntentNotFound           = errors.ErrIntentNotFound
+	ErrIntentConflict           = errors.ErrIntentConflict
+	ErrIntentSucceeded          = errors.ErrIntentSucceeded
+	ErrAmountMismatch           = errors.ErrAmountMismatch
+	ErrAmountUnverified         = errors.ErrAmountUnverified
+	ErrDisbursementNotSupported = errors.ErrDisbursementNotSupported
+	ErrLinkNotFound             = errors.ErrLinkNotFound
+	ErrLinkExpired              = errors.ErrLinkExpired
+	ErrLinkUsed                 = errors.ErrLinkUsed
+
+	// ErrProviderUnavailable matches PROVIDER_TLS_ERROR and PROVIDER_BUSY
+	// errors, and PROVIDER_ERROR errors for a 5xx response
+	ErrProviderUnavailable = errors.ErrProviderUnavailable
+	// ErrRateLimitExceeded matches RATE_LIMITED and TOO_MANY_REQUESTS errors
+	ErrRateLimitExceeded = errors.ErrRateLimitExceeded
 )
@@ -0,0 +1,236 @@
+package rimpay
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// ExportFormat is the file format of ExportTransactions
+type ExportFormat string
+
+const (
+	// ExportCSV writes a header row and one row per transaction
+	ExportCSV ExportFormat = "csv"
+	// ExportJSONLines writes one JSON object per line
+	ExportJSONLines ExportFormat = "jsonl"
+)
+
+// ExportColumns are the CSV columns of ExportTransactions, in order
+var ExportColumns = []string{
+	"transaction_id", "reference", "provider", "provider_reference",
+	"status", "amount", "message", "created_at", "last_updated",
+}
+
+// ExportFilter selects the transactions of ExportTransactions; zero fields
+// select everything
+type ExportFilter struct {
+	// From and To bound CreatedAt: From is inclusive, To exclusive
+	From time.Time
+	To   time.Time
+	// Provider and Status must equal the transaction's
+	Provider string
+	Status   PaymentStatus
+	// MinAmount, when set, excludes smaller amounts and amounts in other
+	// currencies
+	MinAmount money.Money
+}
+
+// Matches reports whether the filter selects stored
+func (f ExportFilter) Matches(stored *StoredTransaction) bool {
+	if !f.From.IsZero() && stored.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !stored.CreatedAt.Before(f.To) {
+		return false
+	}
+	if f.Provider != "" && stored.Provider != f.Provider {
+		return false
+	}
+	if f.Status != "" && stored.Status != f.Status {
+		return false
+	}
+	if f.MinAmount.Currency() != "" {
+		cmp, err := stored.Amount.Compare(f.MinAmount)
+		if err != nil || cmp < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TransactionScanner is implemented by transaction stores that can stream
+// their transactions, as ExportTransactions requires
+type TransactionScanner interface {
+	// ScanTransactions calls fn with every transaction the filter selects,
+	// oldest first, without their events. It stops at the first error fn
+	// returns and returns it.
+	ScanTransactions(ctx context.Context, filter ExportFilter, fn func(*StoredTransaction) error) error
+}
+
+// ScanTransactions calls fn with the selected transactions, oldest first
+func (s *MemoryTransactionStore) ScanTransactions(ctx context.Context, filter ExportFilter, fn func(*StoredTransaction) error) error {
+	s.mu.Lock()
+	var selected []*StoredTransaction
+	for _, stored := range s.transactions {
+		if filter.Matches(stored) {
+			selected = append(selected, stored)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].CreatedAt.Before(selected[j].CreatedAt)
+	})
+
+	// fn runs unlocked, so that slow writers do not hold up payments
+	for _, stored := range selected {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		dup := *stored
+		s.mu.Unlock()
+		dup.Events = nil
+		if err := fn(&dup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportTransactions writes the transactions of the client's
+// TransactionStore selected by filter to w, oldest first. Rows are streamed
+// from the store, which must implement TransactionScanner, so exports of any
+// size use constant memory.
+//
+// CSV files have the ExportColumns. Amounts are written with Money.String,
+// and text starting with =, +, - or @ is prefixed with ' so spreadsheets do
+// not evaluate it as a formula. JSON lines carry the same fields, with
+// amounts in Money's JSON form.
+func (c *Client) ExportTransactions(ctx context.Context, filter ExportFilter, w io.Writer, format ExportFormat) error {
+	store := c.transactionStore()
+	if store == nil {
+		return fmt.Errorf("export transactions: no transaction store: %w", ErrInvalidConfig)
+	}
+	scanner, ok := store.(TransactionScanner)
+	if !ok {
+		return fmt.Errorf("export transactions: %T cannot scan transactions: %w", store, ErrInvalidConfig)
+	}
+
+	var writer exportWriter
+	switch format {
+	case ExportCSV:
+		writer = newCSVExportWriter(w)
+	case ExportJSONLines:
+		writer = &jsonExportWriter{encoder: json.NewEncoder(w)}
+	default:
+		return fmt.Errorf("export transactions: unknown format %q: %w", format, ErrInvalidRequest)
+	}
+
+	if err := writer.begin(); err != nil {
+		return err
+	}
+	if err := scanner.ScanTransactions(ctx, filter, writer.write); err != nil {
+		return err
+	}
+	return writer.end()
+}
+
+// exportWriter writes an export in one format
+type exportWriter interface {
+	begin() error
+	write(stored *StoredTransaction) error
+	end() error
+}
+
+// csvExportWriter writes ExportCSV
+type csvExportWriter struct {
+	writer *csv.Writer
+	row    []string
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w), row: make([]string, len(ExportColumns))}
+}
+
+func (e *csvExportWriter) begin() error {
+	return e.writer.Write(ExportColumns)
+}
+
+func (e *csvExportWriter) write(stored *StoredTransaction) error {
+	e.row[0] = csvText(stored.TransactionID)
+	e.row[1] = csvText(stored.Reference)
+	e.row[2] = csvText(stored.Provider)
+	e.row[3] = csvText(stored.ProviderReference)
+	e.row[4] = string(stored.Status)
+	e.row[5] = stored.Amount.String()
+	e.row[6] = csvText(stored.Message)
+	e.row[7] = exportTime(stored.CreatedAt)
+	e.row[8] = exportTime(stored.LastUpdated)
+	return e.writer.Write(e.row)
+}
+
+func (e *csvExportWriter) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// csvText neutralizes text a spreadsheet would evaluate as a formula
+func csvText(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// exportRecord is a JSON line of ExportJSONLines
+type exportRecord struct {
+	TransactionID     string        `json:"transaction_id"`
+	Reference         string        `json:"reference"`
+	Provider          string        `json:"provider"`
+	ProviderReference string        `json:"provider_reference"`
+	Status            PaymentStatus `json:"status"`
+	Amount            money.Money   `json:"amount"`
+	Message           string        `json:"message"`
+	CreatedAt         string        `json:"created_at"`
+	LastUpdated       string        `json:"last_updated"`
+}
+
+// jsonExportWriter writes ExportJSONLines
+type jsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonExportWriter) begin() error { return nil }
+func (e *jsonExportWriter) end() error   { return nil }
+
+func (e *jsonExportWriter) write(stored *StoredTransaction) error {
+	return e.encoder.Encode(exportRecord{
+		TransactionID:     stored.TransactionID,
+		Reference:         stored.Reference,
+		Provider:          stored.Provider,
+		ProviderReference: stored.ProviderReference,
+		Status:            stored.Status,
+		Amount:            stored.Amount,
+		Message:           stored.Message,
+		CreatedAt:         exportTime(stored.CreatedAt),
+		LastUpdated:       exportTime(stored.LastUpdated),
+	})
+}
@@ -0,0 +1,204 @@
+package rimpay
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportDay = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func exportTestClient(t *testing.T, store TransactionStore) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.WithTransactionStore(store)
+	return client
+}
+
+func saveExportPayment(t *testing.T, store TransactionStore, id, reference, provider string, amount money.Money, status PaymentStatus, at time.Time) {
+	t.Helper()
+	require.NoError(t, store.SavePayment(context.Background(), &PaymentResponse{
+		TransactionID: id, Reference: reference, Provider: provider, Amount: amount,
+		Status: status, CreatedAt: at, UpdatedAt: at,
+	}))
+}
+
+func TestExportTransactionsCSVColumns(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	saveExportPayment(t, store, "TX-2", `INV "7", April`, ProviderMasrvi, money.NewMRU(150050), PaymentStatusPending, exportDay.Add(time.Hour))
+	saveExportPayment(t, store, "TX-1", "=HYPERLINK(\"x\")", ProviderBPay, money.NewMRU(1000), PaymentStatusSuccess, exportDay)
+
+	var out bytes.Buffer
+	require.NoError(t, exportTestClient(t, store).ExportTransactions(context.Background(), ExportFilter{}, &out, ExportCSV))
+
+	assert.True(t, strings.HasPrefix(out.String(),
+		"transaction_id,reference,provider,provider_reference,status,amount,message,created_at,last_updated\n"))
+	assert.Contains(t, out.String(), `"INV ""7"", April"`, "references are quoted")
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, ExportColumns, rows[0])
+	assert.Equal(t, []string{"TX-1", `'=HYPERLINK("x")`, ProviderBPay, "", "success", "10.00 MRU", "",
+		"2026-03-01T09:00:00Z", "2026-03-01T09:00:00Z"}, rows[1], "oldest first")
+	assert.Equal(t, []string{"TX-2", `INV "7", April`, ProviderMasrvi, "", "pending", "1500.50 MRU", "",
+		"2026-03-01T10:00:00Z", "2026-03-01T10:00:00Z"}, rows[2])
+}
+
+func TestExportTransactionsJSONLines(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	saveExportPayment(t, store, "TX-1", "REF-1", ProviderBPay, money.NewMRU(150050), PaymentStatusSuccess, exportDay)
+
+	var out bytes.Buffer
+	require.NoError(t, exportTestClient(t, store).ExportTransactions(context.Background(), ExportFilter{}, &out, ExportJSONLines))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+	assert.JSONEq(t, `{"transaction_id":"TX-1","reference":"REF-1","provider":"bpay","provider_reference":"",
+		"status":"success","amount":{"amount":"1500.5","currency":"MRU"},"message":"",
+		"created_at":"2026-03-01T09:00:00Z","last_updated":"2026-03-01T09:00:00Z"}`, lines[0])
+
+	var record struct {
+		Amount money.Money `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.True(t, record.Amount.Equals(money.NewMRU(150050)))
+}
+
+func TestExportTransactionsFilter(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	saveExportPayment(t, store, "feb", "R1", ProviderBPay, money.NewMRU(500000), PaymentStatusSuccess, exportDay.AddDate(0, -1, 0))
+	saveExportPayment(t, store, "small", "R2", ProviderBPay, money.NewMRU(100), PaymentStatusSuccess, exportDay)
+	saveExportPayment(t, store, "masrvi", "R3", ProviderMasrvi, money.NewMRU(500000), PaymentStatusSuccess, exportDay)
+	saveExportPayment(t, store, "failed", "R4", ProviderBPay, money.NewMRU(500000), PaymentStatusFailed, exportDay)
+	saveExportPayment(t, store, "match", "R5", ProviderBPay, money.NewMRU(500000), PaymentStatusSuccess, exportDay)
+	saveExportPayment(t, store, "apr", "R6", ProviderBPay, money.NewMRU(500000), PaymentStatusSuccess, exportDay.AddDate(0, 1, 0))
+
+	filter := ExportFilter{
+		From:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Provider:  ProviderBPay,
+		Status:    PaymentStatusSuccess,
+		MinAmount: money.NewMRU(100000),
+	}
+	var ids []string
+	require.NoError(t, store.ScanTransactions(context.Background(), filter, func(stored *StoredTransaction) error {
+		ids = append(ids, stored.TransactionID)
+		assert.Nil(t, stored.Events)
+		return nil
+	}))
+	assert.Equal(t, []string{"match"}, ids)
+
+	filter.MinAmount = money.FromCents(100000, money.MRO)
+	assert.False(t, filter.Matches(&StoredTransaction{Provider: ProviderBPay, Status: PaymentStatusSuccess,
+		Amount: money.NewMRU(500000), CreatedAt: exportDay}), "other currencies are excluded")
+}
+
+func TestExportTransactionsErrors(t *testing.T) {
+	client := exportTestClient(t, nil)
+	var out bytes.Buffer
+	assert.ErrorIs(t, client.ExportTransactions(context.Background(), ExportFilter{}, &out, ExportCSV), ErrInvalidConfig)
+
+	client.WithTransactionStore(struct{ TransactionStore }{NewMemoryTransactionStore()})
+	assert.ErrorIs(t, client.ExportTransactions(context.Background(), ExportFilter{}, &out, ExportCSV), ErrInvalidConfig)
+
+	client.WithTransactionStore(NewMemoryTransactionStore())
+	assert.ErrorIs(t, client.ExportTransactions(context.Background(), ExportFilter{}, &out, "xlsx"), ErrInvalidRequest)
+	assert.Empty(t, out.String())
+}
+
+// generatedStore produces rows transactions on demand, calling sample
+// after each one, so that a scan holds no more than the current row
+type generatedStore struct {
+	TransactionStore
+	rows   int
+	sample func(row int)
+}
+
+func (s *generatedStore) ScanTransactions(_ context.Context, _ ExportFilter, fn func(*StoredTransaction) error) error {
+	for i := 1; i <= s.rows; i++ {
+		at := exportDay.Add(time.Duration(i) * time.Second)
+		if err := fn(&StoredTransaction{
+			TransactionID: fmt.Sprintf("TX-%06d", i),
+			Reference:     fmt.Sprintf("REF-%06d, bulk", i),
+			Provider:      ProviderBPay,
+			Amount:        money.NewMRU(int64(i) * 100),
+			Status:        PaymentStatusSuccess,
+			Message:       strings.Repeat("x", 200),
+			CreatedAt:     at,
+			LastUpdated:   at,
+		}); err != nil {
+			return err
+		}
+		s.sample(i)
+	}
+	return nil
+}
+
+// countingWriter discards what it is given
+type countingWriter struct{ n int }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestExportTransactionsStreamsInConstantMemory(t *testing.T) {
+	for _, format := range []ExportFormat{ExportCSV, ExportJSONLines} {
+		t.Run(string(format), func(t *testing.T) {
+			var early, late uint64
+			store := &generatedStore{rows: 10000, sample: func(row int) {
+				switch row {
+				case 1000:
+					early = heapAlloc()
+				case 10000:
+					late = heapAlloc()
+				}
+			}}
+			out := &countingWriter{}
+
+			require.NoError(t, exportTestClient(t, store).ExportTransactions(context.Background(), ExportFilter{}, out, format))
+			assert.Greater(t, out.n, 2<<20, "the export is several megabytes")
+			var growth int64
+			if late > early {
+				growth = int64(late - early)
+			}
+			assert.Less(t, growth, int64(256<<10), "heap grew by %d bytes over 9000 rows", growth)
+		})
+	}
+}
+
+func TestSQLTransactionStoreScanQuery(t *testing.T) {
+	store := NewSQLTransactionStore(nil, SQLPlaceholderDollar)
+	query, args := store.scanQuery(ExportFilter{
+		From:     exportDay,
+		Provider: ProviderBPay,
+		Status:   PaymentStatusSuccess,
+	})
+	assert.True(t, strings.HasSuffix(query, "FROM rimpay_transactions WHERE created_at >= $1 AND provider = $2 AND status = $3 ORDER BY created_at"), query)
+	assert.Equal(t, []interface{}{exportDay, ProviderBPay, "success"}, args)
+
+	query, args = store.scanQuery(ExportFilter{})
+	assert.True(t, strings.HasSuffix(query, "FROM rimpay_transactions ORDER BY created_at"), query)
+	assert.Empty(t, args)
+}
@@ -0,0 +1,184 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Metadata keys set on responses from ProcessPaymentWithFailover
+const (
+	// MetadataFailoverPath lists the providers tried, in order, as []string;
+	// the last one handled the payment
+	MetadataFailoverPath = "failover_path"
+	// MetadataFailoverSkipped lists providers skipped as unavailable
+	MetadataFailoverSkipped = "failover_skipped"
+	// MetadataFailoverIncompatible lists providers skipped because the
+	// customer would complete the payment differently, for example on a
+	// redirect page instead of with a passcode
+	MetadataFailoverIncompatible = "failover_incompatible"
+)
+
+// FailoverPolicy is the ordered provider chain used by
+// ProcessPaymentWithFailover. An empty chain uses only the default provider.
+type FailoverPolicy struct {
+	Providers []string `json:"providers"`
+	// PreferLowLatency tries the providers with calls in the stats window
+	// by ascending P95Latency, in the places they hold in Providers;
+	// providers without calls keep their place
+	PreferLowLatency bool `json:"prefer_low_latency,omitempty"`
+}
+
+// Validate checks that every provider in the chain is configured once
+func (p FailoverPolicy) Validate(providers map[string]ProviderConfig) error {
+	seen := make(map[string]bool, len(p.Providers))
+	for _, name := range p.Providers {
+		if _, exists := providers[name]; !exists {
+			return fmt.Errorf("failover: provider '%s' not found in providers", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("failover: provider '%s' listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// ProcessPaymentWithFailover processes a payment with the first provider in
+// Config.Failover that is available, moving on to the next one only when a
+// provider fails with a network, timeout, busy or other provider-side error.
+// Errors about the payment itself, such as validation failures, declines and
+// insufficient funds, are returned at once. Providers that cannot take the
+// request, or whose Capabilities differ from the first provider tried in
+// how the customer completes the payment, are skipped. The response's Provider is the
+// provider that handled the payment, and its Metadata records the failover
+// path. If every provider fails, the last error is returned.
+func (c *Client) ProcessPaymentWithFailover(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	// Every attempt shares one correlation ID
+	ctx, _, err := ensureCorrelationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := c.currentConfig()
+	chain := config.Failover.Providers
+	if len(chain) == 0 {
+		chain = []string{config.DefaultProvider}
+	} else if config.Failover.PreferLowLatency {
+		chain = c.byLatency(chain)
+	}
+
+	var path, skipped, incompatible []string
+	var flow Capabilities
+	var lastErr error
+	for _, name := range chain {
+		provider, ok := c.registered(name)
+		if providerConfig, configured := config.Providers[name]; !ok || (configured && !providerConfig.Enabled) || !provider.IsAvailable(ctx) {
+			skipped = append(skipped, name)
+			continue
+		}
+		capabilities := provider.Capabilities()
+		if !capabilities.accepts(request) || (len(path) > 0 && !capabilities.SameFlow(flow)) {
+			incompatible = append(incompatible, name)
+			continue
+		}
+		if len(path) == 0 {
+			flow = capabilities
+		}
+		path = append(path, name)
+
+		var result *PaymentResponse
+		prepared := request
+		err := c.checkLimits(name, request.Amount, request.Reference)
+		if err == nil {
+			prepared, err = c.prepareRequest(name, request)
+		}
+		if err == nil {
+			result, err = c.invokePayment(ctx, name, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+				return provider.ProcessPayment(ctx, prepared)
+			})
+		}
+		if err == nil {
+			result.Provider = name
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]interface{})
+			}
+			result.Metadata[MetadataFailoverPath] = path
+			if len(skipped) > 0 {
+				result.Metadata[MetadataFailoverSkipped] = skipped
+			}
+			if len(incompatible) > 0 {
+				result.Metadata[MetadataFailoverIncompatible] = incompatible
+			}
+			c.savePayment(ctx, result)
+			return result, nil
+		}
+
+		lastErr = err
+		if !shouldFailover(err) || ctx.Err() != nil {
+			break
+		}
+		ContextLogger(ctx, c.logger).Warn("Payment failed, trying next provider",
+			"reference", request.Reference, "provider", name, "error", err)
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("failover payment %s: no available provider in %v: %w", request.Reference, chain, ErrNoRoute)
+	}
+
+	var paymentErr *PaymentError
+	if errors.As(lastErr, &paymentErr) {
+		paymentErr.WithDetail(MetadataFailoverPath, path)
+		if len(incompatible) > 0 {
+			paymentErr.WithDetail(MetadataFailoverIncompatible, incompatible)
+		}
+	}
+	return nil, lastErr
+}
+
+// byLatency reorders the providers of chain that have calls in the stats
+// window by ascending P95 latency, leaving the others in place
+func (c *Client) byLatency(chain []string) []string {
+	var places []int
+	var measured []string
+	p95 := make(map[string]time.Duration)
+	for i, name := range chain {
+		if stats := c.stats.percentiles(name); stats.Calls > 0 {
+			places = append(places, i)
+			measured = append(measured, name)
+			p95[name] = stats.P95Latency
+		}
+	}
+	sort.SliceStable(measured, func(i, j int) bool { return p95[measured[i]] < p95[measured[j]] })
+
+	ordered := append([]string(nil), chain...)
+	for i, place := range places {
+		ordered[place] = measured[i]
+	}
+	return ordered
+}
+
+// shouldFailover reports whether another provider might succeed where this
+// one failed: provider-side and transport errors, but never errors about
+// the payment or customer
+func shouldFailover(err error) bool {
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) {
+		switch paymentErr.Code {
+		case ErrorCodeInvalidRequest, ErrorCodeValidationError, ErrorCodeInsufficientFunds,
+			ErrorCodePaymentDeclined, ErrorCodePaymentExpired:
+			return false
+		case ErrorCodeProviderError, ErrorCodeProviderTLSError, ErrorCodeAuthenticationFailed,
+			ErrorCodeRateLimited:
+			return true
+		}
+		return paymentErr.IsRetryable()
+	}
+	return errors.Is(err, ErrNetworkError) || errors.Is(err, ErrTimeout)
+}
@@ -0,0 +1,139 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider fails every payment with err and counts the attempts
+type failingProvider struct {
+	namedProvider
+	err   error
+	calls int
+}
+
+func (p *failingProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	p.calls++
+	return nil, p.err
+}
+
+func newFailoverTestClient(t *testing.T, chain []string, providers ...PaymentProvider) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	for _, provider := range providers {
+		config.Providers[provider.Name()] = ProviderConfig{Enabled: true, BaseURL: "https://provider.test", Timeout: time.Second}
+	}
+	config.DefaultProvider = providers[0].Name()
+	config.Failover = FailoverPolicy{Providers: chain}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	for _, provider := range providers {
+		require.NoError(t, client.AddProvider(provider.Name(), provider))
+	}
+	return client
+}
+
+func failoverRequest() *PaymentRequest {
+	return &PaymentRequest{Amount: money.NewMRU(5000), Reference: "REF-1"}
+}
+
+func TestProcessPaymentWithFailover(t *testing.T) {
+	bpay := &failingProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		err:           NewPaymentError(ErrorCodeProviderError, "service unavailable", ProviderBPay, true),
+	}
+	masrvi := &namedProvider{name: ProviderMasrvi}
+	client := newFailoverTestClient(t, []string{ProviderBPay, ProviderMasrvi}, bpay, masrvi)
+	logger := client.logger.(*recordingLogger)
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, 1, bpay.calls)
+	assert.Equal(t, ProviderMasrvi, resp.TransactionID)
+	assert.Equal(t, ProviderMasrvi, resp.Provider)
+	assert.Equal(t, []string{ProviderBPay, ProviderMasrvi}, resp.Metadata[MetadataFailoverPath])
+	assert.NotContains(t, resp.Metadata, MetadataFailoverSkipped)
+	assert.Equal(t, 1, logger.count("Payment failed, trying next provider"))
+}
+
+func TestProcessPaymentWithFailoverSkipsUnavailable(t *testing.T) {
+	client := newFailoverTestClient(t, []string{ProviderBPay, ProviderMasrvi},
+		&downProvider{namedProvider{name: ProviderBPay}},
+		&namedProvider{name: ProviderMasrvi},
+	)
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, ProviderMasrvi, resp.Provider)
+	assert.Equal(t, []string{ProviderMasrvi}, resp.Metadata[MetadataFailoverPath])
+	assert.Equal(t, []string{ProviderBPay}, resp.Metadata[MetadataFailoverSkipped])
+}
+
+func TestProcessPaymentWithFailoverStopsOnPaymentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"validation", NewValidationError("phone_number", "is required")},
+		{"insufficient funds", NewPaymentError(ErrorCodeInsufficientFunds, "insufficient balance", ProviderBPay, false)},
+		{"declined", NewPaymentError(ErrorCodePaymentDeclined, "declined", ProviderBPay, false)},
+		{"plain error", ErrPaymentFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bpay := &failingProvider{namedProvider: namedProvider{name: ProviderBPay}, err: tt.err}
+			masrvi := &failingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}
+			client := newFailoverTestClient(t, []string{ProviderBPay, ProviderMasrvi}, bpay, masrvi)
+
+			_, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, 1, bpay.calls)
+			assert.Equal(t, 0, masrvi.calls, "payment errors must not fail over")
+		})
+	}
+}
+
+func TestProcessPaymentWithFailoverAllFail(t *testing.T) {
+	bpay := &failingProvider{namedProvider: namedProvider{name: ProviderBPay}, err: ErrNetworkError}
+	masrvi := &failingProvider{
+		namedProvider: namedProvider{name: ProviderMasrvi},
+		err:           NewPaymentError(ErrorCodeTimeout, "timed out", ProviderMasrvi, true),
+	}
+	client := newFailoverTestClient(t, []string{ProviderBPay, ProviderMasrvi}, bpay, masrvi)
+
+	_, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, ErrorCodeTimeout, paymentErr.Code)
+	assert.Equal(t, []string{ProviderBPay, ProviderMasrvi}, paymentErr.Details[MetadataFailoverPath])
+
+	client = newFailoverTestClient(t, []string{ProviderBPay}, &downProvider{namedProvider{name: ProviderBPay}})
+	_, err = client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
+
+func TestProcessPaymentWithFailoverDefaultChain(t *testing.T) {
+	client := newFailoverTestClient(t, nil, &namedProvider{name: ProviderBPay}, &namedProvider{name: ProviderMasrvi})
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []string{ProviderBPay}, resp.Metadata[MetadataFailoverPath])
+}
+
+func TestFailoverPolicyValidate(t *testing.T) {
+	providers := map[string]ProviderConfig{ProviderBPay: {}, ProviderMasrvi: {}}
+
+	assert.NoError(t, FailoverPolicy{Providers: []string{ProviderBPay, ProviderMasrvi}}.Validate(providers))
+	assert.EqualError(t, FailoverPolicy{Providers: []string{ProviderBPay, ProviderClick}}.Validate(providers),
+		"failover: provider 'click' not found in providers")
+	assert.EqualError(t, FailoverPolicy{Providers: []string{ProviderBPay, ProviderBPay}}.Validate(providers),
+		"failover: provider 'bpay' listed more than once")
+}
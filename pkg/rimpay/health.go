@@ -0,0 +1,187 @@
+package rimpay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthProbeTimeout = 5 * time.Second
+	defaultHealthCacheTTL     = 30 * time.Second
+)
+
+// ProviderStatus is the result of a provider health probe
+type ProviderStatus struct {
+	Provider  string
+	Available bool
+	// Latency is how long the probe took
+	Latency time.Duration
+	// LastError explains why the provider is unavailable
+	LastError error
+	// CheckedAt is when the probe ran; cached results keep their time
+	CheckedAt time.Time
+}
+
+// MarshalJSON renders the error as a string and the latency in milliseconds
+func (s ProviderStatus) MarshalJSON() ([]byte, error) {
+	var lastError string
+	if s.LastError != nil {
+		lastError = s.LastError.Error()
+	}
+	return json.Marshal(struct {
+		Provider  string    `json:"provider"`
+		Available bool      `json:"available"`
+		LatencyMS int64     `json:"latency_ms"`
+		LastError string    `json:"last_error,omitempty"`
+		CheckedAt time.Time `json:"checked_at"`
+	}{s.Provider, s.Available, s.Latency.Milliseconds(), lastError, s.CheckedAt})
+}
+
+// healthCache holds the latest probe result per provider
+type healthCache struct {
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	results map[string]ProviderStatus
+}
+
+func newHealthCache(config HealthConfig) *healthCache {
+	h := &healthCache{
+		timeout: config.ProbeTimeout,
+		ttl:     config.CacheTTL,
+		now:     time.Now,
+		results: make(map[string]ProviderStatus),
+	}
+	if h.timeout == 0 {
+		h.timeout = defaultHealthProbeTimeout
+	}
+	if h.ttl == 0 {
+		h.ttl = defaultHealthCacheTTL
+	}
+	return h
+}
+
+// cached returns the result for name if it is younger than the TTL
+func (h *healthCache) cached(name string) (ProviderStatus, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status, ok := h.results[name]
+	if !ok || h.now().Sub(status.CheckedAt) >= h.ttl {
+		return ProviderStatus{}, false
+	}
+	return status, true
+}
+
+// forget drops the results for names, whose providers were replaced
+func (h *healthCache) forget(names ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, name := range names {
+		delete(h.results, name)
+	}
+}
+
+// probe checks provider under the probe timeout and caches the result
+func (h *healthCache) probe(ctx context.Context, name string, provider PaymentProvider) ProviderStatus {
+	if status, ok := h.cached(name); ok {
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := h.now()
+	d := diagnoseProvider(ctx, name, provider)
+	status := ProviderStatus{
+		Provider:  name,
+		Available: d.Healthy,
+		Latency:   h.now().Sub(start),
+		LastError: d.Error,
+		CheckedAt: start,
+	}
+
+	h.mu.Lock()
+	h.results[name] = status
+	h.mu.Unlock()
+	return status
+}
+
+// enabledProviders returns the registered providers not disabled in config
+func (c *Client) enabledProviders() map[string]PaymentProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	enabled := make(map[string]PaymentProvider, len(c.providers))
+	for name, provider := range c.providers {
+		if config, ok := c.config.Providers[name]; ok && !config.Enabled {
+			continue
+		}
+		enabled[name] = provider
+	}
+	return enabled
+}
+
+// HealthCheck probes every enabled provider concurrently, each under
+// Config.Health.ProbeTimeout. Results are cached for Config.Health.CacheTTL
+// so frequent callers, such as readiness probes, do not hammer provider
+// authentication endpoints.
+func (c *Client) HealthCheck(ctx context.Context) map[string]ProviderStatus {
+	providers := c.enabledProviders()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[string]ProviderStatus, len(providers))
+	for name, provider := range providers {
+		wg.Add(1)
+		go func(name string, provider PaymentProvider) {
+			defer wg.Done()
+			status := c.health.probe(ctx, name, provider)
+			mu.Lock()
+			statuses[name] = status
+			mu.Unlock()
+		}(name, provider)
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+// IsProviderAvailable probes a single provider, using the HealthCheck cache.
+// The error explains why the provider is unavailable.
+func (c *Client) IsProviderAvailable(ctx context.Context, name string) (bool, error) {
+	provider, ok := c.enabledProviders()[name]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	status := c.health.probe(ctx, name, provider)
+	return status.Available, status.LastError
+}
+
+// HealthHandler serves HealthCheck as JSON for readiness probes: 200 when
+// every enabled provider is available, 503 otherwise or when none are
+// registered
+func (c *Client) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := c.HealthCheck(r.Context())
+
+		code := http.StatusOK
+		if len(statuses) == 0 {
+			code = http.StatusServiceUnavailable
+		}
+		for _, status := range statuses {
+			if !status.Available {
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(statuses)
+	})
+}
@@ -0,0 +1,118 @@
+package rimpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probedProvider counts availability checks and blocks until ctx is done
+// when hang is set
+type probedProvider struct {
+	namedProvider
+	down   bool
+	hang   bool
+	probes int32
+}
+
+func (p *probedProvider) IsAvailable(ctx context.Context) bool {
+	atomic.AddInt32(&p.probes, 1)
+	if p.hang {
+		<-ctx.Done()
+		return false
+	}
+	return !p.down
+}
+
+func newHealthTestClient(t *testing.T, health HealthConfig, providers ...PaymentProvider) *Client {
+	t.Helper()
+	client := newFailoverTestClient(t, nil, providers...)
+	client.health = newHealthCache(health)
+	return client
+}
+
+func TestHealthCheck(t *testing.T) {
+	bpay := &probedProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	masrvi := &probedProvider{namedProvider: namedProvider{name: ProviderMasrvi}, down: true}
+	client := newHealthTestClient(t, HealthConfig{}, bpay, masrvi)
+
+	statuses := client.HealthCheck(context.Background())
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[ProviderBPay].Available)
+	assert.NoError(t, statuses[ProviderBPay].LastError)
+	assert.False(t, statuses[ProviderMasrvi].Available)
+	assert.EqualError(t, statuses[ProviderMasrvi].LastError, "provider masrvi not available")
+	assert.False(t, statuses[ProviderMasrvi].CheckedAt.IsZero())
+}
+
+func TestHealthCheckCachesResults(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	bpay := &probedProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	client := newHealthTestClient(t, HealthConfig{CacheTTL: time.Minute}, bpay)
+	client.health.now = clock.Now
+
+	client.HealthCheck(context.Background())
+	client.HealthCheck(context.Background())
+	available, err := client.IsProviderAvailable(context.Background(), ProviderBPay)
+	require.NoError(t, err)
+	assert.True(t, available)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bpay.probes))
+
+	clock.Advance(time.Minute)
+	client.HealthCheck(context.Background())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&bpay.probes))
+}
+
+func TestHealthCheckProbeTimeout(t *testing.T) {
+	bpay := &probedProvider{namedProvider: namedProvider{name: ProviderBPay}, hang: true}
+	masrvi := &probedProvider{namedProvider: namedProvider{name: ProviderMasrvi}, hang: true}
+	client := newHealthTestClient(t, HealthConfig{ProbeTimeout: 20 * time.Millisecond}, bpay, masrvi)
+
+	start := time.Now()
+	statuses := client.HealthCheck(context.Background())
+	assert.Less(t, time.Since(start), time.Second, "probes run concurrently under the timeout")
+	assert.False(t, statuses[ProviderBPay].Available)
+	assert.False(t, statuses[ProviderMasrvi].Available)
+}
+
+func TestIsProviderAvailableUnknownProvider(t *testing.T) {
+	client := newHealthTestClient(t, HealthConfig{}, &probedProvider{namedProvider: namedProvider{name: ProviderBPay}})
+
+	available, err := client.IsProviderAvailable(context.Background(), "unknown")
+	assert.False(t, available)
+	assert.True(t, errors.Is(err, ErrProviderNotFound))
+}
+
+func TestHealthHandler(t *testing.T) {
+	bpay := &probedProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	client := newHealthTestClient(t, HealthConfig{CacheTTL: time.Nanosecond}, bpay)
+
+	rec := httptest.NewRecorder()
+	client.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, true, body[ProviderBPay]["available"])
+
+	bpay.down = true
+	rec = httptest.NewRecorder()
+	client.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"last_error":"provider bpay not available"`)
+}
+
+func TestConfigValidateHealth(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Health.CacheTTL = -time.Second
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,318 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// MetadataKeyIntentID is the request metadata key holding the payment
+// intent an attempt belongs to
+const MetadataKeyIntentID = "intent_id"
+
+// PaymentIntentID identifies a payment intent created with CreateIntent
+type PaymentIntentID string
+
+// IntentStatus is the state of a payment intent
+type IntentStatus string
+
+const (
+	// IntentStatusOpen has no attempt yet, or only failed ones
+	IntentStatusOpen IntentStatus = "open"
+	// IntentStatusPending has an attempt in progress or awaiting the customer
+	IntentStatusPending IntentStatus = "pending"
+	// IntentStatusSucceeded has a successful attempt; no more are accepted
+	IntentStatusSucceeded IntentStatus = "succeeded"
+)
+
+// PaymentIntent is one business order paid through any number of attempts,
+// possibly with different providers. It links the attempts' transactions
+// so the order can be reconciled through a single ID.
+type PaymentIntent struct {
+	ID PaymentIntentID `json:"id"`
+	// Reference is the caller's order reference
+	Reference string          `json:"reference,omitempty"`
+	Amount    money.Money     `json:"amount"`
+	Status    IntentStatus    `json:"status"`
+	Attempts  []IntentAttempt `json:"attempts,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	// Version increases with every stored change; IntentStore.UpdateIntent
+	// compares it
+	Version int `json:"version"`
+}
+
+// IntentAttempt is one payment made for an intent
+type IntentAttempt struct {
+	Provider      string        `json:"provider,omitempty"`
+	Reference     string        `json:"reference"`
+	TransactionID string        `json:"transaction_id,omitempty"`
+	Status        PaymentStatus `json:"status"`
+	// Error is the reason a failed attempt gave
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IntentPaymentRequest is an attempt at paying an intent: the request and
+// the provider to send it to. An empty Provider lets ProcessPayment choose.
+// The request's Amount defaults to the intent's and may not differ from
+// it; its Reference defaults to the intent ID and attempt number.
+type IntentPaymentRequest struct {
+	Provider string
+	Request  *PaymentRequest
+}
+
+// copy returns a copy of the intent that shares no attempts with it
+func (i *PaymentIntent) copy() *PaymentIntent {
+	dup := *i
+	dup.Attempts = append([]IntentAttempt(nil), i.Attempts...)
+	return &dup
+}
+
+// currentStatus derives the intent status from its attempts
+func (i *PaymentIntent) currentStatus() IntentStatus {
+	status := IntentStatusOpen
+	for _, attempt := range i.Attempts {
+		switch attempt.Status {
+		case PaymentStatusSuccess:
+			return IntentStatusSucceeded
+		case PaymentStatusPending:
+			status = IntentStatusPending
+		}
+	}
+	return status
+}
+
+// IntentStore is implemented by transaction stores that can keep payment
+// intents, as CreateIntent requires. Implementations must be safe for
+// concurrent use.
+type IntentStore interface {
+	// SaveIntent stores a new intent, failing with ErrIntentConflict when
+	// its ID exists
+	SaveIntent(ctx context.Context, intent *PaymentIntent) error
+	// GetIntent returns the intent, or ErrIntentNotFound
+	GetIntent(ctx context.Context, id PaymentIntentID) (*PaymentIntent, error)
+	// UpdateIntent replaces the intent with the same ID if its stored
+	// Version is still from, else fails with ErrIntentConflict
+	UpdateIntent(ctx context.Context, intent *PaymentIntent, from int) error
+}
+
+// SaveIntent stores intent
+func (s *MemoryTransactionStore) SaveIntent(_ context.Context, intent *PaymentIntent) error {
+	if intent == nil || intent.ID == "" {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.intents[intent.ID]; ok {
+		return fmt.Errorf("payment intent %s already exists: %w", intent.ID, ErrIntentConflict)
+	}
+	s.intents[intent.ID] = intent.copy()
+	return nil
+}
+
+// GetIntent returns the intent
+func (s *MemoryTransactionStore) GetIntent(_ context.Context, id PaymentIntentID) (*PaymentIntent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if intent, ok := s.intents[id]; ok {
+		return intent.copy(), nil
+	}
+	return nil, ErrIntentNotFound
+}
+
+// UpdateIntent replaces intent if its stored version is from
+func (s *MemoryTransactionStore) UpdateIntent(_ context.Context, intent *PaymentIntent, from int) error {
+	if intent == nil {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.intents[intent.ID]
+	if !ok {
+		return ErrIntentNotFound
+	}
+	if stored.Version != from {
+		return fmt.Errorf("payment intent %s is at version %d, not %d: %w", intent.ID, stored.Version, from, ErrIntentConflict)
+	}
+	s.intents[intent.ID] = intent.copy()
+	return nil
+}
+
+// intentStore returns the client's TransactionStore as an IntentStore
+func (c *Client) intentStore() (IntentStore, error) {
+	store := c.transactionStore()
+	if store == nil {
+		return nil, fmt.Errorf("payment intents: no transaction store: %w", ErrInvalidConfig)
+	}
+	intents, ok := store.(IntentStore)
+	if !ok {
+		return nil, fmt.Errorf("payment intents: %T cannot store intents: %w", store, ErrInvalidConfig)
+	}
+	return intents, nil
+}
+
+// CreateIntent creates an open payment intent for amount, identified by the
+// caller's order reference. Intents are kept in the client's
+// TransactionStore, which must implement IntentStore.
+func (c *Client) CreateIntent(ctx context.Context, reference string, amount money.Money) (*PaymentIntent, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	if !amount.IsPositive() {
+		return nil, NewValidationError("amount", "must be positive")
+	}
+	store, err := c.intentStore()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := common.GenerateTransactionID("PI")
+	if err != nil {
+		return nil, err
+	}
+	now := c.scheduler.now()
+	intent := &PaymentIntent{
+		ID:        PaymentIntentID(id),
+		Reference: reference,
+		Amount:    amount,
+		Status:    IntentStatusOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.SaveIntent(ctx, intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// GetIntent returns the intent with the latest status of its attempts, as
+// recorded in the TransactionStore by status checks and notifications
+func (c *Client) GetIntent(ctx context.Context, id PaymentIntentID) (*PaymentIntent, error) {
+	store, err := c.intentStore()
+	if err != nil {
+		return nil, err
+	}
+	intent, err := store.GetIntent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.refreshIntent(ctx, intent)
+	return intent, nil
+}
+
+// refreshIntent updates the pending attempts of intent from the
+// TransactionStore
+func (c *Client) refreshIntent(ctx context.Context, intent *PaymentIntent) {
+	store := c.transactionStore()
+	if store == nil {
+		return
+	}
+	for i := range intent.Attempts {
+		attempt := &intent.Attempts[i]
+		if attempt.Status != PaymentStatusPending || attempt.TransactionID == "" {
+			continue
+		}
+		stored, err := store.GetByTransactionID(ctx, attempt.TransactionID)
+		if err != nil || stored.Status == attempt.Status {
+			continue
+		}
+		attempt.Status = stored.Status
+		attempt.UpdatedAt = stored.LastUpdated
+	}
+	intent.Status = intent.currentStatus()
+}
+
+// AttemptIntent pays the intent with request. It fails with
+// ErrIntentSucceeded once an attempt succeeded, and with ErrIntentConflict
+// while another attempt is in progress or still pending, so an order is
+// never paid twice. Attempts that return an error are recorded as failed.
+func (c *Client) AttemptIntent(ctx context.Context, id PaymentIntentID, request *IntentPaymentRequest) (*PaymentResponse, error) {
+	if request == nil || request.Request == nil {
+		return nil, ErrInvalidRequest
+	}
+	store, err := c.intentStore()
+	if err != nil {
+		return nil, err
+	}
+	intent, err := store.GetIntent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.refreshIntent(ctx, intent)
+
+	switch intent.Status {
+	case IntentStatusSucceeded:
+		return nil, fmt.Errorf("payment intent %s: %w", id, ErrIntentSucceeded)
+	case IntentStatusPending:
+		return nil, fmt.Errorf("payment intent %s: an attempt is still pending: %w", id, ErrIntentConflict)
+	}
+
+	payment := copyPaymentRequest(request.Request)
+	if payment.Amount.IsZero() {
+		payment.Amount = intent.Amount
+	} else if !payment.Amount.Equals(intent.Amount) {
+		return nil, NewValidationError("amount", fmt.Sprintf("must equal the intent amount %s", intent.Amount))
+	}
+	if payment.Reference == "" {
+		payment.Reference = fmt.Sprintf("%s-%d", intent.ID, len(intent.Attempts)+1)
+	}
+	if payment.Metadata == nil {
+		payment.Metadata = make(map[string]interface{})
+	}
+	payment.Metadata[MetadataKeyIntentID] = string(intent.ID)
+
+	// Recording the attempt first claims the intent against concurrent attempts
+	now := c.scheduler.now()
+	intent.Attempts = append(intent.Attempts, IntentAttempt{
+		Provider:  request.Provider,
+		Reference: payment.Reference,
+		Status:    PaymentStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err := c.updateIntent(ctx, store, intent); err != nil {
+		return nil, err
+	}
+
+	var response *PaymentResponse
+	if request.Provider == "" {
+		response, err = c.ProcessPayment(ctx, payment)
+	} else {
+		response, err = c.ProcessPaymentWithProvider(ctx, request.Provider, payment)
+	}
+
+	attempt := &intent.Attempts[len(intent.Attempts)-1]
+	attempt.UpdatedAt = c.scheduler.now()
+	if err != nil {
+		attempt.Status = PaymentStatusFailed
+		attempt.Error = err.Error()
+	} else {
+		attempt.TransactionID = response.TransactionID
+		attempt.Status = response.Status
+		if response.Provider != "" {
+			attempt.Provider = response.Provider
+		}
+	}
+	// Like other store failures, this never fails the payment
+	if updateErr := c.updateIntent(ctx, store, intent); updateErr != nil {
+		ContextLogger(ctx, c.logger).Error("Failed to record payment intent attempt",
+			"intent_id", intent.ID, "reference", attempt.Reference, "error", updateErr)
+	}
+	return response, err
+}
+
+// updateIntent stores intent with its derived status and the next version
+func (c *Client) updateIntent(ctx context.Context, store IntentStore, intent *PaymentIntent) error {
+	from := intent.Version
+	intent.Status = intent.currentStatus()
+	intent.UpdatedAt = c.scheduler.now()
+	intent.Version++
+	return store.UpdateIntent(ctx, intent, from)
+}
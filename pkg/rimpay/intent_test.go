@@ -0,0 +1,165 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// intentProvider answers payments with status, or fails them with err, and
+// keeps the requests it received
+type intentProvider struct {
+	namedProvider
+	status   PaymentStatus
+	err      error
+	requests []*PaymentRequest
+}
+
+func (p *intentProvider) ProcessPayment(_ context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	p.requests = append(p.requests, request)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &PaymentResponse{
+		TransactionID: "TX-" + request.Reference,
+		Reference:     request.Reference,
+		Amount:        request.Amount,
+		Status:        p.status,
+		UpdatedAt:     time.Now(),
+	}, nil
+}
+
+func newIntentTestClient(t *testing.T, providers ...PaymentProvider) (*Client, *MemoryTransactionStore) {
+	t.Helper()
+	client := newFailoverTestClient(t, nil, providers...)
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	return client, store
+}
+
+func TestPaymentIntentAcrossProviders(t *testing.T) {
+	bpay := &intentProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		err:           NewPaymentError(ErrorCodeProviderError, "service unavailable", ProviderBPay, true),
+	}
+	masrvi := &intentProvider{namedProvider: namedProvider{name: ProviderMasrvi}, status: PaymentStatusSuccess}
+	client, _ := newIntentTestClient(t, bpay, masrvi)
+	ctx := context.Background()
+
+	intent, err := client.CreateIntent(ctx, "ORDER-42", money.NewMRU(15000))
+	require.NoError(t, err)
+	assert.Equal(t, IntentStatusOpen, intent.Status)
+
+	_, err = client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Provider: ProviderBPay, Request: &PaymentRequest{}})
+	assert.Error(t, err)
+	require.Len(t, bpay.requests, 1)
+	assert.Equal(t, string(intent.ID)+"-1", bpay.requests[0].Reference)
+	assert.Equal(t, money.NewMRU(15000), bpay.requests[0].Amount, "the amount defaults to the intent's")
+	assert.Equal(t, string(intent.ID), bpay.requests[0].Metadata[MetadataKeyIntentID])
+
+	resp, err := client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{
+		Provider: ProviderMasrvi,
+		Request:  &PaymentRequest{Amount: money.NewMRU(15000)},
+	})
+	require.NoError(t, err)
+
+	got, err := client.GetIntent(ctx, intent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, IntentStatusSucceeded, got.Status)
+	assert.Equal(t, "ORDER-42", got.Reference)
+	require.Len(t, got.Attempts, 2)
+	assert.Equal(t, ProviderBPay, got.Attempts[0].Provider)
+	assert.Equal(t, PaymentStatusFailed, got.Attempts[0].Status)
+	assert.Contains(t, got.Attempts[0].Error, "service unavailable")
+	assert.Equal(t, ProviderMasrvi, got.Attempts[1].Provider)
+	assert.Equal(t, resp.TransactionID, got.Attempts[1].TransactionID)
+	assert.Equal(t, PaymentStatusSuccess, got.Attempts[1].Status)
+
+	_, err = client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Provider: ProviderBPay, Request: &PaymentRequest{}})
+	assert.ErrorIs(t, err, ErrIntentSucceeded)
+	assert.Len(t, bpay.requests, 1, "a succeeded intent is never paid again")
+}
+
+func TestPaymentIntentRejectsAmountChanges(t *testing.T) {
+	provider := &intentProvider{namedProvider: namedProvider{name: ProviderBPay}, status: PaymentStatusSuccess}
+	client, _ := newIntentTestClient(t, provider)
+	ctx := context.Background()
+
+	intent, err := client.CreateIntent(ctx, "ORDER-1", money.NewMRU(1000))
+	require.NoError(t, err)
+
+	_, err = client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Request: &PaymentRequest{Amount: money.NewMRU(900)}})
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Empty(t, provider.requests)
+
+	got, err := client.GetIntent(ctx, intent.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Attempts)
+	assert.Equal(t, IntentStatusOpen, got.Status)
+
+	_, err = client.CreateIntent(ctx, "ORDER-2", money.NewMRU(0))
+	assert.Error(t, err)
+}
+
+func TestPaymentIntentPendingAttemptBlocksOthers(t *testing.T) {
+	provider := &intentProvider{namedProvider: namedProvider{name: ProviderBPay}, status: PaymentStatusPending}
+	client, store := newIntentTestClient(t, provider)
+	ctx := context.Background()
+
+	intent, err := client.CreateIntent(ctx, "ORDER-7", money.NewMRU(2500))
+	require.NoError(t, err)
+	resp, err := client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Request: &PaymentRequest{Reference: "ORDER-7-A"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER-7-A", resp.Reference)
+
+	_, err = client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Request: &PaymentRequest{}})
+	assert.ErrorIs(t, err, ErrIntentConflict)
+
+	// A notification settles the pending attempt through the store
+	require.NoError(t, store.UpdateStatus(ctx, &TransactionStatus{
+		TransactionID: resp.TransactionID,
+		Status:        PaymentStatusSuccess,
+		LastUpdated:   time.Now(),
+	}))
+	got, err := client.GetIntent(ctx, intent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, IntentStatusSucceeded, got.Status)
+	assert.Equal(t, PaymentStatusSuccess, got.Attempts[0].Status)
+
+	_, err = client.AttemptIntent(ctx, intent.ID, &IntentPaymentRequest{Request: &PaymentRequest{}})
+	assert.ErrorIs(t, err, ErrIntentSucceeded)
+}
+
+func TestPaymentIntentRequiresIntentStore(t *testing.T) {
+	client := newFailoverTestClient(t, nil, &namedProvider{name: ProviderBPay})
+	_, err := client.CreateIntent(context.Background(), "ORDER-1", money.NewMRU(1000))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	client.WithTransactionStore(NewMemoryTransactionStore())
+	_, err = client.GetIntent(context.Background(), "PI-unknown")
+	assert.ErrorIs(t, err, ErrIntentNotFound)
+}
+
+func TestMemoryIntentStoreVersions(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	ctx := context.Background()
+	intent := &PaymentIntent{ID: "PI-1", Amount: money.NewMRU(100), Status: IntentStatusOpen}
+	require.NoError(t, store.SaveIntent(ctx, intent))
+	assert.ErrorIs(t, store.SaveIntent(ctx, intent), ErrIntentConflict)
+
+	updated := intent.copy()
+	updated.Version = 1
+	require.NoError(t, store.UpdateIntent(ctx, updated, 0))
+	assert.ErrorIs(t, store.UpdateIntent(ctx, updated, 0), ErrIntentConflict, "a stale version is rejected")
+
+	got, err := store.GetIntent(ctx, "PI-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Version)
+}
@@ -2,11 +2,15 @@ package rimpay
 
 import (
 	"context"
-	"time"
 
+	"github.com/CatoSystems/rim-pay/internal/types"
 	"github.com/CatoSystems/rim-pay/pkg/money"
 )
 
+// PaymentProvider is implemented by every payment provider, built-in or
+// custom. Custom providers are plugged in with RegisterProvider or
+// Client.AddProvider. Providers holding resources may also implement
+// io.Closer; Client.Close calls it.
 type PaymentProvider interface {
 	// Name returns the provider name
 	Name() string
@@ -20,17 +24,20 @@ type PaymentProvider interface {
 	// GetPaymentStatus gets payment status
 	GetPaymentStatus(ctx context.Context, transactionID string) (*TransactionStatus, error)
 
+	// Refund reverses all or part of a payment; providers without refunds
+	// return ErrRefundNotSupported
+	Refund(ctx context.Context, request *RefundRequest) (*RefundResponse, error)
+
 	// ValidateConfig validates provider configuration
 	ValidateConfig() error
-}
 
-type Logger interface {
-	Debug(msg string, fields ...interface{})
-	Info(msg string, fields ...interface{})
-	Warn(msg string, fields ...interface{})
-	Error(msg string, fields ...interface{})
+	// Capabilities describes what the provider supports
+	Capabilities() Capabilities
 }
 
+// Logger is the structured logger used by the client and providers
+type Logger = types.Logger
+
 // Validator defines validation interface
 type Validator interface {
 	ValidatePaymentRequest(request *PaymentRequest) error
@@ -38,21 +45,11 @@ type Validator interface {
 	ValidatePhoneNumber(phone string) error
 }
 
-type HTTPClient interface {
-	Do(req *HTTPRequest) (*HTTPResponse, error)
-}
-
-// HTTPRequest represents an HTTP request
-type HTTPRequest struct {
-	Method  string
-	URL     string
-	Headers map[string]string
-	Body    []byte
-	Timeout time.Duration
-}
+// Re-export HTTP types from internal/types for public API
+type (
+	HTTPClient   = types.HTTPClient
+	HTTPRequest  = types.HTTPRequest
+	HTTPResponse = types.HTTPResponse
 
-type HTTPResponse struct {
-	StatusCode int
-	Headers    map[string]string
-	Body       []byte
-}
+	HTTPStatusError = types.HTTPStatusError
+)
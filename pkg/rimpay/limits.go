@@ -0,0 +1,93 @@
+package rimpay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// CheckAmount returns a VALIDATION_ERROR on the amount field when amount
+// falls outside MinAmount and MaxAmount. Limits are expressed in the
+// amount's currency.
+func (p ProviderConfig) CheckAmount(amount money.Money) error {
+	cents := amount.Cents()
+	min := money.FromCents(p.MinAmount, amount.Currency())
+	max := money.FromCents(p.MaxAmount, amount.Currency())
+
+	var msg string
+	switch {
+	case p.MinAmount > 0 && cents < p.MinAmount:
+		msg = fmt.Sprintf("%s is below the minimum of %s", amount, min)
+	case p.MaxAmount > 0 && cents > p.MaxAmount:
+		msg = fmt.Sprintf("%s exceeds the maximum of %s", amount, max)
+	default:
+		return nil
+	}
+
+	err := NewValidationError("amount", msg)
+	if p.MinAmount > 0 {
+		err.WithDetail("min_amount", min.String())
+	}
+	if p.MaxAmount > 0 {
+		err.WithDetail("max_amount", max.String())
+	}
+	return err
+}
+
+// checkAmount applies the limits of provider, from the config it was added
+// with or the client's Config, to amount
+func (c *Client) checkAmount(provider string, amount money.Money) error {
+	config, ok := c.providerConfig(provider)
+	if !ok {
+		return nil
+	}
+	if err := config.CheckAmount(amount); err != nil {
+		if paymentErr, ok := err.(*PaymentError); ok {
+			paymentErr.Provider = provider
+		}
+		return err
+	}
+	return nil
+}
+
+// DefaultSupportedCurrencies returns the currencies a built-in provider
+// accepts unless it implements CurrencySupporter. Other providers accept any
+// currency.
+func DefaultSupportedCurrencies(provider string) []money.Currency {
+	switch provider {
+	case ProviderBPay, ProviderMasrvi, ProviderClick, ProviderSedad:
+		return []money.Currency{money.MRU}
+	}
+	return nil
+}
+
+// supportedCurrencies returns the currencies provider accepts
+func (c *Client) supportedCurrencies(provider string) []money.Currency {
+	if registered, ok := c.registered(provider); ok {
+		if supporter, ok := registered.(CurrencySupporter); ok {
+			return supporter.SupportedCurrencies()
+		}
+	}
+	return DefaultSupportedCurrencies(provider)
+}
+
+// checkCurrency returns a VALIDATION_ERROR on the amount field when provider
+// does not accept the currency of amount
+func (c *Client) checkCurrency(provider string, amount money.Money) error {
+	supported := c.supportedCurrencies(provider)
+	if len(supported) == 0 {
+		return nil
+	}
+	names := make([]string, len(supported))
+	for i, currency := range supported {
+		if amount.Currency() == currency {
+			return nil
+		}
+		names[i] = string(currency)
+	}
+	err := NewValidationError("amount", fmt.Sprintf("currency %q is not supported by %s; it accepts %s",
+		amount.Currency(), provider, strings.Join(names, ", ")))
+	err.Provider = provider
+	return err
+}
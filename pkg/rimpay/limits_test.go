@@ -0,0 +1,194 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBPayProvider records how many payments reached the provider
+type countingBPayProvider struct {
+	namedProvider
+	calls int
+}
+
+func (p *countingBPayProvider) ProcessBPayPayment(context.Context, *BPayPaymentRequest) (*PaymentResponse, error) {
+	p.calls++
+	return &PaymentResponse{TransactionID: p.name}, nil
+}
+
+func newLimitsTestClient(t *testing.T, min, max int64) (*Client, *countingBPayProvider) {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{
+		Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		MinAmount: min, MaxAmount: max,
+	}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	provider := &countingBPayProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+	return client, provider
+}
+
+func TestCheckAmount(t *testing.T) {
+	limits := ProviderConfig{MinAmount: 1000, MaxAmount: 50000000}
+
+	tests := []struct {
+		name    string
+		amount  money.Money
+		wantErr string
+	}{
+		{"below minimum", money.NewMRU(500), "amount: 5.00 MRU is below the minimum of 10.00 MRU"},
+		{"at minimum", money.NewMRU(1000), ""},
+		{"within range", money.NewMRU(250000), ""},
+		{"at maximum", money.NewMRU(50000000), ""},
+		{"above maximum", money.NewMRU(50000001), "amount: 500000.01 MRU exceeds the maximum of 500000.00 MRU"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.CheckAmount(tt.amount)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var paymentErr *PaymentError
+			require.True(t, errors.As(err, &paymentErr))
+			assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+			assert.Equal(t, tt.wantErr, paymentErr.Message)
+			assert.False(t, paymentErr.Retryable)
+			assert.Equal(t, "amount", paymentErr.Details["field"])
+			assert.Equal(t, "10.00 MRU", paymentErr.Details["min_amount"])
+			assert.Equal(t, "500000.00 MRU", paymentErr.Details["max_amount"])
+		})
+	}
+}
+
+func TestCheckAmountUnlimitedByDefault(t *testing.T) {
+	assert.NoError(t, ProviderConfig{}.CheckAmount(money.NewMRU(1)))
+	assert.NoError(t, ProviderConfig{MinAmount: 100}.CheckAmount(money.NewMRU(1<<40)))
+}
+
+func TestProcessBPayPaymentRejectsOutOfRangeAmount(t *testing.T) {
+	client, provider := newLimitsTestClient(t, 1000, 100000)
+
+	_, err := client.ProcessBPayPayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(200000)})
+
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Equal(t, ProviderBPay, paymentErr.Provider)
+	assert.Zero(t, provider.calls, "out-of-range payments must not reach the provider")
+
+	_, err = client.ProcessBPayPayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(5000)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestAddBPayProviderAppliesAmountLimits(t *testing.T) {
+	provider := &countingBPayProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	prevBPay := createBPayProvider
+	defer func() { createBPayProvider = prevBPay }()
+	createBPayProvider = func(ProviderConfig, Logger) (PaymentProvider, error) {
+		return provider, nil
+	}
+
+	// The Config section sets no limits; the ones given to AddBPayProvider apply
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddBPayProvider(ProviderConfig{
+		Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		MinAmount: 1000, MaxAmount: 100000,
+	}))
+
+	_, err = client.ProcessBPayPayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(500)})
+	assert.True(t, IsValidation(err), "got %v", err)
+	assert.Equal(t, ProviderBPay, err.(*PaymentError).Provider)
+	assert.Zero(t, provider.calls)
+
+	_, err = client.ProcessBPayPayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(5000)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+
+	// A provider instance added directly falls back to the Config section
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+	_, err = client.ProcessBPayPayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(500)})
+	require.NoError(t, err)
+}
+
+func TestValidateRejectsInvalidAmountLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int64
+		wantErr  string
+	}{
+		{"negative", -1, 0, "cannot be negative"},
+		{"max below min", 5000, 1000, "max_amount must not be less than min_amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Providers[ProviderBPay] = ProviderConfig{
+				Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+				MinAmount: tt.min, MaxAmount: tt.max,
+			}
+			err := config.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// euroProvider declares its own currencies
+type euroProvider struct {
+	countingBPayProvider
+}
+
+func (p *euroProvider) SupportedCurrencies() []money.Currency {
+	return []money.Currency{"EUR"}
+}
+
+func TestProcessPaymentRejectsUnsupportedCurrency(t *testing.T) {
+	client, provider := newLimitsTestClient(t, 0, 0)
+	request := newValidBPayRequest()
+
+	for _, currency := range []money.Currency{"USD", money.MRO} {
+		request.Amount = money.FromCents(5000, currency)
+		_, err := client.ProcessBPayPayment(context.Background(), request)
+		var paymentErr *PaymentError
+		require.ErrorAs(t, err, &paymentErr, currency)
+		assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+		assert.Equal(t, ProviderBPay, paymentErr.Provider)
+		assert.Equal(t, "amount", paymentErr.Details["field"])
+		assert.Equal(t, `amount: currency "`+string(currency)+`" is not supported by bpay; it accepts MRU`, paymentErr.Message)
+	}
+	assert.Zero(t, provider.calls, "unsupported currencies never reach the provider")
+
+	request.Amount = money.NewMRU(5000)
+	_, err := client.ProcessBPayPayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestCurrencySupporterReplacesDefaults(t *testing.T) {
+	client, _ := newLimitsTestClient(t, 0, 0)
+	require.NoError(t, client.AddProvider("euro", &euroProvider{countingBPayProvider{namedProvider: namedProvider{name: "euro"}}}))
+
+	assert.Equal(t, []money.Currency{money.MRU}, client.supportedCurrencies(ProviderBPay))
+	assert.NoError(t, client.checkCurrency("euro", money.FromCents(100, "EUR")))
+	assert.Error(t, client.checkCurrency("euro", money.NewMRU(100)))
+	assert.NoError(t, client.checkCurrency("acme", money.FromCents(100, "USD")), "unknown providers accept any currency")
+}
@@ -0,0 +1,87 @@
+package rimpay
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// paymentLog is what ContextLogger adds to the lines logged for one payment
+type paymentLog struct {
+	fields []interface{}
+	// quiet drops the Info lines of payments left out by
+	// LoggingConfig.SampleSuccessRate
+	quiet bool
+}
+
+type paymentLogKey struct{}
+
+// withPaymentLog returns ctx carrying the log fields of a payment to
+// provider and its sampling decision, which ContextLogger applies
+func (c *Client) withPaymentLog(ctx context.Context, provider, reference string) context.Context {
+	config := c.currentConfig()
+	log := &paymentLog{fields: []interface{}{
+		"provider", provider,
+		"reference", reference,
+		"environment", string(config.Environment),
+	}}
+	if rate := config.Logging.SampleSuccessRate; rate > 1 {
+		log.quiet = (atomic.AddUint32(&c.logSamples, 1)-1)%uint32(rate) != 0
+	}
+	return context.WithValue(ctx, paymentLogKey{}, log)
+}
+
+// paymentLogFrom returns the payment log carried by ctx, or nil
+func paymentLogFrom(ctx context.Context) *paymentLog {
+	if ctx == nil {
+		return nil
+	}
+	log, _ := ctx.Value(paymentLogKey{}).(*paymentLog)
+	return log
+}
+
+// paymentLogger adds the fields of a payment to every line that does not
+// set them itself, and drops the Info lines of quiet payments. Warn and
+// Error lines are never dropped.
+type paymentLogger struct {
+	next Logger
+	log  *paymentLog
+}
+
+func (l *paymentLogger) fields(fields []interface{}) []interface{} {
+	merged := make([]interface{}, 0, len(l.log.fields)+len(fields))
+	for i := 0; i+1 < len(l.log.fields); i += 2 {
+		if !hasLogField(fields, l.log.fields[i]) {
+			merged = append(merged, l.log.fields[i], l.log.fields[i+1])
+		}
+	}
+	return append(merged, fields...)
+}
+
+// hasLogField reports whether key is one of the keys of fields
+func hasLogField(fields []interface{}, key interface{}) bool {
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *paymentLogger) Debug(msg string, fields ...interface{}) {
+	l.next.Debug(msg, l.fields(fields)...)
+}
+
+func (l *paymentLogger) Info(msg string, fields ...interface{}) {
+	if l.log.quiet {
+		return
+	}
+	l.next.Info(msg, l.fields(fields)...)
+}
+
+func (l *paymentLogger) Warn(msg string, fields ...interface{}) {
+	l.next.Warn(msg, l.fields(fields)...)
+}
+
+func (l *paymentLogger) Error(msg string, fields ...interface{}) {
+	l.next.Error(msg, l.fields(fields)...)
+}
@@ -0,0 +1,116 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logLine struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// countingLogger keeps every line with its fields
+type countingLogger struct {
+	mu    sync.Mutex
+	lines []logLine
+}
+
+func (l *countingLogger) add(level, msg string, fields []interface{}) {
+	line := logLine{level: level, msg: msg, fields: make(map[string]interface{})}
+	for i := 0; i+1 < len(fields); i += 2 {
+		line.fields[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	l.mu.Lock()
+	l.lines = append(l.lines, line)
+	l.mu.Unlock()
+}
+
+func (l *countingLogger) Debug(msg string, fields ...interface{}) { l.add("debug", msg, fields) }
+func (l *countingLogger) Info(msg string, fields ...interface{})  { l.add("info", msg, fields) }
+func (l *countingLogger) Warn(msg string, fields ...interface{})  { l.add("warn", msg, fields) }
+func (l *countingLogger) Error(msg string, fields ...interface{}) { l.add("error", msg, fields) }
+
+func (l *countingLogger) count(level, msg string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, line := range l.lines {
+		if line.level == level && line.msg == msg {
+			n++
+		}
+	}
+	return n
+}
+
+// loggingProvider logs through ContextLogger as the bundled providers do,
+// and fails the payments whose reference is in fail
+type loggingProvider struct {
+	namedProvider
+	logger Logger
+	fail   map[string]bool
+}
+
+func (p *loggingProvider) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	ContextLogger(ctx, p.logger).Info("Provider request sent")
+	if p.fail[request.Reference] {
+		ContextLogger(ctx, p.logger).Error("Provider rejected payment")
+		return nil, NewPaymentError(ErrorCodeProviderError, "rejected", p.name, false)
+	}
+	return &PaymentResponse{TransactionID: "TX-" + request.Reference, Status: PaymentStatusSuccess}, nil
+}
+
+func TestPaymentLogSampling(t *testing.T) {
+	logger := &countingLogger{}
+	provider := &loggingProvider{namedProvider: namedProvider{name: ProviderBPay}, logger: logger, fail: map[string]bool{"REF-4": true}}
+	client := newFailoverTestClient(t, nil, provider)
+	client.logger = logger
+	client.config.Logging.SampleSuccessRate = 3
+
+	for i := 0; i < 9; i++ {
+		_, _ = client.ProcessPayment(context.Background(), &PaymentRequest{Amount: money.NewMRU(1000), Reference: fmt.Sprintf("REF-%d", i)})
+	}
+
+	// Payments 0, 3 and 6 are sampled; 4 fails
+	assert.Equal(t, 3, logger.count("info", "Payment processed"))
+	assert.Equal(t, 3, logger.count("info", "Provider request sent"))
+	assert.Equal(t, 1, logger.count("warn", "Payment failed"), "failures are never sampled")
+	assert.Equal(t, 1, logger.count("error", "Provider rejected payment"))
+}
+
+func TestPaymentLogFields(t *testing.T) {
+	logger := &countingLogger{}
+	provider := &loggingProvider{namedProvider: namedProvider{name: ProviderBPay}, logger: logger}
+	client := newFailoverTestClient(t, nil, provider)
+	client.logger = logger
+
+	ctx := WithCorrelationID(context.Background(), "corr-1")
+	_, err := client.ProcessPayment(ctx, &PaymentRequest{Amount: money.NewMRU(1000), Reference: "REF-1"})
+	require.NoError(t, err)
+
+	require.Len(t, logger.lines, 2)
+	for _, line := range logger.lines {
+		assert.Equal(t, "corr-1", line.fields["correlation_id"], line.msg)
+		assert.Equal(t, ProviderBPay, line.fields["provider"], line.msg)
+		assert.Equal(t, "REF-1", line.fields["reference"], line.msg)
+		assert.Equal(t, string(EnvironmentSandbox), line.fields["environment"], line.msg)
+	}
+
+	// Fields a line sets itself are not repeated
+	paymentLogger := ContextLogger(client.withPaymentLog(ctx, ProviderBPay, "REF-1"), logger)
+	logger.lines = nil
+	paymentLogger.Warn("Retrying", "reference", "REF-1-retry")
+	require.Len(t, logger.lines, 1)
+	assert.Equal(t, "REF-1-retry", logger.lines[0].fields["reference"])
+
+	config := DefaultConfig()
+	config.Logging.SampleSuccessRate = -1
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,284 @@
+package rimpay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+)
+
+// Log levels accepted by LoggingConfig.Level
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Log formats accepted by LoggingConfig.Format
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// RedactedLogValue replaces the values of sensitive log fields
+const RedactedLogValue = common.RedactedLogValue
+
+// RegisterSensitiveLogKeys adds field names whose values are masked in all
+// log output, along with those of fields ending in them: registering
+// "pin" masks "pin" and "card_pin" but not "pin_length"
+func RegisterSensitiveLogKeys(keys ...string) {
+	common.RegisterSensitiveLogKeys(keys...)
+}
+
+// NewRedactingLogger wraps next so that the values of sensitive fields, such
+// as passcodes, passwords, tokens and credential maps, are replaced with
+// RedactedLogValue before next sees them. A field is sensitive when its name,
+// ignoring case, is or ends with a sensitive key such as "token", so
+// "refresh_token" is masked and "token_expires" is not. Map values are masked
+// by key too. The client and the bundled providers wrap every logger they
+// are given.
+func NewRedactingLogger(next Logger) Logger {
+	return common.NewRedactingLogger(next)
+}
+
+// logLevel orders levels for filtering
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func (l logLevel) String() string {
+	return [...]string{"DEBUG", "INFO", "WARN", "ERROR"}[l]
+}
+
+func parseLogLevel(level string) (logLevel, error) {
+	switch strings.ToLower(level) {
+	case LogLevelDebug:
+		return levelDebug, nil
+	case "", LogLevelInfo:
+		return levelInfo, nil
+	case LogLevelWarn, "warning":
+		return levelWarn, nil
+	case LogLevelError:
+		return levelError, nil
+	default:
+		return levelInfo, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// Validate checks the level and format; the output is checked when the
+// client opens it
+func (c LoggingConfig) Validate() error {
+	if _, err := parseLogLevel(c.Level); err != nil {
+		return err
+	}
+	if c.SampleSuccessRate < 0 {
+		return fmt.Errorf("sample_success_rate cannot be negative")
+	}
+	switch strings.ToLower(c.Format) {
+	case "", LogFormatText, LogFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown log format %q", c.Format)
+	}
+}
+
+// defaultLogger writes leveled text or JSON lines
+type defaultLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level logLevel
+	json  bool
+	now   func() time.Time
+}
+
+// newDefaultLogger creates the logger described by config. Output is
+// "stdout" (the default), "stderr" or a file path, which is appended to.
+func newDefaultLogger(config LoggingConfig) (Logger, error) {
+	logger, err := openDefaultLogger(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedactingLogger(logger), nil
+}
+
+// openDefaultLogger creates the unredacted logger described by config
+func openDefaultLogger(config LoggingConfig) (*defaultLogger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	level, _ := parseLogLevel(config.Level)
+
+	logger := &defaultLogger{
+		level: level,
+		json:  strings.EqualFold(config.Format, LogFormatJSON),
+		now:   time.Now,
+	}
+
+	switch config.Output {
+	case "", "stdout":
+		logger.out = os.Stdout
+	case "stderr":
+		logger.out = os.Stderr
+	default:
+		file, err := os.OpenFile(config.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open log output: %w", err)
+		}
+		logger.out = file
+	}
+	return logger, nil
+}
+
+func (l *defaultLogger) Debug(msg string, fields ...interface{}) {
+	l.log(levelDebug, msg, fields)
+}
+
+func (l *defaultLogger) Info(msg string, fields ...interface{}) {
+	l.log(levelInfo, msg, fields)
+}
+
+func (l *defaultLogger) Warn(msg string, fields ...interface{}) {
+	l.log(levelWarn, msg, fields)
+}
+
+func (l *defaultLogger) Error(msg string, fields ...interface{}) {
+	l.log(levelError, msg, fields)
+}
+
+func (l *defaultLogger) log(level logLevel, msg string, fields []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var line string
+	if l.json {
+		line = l.formatJSON(level, msg, fields)
+	} else {
+		line = l.formatText(level, msg, fields)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.out, line+"\n")
+}
+
+func (l *defaultLogger) formatText(level logLevel, msg string, fields []interface{}) string {
+	var b strings.Builder
+	b.WriteString("time=" + l.now().Format(time.RFC3339))
+	b.WriteString(" level=" + level.String())
+	b.WriteString(" msg=" + quoteLogValue(msg))
+	eachLogField(fields, func(key string, value interface{}) {
+		b.WriteString(" " + key + "=" + quoteLogValue(fmt.Sprint(value)))
+	})
+	return b.String()
+}
+
+func (l *defaultLogger) formatJSON(level logLevel, msg string, fields []interface{}) string {
+	entry := map[string]interface{}{
+		"time":  l.now().Format(time.RFC3339),
+		"level": level.String(),
+		"msg":   msg,
+	}
+	eachLogField(fields, func(key string, value interface{}) {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[key] = value
+	})
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Values that cannot be encoded fall back to their string form
+		for key, value := range entry {
+			entry[key] = fmt.Sprint(value)
+		}
+		data, _ = json.Marshal(entry)
+	}
+	return string(data)
+}
+
+// eachLogField calls fn for each key-value pair; a non-string key or a
+// missing value is reported under !BADKEY like log/slog does
+func eachLogField(fields []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok || i+1 == len(fields) {
+			fn("!BADKEY", fields[i])
+			i--
+			continue
+		}
+		fn(key, fields[i+1])
+	}
+}
+
+func quoteLogValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ZapSugaredLogger is the subset of *zap.SugaredLogger used by NewZapLogger,
+// so rimpay does not depend on zap
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewZapLogger adapts a zap sugared logger, as returned by
+// (*zap.Logger).Sugar(), with sensitive fields masked
+func NewZapLogger(logger ZapSugaredLogger) Logger {
+	return NewRedactingLogger(&zapLogger{logger: logger})
+}
+
+type zapLogger struct {
+	logger ZapSugaredLogger
+}
+
+func (l *zapLogger) Debug(msg string, fields ...interface{}) {
+	l.logger.Debugw(msg, fields...)
+}
+
+func (l *zapLogger) Info(msg string, fields ...interface{}) {
+	l.logger.Infow(msg, fields...)
+}
+
+func (l *zapLogger) Warn(msg string, fields ...interface{}) {
+	l.logger.Warnw(msg, fields...)
+}
+
+func (l *zapLogger) Error(msg string, fields ...interface{}) {
+	l.logger.Errorw(msg, fields...)
+}
+
+// WithLogger replaces the client's logger, for example with NewSlogLogger or
+// NewZapLogger, wrapped by NewRedactingLogger. Set it before adding
+// providers, which keep the logger they were created with.
+func (c *Client) WithLogger(logger Logger) *Client {
+	if logger == nil {
+		return c
+	}
+	logger = NewRedactingLogger(logger)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = logger
+	if c.alerter != nil {
+		c.alerter.logger = logger
+	}
+	return c
+}
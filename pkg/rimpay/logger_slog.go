@@ -0,0 +1,45 @@
+//go:build go1.21
+
+package rimpay
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger adapts a log/slog logger, with sensitive fields masked.
+// Fields are passed to slog as alternating keys and values.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return NewRedactingLogger(&slogLogger{logger: logger})
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) Debug(msg string, fields ...interface{}) {
+	l.log(slog.LevelDebug, msg, fields)
+}
+
+func (l *slogLogger) Info(msg string, fields ...interface{}) {
+	l.log(slog.LevelInfo, msg, fields)
+}
+
+func (l *slogLogger) Warn(msg string, fields ...interface{}) {
+	l.log(slog.LevelWarn, msg, fields)
+}
+
+func (l *slogLogger) Error(msg string, fields ...interface{}) {
+	l.log(slog.LevelError, msg, fields)
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, fields []interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	l.logger.Log(ctx, level, msg, fields...)
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+
+package rimpay
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug("filtered", "reference", "REF-0")
+	logger.Info("Making B-PAY payment request", "passcode", "4321", "reference", "REF-1")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), "exactly one entry is written")
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "****", entry["passcode"])
+	assert.Equal(t, "REF-1", entry["reference"])
+	assert.NotContains(t, buf.String(), "4321")
+}
@@ -0,0 +1,200 @@
+package rimpay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBufferLogger(t *testing.T, config LoggingConfig) (Logger, *bytes.Buffer) {
+	t.Helper()
+	l, err := openDefaultLogger(config)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	l.out = buf
+	l.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return NewRedactingLogger(l), buf
+}
+
+func TestDefaultLoggerLevelFiltering(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{"debug", []string{"DEBUG", "INFO", "WARN", "ERROR"}},
+		{"", []string{"INFO", "WARN", "ERROR"}},
+		{"warn", []string{"WARN", "ERROR"}},
+		{"ERROR", []string{"ERROR"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, buf := newBufferLogger(t, LoggingConfig{Level: tt.level})
+			logger.Debug("d")
+			logger.Info("i")
+			logger.Warn("w")
+			logger.Error("e")
+
+			var levels []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				levels = append(levels, strings.TrimPrefix(strings.Fields(line)[1], "level="))
+			}
+			assert.Equal(t, tt.want, levels)
+		})
+	}
+}
+
+func TestDefaultLoggerFormats(t *testing.T) {
+	logger, buf := newBufferLogger(t, LoggingConfig{Format: "text"})
+	logger.Info("Payment sent", "provider", "bpay", "message", "two words", "dangling")
+	assert.Equal(t,
+		`time=2026-01-02T03:04:05Z level=INFO msg="Payment sent" provider=bpay message="two words" !BADKEY=dangling`+"\n",
+		buf.String())
+
+	logger, buf = newBufferLogger(t, LoggingConfig{Format: "json"})
+	logger.Warn("Payment failed", "attempt", 2, "error", ErrPaymentFailed)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, map[string]interface{}{
+		"time":    "2026-01-02T03:04:05Z",
+		"level":   "WARN",
+		"msg":     "Payment failed",
+		"attempt": float64(2),
+		"error":   ErrPaymentFailed.Error(),
+	}, entry)
+}
+
+func TestDefaultLoggerRedactsSensitiveFields(t *testing.T) {
+	logger, buf := newBufferLogger(t, LoggingConfig{Format: "json"})
+	logger.Info("Making B-PAY payment request",
+		"passcode", "4321",
+		"Client_Secret", "s3cr3t",
+		"credentials", map[string]string{"username": "merchant", "password": "hunter2"},
+		"config", map[string]interface{}{"access_token": "abc", "timeout": "30s"},
+		"operation_id", "REF-1",
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"4321", "s3cr3t", "hunter2", "abc"} {
+		assert.NotContains(t, out, secret)
+	}
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "****", entry["passcode"])
+	assert.Equal(t, "****", entry["credentials"])
+	assert.Equal(t, map[string]interface{}{"access_token": "****", "timeout": "30s"}, entry["config"])
+	assert.Equal(t, "REF-1", entry["operation_id"])
+}
+
+func TestDefaultLoggerFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rimpay.log")
+	logger, err := newDefaultLogger(LoggingConfig{Output: path})
+	require.NoError(t, err)
+
+	logger.Info("hello", "passcode", "1234")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `msg=hello passcode=****`)
+}
+
+func TestLoggingConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Logging.Validate())
+	assert.EqualError(t, LoggingConfig{Level: "verbose"}.Validate(), `unknown log level "verbose"`)
+	assert.EqualError(t, LoggingConfig{Format: "xml"}.Validate(), `unknown log format "xml"`)
+
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Logging.Output = filepath.Join(t.TempDir(), "missing", "rimpay.log")
+	_, err := NewClient(config)
+	assert.ErrorContains(t, err, "invalid logging config: open log output")
+}
+
+// sugaredRecorder records zap-style calls
+type sugaredRecorder struct {
+	calls []string
+	kvs   [][]interface{}
+}
+
+func (s *sugaredRecorder) record(level, msg string, kv []interface{}) {
+	s.calls = append(s.calls, level+" "+msg)
+	s.kvs = append(s.kvs, kv)
+}
+
+func (s *sugaredRecorder) Debugw(msg string, kv ...interface{}) { s.record("debug", msg, kv) }
+func (s *sugaredRecorder) Infow(msg string, kv ...interface{})  { s.record("info", msg, kv) }
+func (s *sugaredRecorder) Warnw(msg string, kv ...interface{})  { s.record("warn", msg, kv) }
+func (s *sugaredRecorder) Errorw(msg string, kv ...interface{}) { s.record("error", msg, kv) }
+
+func TestZapLogger(t *testing.T) {
+	recorder := &sugaredRecorder{}
+	logger := NewZapLogger(recorder)
+
+	fields := []interface{}{"passcode", "4321", "amount", "50.00"}
+	logger.Debug("a", fields...)
+	logger.Info("b")
+	logger.Warn("c")
+	logger.Error("d")
+
+	assert.Equal(t, []string{"debug a", "info b", "warn c", "error d"}, recorder.calls)
+	assert.Equal(t, []interface{}{"passcode", "****", "amount", "50.00"}, recorder.kvs[0])
+	assert.Equal(t, "4321", fields[1], "the caller's fields must not be modified")
+}
+
+func TestClientWithLogger(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	recorder := &sugaredRecorder{}
+	assert.Same(t, client, client.WithLogger(NewZapLogger(recorder)))
+	assert.Same(t, client, client.WithLogger(nil))
+	require.NoError(t, client.AddProvider(ProviderBPay, &namedProvider{name: ProviderBPay}))
+	assert.Equal(t, []string{"info Provider added"}, recorder.calls)
+}
+
+func TestRedactingLogger(t *testing.T) {
+	recorder := &sugaredRecorder{}
+	logger := NewRedactingLogger(&zapLogger{logger: recorder})
+	assert.Same(t, logger, NewRedactingLogger(logger), "wrapping twice is a no-op")
+	assert.Nil(t, NewRedactingLogger(nil))
+
+	RegisterSensitiveLogKeys("X_Test_PIN")
+	logger.Warn("Payment failed",
+		"refresh_token", "r-1",
+		"accessToken", "a-1",
+		"token_expires", 3600,
+		"card_x_test_pin", "9999",
+		"x_test_pin_length", 4,
+		"username", "merchant",
+		"metadata", map[string]interface{}{"passcode": "4321", "provider": "bpay"},
+		"provider", "bpay",
+	)
+
+	assert.Equal(t, []interface{}{
+		"refresh_token", "****",
+		"accessToken", "****",
+		"token_expires", 3600,
+		"card_x_test_pin", "****",
+		"x_test_pin_length", 4,
+		"username", "****",
+		"metadata", map[string]interface{}{"passcode": "****", "provider": "bpay"},
+		"provider", "bpay",
+	}, recorder.kvs[0])
+
+	logger.Error("Request failed", "error", errors.New(`Get "https://masrvi.test/status?merchantid=M-1": EOF`), "body", `{"token":"t-1"}`)
+	assert.Equal(t, []interface{}{
+		"error", `Get "https://masrvi.test/status?merchantid=[REDACTED]": EOF`,
+		"body", `{"token":[REDACTED]}`,
+	}, recorder.kvs[1])
+}
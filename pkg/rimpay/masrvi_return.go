@@ -0,0 +1,135 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MasrviReturnData represents the parameters MASRVI appends when it redirects
+// the customer back to the accept, decline or cancel URL. The values are
+// supplied by the customer's browser and must be treated as hints only.
+type MasrviReturnData struct {
+	Status      string `json:"status"`
+	PurchaseRef string `json:"purchaseref"`
+	PaymentRef  string `json:"paymentref,omitempty"`
+	PayID       string `json:"payid,omitempty"`
+}
+
+// ParseMasrviReturn extracts MASRVI return parameters from the redirect
+// request. Both query string and form-encoded bodies are accepted.
+func ParseMasrviReturn(r *http.Request) (*MasrviReturnData, error) {
+	if r == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("invalid return parameters: %w", err)
+	}
+
+	data := &MasrviReturnData{
+		Status:      strings.TrimSpace(formValue(r, "status")),
+		PurchaseRef: strings.TrimSpace(formValue(r, "purchaseref")),
+		PaymentRef:  strings.TrimSpace(formValue(r, "paymentref")),
+		PayID:       strings.TrimSpace(formValue(r, "payid")),
+	}
+
+	if data.PurchaseRef == "" {
+		return nil, NewValidationError("purchaseref", "is required")
+	}
+
+	return data, nil
+}
+
+// formValue returns a form value, matching the parameter name case-insensitively
+// since MASRVI is inconsistent about capitalisation (e.g. Purchaseref)
+func formValue(r *http.Request, name string) string {
+	return paramValue(r.Form, name)
+}
+
+// paramValue is formValue for parsed parameters
+func paramValue(params url.Values, name string) string {
+	if v := params.Get(name); v != "" {
+		return v
+	}
+	// Sort the keys so a form carrying several spellings resolves the same
+	// way every time
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if values := params[key]; strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// HintedStatus returns the payment status claimed by the return parameters
+func (d *MasrviReturnData) HintedStatus() PaymentStatus {
+	if d == nil {
+		return PaymentStatusPending
+	}
+	switch strings.ToUpper(d.Status) {
+	case "OK":
+		return PaymentStatusSuccess
+	case "NOK":
+		return PaymentStatusFailed
+	default:
+		return PaymentStatusPending
+	}
+}
+
+// ConfirmReturn cross-checks MASRVI return parameters against the provider's
+// own view of the transaction. The returned status is always the provider's.
+// ProviderData["return_verified"] reports whether the hint was confirmed, and
+// ErrReturnMismatch is returned alongside the status when the two disagree.
+func (c *Client) ConfirmReturn(ctx context.Context, data *MasrviReturnData) (*TransactionStatus, error) {
+	if data == nil || data.PurchaseRef == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	provider, err := c.GetMasrviProvider()
+	if err != nil {
+		return nil, fmt.Errorf(providerNotAvailableMsg, ProviderMasrvi)
+	}
+
+	var status *TransactionStatus
+	err = c.invoke(ctx, ProviderMasrvi, func() (err error) {
+		status, err = provider.GetPaymentStatus(ctx, data.PurchaseRef)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.recordStatus(ctx, status)
+
+	if status.ProviderData == nil {
+		status.ProviderData = make(map[string]interface{})
+	}
+	status.ProviderData["return_status"] = data.Status
+
+	hinted := data.HintedStatus()
+	mismatch := status.Status.IsCompleted() && hinted.IsCompleted() && hinted != status.Status
+	if data.PaymentRef != "" && status.ProviderReference != "" && data.PaymentRef != status.ProviderReference {
+		mismatch = true
+	}
+
+	status.ProviderData["return_verified"] = !mismatch && status.Status.IsCompleted() && hinted == status.Status
+
+	if mismatch {
+		c.logger.Warn("MASRVI return parameters do not match provider status",
+			"reference", data.PurchaseRef,
+			"return_status", data.Status,
+			"provider_status", status.Status,
+		)
+		return status, ErrReturnMismatch
+	}
+
+	return status, nil
+}
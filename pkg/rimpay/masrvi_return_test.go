@@ -0,0 +1,123 @@
+package rimpay
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMasrviProvider returns a canned status for every lookup.
+type fakeMasrviProvider struct {
+	status *TransactionStatus
+}
+
+func (f *fakeMasrviProvider) Name() string                     { return ProviderMasrvi }
+func (f *fakeMasrviProvider) IsAvailable(context.Context) bool { return true }
+func (f *fakeMasrviProvider) ValidateConfig() error            { return nil }
+func (f *fakeMasrviProvider) Capabilities() Capabilities       { return Capabilities{} }
+
+func (f *fakeMasrviProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	return nil, ErrPaymentFailed
+}
+
+func (f *fakeMasrviProvider) ProcessMasrviPayment(context.Context, *MasrviPaymentRequest) (*PaymentResponse, error) {
+	return nil, ErrPaymentFailed
+}
+
+func (f *fakeMasrviProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	status := *f.status
+	status.Reference = transactionID
+	return &status, nil
+}
+
+func (f *fakeMasrviProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func (f *fakeMasrviProvider) HandleNotification(*MasrviNotificationData) (*TransactionStatus, error) {
+	return f.status, nil
+}
+
+func newReturnTestClient(t *testing.T, status PaymentStatus, providerRef string) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &fakeMasrviProvider{
+		status: &TransactionStatus{Status: status, ProviderReference: providerRef},
+	}))
+	return client
+}
+
+func TestParseMasrviReturn(t *testing.T) {
+	req := httptest.NewRequest("GET", "/return?status=Ok&Purchaseref=ORDER-1&paymentref=PR-9", nil)
+
+	data, err := ParseMasrviReturn(req)
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER-1", data.PurchaseRef)
+	assert.Equal(t, "PR-9", data.PaymentRef)
+	assert.Equal(t, PaymentStatusSuccess, data.HintedStatus())
+}
+
+func TestParseMasrviReturnMissingReference(t *testing.T) {
+	req := httptest.NewRequest("GET", "/return?status=Ok", nil)
+
+	_, err := ParseMasrviReturn(req)
+	assert.Error(t, err)
+}
+
+func TestConfirmReturnConsistent(t *testing.T) {
+	client := newReturnTestClient(t, PaymentStatusSuccess, "PR-9")
+
+	status, err := client.ConfirmReturn(context.Background(), &MasrviReturnData{
+		Status: "Ok", PurchaseRef: "ORDER-1", PaymentRef: "PR-9",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+	assert.Equal(t, true, status.ProviderData["return_verified"])
+}
+
+func TestConfirmReturnInconsistent(t *testing.T) {
+	client := newReturnTestClient(t, PaymentStatusFailed, "PR-9")
+
+	status, err := client.ConfirmReturn(context.Background(), &MasrviReturnData{
+		Status: "Ok", PurchaseRef: "ORDER-1",
+	})
+	assert.ErrorIs(t, err, ErrReturnMismatch)
+	require.NotNil(t, status)
+	assert.Equal(t, PaymentStatusFailed, status.Status, "provider status must win over the hint")
+	assert.Equal(t, false, status.ProviderData["return_verified"])
+}
+
+func TestConfirmReturnPaymentRefMismatch(t *testing.T) {
+	client := newReturnTestClient(t, PaymentStatusSuccess, "PR-9")
+
+	_, err := client.ConfirmReturn(context.Background(), &MasrviReturnData{
+		Status: "Ok", PurchaseRef: "ORDER-1", PaymentRef: "FORGED",
+	})
+	assert.ErrorIs(t, err, ErrReturnMismatch)
+}
+
+func TestConfirmReturnPendingIsUnverified(t *testing.T) {
+	client := newReturnTestClient(t, PaymentStatusPending, "")
+
+	status, err := client.ConfirmReturn(context.Background(), &MasrviReturnData{
+		Status: "Ok", PurchaseRef: "ORDER-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, false, status.ProviderData["return_verified"])
+}
+
+func TestConfirmReturnMissingData(t *testing.T) {
+	client := newReturnTestClient(t, PaymentStatusSuccess, "")
+
+	_, err := client.ConfirmReturn(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = client.ConfirmReturn(context.Background(), &MasrviReturnData{Status: "Ok"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
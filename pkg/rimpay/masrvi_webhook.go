@@ -0,0 +1,293 @@
+package rimpay
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+)
+
+// MasrviSignatureParam is the notification parameter carrying the HMAC
+const MasrviSignatureParam = "signature"
+
+// MasrviWebhookOptions configures NewMasrviWebhookHandler
+type MasrviWebhookOptions struct {
+	// Handle receives every verified notification, whatever its status. An
+	// error makes the handler answer 500 so MASRVI re-delivers it.
+	Handle func(ctx context.Context, notification *MasrviNotificationData) error
+	// SigningKey, usually Config.Security.SigningKey, enables verification of
+	// the hex HMAC-SHA256 in the signature parameter (empty = no check)
+	SigningKey string
+	// MaxAge rejects notifications whose timestamp is further than MaxAge
+	// from the current time, to stop replays (0 = no check)
+	MaxAge time.Duration
+	// AllowedIPs lists the addresses and CIDR ranges notifications may come
+	// from (empty = any)
+	AllowedIPs []string
+	// Logger receives rejected notifications (optional)
+	Logger Logger
+	// Dispatcher, when set, runs Handle once per unique notification and
+	// one notification per payment at a time (optional)
+	Dispatcher *NotificationDispatcher
+
+	now func() time.Time
+}
+
+type masrviWebhook struct {
+	opts     MasrviWebhookOptions
+	verifier MasrviNotificationVerifier
+}
+
+// NewMasrviWebhookHandler returns an http.Handler that parses MASRVI
+// notifications, verifies their signature and age, passes them to
+// opts.Handle and answers the "OK" body MASRVI expects. Set opts.Dispatcher
+// for deduplication, or use Client.NewMasrviWebhookHandler for load
+// shedding too.
+func NewMasrviWebhookHandler(opts MasrviWebhookOptions) http.Handler {
+	if opts.Handle == nil {
+		panic("rimpay: MasrviWebhookOptions.Handle is required")
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	return &masrviWebhook{opts: opts, verifier: MasrviNotificationVerifier{
+		SigningKey: opts.SigningKey,
+		MaxAge:     opts.MaxAge,
+		AllowedIPs: opts.AllowedIPs,
+		Now:        opts.now,
+	}}
+}
+
+func (h *masrviWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	notification, err := ParseMasrviNotification(r)
+	if err != nil {
+		h.warn("Malformed MASRVI notification", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, duplicate, err := h.opts.Dispatcher.dispatch(r.Context(), inboundNotification{
+		provider:  ProviderMasrvi,
+		reference: notification.Reference,
+		verify: func(context.Context) (*TransactionStatus, string, error) {
+			verified, err := h.verifier.Verify(notification)
+			if err != nil {
+				return nil, "", err
+			}
+			notification = verified
+			return nil, notificationKey(notification), nil
+		},
+		handle: func(ctx context.Context, _ *TransactionStatus) error {
+			return h.opts.Handle(ctx, notification)
+		},
+	})
+	var processing *processingError
+	switch {
+	case errors.As(err, &processing):
+		if h.opts.Logger != nil {
+			h.opts.Logger.Error("MASRVI notification handling failed", "reference", notification.Reference, "error", err)
+		}
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	case err != nil:
+		h.warn("Rejected MASRVI notification", "reference", notification.Reference, "error", err)
+		http.Error(w, "invalid notification", http.StatusUnauthorized)
+		return
+	case duplicate && h.opts.Logger != nil:
+		h.opts.Logger.Debug("Duplicate MASRVI notification acknowledged", "reference", notification.Reference)
+	}
+	writeWebhookOK(w)
+}
+
+// NotificationRejectReason says why a notification failed verification
+type NotificationRejectReason string
+
+const (
+	// NotificationBadSignature means the signature is missing or wrong
+	NotificationBadSignature NotificationRejectReason = "bad_signature"
+	// NotificationStaleTimestamp means the timestamp is missing or outside
+	// the accepted window, as for replays
+	NotificationStaleTimestamp NotificationRejectReason = "stale_timestamp"
+	// NotificationBadSource means the sender is not an allowed address
+	NotificationBadSource NotificationRejectReason = "bad_source_ip"
+)
+
+// NotificationRejectedError is returned for notifications that fail
+// verification. It matches ErrNotificationRejected with errors.Is.
+type NotificationRejectedError struct {
+	Provider string
+	Reason   NotificationRejectReason
+	Err      error
+}
+
+func (e *NotificationRejectedError) Error() string {
+	return fmt.Sprintf("%s notification rejected (%s): %v", e.Provider, e.Reason, e.Err)
+}
+
+func (e *NotificationRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrNotificationRejected
+func (e *NotificationRejectedError) Is(target error) bool {
+	return target == ErrNotificationRejected
+}
+
+// MasrviNotificationVerifier authenticates MASRVI notifications. Its zero
+// value accepts everything.
+type MasrviNotificationVerifier struct {
+	// SigningKey enables verification of the hex HMAC-SHA256 in the
+	// signature parameter over the notification's Params (empty = no check)
+	SigningKey string
+	// MaxAge rejects timestamps further than MaxAge from now (0 = no check)
+	MaxAge time.Duration
+	// AllowedIPs lists the addresses and CIDR ranges notifications may come
+	// from. It applies to notifications with a RemoteAddr (empty = any).
+	AllowedIPs []string
+	// Now is the clock MaxAge is measured against (default time.Now)
+	Now func() time.Time
+}
+
+// Validate checks AllowedIPs
+func (v MasrviNotificationVerifier) Validate() error {
+	for _, entry := range v.AllowedIPs {
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return fmt.Errorf("invalid allowed IP or CIDR %q", entry)
+		}
+	}
+	return nil
+}
+
+// Verify returns the notification to trust, or a NotificationRejectedError.
+// With a SigningKey that is the notification parsed again from its signed
+// Params, so that fields changed after parsing are ignored.
+func (v MasrviNotificationVerifier) Verify(notification *MasrviNotificationData) (*MasrviNotificationData, error) {
+	if notification == nil {
+		return nil, NewValidationError("notification", "is required")
+	}
+
+	if len(v.AllowedIPs) > 0 && notification.RemoteAddr != "" && !v.allows(notification.RemoteAddr) {
+		return nil, rejectMasrvi(NotificationBadSource, fmt.Errorf("%s is not an allowed address", notification.RemoteAddr))
+	}
+
+	if v.SigningKey != "" {
+		if err := VerifyMasrviSignature(notification.Params, v.SigningKey); err != nil {
+			return nil, rejectMasrvi(NotificationBadSignature, err)
+		}
+		signed, err := ParseMasrviParams(notification.Params)
+		if err != nil {
+			return nil, err
+		}
+		signed.RemoteAddr = notification.RemoteAddr
+		notification = signed
+	}
+
+	if v.MaxAge > 0 {
+		sent, err := parseNotificationTime(notification.Timestamp)
+		if err != nil {
+			return nil, rejectMasrvi(NotificationStaleTimestamp, err)
+		}
+		now := time.Now
+		if v.Now != nil {
+			now = v.Now
+		}
+		if age := now().Sub(sent); age > v.MaxAge || age < -v.MaxAge {
+			return nil, rejectMasrvi(NotificationStaleTimestamp,
+				fmt.Errorf("timestamp %s outside the %s window", notification.Timestamp, v.MaxAge))
+		}
+	}
+	return notification, nil
+}
+
+// allows reports whether remoteAddr, a host or host:port, is allowed
+func (v MasrviNotificationVerifier) allows(remoteAddr string) bool {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range v.AllowedIPs {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func rejectMasrvi(reason NotificationRejectReason, err error) error {
+	return &NotificationRejectedError{Provider: ProviderMasrvi, Reason: reason, Err: err}
+}
+
+func (h *masrviWebhook) warn(msg string, fields ...interface{}) {
+	if h.opts.Logger != nil {
+		h.opts.Logger.Warn(msg, fields...)
+	}
+}
+
+// SignMasrviNotification returns the hex HMAC-SHA256 of the notification
+// parameters, excluding the signature itself, in canonical form
+func SignMasrviNotification(params url.Values, key string) string {
+	unsigned := make(url.Values, len(params))
+	for name, values := range params {
+		if name != MasrviSignatureParam {
+			unsigned[name] = values
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(common.CanonicalForm(unsigned)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyMasrviSignature checks the signature parameter of params against key
+func VerifyMasrviSignature(params url.Values, key string) error {
+	signature, err := hex.DecodeString(strings.TrimSpace(params.Get(MasrviSignatureParam)))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("missing or malformed signature")
+	}
+	expected, _ := hex.DecodeString(SignMasrviNotification(params, key))
+	if !hmac.Equal(signature, expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// notificationTimeLayouts are the timestamp formats accepted besides Unix seconds
+var notificationTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "20060102150405"}
+
+// parseNotificationTime parses a notification timestamp
+func parseNotificationTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("timestamp is required")
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) <= 11 {
+		return time.Unix(seconds, 0), nil
+	}
+	for _, layout := range notificationTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
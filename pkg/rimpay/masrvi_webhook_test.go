@@ -0,0 +1,198 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "webhook-secret"
+
+var webhookNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func masrviParams(status string) url.Values {
+	return url.Values{
+		"status":      {status},
+		"clientid":    {"CLIENT-1"},
+		"purchaseref": {"ORDER-1"},
+		"paymentref":  {"PAY-1"},
+		"payid":       {"42"},
+		"timestamp":   {strconv.FormatInt(webhookNow.Unix(), 10)},
+	}
+}
+
+func signed(params url.Values) url.Values {
+	params.Set(MasrviSignatureParam, SignMasrviNotification(params, testSigningKey))
+	return params
+}
+
+// serveMasrvi posts params to a handler built from opts and records the
+// notifications passed to Handle
+func serveMasrvi(t *testing.T, opts MasrviWebhookOptions, params url.Values) (*httptest.ResponseRecorder, []*MasrviNotificationData) {
+	t.Helper()
+	var received []*MasrviNotificationData
+	handle := opts.Handle
+	opts.Handle = func(ctx context.Context, n *MasrviNotificationData) error {
+		received = append(received, n)
+		if handle != nil {
+			return handle(ctx, n)
+		}
+		return nil
+	}
+	opts.now = func() time.Time { return webhookNow }
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/masrvi", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	NewMasrviWebhookHandler(opts).ServeHTTP(rec, req)
+	return rec, received
+}
+
+func TestMasrviWebhookHandlerDeliversNotifications(t *testing.T) {
+	for _, status := range []string{"Ok", "NOK"} {
+		t.Run(status, func(t *testing.T) {
+			params := masrviParams(status)
+			if status == "NOK" {
+				params.Set("error", "insufficient balance")
+			}
+			rec, received := serveMasrvi(t, MasrviWebhookOptions{SigningKey: testSigningKey, MaxAge: 5 * time.Minute}, signed(params))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "OK", rec.Body.String())
+			require.Len(t, received, 1)
+
+			n := received[0]
+			assert.Equal(t, status, n.Status)
+			assert.Equal(t, "ORDER-1", n.Reference)
+			assert.Equal(t, "PAY-1", n.TransactionID)
+			assert.Equal(t, "CLIENT-1", n.Data["client_id"])
+			assert.Equal(t, "42", n.Data["pay_id"])
+			if status == "NOK" {
+				assert.Equal(t, "insufficient balance", n.Data["error"])
+			}
+		})
+	}
+}
+
+func TestMasrviWebhookHandlerQueryString(t *testing.T) {
+	var received *MasrviNotificationData
+	handler := NewMasrviWebhookHandler(MasrviWebhookOptions{Handle: func(_ context.Context, n *MasrviNotificationData) error {
+		received = n
+		return nil
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/webhook/masrvi?"+masrviParams("Ok").Encode(), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, received)
+	assert.Equal(t, "ORDER-1", received.Reference)
+}
+
+func TestMasrviWebhookHandlerMissingParams(t *testing.T) {
+	for _, param := range []string{"status", "purchaseref"} {
+		t.Run(param, func(t *testing.T) {
+			params := masrviParams("Ok")
+			params.Del(param)
+			rec, received := serveMasrvi(t, MasrviWebhookOptions{}, params)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), param)
+			assert.Empty(t, received)
+		})
+	}
+}
+
+func TestMasrviWebhookHandlerRejectsBadSignature(t *testing.T) {
+	tampered := signed(masrviParams("NOK"))
+	tampered.Set("status", "Ok")
+
+	tests := []struct {
+		name   string
+		params url.Values
+	}{
+		{"missing", masrviParams("Ok")},
+		{"not hex", func() url.Values { p := masrviParams("Ok"); p.Set(MasrviSignatureParam, "zz"); return p }()},
+		{"tampered", tampered},
+		{"wrong key", func() url.Values {
+			p := masrviParams("Ok")
+			p.Set(MasrviSignatureParam, SignMasrviNotification(p, "other-key"))
+			return p
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			rec, received := serveMasrvi(t, MasrviWebhookOptions{SigningKey: testSigningKey, Logger: logger}, tt.params)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Empty(t, received)
+			assert.Equal(t, 1, logger.count("Rejected MASRVI notification"))
+		})
+	}
+}
+
+func TestMasrviWebhookHandlerRejectsReplays(t *testing.T) {
+	opts := MasrviWebhookOptions{SigningKey: testSigningKey, MaxAge: 5 * time.Minute}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		want      int
+	}{
+		{"fresh unix", strconv.FormatInt(webhookNow.Add(-time.Minute).Unix(), 10), http.StatusOK},
+		{"fresh layout", webhookNow.Add(-time.Minute).Format("2006-01-02 15:04:05"), http.StatusOK},
+		{"stale", strconv.FormatInt(webhookNow.Add(-time.Hour).Unix(), 10), http.StatusUnauthorized},
+		{"future", webhookNow.Add(time.Hour).Format(time.RFC3339), http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+		{"garbage", "yesterday", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := masrviParams("Ok")
+			params.Set("timestamp", tt.timestamp)
+			rec, _ := serveMasrvi(t, opts, signed(params))
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestMasrviWebhookHandlerChecksSourceAddress(t *testing.T) {
+	// httptest requests come from 192.0.2.1
+	rec, received := serveMasrvi(t, MasrviWebhookOptions{AllowedIPs: []string{"192.0.2.0/24"}}, masrviParams("Ok"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, received, 1)
+	assert.Equal(t, "192.0.2.1:1234", received[0].RemoteAddr)
+
+	rec, received = serveMasrvi(t, MasrviWebhookOptions{AllowedIPs: []string{"198.51.100.9"}}, masrviParams("Ok"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, received)
+}
+
+func TestMasrviWebhookHandlerCallbackError(t *testing.T) {
+	rec, received := serveMasrvi(t, MasrviWebhookOptions{
+		Handle: func(context.Context, *MasrviNotificationData) error { return errors.New("db down") },
+	}, masrviParams("Ok"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Len(t, received, 1)
+}
+
+func TestMasrviWebhookHandlerMethodNotAllowed(t *testing.T) {
+	handler := NewMasrviWebhookHandler(MasrviWebhookOptions{Handle: func(context.Context, *MasrviNotificationData) error { return nil }})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/webhook/masrvi", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,80 @@
+package rimpay
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentResponseMetadataGetters(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	response := &PaymentResponse{Metadata: map[string]interface{}{
+		"string":  "value",
+		"int":     42,
+		"float":   float64(7),
+		"partial": 7.5,
+		"number":  json.Number("9"),
+		"time":    at,
+		"rfc3339": "2026-03-01T09:00:00Z",
+	}}
+
+	s, ok := response.GetMetadataString("string")
+	assert.True(t, ok)
+	assert.Equal(t, "value", s)
+	_, ok = response.GetMetadataString("int")
+	assert.False(t, ok)
+	_, ok = response.GetMetadataString("missing")
+	assert.False(t, ok)
+
+	for key, want := range map[string]int64{"int": 42, "float": 7, "number": 9} {
+		n, ok := response.GetMetadataInt(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, n, key)
+	}
+	_, ok = response.GetMetadataInt("partial")
+	assert.False(t, ok, "fractions are not integers")
+	_, ok = response.GetMetadataInt("string")
+	assert.False(t, ok)
+
+	for _, key := range []string{"time", "rfc3339"} {
+		got, ok := response.GetMetadataTime(key)
+		assert.True(t, ok, key)
+		assert.True(t, at.Equal(got), key)
+	}
+	_, ok = response.GetMetadataTime("string")
+	assert.False(t, ok)
+
+	_, ok = (*PaymentResponse)(nil).GetMetadataString("string")
+	assert.False(t, ok)
+}
+
+func TestPaymentResponseConvenienceGetters(t *testing.T) {
+	response := &PaymentResponse{Metadata: map[string]interface{}{
+		MetadataKeyPaymentURL: "https://pay.test/checkout",
+		MetadataKeyPasscode:   "8812",
+	}}
+	paymentURL, ok := response.PaymentURLValue()
+	assert.True(t, ok, "the metadata URL is used without a PaymentURL")
+	assert.Equal(t, "https://pay.test/checkout", paymentURL)
+	passcode, ok := response.Passcode()
+	assert.True(t, ok)
+	assert.Equal(t, "8812", passcode)
+
+	response.PaymentURL = "https://pay.test/v2"
+	paymentURL, _ = response.PaymentURLValue()
+	assert.Equal(t, "https://pay.test/v2", paymentURL)
+
+	var decoded PaymentResponse
+	data, err := json.Marshal(&PaymentResponse{Metadata: map[string]interface{}{"attempts": 3}})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	attempts, ok := decoded.GetMetadataInt("attempts")
+	assert.True(t, ok, "integers survive a JSON round trip")
+	assert.Equal(t, int64(3), attempts)
+
+	_, ok = (&PaymentResponse{}).Passcode()
+	assert.False(t, ok)
+}
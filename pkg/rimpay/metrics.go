@@ -0,0 +1,117 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/internal/types"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// MetricsCollector receives measurements of payments, retries and provider
+// HTTP calls; see the metrics/prometheus package for an implementation
+type MetricsCollector = types.MetricsCollector
+
+// WithMetrics reports payments, retries and provider HTTP calls to
+// collector. It applies to providers created afterwards, so call it before
+// adding providers; nil disables metrics.
+func (c *Client) WithMetrics(collector MetricsCollector) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = collector
+	return c
+}
+
+// metricsCollector returns the collector set with WithMetrics, if any
+func (c *Client) metricsCollector() MetricsCollector {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metrics
+}
+
+// invokePayment is invoke for payment calls, which are also reported to
+// the metrics collector and traced as a span; call receives the span's
+// context, which carries the correlation ID of ctx or a new one. The
+// response's metadata records the ID.
+func (c *Client) invokePayment(ctx context.Context, provider, reference string, amount money.Money, call func(ctx context.Context) (*PaymentResponse, error)) (*PaymentResponse, error) {
+	ctx, correlationID, err := ensureCorrelationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = c.withPaymentLog(ctx, provider, reference)
+	if c.currentConfig().DryRun {
+		result, err := c.simulatedPayment(provider, reference, amount)
+		if err == nil {
+			result.Metadata[MetadataKeyCorrelationID] = correlationID
+			ContextLogger(ctx, c.logger).Info("Dry run: payment not sent")
+		}
+		return result, err
+	}
+
+	metrics := c.metricsCollector()
+	ctx, span := common.StartSpan(ctx, c.spanTracer(), "rimpay.payment",
+		common.AttrProvider, provider, common.AttrReference, reference, common.AttrAmount, amount.String(),
+		common.AttrCorrelationID, correlationID)
+
+	release, err := c.acquirePayment(ctx, provider)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	defer release()
+
+	var result *PaymentResponse
+	err = c.invoke(ctx, provider, func() (err error) {
+		if metrics == nil {
+			result, err = call(ctx)
+			return err
+		}
+		start := c.stats.now()
+		result, err = call(ctx)
+		metrics.ObservePayment(provider, paymentOutcome(result, err), c.stats.now().Sub(start))
+		return err
+	})
+
+	if err == nil {
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]interface{})
+		}
+		result.Metadata[MetadataKeyCorrelationID] = correlationID
+		span.SetAttribute(common.AttrTransactionID, result.TransactionID)
+		span.SetAttribute(common.AttrStatus, string(result.Status))
+	}
+	common.EndSpan(span, err)
+	c.logPayment(ctx, result, err)
+	return result, err
+}
+
+// logPayment logs the outcome of a payment: failures at Warn, so that
+// sampling never drops them, and other outcomes at Info
+func (c *Client) logPayment(ctx context.Context, result *PaymentResponse, err error) {
+	logger := ContextLogger(ctx, c.logger)
+	switch {
+	case err != nil:
+		logger.Warn("Payment failed", "error", err)
+	case result.Status == PaymentStatusFailed:
+		logger.Warn("Payment failed", "transaction_id", result.TransactionID, "status", result.Status)
+	default:
+		logger.Info("Payment processed", "transaction_id", result.TransactionID, "status", result.Status)
+	}
+}
+
+// paymentOutcome is the status label of a payment: its status, or the
+// error code when it failed
+func paymentOutcome(result *PaymentResponse, err error) string {
+	if err == nil {
+		if result == nil {
+			return "error"
+		}
+		return string(result.Status)
+	}
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) && paymentErr.Code != "" {
+		return string(paymentErr.Code)
+	}
+	return "error"
+}
@@ -0,0 +1,253 @@
+// Package prometheus implements rimpay.MetricsCollector, exposing payment,
+// retry and provider HTTP metrics in the Prometheus text exposition format
+// without depending on the Prometheus client library.
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the latency histogram buckets in seconds, sized for
+// provider calls that can take up to a minute
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector records rimpay metrics in memory and serves them to Prometheus.
+// It is safe for concurrent use.
+type Collector struct {
+	mu sync.Mutex
+
+	payments        *counterVec
+	paymentDuration *histogramVec
+	retries         *counterVec
+	httpRequests    *counterVec
+	httpDuration    *histogramVec
+}
+
+// NewCollector creates a collector whose metrics are prefixed with
+// namespace ("rimpay" when empty) and whose latency histograms use buckets
+// (DefaultBuckets when empty)
+func NewCollector(namespace string, buckets ...float64) *Collector {
+	if namespace == "" {
+		namespace = "rimpay"
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Collector{
+		payments: newCounterVec(namespace+"_payments_total",
+			"Payments processed, by provider and resulting status or error code.", "provider", "status"),
+		paymentDuration: newHistogramVec(namespace+"_payment_duration_seconds",
+			"Time taken by providers to process payments, including retries.", buckets, "provider"),
+		retries: newCounterVec(namespace+"_retries_total",
+			"Provider calls retried after a retryable failure.", "provider"),
+		httpRequests: newCounterVec(namespace+"_http_requests_total",
+			"HTTP requests sent to providers, by endpoint and status code (0 when no response was received).", "provider", "endpoint", "code"),
+		httpDuration: newHistogramVec(namespace+"_http_request_duration_seconds",
+			"Duration of HTTP requests sent to providers.", buckets, "provider", "endpoint"),
+	}
+}
+
+// ObservePayment records a payment attempt through provider
+func (c *Collector) ObservePayment(provider, status string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payments.inc(provider, status)
+	c.paymentDuration.observe(duration.Seconds(), provider)
+}
+
+// IncRetry records a provider call being retried
+func (c *Collector) IncRetry(provider string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries.inc(provider)
+}
+
+// ObserveHTTP records an HTTP call to a provider endpoint
+func (c *Collector) ObserveHTTP(provider, endpoint string, code int, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.httpRequests.inc(provider, endpoint, strconv.Itoa(code))
+	c.httpDuration.observe(duration.Seconds(), provider, endpoint)
+}
+
+// WriteTo writes every metric to w in the Prometheus text format
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	c.payments.write(out)
+	c.paymentDuration.write(out)
+	c.retries.write(out)
+	c.httpRequests.write(out)
+	c.httpDuration.write(out)
+	if out.err == nil {
+		out.err = out.w.Flush()
+	}
+	return out.n, out.err
+}
+
+// ServeHTTP serves the metrics, so the collector can be mounted as the
+// /metrics endpoint scraped by Prometheus
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = c.WriteTo(w)
+}
+
+// countingWriter keeps the first write error and the bytes written
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// counterVec is a counter partitioned by label values
+type counterVec struct {
+	name, help string
+	labels     []string
+	series     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	count  uint64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, series: make(map[string]*counterSeries)}
+}
+
+func (v *counterVec) inc(values ...string) {
+	key := seriesKey(values)
+	s, ok := v.series[key]
+	if !ok {
+		s = &counterSeries{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	s.count++
+}
+
+func (v *counterVec) write(out *countingWriter) {
+	if len(v.series) == 0 {
+		return
+	}
+	out.printf("# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.series[key]
+		out.printf("%s%s %d\n", v.name, formatLabels(v.labels, s.values), s.count)
+	}
+}
+
+// histogramVec is a histogram partitioned by label values
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+}
+
+func (v *histogramVec) observe(value float64, values ...string) {
+	key := seriesKey(values)
+	s, ok := v.series[key]
+	if !ok {
+		s = &histogramSeries{values: append([]string(nil), values...), counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	if i := sort.SearchFloat64s(v.buckets, value); i < len(v.buckets) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+func (v *histogramVec) write(out *countingWriter) {
+	if len(v.series) == 0 {
+		return
+	}
+	out.printf("# HELP %s %s\n# TYPE %s histogram\n", v.name, v.help, v.name)
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bucketLabels := append(append([]string(nil), v.labels...), "le")
+	for _, key := range keys {
+		s := v.series[key]
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += s.counts[i]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			out.printf("%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(s.values, le)), cumulative)
+		}
+		out.printf("%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(s.values, "+Inf")), s.count)
+		labels := formatLabels(v.labels, s.values)
+		out.printf("%s_sum%s %s\n", v.name, labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		out.printf("%s_count%s %d\n", v.name, labels, s.count)
+	}
+}
+
+// seriesKey joins label values with a byte that cannot appear in UTF-8
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders {name="value",...} with values escaped as the text
+// format requires
+func formatLabels(names, values []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
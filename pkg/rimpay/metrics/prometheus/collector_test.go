@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/rimpay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ rimpay.MetricsCollector = (*Collector)(nil)
+
+func TestCollectorWritesTextFormat(t *testing.T) {
+	collector := NewCollector("", 0.1, 1)
+	collector.ObservePayment("bpay", "success", 50*time.Millisecond)
+	collector.ObservePayment("bpay", "success", 500*time.Millisecond)
+	collector.ObservePayment("bpay", "PAYMENT_DECLINED", 2*time.Second)
+	collector.IncRetry("masrvi")
+	collector.ObserveHTTP("bpay", "/payment", 200, 50*time.Millisecond)
+	collector.ObserveHTTP("bpay", "/payment", 0, time.Second)
+
+	var out strings.Builder
+	n, err := collector.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+
+	text := out.String()
+	for _, line := range []string{
+		"# TYPE rimpay_payments_total counter",
+		`rimpay_payments_total{provider="bpay",status="PAYMENT_DECLINED"} 1`,
+		`rimpay_payments_total{provider="bpay",status="success"} 2`,
+		"# TYPE rimpay_payment_duration_seconds histogram",
+		`rimpay_payment_duration_seconds_bucket{provider="bpay",le="0.1"} 1`,
+		`rimpay_payment_duration_seconds_bucket{provider="bpay",le="1"} 2`,
+		`rimpay_payment_duration_seconds_bucket{provider="bpay",le="+Inf"} 3`,
+		`rimpay_payment_duration_seconds_sum{provider="bpay"} 2.55`,
+		`rimpay_payment_duration_seconds_count{provider="bpay"} 3`,
+		`rimpay_retries_total{provider="masrvi"} 1`,
+		`rimpay_http_requests_total{provider="bpay",endpoint="/payment",code="0"} 1`,
+		`rimpay_http_requests_total{provider="bpay",endpoint="/payment",code="200"} 1`,
+		`rimpay_http_request_duration_seconds_count{provider="bpay",endpoint="/payment"} 2`,
+	} {
+		assert.Contains(t, text, line+"\n")
+	}
+}
+
+func TestCollectorEscapesLabels(t *testing.T) {
+	collector := NewCollector("shop")
+	collector.IncRetry("a\"b\\c\nd")
+
+	var out strings.Builder
+	_, err := collector.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `shop_retries_total{provider="a\"b\\c\nd"} 1`)
+}
+
+func TestCollectorServesMetrics(t *testing.T) {
+	collector := NewCollector("")
+	collector.IncRetry("bpay")
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `rimpay_retries_total{provider="bpay"} 1`)
+}
@@ -0,0 +1,125 @@
+package rimpay
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests call exported methods on nil and zero values of the public
+// types. Client and StatusPoller are excluded: they must be built with their
+// constructors and document that their zero values are not usable.
+
+func TestNilRequestTypes(t *testing.T) {
+	assert.NotPanics(t, func() {
+		var bpay *BPayPaymentRequest
+		assert.ErrorIs(t, bpay.Validate(), ErrInvalidRequest)
+		assert.Nil(t, bpay.ToGenericRequest())
+
+		var masrvi *MasrviPaymentRequest
+		assert.ErrorIs(t, masrvi.Validate(), ErrInvalidRequest)
+		assert.Nil(t, masrvi.ToGenericRequest())
+
+		var click *ClickPaymentRequest
+		assert.ErrorIs(t, click.Validate(), ErrInvalidRequest)
+		assert.Equal(t, LanguageFrench, click.GetLanguage())
+		assert.Nil(t, click.ToGenericRequest())
+
+		var generic *PaymentRequest
+		assert.Error(t, generic.Validate())
+		assert.Equal(t, LanguageFrench, generic.GetLanguage())
+		assert.False(t, generic.IsExpired())
+	})
+}
+
+func TestZeroValueRequestTypes(t *testing.T) {
+	assert.NotPanics(t, func() {
+		assert.Error(t, (&BPayPaymentRequest{}).Validate())
+		assert.NotNil(t, (&BPayPaymentRequest{}).ToGenericRequest())
+		assert.Error(t, (&MasrviPaymentRequest{}).Validate())
+		assert.NotNil(t, (&MasrviPaymentRequest{}).ToGenericRequest())
+		assert.Error(t, (&ClickPaymentRequest{}).Validate())
+		assert.NotNil(t, (&ClickPaymentRequest{}).ToGenericRequest())
+		assert.Error(t, (&PaymentRequest{}).Validate())
+	})
+}
+
+func TestNilResponseTypes(t *testing.T) {
+	assert.NotPanics(t, func() {
+		var resp *PaymentResponse
+		assert.False(t, resp.IsCompleted())
+		assert.False(t, resp.IsSuccessful())
+
+		var status *TransactionStatus
+		assert.False(t, status.IsCompleted())
+		assert.False(t, status.IsSuccessful())
+		assert.Nil(t, status.LatestEvent())
+		assert.Nil(t, status.Events())
+		status.AddEvent(PaymentStatusSuccess, "ignored", EventSourceWebhook)
+
+		var zero PaymentResponse
+		assert.Equal(t, "0.00 ", zero.Amount.String())
+		assert.False(t, zero.IsCompleted())
+
+		var data *MasrviReturnData
+		assert.Equal(t, PaymentStatusPending, data.HintedStatus())
+	})
+}
+
+func TestNilPaymentError(t *testing.T) {
+	var err *PaymentError
+	assert.NotPanics(t, func() {
+		assert.Equal(t, "<nil>", err.Error())
+		assert.Nil(t, err.Unwrap())
+		assert.False(t, err.IsRetryable())
+		assert.Nil(t, err.WithCause(ErrNetworkError))
+		assert.Nil(t, err.WithDetail("key", "value"))
+		_, bundleErr := err.SupportBundle()
+		assert.Error(t, bundleErr)
+	})
+}
+
+func TestNilConfigAndRegistry(t *testing.T) {
+	assert.NotPanics(t, func() {
+		var config *Config
+		assert.ErrorIs(t, config.Validate(), ErrInvalidConfig)
+		_, ok := config.GetProviderConfig("bpay")
+		assert.False(t, ok)
+		assert.False(t, config.IsProduction())
+
+		var registry *ProviderRegistry
+		_, err := registry.Create("bpay", ProviderConfig{}, nil)
+		assert.Error(t, err)
+		assert.Empty(t, registry.GetRegisteredProviders())
+
+		var zero ProviderRegistry
+		zero.Register("bpay", nil)
+		assert.Equal(t, []string{"bpay"}, zero.GetRegisteredProviders())
+
+		var options ProviderConfig
+		s, err := options.StringOption("brand_name", "x")
+		assert.NoError(t, err)
+		assert.Equal(t, "x", s)
+		assert.NoError(t, options.CheckOptions())
+	})
+}
+
+func TestZeroValueEnums(t *testing.T) {
+	assert.NotPanics(t, func() {
+		var status PaymentStatus
+		assert.False(t, status.IsCompleted())
+		assert.Equal(t, "", status.String())
+
+		var priority Priority
+		assert.Equal(t, "high", priority.String())
+
+		var diagnosis Diagnosis
+		assert.NotEmpty(t, diagnosis.String())
+		assert.False(t, diagnosis.IsConfigurationFault())
+
+		var schedule PollSchedule
+		assert.Error(t, schedule.Validate())
+		_, ok := schedule.IntervalAt(0)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,161 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// OptionAmountTolerance is how far the amount of a successful notification
+// may be from the payment's before it counts as a mismatch (decimal in
+// currency units, default 0)
+const OptionAmountTolerance = "amount_tolerance"
+
+// ProviderDataAmountMismatch is set in TransactionStatus.ProviderData, with
+// the expected amount under ProviderDataExpectedAmount, when a notification
+// was failed for reporting the wrong amount
+const (
+	ProviderDataAmountMismatch = "amount_mismatch"
+	ProviderDataExpectedAmount = "expected_amount"
+)
+
+// ProviderDataAmountUnverified is set in TransactionStatus.ProviderData when
+// a successful notification was kept pending because the payment's amount
+// could not be looked up
+const ProviderDataAmountUnverified = "amount_unverified"
+
+// AmountLookupFunc returns the amount of the payment the provider created
+// for reference, or ErrTransactionNotFound
+type AmountLookupFunc func(ctx context.Context, provider, reference string) (money.Money, error)
+
+// AmountMismatchError is returned, with a failed TransactionStatus, for a
+// successful notification whose amount differs from the payment's. It
+// matches ErrAmountMismatch with errors.Is.
+type AmountMismatchError struct {
+	Provider  string
+	Reference string
+	Expected  money.Money
+	Received  money.Money
+}
+
+func (e *AmountMismatchError) Error() string {
+	return fmt.Sprintf("%s notification for %s reports %s, expected %s", e.Provider, e.Reference, e.Received, e.Expected)
+}
+
+// Is reports whether target is ErrAmountMismatch
+func (e *AmountMismatchError) Is(target error) bool {
+	return target == ErrAmountMismatch
+}
+
+// AmountUnverifiedError is returned, with a pending TransactionStatus, for a
+// successful notification whose payment amount could not be looked up. It
+// matches ErrAmountUnverified and the lookup error with errors.Is.
+type AmountUnverifiedError struct {
+	Provider  string
+	Reference string
+	Err       error
+}
+
+func (e *AmountUnverifiedError) Error() string {
+	return fmt.Sprintf("%s notification for %s cannot be verified: %v", e.Provider, e.Reference, e.Err)
+}
+
+// Is reports whether target is ErrAmountUnverified
+func (e *AmountUnverifiedError) Is(target error) bool {
+	return target == ErrAmountUnverified
+}
+
+// Unwrap returns the lookup error
+func (e *AmountUnverifiedError) Unwrap() error {
+	return e.Err
+}
+
+// WithAmountLookup makes notification amounts be checked against lookup
+// instead of the TransactionStore
+func (c *Client) WithAmountLookup(lookup AmountLookupFunc) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.amountLookup = lookup
+	return c
+}
+
+// expectedAmount returns the amount of the payment for reference from the
+// amount lookup or else the TransactionStore. ok is false when there is
+// neither.
+func (c *Client) expectedAmount(ctx context.Context, provider, reference string) (_ money.Money, ok bool, err error) {
+	c.mu.RLock()
+	lookup := c.amountLookup
+	c.mu.RUnlock()
+	if lookup != nil {
+		amount, err := lookup(ctx, provider, reference)
+		return amount, true, err
+	}
+
+	store := c.transactionStore()
+	if store == nil {
+		return money.Money{}, false, nil
+	}
+	stored, err := store.GetByReference(ctx, reference)
+	if err != nil {
+		return money.Money{}, true, err
+	}
+	return stored.Amount, true, nil
+}
+
+// checkNotificationAmount fails a successful notification status whose
+// amount is further than the provider's OptionAmountTolerance from the
+// payment's, so an underpaid order is never fulfilled. A successful status
+// whose payment cannot be looked up, including ErrTransactionNotFound, is
+// kept pending. Notifications without an amount are not checked, nor are
+// any when the client has neither an amount lookup nor a TransactionStore.
+func (c *Client) checkNotificationAmount(ctx context.Context, provider string, status *TransactionStatus) error {
+	if status == nil || status.Status != PaymentStatusSuccess || status.Amount.IsZero() {
+		return nil
+	}
+	expected, ok, err := c.expectedAmount(ctx, provider, status.Reference)
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		unverified := &AmountUnverifiedError{Provider: provider, Reference: status.Reference, Err: err}
+		status.AddEvent(PaymentStatusPending, unverified.Error(), EventSourceWebhook)
+		status.Message = unverified.Error()
+		if status.ProviderData == nil {
+			status.ProviderData = make(map[string]interface{})
+		}
+		status.ProviderData[ProviderDataAmountUnverified] = true
+		ContextLogger(ctx, c.logger).Error("Cannot verify notification amount",
+			"provider", provider, "reference", status.Reference, "error", err)
+		return unverified
+	}
+
+	// NewMasrviProvider has already rejected invalid tolerances
+	config, _ := c.providerConfig(provider)
+	tolerance, _ := config.DecimalOption(OptionAmountTolerance, decimal.Zero)
+	if amountWithin(status.Amount, expected, tolerance) {
+		return nil
+	}
+
+	mismatch := &AmountMismatchError{Provider: provider, Reference: status.Reference, Expected: expected, Received: status.Amount}
+	status.AddEvent(PaymentStatusFailed, mismatch.Error(), EventSourceWebhook)
+	status.Message = mismatch.Error()
+	if status.ProviderData == nil {
+		status.ProviderData = make(map[string]interface{})
+	}
+	status.ProviderData[ProviderDataAmountMismatch] = true
+	status.ProviderData[ProviderDataExpectedAmount] = expected.String()
+	ContextLogger(ctx, c.logger).Error("Notification amount does not match the payment",
+		"provider", provider, "reference", status.Reference, "expected", expected.String(), "received", status.Amount.String())
+	return mismatch
+}
+
+// amountWithin reports whether received is in expected's currency and at
+// most tolerance away from it
+func amountWithin(received, expected money.Money, tolerance decimal.Decimal) bool {
+	if received.Currency() != expected.Currency() {
+		return false
+	}
+	return received.Amount().Sub(expected.Amount()).Abs().LessThanOrEqual(tolerance)
+}
@@ -0,0 +1,212 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// amountProvider reports notifications with the amount they carry, or with
+// paid when set, as the real provider does from the parameters
+type amountProvider struct {
+	notifyingProvider
+	paid money.Money
+}
+
+func (p *amountProvider) HandleNotification(n *MasrviNotificationData) (*TransactionStatus, error) {
+	if !p.paid.IsZero() {
+		n.Amount = p.paid
+	}
+	status, err := p.notifyingProvider.HandleNotification(n)
+	status.Amount = n.Amount
+	return status, err
+}
+
+func newAmountTestClient(t *testing.T, options map[string]interface{}, provider *amountProvider) (*Client, *MemoryTransactionStore) {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderMasrvi
+	config.Providers[ProviderMasrvi] = ProviderConfig{Enabled: true, BaseURL: "https://masrvi.test", Timeout: time.Second, Options: options}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	if provider == nil {
+		provider = &amountProvider{}
+	}
+	provider.name = ProviderMasrvi
+	require.NoError(t, client.AddProvider(ProviderMasrvi, provider))
+
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	require.NoError(t, store.SavePayment(context.Background(), &PaymentResponse{
+		TransactionID: "TX-1", Reference: "ORDER-1", Amount: money.NewMRU(10000), Status: PaymentStatusPending, Provider: ProviderMasrvi,
+	}))
+	return client, store
+}
+
+func paidNotification(amount money.Money) *MasrviNotificationData {
+	return &MasrviNotificationData{TransactionID: "TX-1", Reference: "ORDER-1", Status: "OK", Amount: amount}
+}
+
+func TestNotificationAmountMismatchFailsPayment(t *testing.T) {
+	client, store := newAmountTestClient(t, nil, nil)
+
+	status, err := client.HandleMasrviNotification(paidNotification(money.NewMRU(9999)))
+	require.ErrorIs(t, err, ErrAmountMismatch)
+	var mismatch *AmountMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, money.NewMRU(10000), mismatch.Expected)
+	assert.Equal(t, money.NewMRU(9999), mismatch.Received)
+	assert.Equal(t, "ORDER-1", mismatch.Reference)
+
+	require.NotNil(t, status)
+	assert.Equal(t, PaymentStatusFailed, status.Status)
+	assert.Equal(t, true, status.ProviderData[ProviderDataAmountMismatch])
+	assert.Equal(t, money.NewMRU(10000).String(), status.ProviderData[ProviderDataExpectedAmount])
+
+	stored, err := store.GetByReference(context.Background(), "ORDER-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusFailed, stored.Status, "the order is never recorded as paid")
+}
+
+func TestNotificationAmountMatches(t *testing.T) {
+	client, store := newAmountTestClient(t, nil, nil)
+
+	status, err := client.HandleMasrviNotification(paidNotification(money.NewMRU(10000)))
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+
+	_, err = client.HandleMasrviNotification(paidNotification(money.FromCents(10000, money.MRO)))
+	assert.ErrorIs(t, err, ErrAmountMismatch, "the currency must match too")
+
+	_, err = client.HandleMasrviNotification(&MasrviNotificationData{TransactionID: "TX-2", Reference: "ORDER-2", Status: "OK"})
+	assert.NoError(t, err, "notifications without an amount are not checked")
+
+	stored, err := store.GetByReference(context.Background(), "ORDER-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusFailed, stored.Status)
+}
+
+func TestNotificationAmountTolerance(t *testing.T) {
+	client, _ := newAmountTestClient(t, map[string]interface{}{OptionAmountTolerance: "0.50"}, nil)
+
+	_, err := client.HandleMasrviNotification(paidNotification(money.NewMRU(9950)))
+	assert.NoError(t, err)
+	_, err = client.HandleMasrviNotification(paidNotification(money.NewMRU(10050)))
+	assert.NoError(t, err)
+	_, err = client.HandleMasrviNotification(paidNotification(money.NewMRU(9949)))
+	assert.ErrorIs(t, err, ErrAmountMismatch)
+}
+
+func TestNotificationAmountLookup(t *testing.T) {
+	client, _ := newAmountTestClient(t, nil, nil)
+	var looked []string
+	client.WithAmountLookup(func(_ context.Context, provider, reference string) (money.Money, error) {
+		looked = append(looked, provider+":"+reference)
+		if reference == "ORDER-1" {
+			return money.NewMRU(5000), nil
+		}
+		return money.Money{}, ErrTransactionNotFound
+	})
+
+	_, err := client.HandleMasrviNotification(paidNotification(money.NewMRU(10000)))
+	assert.ErrorIs(t, err, ErrAmountMismatch, "the lookup wins over the store")
+
+	_, err = client.HandleMasrviNotification(&MasrviNotificationData{Reference: "ORDER-9", Status: "OK", Amount: money.NewMRU(1)})
+	assert.ErrorIs(t, err, ErrAmountUnverified, "payments of unknown amount are not accepted")
+	assert.Equal(t, []string{"masrvi:ORDER-1", "masrvi:ORDER-9"}, looked)
+}
+
+// brokenLookupStore fails every lookup
+type brokenLookupStore struct {
+	*MemoryTransactionStore
+}
+
+var errStoreDown = errors.New("store down")
+
+func (s brokenLookupStore) GetByReference(context.Context, string) (*StoredTransaction, error) {
+	return nil, errStoreDown
+}
+
+func TestNotificationAmountUnverified(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(client *Client, store *MemoryTransactionStore)
+		notif *MasrviNotificationData
+		cause error
+	}{
+		{
+			name:  "not found",
+			setup: func(*Client, *MemoryTransactionStore) {},
+			notif: &MasrviNotificationData{TransactionID: "TX-9", Reference: "ORDER-9", Status: "OK", Amount: money.NewMRU(10000)},
+			cause: ErrTransactionNotFound,
+		},
+		{
+			name: "store error",
+			setup: func(client *Client, store *MemoryTransactionStore) {
+				client.WithTransactionStore(brokenLookupStore{store})
+			},
+			notif: paidNotification(money.NewMRU(10000)),
+			cause: errStoreDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, store := newAmountTestClient(t, nil, nil)
+			tt.setup(client, store)
+
+			status, err := client.HandleMasrviNotification(tt.notif)
+			require.ErrorIs(t, err, ErrAmountUnverified)
+			assert.ErrorIs(t, err, tt.cause)
+			var unverified *AmountUnverifiedError
+			require.True(t, errors.As(err, &unverified))
+			assert.Equal(t, tt.notif.Reference, unverified.Reference)
+
+			require.NotNil(t, status)
+			assert.Equal(t, PaymentStatusPending, status.Status, "the payment is never accepted as paid")
+			assert.Equal(t, true, status.ProviderData[ProviderDataAmountUnverified])
+
+			stored, err := store.GetByReference(context.Background(), tt.notif.Reference)
+			require.NoError(t, err)
+			assert.Equal(t, PaymentStatusPending, stored.Status)
+		})
+	}
+}
+
+func TestWebhookRedeliversUnverifiedAmount(t *testing.T) {
+	client, _ := newAmountTestClient(t, nil, &amountProvider{paid: money.NewMRU(10000)})
+	handled := 0
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		handled++
+		return nil
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, notificationRequest("ORDER-9"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Zero(t, handled)
+}
+
+func TestWebhookHandsOnAmountMismatch(t *testing.T) {
+	client, _ := newAmountTestClient(t, nil, &amountProvider{paid: money.NewMRU(100)})
+	var handled []*TransactionStatus
+	handler := client.NewMasrviWebhookHandler(func(_ context.Context, status *TransactionStatus) error {
+		handled = append(handled, status)
+		return nil
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+
+	assert.Equal(t, http.StatusOK, rec.Code, "a genuine notification is not re-delivered")
+	require.Len(t, handled, 1)
+	assert.Equal(t, PaymentStatusFailed, handled[0].Status)
+}
@@ -0,0 +1,253 @@
+package rimpay
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OptionStatusOverrides maps raw provider statuses to canonical PaymentStatus
+// values, taking precedence over the provider's built-in mapping
+const OptionStatusOverrides = "status_overrides"
+
+// OptionCallbackSecret is the shared secret B-PAY sends with its status
+// callbacks, which NewBPayWebhookHandler checks
+const OptionCallbackSecret = "callback_secret"
+
+// StringOption returns Options[key] as a string, or def when unset
+func (p ProviderConfig) StringOption(key, def string) (string, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return def, fmt.Errorf("option %s must be a string, got %T", key, value)
+	}
+	return s, nil
+}
+
+// DurationOption returns Options[key] as a duration, or def when unset.
+// Values may be a time.Duration or a string such as "5m".
+func (p ProviderConfig) DurationOption(key string, def time.Duration) (time.Duration, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return def, fmt.Errorf("option %s: %w", key, err)
+		}
+		return d, nil
+	default:
+		return def, fmt.Errorf("option %s must be a duration, got %T", key, value)
+	}
+}
+
+// BoolOption returns Options[key] as a bool, or def when unset. Values may be
+// a bool or a string accepted by strconv.ParseBool.
+func (p ProviderConfig) BoolOption(key string, def bool) (bool, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return def, fmt.Errorf("option %s: %w", key, err)
+		}
+		return b, nil
+	default:
+		return def, fmt.Errorf("option %s must be a bool, got %T", key, value)
+	}
+}
+
+// IntOption returns Options[key] as an int, or def when unset. Values may be
+// an int, a whole float64 as decoded from JSON, or a numeric string.
+func (p ProviderConfig) IntOption(key string, def int) (int, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return def, fmt.Errorf("option %s must be a whole number, got %v", key, v)
+		}
+		return int(v), nil
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return def, fmt.Errorf("option %s: %w", key, err)
+		}
+		return n, nil
+	default:
+		return def, fmt.Errorf("option %s must be an integer, got %T", key, value)
+	}
+}
+
+// DecimalOption returns Options[key] as a decimal, or def when unset. Values
+// may be a decimal.Decimal, a number or a numeric string.
+func (p ProviderConfig) DecimalOption(key string, def decimal.Decimal) (decimal.Decimal, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case decimal.Decimal:
+		return v, nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	case int:
+		return decimal.NewFromInt(int64(v)), nil
+	case string:
+		d, err := decimal.NewFromString(strings.TrimSpace(v))
+		if err != nil {
+			return def, fmt.Errorf("option %s: %w", key, err)
+		}
+		return d, nil
+	default:
+		return def, fmt.Errorf("option %s must be a decimal number, got %T", key, value)
+	}
+}
+
+// StringSliceOption returns Options[key] as a list of strings, or def when
+// unset. Values may be a []string, a decoded JSON/YAML list or a
+// comma-separated string.
+func (p ProviderConfig) StringSliceOption(key string, def []string) ([]string, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return def, fmt.Errorf("option %s must be a list of strings, got element %T", key, item)
+			}
+			items = append(items, s)
+		}
+		return items, nil
+	default:
+		return def, fmt.Errorf("option %s must be a list of strings, got %T", key, value)
+	}
+}
+
+// StringMapOption returns Options[key] as a map of strings, or nil when
+// unset. Values may be a map[string]string or a decoded JSON/YAML map.
+func (p ProviderConfig) StringMapOption(key string) (map[string]string, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case map[string]string:
+		return v, nil
+	case map[string]interface{}:
+		items := make(map[string]string, len(v))
+		for name, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("option %s: %q must map to a string, got %T", key, name, item)
+			}
+			items[name] = s
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("option %s must be a map of strings, got %T", key, value)
+	}
+}
+
+// StatusMapOption returns Options[key] as a map of raw provider statuses to
+// PaymentStatus, or nil when unset. Values may be a map[string]string,
+// map[string]PaymentStatus or a decoded JSON/YAML map; every target must be a
+// known PaymentStatus.
+func (p ProviderConfig) StatusMapOption(key string) (map[string]PaymentStatus, error) {
+	value, ok := p.Options[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	raw := make(map[string]interface{})
+	switch v := value.(type) {
+	case map[string]PaymentStatus:
+		for status, target := range v {
+			raw[status] = string(target)
+		}
+	case map[string]string:
+		for status, target := range v {
+			raw[status] = target
+		}
+	case map[string]interface{}:
+		raw = v
+	default:
+		return nil, fmt.Errorf("option %s must be a map of statuses, got %T", key, value)
+	}
+
+	statuses := make(map[string]PaymentStatus, len(raw))
+	for status, target := range raw {
+		s, ok := target.(string)
+		if !ok {
+			return nil, fmt.Errorf("option %s: status %q must map to a string, got %T", key, status, target)
+		}
+		if !PaymentStatus(s).IsValid() {
+			return nil, fmt.Errorf("option %s: status %q maps to unknown payment status %q", key, status, s)
+		}
+		statuses[status] = PaymentStatus(s)
+	}
+	return statuses, nil
+}
+
+// CheckOptions returns an error naming any option key not in allowed, so
+// typos in configuration fail loudly instead of being ignored
+func (p ProviderConfig) CheckOptions(allowed ...string) error {
+	if unknown := p.UnknownOptions(allowed...); len(unknown) > 0 {
+		return fmt.Errorf("unknown options %v (supported: %v)", unknown, allowed)
+	}
+	return nil
+}
+
+// UnknownOptions returns the option keys not in allowed, sorted, for
+// providers that warn about them rather than fail
+func (p ProviderConfig) UnknownOptions(allowed ...string) []string {
+	known := make(map[string]bool, len(allowed))
+	for _, key := range allowed {
+		known[key] = true
+	}
+
+	var unknown []string
+	for key := range p.Options {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
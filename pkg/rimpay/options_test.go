@@ -0,0 +1,96 @@
+package rimpay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderConfigOptions(t *testing.T) {
+	config := ProviderConfig{Options: map[string]interface{}{
+		"name":     "Shop",
+		"ttl":      "90s",
+		"ttl_raw":  2 * time.Minute,
+		"enabled":  "false",
+		"flag":     true,
+		"bad_int":  42,
+		"bad_time": "soon",
+	}}
+
+	s, err := config.StringOption("name", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "Shop", s)
+
+	s, err = config.StringOption("missing", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", s)
+
+	_, err = config.StringOption("bad_int", "")
+	assert.Error(t, err)
+
+	d, err := config.DurationOption("ttl", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, err = config.DurationOption("ttl_raw", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, d)
+
+	_, err = config.DurationOption("bad_time", time.Minute)
+	assert.Error(t, err)
+
+	b, err := config.BoolOption("enabled", true)
+	assert.NoError(t, err)
+	assert.False(t, b)
+
+	b, err = config.BoolOption("flag", false)
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = config.BoolOption("bad_int", false)
+	assert.Error(t, err)
+
+	config.Options["json_int"] = float64(6)
+	config.Options["text_int"] = "6"
+	for _, key := range []string{"bad_int", "json_int", "text_int"} {
+		n, err := config.IntOption(key, 4)
+		assert.NoError(t, err, key)
+		assert.NotEqual(t, 4, n, key)
+	}
+	n, err := config.IntOption("missing", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	_, err = config.IntOption("ttl", 4)
+	assert.Error(t, err)
+	config.Options["fraction"] = 6.5
+	_, err = config.IntOption("fraction", 4)
+	assert.Error(t, err)
+	_, err = config.IntOption("flag", 4)
+	assert.Error(t, err)
+
+	config.Options["labels"] = map[string]interface{}{"A-": "success"}
+	config.Options["bad_labels"] = map[string]interface{}{"A-": 1}
+	m, err := config.StringMapOption("labels")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A-": "success"}, m)
+
+	m, err = config.StringMapOption("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = config.StringMapOption("bad_labels")
+	assert.Error(t, err)
+	_, err = config.StringMapOption("name")
+	assert.Error(t, err)
+}
+
+func TestProviderConfigCheckOptions(t *testing.T) {
+	config := ProviderConfig{Options: map[string]interface{}{"brand_name": "Shop", "sesion_ttl": "1m"}}
+
+	err := config.CheckOptions("brand_name", "session_ttl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sesion_ttl")
+
+	assert.NoError(t, ProviderConfig{}.CheckOptions("brand_name"))
+}
@@ -0,0 +1,136 @@
+package rimpay
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedProvider answers status checks with its own name as the transaction ID
+type namedProvider struct {
+	name         string
+	capabilities Capabilities
+}
+
+func (p *namedProvider) Name() string                     { return p.name }
+func (p *namedProvider) IsAvailable(context.Context) bool { return true }
+func (p *namedProvider) ValidateConfig() error            { return nil }
+func (p *namedProvider) Capabilities() Capabilities       { return p.capabilities }
+
+func (p *namedProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	return &PaymentResponse{TransactionID: p.name}, nil
+}
+
+func (p *namedProvider) GetPaymentStatus(context.Context, string) (*TransactionStatus, error) {
+	return &TransactionStatus{TransactionID: p.name}, nil
+}
+
+func (p *namedProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func newOrderingTestClient(t *testing.T, defaultProvider string, names ...string) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = defaultProvider
+	config.Providers[defaultProvider] = ProviderConfig{Enabled: true, BaseURL: "https://provider.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	for _, name := range names {
+		require.NoError(t, client.AddProvider(name, &namedProvider{name: name}))
+	}
+	return client
+}
+
+func TestListProvidersIsSorted(t *testing.T) {
+	client := newOrderingTestClient(t, "bpay", "masrvi", "click", "zeta", "bpay", "alpha")
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, []string{"alpha", "bpay", "click", "masrvi", "zeta"}, client.ListProviders())
+	}
+}
+
+func TestGetRegisteredProvidersIsSorted(t *testing.T) {
+	registry := NewProviderRegistry()
+	for _, name := range []string{"masrvi", "click", "bpay", "alpha"} {
+		registry.Register(name, nil)
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, []string{"alpha", "bpay", "click", "masrvi"}, registry.GetRegisteredProviders())
+	}
+}
+
+func TestGenericCallsUseDefaultProvider(t *testing.T) {
+	client := newOrderingTestClient(t, "masrvi", "click", "masrvi", "bpay")
+
+	for i := 0; i < 20; i++ {
+		status, err := client.GetPaymentStatus(context.Background(), "TX-1")
+		require.NoError(t, err)
+		assert.Equal(t, "masrvi", status.TransactionID)
+
+		resp, err := client.ProcessPayment(context.Background(), &PaymentRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "masrvi", resp.TransactionID)
+	}
+}
+
+func TestGenericCallsFallBackToFirstProviderByName(t *testing.T) {
+	client := newOrderingTestClient(t, "unregistered", "masrvi", "click", "bpay")
+
+	for i := 0; i < 20; i++ {
+		status, err := client.GetPaymentStatus(context.Background(), "TX-1")
+		require.NoError(t, err)
+		assert.Equal(t, "bpay", status.TransactionID)
+	}
+}
+
+func TestCallsWithProviderTargetNamedProvider(t *testing.T) {
+	client := newOrderingTestClient(t, "bpay", "bpay", "masrvi", "click")
+	names := []string{"bpay", "masrvi", "click"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		name := names[i%len(names)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.ProcessPaymentWithProvider(context.Background(), name, &PaymentRequest{Amount: money.NewMRU(5000)})
+			if assert.NoError(t, err) {
+				assert.Equal(t, name, resp.TransactionID)
+			}
+
+			status, err := client.GetPaymentStatusWithProvider(context.Background(), name, "TX-1")
+			if assert.NoError(t, err) {
+				assert.Equal(t, name, status.TransactionID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err := client.ProcessPaymentWithProvider(context.Background(), "sedad", &PaymentRequest{Amount: money.NewMRU(5000)})
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+	_, err = client.GetPaymentStatusWithProvider(context.Background(), "sedad", "TX-1")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+}
+
+func TestFormValueIsDeterministic(t *testing.T) {
+	r := &http.Request{Form: url.Values{
+		"PurchaseRef": {"A"},
+		"Purchaseref": {"B"},
+		"PURCHASEREF": {"C"},
+	}}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "C", formValue(r, "purchaseref"))
+	}
+}
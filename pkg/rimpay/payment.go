@@ -10,6 +10,20 @@ type (
 	Language        = types.Language
 	PaymentRequest  = types.PaymentRequest
 	PaymentResponse = types.PaymentResponse
+	RefundRequest   = types.RefundRequest
+	RefundResponse  = types.RefundResponse
+	StatusEvent     = types.StatusEvent
+	RedirectInfo    = types.RedirectInfo
+
+	BPayOperationType = types.BPayOperationType
+	BPayMode          = types.BPayMode
+
+	BPayRawResponse       = types.BPayRawResponse
+	MasrviRawNotification = types.MasrviRawNotification
+
+	DisbursementRequest  = types.DisbursementRequest
+	DisbursementResponse = types.DisbursementResponse
+	DisbursementStatus   = types.DisbursementStatus
 )
 
 // Re-export constants
@@ -23,4 +37,51 @@ const (
 	LanguageEnglish = types.LanguageEnglish
 	LanguageFrench  = types.LanguageFrench
 	LanguageArabic  = types.LanguageArabic
+
+	BPayOperationPayment         = types.BPayOperationPayment
+	BPayOperationMerchantPayment = types.BPayOperationMerchantPayment
+	BPayOperationBillPayment     = types.BPayOperationBillPayment
+
+	BPayModePasscode = types.BPayModePasscode
+	BPayModeUSSDPush = types.BPayModeUSSDPush
+
+	EventSourceInitial = types.EventSourceInitial
+	EventSourcePoll    = types.EventSourcePoll
+	EventSourceWebhook = types.EventSourceWebhook
+
+	DisbursementStatusPending   = types.DisbursementStatusPending
+	DisbursementStatusCompleted = types.DisbursementStatusCompleted
+	DisbursementStatusFailed    = types.DisbursementStatusFailed
 )
+
+// NewStatusEvent creates an event observed now
+func NewStatusEvent(status PaymentStatus, message, source string) StatusEvent {
+	return types.NewStatusEvent(status, message, source)
+}
+
+// BPayOperationTypes returns every defined B-PAY operation type
+func BPayOperationTypes() []BPayOperationType {
+	return types.BPayOperationTypes()
+}
+
+// BPayModes returns every defined B-PAY mode
+func BPayModes() []BPayMode {
+	return types.BPayModes()
+}
+
+// Well-known PaymentResponse.Metadata keys
+const (
+	MetadataKeyPaymentURL        = types.MetadataKeyPaymentURL
+	MetadataKeyFormData          = types.MetadataKeyFormData
+	MetadataKeyPasscode          = types.MetadataKeyPasscode
+	MetadataKeySessionID         = types.MetadataKeySessionID
+	MetadataKeyErrorCode         = types.MetadataKeyErrorCode
+	MetadataKeyErrorMessage      = types.MetadataKeyErrorMessage
+	MetadataKeyProviderReference = types.MetadataKeyProviderReference
+	MetadataKeyCorrelationID     = types.MetadataKeyCorrelationID
+)
+
+// MetadataAwaitingConfirmation is the PaymentResponse.Metadata and
+// TransactionStatus.ProviderData key set to true while a B-PAY USSD push
+// payment waits for the customer to confirm it on their handset
+const MetadataAwaitingConfirmation = "awaiting_confirmation"
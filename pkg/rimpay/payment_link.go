@@ -0,0 +1,453 @@
+package rimpay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// DefaultLinkExpiry is how long a payment link stays usable when
+// LinkOptions.ExpiresIn is 0
+const DefaultLinkExpiry = time.Hour
+
+// LinkOptions configures CreatePaymentLink
+type LinkOptions struct {
+	// ExpiresIn is how long the link stays usable (0 = DefaultLinkExpiry).
+	// The request's ExpiresAt wins when it is sooner.
+	ExpiresIn time.Duration
+	// SingleUse rejects the link once a customer has been sent to the
+	// payment page through it. It requires BaseURL.
+	SingleUse bool
+	// BaseURL is where NewPaymentLinkHandler is served, such as
+	// "https://shop.example/pay/". The link is BaseURL followed by its
+	// token. Empty makes the link the raw MASRVI URL, which expiry and
+	// SingleUse cannot be enforced on and which lasts only as long as its
+	// MASRVI session.
+	BaseURL string
+}
+
+// PaymentLink is a shareable URL that sends the customer to a MASRVI
+// payment page
+type PaymentLink struct {
+	Token     string      `json:"token"`
+	URL       string      `json:"url"`
+	Reference string      `json:"reference"`
+	Amount    money.Money `json:"amount"`
+	// Hosted is set when URL is served by NewPaymentLinkHandler
+	Hosted    bool       `json:"hosted"`
+	SingleUse bool       `json:"single_use,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	// Uses counts the times the link sent a customer to the payment page
+	Uses int `json:"uses"`
+	// Request is the payment of a hosted link, whose MASRVI session is
+	// opened each time the link is used
+	Request *MasrviPaymentRequest `json:"request,omitempty"`
+	// Payment is the MASRVI response holding the payment page and form of a
+	// link that is not hosted
+	Payment *PaymentResponse `json:"payment,omitempty"`
+}
+
+// Expired reports whether the link is no longer usable at now
+func (l *PaymentLink) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+func (l *PaymentLink) copy() *PaymentLink {
+	cp := *l
+	if l.UsedAt != nil {
+		usedAt := *l.UsedAt
+		cp.UsedAt = &usedAt
+	}
+	if l.Request != nil {
+		request := *l.Request
+		cp.Request = &request
+	}
+	return &cp
+}
+
+// PaymentLinkStore keeps payment links. Implementations must be safe for
+// concurrent use.
+type PaymentLinkStore interface {
+	// SaveLink stores a new link
+	SaveLink(ctx context.Context, link *PaymentLink) error
+	// GetLink returns the link, or ErrLinkNotFound
+	GetLink(ctx context.Context, token string) (*PaymentLink, error)
+	// ReserveLink records a use at the given time, before the payment
+	// session is opened, and returns the updated link. It must fail
+	// atomically with ErrLinkExpired when the link is expired at that time,
+	// and with ErrLinkUsed when it is single use and already reserved.
+	ReserveLink(ctx context.Context, token string, at time.Time) (*PaymentLink, error)
+	// ReleaseLink gives back a use reserved for a session that could not
+	// be opened
+	ReleaseLink(ctx context.Context, token string) error
+}
+
+// MemoryLinkStore is a PaymentLinkStore held in memory
+type MemoryLinkStore struct {
+	mu    sync.Mutex
+	links map[string]*PaymentLink
+}
+
+// NewMemoryLinkStore returns an empty MemoryLinkStore
+func NewMemoryLinkStore() *MemoryLinkStore {
+	return &MemoryLinkStore{links: make(map[string]*PaymentLink)}
+}
+
+// SaveLink stores link
+func (s *MemoryLinkStore) SaveLink(_ context.Context, link *PaymentLink) error {
+	if link == nil || link.Token == "" {
+		return ErrInvalidRequest
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links[link.Token] = link.copy()
+	return nil
+}
+
+// GetLink returns the link
+func (s *MemoryLinkStore) GetLink(_ context.Context, token string) (*PaymentLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link, ok := s.links[token]; ok {
+		return link.copy(), nil
+	}
+	return nil, ErrLinkNotFound
+}
+
+// ReserveLink records a use of the link unless it is expired at at or used
+// up
+func (s *MemoryLinkStore) ReserveLink(_ context.Context, token string, at time.Time) (*PaymentLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[token]
+	if !ok {
+		return nil, ErrLinkNotFound
+	}
+	if link.Expired(at) {
+		return nil, ErrLinkExpired
+	}
+	if link.SingleUse && link.Uses > 0 {
+		return nil, ErrLinkUsed
+	}
+	link.Uses++
+	if link.UsedAt == nil {
+		link.UsedAt = &at
+	}
+	return link.copy(), nil
+}
+
+// ReleaseLink gives back a reserved use of the link
+func (s *MemoryLinkStore) ReleaseLink(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[token]
+	if !ok {
+		return ErrLinkNotFound
+	}
+	if link.Uses > 0 {
+		link.Uses--
+	}
+	if link.Uses == 0 {
+		link.UsedAt = nil
+	}
+	return nil
+}
+
+// WithLinkStore makes the client keep payment links in store instead of in
+// memory. Links already created are not moved.
+func (c *Client) WithLinkStore(store PaymentLinkStore) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if store == nil {
+		store = NewMemoryLinkStore()
+	}
+	c.links = store
+	return c
+}
+
+func (c *Client) linkStore() PaymentLinkStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.links
+}
+
+// CreatePaymentLink returns a link to a MASRVI payment page for request that
+// can be shared with the customer, for example by an agent over a messaging
+// app. Hosted links are served by NewPaymentLinkHandler, which enforces their
+// expiry and single use and opens the MASRVI session when the link is used.
+// Other links are the raw URL of a session opened now, and expire with it.
+func (c *Client) CreatePaymentLink(ctx context.Context, request *MasrviPaymentRequest, options LinkOptions) (*PaymentLink, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+	if options.ExpiresIn < 0 {
+		return nil, NewValidationError("expires_in", "must not be negative")
+	}
+	if options.BaseURL == "" && options.SingleUse {
+		return nil, NewValidationError("base_url", "is required for single use links")
+	}
+	if options.BaseURL != "" {
+		base, err := url.Parse(options.BaseURL)
+		if err != nil || base.Host == "" || base.Scheme != "https" && base.Scheme != "http" {
+			return nil, NewValidationError("base_url", "must be an absolute http(s) URL")
+		}
+	}
+
+	var payment *PaymentResponse
+	var target string
+	if options.BaseURL == "" {
+		var err error
+		if payment, err = c.ProcessMasrviPayment(ctx, request); err != nil {
+			return nil, err
+		}
+		if target, err = payment.LinkURL(); err != nil {
+			return nil, err
+		}
+	} else if _, _, err := c.checkMasrviPayment(request); err != nil {
+		return nil, err
+	}
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.scheduler.now()
+	expiresIn := options.ExpiresIn
+	if expiresIn == 0 {
+		expiresIn = DefaultLinkExpiry
+	}
+	link := &PaymentLink{
+		Token:     token,
+		URL:       target,
+		Reference: request.Reference,
+		Amount:    request.Amount,
+		SingleUse: options.SingleUse,
+		CreatedAt: now,
+		ExpiresAt: now.Add(expiresIn),
+		Payment:   payment,
+	}
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(link.ExpiresAt) {
+		link.ExpiresAt = *request.ExpiresAt
+	}
+	if payment != nil && payment.ExpiresAt != nil && payment.ExpiresAt.Before(link.ExpiresAt) {
+		link.ExpiresAt = *payment.ExpiresAt
+	}
+	if options.BaseURL != "" {
+		saved := *request
+		link.Hosted = true
+		link.URL = strings.TrimSuffix(options.BaseURL, "/") + "/" + token
+		link.Request = &saved
+	}
+
+	if err := c.linkStore().SaveLink(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to save payment link: %w", err)
+	}
+	return link.copy(), nil
+}
+
+// GetPaymentLink returns the link created with token, or ErrLinkNotFound
+func (c *Client) GetPaymentLink(ctx context.Context, token string) (*PaymentLink, error) {
+	return c.linkStore().GetLink(ctx, token)
+}
+
+func newLinkToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate link token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PaymentLinkRejection describes a request for a link that cannot be used
+type PaymentLinkRejection struct {
+	Token string
+	// Link is nil when the token is unknown
+	Link *PaymentLink
+	// Err is ErrLinkNotFound, ErrLinkExpired or ErrLinkUsed
+	Err error
+	At  time.Time
+}
+
+// Reason is "not_found", "expired" or "used", for error page templates
+func (r *PaymentLinkRejection) Reason() string {
+	switch {
+	case errors.Is(r.Err, ErrLinkExpired):
+		return "expired"
+	case errors.Is(r.Err, ErrLinkUsed):
+		return "used"
+	}
+	return "not_found"
+}
+
+// PaymentLinkHandlerOptions configures NewPaymentLinkHandler
+type PaymentLinkHandlerOptions struct {
+	// ErrorPage renders unusable links from a *PaymentLinkRejection
+	// (optional)
+	ErrorPage *template.Template
+	// OnRejected is called for every unusable link requested (optional)
+	OnRejected func(ctx context.Context, rejection *PaymentLinkRejection)
+	// Logger receives store failures (optional; defaults to the client's)
+	Logger Logger
+}
+
+// defaultLinkErrorPage is used when PaymentLinkHandlerOptions.ErrorPage is
+// nil
+var defaultLinkErrorPage = template.Must(template.New("link-error").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Payment link unavailable</title></head>
+<body>
+{{- if eq .Reason "expired"}}
+<p>This payment link has expired. Please ask for a new one.</p>
+{{- else if eq .Reason "used"}}
+<p>This payment link has already been used. Please ask for a new one.</p>
+{{- else}}
+<p>This payment link is not valid.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// linkContinuePage posts back to the link so that only a browser, and not
+// a messaging app fetching a preview, uses it
+var linkContinuePage = template.Must(template.New("link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Continue to payment</title></head>
+<body onload="document.forms[0].submit()">
+<form method="POST" action="{{.}}">
+<noscript><button type="submit">Continue to payment</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+type paymentLinkHandler struct {
+	client *Client
+	opts   PaymentLinkHandlerOptions
+}
+
+// NewPaymentLinkHandler returns an http.Handler serving hosted payment
+// links under LinkOptions.BaseURL; the token is the last path segment. A
+// GET answers a page that posts back to the link, and the POST reserves
+// the link, opens the MASRVI session and renders the MASRVI auto-submit
+// form. A session that cannot be opened answers 502 and releases the link.
+// Expired, used and unknown links get the error page with status 410 or
+// 404, and OnRejected.
+func (c *Client) NewPaymentLinkHandler(opts PaymentLinkHandlerOptions) http.Handler {
+	if opts.ErrorPage == nil {
+		opts.ErrorPage = defaultLinkErrorPage
+	}
+	if opts.Logger == nil {
+		opts.Logger = c.logger
+	}
+	return &paymentLinkHandler{client: c, opts: opts}
+}
+
+func (h *paymentLinkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	token := path.Base(r.URL.Path)
+	now := h.client.scheduler.now()
+	store := h.client.linkStore()
+
+	link, err := store.GetLink(ctx, token)
+	if err == nil && link.Expired(now) {
+		err = ErrLinkExpired
+	}
+	if err == nil && link.SingleUse && link.Uses > 0 {
+		err = ErrLinkUsed
+	}
+	var payment *PaymentResponse
+	if err == nil && r.Method == http.MethodPost {
+		// Reserve the link before opening the session, so that concurrent
+		// requests cannot open two; a failed session gives the use back
+		var reserved *PaymentLink
+		if reserved, err = store.ReserveLink(ctx, token, now); err == nil {
+			link = reserved
+			if payment, err = h.client.linkPayment(ctx, link); err != nil {
+				h.opts.Logger.Error("Failed to open payment link session", "token", token, "error", err)
+				if err := store.ReleaseLink(ctx, token); err != nil {
+					h.opts.Logger.Error("Failed to release payment link", "token", token, "error", err)
+				}
+				http.Error(w, "payment page unavailable", http.StatusBadGateway)
+				return
+			}
+		}
+	}
+	if err != nil {
+		h.reject(w, r, token, link, err, now)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if r.Method != http.MethodPost {
+		if err := linkContinuePage.Execute(w, r.URL.Path); err != nil {
+			h.opts.Logger.Warn("Failed to render payment link page", "token", token, "error", err)
+		}
+		return
+	}
+	if err := payment.RenderAutoSubmitForm(w); err != nil {
+		h.opts.Logger.Error("Failed to render payment form", "token", token, "error", err)
+	}
+}
+
+// linkPayment opens the MASRVI session of a hosted link, or returns the
+// payment of a link created with its session
+func (c *Client) linkPayment(ctx context.Context, link *PaymentLink) (*PaymentResponse, error) {
+	if link.Request == nil {
+		if link.Payment == nil {
+			return nil, ErrInvalidRequest
+		}
+		return link.Payment, nil
+	}
+	return c.ProcessMasrviPayment(ctx, link.Request)
+}
+
+func (h *paymentLinkHandler) reject(w http.ResponseWriter, r *http.Request, token string, link *PaymentLink, err error, now time.Time) {
+	status := http.StatusGone
+	switch {
+	case errors.Is(err, ErrLinkNotFound):
+		status = http.StatusNotFound
+		link = nil
+	case !errors.Is(err, ErrLinkExpired) && !errors.Is(err, ErrLinkUsed):
+		h.opts.Logger.Error("Failed to load payment link", "token", token, "error", err)
+		http.Error(w, "payment link unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	rejection := &PaymentLinkRejection{Token: token, Link: link, Err: err, At: now}
+	if h.opts.OnRejected != nil {
+		h.opts.OnRejected(r.Context(), rejection)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := h.opts.ErrorPage.Execute(w, rejection); err != nil {
+		h.opts.Logger.Warn("Failed to render payment link error page", "token", token, "error", err)
+	}
+}
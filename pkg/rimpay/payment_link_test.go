@@ -0,0 +1,374 @@
+package rimpay
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+type linkMasrviProvider struct {
+	fakeMasrviProvider
+	mu    sync.Mutex
+	calls int
+	// sessionExpiry is the ExpiresAt of the responses, err fails them
+	sessionExpiry *time.Time
+	err           error
+}
+
+func (p *linkMasrviProvider) ProcessMasrviPayment(_ context.Context, request *MasrviPaymentRequest) (*PaymentResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	response := masrviRedirectResponse()
+	response.ExpiresAt = p.sessionExpiry
+	response.Reference = request.Reference
+	response.TransactionID = request.Reference
+	response.Status = PaymentStatusPending
+	return response, nil
+}
+
+type linkTest struct {
+	client   *Client
+	provider *linkMasrviProvider
+	clock    *fakeClock
+
+	mu       sync.Mutex
+	rejected []*PaymentLinkRejection
+}
+
+func newLinkTest(t *testing.T) *linkTest {
+	t.Helper()
+	lt := &linkTest{
+		client:   newReturnTestClient(t, PaymentStatusPending, ""),
+		provider: &linkMasrviProvider{},
+		clock:    &fakeClock{now: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	lt.client.providers[ProviderMasrvi] = lt.provider
+	lt.client.scheduler.now = lt.clock.Now
+	return lt
+}
+
+func (lt *linkTest) handler(opts PaymentLinkHandlerOptions) http.Handler {
+	opts.OnRejected = func(_ context.Context, rejection *PaymentLinkRejection) {
+		lt.mu.Lock()
+		defer lt.mu.Unlock()
+		lt.rejected = append(lt.rejected, rejection)
+	}
+	return lt.client.NewPaymentLinkHandler(opts)
+}
+
+func (lt *linkTest) rejections() []*PaymentLinkRejection {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	return append([]*PaymentLinkRejection(nil), lt.rejected...)
+}
+
+func linkRequest(t *testing.T) *MasrviPaymentRequest {
+	t.Helper()
+	number, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &MasrviPaymentRequest{
+		Amount:      money.FromFloat64(500, money.MRU),
+		PhoneNumber: number,
+		Reference:   "ORDER-7",
+		Description: "Order 7",
+	}
+}
+
+func serveLink(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func TestCreatePaymentLinkHosted(t *testing.T) {
+	lt := newLinkTest(t)
+
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		SingleUse: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, link.Hosted)
+	assert.Equal(t, "https://shop.example/pay/"+link.Token, link.URL)
+	assert.Equal(t, "ORDER-7", link.Reference)
+	assert.Equal(t, lt.clock.Now().Add(DefaultLinkExpiry), link.ExpiresAt)
+	assert.Zero(t, lt.provider.calls, "the session is opened when the link is used")
+	require.NotNil(t, link.Request)
+	assert.Nil(t, link.Payment)
+
+	stored, err := lt.client.GetPaymentLink(context.Background(), link.Token)
+	require.NoError(t, err)
+	assert.Equal(t, link.URL, stored.URL)
+
+	other, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{BaseURL: "https://shop.example/pay"})
+	require.NoError(t, err)
+	assert.NotEqual(t, link.Token, other.Token)
+}
+
+func TestCreatePaymentLinkProviderURL(t *testing.T) {
+	lt := newLinkTest(t)
+
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{})
+	require.NoError(t, err)
+	assert.False(t, link.Hosted)
+	assert.True(t, strings.HasPrefix(link.URL, "https://api.masrvi.mr/online/online.php?"))
+	assert.Contains(t, link.URL, "sessionid=SESSION-1")
+	assert.Equal(t, lt.clock.Now().Add(DefaultLinkExpiry), link.ExpiresAt)
+
+	// The raw MASRVI URL stops working with its session
+	sessionExpiry := lt.clock.Now().Add(5 * time.Minute)
+	lt.provider.sessionExpiry = &sessionExpiry
+	link, err = lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, sessionExpiry, link.ExpiresAt)
+}
+
+func TestCreatePaymentLinkOptions(t *testing.T) {
+	lt := newLinkTest(t)
+	ctx := context.Background()
+
+	_, err := lt.client.CreatePaymentLink(ctx, linkRequest(t), LinkOptions{SingleUse: true})
+	assert.True(t, IsValidation(err))
+	_, err = lt.client.CreatePaymentLink(ctx, linkRequest(t), LinkOptions{BaseURL: "/pay/"})
+	assert.True(t, IsValidation(err))
+	_, err = lt.client.CreatePaymentLink(ctx, linkRequest(t), LinkOptions{BaseURL: "https://shop.example/pay/", ExpiresIn: -time.Minute})
+	assert.True(t, IsValidation(err))
+	assert.Zero(t, lt.provider.calls)
+
+	request := linkRequest(t)
+	expiresAt := lt.clock.Now().Add(10 * time.Minute)
+	request.ExpiresAt = &expiresAt
+	link, err := lt.client.CreatePaymentLink(ctx, request, LinkOptions{BaseURL: "https://shop.example/pay/", ExpiresIn: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, link.ExpiresAt)
+}
+
+func TestPaymentLinkHandlerRendersForm(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{BaseURL: "https://shop.example/pay/"})
+	require.NoError(t, err)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	page := serveLink(handler, http.MethodGet, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusOK, page.Code)
+	assert.Equal(t, "no-store", page.Header().Get("Cache-Control"))
+	assert.Contains(t, page.Body.String(), `<form method="POST" action="/pay/`+link.Token+`">`)
+	assert.NotContains(t, page.Body.String(), "SESSION-1")
+
+	form := serveLink(handler, http.MethodPost, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusOK, form.Code)
+	assert.Contains(t, form.Body.String(), `action="https://api.masrvi.mr/online/online.php"`)
+	assert.Contains(t, form.Body.String(), `name="sessionid" value="SESSION-1"`)
+
+	// A multi-use link can be opened again
+	again := serveLink(handler, http.MethodPost, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusOK, again.Code)
+
+	stored, err := lt.client.GetPaymentLink(context.Background(), link.Token)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Uses)
+	assert.Equal(t, 2, lt.provider.calls, "each use opens a session")
+	require.NotNil(t, stored.UsedAt)
+	assert.Equal(t, lt.clock.Now(), *stored.UsedAt)
+	assert.Empty(t, lt.rejections())
+}
+
+func TestPaymentLinkHandlerRejectsReuse(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		SingleUse: true,
+	})
+	require.NoError(t, err)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	// Fetching the page, as a link preview does, does not use the link
+	assert.Equal(t, http.StatusOK, serveLink(handler, http.MethodGet, "/pay/"+link.Token).Code)
+	assert.Equal(t, http.StatusOK, serveLink(handler, http.MethodPost, "/pay/"+link.Token).Code)
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		reused := serveLink(handler, method, "/pay/"+link.Token)
+		assert.Equal(t, http.StatusGone, reused.Code, method)
+		assert.Contains(t, reused.Body.String(), "already been used")
+		assert.NotContains(t, reused.Body.String(), "SESSION-1")
+	}
+
+	rejections := lt.rejections()
+	require.Len(t, rejections, 2)
+	assert.ErrorIs(t, rejections[0].Err, ErrLinkUsed)
+	assert.Equal(t, "used", rejections[0].Reason())
+	assert.Equal(t, link.Token, rejections[0].Token)
+	require.NotNil(t, rejections[0].Link)
+	assert.Equal(t, "ORDER-7", rejections[0].Link.Reference)
+}
+
+func TestPaymentLinkHandlerRejectsExpired(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		ExpiresIn: 30 * time.Minute,
+	})
+	require.NoError(t, err)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	lt.clock.Advance(29 * time.Minute)
+	assert.Equal(t, http.StatusOK, serveLink(handler, http.MethodGet, "/pay/"+link.Token).Code)
+
+	lt.clock.Advance(time.Minute)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		expired := serveLink(handler, method, "/pay/"+link.Token)
+		assert.Equal(t, http.StatusGone, expired.Code, method)
+		assert.Contains(t, expired.Body.String(), "has expired")
+	}
+
+	rejections := lt.rejections()
+	require.Len(t, rejections, 2)
+	assert.ErrorIs(t, rejections[0].Err, ErrLinkExpired)
+	assert.Equal(t, lt.clock.Now(), rejections[0].At)
+
+	stored, err := lt.client.GetPaymentLink(context.Background(), link.Token)
+	require.NoError(t, err)
+	assert.Zero(t, stored.Uses)
+}
+
+func TestPaymentLinkOpensSessionWhenUsed(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		SingleUse: true,
+	})
+	require.NoError(t, err)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	// Long after any session opened at creation would have expired
+	lt.clock.Advance(50 * time.Minute)
+	lt.provider.err = NewPaymentError(ErrorCodeProviderError, "failed to get session ID", ProviderMasrvi, true)
+	failed := serveLink(handler, http.MethodPost, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusBadGateway, failed.Code)
+
+	lt.provider.err = nil
+	form := serveLink(handler, http.MethodPost, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusOK, form.Code, "a failed session does not use the link")
+	assert.Contains(t, form.Body.String(), `name="sessionid" value="SESSION-1"`)
+	assert.Equal(t, 2, lt.provider.calls)
+	assert.Empty(t, lt.rejections())
+}
+
+func TestMemoryLinkStoreReserveLinkChecksExpiry(t *testing.T) {
+	store := NewMemoryLinkStore()
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SaveLink(ctx, &PaymentLink{Token: "t", SingleUse: true, ExpiresAt: now.Add(time.Minute)}))
+
+	_, err := store.ReserveLink(ctx, "t", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrLinkExpired)
+	_, err = store.ReserveLink(ctx, "t", now)
+	require.NoError(t, err)
+	_, err = store.ReserveLink(ctx, "t", now)
+	assert.ErrorIs(t, err, ErrLinkUsed)
+	_, err = store.ReserveLink(ctx, "nope", now)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+
+	require.NoError(t, store.ReleaseLink(ctx, "t"))
+	link, err := store.ReserveLink(ctx, "t", now)
+	require.NoError(t, err, "a released link can be reserved again")
+	assert.Equal(t, 1, link.Uses)
+	assert.ErrorIs(t, store.ReleaseLink(ctx, "nope"), ErrLinkNotFound)
+}
+
+func TestPaymentLinkHandlerConcurrentSingleUse(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		SingleUse: true,
+	})
+	require.NoError(t, err)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serveLink(handler, http.MethodPost, "/pay/"+link.Token).Code
+		}(i)
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			ok++
+		} else {
+			assert.Equal(t, http.StatusGone, code)
+		}
+	}
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, 1, lt.provider.calls, "only one MASRVI session is opened")
+	assert.Len(t, lt.rejections(), 9)
+}
+
+func TestPaymentLinkHandlerUnknownToken(t *testing.T) {
+	lt := newLinkTest(t)
+	handler := lt.handler(PaymentLinkHandlerOptions{})
+
+	recorder := serveLink(handler, http.MethodGet, "/pay/nope")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "not valid")
+
+	rejections := lt.rejections()
+	require.Len(t, rejections, 1)
+	assert.Nil(t, rejections[0].Link)
+	assert.Equal(t, "not_found", rejections[0].Reason())
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serveLink(handler, http.MethodDelete, "/pay/nope").Code)
+}
+
+func TestPaymentLinkHandlerErrorPage(t *testing.T) {
+	lt := newLinkTest(t)
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{
+		BaseURL:   "https://shop.example/pay/",
+		ExpiresIn: time.Minute,
+	})
+	require.NoError(t, err)
+	page := template.Must(template.New("error").Parse(`<p>{{.Reason}}: {{.Link.Reference}} {{.Token}}</p>`))
+	handler := lt.handler(PaymentLinkHandlerOptions{ErrorPage: page})
+
+	lt.clock.Advance(time.Hour)
+	recorder := serveLink(handler, http.MethodGet, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusGone, recorder.Code)
+	assert.Equal(t, "<p>expired: ORDER-7 "+link.Token+"</p>", recorder.Body.String())
+}
+
+type failingLinkStore struct{ *MemoryLinkStore }
+
+func (failingLinkStore) ReserveLink(context.Context, string, time.Time) (*PaymentLink, error) {
+	return nil, ErrProviderUnavailable
+}
+
+func TestPaymentLinkStoreFailure(t *testing.T) {
+	lt := newLinkTest(t)
+	lt.client.WithLinkStore(failingLinkStore{NewMemoryLinkStore()})
+	link, err := lt.client.CreatePaymentLink(context.Background(), linkRequest(t), LinkOptions{BaseURL: "https://shop.example/pay/"})
+	require.NoError(t, err)
+
+	recorder := serveLink(lt.handler(PaymentLinkHandlerOptions{}), http.MethodPost, "/pay/"+link.Token)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Empty(t, lt.rejections())
+}
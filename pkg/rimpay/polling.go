@@ -0,0 +1,355 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PollStage polls at Interval until the transaction is Duration old, measured
+// from the start of polling. Stages run in order, so each Duration must be
+// greater than the one before it.
+type PollStage struct {
+	Duration time.Duration `json:"duration"`
+	Interval time.Duration `json:"interval"`
+}
+
+// PollSchedule is a staged polling plan that slows down as a payment ages
+type PollSchedule []PollStage
+
+// Validate checks that the schedule is non-empty, positive and ordered
+func (s PollSchedule) Validate() error {
+	if len(s) == 0 {
+		return fmt.Errorf("poll schedule must have at least one stage")
+	}
+
+	var previous time.Duration
+	for i, stage := range s {
+		if stage.Interval <= 0 {
+			return fmt.Errorf("poll stage %d: interval must be positive", i)
+		}
+		if stage.Duration <= previous {
+			return fmt.Errorf("poll stage %d: duration %s must be greater than %s", i, stage.Duration, previous)
+		}
+		previous = stage.Duration
+	}
+	return nil
+}
+
+// IntervalAt returns the polling interval for a transaction of the given age;
+// ok is false once the age is past the last stage
+func (s PollSchedule) IntervalAt(age time.Duration) (interval time.Duration, ok bool) {
+	for _, stage := range s {
+		if age < stage.Duration {
+			return stage.Interval, true
+		}
+	}
+	return 0, false
+}
+
+// DefaultPollSchedule returns the polling schedule suited to a provider.
+// B-PAY wallet payments settle within seconds, while MASRVI and CLICK web
+// payments and Sedad invoices wait on the customer and take minutes.
+func DefaultPollSchedule(provider string) PollSchedule {
+	switch provider {
+	case ProviderBPay:
+		return PollSchedule{
+			{Duration: time.Minute, Interval: 2 * time.Second},
+			{Duration: 15 * time.Minute, Interval: 30 * time.Second},
+			{Duration: 24 * time.Hour, Interval: 5 * time.Minute},
+		}
+	case ProviderMasrvi, ProviderClick, ProviderSedad:
+		return PollSchedule{
+			{Duration: 15 * time.Minute, Interval: 15 * time.Second},
+			{Duration: time.Hour, Interval: time.Minute},
+			{Duration: 24 * time.Hour, Interval: 5 * time.Minute},
+		}
+	default:
+		return PollSchedule{
+			{Duration: time.Minute, Interval: 5 * time.Second},
+			{Duration: time.Hour, Interval: 30 * time.Second},
+			{Duration: 24 * time.Hour, Interval: 5 * time.Minute},
+		}
+	}
+}
+
+// StatusPoller polls a provider for a transaction's status until it completes.
+// Create it with Client.NewStatusPoller; the zero value is not usable.
+type StatusPoller struct {
+	client   *Client
+	provider string
+	schedule PollSchedule
+	backoff  *pollBackoff
+	// maxDuration caps total polling time; zero leaves it to the schedule
+	maxDuration time.Duration
+	onProgress  func(PollProgress)
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// pollBackoff replaces the schedule with exponentially growing intervals
+type pollBackoff struct {
+	initial    time.Duration
+	multiplier float64
+	max        time.Duration
+}
+
+// interval returns the wait after the given poll, counting from 1
+func (b *pollBackoff) interval(attempt int) time.Duration {
+	d := float64(b.initial)
+	for i := 1; i < attempt && d < float64(b.max); i++ {
+		d *= b.multiplier
+	}
+	if b.max > 0 && d > float64(b.max) {
+		return b.max
+	}
+	return time.Duration(d)
+}
+
+// PollProgress describes a poll that did not find the transaction completed
+type PollProgress struct {
+	TransactionID string
+	Attempt       int
+	Elapsed       time.Duration
+	// Status is the last known status, nil until a poll succeeds
+	Status *TransactionStatus
+	// Err is the retryable error of this poll, if it failed
+	Err error
+	// NextPoll is the wait before the next poll
+	NextPoll time.Duration
+}
+
+// PollError is returned when polling stops before the transaction completes,
+// either because the schedule or maximum duration ran out or because the
+// context ended. It unwraps to ErrPollingExhausted or the context error.
+type PollError struct {
+	TransactionID string
+	Attempts      int
+	Elapsed       time.Duration
+	// LastStatus is the last known status, nil if no poll succeeded
+	LastStatus *TransactionStatus
+	Err        error
+}
+
+func (e *PollError) Error() string {
+	status := "unknown"
+	if e.LastStatus != nil {
+		status = string(e.LastStatus.Status)
+	}
+	return fmt.Sprintf("transaction %s still %s after %d polls in %s: %v",
+		e.TransactionID, status, e.Attempts, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+func (e *PollError) Unwrap() error {
+	return e.Err
+}
+
+// PollOption configures Client.WaitForCompletion
+type PollOption func(*StatusPoller)
+
+// WithPollProvider polls provider instead of the default provider
+func WithPollProvider(provider string) PollOption {
+	return func(p *StatusPoller) {
+		p.provider = provider
+	}
+}
+
+// WithPollSchedule polls on schedule instead of DefaultPollSchedule
+func WithPollSchedule(schedule PollSchedule) PollOption {
+	return func(p *StatusPoller) {
+		p.schedule = schedule
+	}
+}
+
+// WithPollBackoff polls every initial, multiplying the interval by
+// multiplier after each poll up to max; it takes precedence over any schedule
+func WithPollBackoff(initial time.Duration, multiplier float64, max time.Duration) PollOption {
+	return func(p *StatusPoller) {
+		p.backoff = &pollBackoff{initial: initial, multiplier: multiplier, max: max}
+	}
+}
+
+// WithPollMaxDuration stops polling with ErrPollingExhausted after d
+func WithPollMaxDuration(d time.Duration) PollOption {
+	return func(p *StatusPoller) {
+		p.maxDuration = d
+	}
+}
+
+// WithPollProgress calls fn after every poll that leaves the transaction
+// pending, so callers can report progress
+func WithPollProgress(fn func(PollProgress)) PollOption {
+	return func(p *StatusPoller) {
+		p.onProgress = fn
+	}
+}
+
+// NewStatusPoller creates a poller for provider; a nil schedule uses
+// DefaultPollSchedule(provider)
+func (c *Client) NewStatusPoller(provider string, schedule PollSchedule) (*StatusPoller, error) {
+	poller := &StatusPoller{
+		client:   c,
+		provider: provider,
+		schedule: schedule,
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+	if err := poller.validate(); err != nil {
+		return nil, err
+	}
+	return poller, nil
+}
+
+// Poll checks the transaction status on the schedule until it reaches a final
+// state. Retryable errors are logged and polling continues; once the schedule
+// or maximum duration is exhausted, or the context ends, a *PollError carrying
+// the last status is returned along with that status.
+func (p *StatusPoller) Poll(ctx context.Context, transactionID string) (*TransactionStatus, error) {
+	if transactionID == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	p.client.mu.RLock()
+	provider, ok := p.client.providers[p.provider]
+	p.client.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf(providerNotAvailableMsg, p.provider)
+	}
+
+	start := p.now()
+	var last *TransactionStatus
+	for attempt := 1; ; attempt++ {
+		var status *TransactionStatus
+		err := p.client.invoke(ctx, p.provider, func() (err error) {
+			status, err = provider.GetPaymentStatus(ctx, transactionID)
+			return err
+		})
+
+		switch {
+		case err == nil:
+			p.client.recordStatus(ctx, status)
+			last = status
+			if status.IsCompleted() {
+				return status, nil
+			}
+		case ctx.Err() != nil:
+			return last, p.pollError(transactionID, attempt, start, last, ctx.Err())
+		case IsRetryable(err):
+			p.client.logger.Warn("Status poll failed, will retry",
+				"provider", p.provider, "transaction_id", transactionID, "error", err)
+		default:
+			return last, err
+		}
+
+		elapsed := p.now().Sub(start)
+		interval, ok := p.nextInterval(attempt, elapsed)
+		if !ok {
+			return last, p.pollError(transactionID, attempt, start, last, ErrPollingExhausted)
+		}
+
+		if p.onProgress != nil {
+			p.onProgress(PollProgress{
+				TransactionID: transactionID,
+				Attempt:       attempt,
+				Elapsed:       elapsed,
+				Status:        last,
+				Err:           err,
+				NextPoll:      interval,
+			})
+		}
+
+		if err := p.sleep(ctx, interval); err != nil {
+			return last, p.pollError(transactionID, attempt, start, last, err)
+		}
+	}
+}
+
+// nextInterval returns the wait after the given poll, shortened so that the
+// last poll happens at the maximum duration
+func (p *StatusPoller) nextInterval(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if p.maxDuration > 0 && elapsed >= p.maxDuration {
+		return 0, false
+	}
+
+	var interval time.Duration
+	if p.backoff != nil {
+		interval = p.backoff.interval(attempt)
+	} else {
+		var ok bool
+		if interval, ok = p.schedule.IntervalAt(elapsed); !ok {
+			return 0, false
+		}
+	}
+
+	if p.maxDuration > 0 && elapsed+interval > p.maxDuration {
+		interval = p.maxDuration - elapsed
+	}
+	return interval, true
+}
+
+func (p *StatusPoller) pollError(transactionID string, attempts int, start time.Time, last *TransactionStatus, err error) error {
+	return &PollError{
+		TransactionID: transactionID,
+		Attempts:      attempts,
+		Elapsed:       p.now().Sub(start),
+		LastStatus:    last,
+		Err:           err,
+	}
+}
+
+// WaitForCompletion polls the default provider until the transaction reaches
+// a final state, on DefaultPollSchedule unless options say otherwise
+func (c *Client) WaitForCompletion(ctx context.Context, transactionID string, opts ...PollOption) (*TransactionStatus, error) {
+	poller := &StatusPoller{client: c, now: time.Now, sleep: sleepContext}
+	if provider := c.defaultProvider(); provider != nil {
+		poller.provider = provider.Name()
+	}
+	for _, opt := range opts {
+		opt(poller)
+	}
+
+	if err := poller.validate(); err != nil {
+		return nil, err
+	}
+	return poller.Poll(ctx, transactionID)
+}
+
+// validate fills in the default schedule and checks the options
+func (p *StatusPoller) validate() error {
+	if p.backoff != nil {
+		if p.backoff.initial <= 0 {
+			return fmt.Errorf("invalid poll backoff: initial interval must be positive")
+		}
+		if p.backoff.multiplier < 1 {
+			return fmt.Errorf("invalid poll backoff: multiplier must be at least 1")
+		}
+		if p.backoff.max < 0 {
+			return fmt.Errorf("invalid poll backoff: max interval must not be negative")
+		}
+		if p.maxDuration <= 0 {
+			return fmt.Errorf("invalid poll backoff: a max duration is required")
+		}
+	} else {
+		if p.schedule == nil {
+			p.schedule = DefaultPollSchedule(p.provider)
+		}
+		if err := p.schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid poll schedule: %w", err)
+		}
+	}
+	if p.maxDuration < 0 {
+		return fmt.Errorf("poll max duration must not be negative")
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
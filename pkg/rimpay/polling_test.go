@@ -0,0 +1,238 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pollingProvider reports pending until completeAfter status checks
+type pollingProvider struct {
+	checks        int
+	completeAfter int
+	errs          []error
+}
+
+func (p *pollingProvider) Name() string                     { return "bpay" }
+func (p *pollingProvider) IsAvailable(context.Context) bool { return true }
+func (p *pollingProvider) ValidateConfig() error            { return nil }
+func (p *pollingProvider) Capabilities() Capabilities       { return Capabilities{} }
+
+func (p *pollingProvider) ProcessPayment(context.Context, *PaymentRequest) (*PaymentResponse, error) {
+	return nil, ErrPaymentFailed
+}
+
+func (p *pollingProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	p.checks++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+	status := PaymentStatusPending
+	if p.completeAfter > 0 && p.checks >= p.completeAfter {
+		status = PaymentStatusSuccess
+	}
+	return &TransactionStatus{TransactionID: transactionID, Status: status}, nil
+}
+
+func (p *pollingProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func newTestPoller(t *testing.T, provider *pollingProvider, schedule PollSchedule) (*StatusPoller, *[]time.Duration) {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+
+	poller, err := client.NewStatusPoller(ProviderBPay, schedule)
+	require.NoError(t, err)
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	var intervals []time.Duration
+	poller.now = clock.Now
+	poller.sleep = func(_ context.Context, d time.Duration) error {
+		intervals = append(intervals, d)
+		clock.Advance(d)
+		return nil
+	}
+	return poller, &intervals
+}
+
+func TestPollerIntervalTransitionsAtStageBoundaries(t *testing.T) {
+	schedule := PollSchedule{
+		{Duration: time.Minute, Interval: 5 * time.Second},
+		{Duration: 10 * time.Minute, Interval: 30 * time.Second},
+		{Duration: time.Hour, Interval: 5 * time.Minute},
+	}
+	provider := &pollingProvider{}
+	poller, intervals := newTestPoller(t, provider, schedule)
+
+	status, err := poller.Poll(context.Background(), "TX-1")
+	assert.ErrorIs(t, err, ErrPollingExhausted)
+	require.NotNil(t, status)
+	assert.Equal(t, PaymentStatusPending, status.Status)
+
+	got := *intervals
+	// 12 polls at 5s cover the first minute, 18 at 30s reach 10 minutes,
+	// then 10 at 5m reach the hour
+	require.Len(t, got, 12+18+10)
+	assert.Equal(t, 5*time.Second, got[11])
+	assert.Equal(t, 30*time.Second, got[12])
+	assert.Equal(t, 30*time.Second, got[29])
+	assert.Equal(t, 5*time.Minute, got[30])
+	assert.Equal(t, len(got)+1, provider.checks)
+}
+
+func TestPollerStopsWhenTransactionCompletes(t *testing.T) {
+	provider := &pollingProvider{completeAfter: 3}
+	poller, intervals := newTestPoller(t, provider, nil)
+
+	status, err := poller.Poll(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+	assert.Len(t, *intervals, 2)
+}
+
+func TestPollerRetriesRetryableErrors(t *testing.T) {
+	provider := &pollingProvider{
+		completeAfter: 3,
+		errs:          []error{NewPaymentError(ErrorCodeNetworkError, "status check failed", "bpay", true)},
+	}
+	poller, _ := newTestPoller(t, provider, nil)
+
+	status, err := poller.Poll(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+
+	provider = &pollingProvider{errs: []error{NewPaymentError(ErrorCodeAuthenticationFailed, "denied", "bpay", false)}}
+	poller, _ = newTestPoller(t, provider, nil)
+	_, err = poller.Poll(context.Background(), "TX-1")
+	assert.Error(t, err)
+	assert.Equal(t, 1, provider.checks)
+}
+
+func TestPollScheduleValidate(t *testing.T) {
+	assert.Error(t, PollSchedule{}.Validate())
+	assert.Error(t, PollSchedule{{Duration: time.Minute, Interval: 0}}.Validate())
+	assert.Error(t, PollSchedule{
+		{Duration: 10 * time.Minute, Interval: 30 * time.Second},
+		{Duration: time.Minute, Interval: 5 * time.Second},
+	}.Validate(), "stages must be ordered")
+
+	for _, provider := range []string{ProviderBPay, ProviderMasrvi, ProviderClick, "other"} {
+		assert.NoError(t, DefaultPollSchedule(provider).Validate(), provider)
+	}
+}
+
+// withTestClock replaces real time with a fake clock that sleeps instantly
+func withTestClock(intervals *[]time.Duration) PollOption {
+	return func(p *StatusPoller) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		p.now = clock.Now
+		p.sleep = func(_ context.Context, d time.Duration) error {
+			*intervals = append(*intervals, d)
+			clock.Advance(d)
+			return nil
+		}
+	}
+}
+
+func newWaitTestClient(t *testing.T, provider *pollingProvider) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderBPay
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+	return client
+}
+
+func TestWaitForCompletionBackoff(t *testing.T) {
+	provider := &pollingProvider{completeAfter: 5}
+	client := newWaitTestClient(t, provider)
+
+	var intervals []time.Duration
+	var progress []PollProgress
+	status, err := client.WaitForCompletion(context.Background(), "TX-1",
+		WithPollBackoff(time.Second, 2, 5*time.Second),
+		WithPollMaxDuration(time.Minute),
+		WithPollProgress(func(p PollProgress) { progress = append(progress, p) }),
+		withTestClock(&intervals),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+	assert.Equal(t, 5, provider.checks)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, intervals)
+
+	require.Len(t, progress, 4)
+	assert.Equal(t, 1, progress[0].Attempt)
+	assert.Equal(t, PaymentStatusPending, progress[0].Status.Status)
+	assert.Equal(t, 7*time.Second, progress[3].Elapsed)
+	assert.Equal(t, 5*time.Second, progress[3].NextPoll)
+}
+
+func TestWaitForCompletionMaxDuration(t *testing.T) {
+	provider := &pollingProvider{}
+	client := newWaitTestClient(t, provider)
+
+	var intervals []time.Duration
+	status, err := client.WaitForCompletion(context.Background(), "TX-1",
+		WithPollBackoff(4*time.Second, 1, 0),
+		WithPollMaxDuration(10*time.Second),
+		withTestClock(&intervals),
+	)
+	assert.ErrorIs(t, err, ErrPollingExhausted)
+	require.NotNil(t, status)
+
+	var pollErr *PollError
+	require.ErrorAs(t, err, &pollErr)
+	assert.Equal(t, PaymentStatusPending, pollErr.LastStatus.Status)
+	assert.Equal(t, 4, pollErr.Attempts)
+	assert.Equal(t, 10*time.Second, pollErr.Elapsed)
+	assert.Equal(t, []time.Duration{4 * time.Second, 4 * time.Second, 2 * time.Second}, intervals,
+		"the last wait is shortened to end at the max duration")
+	assert.Contains(t, err.Error(), "transaction TX-1 still pending after 4 polls")
+}
+
+func TestWaitForCompletionContextCancelled(t *testing.T) {
+	provider := &pollingProvider{}
+	client := newWaitTestClient(t, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	status, err := client.WaitForCompletion(ctx, "TX-1",
+		WithPollProgress(func(PollProgress) { cancel() }),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, provider.checks)
+
+	var pollErr *PollError
+	require.ErrorAs(t, err, &pollErr)
+	assert.Same(t, status, pollErr.LastStatus)
+}
+
+func TestWaitForCompletionInvalidOptions(t *testing.T) {
+	client := newWaitTestClient(t, &pollingProvider{})
+
+	for name, opts := range map[string][]PollOption{
+		"backoff without max duration": {WithPollBackoff(time.Second, 2, 0)},
+		"shrinking backoff":            {WithPollBackoff(time.Second, 0.5, 0), WithPollMaxDuration(time.Minute)},
+		"empty schedule":               {WithPollSchedule(PollSchedule{})},
+		"negative max duration":        {WithPollMaxDuration(-time.Second)},
+	} {
+		_, err := client.WaitForCompletion(context.Background(), "TX-1", opts...)
+		assert.Error(t, err, name)
+	}
+
+	_, err := client.WaitForCompletion(context.Background(), "TX-1", WithPollProvider(ProviderMasrvi))
+	assert.EqualError(t, err, "provider masrvi not available")
+}
@@ -0,0 +1,37 @@
+package rimpay
+
+import (
+	"context"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// defaultPortabilityTTL is how long operator lookups are cached when
+// PortabilityConfig.CacheTTL is unset
+const defaultPortabilityTTL = time.Hour
+
+// newOperatorResolver wraps the configured resolver in a cache; it returns nil
+// when no resolver is configured
+func newOperatorResolver(config PortabilityConfig) phone.PortabilityResolver {
+	if config.Resolver == nil {
+		return nil
+	}
+	ttl := config.CacheTTL
+	if ttl == 0 {
+		ttl = defaultPortabilityTTL
+	}
+	return phone.NewCachedResolver(config.Resolver, ttl)
+}
+
+// ResolveOperator returns the operator serving p. Operator-dependent logic
+// should call it instead of p.Operator() so that ported numbers are honoured.
+// If the portability lookup fails the prefix-derived operator is used.
+func (c *Client) ResolveOperator(ctx context.Context, p *phone.Phone) phone.Operator {
+	op, err := phone.ResolveOperator(ctx, c.operators, p)
+	if err != nil {
+		c.logger.Warn("Operator lookup failed, using prefix detection",
+			"operator", op, "error", err)
+	}
+	return op
+}
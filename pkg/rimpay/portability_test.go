@@ -0,0 +1,59 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type portedResolver struct {
+	operator phone.Operator
+	err      error
+}
+
+func (r portedResolver) Lookup(context.Context, *phone.Phone) (phone.Operator, bool, error) {
+	return r.operator, r.err == nil, r.err
+}
+
+func newPortabilityTestClient(t *testing.T, resolver phone.PortabilityResolver) (*Client, *recordingLogger) {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Portability.Resolver = resolver
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	logger := &recordingLogger{}
+	client.logger = logger
+	return client, logger
+}
+
+func TestResolveOperatorHonoursPortedNumbers(t *testing.T) {
+	client, _ := newPortabilityTestClient(t, portedResolver{operator: phone.OperatorMattel})
+	p, err := phone.NewPhone("22334455")
+	require.NoError(t, err)
+
+	assert.Equal(t, phone.OperatorMattel, client.ResolveOperator(context.Background(), p))
+}
+
+func TestResolveOperatorFallsBackToPrefix(t *testing.T) {
+	client, logger := newPortabilityTestClient(t, portedResolver{err: errors.New("registry down")})
+	p, err := phone.NewPhone("22334455")
+	require.NoError(t, err)
+
+	assert.Equal(t, phone.OperatorMauritel, client.ResolveOperator(context.Background(), p))
+	assert.Equal(t, 1, logger.count("Operator lookup failed, using prefix detection"))
+}
+
+func TestResolveOperatorWithoutResolver(t *testing.T) {
+	client, _ := newPortabilityTestClient(t, nil)
+	p, err := phone.NewPhone("44556677")
+	require.NoError(t, err)
+
+	assert.Equal(t, phone.OperatorMattel, client.ResolveOperator(context.Background(), p))
+}
@@ -0,0 +1,196 @@
+package rimpay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+)
+
+// Priority tags outbound provider calls so background work such as
+// reconciliation status checks cannot starve live customer payments
+type Priority int
+
+const (
+	// PriorityHigh is used for customer-facing calls and untagged contexts
+	PriorityHigh Priority = iota
+	// PriorityLow is used for background work and is shed first under load
+	PriorityLow
+)
+
+// String returns string representation
+func (p Priority) String() string {
+	if p == PriorityLow {
+		return "low"
+	}
+	return "high"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context that tags provider calls with the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority carried by ctx, defaulting to PriorityHigh
+func PriorityFromContext(ctx context.Context) Priority {
+	if ctx == nil {
+		return PriorityHigh
+	}
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityHigh
+}
+
+// priorityLimiters keeps one priorityLimiter per provider, so that a slow or
+// busy provider cannot use up the capacity of the others
+type priorityLimiters struct {
+	config PriorityConfig
+
+	mu       sync.Mutex
+	limiters map[string]*priorityLimiter
+}
+
+// newPriorityLimiters creates the limiters from config
+func newPriorityLimiters(config PriorityConfig) *priorityLimiters {
+	return &priorityLimiters{config: config, limiters: make(map[string]*priorityLimiter)}
+}
+
+// acquire reserves capacity for a call to provider and returns the function
+// releasing it
+func (l *priorityLimiters) acquire(ctx context.Context, provider string) (func(), error) {
+	return l.limiter(provider).acquire(ctx)
+}
+
+// acquirePayment reserves one of the provider's payment slots under config
+// and returns the function releasing it
+func (l *priorityLimiters) acquirePayment(ctx context.Context, provider string, config ConcurrencyConfig) (func(), error) {
+	return l.limiter(provider).acquirePayment(ctx, config)
+}
+
+// snapshot returns the provider's payment counts, zero before its first call
+func (l *priorityLimiters) snapshot(provider string) ProviderStats {
+	l.mu.Lock()
+	limiter, ok := l.limiters[provider]
+	l.mu.Unlock()
+
+	if ok {
+		return limiter.snapshot()
+	}
+	return ProviderStats{}
+}
+
+// limiter returns the provider's limiter, creating it on first use
+func (l *priorityLimiters) limiter(provider string) *priorityLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[provider]
+	if !ok {
+		limiter = newPriorityLimiter(l.config, provider)
+		l.limiters[provider] = limiter
+	}
+	return limiter
+}
+
+// priorityLimiter gates the calls to one provider by priority. High priority
+// calls wait for capacity; low priority calls fail fast with PROVIDER_BUSY
+// instead. Payments also hold one of the provider's ConcurrencyConfig slots.
+type priorityLimiter struct {
+	// inFlight and queued count payments; they are first for 64-bit atomic
+	// alignment
+	inFlight int64
+	queued   int64
+
+	provider string
+	slots    chan struct{} // shared by both lanes
+	lowSlots chan struct{} // the unreserved part low priority may use
+	high     *common.TokenBucket
+	low      *common.TokenBucket
+
+	// mu guards the payment cap, which follows the provider's configuration
+	mu          sync.Mutex
+	concurrency ConcurrencyConfig
+	payments    chan struct{}
+}
+
+// newPriorityLimiter creates the limiter of provider from config
+func newPriorityLimiter(config PriorityConfig, provider string) *priorityLimiter {
+	l := &priorityLimiter{
+		provider: provider,
+		high:     common.NewTokenBucket(config.HighRate, config.Burst),
+		low:      common.NewTokenBucket(config.LowRate, config.Burst),
+	}
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+		l.lowSlots = make(chan struct{}, config.MaxConcurrent-config.ReservedHigh)
+	}
+	return l
+}
+
+// acquire reserves capacity for a call and returns the function releasing it
+func (l *priorityLimiter) acquire(ctx context.Context) (func(), error) {
+	if PriorityFromContext(ctx) == PriorityLow {
+		return l.acquireLow()
+	}
+	return l.acquireHigh(ctx)
+}
+
+func (l *priorityLimiter) acquireHigh(ctx context.Context) (func(), error) {
+	if wait := l.high.Reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			// Give back the token reserved in advance
+			l.high.Unreserve()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *priorityLimiter) acquireLow() (func(), error) {
+	if !l.low.TryTake() {
+		return nil, errProviderBusy(l.provider)
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.lowSlots <- struct{}{}:
+	default:
+		return nil, errProviderBusy(l.provider)
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() {
+			<-l.slots
+			<-l.lowSlots
+		}, nil
+	default:
+		<-l.lowSlots
+		return nil, errProviderBusy(l.provider)
+	}
+}
+
+func errProviderBusy(provider string) error {
+	return NewPaymentError(ErrorCodeProviderBusy, "provider busy, low priority request shed", provider, true)
+}
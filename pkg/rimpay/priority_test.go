@@ -0,0 +1,161 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingProvider answers payments immediately but holds status checks
+// until release is closed, simulating a slow provider under reconciliation load.
+type blockingProvider struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func (p *blockingProvider) Name() string                     { return "blocking" }
+func (p *blockingProvider) IsAvailable(context.Context) bool { return true }
+func (p *blockingProvider) ValidateConfig() error            { return nil }
+func (p *blockingProvider) Capabilities() Capabilities       { return Capabilities{} }
+
+func (p *blockingProvider) ProcessPayment(_ context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	return &PaymentResponse{Reference: request.Reference, Status: PaymentStatusPending}, nil
+}
+
+func (p *blockingProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return &TransactionStatus{TransactionID: transactionID, Status: PaymentStatusSuccess}, nil
+}
+
+func (p *blockingProvider) Refund(context.Context, *RefundRequest) (*RefundResponse, error) {
+	return nil, ErrRefundNotSupported
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	assert.Equal(t, PriorityHigh, PriorityFromContext(context.Background()))
+	assert.Equal(t, PriorityLow, PriorityFromContext(WithPriority(context.Background(), PriorityLow)))
+}
+
+func TestPriorityLimiterDisabledByDefault(t *testing.T) {
+	l := newPriorityLimiters(DefaultConfig().Priority)
+	for i := 0; i < 10; i++ {
+		release, err := l.acquire(WithPriority(context.Background(), PriorityLow), "bpay")
+		require.NoError(t, err)
+		defer release()
+	}
+}
+
+func TestHighPriorityProtectedUnderLowPriorityLoad(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers["bpay"] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Priority = PriorityConfig{MaxConcurrent: 3, ReservedHigh: 1}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	provider := &blockingProvider{release: make(chan struct{}), started: make(chan struct{}, 10)}
+	require.NoError(t, client.AddProvider("blocking", provider))
+
+	lowCtx := WithPriority(context.Background(), PriorityLow)
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := client.GetPaymentStatus(lowCtx, "TX")
+			results <- err
+		}()
+	}
+
+	// Only the two unreserved slots may be taken by low priority work; the
+	// remaining eight calls must be shed immediately.
+	for i := 0; i < 8; i++ {
+		var paymentErr *PaymentError
+		require.True(t, errors.As(<-results, &paymentErr))
+		assert.Equal(t, ErrorCodeProviderBusy, paymentErr.Code)
+	}
+	<-provider.started
+	<-provider.started
+
+	start := time.Now()
+	phoneNumber := newValidBPayRequest().PhoneNumber
+	resp, err := client.ProcessPayment(context.Background(), &PaymentRequest{
+		PhoneNumber: phoneNumber,
+		Reference:   "LIVE-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "LIVE-1", resp.Reference)
+	assert.Less(t, time.Since(start), 100*time.Millisecond, "high priority call must not queue behind low priority work")
+
+	close(provider.release)
+	assert.NoError(t, <-results)
+	assert.NoError(t, <-results)
+}
+
+func TestLowPriorityRateLimitedSeparately(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newPriorityLimiter(PriorityConfig{LowRate: 1}, "bpay")
+	l.low.SetClock(func() time.Time { return now })
+
+	lowCtx := WithPriority(context.Background(), PriorityLow)
+	release, err := l.acquire(lowCtx)
+	require.NoError(t, err)
+	release()
+
+	_, err = l.acquire(lowCtx)
+	assert.Error(t, err, "second low priority call within a second must be shed")
+
+	// High priority has its own, unlimited bucket.
+	release, err = l.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	now = now.Add(time.Second)
+	release, err = l.acquire(lowCtx)
+	require.NoError(t, err)
+	release()
+}
+
+func TestPriorityLimitersArePerProvider(t *testing.T) {
+	l := newPriorityLimiters(PriorityConfig{MaxConcurrent: 1})
+
+	release, err := l.acquire(context.Background(), "bpay")
+	require.NoError(t, err)
+	defer release()
+
+	// MASRVI has its own slot
+	other, err := l.acquire(context.Background(), "masrvi")
+	require.NoError(t, err)
+	other()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, "bpay")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = l.acquire(WithPriority(context.Background(), PriorityLow), "bpay")
+	var paymentErr *PaymentError
+	require.True(t, errors.As(err, &paymentErr))
+	assert.Equal(t, "bpay", paymentErr.Provider)
+}
+
+func TestHighPriorityWaitRefundsTokenOnCancel(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newPriorityLimiter(PriorityConfig{HighRate: 1}, "bpay")
+	l.high.SetClock(func() time.Time { return now })
+
+	release, err := l.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The cancelled call left no debt: a second later a token is free
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), l.high.Reserve())
+}
@@ -8,12 +8,16 @@ func (c *Client) AddBPayProvider(config ProviderConfig) error {
 		return fmt.Errorf("B-PAY provider not registered")
 	}
 
+	if err := c.currentConfig().checkProviderTLS(config); err != nil {
+		return err
+	}
+
 	// Create provider using the registered factory
-	provider, err := createBPayProvider(config, c.logger)
+	provider, err := createBPayProvider(c.withSharedHTTP(config), c.logger)
 	if err != nil {
 		return err
 	}
-	return c.AddProvider(ProviderBPay, provider)
+	return c.addProvider(ProviderBPay, provider, &config)
 }
 
 // AddMasrviProvider adds a MASRVI provider to the client
@@ -22,12 +26,16 @@ func (c *Client) AddMasrviProvider(config ProviderConfig) error {
 		return fmt.Errorf("MASRVI provider not registered")
 	}
 
+	if err := c.currentConfig().checkProviderTLS(config); err != nil {
+		return err
+	}
+
 	// Create provider using the registered factory
-	provider, err := createMasrviProvider(config, c.logger)
+	provider, err := createMasrviProvider(c.withSharedHTTP(config), c.logger)
 	if err != nil {
 		return err
 	}
-	return c.AddProvider(ProviderMasrvi, provider)
+	return c.addProvider(ProviderMasrvi, provider, &config)
 }
 
 // AddClickProvider adds a CLICK provider to the client
@@ -36,16 +44,55 @@ func (c *Client) AddClickProvider(config ProviderConfig) error {
 		return fmt.Errorf("CLICK provider not registered")
 	}
 
-	provider, err := createClickProvider(config, c.logger)
+	if err := c.currentConfig().checkProviderTLS(config); err != nil {
+		return err
+	}
+
+	provider, err := createClickProvider(c.withSharedHTTP(config), c.logger)
+	if err != nil {
+		return err
+	}
+	return c.addProvider(ProviderClick, provider, &config)
+}
+
+// AddSedadProvider adds a Sedad provider to the client
+func (c *Client) AddSedadProvider(config ProviderConfig) error {
+	if createSedadProvider == nil {
+		return fmt.Errorf("Sedad provider not registered")
+	}
+
+	if err := c.currentConfig().checkProviderTLS(config); err != nil {
+		return err
+	}
+
+	provider, err := createSedadProvider(c.withSharedHTTP(config), c.logger)
 	if err != nil {
 		return err
 	}
-	return c.AddProvider(ProviderClick, provider)
+	return c.addProvider(ProviderSedad, provider, &config)
+}
+
+// AddMockProvider adds the mock provider, which needs no credentials or
+// network access, to the client
+func (c *Client) AddMockProvider(config ProviderConfig) error {
+	if createMockProvider == nil {
+		return fmt.Errorf("mock provider not registered")
+	}
+
+	if err := c.currentConfig().checkProviderTLS(config); err != nil {
+		return err
+	}
+
+	provider, err := createMockProvider(c.withSharedHTTP(config), c.logger)
+	if err != nil {
+		return err
+	}
+	return c.addProvider(ProviderMock, provider, &config)
 }
 
 // GetClickProvider returns the CLICK provider if available
 func (c *Client) GetClickProvider() (ClickProvider, error) {
-	provider, ok := c.providers[ProviderClick]
+	provider, ok := c.registered(ProviderClick)
 	if !ok {
 		return nil, ErrProviderNotFound
 	}
@@ -60,7 +107,7 @@ func (c *Client) GetClickProvider() (ClickProvider, error) {
 
 // GetBPayProvider returns the B-PAY provider if available
 func (c *Client) GetBPayProvider() (BPayProvider, error) {
-	provider, ok := c.providers[ProviderBPay]
+	provider, ok := c.registered(ProviderBPay)
 	if !ok {
 		return nil, ErrProviderNotFound
 	}
@@ -75,7 +122,7 @@ func (c *Client) GetBPayProvider() (BPayProvider, error) {
 
 // GetMasrviProvider returns the MASRVI provider if available
 func (c *Client) GetMasrviProvider() (MasrviProvider, error) {
-	provider, ok := c.providers[ProviderMasrvi]
+	provider, ok := c.registered(ProviderMasrvi)
 	if !ok {
 		return nil, ErrProviderNotFound
 	}
@@ -87,3 +134,18 @@ func (c *Client) GetMasrviProvider() (MasrviProvider, error) {
 
 	return masrviProvider, nil
 }
+
+// GetSedadProvider returns the Sedad provider if available
+func (c *Client) GetSedadProvider() (SedadProvider, error) {
+	provider, ok := c.registered(ProviderSedad)
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	sedadProvider, ok := provider.(SedadProvider)
+	if !ok {
+		return nil, ErrInvalidProvider
+	}
+
+	return sedadProvider, nil
+}
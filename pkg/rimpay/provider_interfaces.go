@@ -2,6 +2,9 @@ package rimpay
 
 import (
 	"context"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
 )
 
 // BPayProvider represents the B-PAY payment provider interface
@@ -22,6 +25,13 @@ type BPayProvider interface {
 	ValidateConfig() error
 }
 
+// BPayNotificationHandler is implemented by B-PAY providers that accept
+// status callbacks
+type BPayNotificationHandler interface {
+	// HandleNotification maps a B-PAY status callback to a transaction status
+	HandleNotification(notification *BPayNotificationData) (*TransactionStatus, error)
+}
+
 // ClickProvider represents the CLICK (TagPay/BNM) payment provider interface
 type ClickProvider interface {
 	// Name returns the provider name
@@ -54,7 +64,7 @@ type MasrviProvider interface {
 	// ProcessPayment processes a MASRVI payment
 	ProcessMasrviPayment(ctx context.Context, request *MasrviPaymentRequest) (*PaymentResponse, error)
 
-	// GetPaymentStatus gets payment status (note: MASRVI uses webhooks)
+	// GetPaymentStatus queries the payment status; webhooks remain the primary source
 	GetPaymentStatus(ctx context.Context, transactionID string) (*TransactionStatus, error)
 
 	// HandleNotification handles MASRVI webhook notifications
@@ -63,3 +73,49 @@ type MasrviProvider interface {
 	// ValidateConfig validates provider configuration
 	ValidateConfig() error
 }
+
+// SedadProvider represents the Sedad payment provider interface
+type SedadProvider interface {
+	// Name returns the provider name
+	Name() string
+
+	// IsAvailable checks if the provider is available
+	IsAvailable(ctx context.Context) bool
+
+	// ProcessSedadPayment processes a Sedad invoice payment
+	ProcessSedadPayment(ctx context.Context, request *SedadPaymentRequest) (*PaymentResponse, error)
+
+	// GetPaymentStatus queries the payment status; webhooks remain the primary source
+	GetPaymentStatus(ctx context.Context, transactionID string) (*TransactionStatus, error)
+
+	// HandleNotification handles Sedad webhook notifications
+	HandleNotification(notification *SedadNotificationData) (*TransactionStatus, error)
+
+	// ValidateConfig validates provider configuration
+	ValidateConfig() error
+}
+
+// HealthChecker is implemented by providers that can explain why they are
+// unavailable; Diagnose prefers it over IsAvailable
+type HealthChecker interface {
+	// CheckHealth returns nil when the provider is reachable
+	CheckHealth(ctx context.Context) error
+}
+
+// CurrencySupporter is implemented by providers that declare the currencies
+// they accept, replacing DefaultSupportedCurrencies
+type CurrencySupporter interface {
+	// SupportedCurrencies returns the accepted currencies; none means any
+	SupportedCurrencies() []money.Currency
+}
+
+// Disburser is implemented by providers that can send money from the
+// merchant account to a customer's wallet
+type Disburser interface {
+	// ProcessDisbursement sends the disbursement
+	ProcessDisbursement(ctx context.Context, request *DisbursementRequest) (*DisbursementResponse, error)
+	// GetDisbursementStatus checks a disbursement by its reference
+	GetDisbursementStatus(ctx context.Context, reference string) (*DisbursementResponse, error)
+	// DisbursementOperators returns the operators whose wallets can be credited
+	DisbursementOperators() []phone.Operator
+}
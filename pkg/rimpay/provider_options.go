@@ -0,0 +1,198 @@
+package rimpay
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option keys written by ProviderConfig.WithBPayOptions and
+// WithMasrviOptions. Configurations may still set them in Options directly.
+const (
+	// OptionDefaultLanguage is the language of B-PAY payments that set none
+	OptionDefaultLanguage = "default_language"
+	// OptionPasscodeLength is the number of digits of B-PAY passcodes
+	OptionPasscodeLength = "passcode_length"
+	// OptionBrandName is shown on the MASRVI hosted payment page
+	OptionBrandName = "brand_name"
+	// OptionNotificationSecret signs MASRVI notifications
+	OptionNotificationSecret = "notification_secret"
+	// OptionSessionTTL is how long a MASRVI session ID is reused
+	OptionSessionTTL = "session_ttl"
+	// OptionSessionRefreshMargin is how long before expiry a MASRVI session
+	// is replaced
+	OptionSessionRefreshMargin = "session_refresh_margin"
+	// OptionKeepSessionWarm refreshes MASRVI sessions in the background
+	OptionKeepSessionWarm = "keep_session_warm"
+	// OptionRequireMerchantOverride makes every MASRVI payment name its
+	// merchant in PaymentRequest.CredentialOverrides
+	OptionRequireMerchantOverride = "require_merchant_override"
+	// OptionDefaultURLs holds the MASRVI redirect URLs of payments that set
+	// none, keyed success_url, failure_url and cancel_url
+	OptionDefaultURLs = "default_urls"
+)
+
+// BPayOptions are the typed B-PAY provider options. Zero fields keep the
+// provider defaults.
+type BPayOptions struct {
+	// DefaultLanguage is used for payments that set no language
+	DefaultLanguage Language
+	// PasscodePolicy is the merchant contract's passcode format
+	PasscodePolicy BPayPasscodePolicy
+	// CallbackSecret is the shared secret B-PAY sends with its callbacks
+	CallbackSecret string
+}
+
+// BPayPasscodePolicy describes the passcodes of a B-PAY merchant contract
+type BPayPasscodePolicy struct {
+	// Length is 4 or 6 digits
+	Length int
+}
+
+// MasrviOptions are the typed MASRVI provider options. Zero fields keep the
+// provider defaults.
+type MasrviOptions struct {
+	// BrandName is shown on the hosted payment page
+	BrandName string
+	// NotificationSecret is the shared secret notifications are signed with
+	NotificationSecret string
+	// SessionTTL is how long a session ID is reused
+	SessionTTL time.Duration
+	// SessionRefreshMargin is how long before expiry a session is replaced
+	SessionRefreshMargin time.Duration
+	// KeepSessionWarm refreshes the session in the background, so payments
+	// never wait for one
+	KeepSessionWarm bool
+	// RequireMerchantOverride makes every payment name its merchant, as
+	// marketplaces without a merchant account of their own need
+	RequireMerchantOverride bool
+	// DefaultURLs are used for payments that set no redirect URLs
+	DefaultURLs MasrviURLs
+}
+
+// MasrviURLs are the pages MASRVI sends the customer back to
+type MasrviURLs struct {
+	SuccessURL string
+	FailureURL string
+	CancelURL  string
+}
+
+// masrviURLKeys are the OptionDefaultURLs keys
+var masrviURLKeys = []string{"success_url", "failure_url", "cancel_url"}
+
+// WithBPayOptions returns a copy of the config with o written into Options,
+// where the B-PAY provider reads it
+func (p ProviderConfig) WithBPayOptions(o BPayOptions) ProviderConfig {
+	p.Options = copyOptions(p.Options)
+	setOption(p.Options, OptionDefaultLanguage, string(o.DefaultLanguage))
+	if o.PasscodePolicy.Length != 0 {
+		p.Options[OptionPasscodeLength] = o.PasscodePolicy.Length
+	}
+	setOption(p.Options, OptionCallbackSecret, o.CallbackSecret)
+	return p
+}
+
+// WithMasrviOptions returns a copy of the config with o written into
+// Options, where the MASRVI provider reads it
+func (p ProviderConfig) WithMasrviOptions(o MasrviOptions) ProviderConfig {
+	p.Options = copyOptions(p.Options)
+	setOption(p.Options, OptionBrandName, o.BrandName)
+	setOption(p.Options, OptionNotificationSecret, o.NotificationSecret)
+	if o.SessionTTL != 0 {
+		p.Options[OptionSessionTTL] = o.SessionTTL.String()
+	}
+	if o.SessionRefreshMargin != 0 {
+		p.Options[OptionSessionRefreshMargin] = o.SessionRefreshMargin.String()
+	}
+	if o.KeepSessionWarm {
+		p.Options[OptionKeepSessionWarm] = true
+	}
+	if o.RequireMerchantOverride {
+		p.Options[OptionRequireMerchantOverride] = true
+	}
+
+	urls := make(map[string]string)
+	for i, url := range []string{o.DefaultURLs.SuccessURL, o.DefaultURLs.FailureURL, o.DefaultURLs.CancelURL} {
+		if url != "" {
+			urls[masrviURLKeys[i]] = url
+		}
+	}
+	if len(urls) > 0 {
+		p.Options[OptionDefaultURLs] = urls
+	}
+	return p
+}
+
+// BPayOptions reads the typed B-PAY options from Options
+func (p ProviderConfig) BPayOptions() (BPayOptions, error) {
+	var o BPayOptions
+	language, err := p.StringOption(OptionDefaultLanguage, "")
+	if err != nil {
+		return o, err
+	}
+	switch o.DefaultLanguage = Language(language); o.DefaultLanguage {
+	case "", LanguageEnglish, LanguageFrench, LanguageArabic:
+	default:
+		return o, fmt.Errorf("option %s must be EN, FR or AR, got %q", OptionDefaultLanguage, language)
+	}
+	if o.PasscodePolicy.Length, err = p.IntOption(OptionPasscodeLength, 0); err != nil {
+		return o, err
+	}
+	if o.CallbackSecret, err = p.StringOption(OptionCallbackSecret, ""); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// MasrviOptions reads the typed MASRVI options from Options
+func (p ProviderConfig) MasrviOptions() (MasrviOptions, error) {
+	var o MasrviOptions
+	var err error
+	if o.BrandName, err = p.StringOption(OptionBrandName, ""); err != nil {
+		return o, err
+	}
+	if o.NotificationSecret, err = p.StringOption(OptionNotificationSecret, ""); err != nil {
+		return o, err
+	}
+	if o.SessionTTL, err = p.DurationOption(OptionSessionTTL, 0); err != nil {
+		return o, err
+	}
+	if o.SessionRefreshMargin, err = p.DurationOption(OptionSessionRefreshMargin, 0); err != nil {
+		return o, err
+	}
+	if o.KeepSessionWarm, err = p.BoolOption(OptionKeepSessionWarm, false); err != nil {
+		return o, err
+	}
+	if o.RequireMerchantOverride, err = p.BoolOption(OptionRequireMerchantOverride, false); err != nil {
+		return o, err
+	}
+
+	urls, err := p.StringMapOption(OptionDefaultURLs)
+	if err != nil {
+		return o, err
+	}
+	for key := range urls {
+		switch key {
+		case "success_url", "failure_url", "cancel_url":
+		default:
+			return o, fmt.Errorf("option %s: unknown URL %q (supported: %v)", OptionDefaultURLs, key, masrviURLKeys)
+		}
+	}
+	o.DefaultURLs = MasrviURLs{SuccessURL: urls["success_url"], FailureURL: urls["failure_url"], CancelURL: urls["cancel_url"]}
+	return o, nil
+}
+
+// copyOptions returns a copy of options that is never nil
+func copyOptions(options map[string]interface{}) map[string]interface{} {
+	dup := make(map[string]interface{}, len(options))
+	for key, value := range options {
+		dup[key] = value
+	}
+	return dup
+}
+
+// setOption sets options[key] to value unless value is empty
+func setOption(options map[string]interface{}, key, value string) {
+	if value != "" {
+		options[key] = value
+	}
+}
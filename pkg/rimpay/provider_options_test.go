@@ -0,0 +1,66 @@
+package rimpay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPayOptionsRoundTrip(t *testing.T) {
+	base := ProviderConfig{Options: map[string]interface{}{OptionStatusOverrides: map[string]string{"TS": "success"}}}
+	options := BPayOptions{
+		DefaultLanguage: LanguageArabic,
+		PasscodePolicy:  BPayPasscodePolicy{Length: 6},
+		CallbackSecret:  "s3cret",
+	}
+
+	config := base.WithBPayOptions(options)
+	assert.Equal(t, 6, config.Options[OptionPasscodeLength])
+	assert.Contains(t, config.Options, OptionStatusOverrides, "other options are kept")
+	assert.Len(t, base.Options, 1, "the original config is not changed")
+
+	got, err := config.BPayOptions()
+	require.NoError(t, err)
+	assert.Equal(t, options, got)
+
+	empty, err := ProviderConfig{}.WithBPayOptions(BPayOptions{}).BPayOptions()
+	require.NoError(t, err)
+	assert.Equal(t, BPayOptions{}, empty)
+
+	_, err = ProviderConfig{Options: map[string]interface{}{OptionDefaultLanguage: "DE"}}.BPayOptions()
+	assert.ErrorContains(t, err, OptionDefaultLanguage)
+	_, err = ProviderConfig{Options: map[string]interface{}{OptionCallbackSecret: 42}}.BPayOptions()
+	assert.ErrorContains(t, err, OptionCallbackSecret)
+}
+
+func TestMasrviOptionsRoundTrip(t *testing.T) {
+	options := MasrviOptions{
+		BrandName:          "Cato Shop",
+		NotificationSecret: "s3cret",
+		SessionTTL:         90 * time.Second,
+		KeepSessionWarm:    true,
+		DefaultURLs:        MasrviURLs{SuccessURL: "https://shop.test/ok", CancelURL: "https://shop.test/cancel"},
+	}
+
+	config := ProviderConfig{}.WithMasrviOptions(options)
+	assert.Equal(t, "1m30s", config.Options[OptionSessionTTL], "durations are written as strings")
+	assert.Equal(t, map[string]string{"success_url": "https://shop.test/ok", "cancel_url": "https://shop.test/cancel"},
+		config.Options[OptionDefaultURLs])
+
+	got, err := config.MasrviOptions()
+	require.NoError(t, err)
+	assert.Equal(t, options, got)
+
+	_, err = ProviderConfig{Options: map[string]interface{}{
+		OptionDefaultURLs: map[string]interface{}{"sucess_url": "https://shop.test/ok"},
+	}}.MasrviOptions()
+	assert.ErrorContains(t, err, "sucess_url")
+}
+
+func TestProviderConfigUnknownOptions(t *testing.T) {
+	config := ProviderConfig{Options: map[string]interface{}{"brand_name": "x", "sesion_ttl": "1m", "a": 1}}
+	assert.Equal(t, []string{"a", "sesion_ttl"}, config.UnknownOptions("brand_name", "session_ttl"))
+	assert.Empty(t, ProviderConfig{}.UnknownOptions())
+}
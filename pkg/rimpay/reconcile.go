@@ -0,0 +1,181 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultReconcileConcurrency is the number of concurrent status queries when
+// ReconcileOptions.Concurrency is unset
+const defaultReconcileConcurrency = 4
+
+// ReconcileOptions configures Client.Reconcile
+type ReconcileOptions struct {
+	// MinAge skips pending transactions created less than MinAge ago, which
+	// may still be completed by the customer
+	MinAge time.Duration
+	// Concurrency bounds the concurrent status queries; 0 means 4
+	Concurrency int
+	// DryRun queries the providers and reports without updating the store
+	DryRun bool
+	// OnResolved is called, possibly concurrently, for each transaction that
+	// reached a final status, also in dry-run mode. Its error is recorded on
+	// the item.
+	OnResolved func(ctx context.Context, item ReconcileItem) error
+}
+
+// ReconcileOutcome is what reconciliation found for a transaction
+type ReconcileOutcome string
+
+const (
+	// ReconcileResolved means the provider reported a final status
+	ReconcileResolved ReconcileOutcome = "resolved"
+	// ReconcilePending means the provider still reports the payment pending
+	ReconcilePending ReconcileOutcome = "pending"
+	// ReconcileUnqueryable means the status could not be obtained
+	ReconcileUnqueryable ReconcileOutcome = "unqueryable"
+)
+
+// ReconcileItem is the reconciliation of one stored transaction
+type ReconcileItem struct {
+	Transaction *StoredTransaction
+	Outcome     ReconcileOutcome
+	// Status is the provider's answer; nil when unqueryable
+	Status *TransactionStatus
+	// Err is the status query, store or OnResolved error
+	Err error
+}
+
+// ReconcileReport lists the reconciled transactions, oldest first, with
+// counts per outcome
+type ReconcileReport struct {
+	Items        []ReconcileItem
+	Resolved     int
+	StillPending int
+	Unqueryable  int
+	DryRun       bool
+}
+
+// Reconcile queries the provider of every pending transaction in the
+// transaction store older than opts.MinAge and records the statuses that
+// changed. When ctx ends, the transactions not yet queried are left out of
+// the report, which is returned with ctx.Err().
+func (c *Client) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	store := c.transactionStore()
+	if store == nil {
+		return nil, fmt.Errorf("%w: reconciliation needs a transaction store", ErrInvalidConfig)
+	}
+
+	pending, err := store.ListPending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pending transactions: %w", err)
+	}
+
+	cutoff := c.stats.now().Add(-opts.MinAge)
+	var due []*StoredTransaction
+	for _, stored := range pending {
+		if !stored.CreatedAt.After(cutoff) {
+			due = append(due, stored)
+		}
+	}
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = defaultReconcileConcurrency
+	}
+
+	items := make([]ReconcileItem, len(due))
+	done := make([]bool, len(due))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				items[i] = c.reconcileTransaction(ctx, store, opts, due[i])
+				done[i] = true
+			}
+		}()
+	}
+
+dispatch:
+	for i := range due {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	report := &ReconcileReport{DryRun: opts.DryRun}
+	for i, item := range items {
+		if !done[i] {
+			continue
+		}
+		report.Items = append(report.Items, item)
+		switch item.Outcome {
+		case ReconcileResolved:
+			report.Resolved++
+		case ReconcilePending:
+			report.StillPending++
+		default:
+			report.Unqueryable++
+		}
+	}
+
+	c.logger.Info("Reconciliation finished", "resolved", report.Resolved,
+		"pending", report.StillPending, "unqueryable", report.Unqueryable, "dry_run", opts.DryRun)
+	return report, ctx.Err()
+}
+
+// reconcileTransaction queries and records the status of one transaction
+func (c *Client) reconcileTransaction(ctx context.Context, store TransactionStore, opts ReconcileOptions, stored *StoredTransaction) ReconcileItem {
+	item := ReconcileItem{Transaction: stored, Outcome: ReconcileUnqueryable}
+
+	var provider PaymentProvider
+	if stored.Provider == "" {
+		provider = c.defaultProvider()
+	} else {
+		c.mu.RLock()
+		provider = c.providers[stored.Provider]
+		c.mu.RUnlock()
+	}
+	if provider == nil {
+		item.Err = fmt.Errorf(providerNotAvailableMsg, stored.Provider)
+		return item
+	}
+
+	var status *TransactionStatus
+	err := c.invoke(ctx, provider.Name(), func() (err error) {
+		status, err = provider.GetPaymentStatus(ctx, stored.TransactionID)
+		return err
+	})
+	if err != nil {
+		item.Err = err
+		return item
+	}
+	item.Status = status
+
+	if !status.IsCompleted() {
+		item.Outcome = ReconcilePending
+		return item
+	}
+	item.Outcome = ReconcileResolved
+
+	if !opts.DryRun {
+		if err := store.UpdateStatus(ctx, status); err != nil {
+			item.Err = fmt.Errorf("recording status: %w", err)
+			return item
+		}
+	}
+	if opts.OnResolved != nil {
+		item.Err = opts.OnResolved(ctx, item)
+	}
+	return item
+}
@@ -0,0 +1,154 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reconcileProvider answers status checks from statuses, failing unknown
+// transactions with ErrStatusNotSupported, and tracks concurrent queries
+type reconcileProvider struct {
+	namedProvider
+	statuses map[string]PaymentStatus
+	inFlight int32
+	peak     int32
+}
+
+func (p *reconcileProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	n := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	status, ok := p.statuses[transactionID]
+	if !ok {
+		return nil, ErrStatusNotSupported
+	}
+	result := &TransactionStatus{TransactionID: transactionID, Reference: "REF-" + transactionID}
+	result.AddEvent(status, "", EventSourcePoll)
+	return result, nil
+}
+
+func newReconcileTestClient(t *testing.T, clock *fakeClock) (*Client, *reconcileProvider, *MemoryTransactionStore) {
+	t.Helper()
+	provider := &reconcileProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		statuses: map[string]PaymentStatus{
+			"TX-1": PaymentStatusSuccess,
+			"TX-2": PaymentStatusPending,
+			"TX-3": PaymentStatusFailed,
+			"TX-5": PaymentStatusSuccess,
+		},
+	}
+	client := newFailoverTestClient(t, nil, provider)
+	client.stats.now = clock.Now
+
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	for i, id := range []string{"TX-1", "TX-2", "TX-3", "TX-4", "TX-5"} {
+		created := clock.Now().Add(-time.Duration(5-i) * time.Hour)
+		require.NoError(t, store.SavePayment(context.Background(), &PaymentResponse{
+			TransactionID: id,
+			Reference:     "REF-" + id,
+			Provider:      ProviderBPay,
+			Status:        PaymentStatusPending,
+			CreatedAt:     created,
+			UpdatedAt:     created,
+		}))
+	}
+	return client, provider, store
+}
+
+func TestReconcile(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(100000, 0)}
+	client, provider, store := newReconcileTestClient(t, clock)
+
+	var mu sync.Mutex
+	var ledger []string
+	report, err := client.Reconcile(context.Background(), ReconcileOptions{
+		MinAge:      90 * time.Minute,
+		Concurrency: 2,
+		OnResolved: func(_ context.Context, item ReconcileItem) error {
+			mu.Lock()
+			defer mu.Unlock()
+			ledger = append(ledger, item.Transaction.TransactionID)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	// TX-5 was created an hour ago and is too recent
+	require.Len(t, report.Items, 4)
+	assert.Equal(t, 2, report.Resolved)
+	assert.Equal(t, 1, report.StillPending)
+	assert.Equal(t, 1, report.Unqueryable)
+	assert.LessOrEqual(t, atomic.LoadInt32(&provider.peak), int32(2))
+	assert.ElementsMatch(t, []string{"TX-1", "TX-3"}, ledger)
+
+	outcomes := make(map[string]ReconcileOutcome)
+	for _, item := range report.Items {
+		outcomes[item.Transaction.TransactionID] = item.Outcome
+	}
+	assert.Equal(t, map[string]ReconcileOutcome{
+		"TX-1": ReconcileResolved,
+		"TX-2": ReconcilePending,
+		"TX-3": ReconcileResolved,
+		"TX-4": ReconcileUnqueryable,
+	}, outcomes)
+	assert.True(t, errors.Is(report.Items[3].Err, ErrStatusNotSupported))
+
+	stored, err := store.GetByTransactionID(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, stored.Status)
+	pending, err := store.ListPending(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(100000, 0)}
+	client, _, store := newReconcileTestClient(t, clock)
+
+	report, err := client.Reconcile(context.Background(), ReconcileOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 3, report.Resolved)
+
+	pending, err := store.ListPending(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, pending, 5)
+}
+
+func TestReconcileCallbackError(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(100000, 0)}
+	client, _, _ := newReconcileTestClient(t, clock)
+	ledgerDown := errors.New("ledger unavailable")
+
+	report, err := client.Reconcile(context.Background(), ReconcileOptions{
+		OnResolved: func(context.Context, ReconcileItem) error { return ledgerDown },
+	})
+	require.NoError(t, err)
+	for _, item := range report.Items {
+		if item.Outcome == ReconcileResolved {
+			assert.Equal(t, ledgerDown, item.Err)
+		}
+	}
+}
+
+func TestReconcileRequiresStore(t *testing.T) {
+	client := newFailoverTestClient(t, nil, &namedProvider{name: ProviderBPay})
+	_, err := client.Reconcile(context.Background(), ReconcileOptions{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}
@@ -0,0 +1,136 @@
+package rimpay
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renderedForm is the form of a rendered auto-submit page
+type renderedForm struct {
+	method, action string
+	fields         [][2]string
+}
+
+// parseForm parses page with the lenient HTML mode of encoding/xml
+func parseForm(t *testing.T, page string) renderedForm {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(page))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var form renderedForm
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return form
+		}
+		require.NoError(t, err)
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, attr := range start.Attr {
+			attrs[attr.Name.Local] = attr.Value
+		}
+		switch start.Name.Local {
+		case "form":
+			form.method, form.action = attrs["method"], attrs["action"]
+		case "input":
+			form.fields = append(form.fields, [2]string{attrs["name"], attrs["value"]})
+		}
+	}
+}
+
+func masrviRedirectResponse() *PaymentResponse {
+	return &PaymentResponse{
+		Provider:   ProviderMasrvi,
+		PaymentURL: "https://api.masrvi.mr/online/online.php",
+		Metadata: map[string]interface{}{
+			"payment_url": "https://api.masrvi.mr/online/online.php",
+			"form_data": url.Values{
+				"sessionid":   {"SESSION-1"},
+				"purchaseref": {"ORDER-7"},
+				"brand":       {`Café "Le <Coin>" & Co`},
+			},
+		},
+	}
+}
+
+func TestPaymentResponseRedirectInfo(t *testing.T) {
+	info, ok := masrviRedirectResponse().RedirectInfo()
+	require.True(t, ok)
+	assert.Equal(t, "https://api.masrvi.mr/online/online.php", info.URL)
+	assert.Equal(t, http.MethodPost, info.Method)
+	assert.Equal(t, "ORDER-7", info.Fields.Get("purchaseref"))
+
+	decoded := &PaymentResponse{Metadata: map[string]interface{}{
+		"payment_url": "https://pay.test/checkout",
+		"form_data":   map[string]interface{}{"sessionid": []interface{}{"S-1"}, "amount": "100"},
+	}}
+	info, ok = decoded.RedirectInfo()
+	require.True(t, ok, "metadata decoded from JSON is understood")
+	assert.Equal(t, url.Values{"sessionid": {"S-1"}, "amount": {"100"}}, info.Fields)
+
+	info, ok = (&PaymentResponse{PaymentURL: "https://pay.test/x"}).RedirectInfo()
+	require.True(t, ok)
+	assert.Equal(t, http.MethodGet, info.Method)
+	assert.Empty(t, info.Fields)
+
+	_, ok = (&PaymentResponse{Provider: ProviderBPay}).RedirectInfo()
+	assert.False(t, ok)
+	_, ok = (*PaymentResponse)(nil).RedirectInfo()
+	assert.False(t, ok)
+}
+
+func TestRenderAutoSubmitFormEscapesFields(t *testing.T) {
+	var page bytes.Buffer
+	require.NoError(t, masrviRedirectResponse().RenderAutoSubmitForm(&page))
+
+	assert.NotContains(t, page.String(), "<Coin>")
+	assert.Contains(t, page.String(), "document.forms[0].submit()")
+
+	form := parseForm(t, page.String())
+	assert.Equal(t, "POST", form.method)
+	assert.Equal(t, "https://api.masrvi.mr/online/online.php", form.action)
+	assert.Equal(t, [][2]string{
+		{"brand", `Café "Le <Coin>" & Co`},
+		{"purchaseref", "ORDER-7"},
+		{"sessionid", "SESSION-1"},
+	}, form.fields)
+}
+
+func TestRenderAutoSubmitFormRejectsUnsafeURL(t *testing.T) {
+	var page bytes.Buffer
+	response := &PaymentResponse{PaymentURL: `javascript:alert("x")`}
+	require.NoError(t, response.RenderAutoSubmitForm(&page))
+	assert.NotContains(t, page.String(), "javascript:")
+
+	err := (&PaymentResponse{}).RenderAutoSubmitForm(&page)
+	assert.ErrorIs(t, err, ErrNoRedirect)
+}
+
+func TestPaymentQRCode(t *testing.T) {
+	data, err := masrviRedirectResponse().PaymentQRCode(300)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	_, err = masrviRedirectResponse().PaymentQRCode(10)
+	assert.Error(t, err, "too small for the code")
+
+	_, err = (&PaymentResponse{}).PaymentQRCode(300)
+	assert.ErrorIs(t, err, ErrNoRedirect)
+}
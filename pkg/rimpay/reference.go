@@ -0,0 +1,191 @@
+package rimpay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// ReferenceCharset is the set of characters a provider accepts in payment
+// references
+type ReferenceCharset string
+
+const (
+	// ReferenceCharsetAny accepts every character
+	ReferenceCharsetAny ReferenceCharset = ""
+	// ReferenceCharsetAlphanumeric accepts ASCII letters and digits
+	ReferenceCharsetAlphanumeric ReferenceCharset = "alphanumeric"
+	// ReferenceCharsetAlphanumericDash also accepts - and _
+	ReferenceCharsetAlphanumericDash ReferenceCharset = "alphanumeric_dash"
+)
+
+// IsValid reports whether c is a known charset
+func (c ReferenceCharset) IsValid() bool {
+	switch c {
+	case ReferenceCharsetAny, ReferenceCharsetAlphanumeric, ReferenceCharsetAlphanumericDash:
+		return true
+	}
+	return false
+}
+
+func (c ReferenceCharset) allows(r rune) bool {
+	if c == ReferenceCharsetAny {
+		return true
+	}
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+	return c == ReferenceCharsetAlphanumericDash && (r == '-' || r == '_')
+}
+
+func (c ReferenceCharset) describe() string {
+	if c == ReferenceCharsetAlphanumericDash {
+		return "letters, digits, - and _"
+	}
+	return "letters and digits"
+}
+
+// ReferencePolicy is what a provider accepts as payment reference
+type ReferencePolicy struct {
+	// MaxLength caps the reference length; 0 means no cap
+	MaxLength int              `json:"max_length,omitempty"`
+	Charset   ReferenceCharset `json:"charset,omitempty"`
+	// Prefix must start every reference, for example a merchant code
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// DefaultReferencePolicy returns the reference rules of a built-in
+// provider, which apply unless ProviderConfig.Reference replaces them.
+// Other providers have no rules.
+func DefaultReferencePolicy(provider string) ReferencePolicy {
+	switch provider {
+	case ProviderBPay, ProviderMasrvi:
+		return ReferencePolicy{MaxLength: 50, Charset: ReferenceCharsetAlphanumericDash}
+	case ProviderClick:
+		return ReferencePolicy{MaxLength: 250}
+	case ProviderSedad:
+		return ReferencePolicy{MaxLength: 50}
+	}
+	return ReferencePolicy{}
+}
+
+// Validate checks the policy itself
+func (p ReferencePolicy) Validate() error {
+	if p.MaxLength < 0 {
+		return fmt.Errorf("reference max_length cannot be negative")
+	}
+	if !p.Charset.IsValid() {
+		return fmt.Errorf("unknown reference charset %q", p.Charset)
+	}
+	if p.MaxLength > 0 && len(p.Prefix) >= p.MaxLength {
+		return fmt.Errorf("reference prefix must be shorter than max_length")
+	}
+	for _, r := range p.Prefix {
+		if !p.Charset.allows(r) {
+			return fmt.Errorf("reference prefix contains %q, outside the reference charset", r)
+		}
+	}
+	return nil
+}
+
+// Check returns a VALIDATION_ERROR on the reference field, naming provider
+// and its constraint, when reference breaks the policy
+func (p ReferencePolicy) Check(provider, reference string) error {
+	var errs ValidationErrors
+	p.check(&errs, provider, reference)
+	return errs.Err()
+}
+
+func (p ReferencePolicy) check(errs *ValidationErrors, provider, reference string) {
+	if p.MaxLength > 0 && len(reference) > p.MaxLength {
+		errs.Add("reference", fmt.Sprintf("is %d characters; %s accepts at most %d", len(reference), provider, p.MaxLength))
+	}
+	for _, r := range reference {
+		if !p.Charset.allows(r) {
+			errs.Add("reference", fmt.Sprintf("contains %q; %s accepts only %s", r, provider, p.Charset.describe()))
+			break
+		}
+	}
+	if p.Prefix != "" && !strings.HasPrefix(reference, p.Prefix) {
+		errs.Add("reference", fmt.Sprintf("must start with %q for %s", p.Prefix, provider))
+	}
+}
+
+// validateReference records the failures of a typed request's reference
+// against provider's default policy
+func validateReference(errs *ValidationErrors, provider, reference string) {
+	if strings.TrimSpace(reference) == "" {
+		errs.Add("reference", "cannot be empty")
+		return
+	}
+	DefaultReferencePolicy(provider).check(errs, provider, reference)
+}
+
+// referencePolicy returns the configured reference policy of provider
+func (c *Client) referencePolicy(provider string) ReferencePolicy {
+	if config, ok := c.providerConfig(provider); ok && config.Reference != nil {
+		return *config.Reference
+	}
+	return DefaultReferencePolicy(provider)
+}
+
+// checkReference applies the reference policy of provider
+func (c *Client) checkReference(provider, reference string) error {
+	return c.referencePolicy(provider).Check(provider, reference)
+}
+
+// checkLimits applies the currencies, amount limits and reference policy of
+// provider
+func (c *Client) checkLimits(provider string, amount money.Money, reference string) error {
+	if err := c.checkCurrency(provider, amount); err != nil {
+		return err
+	}
+	if err := c.checkAmount(provider, amount); err != nil {
+		return err
+	}
+	return c.checkReference(provider, reference)
+}
+
+// minGeneratedRandom is the fewest random digits GenerateReference uses
+const minGeneratedRandom = 8
+
+// GenerateReference returns a new unique reference that provider's
+// reference policy accepts. It starts with the policy's prefix and then
+// prefix, followed by up to 12 random digits, as many as the policy's
+// length cap leaves room for.
+func (c *Client) GenerateReference(provider, prefix string) (string, error) {
+	return generateReference(c.referencePolicy(provider), provider, prefix)
+}
+
+func generateReference(policy ReferencePolicy, provider, prefix string) (string, error) {
+	if !strings.HasPrefix(prefix, policy.Prefix) {
+		prefix = policy.Prefix + prefix
+	}
+	separator := ""
+	if prefix != "" && policy.Charset.allows('_') {
+		separator = "_"
+	}
+
+	random := common.ReferenceFormat.RandomLength
+	if policy.MaxLength > 0 {
+		if room := policy.MaxLength - len(prefix) - len(separator); room < random {
+			random = room
+		}
+	}
+	if random < minGeneratedRandom {
+		return "", NewValidationError("prefix", fmt.Sprintf("%q leaves no room for %d unique characters within the %d characters %s accepts",
+			prefix, minGeneratedRandom, policy.MaxLength, provider))
+	}
+
+	suffix, err := common.IDFormat{RandomLength: random, NoTimestamp: true, Uppercase: true}.Generate()
+	if err != nil {
+		return "", err
+	}
+	reference := prefix + separator + suffix
+	if err := policy.Check(provider, reference); err != nil {
+		return "", err
+	}
+	return reference, nil
+}
@@ -0,0 +1,112 @@
+package rimpay
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPayRequestRejectsLongReference(t *testing.T) {
+	request := newValidBPayRequest()
+	request.Reference = strings.Repeat("R", 60)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	assert.Equal(t, map[string]string{"reference": "is 60 characters; bpay accepts at most 50"}, errs.Fields())
+
+	request.Reference = request.Reference[:50]
+	assert.NoError(t, request.Validate(), "the truncated reference is accepted")
+}
+
+func TestReferencePolicyCharsetAndPrefix(t *testing.T) {
+	request := newValidBPayRequest()
+	request.Reference = "ORDER #1"
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	assert.Equal(t, `contains ' '; bpay accepts only letters, digits, - and _`, errs.Fields()["reference"])
+
+	assert.NoError(t, (&ClickPaymentRequest{Amount: money.NewMRU(100), Reference: "ORDER #1"}).Validate(),
+		"CLICK accepts any character")
+
+	policy := ReferencePolicy{MaxLength: 20, Charset: ReferenceCharsetAlphanumeric, Prefix: "SHOP"}
+	assert.NoError(t, policy.Check("acme", "SHOP123"))
+	var paymentErr *PaymentError
+	require.ErrorAs(t, policy.Check("acme", "ORDER-123"), &paymentErr)
+	assert.Equal(t, "reference", paymentErr.Details["field"])
+	assert.Equal(t, `reference: contains '-'; acme accepts only letters and digits; reference: must start with "SHOP" for acme`,
+		paymentErr.Message)
+}
+
+func newReferenceTestClient(t *testing.T, policy *ReferencePolicy) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second, Reference: policy}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderBPay, &namedProvider{name: ProviderBPay}))
+	return client
+}
+
+func TestConfiguredReferencePolicyFailsLocally(t *testing.T) {
+	client := newReferenceTestClient(t, &ReferencePolicy{MaxLength: 20, Prefix: "SHOP-"})
+
+	request := failoverRequest()
+	request.Reference = "SHOP-" + strings.Repeat("1", 20)
+	_, err := client.ProcessPaymentWithProvider(context.Background(), ProviderBPay, request)
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Equal(t, "reference: is 25 characters; bpay accepts at most 20", paymentErr.Message)
+
+	request.Reference = "SHOP-1"
+	_, err = client.ProcessPaymentWithProvider(context.Background(), ProviderBPay, request)
+	assert.NoError(t, err)
+}
+
+func TestGenerateReferenceFitsPolicy(t *testing.T) {
+	client := newReferenceTestClient(t, nil)
+	reference, err := client.GenerateReference(ProviderBPay, "ORDER")
+	require.NoError(t, err)
+	assert.Regexp(t, `^ORDER_[0-9A-F]{12}$`, reference)
+	request := newValidBPayRequest()
+	request.Reference = reference
+	assert.NoError(t, request.Validate())
+
+	other, err := client.GenerateReference(ProviderBPay, "ORDER")
+	require.NoError(t, err)
+	assert.NotEqual(t, reference, other)
+
+	client = newReferenceTestClient(t, &ReferencePolicy{MaxLength: 20, Charset: ReferenceCharsetAlphanumeric, Prefix: "SHOP"})
+	reference, err = client.GenerateReference(ProviderBPay, "INV")
+	require.NoError(t, err)
+	assert.Regexp(t, `^SHOPINV[0-9A-F]{12}$`, reference)
+
+	reference, err = client.GenerateReference(ProviderBPay, "SHOPINVOICE")
+	require.NoError(t, err)
+	assert.Regexp(t, `^SHOPINVOICE[0-9A-F]{9}$`, reference, "the random part shrinks to fit")
+
+	_, err = client.GenerateReference(ProviderBPay, "SHOPINVOICE2026")
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, "prefix", paymentErr.Details["field"])
+
+	_, err = client.GenerateReference(ProviderBPay, "INV-")
+	assert.Error(t, err, "a prefix outside the charset cannot produce a valid reference")
+}
+
+func TestReferencePolicyConfigValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Reference: &ReferencePolicy{Charset: "emoji"}}
+	assert.ErrorContains(t, config.Validate(), `unknown reference charset "emoji"`)
+
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Reference: &ReferencePolicy{MaxLength: 4, Prefix: "SHOP"}}
+	assert.ErrorContains(t, config.Validate(), "reference prefix must be shorter than max_length")
+}
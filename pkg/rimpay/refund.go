@@ -0,0 +1,93 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// Refund reverses all or part of a payment through request.Provider, or the
+// default provider when it is empty. Providers without a refund API return
+// ErrRefundNotSupported.
+//
+// When request.OriginalAmount is zero, the amount of the payment is read
+// from the TransactionStore, or else queried from the provider, so refunds
+// above it fail with ErrorCodeInvalidRequest before reaching the provider.
+func (c *Client) Refund(ctx context.Context, request *RefundRequest) (*RefundResponse, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	var provider PaymentProvider
+	if request.Provider == "" {
+		provider = c.defaultProvider()
+	} else {
+		c.mu.RLock()
+		p, ok := c.providers[request.Provider]
+		c.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf(providerNotAvailableMsg, request.Provider)
+		}
+		provider = p
+	}
+	if provider == nil {
+		return nil, ErrProviderNotFound
+	}
+
+	if request.OriginalAmount.IsZero() {
+		original, err := c.originalAmount(ctx, provider, request.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		if !original.IsZero() {
+			filled := *request
+			filled.OriginalAmount = original
+			request = &filled
+			if err := request.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var result *RefundResponse
+	err := c.invoke(ctx, provider.Name(), func() (err error) {
+		result, err = provider.Refund(ctx, request)
+		return err
+	})
+	return result, err
+}
+
+// originalAmount returns the amount of the payment transactionID, or zero
+// when neither the store nor the provider reports it
+func (c *Client) originalAmount(ctx context.Context, provider PaymentProvider, transactionID string) (money.Money, error) {
+	if store := c.transactionStore(); store != nil {
+		stored, err := store.GetByTransactionID(ctx, transactionID)
+		switch {
+		case err == nil && !stored.Amount.IsZero():
+			return stored.Amount, nil
+		case err != nil && !errors.Is(err, ErrTransactionNotFound):
+			return money.Money{}, fmt.Errorf("looking up payment %s: %w", transactionID, err)
+		}
+	}
+
+	var status *TransactionStatus
+	err := c.invoke(ctx, provider.Name(), func() (err error) {
+		status, err = provider.GetPaymentStatus(ctx, transactionID)
+		return err
+	})
+	if errors.Is(err, ErrStatusNotSupported) {
+		return money.Money{}, nil
+	}
+	if err != nil {
+		return money.Money{}, fmt.Errorf("looking up payment %s: %w", transactionID, err)
+	}
+	if status == nil {
+		return money.Money{}, nil
+	}
+	return status.Amount, nil
+}
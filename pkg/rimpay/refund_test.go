@@ -0,0 +1,124 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refundingProvider records the refunds that reach it
+type refundingProvider struct {
+	namedProvider
+	refunds []*RefundRequest
+	// paid is the amount status queries report
+	paid    money.Money
+	queries int
+}
+
+func (p *refundingProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	p.queries++
+	return &TransactionStatus{TransactionID: transactionID, Status: PaymentStatusSuccess, Amount: p.paid}, nil
+}
+
+func (p *refundingProvider) Refund(_ context.Context, request *RefundRequest) (*RefundResponse, error) {
+	p.refunds = append(p.refunds, request)
+	return &RefundResponse{RefundID: "RF-" + p.name, Status: PaymentStatusSuccess, Provider: p.name}, nil
+}
+
+func newRefundTestClient(t *testing.T) (*Client, *refundingProvider) {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderBPay
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	bpay := &refundingProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	require.NoError(t, client.AddProvider(ProviderBPay, bpay))
+	require.NoError(t, client.AddProvider(ProviderClick, &namedProvider{name: ProviderClick}))
+	return client, bpay
+}
+
+func TestClientRefund(t *testing.T) {
+	client, bpay := newRefundTestClient(t)
+	request := &RefundRequest{TransactionID: "TX-1", Amount: money.NewMRU(5000), Reference: "RF-1"}
+
+	resp, err := client.Refund(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "RF-bpay", resp.RefundID)
+	assert.Equal(t, []*RefundRequest{request}, bpay.refunds)
+}
+
+func TestClientRefundErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *RefundRequest
+		wantErr string
+		wantIs  error
+	}{
+		{"nil request", nil, "", ErrInvalidRequest},
+		{"missing transaction", &RefundRequest{Amount: money.NewMRU(100), Reference: "RF-1"}, "transaction_id: is required", nil},
+		{"exceeds original", &RefundRequest{
+			TransactionID: "TX-1", Amount: money.NewMRU(2000), OriginalAmount: money.NewMRU(1000), Reference: "RF-1",
+		}, "refund of 20.00 MRU exceeds the original amount of 10.00 MRU", nil},
+		{"unsupported provider", &RefundRequest{
+			TransactionID: "TX-1", Amount: money.NewMRU(100), Reference: "RF-1", Provider: ProviderClick,
+		}, "", ErrRefundNotSupported},
+		{"unknown provider", &RefundRequest{
+			TransactionID: "TX-1", Amount: money.NewMRU(100), Reference: "RF-1", Provider: "sedad",
+		}, "provider sedad not available", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, bpay := newRefundTestClient(t)
+
+			_, err := client.Refund(context.Background(), tt.request)
+			require.Error(t, err)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			} else {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+			assert.Empty(t, bpay.refunds)
+		})
+	}
+}
+
+func TestClientRefundLooksUpOriginalAmount(t *testing.T) {
+	client, bpay := newRefundTestClient(t)
+	bpay.paid = money.NewMRU(10000)
+
+	_, err := client.Refund(context.Background(), &RefundRequest{TransactionID: "TX-1", Amount: money.NewMRU(20000), Reference: "RF-1"})
+	assert.Equal(t, ErrorCodeInvalidRequest, CodeOf(err), "got %v", err)
+	assert.Empty(t, bpay.refunds)
+
+	request := &RefundRequest{TransactionID: "TX-1", Amount: money.NewMRU(4000), Reference: "RF-2"}
+	_, err = client.Refund(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, bpay.refunds, 1)
+	assert.True(t, bpay.refunds[0].OriginalAmount.Equals(money.NewMRU(10000)))
+	assert.True(t, bpay.refunds[0].IsPartial())
+	assert.True(t, request.OriginalAmount.IsZero(), "the caller's request is not modified")
+	assert.Equal(t, 2, bpay.queries)
+}
+
+func TestClientRefundReadsOriginalAmountFromStore(t *testing.T) {
+	client, bpay := newRefundTestClient(t)
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	require.NoError(t, store.SavePayment(context.Background(), &PaymentResponse{
+		TransactionID: "TX-1", Reference: "ORDER-1", Provider: ProviderBPay,
+		Amount: money.NewMRU(10000), Status: PaymentStatusSuccess,
+	}))
+
+	_, err := client.Refund(context.Background(), &RefundRequest{TransactionID: "TX-1", Amount: money.NewMRU(10001), Reference: "RF-1"})
+	assert.Equal(t, ErrorCodeInvalidRequest, CodeOf(err), "got %v", err)
+	assert.Zero(t, bpay.queries, "the store answers first")
+	assert.Empty(t, bpay.refunds)
+}
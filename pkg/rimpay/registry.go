@@ -2,6 +2,9 @@ package rimpay
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 )
 
 // ProviderFactory creates payment providers
@@ -9,6 +12,7 @@ type ProviderFactory func(config ProviderConfig, logger Logger) (PaymentProvider
 
 // ProviderRegistry manages payment provider factories
 type ProviderRegistry struct {
+	mu        sync.RWMutex
 	factories map[string]ProviderFactory
 }
 
@@ -19,28 +23,109 @@ func NewProviderRegistry() *ProviderRegistry {
 	}
 }
 
-// Register registers a provider factory
+// Register registers a provider factory, replacing any factory registered
+// under name. It panics on a nil registry, since silently dropping a factory
+// would hide the mistake until the first payment.
 func (r *ProviderRegistry) Register(name string, factory ProviderFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.factories == nil {
+		r.factories = make(map[string]ProviderFactory)
+	}
 	r.factories[name] = factory
 }
 
-// Create creates a provider instance
+// Add registers a provider factory, failing if name is empty, factory is
+// nil or name is already registered
+func (r *ProviderRegistry) Add(name string, factory ProviderFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("%w: provider name and factory are required", ErrInvalidProvider)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("provider %q is already registered", name)
+	}
+	if r.factories == nil {
+		r.factories = make(map[string]ProviderFactory)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Create creates a provider instance. An unknown name fails with an error
+// wrapping ErrProviderNotFound that lists the registered providers.
 func (r *ProviderRegistry) Create(name string, config ProviderConfig, logger Logger) (PaymentProvider, error) {
-	factory, exists := r.factories[name]
+	var factory ProviderFactory
+	exists := false
+	if r != nil {
+		r.mu.RLock()
+		factory, exists = r.factories[name]
+		r.mu.RUnlock()
+	}
 	if !exists {
-		return nil, fmt.Errorf("unknown provider: %s", name)
+		return nil, fmt.Errorf("provider %q not registered (registered: %s): %w",
+			name, strings.Join(r.GetRegisteredProviders(), ", "), ErrProviderNotFound)
 	}
 	return factory(config, logger)
 }
 
-// GetRegisteredProviders returns list of registered provider names
+// GetRegisteredProviders returns the registered provider names, sorted
 func (r *ProviderRegistry) GetRegisteredProviders() []string {
+	if r == nil {
+		return []string{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.factories))
 	for name := range r.factories {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
 // DefaultRegistry is the default global provider registry
 var DefaultRegistry = NewProviderRegistry()
+
+// RegisterProvider makes a custom provider available under name to
+// Client.InitializeProviders, typically from an init function. Registering a
+// name twice, including a built-in provider's, is an error.
+func RegisterProvider(name string, factory ProviderFactory) error {
+	return DefaultRegistry.Add(name, factory)
+}
+
+// InitializeProviders creates every enabled provider in Config.Providers
+// that has not been added yet, using the factory registered under its name
+// in DefaultRegistry. Built-in providers are registered by importing
+// pkg/providers.
+func (c *Client) InitializeProviders() error {
+	return c.initializeProviders(DefaultRegistry)
+}
+
+func (c *Client) initializeProviders(registry *ProviderRegistry) error {
+	providers := c.currentConfig().Providers
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := providers[name]
+		_, added := c.registered(name)
+		if !config.Enabled || added {
+			continue
+		}
+
+		provider, err := registry.Create(name, c.withSharedHTTP(config), c.logger)
+		if err != nil {
+			return fmt.Errorf("initializing provider %s: %w", name, err)
+		}
+		if err := c.AddProvider(name, provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
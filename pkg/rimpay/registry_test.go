@@ -0,0 +1,62 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterProviderRejectsDuplicates(t *testing.T) {
+	factory := func(ProviderConfig, Logger) (PaymentProvider, error) {
+		return &namedProvider{name: "sedad"}, nil
+	}
+	require.NoError(t, RegisterProvider("registry-test-sedad", factory))
+	assert.Error(t, RegisterProvider("registry-test-sedad", factory))
+	assert.True(t, errors.Is(RegisterProvider("", factory), ErrInvalidProvider))
+	assert.True(t, errors.Is(RegisterProvider("registry-test-nil", nil), ErrInvalidProvider))
+}
+
+func TestRegistryCreateUnknownProvider(t *testing.T) {
+	registry := NewProviderRegistry()
+	registry.Register("bpay", nil)
+	registry.Register("masrvi", nil)
+
+	_, err := registry.Create("sedad", ProviderConfig{}, nil)
+	assert.True(t, errors.Is(err, ErrProviderNotFound))
+	assert.EqualError(t, err, `provider "sedad" not registered (registered: bpay, masrvi): payment provider not found`)
+}
+
+func TestInitializeProviders(t *testing.T) {
+	var created ProviderConfig
+	registry := NewProviderRegistry()
+	require.NoError(t, registry.Add("sedad", func(config ProviderConfig, _ Logger) (PaymentProvider, error) {
+		created = config
+		return &namedProvider{name: "sedad"}, nil
+	}))
+
+	config := DefaultConfig()
+	config.DefaultProvider = "sedad"
+	config.Providers["sedad"] = ProviderConfig{Enabled: true, BaseURL: "https://sedad.test", Timeout: time.Second}
+	config.Providers["disabled"] = ProviderConfig{BaseURL: "https://disabled.test"}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+
+	require.NoError(t, client.initializeProviders(registry))
+	assert.Equal(t, []string{"sedad"}, client.ListProviders())
+	assert.Equal(t, "https://sedad.test", created.BaseURL)
+	assert.NotNil(t, created.HTTPClient)
+
+	status, err := client.GetPaymentStatus(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, "sedad", status.TransactionID)
+
+	config.Providers["bank"] = ProviderConfig{Enabled: true, BaseURL: "https://bank.test", Timeout: time.Second}
+	err = client.initializeProviders(registry)
+	assert.True(t, errors.Is(err, ErrProviderNotFound))
+	assert.Contains(t, err.Error(), `provider "bank" not registered (registered: sedad)`)
+}
@@ -0,0 +1,156 @@
+package rimpay
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ReloadConfig applies newConfig to a running client, for example after a
+// credential rotation. Providers whose section changed are recreated with the
+// factory registered in DefaultRegistry and the previous instance is closed,
+// newly enabled providers are added, and providers that were disabled or
+// removed are closed and removed. Other providers are left untouched, and
+// calls in flight on a replaced provider finish on the old instance.
+//
+// DefaultProvider, routing, failover, batch, webhook and amount limit
+// settings apply from the next call. The HTTP, logging, priority, alerts,
+// portability and health settings keep the values the client was created
+// with.
+//
+// newConfig is validated first; if it is invalid or a provider cannot be
+// created, the client keeps its current configuration and providers.
+func (c *Client) ReloadConfig(newConfig *Config) error {
+	return c.reloadConfig(DefaultRegistry, newConfig)
+}
+
+func (c *Client) reloadConfig(registry *ProviderRegistry, newConfig *Config) error {
+	if newConfig == nil {
+		return ErrInvalidConfig
+	}
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if err := newConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	old := c.currentConfig()
+	config := *newConfig
+	// The recorder set with WithHTTPRecorder is not part of the file config
+	if config.HTTP.Recorder == nil {
+		config.HTTP.Recorder = old.HTTP.Recorder
+	}
+
+	created, removed, err := c.reloadProviders(registry, old, &config)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	retired := make(map[string]PaymentProvider, len(created)+len(removed))
+	for name, provider := range created {
+		if previous, ok := c.providers[name]; ok {
+			retired[name] = previous
+		}
+		c.providers[name] = provider
+		delete(c.configs, name)
+	}
+	for _, name := range removed {
+		if previous, ok := c.providers[name]; ok {
+			retired[name] = previous
+		}
+		delete(c.providers, name)
+		delete(c.configs, name)
+	}
+	c.config = &config
+	c.mu.Unlock()
+
+	changed := append(sortedProviderNames(created), removed...)
+	c.health.forget(changed...)
+	closeProviders(c.logger, retired)
+
+	c.logger.Info("Configuration reloaded",
+		"default_provider", config.DefaultProvider,
+		"created", sortedProviderNames(created),
+		"removed", removed,
+	)
+	return nil
+}
+
+// reloadProviders creates the providers that newConfig adds or changes and
+// lists the registered providers it disables or removes. On error the
+// providers created so far are closed.
+func (c *Client) reloadProviders(registry *ProviderRegistry, old, newConfig *Config) (created map[string]PaymentProvider, removed []string, err error) {
+	names := make([]string, 0, len(old.Providers)+len(newConfig.Providers))
+	for name := range old.Providers {
+		names = append(names, name)
+	}
+	for name := range newConfig.Providers {
+		if _, ok := old.Providers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	created = make(map[string]PaymentProvider)
+	for _, name := range names {
+		before, wasConfigured := old.Providers[name]
+		after, configured := newConfig.Providers[name]
+		_, registered := c.registered(name)
+
+		if !configured || !after.Enabled {
+			// Providers added without a config section are not ours to remove
+			if registered && wasConfigured {
+				removed = append(removed, name)
+			}
+			continue
+		}
+
+		wasEnabled := wasConfigured && before.Enabled
+		if wasEnabled && !providerConfigChanged(c.withShared(old, before), c.withShared(newConfig, after)) {
+			continue
+		}
+
+		provider, err := registry.Create(name, c.withShared(newConfig, after), c.logger)
+		if err != nil {
+			closeProviders(c.logger, created)
+			return nil, nil, fmt.Errorf("reloading provider %s: %w", name, err)
+		}
+		created[name] = provider
+	}
+	return created, removed, nil
+}
+
+// providerConfigChanged reports whether a provider must be recreated to
+// apply after. Retry callbacks cannot be compared and are ignored.
+func providerConfigChanged(before, after ProviderConfig) bool {
+	return !reflect.DeepEqual(withoutCallbacks(before), withoutCallbacks(after))
+}
+
+func withoutCallbacks(config ProviderConfig) ProviderConfig {
+	if config.Retry != nil {
+		retry := *config.Retry
+		retry.OnRetry = nil
+		config.Retry = &retry
+	}
+	return config
+}
+
+// closeProviders closes the providers that implement io.Closer, logging
+// failures
+func closeProviders(logger Logger, providers map[string]PaymentProvider) {
+	for _, name := range sortedProviderNames(providers) {
+		closer, ok := providers[name].(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			logger.Warn("Failed to close provider", "provider", name, "error", err)
+		}
+	}
+}
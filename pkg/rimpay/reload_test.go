@@ -0,0 +1,234 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadableProvider remembers the base URL it was created with and whether
+// it was closed. When hold is set, payments signal started and wait for it.
+type reloadableProvider struct {
+	namedProvider
+	baseURL string
+	started chan struct{}
+	hold    chan struct{}
+	closed  int32
+}
+
+func (p *reloadableProvider) ProcessPayment(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	if p.hold != nil {
+		close(p.started)
+		select {
+		case <-p.hold:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &PaymentResponse{TransactionID: p.name + "@" + p.baseURL, Reference: request.Reference, Status: PaymentStatusSuccess}, nil
+}
+
+func (p *reloadableProvider) Close() error {
+	atomic.StoreInt32(&p.closed, 1)
+	return nil
+}
+
+func (p *reloadableProvider) isClosed() bool {
+	return atomic.LoadInt32(&p.closed) == 1
+}
+
+// reloadRegistry creates reloadableProviders, failing for the base URL
+// https://broken.test
+func reloadRegistry(names ...string) *ProviderRegistry {
+	registry := NewProviderRegistry()
+	for _, name := range names {
+		name := name
+		registry.Register(name, func(config ProviderConfig, _ Logger) (PaymentProvider, error) {
+			if config.BaseURL == "https://broken.test" {
+				return nil, errors.New("cannot connect")
+			}
+			return &reloadableProvider{namedProvider: namedProvider{name: name}, baseURL: config.BaseURL}, nil
+		})
+	}
+	return registry
+}
+
+func reloadConfig(defaultProvider string, providers map[string]string) *Config {
+	config := DefaultConfig()
+	config.DefaultProvider = defaultProvider
+	for name, baseURL := range providers {
+		config.Providers[name] = ProviderConfig{Enabled: baseURL != "", BaseURL: baseURL, Timeout: time.Second}
+	}
+	return config
+}
+
+func newReloadTestClient(t *testing.T, registry *ProviderRegistry, config *Config) *Client {
+	t.Helper()
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.initializeProviders(registry))
+	return client
+}
+
+func reloadable(t *testing.T, client *Client, name string) *reloadableProvider {
+	t.Helper()
+	provider, ok := client.registered(name)
+	require.True(t, ok, "provider %s is registered", name)
+	return provider.(*reloadableProvider)
+}
+
+func TestReloadConfigReinitializesChangedProviders(t *testing.T) {
+	registry := reloadRegistry("alpha", "beta", "gamma", "delta")
+	client := newReloadTestClient(t, registry, reloadConfig("alpha", map[string]string{
+		"alpha": "https://alpha.test",
+		"beta":  "https://beta-v1.test",
+		"delta": "https://delta.test",
+	}))
+	alpha, beta, delta := reloadable(t, client, "alpha"), reloadable(t, client, "beta"), reloadable(t, client, "delta")
+
+	require.NoError(t, client.reloadConfig(registry, reloadConfig("alpha", map[string]string{
+		"alpha": "https://alpha.test",
+		"beta":  "https://beta-v2.test",
+		"gamma": "https://gamma.test",
+		"delta": "",
+	})))
+
+	assert.Equal(t, []string{"alpha", "beta", "gamma"}, client.ListProviders())
+	assert.Same(t, alpha, reloadable(t, client, "alpha"), "unchanged providers are kept")
+	assert.False(t, alpha.isClosed())
+
+	assert.Equal(t, "https://beta-v2.test", reloadable(t, client, "beta").baseURL)
+	assert.True(t, beta.isClosed(), "the replaced instance is closed")
+	assert.True(t, delta.isClosed(), "disabled providers are closed")
+}
+
+func TestReloadConfigKeepsProvidersWithoutConfig(t *testing.T) {
+	registry := reloadRegistry("alpha")
+	client := newReloadTestClient(t, registry, reloadConfig("alpha", map[string]string{"alpha": "https://alpha.test"}))
+	require.NoError(t, client.AddProvider("manual", &namedProvider{name: "manual"}))
+
+	require.NoError(t, client.reloadConfig(registry, reloadConfig("alpha", map[string]string{"alpha": "https://alpha.test"})))
+	assert.Equal(t, []string{"alpha", "manual"}, client.ListProviders())
+}
+
+func TestReloadConfigSwapsDefaultProvider(t *testing.T) {
+	registry := reloadRegistry("alpha", "beta")
+	providers := map[string]string{"alpha": "https://alpha.test", "beta": "https://beta.test"}
+	client := newReloadTestClient(t, registry, reloadConfig("alpha", providers))
+
+	response, err := client.ProcessPayment(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "alpha@https://alpha.test", response.TransactionID)
+
+	require.NoError(t, client.reloadConfig(registry, reloadConfig("beta", providers)))
+
+	response, err = client.ProcessPayment(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "beta@https://beta.test", response.TransactionID)
+}
+
+func TestReloadConfigFailureKeepsCurrentState(t *testing.T) {
+	registry := reloadRegistry("alpha", "beta", "gamma")
+	client := newReloadTestClient(t, registry, reloadConfig("alpha", map[string]string{"alpha": "https://alpha.test"}))
+	alpha := reloadable(t, client, "alpha")
+
+	err := client.reloadConfig(registry, reloadConfig("missing", map[string]string{"alpha": "https://alpha.test"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid config")
+
+	err = client.reloadConfig(registry, reloadConfig("beta", map[string]string{
+		"alpha": "https://alpha-v2.test",
+		"beta":  "https://beta.test",
+		"gamma": "https://broken.test",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reloading provider gamma: cannot connect")
+
+	assert.Equal(t, []string{"alpha"}, client.ListProviders())
+	assert.Same(t, alpha, reloadable(t, client, "alpha"))
+	assert.False(t, alpha.isClosed())
+	assert.Equal(t, "alpha", client.currentConfig().DefaultProvider)
+
+	assert.ErrorIs(t, client.ReloadConfig(nil), ErrInvalidConfig)
+	require.NoError(t, client.Close(context.Background()))
+	assert.ErrorIs(t, client.ReloadConfig(reloadConfig("alpha", map[string]string{"alpha": "https://alpha.test"})), ErrClientClosed)
+}
+
+func TestPaymentsProceedDuringReload(t *testing.T) {
+	registry := reloadRegistry("alpha", "beta")
+	client := newReloadTestClient(t, registry, reloadConfig("alpha", map[string]string{
+		"alpha": "https://alpha.test",
+		"beta":  "https://beta-v0.test",
+	}))
+
+	// A payment in flight on beta when beta is replaced
+	oldBeta := reloadable(t, client, "beta")
+	oldBeta.started, oldBeta.hold = make(chan struct{}), make(chan struct{})
+	type result struct {
+		response *PaymentResponse
+		err      error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		response, err := client.ProcessPaymentWithProvider(context.Background(), "beta", failoverRequest())
+		inFlight <- result{response, err}
+	}()
+	<-oldBeta.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	var payments int64
+	errs := make(chan error, 8)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				response, err := client.ProcessPayment(ctx, failoverRequest())
+				if err != nil {
+					if ctx.Err() == nil {
+						errs <- err
+					}
+					return
+				}
+				if response.TransactionID != "alpha@https://alpha.test" {
+					errs <- fmt.Errorf("payment went to %s", response.TransactionID)
+					return
+				}
+				atomic.AddInt64(&payments, 1)
+			}
+		}()
+	}
+
+	for version := 1; version <= 20; version++ {
+		require.NoError(t, client.reloadConfig(registry, reloadConfig("alpha", map[string]string{
+			"alpha": "https://alpha.test",
+			"beta":  fmt.Sprintf("https://beta-v%d.test", version),
+		})))
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	assert.Positive(t, atomic.LoadInt64(&payments))
+	assert.Equal(t, "https://beta-v20.test", reloadable(t, client, "beta").baseURL)
+
+	// The payment in flight on the replaced instance completes on it
+	assert.True(t, oldBeta.isClosed())
+	close(oldBeta.hold)
+	paid := <-inFlight
+	require.NoError(t, paid.err)
+	assert.Equal(t, "beta@https://beta-v0.test", paid.response.TransactionID)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/CatoSystems/rim-pay/pkg/money"
 	"github.com/CatoSystems/rim-pay/pkg/phone"
@@ -17,45 +18,70 @@ type BPayPaymentRequest struct {
 	Reference   string                 `json:"reference"`
 	Passcode    string                 `json:"passcode"` // B-PAY specific: user passcode
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	// OperationType defaults to BPayOperationPayment when empty
+	OperationType BPayOperationType `json:"operation_type,omitempty"`
+
+	// Mode defaults to BPayModePasscode when empty. BPayModeUSSDPush asks
+	// the customer to confirm on their handset and takes no Passcode.
+	Mode BPayMode `json:"mode,omitempty"`
+
+	// Language defaults to French when empty
+	Language  Language   `json:"language,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// CallbackURL receives B-PAY's status callback (optional)
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
-// Validate validates the B-PAY payment request
+// Validate validates the B-PAY payment request, returning ValidationErrors
+// with every failed field
 func (r *BPayPaymentRequest) Validate() error {
+	if r == nil {
+		return ErrInvalidRequest
+	}
+
+	var errs ValidationErrors
 	if r.PhoneNumber == nil {
-		return fmt.Errorf("phone number is required")
+		errs.Add("phone_number", "is required")
 	}
 
 	if r.Amount.IsZero() {
-		return fmt.Errorf("amount must be positive")
+		errs.Add("amount", "must be positive")
 	}
 
 	if strings.TrimSpace(r.Description) == "" {
-		return fmt.Errorf("description cannot be empty")
+		errs.Add("description", "cannot be empty")
 	}
 
-	if strings.TrimSpace(r.Reference) == "" {
-		return fmt.Errorf("reference cannot be empty")
-	}
-
-	if len(r.Reference) > 50 {
-		return fmt.Errorf("reference cannot exceed 50 characters")
-	}
+	validateReference(&errs, ProviderBPay, r.Reference)
 
-	if strings.TrimSpace(r.Passcode) == "" {
-		return fmt.Errorf("passcode is required (the customer's Bankily verification code)")
+	switch r.Mode.OrDefault() {
+	case BPayModePasscode:
+		if strings.TrimSpace(r.Passcode) == "" {
+			errs.Add("passcode", "is required (the customer's Bankily verification code)")
+		} else if !isPasscode(r.Passcode) {
+			errs.Add("passcode", "must be 4 or 6 digits")
+		}
+	case BPayModeUSSDPush:
+		if r.Passcode != "" {
+			errs.Add("passcode", "must be empty in ussd_push mode (the customer confirms on their handset)")
+		}
+	default:
+		errs.Add("mode", fmt.Sprintf("unknown mode %q", r.Mode))
 	}
 
-	if !isFourDigitPasscode(r.Passcode) {
-		return fmt.Errorf("passcode must be exactly 4 digits")
+	if r.OperationType != "" && !r.OperationType.IsValid() {
+		errs.Add("operation_type", fmt.Sprintf("unknown operation type %q", r.OperationType))
 	}
 
-	return nil
+	return errs.Err()
 }
 
-// isFourDigitPasscode reports whether s is exactly four ASCII digits, matching
-// the Bankily B-PAY passcode format.
-func isFourDigitPasscode(s string) bool {
-	if len(s) != 4 {
+// isPasscode reports whether s is four or six ASCII digits, the Bankily
+// B-PAY passcode formats. The provider's passcode_length option picks one.
+func isPasscode(s string) bool {
+	if len(s) != 4 && len(s) != 6 {
 		return false
 	}
 	for _, c := range s {
@@ -66,8 +92,12 @@ func isFourDigitPasscode(s string) bool {
 	return true
 }
 
-// ToGenericRequest converts B-PAY request to generic payment request
+// ToGenericRequest converts B-PAY request to generic payment request; a nil
+// request converts to nil
 func (r *BPayPaymentRequest) ToGenericRequest() *PaymentRequest {
+	if r == nil {
+		return nil
+	}
 	metadata := make(map[string]interface{})
 	for k, v := range r.Metadata {
 		metadata[k] = v
@@ -80,9 +110,29 @@ func (r *BPayPaymentRequest) ToGenericRequest() *PaymentRequest {
 		Reference:   r.Reference,
 		Passcode:    r.Passcode,
 		Metadata:    metadata,
+		Language:    r.Language,
+		ExpiresAt:   copyTime(r.ExpiresAt),
+		CallbackURL: r.CallbackURL,
+
+		OperationType: r.OperationType,
+		Mode:          r.Mode,
 	}
 }
 
+// BPayNotificationData is the JSON body of a B-PAY status callback
+type BPayNotificationData struct {
+	// OperationID is the payment reference
+	OperationID   string `json:"operationId"`
+	TransactionID string `json:"transactionId"`
+	// Status is the raw checkTransaction status, such as TS or TF
+	Status       string `json:"status"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	Amount       string `json:"amount,omitempty"`
+	ClientPhone  string `json:"clientPhone,omitempty"`
+	Timestamp    string `json:"timestamp,omitempty"`
+}
+
 // MasrviPaymentRequest represents a MASRVI specific payment request
 type MasrviPaymentRequest struct {
 	PhoneNumber *phone.Phone           `json:"phone_number"`
@@ -92,101 +142,136 @@ type MasrviPaymentRequest struct {
 	CallbackURL string                 `json:"callback_url"` // MASRVI specific: webhook URL
 	ReturnURL   string                 `json:"return_url"`   // MASRVI specific: return URL after payment
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Language    Language               `json:"language,omitempty"`
+	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`
+	// MerchantID takes the payment for this merchant instead of the
+	// configured merchant_id, as marketplaces paying sellers directly need
+	MerchantID string `json:"merchant_id,omitempty"`
+
+	// Brand is shown on the MASRVI payment page instead of the configured
+	// brand_name
+	Brand string `json:"brand,omitempty"`
+	// CustomerName prefills the customer's name on the payment page
+	CustomerName string `json:"customer_name,omitempty"`
+	// Text is extra text or instructions shown on the payment page
+	Text string `json:"text,omitempty"`
 }
 
-// Validate validates the MASRVI payment request
-func (r *MasrviPaymentRequest) Validate() error {
-	if err := r.validateBasicFields(); err != nil {
-		return err
-	}
-
-	if err := r.validateStringLengths(); err != nil {
-		return err
-	}
+// PaymentRequest.Metadata keys carrying the MasrviPaymentRequest fields the
+// generic request has no place for; generic MASRVI requests may set them too.
+// They are namespaced so that the caller's own metadata never reaches the
+// payment page.
+const (
+	MasrviMetadataBrand        = "masrvi.brand"
+	MasrviMetadataCustomerName = "masrvi.customer_name"
+	MasrviMetadataText         = "masrvi.text"
+)
 
-	if err := r.validateURLs(); err != nil {
-		return err
+// Validate validates the MASRVI payment request, returning ValidationErrors
+// with every failed field
+func (r *MasrviPaymentRequest) Validate() error {
+	if r == nil {
+		return ErrInvalidRequest
 	}
 
-	return nil
+	var errs ValidationErrors
+	r.validateBasicFields(&errs)
+	r.validateStringLengths(&errs)
+	r.validateURLs(&errs)
+	return errs.Err()
 }
 
-func (r *MasrviPaymentRequest) validateBasicFields() error {
+func (r *MasrviPaymentRequest) validateBasicFields(errs *ValidationErrors) {
 	if r.PhoneNumber == nil {
-		return fmt.Errorf("phone number is required")
+		errs.Add("phone_number", "is required")
 	}
 
 	if r.Amount.IsZero() {
-		return fmt.Errorf("amount must be positive")
+		errs.Add("amount", "must be positive")
 	}
-
-	return nil
 }
 
-func (r *MasrviPaymentRequest) validateStringLengths() error {
+func (r *MasrviPaymentRequest) validateStringLengths(errs *ValidationErrors) {
 	const (
-		maxDescriptionLength = 200
-		maxReferenceLength   = 50
+		maxDescriptionLength  = 200
+		maxCustomerNameLength = 100
+		maxTextLength         = 500
 	)
 
 	if strings.TrimSpace(r.Description) == "" {
-		return fmt.Errorf("description cannot be empty")
-	}
-
-	if len(r.Description) > maxDescriptionLength {
-		return fmt.Errorf("description cannot exceed %d characters", maxDescriptionLength)
+		errs.Add("description", "cannot be empty")
+	} else if len(r.Description) > maxDescriptionLength {
+		errs.Add("description", fmt.Sprintf("cannot exceed %d characters", maxDescriptionLength))
 	}
 
-	if strings.TrimSpace(r.Reference) == "" {
-		return fmt.Errorf("reference cannot be empty")
+	if len(r.CustomerName) > maxCustomerNameLength {
+		errs.Add("customer_name", fmt.Sprintf("cannot exceed %d characters", maxCustomerNameLength))
 	}
 
-	if len(r.Reference) > maxReferenceLength {
-		return fmt.Errorf("reference cannot exceed %d characters", maxReferenceLength)
+	if len(r.Text) > maxTextLength {
+		errs.Add("text", fmt.Sprintf("cannot exceed %d characters", maxTextLength))
 	}
 
-	return nil
+	validateReference(errs, ProviderMasrvi, r.Reference)
 }
 
-func (r *MasrviPaymentRequest) validateURLs() error {
-	if strings.TrimSpace(r.CallbackURL) == "" {
-		return fmt.Errorf("callback_url cannot be empty")
-	}
-
-	if _, err := url.Parse(r.CallbackURL); err != nil {
-		return fmt.Errorf("invalid callback_url: %w", err)
-	}
+func (r *MasrviPaymentRequest) validateURLs(errs *ValidationErrors) {
+	validateRequiredURL(errs, "callback_url", r.CallbackURL)
+	validateRequiredURL(errs, "return_url", r.ReturnURL)
+}
 
-	if strings.TrimSpace(r.ReturnURL) == "" {
-		return fmt.Errorf("return_url cannot be empty")
+// validateRequiredURL records a failure of field when value is empty or not
+// a URL
+func validateRequiredURL(errs *ValidationErrors, field, value string) {
+	if strings.TrimSpace(value) == "" {
+		errs.Add(field, "cannot be empty")
+		return
 	}
-
-	if _, err := url.Parse(r.ReturnURL); err != nil {
-		return fmt.Errorf("invalid return_url: %w", err)
+	if _, err := url.Parse(value); err != nil {
+		errs.Add(field, fmt.Sprintf("invalid URL: %v", err))
 	}
-
-	return nil
 }
 
-// ToGenericRequest converts MASRVI request to generic payment request
+// ToGenericRequest converts MASRVI request to generic payment request; a nil
+// request converts to nil
 func (r *MasrviPaymentRequest) ToGenericRequest() *PaymentRequest {
+	if r == nil {
+		return nil
+	}
 	metadata := make(map[string]interface{})
 	for k, v := range r.Metadata {
 		metadata[k] = v
 	}
 	metadata["callback_url"] = r.CallbackURL
 	metadata["return_url"] = r.ReturnURL
+	for key, value := range map[string]string{
+		MasrviMetadataBrand:        r.Brand,
+		MasrviMetadataCustomerName: r.CustomerName,
+		MasrviMetadataText:         r.Text,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
 
-	return &PaymentRequest{
+	request := &PaymentRequest{
 		PhoneNumber: r.PhoneNumber,
 		Amount:      r.Amount,
 		Description: r.Description,
 		Reference:   r.Reference,
+		Language:    r.Language,
+		ExpiresAt:   copyTime(r.ExpiresAt),
 		Metadata:    metadata,
 	}
+	if r.MerchantID != "" {
+		request.CredentialOverrides = map[string]string{CredentialMerchantID: r.MerchantID}
+	}
+	return request
 }
 
-// MasrviNotificationData represents MASRVI webhook notification
+// MasrviNotificationData represents MASRVI webhook notification. Amount is
+// what the customer paid; the MASRVI provider sets it from the amount and
+// currency parameters when zero.
 type MasrviNotificationData struct {
 	TransactionID string                 `json:"transaction_id"`
 	Status        string                 `json:"status"`
@@ -195,6 +280,12 @@ type MasrviNotificationData struct {
 	PhoneNumber   string                 `json:"phone_number"`
 	Timestamp     string                 `json:"timestamp"`
 	Data          map[string]interface{} `json:"data,omitempty"`
+
+	// Params are the parameters as received, which signature verification
+	// needs; ParseMasrviNotification sets them
+	Params url.Values `json:"-"`
+	// RemoteAddr is the address the notification came from, when known
+	RemoteAddr string `json:"-"`
 }
 
 // ClickPaymentRequest represents a CLICK (TagPay/BNM) specific payment request.
@@ -211,33 +302,37 @@ type ClickPaymentRequest struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// Validate validates the CLICK payment request.
+// Validate validates the CLICK payment request, returning ValidationErrors
+// with every failed field.
 func (r *ClickPaymentRequest) Validate() error {
-	if r.Amount.IsZero() {
-		return fmt.Errorf("amount must be positive")
+	if r == nil {
+		return ErrInvalidRequest
 	}
-	if strings.TrimSpace(r.Reference) == "" {
-		return fmt.Errorf("reference cannot be empty")
-	}
-	if len(r.Reference) > 250 {
-		return fmt.Errorf("reference cannot exceed 250 characters")
+	var errs ValidationErrors
+	if r.Amount.IsZero() {
+		errs.Add("amount", "must be positive")
 	}
+	validateReference(&errs, ProviderClick, r.Reference)
 	if len(r.Description) > 255 {
-		return fmt.Errorf("description cannot exceed 255 characters")
+		errs.Add("description", "cannot exceed 255 characters")
 	}
-	return nil
+	return errs.Err()
 }
 
 // GetLanguage returns the language with fallback to French.
 func (r *ClickPaymentRequest) GetLanguage() Language {
-	if r.Language == "" {
+	if r == nil || r.Language == "" {
 		return LanguageFrench
 	}
 	return r.Language
 }
 
 // ToGenericRequest converts CLICK request to the generic payment request.
+// A nil request converts to nil.
 func (r *ClickPaymentRequest) ToGenericRequest() *PaymentRequest {
+	if r == nil {
+		return nil
+	}
 	metadata := make(map[string]interface{})
 	for k, v := range r.Metadata {
 		metadata[k] = v
@@ -275,3 +370,100 @@ type ClickNotificationData struct {
 	Error       string `json:"error,omitempty"`
 	Reason      string `json:"reason,omitempty"`
 }
+
+// SedadPaymentRequest represents a Sedad invoice payment request
+type SedadPaymentRequest struct {
+	PhoneNumber   *phone.Phone           `json:"phone_number"`
+	Amount        money.Money            `json:"amount"`
+	Description   string                 `json:"description,omitempty"`
+	Reference     string                 `json:"reference"`
+	InvoiceNumber string                 `json:"invoice_number"` // Sedad specific: the biller's invoice number
+	BillerCode    string                 `json:"biller_code"`    // Sedad specific: the biller the invoice is paid to
+	CallbackURL   string                 `json:"callback_url,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Validate validates the Sedad payment request, returning ValidationErrors
+// with every failed field
+func (r *SedadPaymentRequest) Validate() error {
+	if r == nil {
+		return ErrInvalidRequest
+	}
+
+	const (
+		maxInvoiceNumberLength = 35
+		maxDescriptionLength   = 200
+	)
+
+	var errs ValidationErrors
+	if r.PhoneNumber == nil {
+		errs.Add("phone_number", "is required")
+	}
+
+	if r.Amount.IsZero() {
+		errs.Add("amount", "must be positive")
+	}
+
+	if len(r.Description) > maxDescriptionLength {
+		errs.Add("description", fmt.Sprintf("cannot exceed %d characters", maxDescriptionLength))
+	}
+
+	validateReference(&errs, ProviderSedad, r.Reference)
+
+	if strings.TrimSpace(r.InvoiceNumber) == "" {
+		errs.Add("invoice_number", "cannot be empty")
+	} else if len(r.InvoiceNumber) > maxInvoiceNumberLength {
+		errs.Add("invoice_number", fmt.Sprintf("cannot exceed %d characters", maxInvoiceNumberLength))
+	}
+
+	if strings.TrimSpace(r.BillerCode) == "" {
+		errs.Add("biller_code", "cannot be empty")
+	}
+
+	if r.CallbackURL != "" {
+		validateRequiredURL(&errs, "callback_url", r.CallbackURL)
+	}
+
+	return errs.Err()
+}
+
+// ToGenericRequest converts Sedad request to generic payment request, with
+// the invoice fields in its metadata; a nil request converts to nil
+func (r *SedadPaymentRequest) ToGenericRequest() *PaymentRequest {
+	if r == nil {
+		return nil
+	}
+	metadata := make(map[string]interface{})
+	for k, v := range r.Metadata {
+		metadata[k] = v
+	}
+	metadata["invoice_number"] = r.InvoiceNumber
+	metadata["biller_code"] = r.BillerCode
+	if r.CallbackURL != "" {
+		metadata["callback_url"] = r.CallbackURL
+	}
+
+	return &PaymentRequest{
+		PhoneNumber: r.PhoneNumber,
+		Amount:      r.Amount,
+		Description: r.Description,
+		Reference:   r.Reference,
+		Metadata:    metadata,
+	}
+}
+
+// SedadNotificationData is the JSON body Sedad posts to the callback URL
+// when a payment settles
+type SedadNotificationData struct {
+	PaymentID     string `json:"paymentId"`
+	Reference     string `json:"reference"`
+	InvoiceNumber string `json:"invoiceNumber"`
+	BillerCode    string `json:"billerCode"`
+	Status        string `json:"status"` // PAID, FAILED, CANCELLED, EXPIRED
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	CustomerPhone string `json:"customerPhone"`
+	PaidAt        string `json:"paidAt,omitempty"`
+	ResultCode    string `json:"resultCode,omitempty"`
+	ResultMessage string `json:"resultMessage,omitempty"`
+}
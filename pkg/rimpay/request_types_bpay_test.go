@@ -54,3 +54,70 @@ func TestBPayRequestRejectsNonFourDigitPasscode(t *testing.T) {
 		}
 	}
 }
+
+func TestBPayRequestForwardsOperationType(t *testing.T) {
+	req := newValidBPayRequest()
+	req.OperationType = BPayOperationBillPayment
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if got := req.ToGenericRequest().OperationType; got != BPayOperationBillPayment {
+		t.Errorf("operation type not forwarded: got %q want %q", got, BPayOperationBillPayment)
+	}
+}
+
+func TestBPayRequestRejectsUnknownOperationType(t *testing.T) {
+	req := newValidBPayRequest()
+	req.OperationType = "refund"
+	if err := req.Validate(); err == nil {
+		t.Error("expected validation error for unknown operation type, got nil")
+	}
+}
+
+func TestBPayRequestUSSDPushTakesNoPasscode(t *testing.T) {
+	req := newValidBPayRequest()
+	req.Mode = BPayModeUSSDPush
+	req.Passcode = ""
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if got := req.ToGenericRequest().Mode; got != BPayModeUSSDPush {
+		t.Errorf("mode not forwarded: got %q want %q", got, BPayModeUSSDPush)
+	}
+
+	req.Passcode = "1234"
+	if err := req.Validate(); err == nil {
+		t.Error("expected validation error for a passcode in ussd_push mode, got nil")
+	}
+}
+
+func TestBPayRequestRejectsUnknownMode(t *testing.T) {
+	req := newValidBPayRequest()
+	req.Mode = "sms"
+	err := req.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	if got := errs.Fields()["mode"]; got != `unknown mode "sms"` {
+		t.Errorf("mode error = %q", got)
+	}
+}
+
+func TestBPayOperationTypeLabel(t *testing.T) {
+	tests := []struct {
+		op   BPayOperationType
+		lang Language
+		want string
+	}{
+		{"", LanguageEnglish, "Payment"},
+		{BPayOperationBillPayment, LanguageFrench, "Paiement de facture"},
+		{BPayOperationMerchantPayment, LanguageArabic, "دفع التاجر"},
+		{BPayOperationMerchantPayment, "", "Paiement marchand"},
+	}
+	for _, tt := range tests {
+		if got := tt.op.Label(tt.lang); got != tt.want {
+			t.Errorf("%q.Label(%q) = %q, want %q", tt.op, tt.lang, got, tt.want)
+		}
+	}
+}
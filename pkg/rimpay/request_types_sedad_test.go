@@ -0,0 +1,53 @@
+package rimpay
+
+import (
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidSedadRequest() *SedadPaymentRequest {
+	p, _ := phone.NewPhone("+22220000000")
+	return &SedadPaymentRequest{
+		PhoneNumber:   p,
+		Amount:        money.FromFloat64(1250.00, money.MRU),
+		Description:   "Electricity bill",
+		Reference:     "ORDER-1",
+		InvoiceNumber: "INV-2024-0042",
+		BillerCode:    "SOMELEC",
+	}
+}
+
+func TestSedadRequestValid(t *testing.T) {
+	assert.NoError(t, newValidSedadRequest().Validate())
+}
+
+func TestSedadRequestRequiresInvoiceFields(t *testing.T) {
+	request := newValidSedadRequest()
+	request.InvoiceNumber = "INV-0123456789-0123456789-0123456789"
+	request.BillerCode = " "
+	request.CallbackURL = "://bad"
+
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	assert.Equal(t, []string{"invoice_number", "biller_code", "callback_url"}, []string{errs[0].Field, errs[1].Field, errs[2].Field})
+	assert.Equal(t, "cannot exceed 35 characters", errs.Fields()["invoice_number"])
+	assert.Equal(t, "cannot be empty", errs.Fields()["biller_code"])
+}
+
+func TestSedadRequestToGeneric(t *testing.T) {
+	request := newValidSedadRequest()
+	request.Metadata = map[string]interface{}{"order": "42"}
+
+	generic := request.ToGenericRequest()
+	assert.Equal(t, "ORDER-1", generic.Reference)
+	assert.Equal(t, map[string]interface{}{
+		"order":          "42",
+		"invoice_number": "INV-2024-0042",
+		"biller_code":    "SOMELEC",
+	}, generic.Metadata)
+	assert.Nil(t, (*SedadPaymentRequest)(nil).ToGenericRequest())
+}
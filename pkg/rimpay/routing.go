@@ -0,0 +1,57 @@
+package rimpay
+
+import (
+	"context"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// SuggestProvider returns the provider preferred for p's operator in
+// Config.Routing, or DefaultProvider when the operator is not mapped or its
+// provider is not registered. Ported numbers are honoured when a
+// portability resolver is configured.
+func (c *Client) SuggestProvider(p *phone.Phone) string {
+	return c.suggestProvider(context.Background(), p)
+}
+
+func (c *Client) suggestProvider(ctx context.Context, p *phone.Phone) string {
+	config := c.currentConfig()
+	if p == nil || len(config.Routing.Operators) == 0 {
+		return config.DefaultProvider
+	}
+
+	name, ok := config.Routing.Operators[c.ResolveOperator(ctx, p)]
+	if !ok {
+		return config.DefaultProvider
+	}
+
+	if _, registered := c.registered(name); !registered {
+		return config.DefaultProvider
+	}
+	return name
+}
+
+// routedProvider picks the provider for a generic payment: the operator's
+// preferred provider when RouteByOperator is set, otherwise the default
+func (c *Client) routedProvider(ctx context.Context, request *PaymentRequest) PaymentProvider {
+	if !c.currentConfig().Routing.RouteByOperator {
+		return c.defaultProvider()
+	}
+
+	name := c.suggestProvider(ctx, request.PhoneNumber)
+	provider, ok := c.registered(name)
+	if !ok {
+		return c.defaultProvider()
+	}
+	return provider
+}
+
+// isKnownOperator reports whether op is a routable operator
+func isKnownOperator(op phone.Operator) bool {
+	for _, known := range phone.AllOperators() {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package rimpay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoutingTestClient(t *testing.T, routing RoutingConfig) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	for _, name := range []string{ProviderBPay, ProviderMasrvi} {
+		config.Providers[name] = ProviderConfig{Enabled: true, BaseURL: "https://" + name + ".test", Timeout: time.Second}
+	}
+	config.Routing = routing
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	for _, name := range []string{ProviderBPay, ProviderMasrvi} {
+		require.NoError(t, client.AddProvider(name, &namedProvider{name: name}))
+	}
+	return client
+}
+
+func TestSuggestProvider(t *testing.T) {
+	client := newRoutingTestClient(t, RoutingConfig{
+		Operators: map[phone.Operator]string{
+			phone.OperatorChinguitel: ProviderMasrvi,
+			phone.OperatorMauritel:   ProviderBPay,
+		},
+	})
+
+	tests := []struct {
+		name     string
+		number   string
+		operator phone.Operator
+		want     string
+	}{
+		{"mauritel", "22334455", phone.OperatorMauritel, ProviderBPay},
+		{"chinguitel", "33445566", phone.OperatorChinguitel, ProviderMasrvi},
+		{"mattel unmapped", "44556677", phone.OperatorMattel, ProviderBPay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := phone.NewPhone(tt.number)
+			require.NoError(t, err)
+			require.Equal(t, tt.operator, p.Operator())
+
+			assert.Equal(t, tt.want, client.SuggestProvider(p))
+		})
+	}
+
+	assert.Equal(t, ProviderBPay, client.SuggestProvider(nil))
+}
+
+func TestSuggestProviderSkipsUnregisteredProvider(t *testing.T) {
+	client := newRoutingTestClient(t, RoutingConfig{
+		Operators: map[phone.Operator]string{phone.OperatorChinguitel: ProviderMasrvi},
+	})
+	client.providers = map[string]PaymentProvider{ProviderBPay: &namedProvider{name: ProviderBPay}}
+
+	p, err := phone.NewPhone("33445566")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderBPay, client.SuggestProvider(p))
+}
+
+func TestProcessPaymentRoutesByOperator(t *testing.T) {
+	routing := RoutingConfig{
+		Operators: map[phone.Operator]string{phone.OperatorChinguitel: ProviderMasrvi},
+	}
+	p, err := phone.NewPhone("33445566")
+	require.NoError(t, err)
+	request := &PaymentRequest{PhoneNumber: p, Reference: "REF-1"}
+
+	client := newRoutingTestClient(t, routing)
+	resp, err := client.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderBPay, resp.TransactionID, "routing is opt-in")
+
+	routing.RouteByOperator = true
+	client = newRoutingTestClient(t, routing)
+	resp, err = client.ProcessPayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderMasrvi, resp.TransactionID)
+}
+
+func TestValidateRejectsInvalidRouting(t *testing.T) {
+	tests := []struct {
+		name      string
+		operators map[phone.Operator]string
+		wantErr   string
+	}{
+		{"unknown operator", map[phone.Operator]string{"orange": ProviderBPay}, "unknown operator 'orange'"},
+		{"unknown provider", map[phone.Operator]string{phone.OperatorMattel: ProviderClick}, "provider 'click' for operator 'mattel' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+			config.Routing.Operators = tt.operators
+
+			err := config.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
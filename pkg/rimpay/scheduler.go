@@ -0,0 +1,368 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+)
+
+// DefaultSchedulerInterval is how often the scheduler looks for due payments
+const DefaultSchedulerInterval = time.Second
+
+// ScheduledPaymentID identifies a payment scheduled with SchedulePayment
+type ScheduledPaymentID string
+
+// ScheduleState is the state of a scheduled payment
+type ScheduleState string
+
+const (
+	// ScheduleStatePending waits for its execution time
+	ScheduleStatePending ScheduleState = "pending"
+	// ScheduleStateRunning is being processed by the scheduler
+	ScheduleStateRunning ScheduleState = "running"
+	// ScheduleStateExecuted was processed; Response holds the result
+	ScheduleStateExecuted ScheduleState = "executed"
+	// ScheduleStateFailed was processed and failed; Error holds the reason
+	ScheduleStateFailed ScheduleState = "failed"
+	// ScheduleStateExpired reached its ExpiresAt before it could run
+	ScheduleStateExpired ScheduleState = "expired"
+	// ScheduleStateCancelled was cancelled with CancelScheduledPayment
+	ScheduleStateCancelled ScheduleState = "cancelled"
+)
+
+// ScheduledPayment is a payment waiting for, or done with, its execution
+type ScheduledPayment struct {
+	ID          ScheduledPaymentID `json:"id"`
+	Request     *PaymentRequest    `json:"request"`
+	ExecuteAt   time.Time          `json:"execute_at"`
+	State       ScheduleState      `json:"state"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt time.Time          `json:"completed_at,omitempty"`
+	Response    *PaymentResponse   `json:"response,omitempty"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// copy returns a copy of the scheduled payment that shares no request
+// metadata with it
+func (p *ScheduledPayment) copy() *ScheduledPayment {
+	dup := *p
+	dup.Request = copyPaymentRequest(p.Request)
+	return &dup
+}
+
+// copyPaymentRequest copies request and its metadata
+func copyPaymentRequest(request *PaymentRequest) *PaymentRequest {
+	if request == nil {
+		return nil
+	}
+	dup := *request
+	if request.Metadata != nil {
+		dup.Metadata = make(map[string]interface{}, len(request.Metadata))
+		for key, value := range request.Metadata {
+			dup.Metadata[key] = value
+		}
+	}
+	if request.CredentialOverrides != nil {
+		dup.CredentialOverrides = make(map[string]string, len(request.CredentialOverrides))
+		for key, value := range request.CredentialOverrides {
+			dup.CredentialOverrides[key] = value
+		}
+	}
+	return &dup
+}
+
+// ScheduleStore keeps scheduled payments. Requests are stored as given,
+// B-PAY passcodes included, so persistent stores should protect them.
+//
+// Implementations must be safe for concurrent use. Update is a
+// compare-and-set on State, which lets several scheduler instances share a
+// store without running a payment twice.
+type ScheduleStore interface {
+	// Add stores a newly scheduled payment
+	Add(ctx context.Context, payment *ScheduledPayment) error
+	// Get returns the scheduled payment, or ErrScheduledPaymentNotFound
+	Get(ctx context.Context, id ScheduledPaymentID) (*ScheduledPayment, error)
+	// List returns every scheduled payment, earliest ExecuteAt first
+	List(ctx context.Context) ([]*ScheduledPayment, error)
+	// Due returns the pending payments whose ExecuteAt is not after now,
+	// earliest first
+	Due(ctx context.Context, now time.Time) ([]*ScheduledPayment, error)
+	// Update replaces the payment with the same ID if its stored state is
+	// still from, else fails with ErrScheduleConflict
+	Update(ctx context.Context, payment *ScheduledPayment, from ScheduleState) error
+}
+
+// MemoryScheduleStore is an in-process ScheduleStore, for tests and
+// single-instance deployments
+type MemoryScheduleStore struct {
+	mu       sync.Mutex
+	payments map[ScheduledPaymentID]*ScheduledPayment
+}
+
+// NewMemoryScheduleStore creates an empty in-memory store
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{payments: make(map[ScheduledPaymentID]*ScheduledPayment)}
+}
+
+// Add stores payment
+func (s *MemoryScheduleStore) Add(_ context.Context, payment *ScheduledPayment) error {
+	if payment == nil || payment.ID == "" {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.payments[payment.ID]; ok {
+		return fmt.Errorf("scheduled payment %s already exists: %w", payment.ID, ErrScheduleConflict)
+	}
+	s.payments[payment.ID] = payment.copy()
+	return nil
+}
+
+// Get returns the scheduled payment
+func (s *MemoryScheduleStore) Get(_ context.Context, id ScheduledPaymentID) (*ScheduledPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if payment, ok := s.payments[id]; ok {
+		return payment.copy(), nil
+	}
+	return nil, ErrScheduledPaymentNotFound
+}
+
+// List returns every scheduled payment, earliest first
+func (s *MemoryScheduleStore) List(_ context.Context) ([]*ScheduledPayment, error) {
+	return s.collect(func(*ScheduledPayment) bool { return true }), nil
+}
+
+// Due returns the pending payments due at now, earliest first
+func (s *MemoryScheduleStore) Due(_ context.Context, now time.Time) ([]*ScheduledPayment, error) {
+	return s.collect(func(payment *ScheduledPayment) bool {
+		return payment.State == ScheduleStatePending && !payment.ExecuteAt.After(now)
+	}), nil
+}
+
+// collect returns copies of the payments matching keep, earliest first
+func (s *MemoryScheduleStore) collect(keep func(*ScheduledPayment) bool) []*ScheduledPayment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var payments []*ScheduledPayment
+	for _, payment := range s.payments {
+		if keep(payment) {
+			payments = append(payments, payment.copy())
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if payments[i].ExecuteAt.Equal(payments[j].ExecuteAt) {
+			return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+		}
+		return payments[i].ExecuteAt.Before(payments[j].ExecuteAt)
+	})
+	return payments
+}
+
+// Update replaces payment if its stored state is from
+func (s *MemoryScheduleStore) Update(_ context.Context, payment *ScheduledPayment, from ScheduleState) error {
+	if payment == nil {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.payments[payment.ID]
+	if !ok {
+		return ErrScheduledPaymentNotFound
+	}
+	if stored.State != from {
+		return fmt.Errorf("scheduled payment %s is %s, not %s: %w", payment.ID, stored.State, from, ErrScheduleConflict)
+	}
+	s.payments[payment.ID] = payment.copy()
+	return nil
+}
+
+// paymentScheduler holds the runner state of StartScheduler
+type paymentScheduler struct {
+	interval time.Duration
+	now      func() time.Time
+	after    func(time.Duration) <-chan time.Time
+	running  uint32
+}
+
+func newPaymentScheduler() *paymentScheduler {
+	return &paymentScheduler{interval: DefaultSchedulerInterval, now: time.Now, after: time.After}
+}
+
+// WithScheduleStore makes the client keep scheduled payments in store
+// instead of in memory. Payments already scheduled are not moved.
+func (c *Client) WithScheduleStore(store ScheduleStore) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if store == nil {
+		store = NewMemoryScheduleStore()
+	}
+	c.schedules = store
+	return c
+}
+
+func (c *Client) scheduleStore() ScheduleStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.schedules
+}
+
+// SchedulePayment stores request to be processed with ProcessPayment at
+// executeAt, or at the request's NotBefore when that is later. Payments run
+// once StartScheduler is called; one whose ExpiresAt passes first is marked
+// expired instead. The stored request is a copy with NotBefore set.
+func (c *Client) SchedulePayment(ctx context.Context, request *PaymentRequest, executeAt time.Time) (ScheduledPaymentID, error) {
+	if c.isClosed() {
+		return "", ErrClientClosed
+	}
+	if request == nil || executeAt.IsZero() {
+		return "", ErrInvalidRequest
+	}
+
+	scheduled := copyPaymentRequest(request)
+	if scheduled.NotBefore != nil && scheduled.NotBefore.After(executeAt) {
+		executeAt = *scheduled.NotBefore
+	}
+	if scheduled.ExpiresAt != nil && scheduled.ExpiresAt.Before(executeAt) {
+		return "", NewValidationError("expires_at", "is before the scheduled execution time")
+	}
+	scheduled.NotBefore = &executeAt
+
+	id, err := common.GenerateTransactionID("SCH")
+	if err != nil {
+		return "", err
+	}
+	payment := &ScheduledPayment{
+		ID:        ScheduledPaymentID(id),
+		Request:   scheduled,
+		ExecuteAt: executeAt,
+		State:     ScheduleStatePending,
+		CreatedAt: c.scheduler.now(),
+	}
+	if err := c.scheduleStore().Add(ctx, payment); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Payment scheduled", "id", payment.ID, "reference", request.Reference, "execute_at", executeAt)
+	return payment.ID, nil
+}
+
+// CancelScheduledPayment cancels a payment that has not run yet. Payments
+// that already ran, expired or are running fail with ErrScheduleConflict.
+func (c *Client) CancelScheduledPayment(ctx context.Context, id ScheduledPaymentID) error {
+	store := c.scheduleStore()
+	payment, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if payment.State != ScheduleStatePending {
+		return fmt.Errorf("cannot cancel scheduled payment %s: it is %s: %w", id, payment.State, ErrScheduleConflict)
+	}
+
+	payment.State = ScheduleStateCancelled
+	payment.CompletedAt = c.scheduler.now()
+	return store.Update(ctx, payment, ScheduleStatePending)
+}
+
+// GetScheduledPayment returns a scheduled payment, or
+// ErrScheduledPaymentNotFound
+func (c *Client) GetScheduledPayment(ctx context.Context, id ScheduledPaymentID) (*ScheduledPayment, error) {
+	return c.scheduleStore().Get(ctx, id)
+}
+
+// ListScheduledPayments returns every scheduled payment, earliest first
+func (c *Client) ListScheduledPayments(ctx context.Context) ([]*ScheduledPayment, error) {
+	return c.scheduleStore().List(ctx)
+}
+
+// StartScheduler starts processing scheduled payments and subscription
+// cycles as they fall due, checking every DefaultSchedulerInterval. It returns at once; the runner
+// stops when ctx is done or the client is closed. Starting a second runner
+// on the same client fails with ErrSchedulerRunning.
+func (c *Client) StartScheduler(ctx context.Context) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	scheduler := c.scheduler
+	if !atomic.CompareAndSwapUint32(&scheduler.running, 0, 1) {
+		return ErrSchedulerRunning
+	}
+
+	go func() {
+		defer atomic.StoreUint32(&scheduler.running, 0)
+		for ctx.Err() == nil && !c.isClosed() {
+			c.runScheduledPayments(ctx)
+			c.runSubscriptions(ctx)
+			select {
+			case <-ctx.Done():
+			case <-scheduler.after(scheduler.interval):
+			}
+		}
+	}()
+	return nil
+}
+
+// runScheduledPayments runs the payments due now
+func (c *Client) runScheduledPayments(ctx context.Context) {
+	store := c.scheduleStore()
+	now := c.scheduler.now()
+	due, err := store.Due(ctx, now)
+	if err != nil {
+		c.logger.Error("Failed to list due scheduled payments", "error", err)
+		return
+	}
+
+	for _, payment := range due {
+		if ctx.Err() != nil || c.isClosed() {
+			return
+		}
+		c.runScheduledPayment(ctx, store, payment, now)
+	}
+}
+
+// runScheduledPayment claims payment and processes it, unless it expired
+func (c *Client) runScheduledPayment(ctx context.Context, store ScheduleStore, payment *ScheduledPayment, now time.Time) {
+	if payment.Request.ExpiredAt(now) {
+		payment.State = ScheduleStateExpired
+		payment.CompletedAt = now
+		if err := store.Update(ctx, payment, ScheduleStatePending); err == nil {
+			c.logger.Warn("Scheduled payment expired before it ran", "id", payment.ID, "reference", payment.Request.Reference)
+		} else if !errors.Is(err, ErrScheduleConflict) {
+			c.logger.Error("Failed to expire scheduled payment", "id", payment.ID, "error", err)
+		}
+		return
+	}
+
+	// Claiming fails when the payment was cancelled or claimed by another
+	// runner since it was listed
+	payment.State = ScheduleStateRunning
+	if err := store.Update(ctx, payment, ScheduleStatePending); err != nil {
+		if !errors.Is(err, ErrScheduleConflict) {
+			c.logger.Error("Failed to claim scheduled payment", "id", payment.ID, "error", err)
+		}
+		return
+	}
+
+	response, err := c.ProcessPayment(ctx, copyPaymentRequest(payment.Request))
+	payment.CompletedAt = c.scheduler.now()
+	if err != nil {
+		payment.State = ScheduleStateFailed
+		payment.Error = err.Error()
+		c.logger.Warn("Scheduled payment failed", "id", payment.ID, "reference", payment.Request.Reference, "error", err)
+	} else {
+		payment.State = ScheduleStateExecuted
+		payment.Response = response
+		c.logger.Info("Scheduled payment executed", "id", payment.ID, "reference", payment.Request.Reference)
+	}
+	if err := store.Update(ctx, payment, ScheduleStateRunning); err != nil {
+		c.logger.Error("Failed to record scheduled payment result", "id", payment.ID, "error", err)
+	}
+}
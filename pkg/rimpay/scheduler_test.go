@@ -0,0 +1,197 @@
+package rimpay
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScheduleClock drives the scheduler: the runner signals waiting each
+// time it sleeps, and wakes up on tick
+type fakeScheduleClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiting chan struct{}
+	ticks   chan time.Time
+}
+
+func newFakeScheduleClock(client *Client, now time.Time) *fakeScheduleClock {
+	clock := &fakeScheduleClock{now: now, waiting: make(chan struct{}), ticks: make(chan time.Time)}
+	client.scheduler.now = clock.Now
+	client.scheduler.after = func(time.Duration) <-chan time.Time {
+		clock.waiting <- struct{}{}
+		return clock.ticks
+	}
+	return clock
+}
+
+func (c *fakeScheduleClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// advance moves the clock forward and lets the runner complete one pass
+func (c *fakeScheduleClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.mu.Unlock()
+	c.ticks <- now
+	<-c.waiting
+}
+
+func newSchedulerTestClient(t *testing.T) (*Client, *fakeScheduleClock) {
+	t.Helper()
+	client := newOrderingTestClient(t, ProviderBPay, ProviderBPay)
+	return client, newFakeScheduleClock(client, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+}
+
+func startScheduler(t *testing.T, client *Client, clock *fakeScheduleClock) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, client.StartScheduler(ctx))
+	<-clock.waiting
+}
+
+func scheduledState(t *testing.T, client *Client, id ScheduledPaymentID) *ScheduledPayment {
+	t.Helper()
+	payment, err := client.GetScheduledPayment(context.Background(), id)
+	require.NoError(t, err)
+	return payment
+}
+
+func TestScheduledPaymentRunsWhenDue(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	ctx := context.Background()
+
+	request := failoverRequest()
+	id, err := client.SchedulePayment(ctx, request, clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, request.NotBefore, "the caller's request is not modified")
+
+	startScheduler(t, client, clock)
+	assert.Equal(t, ScheduleStatePending, scheduledState(t, client, id).State, "not due yet")
+
+	clock.advance(30 * time.Second)
+	assert.Equal(t, ScheduleStatePending, scheduledState(t, client, id).State)
+
+	clock.advance(30 * time.Second)
+	payment := scheduledState(t, client, id)
+	assert.Equal(t, ScheduleStateExecuted, payment.State)
+	require.NotNil(t, payment.Response)
+	assert.Equal(t, ProviderBPay, payment.Response.TransactionID)
+	assert.Equal(t, clock.Now(), payment.CompletedAt)
+	require.NotNil(t, payment.Request.NotBefore)
+	assert.Equal(t, payment.ExecuteAt, *payment.Request.NotBefore)
+
+	clock.advance(time.Minute)
+	assert.Equal(t, ScheduleStateExecuted, scheduledState(t, client, id).State, "payments run once")
+}
+
+func TestScheduledPaymentExpiresBeforeRunning(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	ctx := context.Background()
+
+	request := failoverRequest()
+	expiresAt := clock.Now().Add(2 * time.Minute)
+	request.ExpiresAt = &expiresAt
+	id, err := client.SchedulePayment(ctx, request, clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	// The scheduler was down past the expiry
+	clock.mu.Lock()
+	clock.now = clock.now.Add(5 * time.Minute)
+	clock.mu.Unlock()
+	startScheduler(t, client, clock)
+
+	payment := scheduledState(t, client, id)
+	assert.Equal(t, ScheduleStateExpired, payment.State)
+	assert.Nil(t, payment.Response)
+	assert.Equal(t, 1, client.logger.(*recordingLogger).count("Scheduled payment expired before it ran"))
+}
+
+func TestSchedulePaymentRejectsExpiryBeforeExecution(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	ctx := context.Background()
+
+	request := failoverRequest()
+	expiresAt := clock.Now().Add(time.Minute)
+	request.ExpiresAt = &expiresAt
+	_, err := client.SchedulePayment(ctx, request, clock.Now().Add(time.Hour))
+	var paymentErr *PaymentError
+	require.ErrorAs(t, err, &paymentErr)
+	assert.Equal(t, "expires_at", paymentErr.Details["field"])
+
+	_, err = client.SchedulePayment(ctx, nil, clock.Now())
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	// A later NotBefore on the request wins over executeAt
+	request = failoverRequest()
+	notBefore := clock.Now().Add(time.Hour)
+	request.NotBefore = &notBefore
+	id, err := client.SchedulePayment(ctx, request, clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, notBefore, scheduledState(t, client, id).ExecuteAt)
+}
+
+func TestCancelScheduledPayment(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	ctx := context.Background()
+
+	cancelled, err := client.SchedulePayment(ctx, failoverRequest(), clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+	kept, err := client.SchedulePayment(ctx, failoverRequest(), clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	require.NoError(t, client.CancelScheduledPayment(ctx, cancelled))
+	startScheduler(t, client, clock)
+	clock.advance(time.Minute)
+
+	assert.Equal(t, ScheduleStateCancelled, scheduledState(t, client, cancelled).State)
+	assert.Equal(t, ScheduleStateExecuted, scheduledState(t, client, kept).State)
+	assert.ErrorIs(t, client.CancelScheduledPayment(ctx, kept), ErrScheduleConflict)
+	assert.ErrorIs(t, client.CancelScheduledPayment(ctx, "SCH_missing"), ErrScheduledPaymentNotFound)
+}
+
+func TestListScheduledPaymentsEarliestFirst(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	ctx := context.Background()
+
+	late, err := client.SchedulePayment(ctx, failoverRequest(), clock.Now().Add(time.Hour))
+	require.NoError(t, err)
+	early, err := client.SchedulePayment(ctx, failoverRequest(), clock.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	payments, err := client.ListScheduledPayments(ctx)
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+	assert.Equal(t, early, payments[0].ID)
+	assert.Equal(t, late, payments[1].ID)
+}
+
+func TestStartSchedulerOnce(t *testing.T) {
+	client, clock := newSchedulerTestClient(t)
+	startScheduler(t, client, clock)
+	assert.ErrorIs(t, client.StartScheduler(context.Background()), ErrSchedulerRunning)
+}
+
+func TestMemoryScheduleStoreUpdateIsCompareAndSet(t *testing.T) {
+	store := NewMemoryScheduleStore()
+	ctx := context.Background()
+	payment := &ScheduledPayment{ID: "SCH_1", Request: failoverRequest(), State: ScheduleStatePending}
+	require.NoError(t, store.Add(ctx, payment))
+	assert.ErrorIs(t, store.Add(ctx, payment), ErrScheduleConflict)
+
+	payment.State = ScheduleStateRunning
+	require.NoError(t, store.Update(ctx, payment, ScheduleStatePending))
+	assert.ErrorIs(t, store.Update(ctx, payment, ScheduleStatePending), ErrScheduleConflict, "a second claim fails")
+
+	payment.ID = "SCH_2"
+	assert.ErrorIs(t, store.Update(ctx, payment, ScheduleStatePending), ErrScheduledPaymentNotFound)
+}
@@ -0,0 +1,193 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/internal/validation"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// MetadataSimulated is set to true on the responses of Config.DryRun
+// payments, which never reach the provider
+const MetadataSimulated = "simulated"
+
+// SimulationRequest is a payment to simulate with SimulatePayment, for
+// callers that need more than the request itself
+type SimulationRequest struct {
+	// Request is a *PaymentRequest or a provider request such as
+	// *BPayPaymentRequest
+	Request interface{}
+	// Provider sends a *PaymentRequest to this provider instead of the one
+	// ProcessPayment would route it to
+	Provider string
+	// ProbeAvailability asks the provider whether it is available, which
+	// may call its API
+	ProbeAvailability bool
+}
+
+// SimulationResult is what a payment would do if it were sent
+type SimulationResult struct {
+	// Provider would receive the payment; empty when none could be chosen
+	Provider string
+	// Request is the payment in its generic form
+	Request *PaymentRequest
+	// Fee is what the provider's FeeSchedule charges on the amount; zero
+	// without one
+	Fee money.Money
+	// Available is the result of the availability probe, nil when the
+	// provider was not probed
+	Available *bool
+	// Errors are the reasons the payment would be rejected, such as
+	// ValidationErrors or an AMOUNT_OUT_OF_RANGE *PaymentError
+	Errors []error
+}
+
+// Valid reports whether the payment would be sent to the provider
+func (r *SimulationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *SimulationResult) fail(err error) {
+	if err != nil {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+// SimulatePayment runs the checks a payment goes through without sending
+// it: request validation, provider selection, supported currencies, amount
+// limits and the reference policy, plus the availability probe when asked
+// for. request is a *PaymentRequest, a provider request such as
+// *MasrviPaymentRequest, or a *SimulationRequest. Rejections are reported
+// in the result; the error is only set for requests that cannot be
+// simulated at all.
+func (c *Client) SimulatePayment(ctx context.Context, request interface{}) (*SimulationResult, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	simulation, ok := request.(*SimulationRequest)
+	if !ok {
+		simulation = &SimulationRequest{Request: request}
+	}
+	if simulation == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	var typed providerRequest
+	var name string
+	switch r := simulation.Request.(type) {
+	case *PaymentRequest:
+		if r == nil {
+			return nil, ErrInvalidRequest
+		}
+	case *BPayPaymentRequest:
+		if r == nil {
+			return nil, ErrInvalidRequest
+		}
+		typed, name = r, ProviderBPay
+	case *MasrviPaymentRequest:
+		if r == nil {
+			return nil, ErrInvalidRequest
+		}
+		typed, name = r, ProviderMasrvi
+	case *ClickPaymentRequest:
+		if r == nil {
+			return nil, ErrInvalidRequest
+		}
+		typed, name = r, ProviderClick
+	case *SedadPaymentRequest:
+		if r == nil {
+			return nil, ErrInvalidRequest
+		}
+		typed, name = r, ProviderSedad
+	default:
+		return nil, fmt.Errorf("%w: cannot simulate a %T", ErrInvalidRequest, simulation.Request)
+	}
+
+	result := &SimulationResult{}
+	var provider PaymentProvider
+	if typed == nil {
+		request := simulation.Request.(*PaymentRequest)
+		result.Request = request
+		validator := validation.NewValidatorWithOptions(validation.Options{Expiry: c.expiryWindow(), Now: c.scheduler.now})
+		result.fail(validator.ValidatePaymentRequest(request))
+		if simulation.Provider != "" {
+			var err error
+			if provider, err = c.provider(simulation.Provider); err != nil {
+				return nil, err
+			}
+		} else if provider = c.routedProvider(ctx, request); provider == nil {
+			return nil, ErrProviderNotFound
+		}
+	} else {
+		result.Request = typed.ToGenericRequest()
+		result.fail(typed.Validate())
+		var errs ValidationErrors
+		c.checkExpiry(&errs, result.Request.ExpiresAt)
+		result.fail(errs.Err())
+		var ok bool
+		if provider, ok = c.registered(name); !ok {
+			result.fail(fmt.Errorf(providerNotAvailableMsg, name))
+			return result, nil
+		}
+	}
+
+	name = provider.Name()
+	result.Provider = name
+	if !provider.Capabilities().accepts(result.Request) {
+		result.fail(NewValidationError("passcode", fmt.Sprintf("%s does not take passcode payments", name)))
+	}
+	result.fail(c.checkCurrency(name, result.Request.Amount))
+	result.fail(c.checkAmount(name, result.Request.Amount))
+	result.fail(c.checkReference(name, result.Request.Reference))
+	var errs ValidationErrors
+	if description := c.checkDescription(&errs, name, result.Request.Description); description != result.Request.Description {
+		sanitized := *result.Request
+		sanitized.Description = description
+		result.Request = &sanitized
+	}
+	result.fail(errs.Err())
+
+	if config, ok := c.providerConfig(name); ok && config.Fees != nil {
+		fee, err := config.Fees.Fee(result.Request.Amount)
+		result.fail(err)
+		result.Fee = fee
+	}
+
+	if simulation.ProbeAvailability {
+		available := provider.IsAvailable(ctx)
+		result.Available = &available
+		if !available {
+			result.fail(fmt.Errorf("provider %s is not available", name))
+		}
+	}
+	return result, nil
+}
+
+// providerRequest is implemented by the provider request types
+type providerRequest interface {
+	Validate() error
+	ToGenericRequest() *PaymentRequest
+}
+
+// simulatedPayment is the pending response of a Config.DryRun payment
+func (c *Client) simulatedPayment(provider, reference string, amount money.Money) (*PaymentResponse, error) {
+	id, err := common.GenerateTransactionID("SIM")
+	if err != nil {
+		return nil, err
+	}
+	at := c.scheduler.now()
+	return &PaymentResponse{
+		TransactionID: id,
+		Status:        PaymentStatusPending,
+		Amount:        amount,
+		Reference:     reference,
+		Provider:      provider,
+		CreatedAt:     at,
+		UpdatedAt:     at,
+		Metadata:      map[string]interface{}{MetadataSimulated: true},
+		Events:        []StatusEvent{{Status: PaymentStatusPending, Message: "Simulated payment", At: at, Source: EventSourceInitial}},
+	}, nil
+}
@@ -0,0 +1,108 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableProvider reports itself unavailable
+type unavailableProvider struct {
+	failingProvider
+}
+
+func (p *unavailableProvider) IsAvailable(context.Context) bool { return false }
+
+func simulationRequest(t *testing.T, cents int64) *PaymentRequest {
+	t.Helper()
+	number, err := phone.NewPhone("+22220000000")
+	require.NoError(t, err)
+	return &PaymentRequest{PhoneNumber: number, Amount: money.FromCents(cents, money.MRU), Reference: "REF-1"}
+}
+
+func TestSimulatePayment(t *testing.T) {
+	provider := &failingProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	client := newFailoverTestClient(t, nil, provider)
+	config := client.currentConfig().Providers[ProviderBPay]
+	config.MaxAmount = 100000
+	config.Fees = &money.FeeSchedule{Percentage: decimal.NewFromFloat(1.5), Fixed: money.NewMRU(200)}
+	client.config.Providers[ProviderBPay] = config
+
+	result, err := client.SimulatePayment(context.Background(), simulationRequest(t, 10000))
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors)
+	assert.Equal(t, ProviderBPay, result.Provider)
+	assert.True(t, result.Fee.Equals(money.NewMRU(350)), "1.5%% + 2 MRU of 100 MRU, got %s", result.Fee)
+	assert.Nil(t, result.Available, "not probed unless asked")
+
+	request := simulationRequest(t, 200000)
+	request.Reference = ""
+	request.PhoneNumber = nil
+	result, err = client.SimulatePayment(context.Background(), request)
+	require.NoError(t, err)
+	assert.False(t, result.Valid())
+	var validationErrs ValidationErrors
+	require.True(t, errors.As(result.Errors[0], &validationErrs))
+	assert.Contains(t, validationErrs.Fields(), "phone_number")
+	var paymentErr *PaymentError
+	require.True(t, errors.As(result.Errors[1], &paymentErr))
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+
+	assert.Zero(t, provider.calls, "simulations never reach the provider")
+}
+
+func TestSimulateProviderRequests(t *testing.T) {
+	masrvi := &unavailableProvider{failingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}}
+	client := newFailoverTestClient(t, nil, masrvi)
+
+	result, err := client.SimulatePayment(context.Background(), &SimulationRequest{
+		Request:           &MasrviPaymentRequest{Amount: money.NewMRU(5000), Reference: "REF-1"},
+		ProbeAvailability: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderMasrvi, result.Provider)
+	assert.Equal(t, money.NewMRU(5000), result.Request.Amount)
+	require.NotNil(t, result.Available)
+	assert.False(t, *result.Available)
+	require.Len(t, result.Errors, 2, "validation and availability")
+	assert.Contains(t, result.Errors[1].Error(), "not available")
+
+	result, err = client.SimulatePayment(context.Background(), &BPayPaymentRequest{Amount: money.NewMRU(5000)})
+	require.NoError(t, err)
+	assert.Empty(t, result.Provider, "B-PAY is not registered")
+	assert.False(t, result.Valid())
+
+	_, err = client.SimulatePayment(context.Background(), "REF-1")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = client.SimulatePayment(context.Background(), (*PaymentRequest)(nil))
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Zero(t, masrvi.calls)
+}
+
+func TestDryRunPaymentsAreNotSent(t *testing.T) {
+	provider := &failingProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	client := newFailoverTestClient(t, nil, provider)
+	client.config.DryRun = true
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+
+	resp, err := client.ProcessPayment(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Zero(t, provider.calls)
+	assert.Equal(t, PaymentStatusPending, resp.Status)
+	assert.Equal(t, ProviderBPay, resp.Provider)
+	assert.Equal(t, true, resp.Metadata[MetadataSimulated])
+	assert.Equal(t, "REF-1", resp.Reference)
+	_, err = store.GetByTransactionID(context.Background(), resp.TransactionID)
+	assert.Error(t, err, "dry-run payments are not stored")
+
+	_, err = client.ProcessPaymentWithProvider(context.Background(), ProviderBPay,
+		&PaymentRequest{Amount: money.FromCents(5000, money.MRO), Reference: "REF-2"})
+	assert.Error(t, err, "limits still apply")
+}
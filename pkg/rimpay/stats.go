@@ -0,0 +1,186 @@
+package rimpay
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultStatsWindow is the sliding window used when none is configured
+const defaultStatsWindow = time.Minute
+
+// defaultStatsSamples is the ring size used when none is configured
+const defaultStatsSamples = 1024
+
+// callSample is a single observed provider call
+type callSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// windowStats summarises provider calls over the sliding window
+type windowStats struct {
+	Calls      int
+	Errors     int
+	ErrorRate  float64
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+	P50Latency time.Duration
+	P95Latency time.Duration
+}
+
+// statsCollector keeps the latest call samples of each provider in a ring
+// buffer. Recording a call takes no lock: the ring is found in a sync.Map
+// and its slot claimed with an atomic counter.
+type statsCollector struct {
+	window time.Duration
+	size   int
+	now    func() time.Time
+	rings  sync.Map // provider name -> *sampleRing
+}
+
+// sampleRing holds a provider's latest samples
+type sampleRing struct {
+	next  uint64
+	slots []sampleSlot
+}
+
+// sampleSlot is a callSample stored in atomically written words. at is
+// zeroed while the slot is rewritten, so readers skip half-written slots.
+type sampleSlot struct {
+	at      int64 // Unix nanoseconds
+	outcome int64 // latency << 1, with the low bit set for failures
+}
+
+func newStatsCollector(window time.Duration, samples int) *statsCollector {
+	if window <= 0 {
+		window = defaultStatsWindow
+	}
+	if samples <= 0 {
+		samples = defaultStatsSamples
+	}
+	return &statsCollector{
+		window: window,
+		size:   samples,
+		now:    time.Now,
+	}
+}
+
+// ring returns the provider's ring, creating it on first use
+func (s *statsCollector) ring(provider string) *sampleRing {
+	if ring, ok := s.rings.Load(provider); ok {
+		return ring.(*sampleRing)
+	}
+	ring, _ := s.rings.LoadOrStore(provider, &sampleRing{slots: make([]sampleSlot, s.size)})
+	return ring.(*sampleRing)
+}
+
+// record adds a call outcome
+func (s *statsCollector) record(provider string, latency time.Duration, err error) {
+	ring := s.ring(provider)
+	slot := &ring.slots[(atomic.AddUint64(&ring.next, 1)-1)%uint64(len(ring.slots))]
+
+	outcome := int64(latency) << 1
+	if isProviderFailure(err) {
+		outcome |= 1
+	}
+	atomic.StoreInt64(&slot.at, 0)
+	atomic.StoreInt64(&slot.outcome, outcome)
+	atomic.StoreInt64(&slot.at, s.now().UnixNano())
+}
+
+// snapshot returns the provider's stats for the current window, without
+// latency percentiles
+func (s *statsCollector) snapshot(provider string) windowStats {
+	return summarize(s.samples(provider), false)
+}
+
+// percentiles returns the provider's stats for the current window
+func (s *statsCollector) percentiles(provider string) windowStats {
+	return summarize(s.samples(provider), true)
+}
+
+// samples returns the provider's samples inside the window
+func (s *statsCollector) samples(provider string) []callSample {
+	value, ok := s.rings.Load(provider)
+	if !ok {
+		return nil
+	}
+	ring := value.(*sampleRing)
+
+	cutoff := s.now().Add(-s.window).UnixNano()
+	samples := make([]callSample, 0, len(ring.slots))
+	for i := range ring.slots {
+		slot := &ring.slots[i]
+		at := atomic.LoadInt64(&slot.at)
+		if at == 0 || at <= cutoff {
+			continue
+		}
+		outcome := atomic.LoadInt64(&slot.outcome)
+		samples = append(samples, callSample{
+			at:      time.Unix(0, at),
+			latency: time.Duration(outcome >> 1),
+			failed:  outcome&1 == 1,
+		})
+	}
+	return samples
+}
+
+func summarize(samples []callSample, percentiles bool) windowStats {
+	stats := windowStats{Calls: len(samples)}
+	if stats.Calls == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample.latency
+		if sample.latency > stats.MaxLatency {
+			stats.MaxLatency = sample.latency
+		}
+		if sample.failed {
+			stats.Errors++
+		}
+	}
+	stats.AvgLatency = total / time.Duration(stats.Calls)
+	stats.ErrorRate = float64(stats.Errors) / float64(stats.Calls)
+
+	if percentiles {
+		latencies := make([]time.Duration, len(samples))
+		for i, sample := range samples {
+			latencies[i] = sample.latency
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.P50Latency = percentile(latencies, 50)
+		stats.P95Latency = percentile(latencies, 95)
+	}
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// isProviderFailure reports whether err counts against the provider. Caller
+// mistakes such as validation errors are not the provider's fault.
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) {
+		switch paymentErr.Code {
+		case ErrorCodeValidationError, ErrorCodeInvalidRequest:
+			return false
+		}
+	}
+	return !errors.Is(err, ErrInvalidRequest)
+}
@@ -0,0 +1,123 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsPercentiles(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	stats := newStatsCollector(time.Minute, 0)
+	stats.now = clock.Now
+
+	for i := 1; i <= 100; i++ {
+		var err error
+		if i%10 == 0 {
+			err = ErrNetworkError
+		}
+		stats.record("bpay", time.Duration(i)*time.Millisecond, err)
+	}
+
+	snapshot := stats.percentiles("bpay")
+	assert.Equal(t, 100, snapshot.Calls)
+	assert.Equal(t, 10, snapshot.Errors)
+	assert.InDelta(t, 0.1, snapshot.ErrorRate, 1e-9)
+	assert.Equal(t, 50*time.Millisecond, snapshot.P50Latency)
+	assert.Equal(t, 95*time.Millisecond, snapshot.P95Latency)
+	assert.Equal(t, 100*time.Millisecond, snapshot.MaxLatency)
+	assert.Zero(t, stats.snapshot("bpay").P95Latency, "alerts skip the percentiles")
+	assert.Equal(t, windowStats{}, stats.percentiles("masrvi"))
+}
+
+func TestStatsRingKeepsLatestSamples(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	stats := newStatsCollector(time.Minute, 4)
+	stats.now = clock.Now
+
+	for i := 1; i <= 6; i++ {
+		stats.record("bpay", time.Duration(i)*time.Second, nil)
+	}
+
+	snapshot := stats.percentiles("bpay")
+	assert.Equal(t, 4, snapshot.Calls)
+	assert.Equal(t, 6*time.Second, snapshot.MaxLatency)
+	assert.Equal(t, 4*time.Second, snapshot.P50Latency)
+}
+
+func TestClientStatsReportsRecentCalls(t *testing.T) {
+	bpay := &failingProvider{
+		namedProvider: namedProvider{name: ProviderBPay},
+		err:           NewPaymentError(ErrorCodeProviderError, "down", ProviderBPay, true),
+	}
+	client := newFailoverTestClient(t, nil, bpay, &namedProvider{name: ProviderMasrvi})
+
+	for i := 0; i < 4; i++ {
+		_, _ = client.ProcessPayment(context.Background(), failoverRequest())
+	}
+
+	stats := client.Stats()
+	assert.Equal(t, 4, stats[ProviderBPay].Calls)
+	assert.Equal(t, 4, stats[ProviderBPay].Errors)
+	assert.Equal(t, 1.0, stats[ProviderBPay].ErrorRate)
+	assert.Equal(t, defaultStatsWindow, stats[ProviderBPay].Window)
+	assert.Equal(t, 0, stats[ProviderMasrvi].Calls)
+}
+
+func TestFailoverPrefersLowLatency(t *testing.T) {
+	providers := []PaymentProvider{
+		&namedProvider{name: "slow"}, &namedProvider{name: "unmeasured"}, &namedProvider{name: "fast"},
+	}
+	client := newFailoverTestClient(t, []string{"slow", "unmeasured", "fast"}, providers...)
+	client.config.Failover.PreferLowLatency = true
+	for i := 0; i < 5; i++ {
+		client.stats.record("slow", 3*time.Second, nil)
+		client.stats.record("fast", 200*time.Millisecond, nil)
+	}
+
+	assert.Equal(t, []string{"fast", "unmeasured", "slow"}, client.byLatency(client.config.Failover.Providers))
+
+	resp, err := client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "fast", resp.Provider)
+
+	client.config.Failover.PreferLowLatency = false
+	resp, err = client.ProcessPaymentWithFailover(context.Background(), failoverRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "slow", resp.Provider)
+}
+
+// BenchmarkStatsRecord measures the cost stats add to every provider call
+func BenchmarkStatsRecord(b *testing.B) {
+	stats := newStatsCollector(time.Minute, 0)
+	providers := make([]string, 4)
+	for i := range providers {
+		providers[i] = fmt.Sprintf("provider-%d", i)
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			stats.record(providers[i%len(providers)], time.Millisecond, nil)
+			i++
+		}
+	})
+}
+
+// BenchmarkStatsSnapshot measures Client.Stats for one provider with a full ring
+func BenchmarkStatsSnapshot(b *testing.B) {
+	stats := newStatsCollector(time.Minute, 0)
+	for i := 0; i < defaultStatsSamples; i++ {
+		stats.record("bpay", time.Duration(i)*time.Microsecond, nil)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stats.percentiles("bpay")
+	}
+}
@@ -0,0 +1,134 @@
+package rimpay
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultStatusCachePendingTTL is how long pending statuses are reused
+// unless StatusCacheConfig.PendingTTL says otherwise
+const defaultStatusCachePendingTTL = 5 * time.Second
+
+type forceRefreshKey struct{}
+
+// WithForceRefresh returns a context whose GetPaymentStatus calls ask the
+// provider even when the status cache holds the transaction
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+// forceRefresh reports whether ctx was made with WithForceRefresh
+func forceRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return refresh
+}
+
+// statusCache is a bounded LRU of the statuses GetPaymentStatus returned.
+// Final statuses never expire; pending ones expire after the pending TTL.
+type statusCache struct {
+	maxEntries int
+	pendingTTL time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // of *statusEntry, most recently used first
+	entries map[statusKey]*list.Element
+}
+
+type statusKey struct {
+	provider      string
+	transactionID string
+}
+
+type statusEntry struct {
+	key    statusKey
+	status TransactionStatus
+	// expires is zero for final statuses
+	expires time.Time
+}
+
+// newStatusCache creates a cache from config; nil means caching is off
+func newStatusCache(config StatusCacheConfig) *statusCache {
+	if config.MaxEntries <= 0 {
+		return nil
+	}
+	ttl := config.PendingTTL
+	if ttl == 0 {
+		ttl = defaultStatusCachePendingTTL
+	}
+	return &statusCache{
+		maxEntries: config.MaxEntries,
+		pendingTTL: ttl,
+		order:      list.New(),
+		entries:    make(map[statusKey]*list.Element),
+	}
+}
+
+// get returns a copy of the cached status of transactionID at provider
+// unless it expired at now
+func (s *statusCache) get(provider, transactionID string, now time.Time) (*TransactionStatus, bool) {
+	key := statusKey{provider, transactionID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*statusEntry)
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		s.order.Remove(element)
+		delete(s.entries, key)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return entry.copy(), true
+}
+
+// put caches the status of transactionID at provider, evicting the least
+// recently used entry when full
+func (s *statusCache) put(provider, transactionID string, status *TransactionStatus, now time.Time) {
+	if status == nil {
+		return
+	}
+	entry := &statusEntry{key: statusKey{provider, transactionID}, status: *status}
+	entry.status = *entry.copy()
+	if !status.Status.IsCompleted() {
+		entry.expires = now.Add(s.pendingTTL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[entry.key]; ok {
+		element.Value = entry
+		s.order.MoveToFront(element)
+		return
+	}
+	s.entries[entry.key] = s.order.PushFront(entry)
+	if s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*statusEntry).key)
+	}
+}
+
+// len returns the number of cached statuses
+func (s *statusCache) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// copy returns a status the caller may modify without changing the cache
+func (e *statusEntry) copy() *TransactionStatus {
+	status := e.status
+	status.EventLog = append([]StatusEvent(nil), e.status.EventLog...)
+	if e.status.ProviderData != nil {
+		status.ProviderData = make(map[string]interface{}, len(e.status.ProviderData))
+		for k, v := range e.status.ProviderData {
+			status.ProviderData[k] = v
+		}
+	}
+	return &status
+}
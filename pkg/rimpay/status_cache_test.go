@@ -0,0 +1,151 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusProvider answers status checks with status and counts them
+type statusProvider struct {
+	namedProvider
+	status atomic.Value // PaymentStatus
+	calls  int32
+}
+
+func (p *statusProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	atomic.AddInt32(&p.calls, 1)
+	return &TransactionStatus{TransactionID: transactionID, Status: p.status.Load().(PaymentStatus),
+		EventLog: []StatusEvent{{Status: p.status.Load().(PaymentStatus), Source: EventSourcePoll}}}, nil
+}
+
+func newStatusCacheTestClient(t *testing.T, cache StatusCacheConfig, status PaymentStatus) (*Client, *statusProvider, *fakeClock) {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderBPay
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.StatusCache = cache
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+
+	clock := &fakeClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	client.scheduler.now = clock.Now
+
+	provider := &statusProvider{namedProvider: namedProvider{name: ProviderBPay}}
+	provider.status.Store(status)
+	require.NoError(t, client.AddProvider(ProviderBPay, provider))
+	return client, provider, clock
+}
+
+func TestStatusCacheKeepsFinalStatuses(t *testing.T) {
+	for _, status := range []PaymentStatus{PaymentStatusSuccess, PaymentStatusFailed, PaymentStatusCancelled, PaymentStatusExpired} {
+		client, provider, clock := newStatusCacheTestClient(t, StatusCacheConfig{MaxEntries: 10}, status)
+
+		for i := 0; i < 3; i++ {
+			result, err := client.GetPaymentStatus(context.Background(), "TX-1")
+			require.NoError(t, err)
+			assert.Equal(t, status, result.Status)
+			clock.Advance(24 * time.Hour)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&provider.calls), "%s is asked for once", status)
+
+		_, err := client.GetPaymentStatus(WithForceRefresh(context.Background()), "TX-1")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&provider.calls), "WithForceRefresh bypasses the cache")
+	}
+}
+
+func TestStatusCacheRefreshesPendingAfterTTL(t *testing.T) {
+	client, provider, clock := newStatusCacheTestClient(t, StatusCacheConfig{MaxEntries: 10, PendingTTL: 2 * time.Second}, PaymentStatusPending)
+	ctx := context.Background()
+
+	_, err := client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	result, err := client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusPending, result.Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&provider.calls))
+
+	provider.status.Store(PaymentStatusSuccess)
+	clock.Advance(time.Second)
+	result, err = client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, result.Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&provider.calls))
+
+	clock.Advance(time.Hour)
+	_, err = client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&provider.calls), "the final status is kept")
+}
+
+func TestStatusCacheOffByDefault(t *testing.T) {
+	client, provider, _ := newStatusCacheTestClient(t, StatusCacheConfig{}, PaymentStatusSuccess)
+	for i := 0; i < 2; i++ {
+		_, err := client.GetPaymentStatus(context.Background(), "TX-1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&provider.calls))
+}
+
+func TestStatusCacheReturnsCopies(t *testing.T) {
+	client, _, _ := newStatusCacheTestClient(t, StatusCacheConfig{MaxEntries: 10}, PaymentStatusSuccess)
+	first, err := client.GetPaymentStatus(context.Background(), "TX-1")
+	require.NoError(t, err)
+	first.Status = PaymentStatusFailed
+	first.EventLog[0].Message = "changed"
+
+	second, err := client.GetPaymentStatus(context.Background(), "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, second.Status)
+	assert.Empty(t, second.EventLog[0].Message)
+}
+
+func TestStatusCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	cache := newStatusCache(StatusCacheConfig{MaxEntries: 2})
+	for _, id := range []string{"TX-1", "TX-2"} {
+		cache.put(ProviderBPay, id, &TransactionStatus{TransactionID: id, Status: PaymentStatusSuccess}, now)
+	}
+	_, ok := cache.get(ProviderBPay, "TX-1", now)
+	require.True(t, ok)
+
+	cache.put(ProviderBPay, "TX-3", &TransactionStatus{TransactionID: "TX-3", Status: PaymentStatusSuccess}, now)
+	assert.Equal(t, 2, cache.len())
+	_, ok = cache.get(ProviderBPay, "TX-2", now)
+	assert.False(t, ok, "TX-2 was the least recently used")
+	_, ok = cache.get(ProviderBPay, "TX-1", now)
+	assert.True(t, ok)
+	_, ok = cache.get(ProviderMasrvi, "TX-1", now)
+	assert.False(t, ok, "entries are per provider")
+}
+
+func TestStatusCacheConcurrentUse(t *testing.T) {
+	cache := newStatusCache(StatusCacheConfig{MaxEntries: 16, PendingTTL: time.Millisecond})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				id := fmt.Sprintf("TX-%d", (g*i)%40)
+				status := PaymentStatusPending
+				if i%3 == 0 {
+					status = PaymentStatusSuccess
+				}
+				cache.put(ProviderBPay, id, &TransactionStatus{TransactionID: id, Status: status}, time.Now())
+				cache.get(ProviderBPay, id, time.Now())
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, cache.len(), 16)
+}
@@ -0,0 +1,298 @@
+package rimpay
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// TransactionStore persists payments and their status trail. Once set with
+// Client.WithTransactionStore, the client saves every payment it creates and
+// records every status change seen by GetPaymentStatus, polling, return
+// confirmation and notification handling.
+//
+// Implementations must be safe for concurrent use. UpdateStatus is
+// last-write-wins on TransactionStatus.LastUpdated: an older status never
+// replaces a newer one, but its events are still added to the trail.
+type TransactionStore interface {
+	// SavePayment stores a newly created payment
+	SavePayment(ctx context.Context, payment *PaymentResponse) error
+	// UpdateStatus records a status for the transaction with the same ID, or
+	// else the same reference, creating it when neither is known
+	UpdateStatus(ctx context.Context, status *TransactionStatus) error
+	// GetByReference returns the transaction created for reference, or
+	// ErrTransactionNotFound
+	GetByReference(ctx context.Context, reference string) (*StoredTransaction, error)
+	// GetByTransactionID returns the transaction, or ErrTransactionNotFound
+	GetByTransactionID(ctx context.Context, transactionID string) (*StoredTransaction, error)
+	// ListPending returns the transactions still pending, oldest first
+	ListPending(ctx context.Context) ([]*StoredTransaction, error)
+}
+
+// StoredTransaction is a payment as kept by a TransactionStore
+type StoredTransaction struct {
+	TransactionID     string        `json:"transaction_id"`
+	Reference         string        `json:"reference"`
+	Provider          string        `json:"provider,omitempty"`
+	ProviderReference string        `json:"provider_reference,omitempty"`
+	Amount            money.Money   `json:"amount"`
+	Status            PaymentStatus `json:"status"`
+	Message           string        `json:"message,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	LastUpdated       time.Time     `json:"last_updated"`
+	Events            []StatusEvent `json:"events,omitempty"`
+}
+
+// newStoredTransaction creates the record of a new payment
+func newStoredTransaction(payment *PaymentResponse) *StoredTransaction {
+	stored := &StoredTransaction{
+		TransactionID: payment.TransactionID,
+		Reference:     payment.Reference,
+		Provider:      payment.Provider,
+		Amount:        payment.Amount,
+		CreatedAt:     payment.CreatedAt,
+	}
+	stored.apply(paymentStatus(payment))
+	return stored
+}
+
+// paymentStatus returns the status carried by a payment response
+func paymentStatus(payment *PaymentResponse) *TransactionStatus {
+	status := &TransactionStatus{
+		TransactionID: payment.TransactionID,
+		Status:        payment.Status,
+		Amount:        payment.Amount,
+		Reference:     payment.Reference,
+		LastUpdated:   payment.UpdatedAt,
+		EventLog:      payment.Events,
+	}
+	if ref, ok := payment.Metadata[MetadataKeyProviderReference].(string); ok {
+		status.ProviderReference = ref
+	}
+	return status
+}
+
+// newEvents returns the events of status not yet in the trail
+func (t *StoredTransaction) newEvents(status *TransactionStatus) []StatusEvent {
+	var events []StatusEvent
+	for _, event := range status.EventLog {
+		known := false
+		for _, existing := range t.Events {
+			if existing.At.Equal(event.At) && existing.Status == event.Status &&
+				existing.Source == event.Source && existing.Message == event.Message {
+				known = true
+				break
+			}
+		}
+		if !known {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// apply merges status into the record: its new events join the trail, and
+// it becomes the current status unless the record is more recent. It reports
+// whether the current status was replaced.
+func (t *StoredTransaction) apply(status *TransactionStatus) bool {
+	t.Events = append(t.Events, t.newEvents(status)...)
+	sort.SliceStable(t.Events, func(i, j int) bool {
+		return t.Events[i].At.Before(t.Events[j].At)
+	})
+
+	if status.LastUpdated.Before(t.LastUpdated) {
+		return false
+	}
+	t.Status = status.Status
+	t.Message = status.Message
+	t.LastUpdated = status.LastUpdated
+	if status.ProviderReference != "" {
+		t.ProviderReference = status.ProviderReference
+	}
+	if t.Amount.IsZero() && !status.Amount.IsZero() {
+		t.Amount = status.Amount
+	}
+	return true
+}
+
+// copy returns a deep copy of the record
+func (t *StoredTransaction) copy() *StoredTransaction {
+	dup := *t
+	dup.Events = append([]StatusEvent(nil), t.Events...)
+	return &dup
+}
+
+// MemoryTransactionStore is an in-process TransactionStore, for tests and
+// single-instance deployments
+type MemoryTransactionStore struct {
+	mu           sync.Mutex
+	transactions map[string]*StoredTransaction
+	references   map[string]string
+	intents      map[PaymentIntentID]*PaymentIntent
+}
+
+// NewMemoryTransactionStore creates an empty in-memory store
+func NewMemoryTransactionStore() *MemoryTransactionStore {
+	return &MemoryTransactionStore{
+		transactions: make(map[string]*StoredTransaction),
+		references:   make(map[string]string),
+		intents:      make(map[PaymentIntentID]*PaymentIntent),
+	}
+}
+
+// find returns the record for transactionID, else for reference
+func (s *MemoryTransactionStore) find(transactionID, reference string) *StoredTransaction {
+	if stored, ok := s.transactions[transactionID]; ok {
+		return stored
+	}
+	if id, ok := s.references[reference]; ok && reference != "" {
+		return s.transactions[id]
+	}
+	return nil
+}
+
+func (s *MemoryTransactionStore) insert(stored *StoredTransaction) {
+	s.transactions[stored.TransactionID] = stored
+	if _, ok := s.references[stored.Reference]; !ok && stored.Reference != "" {
+		s.references[stored.Reference] = stored.TransactionID
+	}
+}
+
+// SavePayment stores payment; saving it again merges its status
+func (s *MemoryTransactionStore) SavePayment(_ context.Context, payment *PaymentResponse) error {
+	if payment == nil || payment.TransactionID == "" {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored := s.find(payment.TransactionID, payment.Reference); stored != nil {
+		stored.apply(paymentStatus(payment))
+		return nil
+	}
+	s.insert(newStoredTransaction(payment))
+	return nil
+}
+
+// UpdateStatus records status
+func (s *MemoryTransactionStore) UpdateStatus(_ context.Context, status *TransactionStatus) error {
+	if status == nil || (status.TransactionID == "" && status.Reference == "") {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.find(status.TransactionID, status.Reference)
+	if stored == nil {
+		stored = unknownTransaction(status)
+		s.insert(stored)
+	}
+	stored.apply(status)
+	return nil
+}
+
+// unknownTransaction creates the record for a status whose payment was never
+// saved
+func unknownTransaction(status *TransactionStatus) *StoredTransaction {
+	id := status.TransactionID
+	if id == "" {
+		id = status.Reference
+	}
+	return &StoredTransaction{
+		TransactionID: id,
+		Reference:     status.Reference,
+		CreatedAt:     status.LastUpdated,
+	}
+}
+
+// GetByReference returns the transaction created for reference
+func (s *MemoryTransactionStore) GetByReference(_ context.Context, reference string) (*StoredTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.references[reference]; ok {
+		return s.transactions[id].copy(), nil
+	}
+	return nil, ErrTransactionNotFound
+}
+
+// GetByTransactionID returns the transaction
+func (s *MemoryTransactionStore) GetByTransactionID(_ context.Context, transactionID string) (*StoredTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.transactions[transactionID]; ok {
+		return stored.copy(), nil
+	}
+	return nil, ErrTransactionNotFound
+}
+
+// ListPending returns the pending transactions, oldest first
+func (s *MemoryTransactionStore) ListPending(_ context.Context) ([]*StoredTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pending []*StoredTransaction
+	for _, stored := range s.transactions {
+		if stored.Status == PaymentStatusPending {
+			pending = append(pending, stored.copy())
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+	return pending, nil
+}
+
+// WithTransactionStore makes the client persist payments and status changes
+// in store; nil stops persisting. Store failures are logged and never fail
+// the payment or status call.
+func (c *Client) WithTransactionStore(store TransactionStore) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = store
+	return c
+}
+
+func (c *Client) transactionStore() TransactionStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.store
+}
+
+// savePayment stores a payment the client just created
+func (c *Client) savePayment(ctx context.Context, payment *PaymentResponse) {
+	store := c.transactionStore()
+	if store == nil || payment == nil {
+		return
+	}
+	// Dry-run payments do not exist at the provider
+	if simulated, _ := payment.Metadata[MetadataSimulated].(bool); simulated {
+		return
+	}
+	if err := store.SavePayment(ctx, payment); err != nil {
+		correlationID, _ := payment.GetMetadataString(MetadataKeyCorrelationID)
+		c.logger.Error("Failed to save payment",
+			"transaction_id", payment.TransactionID, "correlation_id", correlationID, "error", err)
+	}
+}
+
+// recordStatus stores status when it differs from the stored one
+func (c *Client) recordStatus(ctx context.Context, status *TransactionStatus) {
+	store := c.transactionStore()
+	if store == nil || status == nil {
+		return
+	}
+
+	stored, err := store.GetByTransactionID(ctx, status.TransactionID)
+	if err != nil && status.Reference != "" {
+		stored, err = store.GetByReference(ctx, status.Reference)
+	}
+	if err == nil && stored.Status == status.Status {
+		return
+	}
+
+	if err := store.UpdateStatus(ctx, status); err != nil {
+		ContextLogger(ctx, c.logger).Error("Failed to record transaction status", "transaction_id", status.TransactionID, "error", err)
+	}
+}
@@ -0,0 +1,414 @@
+package rimpay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+// SQLTransactionSchema creates the tables used by SQLTransactionStore. It is
+// portable across PostgreSQL, MySQL and SQLite; adapt the column types to
+// your database as needed.
+const SQLTransactionSchema = `
+CREATE TABLE rimpay_transactions (
+    transaction_id     VARCHAR(128) PRIMARY KEY,
+    reference          VARCHAR(128) NOT NULL,
+    provider           VARCHAR(32)  NOT NULL,
+    provider_reference VARCHAR(128) NOT NULL,
+    amount             VARCHAR(32)  NOT NULL,
+    currency           VARCHAR(8)   NOT NULL,
+    status             VARCHAR(16)  NOT NULL,
+    message            TEXT         NOT NULL,
+    created_at         TIMESTAMP    NOT NULL,
+    last_updated       TIMESTAMP    NOT NULL
+);
+CREATE INDEX rimpay_transactions_reference ON rimpay_transactions (reference);
+CREATE INDEX rimpay_transactions_status ON rimpay_transactions (status);
+
+CREATE TABLE rimpay_transaction_events (
+    transaction_id VARCHAR(128) NOT NULL,
+    status         VARCHAR(16)  NOT NULL,
+    message        TEXT         NOT NULL,
+    source         VARCHAR(16)  NOT NULL,
+    occurred_at    TIMESTAMP    NOT NULL
+);
+CREATE INDEX rimpay_transaction_events_transaction ON rimpay_transaction_events (transaction_id, occurred_at);
+
+CREATE TABLE rimpay_payment_intents (
+    intent_id  VARCHAR(128) PRIMARY KEY,
+    reference  VARCHAR(128) NOT NULL,
+    amount     VARCHAR(32)  NOT NULL,
+    currency   VARCHAR(8)   NOT NULL,
+    status     VARCHAR(16)  NOT NULL,
+    attempts   TEXT         NOT NULL,
+    version    INTEGER      NOT NULL,
+    created_at TIMESTAMP    NOT NULL,
+    updated_at TIMESTAMP    NOT NULL
+);
+CREATE INDEX rimpay_payment_intents_reference ON rimpay_payment_intents (reference);
+`
+
+// SQLPlaceholder is the bind parameter style of a database driver
+type SQLPlaceholder int
+
+const (
+	// SQLPlaceholderQuestion binds with ?, as MySQL and SQLite do
+	SQLPlaceholderQuestion SQLPlaceholder = iota
+	// SQLPlaceholderDollar binds with $1, $2..., as PostgreSQL does
+	SQLPlaceholderDollar
+)
+
+// SQLTransactionStore is a reference TransactionStore on database/sql using
+// the tables of SQLTransactionSchema. Each write runs in a database
+// transaction; events are only ever inserted, so concurrent updates cannot
+// lose them, and the current status is only replaced by a status whose
+// LastUpdated is not older.
+type SQLTransactionStore struct {
+	db          *sql.DB
+	placeholder SQLPlaceholder
+}
+
+// NewSQLTransactionStore creates a store on db, which must already have the
+// SQLTransactionSchema tables
+func NewSQLTransactionStore(db *sql.DB, placeholder SQLPlaceholder) *SQLTransactionStore {
+	return &SQLTransactionStore{db: db, placeholder: placeholder}
+}
+
+// sqlQueryer is implemented by *sql.DB and *sql.Tx
+type sqlQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const sqlTransactionColumns = `transaction_id, reference, provider, provider_reference, amount, currency,
+status, message, created_at, last_updated`
+
+// rebind rewrites ? placeholders in query to the store's style
+func (s *SQLTransactionStore) rebind(query string) string {
+	if s.placeholder != SQLPlaceholderDollar {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SavePayment stores payment; saving it again merges its status
+func (s *SQLTransactionStore) SavePayment(ctx context.Context, payment *PaymentResponse) error {
+	if payment == nil || payment.TransactionID == "" {
+		return ErrInvalidRequest
+	}
+	return s.write(ctx, payment.TransactionID, payment.Reference, func() *StoredTransaction {
+		return &StoredTransaction{
+			TransactionID: payment.TransactionID,
+			Reference:     payment.Reference,
+			Provider:      payment.Provider,
+			Amount:        payment.Amount,
+			CreatedAt:     payment.CreatedAt,
+		}
+	}, paymentStatus(payment))
+}
+
+// UpdateStatus records status
+func (s *SQLTransactionStore) UpdateStatus(ctx context.Context, status *TransactionStatus) error {
+	if status == nil || (status.TransactionID == "" && status.Reference == "") {
+		return ErrInvalidRequest
+	}
+	return s.write(ctx, status.TransactionID, status.Reference, func() *StoredTransaction {
+		return unknownTransaction(status)
+	}, status)
+}
+
+// write merges status into the transaction found by ID or reference, or
+// into the one created by create
+func (s *SQLTransactionStore) write(ctx context.Context, transactionID, reference string,
+	create func() *StoredTransaction, status *TransactionStatus) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stored, err := s.find(ctx, tx, transactionID, reference)
+	switch {
+	case errors.Is(err, ErrTransactionNotFound):
+		stored = create()
+		if err = s.insert(ctx, tx, stored); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	events := stored.newEvents(status)
+	for _, event := range events {
+		if _, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO rimpay_transaction_events
+(transaction_id, status, message, source, occurred_at) VALUES (?, ?, ?, ?, ?)`),
+			stored.TransactionID, string(event.Status), event.Message, event.Source, event.At.UTC()); err != nil {
+			return err
+		}
+	}
+
+	if stored.apply(status) {
+		if _, err = tx.ExecContext(ctx, s.rebind(`UPDATE rimpay_transactions
+SET status = ?, message = ?, provider_reference = ?, amount = ?, currency = ?, last_updated = ?
+WHERE transaction_id = ? AND last_updated <= ?`),
+			string(stored.Status), stored.Message, stored.ProviderReference,
+			stored.Amount.Amount().String(), string(stored.Amount.Currency()), stored.LastUpdated.UTC(),
+			stored.TransactionID, stored.LastUpdated.UTC()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLTransactionStore) insert(ctx context.Context, q sqlQueryer, stored *StoredTransaction) error {
+	_, err := q.ExecContext(ctx, s.rebind(`INSERT INTO rimpay_transactions (`+sqlTransactionColumns+`)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		stored.TransactionID, stored.Reference, stored.Provider, stored.ProviderReference,
+		stored.Amount.Amount().String(), string(stored.Amount.Currency()),
+		string(stored.Status), stored.Message, stored.CreatedAt.UTC(), stored.LastUpdated.UTC())
+	return err
+}
+
+// find returns the transaction with transactionID, else the oldest one
+// with reference
+func (s *SQLTransactionStore) find(ctx context.Context, q sqlQueryer, transactionID, reference string) (*StoredTransaction, error) {
+	stored, err := s.get(ctx, q, "transaction_id", transactionID)
+	if errors.Is(err, ErrTransactionNotFound) && reference != "" {
+		stored, err = s.get(ctx, q, "reference", reference)
+	}
+	return stored, err
+}
+
+// get loads the oldest transaction whose column equals value, with its events
+func (s *SQLTransactionStore) get(ctx context.Context, q sqlQueryer, column, value string) (*StoredTransaction, error) {
+	row := q.QueryRowContext(ctx, s.rebind(`SELECT `+sqlTransactionColumns+`
+FROM rimpay_transactions WHERE `+column+` = ? ORDER BY created_at LIMIT 1`), value)
+	stored, err := scanStoredTransaction(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, s.rebind(`SELECT status, message, source, occurred_at
+FROM rimpay_transaction_events WHERE transaction_id = ? ORDER BY occurred_at`), stored.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event StatusEvent
+		var status string
+		if err := rows.Scan(&status, &event.Message, &event.Source, &event.At); err != nil {
+			return nil, err
+		}
+		event.Status = PaymentStatus(status)
+		stored.Events = append(stored.Events, event)
+	}
+	return stored, rows.Err()
+}
+
+// sqlScanner is implemented by *sql.Row and *sql.Rows
+type sqlScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStoredTransaction(row sqlScanner) (*StoredTransaction, error) {
+	var stored StoredTransaction
+	var amount, currency, status string
+	var createdAt, lastUpdated time.Time
+	if err := row.Scan(&stored.TransactionID, &stored.Reference, &stored.Provider, &stored.ProviderReference,
+		&amount, &currency, &status, &stored.Message, &createdAt, &lastUpdated); err != nil {
+		return nil, err
+	}
+	parsed, err := money.FromString(amount, money.Currency(currency))
+	if err != nil {
+		return nil, err
+	}
+	stored.Amount = parsed
+	stored.Status = PaymentStatus(status)
+	stored.CreatedAt = createdAt
+	stored.LastUpdated = lastUpdated
+	return &stored, nil
+}
+
+// GetByReference returns the oldest transaction created for reference
+func (s *SQLTransactionStore) GetByReference(ctx context.Context, reference string) (*StoredTransaction, error) {
+	return s.get(ctx, s.db, "reference", reference)
+}
+
+// GetByTransactionID returns the transaction
+func (s *SQLTransactionStore) GetByTransactionID(ctx context.Context, transactionID string) (*StoredTransaction, error) {
+	return s.get(ctx, s.db, "transaction_id", transactionID)
+}
+
+// ListPending returns the pending transactions, oldest first. Their events
+// are not loaded; use GetByTransactionID for the trail.
+func (s *SQLTransactionStore) ListPending(ctx context.Context) ([]*StoredTransaction, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT `+sqlTransactionColumns+`
+FROM rimpay_transactions WHERE status = ? ORDER BY created_at`), string(PaymentStatusPending))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*StoredTransaction
+	for rows.Next() {
+		stored, err := scanStoredTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, stored)
+	}
+	return pending, rows.Err()
+}
+
+// ScanTransactions streams the selected transactions, oldest first. The
+// date, provider and status filters run in the database; MinAmount is
+// checked on each row, as amounts are stored as text.
+func (s *SQLTransactionStore) ScanTransactions(ctx context.Context, filter ExportFilter, fn func(*StoredTransaction) error) error {
+	query, args := s.scanQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		stored, err := scanStoredTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if !filter.Matches(stored) {
+			continue
+		}
+		if err := fn(stored); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanQuery returns the query of ScanTransactions and its arguments
+func (s *SQLTransactionStore) scanQuery(filter ExportFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.Provider != "" {
+		conditions = append(conditions, "provider = ?")
+		args = append(args, filter.Provider)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+
+	query := `SELECT ` + sqlTransactionColumns + `
+FROM rimpay_transactions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return s.rebind(query + " ORDER BY created_at"), args
+}
+
+// SaveIntent stores intent; its attempts are kept as JSON
+func (s *SQLTransactionStore) SaveIntent(ctx context.Context, intent *PaymentIntent) error {
+	if intent == nil || intent.ID == "" {
+		return ErrInvalidRequest
+	}
+	attempts, err := json.Marshal(intent.Attempts)
+	if err != nil {
+		return err
+	}
+	if _, err := s.GetIntent(ctx, intent.ID); err == nil {
+		return fmt.Errorf("payment intent %s already exists: %w", intent.ID, ErrIntentConflict)
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO rimpay_payment_intents
+(intent_id, reference, amount, currency, status, attempts, version, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		string(intent.ID), intent.Reference, intent.Amount.Amount().String(), string(intent.Amount.Currency()),
+		string(intent.Status), string(attempts), intent.Version, intent.CreatedAt.UTC(), intent.UpdatedAt.UTC())
+	return err
+}
+
+// GetIntent returns the intent
+func (s *SQLTransactionStore) GetIntent(ctx context.Context, id PaymentIntentID) (*PaymentIntent, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(`SELECT intent_id, reference, amount, currency, status,
+attempts, version, created_at, updated_at FROM rimpay_payment_intents WHERE intent_id = ?`), string(id))
+
+	var intent PaymentIntent
+	var intentID, amount, currency, status, attempts string
+	err := row.Scan(&intentID, &intent.Reference, &amount, &currency, &status,
+		&attempts, &intent.Version, &intent.CreatedAt, &intent.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrIntentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if intent.Amount, err = money.FromString(amount, money.Currency(currency)); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attempts), &intent.Attempts); err != nil {
+		return nil, err
+	}
+	intent.ID = PaymentIntentID(intentID)
+	intent.Status = IntentStatus(status)
+	return &intent, nil
+}
+
+// UpdateIntent replaces intent if its stored version is from
+func (s *SQLTransactionStore) UpdateIntent(ctx context.Context, intent *PaymentIntent, from int) error {
+	if intent == nil {
+		return ErrInvalidRequest
+	}
+	attempts, err := json.Marshal(intent.Attempts)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, s.rebind(`UPDATE rimpay_payment_intents
+SET status = ?, attempts = ?, version = ?, updated_at = ?
+WHERE intent_id = ? AND version = ?`),
+		string(intent.Status), string(attempts), intent.Version, intent.UpdatedAt.UTC(), string(intent.ID), from)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if _, err := s.GetIntent(ctx, intent.ID); err != nil {
+		return err
+	}
+	return fmt.Errorf("payment intent %s is no longer at version %d: %w", intent.ID, from, ErrIntentConflict)
+}
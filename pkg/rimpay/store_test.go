@@ -0,0 +1,204 @@
+package rimpay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedProvider creates pending payments and answers status checks with
+// status
+type storedProvider struct {
+	namedProvider
+	status PaymentStatus
+}
+
+func (p *storedProvider) ProcessPayment(_ context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	now := time.Now()
+	return &PaymentResponse{
+		TransactionID: "TX-1",
+		Status:        PaymentStatusPending,
+		Amount:        request.Amount,
+		Reference:     request.Reference,
+		Provider:      p.name,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Events:        []StatusEvent{NewStatusEvent(PaymentStatusPending, "", EventSourceInitial)},
+	}, nil
+}
+
+func (p *storedProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	status := &TransactionStatus{TransactionID: transactionID, Reference: "REF-1"}
+	status.AddEvent(p.status, "", EventSourcePoll)
+	return status, nil
+}
+
+func TestClientPersistsPaymentsAndStatusChanges(t *testing.T) {
+	provider := &storedProvider{namedProvider: namedProvider{name: ProviderBPay}, status: PaymentStatusPending}
+	client := newFailoverTestClient(t, nil, provider)
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	ctx := context.Background()
+
+	_, err := client.ProcessPayment(ctx, &PaymentRequest{Amount: money.NewMRU(5000), Reference: "REF-1"})
+	require.NoError(t, err)
+
+	stored, err := store.GetByReference(ctx, "REF-1")
+	require.NoError(t, err)
+	assert.Equal(t, "TX-1", stored.TransactionID)
+	assert.Equal(t, ProviderBPay, stored.Provider)
+	assert.Equal(t, PaymentStatusPending, stored.Status)
+	assert.True(t, stored.Amount.Equals(money.NewMRU(5000)))
+	require.Len(t, stored.Events, 1)
+
+	// An unchanged status is not recorded again
+	_, err = client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	stored, err = store.GetByTransactionID(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Len(t, stored.Events, 1)
+
+	provider.status = PaymentStatusSuccess
+	_, err = client.GetPaymentStatus(ctx, "TX-1")
+	require.NoError(t, err)
+	stored, err = store.GetByTransactionID(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, stored.Status)
+	require.Len(t, stored.Events, 2)
+	assert.Equal(t, EventSourceInitial, stored.Events[0].Source)
+	assert.Equal(t, EventSourcePoll, stored.Events[1].Source)
+
+	pending, err := store.ListPending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestClientPersistsNotificationsByReference(t *testing.T) {
+	status := &TransactionStatus{TransactionID: "PAY-9", Reference: "ORDER-1", ProviderReference: "PR-9"}
+	status.AddEvent(PaymentStatusSuccess, "paid", EventSourceWebhook)
+	client := newReturnTestClient(t, PaymentStatusSuccess, "")
+	client.providers[ProviderMasrvi] = &fakeMasrviProvider{status: status}
+
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	ctx := context.Background()
+	require.NoError(t, store.SavePayment(ctx, &PaymentResponse{
+		TransactionID: "ORDER-1",
+		Reference:     "ORDER-1",
+		Status:        PaymentStatusPending,
+		Provider:      ProviderMasrvi,
+		UpdatedAt:     time.Now().Add(-time.Minute),
+	}))
+
+	_, err := client.HandleMasrviNotification(&MasrviNotificationData{Reference: "ORDER-1"})
+	require.NoError(t, err)
+
+	stored, err := store.GetByReference(ctx, "ORDER-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER-1", stored.TransactionID)
+	assert.Equal(t, PaymentStatusSuccess, stored.Status)
+	assert.Equal(t, "PR-9", stored.ProviderReference)
+	require.Len(t, stored.Events, 1)
+	assert.Equal(t, EventSourceWebhook, stored.Events[0].Source)
+}
+
+func TestMemoryTransactionStoreLastWriteWins(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	ctx := context.Background()
+	base := time.Unix(1000, 0)
+	require.NoError(t, store.SavePayment(ctx, &PaymentResponse{
+		TransactionID: "TX-1", Reference: "REF-1", Status: PaymentStatusPending, CreatedAt: base, UpdatedAt: base,
+	}))
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 1; i <= writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			at := base.Add(time.Duration(i) * time.Second)
+			status := PaymentStatusPending
+			if i == writers/2 {
+				status = PaymentStatusSuccess
+			}
+			require.NoError(t, store.UpdateStatus(ctx, &TransactionStatus{
+				TransactionID: "TX-1",
+				Status:        status,
+				Message:       fmt.Sprint(i),
+				LastUpdated:   at,
+				EventLog:      []StatusEvent{{Status: status, Message: fmt.Sprint(i), At: at, Source: EventSourcePoll}},
+			}))
+		}(i)
+	}
+	wg.Wait()
+
+	stored, err := store.GetByTransactionID(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(writers), stored.Message)
+	assert.Equal(t, base.Add(writers*time.Second), stored.LastUpdated)
+	require.Len(t, stored.Events, writers)
+	for i := 1; i < len(stored.Events); i++ {
+		assert.True(t, stored.Events[i-1].At.Before(stored.Events[i].At))
+	}
+
+	// Replaying an old status adds nothing and changes nothing
+	require.NoError(t, store.UpdateStatus(ctx, &TransactionStatus{
+		TransactionID: "TX-1",
+		Status:        PaymentStatusFailed,
+		LastUpdated:   base.Add(time.Second),
+		EventLog:      stored.Events[:1],
+	}))
+	again, err := store.GetByTransactionID(ctx, "TX-1")
+	require.NoError(t, err)
+	assert.Equal(t, stored, again)
+}
+
+func TestMemoryTransactionStoreLookups(t *testing.T) {
+	store := NewMemoryTransactionStore()
+	ctx := context.Background()
+
+	_, err := store.GetByTransactionID(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, err = store.GetByReference(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	assert.ErrorIs(t, store.SavePayment(ctx, &PaymentResponse{}), ErrInvalidRequest)
+	assert.ErrorIs(t, store.UpdateStatus(ctx, &TransactionStatus{}), ErrInvalidRequest)
+
+	base := time.Unix(1000, 0)
+	for i, id := range []string{"TX-2", "TX-1", "TX-3"} {
+		require.NoError(t, store.SavePayment(ctx, &PaymentResponse{
+			TransactionID: id, Reference: "REF-" + id, Status: PaymentStatusPending,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, store.UpdateStatus(ctx, &TransactionStatus{
+		TransactionID: "TX-1", Status: PaymentStatusFailed, LastUpdated: base.Add(time.Hour),
+	}))
+	// A status for an unknown transaction creates it
+	require.NoError(t, store.UpdateStatus(ctx, &TransactionStatus{
+		TransactionID: "TX-4", Reference: "REF-TX-4", Status: PaymentStatusSuccess, LastUpdated: base,
+	}))
+
+	pending, err := store.ListPending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "TX-2", pending[0].TransactionID)
+	assert.Equal(t, "TX-3", pending[1].TransactionID)
+
+	stored, err := store.GetByReference(ctx, "REF-TX-4")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, stored.Status)
+}
+
+func TestSQLTransactionStoreRebind(t *testing.T) {
+	query := "UPDATE t SET a = ? WHERE b = ? AND c <= ?"
+	assert.Equal(t, query, NewSQLTransactionStore(nil, SQLPlaceholderQuestion).rebind(query))
+	assert.Equal(t, "UPDATE t SET a = $1 WHERE b = $2 AND c <= $3",
+		NewSQLTransactionStore(nil, SQLPlaceholderDollar).rebind(query))
+}
@@ -0,0 +1,227 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// RoutingStrategy chooses the provider for a payment among the available
+// providers, which are enabled, healthy and sorted by name. A strategy that
+// has no opinion about a payment returns ErrNoRoute.
+type RoutingStrategy interface {
+	ChooseProvider(ctx context.Context, request *PaymentRequest, available []string) (string, error)
+}
+
+// pick returns name if it is available, otherwise ErrNoRoute
+func pick(name string, available []string) (string, error) {
+	for _, candidate := range available {
+		if candidate == name {
+			return name, nil
+		}
+	}
+	return "", ErrNoRoute
+}
+
+// AmountThreshold routes amounts below Below, in cents (khoums), to Provider.
+// A zero Below matches any amount.
+type AmountThreshold struct {
+	Below    int64
+	Provider string
+}
+
+type amountStrategy struct {
+	thresholds []AmountThreshold
+}
+
+// ByAmountThresholds routes by amount; thresholds are checked in order and
+// the first one the amount falls below wins
+func ByAmountThresholds(thresholds ...AmountThreshold) RoutingStrategy {
+	return &amountStrategy{thresholds: thresholds}
+}
+
+func (s *amountStrategy) ChooseProvider(_ context.Context, request *PaymentRequest, available []string) (string, error) {
+	cents := request.Amount.Cents()
+	for _, threshold := range s.thresholds {
+		if threshold.Below == 0 || cents < threshold.Below {
+			return pick(threshold.Provider, available)
+		}
+	}
+	return "", ErrNoRoute
+}
+
+type operatorStrategy struct {
+	operators map[phone.Operator]string
+	resolver  phone.PortabilityResolver
+}
+
+// ByOperator routes by the mobile operator of the payer's phone number,
+// derived from its prefix
+func ByOperator(operators map[phone.Operator]string) RoutingStrategy {
+	return &operatorStrategy{operators: operators}
+}
+
+func (s *operatorStrategy) ChooseProvider(ctx context.Context, request *PaymentRequest, available []string) (string, error) {
+	if request.PhoneNumber == nil {
+		return "", ErrNoRoute
+	}
+	// A failed portability lookup still yields the prefix-derived operator
+	op, _ := phone.ResolveOperator(ctx, s.resolver, request.PhoneNumber)
+	name, ok := s.operators[op]
+	if !ok {
+		return "", ErrNoRoute
+	}
+	return pick(name, available)
+}
+
+// ProviderWeight gives Provider Weight shares of the traffic in WeightedSplit
+type ProviderWeight struct {
+	Provider string
+	Weight   int
+}
+
+type weightedStrategy struct {
+	weights []ProviderWeight
+	mu      sync.Mutex
+	rng     *rand.Rand
+}
+
+// WeightedSplit spreads payments across providers in proportion to their
+// weights, e.g. 90/10 while migrating traffic to a new provider. Weights of
+// unavailable providers are ignored. A nil source seeds from the clock.
+func WeightedSplit(source rand.Source, weights ...ProviderWeight) RoutingStrategy {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &weightedStrategy{weights: weights, rng: rand.New(source)}
+}
+
+func (s *weightedStrategy) ChooseProvider(_ context.Context, _ *PaymentRequest, available []string) (string, error) {
+	var candidates []ProviderWeight
+	total := 0
+	for _, w := range s.weights {
+		if _, err := pick(w.Provider, available); err != nil || w.Weight <= 0 {
+			continue
+		}
+		candidates = append(candidates, w)
+		total += w.Weight
+	}
+	if total == 0 {
+		return "", ErrNoRoute
+	}
+
+	s.mu.Lock()
+	n := s.rng.Intn(total)
+	s.mu.Unlock()
+
+	for _, w := range candidates {
+		if n < w.Weight {
+			return w.Provider, nil
+		}
+		n -= w.Weight
+	}
+	return "", ErrNoRoute
+}
+
+type chainStrategy struct {
+	strategies []RoutingStrategy
+}
+
+// Chain tries strategies in order; the first one to choose a provider wins
+func Chain(strategies ...RoutingStrategy) RoutingStrategy {
+	return &chainStrategy{strategies: strategies}
+}
+
+func (s *chainStrategy) ChooseProvider(ctx context.Context, request *PaymentRequest, available []string) (string, error) {
+	for _, strategy := range s.strategies {
+		name, err := strategy.ChooseProvider(ctx, request, available)
+		if errors.Is(err, ErrNoRoute) {
+			continue
+		}
+		return name, err
+	}
+	return "", ErrNoRoute
+}
+
+// availableProviders returns the registered providers that are not disabled
+// in the configuration and report themselves available
+func (c *Client) availableProviders(ctx context.Context) map[string]PaymentProvider {
+	enabled := c.enabledProviders()
+	available := make(map[string]PaymentProvider, len(enabled))
+	for name, provider := range enabled {
+		if provider.IsAvailable(ctx) {
+			available[name] = provider
+		}
+	}
+	return available
+}
+
+// routingStrategy returns Config.Routing.Strategy, or operator routing from
+// Config.Routing.Operators when no strategy is set
+func (c *Client) routingStrategy() RoutingStrategy {
+	routing := c.currentConfig().Routing
+	if routing.Strategy != nil {
+		return routing.Strategy
+	}
+	return &operatorStrategy{operators: routing.Operators, resolver: c.operators}
+}
+
+// ProcessRouted processes a payment with the provider chosen by the
+// configured routing strategy among the providers whose Capabilities accept
+// the request. When no strategy matches, the default provider is used if it
+// is available.
+func (c *Client) ProcessRouted(ctx context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	if request == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	ctx, _, err := ensureCorrelationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := c.availableProviders(ctx)
+	for name, provider := range providers {
+		if !provider.Capabilities().accepts(request) {
+			delete(providers, name)
+		}
+	}
+	available := sortedProviderNames(providers)
+
+	name, err := c.routingStrategy().ChooseProvider(ctx, request, available)
+	if errors.Is(err, ErrNoRoute) {
+		name, err = pick(c.currentConfig().DefaultProvider, available)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("routing payment %s: %w", request.Reference, err)
+	}
+
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("routing payment %s: strategy chose unavailable provider %s", request.Reference, name)
+	}
+
+	if err := c.checkLimits(name, request.Amount, request.Reference); err != nil {
+		return nil, err
+	}
+
+	request, err = c.prepareRequest(name, request)
+	if err != nil {
+		return nil, err
+	}
+
+	ContextLogger(ctx, c.logger).Debug("Payment routed", "reference", request.Reference, "provider", name)
+
+	result, err := c.invokePayment(ctx, name, request.Reference, request.Amount, func(ctx context.Context) (*PaymentResponse, error) {
+		return provider.ProcessPayment(ctx, request)
+	})
+	if err == nil {
+		c.savePayment(ctx, result)
+	}
+	return result, err
+}
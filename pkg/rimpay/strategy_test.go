@@ -0,0 +1,216 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// downProvider is registered but reports itself unavailable
+type downProvider struct {
+	namedProvider
+}
+
+func (p *downProvider) IsAvailable(context.Context) bool { return false }
+
+func routedRequest(t *testing.T, number string, cents int64) *PaymentRequest {
+	t.Helper()
+	p, err := phone.NewPhone(number)
+	require.NoError(t, err)
+	return &PaymentRequest{PhoneNumber: p, Amount: money.NewMRU(cents), Reference: "REF-1"}
+}
+
+func TestByAmountThresholds(t *testing.T) {
+	strategy := ByAmountThresholds(
+		AmountThreshold{Below: 5000, Provider: ProviderBPay},
+		AmountThreshold{Below: 20000, Provider: ProviderClick},
+		AmountThreshold{Provider: ProviderMasrvi},
+	)
+	available := []string{ProviderBPay, ProviderClick, ProviderMasrvi}
+
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{1000, ProviderBPay},
+		{4999, ProviderBPay},
+		{5000, ProviderClick},
+		{19999, ProviderClick},
+		{50000, ProviderMasrvi},
+	}
+	for _, tt := range tests {
+		name, err := strategy.ChooseProvider(context.Background(), routedRequest(t, "22334455", tt.cents), available)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, name, "amount %d", tt.cents)
+	}
+
+	_, err := strategy.ChooseProvider(context.Background(), routedRequest(t, "22334455", 1000), []string{ProviderMasrvi})
+	assert.ErrorIs(t, err, ErrNoRoute, "unavailable providers are never chosen")
+}
+
+func TestByOperator(t *testing.T) {
+	strategy := ByOperator(map[phone.Operator]string{
+		phone.OperatorChinguitel: ProviderMasrvi,
+		phone.OperatorMauritel:   ProviderBPay,
+	})
+	available := []string{ProviderBPay, ProviderMasrvi}
+
+	name, err := strategy.ChooseProvider(context.Background(), routedRequest(t, "33445566", 1000), available)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderMasrvi, name)
+
+	name, err = strategy.ChooseProvider(context.Background(), routedRequest(t, "22334455", 1000), available)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderBPay, name)
+
+	_, err = strategy.ChooseProvider(context.Background(), routedRequest(t, "44556677", 1000), available)
+	assert.ErrorIs(t, err, ErrNoRoute)
+
+	_, err = strategy.ChooseProvider(context.Background(), &PaymentRequest{}, available)
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
+
+func TestWeightedSplitDistribution(t *testing.T) {
+	strategy := WeightedSplit(rand.NewSource(42),
+		ProviderWeight{Provider: ProviderBPay, Weight: 90},
+		ProviderWeight{Provider: ProviderMasrvi, Weight: 10},
+	)
+	available := []string{ProviderBPay, ProviderMasrvi}
+	request := routedRequest(t, "22334455", 1000)
+
+	const calls = 10000
+	counts := make(map[string]int)
+	for i := 0; i < calls; i++ {
+		name, err := strategy.ChooseProvider(context.Background(), request, available)
+		require.NoError(t, err)
+		counts[name]++
+	}
+
+	assert.InDelta(t, 0.90, float64(counts[ProviderBPay])/calls, 0.02)
+	assert.InDelta(t, 0.10, float64(counts[ProviderMasrvi])/calls, 0.02)
+}
+
+func TestWeightedSplitSkipsUnavailable(t *testing.T) {
+	strategy := WeightedSplit(rand.NewSource(1),
+		ProviderWeight{Provider: ProviderBPay, Weight: 90},
+		ProviderWeight{Provider: ProviderMasrvi, Weight: 10},
+	)
+	request := routedRequest(t, "22334455", 1000)
+
+	for i := 0; i < 100; i++ {
+		name, err := strategy.ChooseProvider(context.Background(), request, []string{ProviderMasrvi})
+		require.NoError(t, err)
+		assert.Equal(t, ProviderMasrvi, name)
+	}
+
+	_, err := strategy.ChooseProvider(context.Background(), request, []string{ProviderClick})
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
+
+type failingStrategy struct{ err error }
+
+func (s failingStrategy) ChooseProvider(context.Context, *PaymentRequest, []string) (string, error) {
+	return "", s.err
+}
+
+func TestChainFirstMatchWins(t *testing.T) {
+	strategy := Chain(
+		ByOperator(map[phone.Operator]string{phone.OperatorChinguitel: ProviderMasrvi}),
+		ByAmountThresholds(AmountThreshold{Below: 5000, Provider: ProviderClick}),
+		ByAmountThresholds(AmountThreshold{Provider: ProviderBPay}),
+	)
+	available := []string{ProviderBPay, ProviderClick, ProviderMasrvi}
+
+	tests := []struct {
+		number string
+		cents  int64
+		want   string
+	}{
+		{"33445566", 100000, ProviderMasrvi},
+		{"22334455", 1000, ProviderClick},
+		{"22334455", 100000, ProviderBPay},
+	}
+	for _, tt := range tests {
+		name, err := strategy.ChooseProvider(context.Background(), routedRequest(t, tt.number, tt.cents), available)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, name)
+	}
+
+	boom := errors.New("boom")
+	_, err := Chain(failingStrategy{err: ErrNoRoute}, failingStrategy{err: boom}).
+		ChooseProvider(context.Background(), &PaymentRequest{}, available)
+	assert.ErrorIs(t, err, boom)
+}
+
+func newStrategyTestClient(t *testing.T, strategy RoutingStrategy, providers ...PaymentProvider) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	config.Providers[ProviderClick] = ProviderConfig{Enabled: false}
+	config.Routing.Strategy = strategy
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	for _, provider := range providers {
+		require.NoError(t, client.AddProvider(provider.Name(), provider))
+	}
+	return client
+}
+
+func TestProcessRoutedUsesHealthyEnabledProviders(t *testing.T) {
+	var seen []string
+	strategy := strategyFunc(func(_ context.Context, _ *PaymentRequest, available []string) (string, error) {
+		seen = available
+		return ProviderMasrvi, nil
+	})
+	client := newStrategyTestClient(t, strategy,
+		&namedProvider{name: ProviderBPay},
+		&namedProvider{name: ProviderMasrvi},
+		&namedProvider{name: ProviderClick},
+		&downProvider{namedProvider{name: "sedad"}},
+	)
+
+	resp, err := client.ProcessRouted(context.Background(), routedRequest(t, "22334455", 1000))
+	require.NoError(t, err)
+	assert.Equal(t, ProviderMasrvi, resp.TransactionID)
+	assert.Equal(t, []string{ProviderBPay, ProviderMasrvi}, seen, "disabled and unhealthy providers are excluded")
+}
+
+func TestProcessRoutedFallsBackToDefault(t *testing.T) {
+	client := newStrategyTestClient(t, ByAmountThresholds(AmountThreshold{Below: 100, Provider: ProviderMasrvi}),
+		&namedProvider{name: ProviderBPay},
+		&namedProvider{name: ProviderMasrvi},
+	)
+
+	resp, err := client.ProcessRouted(context.Background(), routedRequest(t, "22334455", 1000))
+	require.NoError(t, err)
+	assert.Equal(t, ProviderBPay, resp.TransactionID)
+}
+
+func TestProcessRoutedRejectsUnavailableChoice(t *testing.T) {
+	client := newStrategyTestClient(t, failingStrategy{err: nil},
+		&namedProvider{name: ProviderBPay},
+	)
+
+	_, err := client.ProcessRouted(context.Background(), routedRequest(t, "22334455", 1000))
+	assert.Error(t, err)
+
+	client = newStrategyTestClient(t, ByAmountThresholds(), &downProvider{namedProvider{name: ProviderBPay}})
+	_, err = client.ProcessRouted(context.Background(), routedRequest(t, "22334455", 1000))
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
+
+// strategyFunc adapts a function to RoutingStrategy
+type strategyFunc func(context.Context, *PaymentRequest, []string) (string, error)
+
+func (f strategyFunc) ChooseProvider(ctx context.Context, request *PaymentRequest, available []string) (string, error) {
+	return f(ctx, request, available)
+}
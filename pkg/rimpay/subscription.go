@@ -0,0 +1,584 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/internal/providers/common"
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+)
+
+// DefaultSubscriptionRetryDelay separates the attempts of a failed cycle
+// unless the subscription's retry policy sets a delay
+const DefaultSubscriptionRetryDelay = time.Hour
+
+// SubscriptionID identifies a subscription created with CreateSubscription
+type SubscriptionID string
+
+// SubscriptionInterval is how often a subscription is charged
+type SubscriptionInterval string
+
+const (
+	SubscriptionDaily   SubscriptionInterval = "daily"
+	SubscriptionWeekly  SubscriptionInterval = "weekly"
+	SubscriptionMonthly SubscriptionInterval = "monthly"
+)
+
+// SubscriptionState is the state of a subscription
+type SubscriptionState string
+
+const (
+	SubscriptionActive    SubscriptionState = "active"
+	SubscriptionPaused    SubscriptionState = "paused"
+	SubscriptionCancelled SubscriptionState = "cancelled"
+	// SubscriptionCompleted ran its MaxOccurrences cycles
+	SubscriptionCompleted SubscriptionState = "completed"
+)
+
+// CycleState is the state of a subscription cycle
+type CycleState string
+
+const (
+	// CycleRunning is submitting a payment
+	CycleRunning CycleState = "running"
+	// CyclePending waits for the final status of an accepted payment
+	CyclePending CycleState = "pending"
+	// CycleRetrying failed its last attempt and retries at NextAttemptAt
+	CycleRetrying  CycleState = "retrying"
+	CycleSucceeded CycleState = "succeeded"
+	// CycleFailed failed every attempt allowed by the retry policy
+	CycleFailed CycleState = "failed"
+)
+
+// SubscriptionRetryPolicy sets how a failed cycle is retried
+type SubscriptionRetryPolicy struct {
+	// MaxAttempts is the number of payments tried per cycle; 0 means 1
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Delay separates attempts; 0 means DefaultSubscriptionRetryDelay
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+func (p SubscriptionRetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p SubscriptionRetryPolicy) delay() time.Duration {
+	if p.Delay <= 0 {
+		return DefaultSubscriptionRetryDelay
+	}
+	return p.Delay
+}
+
+// Subscription charges a customer the same amount every interval. The
+// fields up to Metadata are set by the caller; the others are maintained by
+// the client.
+type Subscription struct {
+	ID SubscriptionID `json:"id"`
+	// Provider processes the payments; empty means the default provider
+	Provider    string               `json:"provider,omitempty"`
+	PhoneNumber *phone.Phone         `json:"phone_number"`
+	Amount      money.Money          `json:"amount"`
+	Description string               `json:"description,omitempty"`
+	Interval    SubscriptionInterval `json:"interval"`
+	// DayOfMonth is the day monthly subscriptions are charged; 0 means the
+	// day of StartAt. Shorter months are charged on their last day.
+	DayOfMonth int `json:"day_of_month,omitempty"`
+	// MaxOccurrences ends the subscription after that many cycles; 0 means
+	// no limit
+	MaxOccurrences int `json:"max_occurrences,omitempty"`
+	// StartAt is the earliest first charge and sets the time of day of every
+	// charge; zero means now
+	StartAt  time.Time               `json:"start_at"`
+	Retry    SubscriptionRetryPolicy `json:"retry"`
+	Metadata map[string]interface{}  `json:"metadata,omitempty"`
+
+	State     SubscriptionState `json:"state"`
+	CreatedAt time.Time         `json:"created_at"`
+	// Occurrences counts the cycles started
+	Occurrences int `json:"occurrences"`
+	// NextRunAt is when the next cycle is due
+	NextRunAt time.Time `json:"next_run_at"`
+	// Current is the cycle in progress
+	Current *SubscriptionCycle `json:"current,omitempty"`
+	// Cycles are the finished cycles, oldest first
+	Cycles []SubscriptionCycle `json:"cycles,omitempty"`
+}
+
+// SubscriptionCycle is one billing period of a subscription
+type SubscriptionCycle struct {
+	// Period is the billed month as 2006-01, or day as 2006-01-02 for daily
+	// and weekly subscriptions
+	Period string    `json:"period"`
+	DueAt  time.Time `json:"due_at"`
+	// Reference is the payment reference of the latest attempt:
+	// SUB-<id>-<period>, followed by -<attempt> for retries
+	Reference     string           `json:"reference"`
+	State         CycleState       `json:"state"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at,omitempty"`
+	Response      *PaymentResponse `json:"response,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	CompletedAt   time.Time        `json:"completed_at,omitempty"`
+}
+
+// SubscriptionCallback receives the outcome of every finished cycle
+type SubscriptionCallback func(ctx context.Context, subscription *Subscription, cycle SubscriptionCycle)
+
+// validate checks the caller's fields of a new subscription
+func (s *Subscription) validate() error {
+	var errs ValidationErrors
+	if s.PhoneNumber == nil {
+		errs.Add("phone_number", "is required")
+	}
+	if !s.Amount.IsPositive() {
+		errs.Add("amount", "must be positive")
+	}
+	switch s.Interval {
+	case SubscriptionDaily, SubscriptionWeekly, SubscriptionMonthly:
+	default:
+		errs.Add("interval", fmt.Sprintf("unknown interval %q", s.Interval))
+	}
+	if s.DayOfMonth < 0 || s.DayOfMonth > 31 {
+		errs.Add("day_of_month", "must be between 1 and 31")
+	} else if s.DayOfMonth != 0 && s.Interval != SubscriptionMonthly {
+		errs.Add("day_of_month", "applies to monthly subscriptions only")
+	}
+	if s.MaxOccurrences < 0 {
+		errs.Add("max_occurrences", "cannot be negative")
+	}
+	if s.Retry.MaxAttempts < 0 || s.Retry.Delay < 0 {
+		errs.Add("retry", "cannot be negative")
+	}
+	return errs.Err()
+}
+
+// cycleDue returns when cycle n, counted from 0, is due
+func (s *Subscription) cycleDue(n int) time.Time {
+	switch s.Interval {
+	case SubscriptionDaily:
+		return s.StartAt.AddDate(0, 0, n)
+	case SubscriptionWeekly:
+		return s.StartAt.AddDate(0, 0, 7*n)
+	}
+
+	day := s.DayOfMonth
+	if day == 0 {
+		day = s.StartAt.Day()
+	}
+	if monthDay(s.StartAt, 0, day).Before(s.StartAt) {
+		n++
+	}
+	return monthDay(s.StartAt, n, day)
+}
+
+// monthDay returns day of the month months after start's, at start's time
+// of day, or the last day of that month when it is shorter
+func monthDay(start time.Time, months, day int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// period names the billing period of a cycle due at dueAt
+func (s *Subscription) period(dueAt time.Time) string {
+	if s.Interval == SubscriptionMonthly {
+		return dueAt.Format("2006-01")
+	}
+	return dueAt.Format("2006-01-02")
+}
+
+// paymentRequest builds the request of the cycle's current attempt
+func (s *Subscription) paymentRequest(cycle *SubscriptionCycle) *PaymentRequest {
+	metadata := make(map[string]interface{}, len(s.Metadata)+2)
+	for key, value := range s.Metadata {
+		metadata[key] = value
+	}
+	metadata["subscription_id"] = string(s.ID)
+	metadata["subscription_period"] = cycle.Period
+
+	request := &PaymentRequest{
+		Amount:      s.Amount,
+		PhoneNumber: s.PhoneNumber,
+		Reference:   cycle.Reference,
+		Description: s.Description,
+		Metadata:    metadata,
+	}
+	// Nobody is at hand to type a B-PAY passcode
+	if s.Provider == ProviderBPay {
+		request.Mode = BPayModeUSSDPush
+	}
+	return request
+}
+
+// copy returns a copy of the subscription that shares no cycles with it
+func (s *Subscription) copy() *Subscription {
+	dup := *s
+	if s.Current != nil {
+		current := *s.Current
+		dup.Current = &current
+	}
+	dup.Cycles = append([]SubscriptionCycle(nil), s.Cycles...)
+	return &dup
+}
+
+// subscriptionBook keeps the client's subscriptions
+type subscriptionBook struct {
+	mu            sync.Mutex
+	subscriptions map[SubscriptionID]*Subscription
+	callback      SubscriptionCallback
+}
+
+func newSubscriptionBook() *subscriptionBook {
+	return &subscriptionBook{subscriptions: make(map[SubscriptionID]*Subscription)}
+}
+
+// subscriptionIDFormat keeps subscription IDs short, and so cycle
+// references within provider length limits
+var subscriptionIDFormat = common.IDFormat{RandomLength: 8, NoTimestamp: true}
+
+// add stores subscription under a new ID
+func (b *subscriptionBook) add(subscription *Subscription) (SubscriptionID, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		id, err := subscriptionIDFormat.Generate()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := b.subscriptions[SubscriptionID(id)]; !ok {
+			subscription.ID = SubscriptionID(id)
+			b.subscriptions[subscription.ID] = subscription
+			return subscription.ID, nil
+		}
+	}
+}
+
+// get returns a copy of the subscription
+func (b *subscriptionBook) get(id SubscriptionID) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscription, ok := b.subscriptions[id]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription.copy(), nil
+}
+
+// list returns copies of every subscription, oldest first
+func (b *subscriptionBook) list() []*Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscriptions := make([]*Subscription, 0, len(b.subscriptions))
+	for _, subscription := range b.subscriptions {
+		subscriptions = append(subscriptions, subscription.copy())
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].ID < subscriptions[j].ID
+		}
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
+
+// transition moves the subscription from one of from to state
+func (b *subscriptionBook) transition(id SubscriptionID, state SubscriptionState, from ...SubscriptionState) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscription, ok := b.subscriptions[id]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	for _, allowed := range from {
+		if subscription.State == allowed {
+			subscription.State = state
+			return nil
+		}
+	}
+	return fmt.Errorf("subscription %s is %s: %w", id, subscription.State, ErrSubscriptionState)
+}
+
+// claim returns the work due for the subscription at now: a copy of its
+// cycle to pay, with the attempt already counted, or of its pending cycle to
+// check. It returns a nil cycle when nothing is due.
+func (b *subscriptionBook) claim(id SubscriptionID, now time.Time) (*Subscription, *SubscriptionCycle) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscription, ok := b.subscriptions[id]
+	if !ok || subscription.State != SubscriptionActive {
+		return nil, nil
+	}
+
+	if cycle := subscription.Current; cycle != nil {
+		switch {
+		case cycle.State == CyclePending:
+		case cycle.State == CycleRetrying && !now.Before(cycle.NextAttemptAt):
+			cycle.State = CycleRunning
+			cycle.Attempts++
+			cycle.Reference = fmt.Sprintf("SUB-%s-%s-%d", subscription.ID, cycle.Period, cycle.Attempts)
+		default:
+			return nil, nil
+		}
+		dup := *cycle
+		return subscription.copy(), &dup
+	}
+
+	if now.Before(subscription.NextRunAt) {
+		return nil, nil
+	}
+	period := subscription.period(subscription.NextRunAt)
+	subscription.Current = &SubscriptionCycle{
+		Period:    period,
+		DueAt:     subscription.NextRunAt,
+		Reference: fmt.Sprintf("SUB-%s-%s", subscription.ID, period),
+		State:     CycleRunning,
+		Attempts:  1,
+	}
+	subscription.Occurrences++
+	subscription.NextRunAt = subscription.cycleDue(subscription.Occurrences)
+	dup := *subscription.Current
+	return subscription.copy(), &dup
+}
+
+// record stores the outcome of the cycle's latest attempt. A failure is
+// retried while the retry policy allows. It returns the subscription and
+// cycle when the cycle finished.
+func (b *subscriptionBook) record(id SubscriptionID, outcome CycleState, response *PaymentResponse, failure string, now time.Time) (*Subscription, *SubscriptionCycle) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscription, ok := b.subscriptions[id]
+	if !ok || subscription.Current == nil {
+		return nil, nil
+	}
+
+	cycle := subscription.Current
+	if response != nil {
+		cycle.Response = response
+	}
+	cycle.Error = failure
+	if outcome == CycleFailed && cycle.Attempts < subscription.Retry.maxAttempts() {
+		outcome = CycleRetrying
+		cycle.NextAttemptAt = now.Add(subscription.Retry.delay())
+	}
+	cycle.State = outcome
+	if outcome != CycleSucceeded && outcome != CycleFailed {
+		return nil, nil
+	}
+
+	cycle.NextAttemptAt = time.Time{}
+	cycle.CompletedAt = now
+	subscription.Cycles = append(subscription.Cycles, *cycle)
+	subscription.Current = nil
+	if subscription.MaxOccurrences > 0 && subscription.Occurrences >= subscription.MaxOccurrences &&
+		subscription.State == SubscriptionActive {
+		subscription.State = SubscriptionCompleted
+	}
+	finished := subscription.Cycles[len(subscription.Cycles)-1]
+	return subscription.copy(), &finished
+}
+
+func (b *subscriptionBook) ids() []SubscriptionID {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]SubscriptionID, 0, len(b.subscriptions))
+	for id := range b.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (b *subscriptionBook) onCycle() SubscriptionCallback {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.callback
+}
+
+// WithSubscriptionCallback makes the client call callback with the outcome
+// of every finished subscription cycle; nil stops the calls. The callback
+// runs on the scheduler goroutine and should return quickly.
+func (c *Client) WithSubscriptionCallback(callback SubscriptionCallback) *Client {
+	c.subscriptions.mu.Lock()
+	defer c.subscriptions.mu.Unlock()
+	c.subscriptions.callback = callback
+	return c
+}
+
+// CreateSubscription starts charging subscription.Amount every interval,
+// from StartAt on, through the subscription's provider. Cycles run with the
+// scheduler started by StartScheduler. Subscriptions are kept in memory by
+// the client, and their payments are saved in its TransactionStore.
+func (c *Client) CreateSubscription(ctx context.Context, subscription *Subscription) (SubscriptionID, error) {
+	if c.isClosed() {
+		return "", ErrClientClosed
+	}
+	if subscription == nil {
+		return "", ErrInvalidRequest
+	}
+	if err := subscription.validate(); err != nil {
+		return "", err
+	}
+
+	created := subscription.copy()
+	if created.Provider == "" {
+		created.Provider = c.currentConfig().DefaultProvider
+	}
+	if _, ok := c.registered(created.Provider); !ok {
+		return "", fmt.Errorf("subscription provider %s: %w", created.Provider, ErrProviderNotFound)
+	}
+
+	now := c.scheduler.now()
+	if created.StartAt.IsZero() {
+		created.StartAt = now
+	}
+	created.State = SubscriptionActive
+	created.CreatedAt = now
+	created.Occurrences = 0
+	created.Current = nil
+	created.Cycles = nil
+	created.NextRunAt = created.cycleDue(0)
+
+	id, err := c.subscriptions.add(created)
+	if err != nil {
+		return "", err
+	}
+	c.logger.Info("Subscription created", "id", id, "provider", created.Provider,
+		"interval", created.Interval, "next_run_at", created.NextRunAt)
+	return id, nil
+}
+
+// PauseSubscription stops charging an active subscription until
+// ResumeSubscription. A cycle in progress waits too.
+func (c *Client) PauseSubscription(ctx context.Context, id SubscriptionID) error {
+	return c.subscriptions.transition(id, SubscriptionPaused, SubscriptionActive)
+}
+
+// ResumeSubscription charges a paused subscription again. Cycles that fell
+// due while it was paused run one at a time.
+func (c *Client) ResumeSubscription(ctx context.Context, id SubscriptionID) error {
+	return c.subscriptions.transition(id, SubscriptionActive, SubscriptionPaused)
+}
+
+// CancelSubscription stops a subscription for good; a cycle in progress is
+// not retried
+func (c *Client) CancelSubscription(ctx context.Context, id SubscriptionID) error {
+	return c.subscriptions.transition(id, SubscriptionCancelled, SubscriptionActive, SubscriptionPaused)
+}
+
+// GetSubscription returns a subscription, or ErrSubscriptionNotFound
+func (c *Client) GetSubscription(ctx context.Context, id SubscriptionID) (*Subscription, error) {
+	return c.subscriptions.get(id)
+}
+
+// ListSubscriptions returns every subscription, oldest first
+func (c *Client) ListSubscriptions(ctx context.Context) []*Subscription {
+	return c.subscriptions.list()
+}
+
+// runSubscriptions runs the subscription cycles due now. A subscription
+// starts no cycle while the previous one is pending or being retried.
+func (c *Client) runSubscriptions(ctx context.Context) {
+	for _, id := range c.subscriptions.ids() {
+		if ctx.Err() != nil || c.isClosed() {
+			return
+		}
+		subscription, cycle := c.subscriptions.claim(id, c.scheduler.now())
+		if cycle == nil {
+			continue
+		}
+		if cycle.State == CyclePending {
+			c.checkSubscriptionCycle(ctx, subscription, cycle)
+		} else {
+			c.paySubscriptionCycle(ctx, subscription, cycle)
+		}
+	}
+}
+
+// paySubscriptionCycle makes the cycle's payment attempt
+func (c *Client) paySubscriptionCycle(ctx context.Context, subscription *Subscription, cycle *SubscriptionCycle) {
+	response, err := c.ProcessPaymentWithProvider(ctx, subscription.Provider, subscription.paymentRequest(cycle))
+	if err != nil {
+		c.logger.Warn("Subscription payment failed", "id", subscription.ID, "reference", cycle.Reference,
+			"attempt", cycle.Attempts, "error", err)
+		c.recordSubscriptionCycle(ctx, subscription.ID, CycleFailed, nil, err.Error())
+		return
+	}
+	c.recordSubscriptionCycle(ctx, subscription.ID, cycleOutcome(response.Status), response, "")
+}
+
+// checkSubscriptionCycle queries the final status of the cycle's payment.
+// Providers without status queries are read from the TransactionStore,
+// which their notifications update.
+func (c *Client) checkSubscriptionCycle(ctx context.Context, subscription *Subscription, cycle *SubscriptionCycle) {
+	transactionID := cycle.Response.TransactionID
+	status, err := c.GetPaymentStatusWithProvider(ctx, subscription.Provider, transactionID)
+	if errors.Is(err, ErrStatusNotSupported) {
+		var stored *StoredTransaction
+		if store := c.transactionStore(); store != nil {
+			if stored, err = store.GetByTransactionID(ctx, transactionID); err == nil {
+				status = &TransactionStatus{TransactionID: transactionID, Status: stored.Status, Message: stored.Message}
+			}
+		}
+	}
+	if err != nil {
+		c.logger.Warn("Failed to check subscription payment", "id", subscription.ID, "reference", cycle.Reference, "error", err)
+		return
+	}
+
+	outcome := cycleOutcome(status.Status)
+	if outcome == CyclePending {
+		c.logger.Debug("Subscription payment still pending", "id", subscription.ID, "reference", cycle.Reference)
+		return
+	}
+	response := *cycle.Response
+	response.Status = status.Status
+	c.recordSubscriptionCycle(ctx, subscription.ID, outcome, &response, status.Message)
+}
+
+// cycleOutcome maps a payment status to the outcome of an attempt
+func cycleOutcome(status PaymentStatus) CycleState {
+	switch status {
+	case PaymentStatusSuccess:
+		return CycleSucceeded
+	case PaymentStatusPending:
+		return CyclePending
+	default:
+		return CycleFailed
+	}
+}
+
+// recordSubscriptionCycle records an attempt and reports a finished cycle
+// to the callback
+func (c *Client) recordSubscriptionCycle(ctx context.Context, id SubscriptionID, outcome CycleState, response *PaymentResponse, message string) {
+	failure := ""
+	if outcome == CycleFailed {
+		failure = message
+		if failure == "" && response != nil {
+			failure = fmt.Sprintf("payment %s", response.Status)
+		}
+	}
+	subscription, cycle := c.subscriptions.record(id, outcome, response, failure, c.scheduler.now())
+	if cycle == nil {
+		return
+	}
+
+	if cycle.State == CycleFailed {
+		c.logger.Warn("Subscription cycle failed", "id", id, "period", cycle.Period, "attempts", cycle.Attempts)
+	} else {
+		c.logger.Info("Subscription cycle succeeded", "id", id, "period", cycle.Period)
+	}
+	if callback := c.subscriptions.onCycle(); callback != nil {
+		callback(ctx, subscription, *cycle)
+	}
+}
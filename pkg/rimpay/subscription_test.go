@@ -0,0 +1,259 @@
+package rimpay
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+	"github.com/CatoSystems/rim-pay/pkg/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// billingProvider answers payments with the statuses queued in outcomes,
+// failing once they run out, and status queries with status
+type billingProvider struct {
+	namedProvider
+	mu         sync.Mutex
+	outcomes   []PaymentStatus
+	status     PaymentStatus
+	references []string
+}
+
+func (p *billingProvider) ProcessPayment(_ context.Context, request *PaymentRequest) (*PaymentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.references = append(p.references, request.Reference)
+	if len(p.outcomes) == 0 {
+		return nil, NewPaymentError(ErrorCodeInsufficientFunds, "insufficient funds", p.name, false)
+	}
+	outcome := p.outcomes[0]
+	p.outcomes = p.outcomes[1:]
+	return &PaymentResponse{TransactionID: "TX-" + request.Reference, Reference: request.Reference, Status: outcome}, nil
+}
+
+func (p *billingProvider) GetPaymentStatus(_ context.Context, transactionID string) (*TransactionStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &TransactionStatus{TransactionID: transactionID, Status: p.status}, nil
+}
+
+func (p *billingProvider) queue(outcomes ...PaymentStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outcomes = append(p.outcomes, outcomes...)
+}
+
+func (p *billingProvider) settle(status PaymentStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+}
+
+func (p *billingProvider) paid() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.references...)
+}
+
+func newSubscriptionTestClient(t *testing.T, start time.Time) (*Client, *billingProvider, *fakeScheduleClock) {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderMasrvi
+	config.Providers[ProviderMasrvi] = ProviderConfig{Enabled: true, BaseURL: "https://masrvi.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+
+	provider := &billingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}
+	require.NoError(t, client.AddProvider(ProviderMasrvi, provider))
+	return client, provider, newFakeScheduleClock(client, start)
+}
+
+func monthlySubscription(t *testing.T) *Subscription {
+	t.Helper()
+	customer, err := phone.NewPhone("22233445566")
+	require.NoError(t, err)
+	return &Subscription{
+		PhoneNumber: customer,
+		Amount:      money.NewMRU(150000),
+		Description: "Electricity",
+		Interval:    SubscriptionMonthly,
+	}
+}
+
+func TestSubscriptionMonthlyDueDatesClampToMonthEnd(t *testing.T) {
+	subscription := &Subscription{
+		Interval:   SubscriptionMonthly,
+		DayOfMonth: 31,
+		StartAt:    time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t, time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC), subscription.cycleDue(0))
+	assert.Equal(t, time.Date(2026, 2, 28, 8, 0, 0, 0, time.UTC), subscription.cycleDue(1))
+	assert.Equal(t, time.Date(2026, 3, 31, 8, 0, 0, 0, time.UTC), subscription.cycleDue(2))
+	assert.Equal(t, time.Date(2026, 4, 30, 8, 0, 0, 0, time.UTC), subscription.cycleDue(3))
+	assert.Equal(t, time.Date(2027, 1, 31, 8, 0, 0, 0, time.UTC), subscription.cycleDue(12))
+
+	// A day already past in the start month begins the month after
+	subscription.DayOfMonth = 5
+	assert.Equal(t, time.Date(2026, 2, 5, 8, 0, 0, 0, time.UTC), subscription.cycleDue(0))
+
+	subscription = &Subscription{Interval: SubscriptionWeekly, StartAt: time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)}
+	assert.Equal(t, time.Date(2026, 1, 29, 8, 0, 0, 0, time.UTC), subscription.cycleDue(2))
+}
+
+func TestSubscriptionChargesEachCycle(t *testing.T) {
+	start := time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC)
+	client, provider, clock := newSubscriptionTestClient(t, start)
+	store := NewMemoryTransactionStore()
+	client.WithTransactionStore(store)
+	var cycles []SubscriptionCycle
+	client.WithSubscriptionCallback(func(_ context.Context, _ *Subscription, cycle SubscriptionCycle) {
+		cycles = append(cycles, cycle)
+	})
+	provider.queue(PaymentStatusSuccess, PaymentStatusSuccess)
+
+	subscription := monthlySubscription(t)
+	subscription.MaxOccurrences = 2
+	id, err := client.CreateSubscription(context.Background(), subscription)
+	require.NoError(t, err)
+	startScheduler(t, client, clock)
+
+	clock.advance(28 * 24 * time.Hour)
+	require.Len(t, cycles, 2)
+	assert.Equal(t, "SUB-"+string(id)+"-2026-01", cycles[0].Reference)
+	assert.Equal(t, CycleSucceeded, cycles[0].State)
+	assert.Equal(t, "2026-02", cycles[1].Period)
+	assert.Equal(t, time.Date(2026, 2, 28, 8, 0, 0, 0, time.UTC), cycles[1].DueAt)
+
+	stored, err := store.GetByReference(context.Background(), cycles[1].Reference)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentStatusSuccess, stored.Status)
+
+	got, err := client.GetSubscription(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, SubscriptionCompleted, got.State)
+	assert.Equal(t, 2, got.Occurrences)
+	assert.Len(t, got.Cycles, 2)
+
+	clock.advance(31 * 24 * time.Hour)
+	assert.Len(t, provider.paid(), 2, "a completed subscription is not charged again")
+}
+
+func TestSubscriptionRetriesFailedCycleThenMovesOn(t *testing.T) {
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	client, provider, clock := newSubscriptionTestClient(t, start)
+	var outcomes []CycleState
+	client.WithSubscriptionCallback(func(_ context.Context, _ *Subscription, cycle SubscriptionCycle) {
+		outcomes = append(outcomes, cycle.State)
+	})
+
+	subscription := monthlySubscription(t)
+	subscription.Retry = SubscriptionRetryPolicy{MaxAttempts: 2, Delay: time.Hour}
+	id, err := client.CreateSubscription(context.Background(), subscription)
+	require.NoError(t, err)
+	startScheduler(t, client, clock)
+
+	// The first attempt fails and waits an hour to retry
+	got, err := client.GetSubscription(context.Background(), id)
+	require.NoError(t, err)
+	require.NotNil(t, got.Current)
+	assert.Equal(t, CycleRetrying, got.Current.State)
+	assert.Equal(t, start.Add(time.Hour), got.Current.NextAttemptAt)
+
+	clock.advance(30 * time.Minute)
+	assert.Len(t, provider.paid(), 1)
+
+	clock.advance(30 * time.Minute)
+	assert.Equal(t, []CycleState{CycleFailed}, outcomes)
+	prefix := "SUB-" + string(id)
+	assert.Equal(t, []string{prefix + "-2026-03", prefix + "-2026-03-2"}, provider.paid())
+
+	got, err = client.GetSubscription(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, SubscriptionActive, got.State, "a failed cycle does not cancel the subscription")
+	assert.Contains(t, got.Cycles[0].Error, "insufficient funds")
+
+	provider.queue(PaymentStatusSuccess)
+	clock.advance(31 * 24 * time.Hour)
+	assert.Equal(t, []CycleState{CycleFailed, CycleSucceeded}, outcomes)
+	assert.Equal(t, prefix+"-2026-04", provider.paid()[2])
+}
+
+func TestSubscriptionWaitsForPendingCycle(t *testing.T) {
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	client, provider, clock := newSubscriptionTestClient(t, start)
+	provider.queue(PaymentStatusPending, PaymentStatusSuccess)
+	provider.settle(PaymentStatusPending)
+
+	subscription := monthlySubscription(t)
+	subscription.Interval = SubscriptionDaily
+	id, err := client.CreateSubscription(context.Background(), subscription)
+	require.NoError(t, err)
+	startScheduler(t, client, clock)
+
+	clock.advance(48 * time.Hour)
+	assert.Len(t, provider.paid(), 1, "no cycle starts while the previous one is pending")
+	got, err := client.GetSubscription(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, CyclePending, got.Current.State)
+
+	provider.settle(PaymentStatusSuccess)
+	clock.advance(time.Minute)
+	got, err = client.GetSubscription(context.Background(), id)
+	require.NoError(t, err)
+	require.Len(t, got.Cycles, 1)
+	assert.Equal(t, CycleSucceeded, got.Cycles[0].State)
+
+	clock.advance(time.Minute)
+	assert.Equal(t, "SUB-"+string(id)+"-2026-03-02", provider.paid()[1], "the overdue cycle runs next")
+}
+
+func TestPauseAndCancelSubscription(t *testing.T) {
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	client, provider, clock := newSubscriptionTestClient(t, start)
+	ctx := context.Background()
+
+	subscription := monthlySubscription(t)
+	subscription.StartAt = start.Add(time.Hour)
+	id, err := client.CreateSubscription(ctx, subscription)
+	require.NoError(t, err)
+	startScheduler(t, client, clock)
+
+	require.NoError(t, client.PauseSubscription(ctx, id))
+	clock.advance(2 * time.Hour)
+	assert.Empty(t, provider.paid())
+
+	require.NoError(t, client.ResumeSubscription(ctx, id))
+	provider.queue(PaymentStatusSuccess)
+	clock.advance(time.Minute)
+	assert.Len(t, provider.paid(), 1)
+
+	require.NoError(t, client.CancelSubscription(ctx, id))
+	clock.advance(31 * 24 * time.Hour)
+	assert.Len(t, provider.paid(), 1)
+
+	assert.ErrorIs(t, client.PauseSubscription(ctx, id), ErrSubscriptionState)
+	assert.ErrorIs(t, client.CancelSubscription(ctx, "missing"), ErrSubscriptionNotFound)
+}
+
+func TestCreateSubscriptionValidates(t *testing.T) {
+	client, _, _ := newSubscriptionTestClient(t, time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC))
+
+	_, err := client.CreateSubscription(context.Background(), &Subscription{Interval: "yearly", DayOfMonth: 40})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, map[string]string{
+		"phone_number": "is required",
+		"amount":       "must be positive",
+		"interval":     `unknown interval "yearly"`,
+		"day_of_month": "must be between 1 and 31",
+	}, errs.Fields())
+
+	subscription := monthlySubscription(t)
+	subscription.Provider = ProviderClick
+	_, err = client.CreateSubscription(context.Background(), subscription)
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+}
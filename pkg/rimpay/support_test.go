@@ -0,0 +1,55 @@
+package rimpay
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportBundleMergesCauseDetailsAndRedacts(t *testing.T) {
+	inner := NewPaymentError(ErrorCodeNetworkError, "failed to create session", "masrvi", true).
+		WithDetail("endpoint", "GET https://masrvi.test/online/online.php").
+		WithDetail("http_status", 502).
+		WithDetail("access_token", "abc")
+	outer := NewPaymentError(ErrorCodeProviderError, "failed to get session ID", "masrvi", true).
+		WithCause(inner).
+		WithDetail("http_status", 503).
+		WithDetail("request", map[string]interface{}{"Passcode": "1234", "reference": "R-1"})
+
+	bundle, err := outer.SupportBundle()
+	require.NoError(t, err)
+
+	var report SupportReport
+	require.NoError(t, json.Unmarshal(bundle, &report))
+
+	assert.Equal(t, ErrorCodeProviderError, report.Code)
+	assert.Equal(t, "GET https://masrvi.test/online/online.php", report.Endpoint)
+	assert.Empty(t, report.Attempts)
+	assert.NotNil(t, report.Attempts, "attempts is always present for a stable schema")
+	assert.Equal(t, float64(503), report.Details["http_status"], "outer details win")
+	assert.Equal(t, "[REDACTED]", report.Details["access_token"])
+	assert.Equal(t, "[REDACTED]", report.Details["request"].(map[string]interface{})["Passcode"])
+	assert.Equal(t, "R-1", report.Details["request"].(map[string]interface{})["reference"])
+	assert.Contains(t, report.Cause, "failed to create session")
+}
+
+func TestSupportBundleRedactsMessageAndCause(t *testing.T) {
+	cause := errors.New(`Get "https://masrvi.test/online/online.php?merchantid=M-SECRET": EOF`)
+	paymentErr := NewPaymentError(ErrorCodeAuthenticationFailed,
+		`rejected: {"access_token":"tok-123","error":"invalid_grant"} Authorization: Bearer abc.def`, "bpay", false).
+		WithCause(cause)
+
+	bundle, err := paymentErr.SupportBundle()
+	require.NoError(t, err)
+	for _, secret := range []string{"M-SECRET", "tok-123", "abc.def"} {
+		assert.NotContains(t, string(bundle), secret)
+	}
+
+	var report SupportReport
+	require.NoError(t, json.Unmarshal(bundle, &report))
+	assert.Contains(t, report.Message, "invalid_grant")
+	assert.Contains(t, report.Cause, "merchantid=[REDACTED]")
+}
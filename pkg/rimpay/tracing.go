@@ -0,0 +1,29 @@
+package rimpay
+
+import "github.com/CatoSystems/rim-pay/internal/types"
+
+// Tracer starts the spans recorded while processing payments; adapt an
+// OpenTelemetry tracer to it to export them
+type Tracer = types.Tracer
+
+// Span is a unit of traced work started by a Tracer
+type Span = types.Span
+
+// WithTracer records payments as span trees: a span per payment, with a
+// child per attempt, holding the provider's authentication, session and
+// HTTP spans. Spans carry the provider, reference, amount, error code and
+// attempt, never passcodes or credentials. It applies to providers created
+// afterwards, so call it before adding providers; nil disables tracing.
+func (c *Client) WithTracer(tracer Tracer) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracer = tracer
+	return c
+}
+
+// spanTracer returns the tracer set with WithTracer, if any
+func (c *Client) spanTracer() Tracer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tracer
+}
@@ -7,52 +7,77 @@ import (
 )
 
 type TransactionStatus struct {
-	TransactionID     string                 `json:"transaction_id"`
-	Status            PaymentStatus          `json:"status"`
-	Amount            money.Money            `json:"amount,omitempty"`
-	Reference         string                 `json:"reference"`
-	ProviderReference string                 `json:"provider_reference,omitempty"`
-	Message           string                 `json:"message,omitempty"`
-	LastUpdated       time.Time              `json:"last_updated"`
-	Events            []StatusEvent          `json:"events,omitempty"`
-	ProviderData      map[string]interface{} `json:"provider_data,omitempty"`
-}
-
-// StatusEvent represents status change event
-type StatusEvent struct {
-	Status    PaymentStatus          `json:"status"`
-	Timestamp time.Time              `json:"timestamp"`
-	Message   string                 `json:"message,omitempty"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-}
-
-// AddEvent adds a status event
-func (ts *TransactionStatus) AddEvent(status PaymentStatus, message string) {
-	event := StatusEvent{
-		Status:    status,
-		Timestamp: time.Now(),
-		Message:   message,
-		Metadata:  make(map[string]interface{}),
+	TransactionID     string        `json:"transaction_id"`
+	Status            PaymentStatus `json:"status"`
+	Amount            money.Money   `json:"amount,omitempty"`
+	Reference         string        `json:"reference"`
+	ProviderReference string        `json:"provider_reference,omitempty"`
+	Message           string        `json:"message,omitempty"`
+	LastUpdated       time.Time     `json:"last_updated"`
+	// EventLog is the status trail, oldest first
+	EventLog     []StatusEvent          `json:"events,omitempty"`
+	ProviderData map[string]interface{} `json:"provider_data,omitempty"`
+	// Raw is the provider's answer as an exported type such as
+	// *BPayRawResponse or *MasrviRawNotification; it is not stored
+	Raw interface{} `json:"-"`
+}
+
+// AddEvent appends an event observed now from source ("initial", "poll" or
+// "webhook") and makes it the current status; it is a no-op on a nil status
+func (ts *TransactionStatus) AddEvent(status PaymentStatus, message, source string) {
+	if ts == nil {
+		return
 	}
-	ts.Events = append(ts.Events, event)
+	event := NewStatusEvent(status, message, source)
+	ts.EventLog = append(ts.EventLog, event)
 	ts.Status = status
-	ts.LastUpdated = event.Timestamp
+	ts.LastUpdated = event.At
+}
+
+// AsBPayRaw returns the B-PAY response behind the status, if it came from a
+// B-PAY status check or callback
+func (ts *TransactionStatus) AsBPayRaw() (*BPayRawResponse, bool) {
+	if ts == nil {
+		return nil, false
+	}
+	raw, ok := ts.Raw.(*BPayRawResponse)
+	return raw, ok && raw != nil
+}
+
+// AsMasrviRaw returns the MASRVI notification behind the status, if it came
+// from one
+func (ts *TransactionStatus) AsMasrviRaw() (*MasrviRawNotification, bool) {
+	if ts == nil {
+		return nil, false
+	}
+	raw, ok := ts.Raw.(*MasrviRawNotification)
+	return raw, ok && raw != nil
+}
+
+// Events returns a copy of the recorded events, oldest first
+func (ts *TransactionStatus) Events() []StatusEvent {
+	if ts == nil {
+		return nil
+	}
+	return append([]StatusEvent(nil), ts.EventLog...)
 }
 
-// GetLatestEvent returns the most recent status event
-func (ts *TransactionStatus) GetLatestEvent() *StatusEvent {
-	if len(ts.Events) == 0 {
+// LatestEvent returns the most recent status event, or nil when none was
+// recorded
+func (ts *TransactionStatus) LatestEvent() *StatusEvent {
+	if ts == nil || len(ts.EventLog) == 0 {
 		return nil
 	}
-	return &ts.Events[len(ts.Events)-1]
+	event := ts.EventLog[len(ts.EventLog)-1]
+	return &event
 }
 
 // IsCompleted returns true if transaction is completed
 func (ts *TransactionStatus) IsCompleted() bool {
-	return ts.Status.IsCompleted()
+	return ts != nil && ts.Status.IsCompleted()
 }
 
 // IsSuccessful returns true if transaction was successful
 func (ts *TransactionStatus) IsSuccessful() bool {
-	return ts.Status.IsSuccessful()
+	return ts != nil && ts.Status.IsSuccessful()
 }
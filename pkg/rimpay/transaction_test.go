@@ -0,0 +1,50 @@
+package rimpay
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionStatusEvents(t *testing.T) {
+	status := &TransactionStatus{TransactionID: "TX-1", Status: PaymentStatusPending}
+	status.AddEvent(PaymentStatusPending, "Payment initiated", EventSourceInitial)
+	status.AddEvent(PaymentStatusSuccess, "paid", EventSourceWebhook)
+
+	events := status.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, EventSourceInitial, events[0].Source)
+	assert.Equal(t, PaymentStatusSuccess, events[1].Status)
+	assert.False(t, events[1].At.Before(events[0].At))
+	assert.Equal(t, PaymentStatusSuccess, status.Status)
+	assert.Equal(t, events[1].At, status.LastUpdated)
+
+	// Callers cannot rewrite the trail through the returned values
+	events[0].Status = PaymentStatusFailed
+	status.LatestEvent().Message = "changed"
+	assert.Equal(t, PaymentStatusPending, status.Events()[0].Status)
+	assert.Equal(t, "paid", status.LatestEvent().Message)
+}
+
+func TestTransactionStatusEventsJSON(t *testing.T) {
+	status := &TransactionStatus{TransactionID: "TX-1"}
+	status.AddEvent(PaymentStatusPending, "", EventSourcePoll)
+
+	data, err := json.Marshal(status)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Events []map[string]interface{} `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Len(t, decoded.Events, 1)
+	assert.Equal(t, "pending", decoded.Events[0]["status"])
+	assert.Equal(t, "poll", decoded.Events[0]["source"])
+	assert.Contains(t, decoded.Events[0], "at")
+
+	var roundTrip TransactionStatus
+	require.NoError(t, json.Unmarshal(data, &roundTrip))
+	assert.Equal(t, status.Events()[0].Source, roundTrip.LatestEvent().Source)
+}
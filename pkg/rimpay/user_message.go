@@ -0,0 +1,139 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Customer-facing messages shared by several error codes
+const (
+	unavailableEN = "The payment service is temporarily unavailable. Please try again later."
+	unavailableFR = "Le service de paiement est momentanément indisponible. Veuillez réessayer plus tard."
+	unavailableAR = "خدمة الدفع غير متاحة مؤقتًا. يرجى المحاولة لاحقًا."
+
+	busyEN = "The payment service is busy. Please try again in a moment."
+	busyFR = "Le service de paiement est surchargé. Veuillez réessayer dans un instant."
+	busyAR = "خدمة الدفع مشغولة. يرجى المحاولة بعد قليل."
+
+	invalidEN = "The payment details are invalid. Please check them and try again."
+	invalidFR = "Les informations de paiement sont invalides. Veuillez les vérifier et réessayer."
+	invalidAR = "بيانات الدفع غير صحيحة. يرجى التحقق منها والمحاولة مرة أخرى."
+)
+
+// genericMessages are shown for errors without a catalogued code
+var genericMessages = map[Language]string{
+	LanguageEnglish: "The payment could not be completed. Please try again.",
+	LanguageFrench:  "Le paiement n'a pas pu être effectué. Veuillez réessayer.",
+	LanguageArabic:  "تعذر إتمام عملية الدفع. يرجى المحاولة مرة أخرى.",
+}
+
+// userMessages is the catalog of UserMessage, seeded with the built-in
+// messages and extended by RegisterUserMessages
+var userMessages = struct {
+	sync.RWMutex
+	byLanguage map[Language]map[ErrorCode]string
+}{byLanguage: map[Language]map[ErrorCode]string{
+	LanguageEnglish: {
+		ErrorCodeInvalidRequest:              invalidEN,
+		ErrorCodeAuthenticationFailed:        unavailableEN,
+		ErrorCodeInsufficientFunds:           "Insufficient balance. Please top up your account and try again.",
+		ErrorCodePaymentDeclined:             "The payment was declined.",
+		ErrorCodeNetworkError:                "A connection problem occurred. Please try again.",
+		ErrorCodeTimeout:                     "The payment took too long. Please try again.",
+		ErrorCodeProviderError:               unavailableEN,
+		ErrorCodeValidationError:             invalidEN,
+		ErrorCodePaymentExpired:              "The payment has expired. Please start again.",
+		ErrorCodeProviderBusy:                busyEN,
+		ErrorCodeProviderTLSError:            unavailableEN,
+		ErrorCodeRateLimited:                 busyEN,
+		ErrorCodeTooManyRequests:             busyEN,
+		ErrorCodeInsufficientMerchantBalance: "The transfer cannot be made at the moment. Please try again later.",
+	},
+	LanguageFrench: {
+		ErrorCodeInvalidRequest:              invalidFR,
+		ErrorCodeAuthenticationFailed:        unavailableFR,
+		ErrorCodeInsufficientFunds:           "Solde insuffisant. Veuillez recharger votre compte et réessayer.",
+		ErrorCodePaymentDeclined:             "Le paiement a été refusé.",
+		ErrorCodeNetworkError:                "Un problème de connexion est survenu. Veuillez réessayer.",
+		ErrorCodeTimeout:                     "Le paiement a pris trop de temps. Veuillez réessayer.",
+		ErrorCodeProviderError:               unavailableFR,
+		ErrorCodeValidationError:             invalidFR,
+		ErrorCodePaymentExpired:              "Le paiement a expiré. Veuillez recommencer.",
+		ErrorCodeProviderBusy:                busyFR,
+		ErrorCodeProviderTLSError:            unavailableFR,
+		ErrorCodeRateLimited:                 busyFR,
+		ErrorCodeTooManyRequests:             busyFR,
+		ErrorCodeInsufficientMerchantBalance: "Le transfert ne peut pas être effectué pour le moment. Veuillez réessayer plus tard.",
+	},
+	LanguageArabic: {
+		ErrorCodeInvalidRequest:              invalidAR,
+		ErrorCodeAuthenticationFailed:        unavailableAR,
+		ErrorCodeInsufficientFunds:           "الرصيد غير كافٍ. يرجى شحن حسابك والمحاولة مرة أخرى.",
+		ErrorCodePaymentDeclined:             "تم رفض عملية الدفع.",
+		ErrorCodeNetworkError:                "حدثت مشكلة في الاتصال. يرجى المحاولة مرة أخرى.",
+		ErrorCodeTimeout:                     "استغرقت عملية الدفع وقتًا طويلًا. يرجى المحاولة مرة أخرى.",
+		ErrorCodeProviderError:               unavailableAR,
+		ErrorCodeValidationError:             invalidAR,
+		ErrorCodePaymentExpired:              "انتهت صلاحية عملية الدفع. يرجى البدء من جديد.",
+		ErrorCodeProviderBusy:                busyAR,
+		ErrorCodeProviderTLSError:            unavailableAR,
+		ErrorCodeRateLimited:                 busyAR,
+		ErrorCodeTooManyRequests:             busyAR,
+		ErrorCodeInsufficientMerchantBalance: "لا يمكن إجراء التحويل حاليًا. يرجى المحاولة لاحقًا.",
+	},
+}}
+
+// UserMessage returns a short message about err that is safe to show the
+// customer in lang. It depends only on the error code, so provider messages,
+// transaction IDs and references never reach the customer. Errors without a
+// catalogued code get a generic message, and languages without messages
+// fall back to French. UserMessage returns "" for a nil error.
+func UserMessage(err error, lang Language) string {
+	if err == nil {
+		return ""
+	}
+	lang = Language(strings.ToUpper(string(lang)))
+
+	code := ErrorCode("")
+	var paymentErr *PaymentError
+	if errors.As(err, &paymentErr) && paymentErr != nil {
+		code = paymentErr.Code
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		code = ErrorCodeTimeout
+	}
+
+	userMessages.RLock()
+	defer userMessages.RUnlock()
+	for _, l := range []Language{lang, LanguageFrench} {
+		messages := userMessages.byLanguage[l]
+		if message, ok := messages[code]; ok {
+			return message
+		}
+		if message, ok := messages[""]; ok {
+			return message
+		}
+		if message, ok := genericMessages[l]; ok {
+			return message
+		}
+	}
+	return genericMessages[LanguageFrench]
+}
+
+// RegisterUserMessages adds or replaces the UserMessage messages of lang,
+// which may be a language without built-in messages. The empty ErrorCode
+// replaces the generic message shown for uncatalogued errors.
+func RegisterUserMessages(lang Language, messages map[ErrorCode]string) {
+	lang = Language(strings.ToUpper(string(lang)))
+	userMessages.Lock()
+	defer userMessages.Unlock()
+	catalog, ok := userMessages.byLanguage[lang]
+	if !ok {
+		catalog = make(map[ErrorCode]string, len(messages))
+		userMessages.byLanguage[lang] = catalog
+	}
+	for code, message := range messages {
+		catalog[code] = message
+	}
+}
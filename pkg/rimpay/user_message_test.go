@@ -0,0 +1,109 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allErrorCodes are the ErrorCodes defined by the package
+var allErrorCodes = []ErrorCode{
+	ErrorCodeInvalidRequest, ErrorCodeAuthenticationFailed, ErrorCodeInsufficientFunds,
+	ErrorCodePaymentDeclined, ErrorCodeNetworkError, ErrorCodeTimeout, ErrorCodeProviderError,
+	ErrorCodeValidationError, ErrorCodePaymentExpired, ErrorCodeProviderBusy,
+	ErrorCodeProviderTLSError, ErrorCodeRateLimited,
+	ErrorCodeTooManyRequests, ErrorCodeInsufficientMerchantBalance,
+}
+
+// restoreUserMessages undoes the RegisterUserMessages calls of a test
+func restoreUserMessages(t *testing.T) {
+	userMessages.RLock()
+	saved := make(map[Language]map[ErrorCode]string, len(userMessages.byLanguage))
+	for lang, messages := range userMessages.byLanguage {
+		saved[lang] = make(map[ErrorCode]string, len(messages))
+		for code, message := range messages {
+			saved[lang][code] = message
+		}
+	}
+	userMessages.RUnlock()
+
+	t.Cleanup(func() {
+		userMessages.Lock()
+		userMessages.byLanguage = saved
+		userMessages.Unlock()
+	})
+}
+
+func TestUserMessageCoversEveryCode(t *testing.T) {
+	for _, lang := range []Language{LanguageEnglish, LanguageFrench, LanguageArabic} {
+		for _, code := range allErrorCodes {
+			t.Run(fmt.Sprintf("%s/%s", lang, code), func(t *testing.T) {
+				err := NewPaymentError(code, "B-PAY error 1: ref ORDER-42 TX-9", ProviderBPay, false).
+					WithDetail("reference", "ORDER-42")
+
+				message := UserMessage(err, lang)
+				require.Contains(t, userMessages.byLanguage[lang], code)
+				assert.Equal(t, userMessages.byLanguage[lang][code], message)
+				assert.NotEqual(t, genericMessages[lang], message)
+				for _, leak := range []string{"ORDER-42", "TX-9", "B-PAY", string(code)} {
+					assert.NotContains(t, message, leak)
+				}
+			})
+		}
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	insufficient := NewPaymentError(ErrorCodeInsufficientFunds, "insufficient funds", ProviderBPay, false)
+
+	tests := []struct {
+		name string
+		err  error
+		lang Language
+		want string
+	}{
+		{"french", insufficient, LanguageFrench, "Solde insuffisant. Veuillez recharger votre compte et réessayer."},
+		{"lower-case language", insufficient, "fr", "Solde insuffisant. Veuillez recharger votre compte et réessayer."},
+		{"wrapped", fmt.Errorf("checkout: %w", insufficient), LanguageEnglish,
+			"Insufficient balance. Please top up your account and try again."},
+		{"validation errors", ValidationErrors{{Field: "amount", Message: "must be positive"}}, LanguageEnglish,
+			"The payment details are invalid. Please check them and try again."},
+		{"deadline", context.DeadlineExceeded, LanguageEnglish, "The payment took too long. Please try again."},
+		{"unknown code", NewPaymentError("MYSTERY", "secret", ProviderBPay, false), LanguageArabic,
+			genericMessages[LanguageArabic]},
+		{"plain error", errors.New("dial tcp 10.0.0.1:443: refused"), LanguageEnglish,
+			genericMessages[LanguageEnglish]},
+		{"unknown language", insufficient, "ES", "Solde insuffisant. Veuillez recharger votre compte et réessayer."},
+		{"nil error", nil, LanguageFrench, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, UserMessage(tt.err, tt.lang))
+		})
+	}
+}
+
+func TestRegisterUserMessages(t *testing.T) {
+	restoreUserMessages(t)
+	declined := NewPaymentError(ErrorCodePaymentDeclined, "declined", ProviderBPay, false)
+	expired := NewPaymentError(ErrorCodePaymentExpired, "expired", ProviderBPay, false)
+
+	RegisterUserMessages(LanguageFrench, map[ErrorCode]string{ErrorCodePaymentDeclined: "Paiement refusé par votre banque."})
+	assert.Equal(t, "Paiement refusé par votre banque.", UserMessage(declined, LanguageFrench))
+	assert.Equal(t, "Le paiement a expiré. Veuillez recommencer.", UserMessage(expired, LanguageFrench))
+
+	RegisterUserMessages("es", map[ErrorCode]string{
+		ErrorCodePaymentDeclined: "El pago fue rechazado.",
+		"":                       "No se pudo completar el pago.",
+	})
+	assert.Equal(t, "El pago fue rechazado.", UserMessage(declined, "ES"))
+	assert.Equal(t, "No se pudo completar el pago.", UserMessage(expired, "ES"))
+	assert.Equal(t, "No se pudo completar el pago.", UserMessage(errors.New("boom"), "ES"))
+	assert.False(t, strings.Contains(UserMessage(declined, LanguageEnglish), "rechazado"))
+}
@@ -0,0 +1,101 @@
+package rimpay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CatoSystems/rim-pay/pkg/money"
+)
+
+func TestBPayRequestReportsEveryFailedField(t *testing.T) {
+	request := &BPayPaymentRequest{Reference: "REF-1", Passcode: "12", OperationType: "wire"}
+
+	err := request.Validate()
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs), "got %T", err)
+	assert.Equal(t, map[string]string{
+		"phone_number":   "is required",
+		"amount":         "must be positive",
+		"description":    "cannot be empty",
+		"passcode":       "must be 4 or 6 digits",
+		"operation_type": `unknown operation type "wire"`,
+	}, errs.Fields())
+	assert.Len(t, errs, 5)
+	assert.Equal(t, "VALIDATION_ERROR: phone_number: is required; amount: must be positive; description: cannot be empty; "+
+		`passcode: must be 4 or 6 digits; operation_type: unknown operation type "wire"`, err.Error())
+}
+
+func TestMasrviRequestReportsEveryFailedField(t *testing.T) {
+	request := &MasrviPaymentRequest{Description: "Order", Reference: "REF-1", CallbackURL: "https://shop.test/hook"}
+
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	assert.Equal(t, map[string]string{
+		"phone_number": "is required",
+		"amount":       "must be positive",
+		"return_url":   "cannot be empty",
+	}, errs.Fields())
+}
+
+func TestMasrviPaymentPageFields(t *testing.T) {
+	request := &MasrviPaymentRequest{
+		PhoneNumber:  newValidBPayRequest().PhoneNumber,
+		Amount:       money.NewMRU(5000),
+		Description:  "Order",
+		Reference:    "REF-1",
+		CallbackURL:  "https://shop.test/hook",
+		ReturnURL:    "https://shop.test/return",
+		Brand:        "Cato Shop",
+		CustomerName: strings.Repeat("a", 101),
+		Text:         strings.Repeat("t", 501),
+	}
+
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	assert.Equal(t, map[string]string{
+		"customer_name": "cannot exceed 100 characters",
+		"text":          "cannot exceed 500 characters",
+	}, errs.Fields())
+
+	request.CustomerName = "Aïcha"
+	request.Text = strings.Repeat("t", 500)
+	require.NoError(t, request.Validate())
+	metadata := request.ToGenericRequest().Metadata
+	assert.Equal(t, "Cato Shop", metadata[MasrviMetadataBrand])
+	assert.Equal(t, "Aïcha", metadata[MasrviMetadataCustomerName])
+	assert.Equal(t, request.Text, metadata[MasrviMetadataText])
+}
+
+func TestSingleValidationFailureIsPaymentError(t *testing.T) {
+	request := newValidBPayRequest()
+	request.Passcode = ""
+
+	var paymentErr *PaymentError
+	require.ErrorAs(t, request.Validate(), &paymentErr)
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Equal(t, "passcode", paymentErr.Details["field"])
+	assert.Equal(t, "passcode: is required (the customer's Bankily verification code)", paymentErr.Message)
+	assert.False(t, paymentErr.Retryable)
+}
+
+func TestSeveralValidationFailuresArePaymentError(t *testing.T) {
+	var paymentErr *PaymentError
+	require.ErrorAs(t, (&ClickPaymentRequest{}).Validate(), &paymentErr)
+	assert.Equal(t, ErrorCodeValidationError, paymentErr.Code)
+	assert.Equal(t, "amount", paymentErr.Details["field"])
+	assert.Equal(t, map[string]string{"amount": "must be positive", "reference": "cannot be empty"}, paymentErr.Details["fields"])
+}
+
+func TestValidationErrorsFieldsJoinRepeatedFields(t *testing.T) {
+	var errs ValidationErrors
+	assert.NoError(t, errs.Err())
+
+	errs.Add("reference", "cannot be empty")
+	errs.Add("reference", "invalid format")
+	assert.Equal(t, map[string]string{"reference": "cannot be empty; invalid format"}, errs.Fields())
+	assert.Error(t, errs.Err())
+}
@@ -0,0 +1,325 @@
+package rimpay
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultWebhookRetryAfter is the Retry-After sent when shedding notifications
+// and WebhookConfig.RetryAfter is unset
+const defaultWebhookRetryAfter = 30 * time.Second
+
+// DefaultNotificationTTL is how long a MemoryDeduplicator remembers a
+// processed notification when no TTL is given
+const DefaultNotificationTTL = 24 * time.Hour
+
+// NotificationDeduplicator makes sure each notification is processed once,
+// since providers re-deliver until they receive a 200. Implementations must
+// be safe for concurrent use; several instances share one backed by Redis or
+// a database, where Claim maps to SET NX.
+type NotificationDeduplicator interface {
+	// Claim atomically takes key for processing. It returns false when key
+	// was already processed or is claimed by a delivery in progress.
+	Claim(ctx context.Context, key string) (bool, error)
+	// Complete records that the claimed key was processed
+	Complete(ctx context.Context, key string) error
+	// Release gives up the claim on key after processing failed, so that a
+	// re-delivery is processed
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryDeduplicator is an in-process NotificationDeduplicator that
+// remembers processed keys for a TTL. Deployments with several instances
+// should back the interface with shared storage.
+type MemoryDeduplicator struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	claimed   map[string]struct{}
+	processed map[string]*list.Element
+	// expiries holds the processed keys' dedupEntry, oldest first
+	expiries *list.List
+}
+
+type dedupEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDeduplicator creates an empty in-memory deduplicator remembering
+// processed keys for ttl, or DefaultNotificationTTL when 0
+func NewMemoryDeduplicator(ttl time.Duration) *MemoryDeduplicator {
+	if ttl <= 0 {
+		ttl = DefaultNotificationTTL
+	}
+	return &MemoryDeduplicator{
+		ttl:       ttl,
+		now:       time.Now,
+		claimed:   make(map[string]struct{}),
+		processed: make(map[string]*list.Element),
+		expiries:  list.New(),
+	}
+}
+
+// Claim takes key unless it is claimed or was processed within the TTL
+func (d *MemoryDeduplicator) Claim(_ context.Context, key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+	if _, ok := d.claimed[key]; ok {
+		return false, nil
+	}
+	if _, ok := d.processed[key]; ok {
+		return false, nil
+	}
+	d.claimed[key] = struct{}{}
+	return true, nil
+}
+
+// Complete records key as processed for the TTL
+func (d *MemoryDeduplicator) Complete(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.claimed, key)
+	if element, ok := d.processed[key]; ok {
+		d.expiries.Remove(element)
+	}
+	d.processed[key] = d.expiries.PushBack(dedupEntry{key: key, expires: d.now().Add(d.ttl)})
+	d.prune()
+	return nil
+}
+
+// Release forgets the claim on key
+func (d *MemoryDeduplicator) Release(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.claimed, key)
+	return nil
+}
+
+// Processed reports whether key was processed within the TTL
+func (d *MemoryDeduplicator) Processed(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+	_, ok := d.processed[key]
+	return ok
+}
+
+// Len returns the number of processed keys remembered
+func (d *MemoryDeduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+	return len(d.processed)
+}
+
+// prune forgets expired keys; callers hold mu
+func (d *MemoryDeduplicator) prune() {
+	now := d.now()
+	for front := d.expiries.Front(); front != nil; front = d.expiries.Front() {
+		entry := front.Value.(dedupEntry)
+		if now.Before(entry.expires) {
+			return
+		}
+		d.expiries.Remove(front)
+		delete(d.processed, entry.key)
+	}
+}
+
+// NotificationFunc receives each verified, non-duplicate notification. An
+// error makes the handler answer 500 so the provider re-delivers it.
+type NotificationFunc func(ctx context.Context, status *TransactionStatus) error
+
+// MasrviWebhookHandler is an http.Handler for MASRVI payment notifications,
+// which it passes through a NotificationDispatcher. When
+// Config.Webhooks.MaxInFlight notifications are already being processed it
+// answers 503 with Retry-After instead of queueing more work, and MASRVI
+// re-delivers later. Notifications for the same payment are processed one at
+// a time, so a duplicate arriving while the first is handled waits for its
+// outcome without taking a slot. Create it with
+// Client.NewMasrviWebhookHandler or NotificationDispatcher.NewMasrviWebhookHandler.
+type MasrviWebhookHandler struct {
+	client     *Client
+	handle     NotificationFunc
+	dispatcher *NotificationDispatcher
+	slots      chan struct{}
+	retryAfter time.Duration
+	shed       uint64
+}
+
+// NewMasrviWebhookHandler creates a webhook handler passing notifications to
+// handle. dedup may be nil to disable deduplication.
+func (c *Client) NewMasrviWebhookHandler(handle NotificationFunc, dedup NotificationDeduplicator) *MasrviWebhookHandler {
+	return newNotificationDispatcher(c, dedup).NewMasrviWebhookHandler(handle)
+}
+
+// NewMasrviWebhookHandler creates a webhook handler passing the
+// notifications the dispatcher has not seen to handle
+func (d *NotificationDispatcher) NewMasrviWebhookHandler(handle NotificationFunc) *MasrviWebhookHandler {
+	config := d.client.currentConfig().Webhooks
+	h := &MasrviWebhookHandler{
+		client:     d.client,
+		handle:     handle,
+		dispatcher: d,
+		retryAfter: config.RetryAfter,
+	}
+	if h.retryAfter <= 0 {
+		h.retryAfter = defaultWebhookRetryAfter
+	}
+	if config.MaxInFlight > 0 {
+		h.slots = make(chan struct{}, config.MaxInFlight)
+	}
+	return h
+}
+
+// Shed returns the number of notifications rejected because the handler was
+// at capacity
+func (h *MasrviWebhookHandler) Shed() uint64 {
+	return atomic.LoadUint64(&h.shed)
+}
+
+// ServeHTTP takes the payment's lock, sheds load when at capacity, verifies
+// the notification and dispatches it, so that duplicates are acknowledged
+// without being recorded or handled again
+func (h *MasrviWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	notification, err := ParseMasrviNotification(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The slot is taken before verifying, so that a flood costs no signature
+	// checks. A notification with the wrong amount is genuine: it is handed
+	// on with its failed status rather than rejected and re-delivered.
+	inbound := h.dispatcher.masrvi(notification, h.handle)
+	inbound.admit = h.acquire
+	_, duplicate, err := h.dispatcher.dispatch(r.Context(), inbound)
+	var processing *processingError
+	switch {
+	case errors.Is(err, errNotificationShed):
+		atomic.AddUint64(&h.shed, 1)
+		h.client.logger.Warn("MASRVI notification shed, webhook at capacity",
+			"reference", notification.Reference, "retry_after", h.retryAfter)
+		seconds := (h.retryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+		http.Error(w, "overloaded, retry later", http.StatusServiceUnavailable)
+		return
+	case errors.As(err, &processing):
+		h.client.logger.Error("MASRVI notification handling failed", "reference", notification.Reference, "error", err)
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	case errors.Is(err, ErrAmountUnverified):
+		// Re-delivered once the amount can be checked
+		h.client.logger.Error("MASRVI notification not processed", "reference", notification.Reference, "error", err)
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	case err != nil && !errors.Is(err, ErrAmountMismatch):
+		h.client.logger.Warn("Rejected MASRVI notification", "reference", notification.Reference, "error", err)
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrNotificationRejected) {
+			code = http.StatusUnauthorized
+		}
+		http.Error(w, "invalid notification", code)
+		return
+	}
+	if duplicate {
+		h.client.logger.Debug("Duplicate MASRVI notification acknowledged", "reference", notification.Reference)
+	}
+	writeWebhookOK(w)
+}
+
+// acquire takes a processing slot without waiting
+func (h *MasrviWebhookHandler) acquire() (release func(), ok bool) {
+	if h.slots == nil {
+		return func() {}, true
+	}
+	select {
+	case h.slots <- struct{}{}:
+		return func() { <-h.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// masrviNotificationExtras maps optional notification parameters to the
+// MasrviNotificationData.Data keys they are stored under
+var masrviNotificationExtras = map[string]string{
+	"clientid": "client_id",
+	"payid":    "pay_id",
+	"error":    "error",
+	"cname":    "customer_name",
+	"ipaddr":   "ip_address",
+	"amount":   "amount",
+	"currency": "currency",
+}
+
+// ParseMasrviNotification extracts a MASRVI notification from the webhook
+// request. Both query string and form-encoded bodies are accepted; optional
+// parameters such as clientid, payid and error are stored in Data.
+func ParseMasrviNotification(r *http.Request) (*MasrviNotificationData, error) {
+	if r == nil {
+		return nil, ErrInvalidRequest
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, NewValidationError("notification", "invalid parameters")
+	}
+	notification, err := ParseMasrviParams(r.Form)
+	if err != nil {
+		return nil, err
+	}
+	notification.RemoteAddr = r.RemoteAddr
+	return notification, nil
+}
+
+// ParseMasrviParams extracts a MASRVI notification from its parameters,
+// which it keeps in Params
+func ParseMasrviParams(params url.Values) (*MasrviNotificationData, error) {
+	notification := &MasrviNotificationData{
+		TransactionID: strings.TrimSpace(paramValue(params, "paymentref")),
+		Status:        strings.TrimSpace(paramValue(params, "status")),
+		Reference:     strings.TrimSpace(paramValue(params, "purchaseref")),
+		PhoneNumber:   strings.TrimSpace(paramValue(params, "mobile")),
+		Timestamp:     strings.TrimSpace(paramValue(params, "timestamp")),
+		Params:        params,
+	}
+	for param, key := range masrviNotificationExtras {
+		if value := strings.TrimSpace(paramValue(params, param)); value != "" {
+			if notification.Data == nil {
+				notification.Data = make(map[string]interface{})
+			}
+			notification.Data[key] = value
+		}
+	}
+	if notification.Reference == "" {
+		return nil, NewValidationError("purchaseref", "is required")
+	}
+	if notification.Status == "" {
+		return nil, NewValidationError("status", "is required")
+	}
+	return notification, nil
+}
+
+// notificationKey identifies a notification for deduplication; a status
+// change for the same payment is a new notification
+func notificationKey(n *MasrviNotificationData) string {
+	return ProviderMasrvi + ":" + n.Reference + ":" + n.TransactionID + ":" + strings.ToUpper(n.Status)
+}
+
+func writeWebhookOK(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
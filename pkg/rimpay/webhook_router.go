@@ -0,0 +1,234 @@
+package rimpay
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultWebhookPathPrefix is the path under which a WebhookRouter mounts
+// one sub-path per provider, such as /webhooks/masrvi
+const DefaultWebhookPathPrefix = "/webhooks/"
+
+// DefaultWebhookMaxBodySize bounds notification bodies when no
+// WithWebhookMaxBodySize option is given
+const DefaultWebhookMaxBodySize = 64 << 10
+
+// NotificationHandler is implemented by custom providers whose
+// notifications a WebhookRouter receives. The built-in MASRVI and B-PAY
+// notifications are handled without it.
+type NotificationHandler interface {
+	// ParseNotification authenticates the notification in r and maps it to
+	// a transaction status. Errors matching ErrNotificationRejected answer
+	// 401, others 400.
+	ParseNotification(r *http.Request) (*TransactionStatus, error)
+}
+
+// WebhookFunc receives every notification a WebhookRouter accepts, with
+// the name of the provider it came from. An error makes the router answer
+// 500 so the provider re-delivers it.
+type WebhookFunc func(ctx context.Context, provider string, status *TransactionStatus) error
+
+// WebhookRouterOption configures NewWebhookRouter
+type WebhookRouterOption func(*webhookRouter)
+
+// WithWebhookHandler sets the function notifications are passed to; it is
+// required
+func WithWebhookHandler(handle WebhookFunc) WebhookRouterOption {
+	return func(r *webhookRouter) {
+		r.handle = handle
+	}
+}
+
+// WithWebhookPathPrefix mounts the provider sub-paths under prefix instead
+// of DefaultWebhookPathPrefix
+func WithWebhookPathPrefix(prefix string) WebhookRouterOption {
+	return func(r *webhookRouter) {
+		r.prefix = prefix
+	}
+}
+
+// WithWebhookMaxBodySize rejects bodies larger than n bytes with 413
+func WithWebhookMaxBodySize(n int64) WebhookRouterOption {
+	return func(r *webhookRouter) {
+		r.maxBodySize = n
+	}
+}
+
+// WithNotificationDispatcher deduplicates notifications with d, which may
+// be shared with the client's other webhook handlers, instead of a
+// dispatcher of the router's own
+func WithNotificationDispatcher(d *NotificationDispatcher) WebhookRouterOption {
+	return func(r *webhookRouter) {
+		r.dispatcher = d
+	}
+}
+
+// WithWebhookLogger logs to logger instead of the client's logger. It is
+// wrapped with NewRedactingLogger.
+func WithWebhookLogger(logger Logger) WebhookRouterOption {
+	return func(r *webhookRouter) {
+		r.logger = NewRedactingLogger(logger)
+	}
+}
+
+type webhookRouter struct {
+	client      *Client
+	handle      WebhookFunc
+	prefix      string
+	maxBodySize int64
+	logger      Logger
+	dispatcher  *NotificationDispatcher
+}
+
+// NewWebhookRouter returns an http.Handler serving the notifications of
+// every registered provider under one prefix: /webhooks/masrvi,
+// /webhooks/bpay and /webhooks/<name> for custom providers implementing
+// NotificationHandler. Each notification is verified by its provider, as
+// by Client.HandleMasrviNotification, or against the B-PAY
+// OptionCallbackSecret, and passed to the WithWebhookHandler function
+// through a NotificationDispatcher, so re-deliveries are acknowledged
+// without being recorded or handled again. Unknown providers answer 404, unverified notifications 401, malformed
+// ones 400 and handler errors 500. Notifications with the wrong amount are
+// passed on failed; those whose amount cannot be verified answer 500.
+func NewWebhookRouter(client *Client, opts ...WebhookRouterOption) http.Handler {
+	r := &webhookRouter{
+		client:      client,
+		prefix:      DefaultWebhookPathPrefix,
+		maxBodySize: DefaultWebhookMaxBodySize,
+		logger:      client.logger,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.handle == nil {
+		panic("rimpay: NewWebhookRouter requires WithWebhookHandler")
+	}
+	if r.dispatcher == nil {
+		r.dispatcher = client.NewNotificationDispatcher(nil)
+	}
+	if !strings.HasSuffix(r.prefix, "/") {
+		r.prefix += "/"
+	}
+	return r
+}
+
+func (h *webhookRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, h.prefix)
+	if name == r.URL.Path || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	provider, ok := h.client.registered(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	custom, isCustom := provider.(NotificationHandler)
+	if !isCustom && name != ProviderMasrvi && name != ProviderBPay {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.maxBodySize > 0 {
+		if r.ContentLength > h.maxBodySize {
+			h.logger.Warn("Oversized webhook notification", "provider", name, "size", r.ContentLength)
+			http.Error(w, "notification too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+
+	start := time.Now()
+	handle := func(ctx context.Context, status *TransactionStatus) error {
+		return h.handle(ctx, name, status)
+	}
+	var status *TransactionStatus
+	var duplicate bool
+	var err error
+	switch {
+	case isCustom:
+		status, duplicate, err = h.custom(r, name, custom, handle)
+	case name == ProviderMasrvi:
+		status, duplicate, err = h.masrvi(r, handle)
+	default:
+		status, duplicate, err = h.bpay(r, handle)
+	}
+	var processing *processingError
+	switch {
+	case errors.As(err, &processing):
+		h.logger.Error("Webhook notification handling failed", "provider", name,
+			"reference", status.Reference, "error", err)
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	case errors.Is(err, ErrAmountUnverified):
+		h.logger.Error("Webhook notification not processed", "provider", name, "error", err)
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	case err != nil && !errors.Is(err, ErrAmountMismatch):
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrNotificationRejected) {
+			code = http.StatusUnauthorized
+		}
+		h.logger.Warn("Rejected webhook notification", "provider", name, "remote_addr", r.RemoteAddr, "error", err)
+		http.Error(w, "invalid notification", code)
+		return
+	}
+
+	if duplicate {
+		h.logger.Debug("Duplicate webhook notification acknowledged", "provider", name, "reference", status.Reference)
+	} else {
+		h.logger.Debug("Webhook notification handled", "provider", name, "reference", status.Reference,
+			"status", status.Status, "duration", time.Since(start))
+	}
+	writeWebhookOK(w)
+}
+
+// custom has a custom provider verify its notification and dispatches it
+func (h *webhookRouter) custom(r *http.Request, name string, custom NotificationHandler, handle NotificationFunc) (*TransactionStatus, bool, error) {
+	status, err := custom.ParseNotification(r)
+	if err != nil {
+		return nil, false, err
+	}
+	if status == nil {
+		return nil, false, NewValidationError("notification", "no transaction status")
+	}
+	duplicate, err := h.dispatcher.Dispatch(r.Context(), name, status, handle)
+	return status, duplicate, err
+}
+
+// masrvi parses a MASRVI notification and dispatches it for the provider to
+// verify
+func (h *webhookRouter) masrvi(r *http.Request, handle NotificationFunc) (*TransactionStatus, bool, error) {
+	notification, err := ParseMasrviNotification(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return h.dispatcher.DispatchMasrvi(r.Context(), notification, handle)
+}
+
+// bpay checks the shared secret of a B-PAY callback against the registered
+// provider's configuration and dispatches it
+func (h *webhookRouter) bpay(r *http.Request, handle NotificationFunc) (*TransactionStatus, bool, error) {
+	config, _ := h.client.providerConfig(ProviderBPay)
+	secret, err := config.StringOption(OptionCallbackSecret, "")
+	if err != nil {
+		return nil, false, err
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(BPaySecretHeader)), []byte(secret)) != 1 {
+		return nil, false, &NotificationRejectedError{Provider: ProviderBPay, Reason: NotificationBadSignature,
+			Err: errors.New("callback secret mismatch")}
+	}
+	notification, err := ParseBPayNotification(r.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return h.dispatcher.DispatchBPay(r.Context(), notification, handle)
+}
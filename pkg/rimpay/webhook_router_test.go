@@ -0,0 +1,199 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callbackProvider maps B-PAY callbacks like the real provider
+type callbackProvider struct {
+	namedProvider
+}
+
+func (p *callbackProvider) HandleNotification(n *BPayNotificationData) (*TransactionStatus, error) {
+	return &TransactionStatus{TransactionID: n.TransactionID, Reference: n.OperationID, Status: PaymentStatusSuccess}, nil
+}
+
+// customNotifier is a custom provider reading its notifications from a
+// header
+type customNotifier struct {
+	namedProvider
+}
+
+func (p *customNotifier) ParseNotification(r *http.Request) (*TransactionStatus, error) {
+	if r.Header.Get("X-Token") != "acme-token" {
+		return nil, &NotificationRejectedError{Provider: p.name, Reason: NotificationBadSignature, Err: errors.New("bad token")}
+	}
+	return &TransactionStatus{Reference: r.URL.Query().Get("ref"), Status: PaymentStatusSuccess}, nil
+}
+
+type routedNotification struct {
+	provider  string
+	reference string
+}
+
+func newRouterTestClient(t *testing.T) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderMasrvi
+	config.Providers[ProviderMasrvi] = ProviderConfig{Enabled: true, BaseURL: "https://masrvi.test", Timeout: time.Second}
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Options: map[string]interface{}{OptionCallbackSecret: testCallbackSecret}}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &notifyingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}))
+	require.NoError(t, client.AddProvider(ProviderBPay, &callbackProvider{namedProvider{name: ProviderBPay}}))
+	require.NoError(t, client.AddProvider("acme", &customNotifier{namedProvider{name: "acme"}}))
+	require.NoError(t, client.AddProvider("plain", &namedProvider{name: "plain"}))
+	return client
+}
+
+func newTestRouter(t *testing.T, client *Client, opts ...WebhookRouterOption) (http.Handler, *[]routedNotification) {
+	t.Helper()
+	var received []routedNotification
+	opts = append([]WebhookRouterOption{WithWebhookHandler(func(ctx context.Context, provider string, status *TransactionStatus) error {
+		received = append(received, routedNotification{provider, status.Reference})
+		if status.Reference == "FAIL" {
+			return errors.New("db down")
+		}
+		return nil
+	})}, opts...)
+	return NewWebhookRouter(client, opts...), &received
+}
+
+func bpayCallback(secret, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/bpay", strings.NewReader(body))
+	req.Header.Set(BPaySecretHeader, secret)
+	return req
+}
+
+func TestWebhookRouterDispatchesByPath(t *testing.T) {
+	client := newRouterTestClient(t)
+	router, received := newTestRouter(t, client)
+
+	masrvi := notificationRequest("ORDER-1")
+	masrvi.URL.Path = "/webhooks/masrvi"
+	custom := httptest.NewRequest(http.MethodPost, "/webhooks/acme?ref=ORDER-3", nil)
+	custom.Header.Set("X-Token", "acme-token")
+
+	for _, req := range []*http.Request{
+		masrvi,
+		bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS"}`),
+		custom,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, req.URL.Path)
+		assert.Equal(t, "OK", rec.Body.String())
+	}
+	assert.Equal(t, []routedNotification{
+		{ProviderMasrvi, "ORDER-1"},
+		{ProviderBPay, "ORDER-2"},
+		{"acme", "ORDER-3"},
+	}, *received)
+}
+
+func TestWebhookRouterUsesAddBPayProviderSecret(t *testing.T) {
+	prevBPay := createBPayProvider
+	defer func() { createBPayProvider = prevBPay }()
+	createBPayProvider = func(ProviderConfig, Logger) (PaymentProvider, error) {
+		return &callbackProvider{namedProvider{name: ProviderBPay}}, nil
+	}
+
+	// The Config section has no callback secret; the one given to
+	// AddBPayProvider is checked
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderBPay
+	config.Providers[ProviderBPay] = ProviderConfig{Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second}
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddBPayProvider(ProviderConfig{
+		Enabled: true, BaseURL: "https://bpay.test", Timeout: time.Second,
+		Options: map[string]interface{}{OptionCallbackSecret: testCallbackSecret},
+	}))
+	router, received := newTestRouter(t, client)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS"}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []routedNotification{{ProviderBPay, "ORDER-2"}}, *received)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, bpayCallback("wrong", `{"operationId":"ORDER-2","status":"TS"}`))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestWebhookRouterStatusCodes(t *testing.T) {
+	client := newRouterTestClient(t)
+	router, received := newTestRouter(t, client)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"unknown provider", httptest.NewRequest(http.MethodPost, "/webhooks/other", nil), http.StatusNotFound},
+		{"provider without notifications", httptest.NewRequest(http.MethodPost, "/webhooks/plain", nil), http.StatusNotFound},
+		{"nested path", httptest.NewRequest(http.MethodPost, "/webhooks/bpay/extra", nil), http.StatusNotFound},
+		{"outside prefix", httptest.NewRequest(http.MethodPost, "/hooks/bpay", nil), http.StatusNotFound},
+		{"bad secret", bpayCallback("wrong", `{"operationId":"ORDER-2","status":"TS"}`), http.StatusUnauthorized},
+		{"bad token", httptest.NewRequest(http.MethodPost, "/webhooks/acme?ref=ORDER-3", nil), http.StatusUnauthorized},
+		{"malformed", bpayCallback(testCallbackSecret, `{"status":"TS"}`), http.StatusBadRequest},
+		{"method", httptest.NewRequest(http.MethodDelete, "/webhooks/bpay", nil), http.StatusMethodNotAllowed},
+		{"handler error", bpayCallback(testCallbackSecret, `{"operationId":"FAIL","status":"TS"}`), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, tt.req)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+	assert.Equal(t, []routedNotification{{ProviderBPay, "FAIL"}}, *received, "only verified notifications reach the handler")
+}
+
+func TestWebhookRouterLimitsBodySize(t *testing.T) {
+	client := newRouterTestClient(t)
+	logger := &recordingLogger{}
+	router, received := newTestRouter(t, client, WithWebhookMaxBodySize(32), WithWebhookLogger(logger))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS","errorMessage":"padding"}`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Empty(t, *received)
+	assert.Equal(t, 1, logger.count("Oversized webhook notification"))
+
+	req := bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS","errorMessage":"padding"}`)
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "bodies of unknown length are cut off")
+	assert.Empty(t, *received)
+}
+
+func TestWebhookRouterPathPrefix(t *testing.T) {
+	client := newRouterTestClient(t)
+	router, received := newTestRouter(t, client, WithWebhookPathPrefix("/hooks"))
+
+	req := bpayCallback(testCallbackSecret, `{"operationId":"ORDER-2","status":"TS"}`)
+	req.URL.Path = "/hooks/bpay"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, *received, 1)
+}
+
+func TestNewWebhookRouterRequiresHandler(t *testing.T) {
+	assert.Panics(t, func() { NewWebhookRouter(newRouterTestClient(t)) })
+}
@@ -0,0 +1,292 @@
+package rimpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notifyingProvider converts MASRVI notifications like the real provider
+type notifyingProvider struct {
+	namedProvider
+	verified int64
+}
+
+func (p *notifyingProvider) ProcessMasrviPayment(context.Context, *MasrviPaymentRequest) (*PaymentResponse, error) {
+	return nil, ErrPaymentFailed
+}
+
+func (p *notifyingProvider) HandleNotification(n *MasrviNotificationData) (*TransactionStatus, error) {
+	atomic.AddInt64(&p.verified, 1)
+	status := PaymentStatusPending
+	if n.Status == "OK" {
+		status = PaymentStatusSuccess
+	}
+	return &TransactionStatus{TransactionID: n.TransactionID, Reference: n.Reference, Status: status}, nil
+}
+
+func newWebhookTestClient(t *testing.T, webhooks WebhookConfig) *Client {
+	t.Helper()
+	config := DefaultConfig()
+	config.DefaultProvider = ProviderMasrvi
+	config.Providers[ProviderMasrvi] = ProviderConfig{Enabled: true, BaseURL: "https://masrvi.test", Timeout: time.Second}
+	config.Webhooks = webhooks
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	client.logger = &recordingLogger{}
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &notifyingProvider{namedProvider: namedProvider{name: ProviderMasrvi}}))
+	return client
+}
+
+func notificationRequest(reference string) *http.Request {
+	form := url.Values{
+		"status":      {"OK"},
+		"purchaseref": {reference},
+		"paymentref":  {"PAY-" + reference},
+	}
+	return httptest.NewRequest(http.MethodPost, "/webhook?"+form.Encode(), nil)
+}
+
+func TestWebhookShedsLoadPastLimit(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{MaxInFlight: 2, RetryAfter: 1500 * time.Millisecond})
+	dedup := NewMemoryDeduplicator(0)
+
+	entered := make(chan struct{}, 3)
+	unblock := make(chan struct{})
+	handler := client.NewMasrviWebhookHandler(func(ctx context.Context, status *TransactionStatus) error {
+		entered <- struct{}{}
+		<-unblock
+		return nil
+	}, dedup)
+
+	// Fill both slots with notifications stuck downstream
+	var wg sync.WaitGroup
+	inFlight := make([]*httptest.ResponseRecorder, 2)
+	for i := range inFlight {
+		inFlight[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder, ref string) {
+			defer wg.Done()
+			handler.ServeHTTP(rec, notificationRequest(ref))
+		}(inFlight[i], []string{"ORDER-1", "ORDER-2"}[i])
+		<-entered
+	}
+
+	shed := httptest.NewRecorder()
+	handler.ServeHTTP(shed, notificationRequest("ORDER-3"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, shed.Code)
+	assert.Equal(t, "2", shed.Header().Get("Retry-After"))
+	assert.Equal(t, uint64(1), handler.Shed())
+	assert.False(t, dedup.Processed(notificationKey(&MasrviNotificationData{
+		Status: "OK", Reference: "ORDER-3", TransactionID: "PAY-ORDER-3",
+	})), "shed notifications must not be marked processed")
+	assert.Equal(t, 1, client.logger.(*recordingLogger).count("MASRVI notification shed, webhook at capacity"))
+	provider, _ := client.registered(ProviderMasrvi)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&provider.(*notifyingProvider).verified),
+		"shed notifications must not be verified")
+
+	close(unblock)
+	wg.Wait()
+	for _, rec := range inFlight {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Once capacity frees up the re-delivered notification is accepted
+	redelivered := httptest.NewRecorder()
+	handler.ServeHTTP(redelivered, notificationRequest("ORDER-3"))
+	assert.Equal(t, http.StatusOK, redelivered.Code)
+}
+
+func TestWebhookSkipsDuplicates(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	calls := 0
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		calls++
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookFailedHandlingIsRedelivered(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	dedup := NewMemoryDeduplicator(0)
+	fail := true
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		if fail {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}, dedup)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	fail = false
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestWebhookRejectsInvalidNotifications(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error { return nil }, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook?status=OK", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/webhook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestWebhookConcurrentDuplicatesProcessedOnce(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	var calls int64
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		atomic.AddInt64(&calls, 1)
+		close(entered)
+		<-unblock
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(first, notificationRequest("ORDER-1"))
+	}()
+	<-entered
+
+	var wg sync.WaitGroup
+	duplicates := make([]*httptest.ResponseRecorder, 5)
+	for i := range duplicates {
+		duplicates[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+		}(duplicates[i])
+	}
+
+	close(unblock)
+	<-done
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, first.Code)
+	for _, rec := range duplicates {
+		assert.Equal(t, http.StatusOK, rec.Code, "duplicates are acknowledged")
+	}
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	assert.Empty(t, handler.dispatcher.locks, "reference locks are released")
+}
+
+func TestWebhookDuplicateWaitsForFailedDelivery(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	var calls int64
+	entered := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		entered <- struct{}{}
+		if atomic.AddInt64(&calls, 1) == 1 {
+			<-unblock
+			return errors.New("db down")
+		}
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(first, notificationRequest("ORDER-1"))
+	}()
+	<-entered
+
+	duplicate := httptest.NewRecorder()
+	duplicateDone := make(chan struct{})
+	go func() {
+		defer close(duplicateDone)
+		handler.ServeHTTP(duplicate, notificationRequest("ORDER-1"))
+	}()
+
+	close(unblock)
+	<-done
+	<-duplicateDone
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusOK, duplicate.Code, "the duplicate processes the released notification")
+	assert.Equal(t, int64(2), atomic.LoadInt64(&calls))
+}
+
+// rejectingProvider rejects every notification
+type rejectingProvider struct {
+	notifyingProvider
+}
+
+func (p *rejectingProvider) HandleNotification(*MasrviNotificationData) (*TransactionStatus, error) {
+	return nil, &NotificationRejectedError{Provider: ProviderMasrvi, Reason: NotificationBadSignature, Err: errors.New("signature mismatch")}
+}
+
+func TestWebhookRejectsUnverifiedNotifications(t *testing.T) {
+	client := newWebhookTestClient(t, WebhookConfig{})
+	require.NoError(t, client.AddProvider(ProviderMasrvi, &rejectingProvider{}))
+	handler := client.NewMasrviWebhookHandler(func(context.Context, *TransactionStatus) error {
+		t.Fatal("the handler must not see rejected notifications")
+		return nil
+	}, NewMemoryDeduplicator(0))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, notificationRequest("ORDER-1"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestMemoryDeduplicatorClaims(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	dedup := NewMemoryDeduplicator(time.Hour)
+	dedup.now = func() time.Time { return now }
+
+	claimed, err := dedup.Claim(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, _ = dedup.Claim(ctx, "a")
+	assert.False(t, claimed, "a claim in progress blocks duplicates")
+
+	require.NoError(t, dedup.Release(ctx, "a"))
+	claimed, _ = dedup.Claim(ctx, "a")
+	assert.True(t, claimed, "a released claim can be taken again")
+	require.NoError(t, dedup.Complete(ctx, "a"))
+	claimed, _ = dedup.Claim(ctx, "a")
+	assert.False(t, claimed)
+
+	now = now.Add(30 * time.Minute)
+	claimed, _ = dedup.Claim(ctx, "b")
+	require.True(t, claimed)
+	require.NoError(t, dedup.Complete(ctx, "b"))
+	assert.Equal(t, 2, dedup.Len())
+
+	// Processed keys are forgotten after the TTL
+	now = now.Add(31 * time.Minute)
+	assert.Equal(t, 1, dedup.Len())
+	assert.False(t, dedup.Processed("a"))
+	assert.True(t, dedup.Processed("b"))
+}